@@ -0,0 +1,134 @@
+// Package access maps the access levels of the accounts onto tiers granting
+// named capabilities, such as "server.testing.login", so the servers check
+// what an account can do rather than comparing raw levels
+package access
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/frostwind/l2go/config"
+)
+
+// Capabilities checked by the servers
+const (
+	LOGIN         = "server.login"         // Log in at all
+	TESTING_LOGIN = "server.testing.login" // Enter the game servers flagged as testing
+	CLOSED_LOGIN  = "server.closed.login"  // Log in while the server is in the gm-only or maintenance mode
+	ADMIN_READ    = "admin.read"           // Read the admin API
+	ADMIN_WRITE   = "admin.write"          // Change the server state through the admin API
+	GM_GIVE_ITEM  = "gm.give_item"         // Give items to oneself with the //give_item command
+	GM_ENCHANT    = "gm.enchant"           // Enchant one's items with the //enchant command
+)
+
+var ErrInvalidTiers = errors.New("invalid access tiers")
+
+// Tier is a named range of access levels, starting at Level, along with its capabilities.
+// A capability ending with ".*" grants all the capabilities under it, "*" grants everything.
+type Tier struct {
+	Name         string
+	Level        int8
+	Capabilities []string
+}
+
+// Can returns whether the tier grants the capability
+func (t *Tier) Can(capability string) bool {
+	if t == nil {
+		return false
+	}
+	for _, granted := range t.Capabilities {
+		if granted == "*" || granted == capability {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(granted, "*"); ok && strings.HasPrefix(capability, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultTiers is used when the configuration doesn't list any tier.
+// The levels below the player tier, such as the banned accounts, can't do anything.
+func DefaultTiers() []config.AccessTierType {
+	return []config.AccessTierType{
+		{Name: "player", Level: 0, Capabilities: []string{LOGIN}},
+		{Name: "tester", Level: 1, Capabilities: []string{TESTING_LOGIN}},
+		{Name: "gm", Level: 2, Capabilities: []string{CLOSED_LOGIN, "gm.*", ADMIN_READ}},
+		{Name: "admin", Level: 3, Capabilities: []string{"*"}},
+	}
+}
+
+// Model resolves the access levels into tiers
+type Model struct {
+	tiers []*Tier // Sorted by level
+}
+
+// NewModel builds the tiers of the configuration, each tier also granting the
+// capabilities of the tiers below it. No tier at all means DefaultTiers.
+func NewModel(tiers []config.AccessTierType) (*Model, error) {
+	if len(tiers) == 0 {
+		tiers = DefaultTiers()
+	}
+
+	sorted := make([]config.AccessTierType, len(tiers))
+	copy(sorted, tiers)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Level < sorted[j].Level })
+
+	model := &Model{}
+	names := make(map[string]bool)
+	var inherited []string
+	for i, tier := range sorted {
+		if tier.Name == "" {
+			return nil, fmt.Errorf("%w: the tier of level %d has no name", ErrInvalidTiers, tier.Level)
+		}
+		if names[tier.Name] {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidTiers, tier.Name)
+		}
+		if i > 0 && sorted[i-1].Level == tier.Level {
+			return nil, fmt.Errorf("%w: %s and %s share the level %d", ErrInvalidTiers, sorted[i-1].Name, tier.Name, tier.Level)
+		}
+		names[tier.Name] = true
+
+		inherited = append(inherited, tier.Capabilities...)
+		capabilities := make([]string, len(inherited))
+		copy(capabilities, inherited)
+		model.tiers = append(model.tiers, &Tier{Name: tier.Name, Level: tier.Level, Capabilities: capabilities})
+	}
+
+	return model, nil
+}
+
+// DefaultModel returns the model of the default tiers
+func DefaultModel() *Model {
+	model, _ := NewModel(DefaultTiers())
+	return model
+}
+
+// TierOf returns the tier of an access level, nil when it is below every tier
+func (m *Model) TierOf(level int8) *Tier {
+	var found *Tier
+	for _, tier := range m.tiers {
+		if tier.Level > level {
+			break
+		}
+		found = tier
+	}
+	return found
+}
+
+// Tier returns the tier of the given name, nil when there is none
+func (m *Model) Tier(name string) *Tier {
+	for _, tier := range m.tiers {
+		if tier.Name == name {
+			return tier
+		}
+	}
+	return nil
+}
+
+// Can returns whether the access level grants the capability
+func (m *Model) Can(level int8, capability string) bool {
+	return m.TierOf(level).Can(capability)
+}
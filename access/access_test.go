@@ -0,0 +1,69 @@
+package access
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/frostwind/l2go/config"
+)
+
+func TestDefaultTiers(t *testing.T) {
+	model := DefaultModel()
+
+	tests := []struct {
+		level      int8
+		capability string
+		want       bool
+	}{
+		{level: -1, capability: LOGIN, want: false},
+		{level: 0, capability: LOGIN, want: true},
+		{level: 0, capability: TESTING_LOGIN, want: false},
+		{level: 1, capability: LOGIN, want: true},
+		{level: 1, capability: TESTING_LOGIN, want: true},
+		{level: 1, capability: CLOSED_LOGIN, want: false},
+		{level: 2, capability: CLOSED_LOGIN, want: true},
+		{level: 2, capability: "gm.teleport", want: true},
+		{level: 2, capability: ADMIN_READ, want: true},
+		{level: 2, capability: ADMIN_WRITE, want: false},
+		{level: 3, capability: ADMIN_WRITE, want: true},
+		{level: 100, capability: "anything", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := model.Can(tt.level, tt.capability); got != tt.want {
+			t.Errorf("Can(%d, %s) = %v, want %v", tt.level, tt.capability, got, tt.want)
+		}
+	}
+
+	if tier := model.TierOf(2); tier == nil || tier.Name != "gm" {
+		t.Errorf("TierOf(2) = %+v, want gm", tier)
+	}
+	if tier := model.TierOf(-1); tier != nil {
+		t.Errorf("TierOf(-1) = %+v, want none", tier)
+	}
+}
+
+func TestNewModel(t *testing.T) {
+	// Unsorted tiers, each one inheriting from the lower ones
+	model, err := NewModel([]config.AccessTierType{
+		{Name: "moderator", Level: 5, Capabilities: []string{"chat.*"}},
+		{Name: "member", Level: 0, Capabilities: []string{LOGIN}},
+	})
+	if err != nil {
+		t.Fatalf("NewModel() error = %v", err)
+	}
+	if !model.Can(7, "chat.mute") || !model.Can(5, LOGIN) || model.Can(4, "chat.mute") || model.Can(5, "chatty") {
+		t.Errorf("unexpected capabilities %+v", model.Tier("moderator"))
+	}
+
+	invalid := [][]config.AccessTierType{
+		{{Name: "", Level: 0}},
+		{{Name: "player", Level: 0}, {Name: "player", Level: 1}},
+		{{Name: "player", Level: 0}, {Name: "member", Level: 0}},
+	}
+	for _, tiers := range invalid {
+		if _, err := NewModel(tiers); !errors.Is(err, ErrInvalidTiers) {
+			t.Errorf("NewModel(%+v) error = %v, want ErrInvalidTiers", tiers, err)
+		}
+	}
+}
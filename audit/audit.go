@@ -0,0 +1,176 @@
+// Package audit records the administrative and security events of the servers
+// in an append-only JSON lines file, rotated by size and pruned by age
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/config"
+)
+
+// Actions recorded in the audit log
+const (
+	ACCOUNT_CREATED = "account.created"
+	ACCOUNT_BANNED  = "account.banned"
+	GM_COMMAND      = "gm.command"
+	ADMIN_CALL      = "admin.call"
+	HACK_ATTEMPT    = "security.hack"
+	MODE_CHANGED    = "server.mode"
+)
+
+var ErrClosed = errors.New("audit log is closed")
+
+// Entry is a line of the audit log
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Actor   string    `json:"actor,omitempty"`   // Account, or server, which acted
+	Target  string    `json:"target,omitempty"`  // Account or resource acted upon
+	Address string    `json:"address,omitempty"` // IP address the action came from
+	Details string    `json:"details,omitempty"`
+}
+
+// Logger appends entries to the audit log
+type Logger struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	maxAge   time.Duration
+	file     *os.File
+	size     int64
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+// Open opens the audit log of the configuration for appending, creating it if needed
+func Open(cfg config.AuditType) (*Logger, error) {
+	l := &Logger{
+		path:     cfg.Path,
+		maxSize:  cfg.RotationSize(),
+		maxFiles: cfg.RotationFiles(),
+		maxAge:   cfg.MaxAge,
+		now:      time.Now,
+	}
+
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	l.prune()
+
+	return l, nil
+}
+
+// Record appends an entry, stamped with the current time when it has none
+func (l *Logger) Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = l.now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return ErrClosed
+	}
+
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// Close flushes and closes the audit log
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Sync()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+func (l *Logger) open() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the audit log %s: %w", l.path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open the audit log %s: %w", l.path, err)
+	}
+
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// rotate shifts the log into path.1, path.1 into path.2 and so on, dropping
+// the files past the last one kept, then starts a new log
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+
+	os.Remove(l.rotated(l.maxFiles))
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		os.Rename(l.rotated(i), l.rotated(i+1))
+	}
+	if l.maxFiles > 0 {
+		if err := os.Rename(l.path, l.rotated(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.path); err != nil {
+		return err
+	}
+
+	if err := l.open(); err != nil {
+		return err
+	}
+	l.prune()
+	return nil
+}
+
+// prune removes the rotated files older than the retention period
+func (l *Logger) prune() {
+	if l.maxAge <= 0 {
+		return
+	}
+
+	oldest := l.now().Add(-l.maxAge)
+	for i := 1; i <= l.maxFiles; i++ {
+		info, err := os.Stat(l.rotated(i))
+		if err == nil && info.ModTime().Before(oldest) {
+			os.Remove(l.rotated(i))
+		}
+	}
+}
+
+func (l *Logger) rotated(index int) string {
+	return fmt.Sprintf("%s.%d", l.path, index)
+}
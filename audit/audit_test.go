@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/config"
+)
+
+// readEntries reads the entries of an audit log file
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoggerRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	logger, err := Open(config.AuditType{Path: path})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	logger.Record(Entry{Time: at, Action: ACCOUNT_CREATED, Actor: "newuser", Target: "newuser", Address: "10.0.0.1"})
+	logger.Close()
+
+	// Reopening appends to the log
+	logger, err = Open(config.AuditType{Path: path})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	logger.Record(Entry{Action: HACK_ATTEMPT, Address: "10.0.0.2", Details: "oversized packet"})
+	logger.Close()
+
+	entries := readEntries(t, path)
+	if len(entries) != 2 || !entries[0].Time.Equal(at) || entries[0].Actor != "newuser" || entries[1].Action != HACK_ATTEMPT || entries[1].Time.IsZero() {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	if err := logger.Record(Entry{Action: ADMIN_CALL}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Record() error = %v once closed, want ErrClosed", err)
+	}
+}
+
+func TestLoggerRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	entry := Entry{Time: time.Now(), Action: ADMIN_CALL, Actor: "admin", Target: "/stats", Details: "GET 200"}
+	line, _ := json.Marshal(entry)
+
+	// Every file holds two entries
+	logger, err := Open(config.AuditType{Path: path, MaxSize: int64(2 * (len(line) + 1)), MaxFiles: 2, MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer logger.Close()
+
+	for i := 0; i < 7; i++ {
+		if err := logger.Record(entry); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	for file, want := range map[string]int{path: 1, path + ".1": 2, path + ".2": 2} {
+		if entries := readEntries(t, file); len(entries) != want {
+			t.Errorf("%s holds %d entries, want %d", filepath.Base(file), len(entries), want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("a third rotated file was kept: %v", err)
+	}
+
+	// The rotated files past the retention period are removed at the next rotation
+	old := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(path+".1", old, old)
+	logger.Record(entry)
+	logger.Record(entry)
+
+	if _, err := os.Stat(path + ".2"); !os.IsNotExist(err) {
+		t.Errorf("the expired rotated file was kept: %v", err)
+	}
+	if entries := readEntries(t, path+".1"); len(entries) != 2 {
+		t.Errorf("the last rotated file holds %d entries", len(entries))
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Account is a username and a password a client logs in with
+type Account struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// AccountPoolConfig lists the accounts the clients of a manager share, each of them logged in with by a
+// single client at a time, so that the clients don't trip the single login of the servers
+type AccountPoolConfig struct {
+	Accounts []Account `json:"accounts,omitempty"`
+
+	// Size accounts named after Username followed by an index from 0, all with Password, follow Accounts
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Size     int    `json:"size,omitempty"`
+
+	// How long a connecting client waits for an account to be released when they are all in use, failing at once when 0
+	Wait time.Duration `json:"wait,omitempty"`
+}
+
+// Enabled reports whether the configuration lists any account, the clients logging in with their own otherwise
+func (pc AccountPoolConfig) Enabled() bool {
+	return len(pc.Accounts) > 0 || pc.Size > 0
+}
+
+// List returns the accounts of the pool, the listed ones first
+func (pc AccountPoolConfig) List() []Account {
+	accounts := append([]Account(nil), pc.Accounts...)
+	for i := 0; i < pc.Size; i++ {
+		accounts = append(accounts, Account{Username: fmt.Sprintf("%s%d", pc.Username, i), Password: pc.Password})
+	}
+	return accounts
+}
+
+// Validate validates the account pool
+func (pc AccountPoolConfig) Validate() error {
+	if pc.Size < 0 {
+		return fmt.Errorf("size must be non-negative, got %d", pc.Size)
+	}
+	if pc.Size > 0 && pc.Username == "" {
+		return fmt.Errorf("username is required to name the %d accounts", pc.Size)
+	}
+	if pc.Wait < 0 {
+		return fmt.Errorf("wait must be non-negative, got %v", pc.Wait)
+	}
+
+	seen := make(map[string]bool)
+	for _, account := range pc.List() {
+		if account.Username == "" {
+			return fmt.Errorf("%w: an account has no username", ErrInvalidUsername)
+		}
+		if seen[account.Username] {
+			return fmt.Errorf("account %s is listed twice", account.Username)
+		}
+		seen[account.Username] = true
+	}
+	return nil
+}
+
+// AccountPool lends its accounts to the clients connecting, a single client holding an account at a time.
+// A client gets back the account it held last when it is free, keeping its characters across reconnections.
+type AccountPool struct {
+	accounts []Account
+	wait     time.Duration
+	holders  []string       // Client holding each account, by index of the account, none when empty
+	last     map[string]int // Account each client held last, by client id
+	released chan struct{}  // Closed and replaced whenever an account is released
+	mu       sync.Mutex
+}
+
+// NewAccountPool creates a pool of the accounts of the configuration
+func NewAccountPool(config AccountPoolConfig) *AccountPool {
+	accounts := config.List()
+	return &AccountPool{
+		accounts: accounts,
+		wait:     config.Wait,
+		holders:  make([]string, len(accounts)),
+		last:     make(map[string]int),
+		released: make(chan struct{}),
+	}
+}
+
+// Acquire lends an account to a client, the one it already holds if any. When every account is held
+// by another client, it waits for one to be released for as long as the pool allows, then returns
+// ErrAccountPoolExhausted.
+func (p *AccountPool) Acquire(clientID string) (Account, error) {
+	var expired <-chan time.Time
+	if p.wait > 0 {
+		timer := time.NewTimer(p.wait)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		p.mu.Lock()
+		if index, ok := p.free(clientID); ok {
+			p.holders[index] = clientID
+			p.last[clientID] = index
+			p.mu.Unlock()
+			return p.accounts[index], nil
+		}
+		released := p.released
+		p.mu.Unlock()
+
+		if expired == nil {
+			return Account{}, fmt.Errorf("%w: all %d accounts are in use", ErrAccountPoolExhausted, len(p.accounts))
+		}
+		select {
+		case <-released:
+		case <-expired:
+			return Account{}, fmt.Errorf("%w: all %d accounts are still in use after %v", ErrAccountPoolExhausted, len(p.accounts), p.wait)
+		}
+	}
+}
+
+// free returns the account a client should hold: the one it holds or held last if it is free, or any free one
+func (p *AccountPool) free(clientID string) (int, bool) {
+	if index, ok := p.last[clientID]; ok && (p.holders[index] == "" || p.holders[index] == clientID) {
+		return index, true
+	}
+	for index, holder := range p.holders {
+		if holder == "" {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// Release takes back the account a client holds, if any, waking up the clients waiting for one
+func (p *AccountPool) Release(clientID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	index, ok := p.last[clientID]
+	if !ok || p.holders[index] != clientID {
+		return
+	}
+	p.holders[index] = ""
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+// Holders returns the client holding each account in use, by username
+func (p *AccountPool) Holders() map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	holders := make(map[string]string)
+	for index, holder := range p.holders {
+		if holder != "" {
+			holders[p.accounts[index].Username] = holder
+		}
+	}
+	return holders
+}
+
+// Size returns how many accounts the pool lends
+func (p *AccountPool) Size() int {
+	return len(p.accounts)
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAccountPoolConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AccountPoolConfig
+		want    []string
+		wantErr bool
+	}{
+		{name: "disabled", config: AccountPoolConfig{}, want: nil},
+		{name: "listed then named", config: AccountPoolConfig{
+			Accounts: []Account{{Username: "admin", Password: "secret"}},
+			Username: "bot", Password: "pass", Size: 2,
+		}, want: []string{"admin", "bot0", "bot1"}},
+		{name: "listed twice", config: AccountPoolConfig{Accounts: []Account{{Username: "bot0"}}, Username: "bot", Size: 1}, wantErr: true},
+		{name: "no username", config: AccountPoolConfig{Size: 2}, wantErr: true},
+		{name: "negative wait", config: AccountPoolConfig{Username: "bot", Size: 1, Wait: -time.Second}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var usernames []string
+			for _, account := range tt.config.List() {
+				usernames = append(usernames, account.Username)
+			}
+			if len(usernames) != len(tt.want) {
+				t.Fatalf("List() = %v, want %v", usernames, tt.want)
+			}
+			for i := range usernames {
+				if usernames[i] != tt.want[i] {
+					t.Errorf("List() = %v, want %v", usernames, tt.want)
+				}
+			}
+			if tt.config.Enabled() != (len(tt.want) > 0) {
+				t.Errorf("Enabled() = %v for %d accounts", tt.config.Enabled(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestAccountPool(t *testing.T) {
+	pool := NewAccountPool(AccountPoolConfig{Username: "bot", Password: "pass", Size: 2})
+
+	first, err := pool.Acquire("client-1")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	second, err := pool.Acquire("client-2")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if first.Username == second.Username {
+		t.Fatalf("both clients hold %s", first.Username)
+	}
+	if again, err := pool.Acquire("client-1"); err != nil || again != first {
+		t.Errorf("Acquire() = %v, %v again, want the account held %v", again, err, first)
+	}
+
+	if _, err := pool.Acquire("client-3"); !errors.Is(err, ErrAccountPoolExhausted) {
+		t.Fatalf("Acquire() error = %v, want %v", err, ErrAccountPoolExhausted)
+	}
+
+	// A client gets back the account it held last, unless another one took it meanwhile
+	pool.Release("client-1")
+	pool.Release("client-1")
+	if again, err := pool.Acquire("client-1"); err != nil || again != first {
+		t.Errorf("Acquire() = %v, %v after a release, want %v", again, err, first)
+	}
+	pool.Release("client-1")
+	if taken, err := pool.Acquire("client-3"); err != nil || taken != first {
+		t.Errorf("Acquire() = %v, %v, want the released %v", taken, err, first)
+	}
+
+	if holders := pool.Holders(); len(holders) != 2 || holders[first.Username] != "client-3" || holders[second.Username] != "client-2" {
+		t.Errorf("Holders() = %v", holders)
+	}
+}
+
+func TestAccountPoolWait(t *testing.T) {
+	pool := NewAccountPool(AccountPoolConfig{Username: "bot", Size: 1, Wait: time.Second})
+	if _, err := pool.Acquire("client-1"); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		pool.Release("client-1")
+	}()
+	start := time.Now()
+	if _, err := pool.Acquire("client-2"); err != nil {
+		t.Fatalf("Acquire() error = %v while the account is released", err)
+	}
+	if took := time.Since(start); took >= time.Second {
+		t.Errorf("Acquire() took %v, want it to return on the release", took)
+	}
+
+	expiring := NewAccountPool(AccountPoolConfig{Username: "bot", Size: 1, Wait: 50 * time.Millisecond})
+	expiring.Acquire("client-1")
+	if _, err := expiring.Acquire("client-2"); !errors.Is(err, ErrAccountPoolExhausted) {
+		t.Errorf("Acquire() error = %v after the wait, want %v", err, ErrAccountPoolExhausted)
+	}
+}
@@ -0,0 +1,289 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// UseAction uses an action of the action window, as a click or an action shortcut would
+func (c *Client) UseAction(actionID int, ctrl, shift bool) error {
+	if err := c.requireInGame("use an action"); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestActionUse, newActionUsePayload(actionID, ctrl, shift)); err != nil {
+		return c.fail(err)
+	}
+
+	c.touch()
+	return nil
+}
+
+// Sit makes the character sit down, if it isn't already sitting
+func (c *Client) Sit() error {
+	return c.setSitting(true)
+}
+
+// Stand makes the character stand up, if it isn't already standing
+func (c *Client) Stand() error {
+	return c.setSitting(false)
+}
+
+// Run makes the character run, if it isn't already running
+func (c *Client) Run() error {
+	return c.setRunning(true)
+}
+
+// Walk makes the character walk, if it isn't already walking
+func (c *Client) Walk() error {
+	return c.setRunning(false)
+}
+
+// Emote plays an emote of the character, such as EmoteBow, and returns once the game server shows it.
+// A sitting character stands up first, as the game server refuses the emotes of the ones sitting.
+func (c *Client) Emote(actionID int) error {
+	if actionID < EmoteGreeting || actionID > EmoteSorrow {
+		return fmt.Errorf("%w: %d", ErrInvalidEmote, actionID)
+	}
+
+	if err := c.requireInGame("emote"); err != nil {
+		return err
+	}
+	if err := c.setSitting(false); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestSocialAction, newSocialActionPayload(actionID)); err != nil {
+		return c.fail(err)
+	}
+
+	// The players around may play emotes of their own meanwhile
+	for {
+		_, data, err := c.receiveGame(opcodes.GameServerSocialAction)
+		if err != nil {
+			return c.fail(err)
+		}
+
+		_, played, err := parseSocialActionPayload(data)
+		if err != nil {
+			return c.fail(err)
+		}
+		if played == actionID {
+			break
+		}
+	}
+
+	c.touch()
+	return nil
+}
+
+// setSitting toggles the sit/stand action until the game server confirms the wanted position
+func (c *Client) setSitting(sitting bool) error {
+	if err := c.requireInGame("sit or stand"); err != nil {
+		return err
+	}
+
+	state := c.sessions.GameSession().GameState
+	if state.IsSitting == sitting {
+		return nil
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestActionUse, newActionUsePayload(ActionSitStand, false, false)); err != nil {
+		return c.fail(err)
+	}
+
+	_, data, err := c.receiveGame(opcodes.GameServerChangeWaitType)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	state.IsSitting, err = parseChangeWaitTypePayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	c.touch()
+	return nil
+}
+
+// setRunning toggles the walk/run action until the game server confirms the wanted move type
+func (c *Client) setRunning(running bool) error {
+	if err := c.requireInGame("walk or run"); err != nil {
+		return err
+	}
+
+	state := c.sessions.GameSession().GameState
+	if state.IsRunning == running {
+		return nil
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestActionUse, newActionUsePayload(ActionWalkRun, false, false)); err != nil {
+		return c.fail(err)
+	}
+
+	_, data, err := c.receiveGame(opcodes.GameServerChangeMoveType)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	state.IsRunning, err = parseChangeMoveTypePayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	c.touch()
+	return nil
+}
+
+// RegisterShortcut binds an item, a skill, an action or a macro to a slot of the shortcut bar
+func (c *Client) RegisterShortcut(shortcut Shortcut) error {
+	if err := validShortcutSlot(shortcut.Slot, shortcut.Page); err != nil {
+		return err
+	}
+	if shortcut.Type < ShortcutItem || shortcut.Type > ShortcutRecipe {
+		return fmt.Errorf("%w: unknown type %d", ErrInvalidShortcut, shortcut.Type)
+	}
+
+	if err := c.requireInGame("register a shortcut"); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestShortCutReg, newShortcutRegPayload(shortcut)); err != nil {
+		return c.fail(err)
+	}
+
+	_, data, err := c.receiveGame(opcodes.GameServerShortCutRegister)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	registered, err := parseShortcutRegisterPayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	session := c.sessions.GameSession()
+	session.Shortcuts = append(removeShortcut(session.Shortcuts, registered.Slot, registered.Page), registered)
+
+	c.touch()
+	return nil
+}
+
+// DeleteShortcut empties a slot of the shortcut bar. The game server doesn't answer.
+func (c *Client) DeleteShortcut(slot, page int) error {
+	if err := validShortcutSlot(slot, page); err != nil {
+		return err
+	}
+
+	if err := c.requireInGame("delete a shortcut"); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestShortCutDel, newShortcutDelPayload(slot, page)); err != nil {
+		return c.fail(err)
+	}
+
+	session := c.sessions.GameSession()
+	session.Shortcuts = removeShortcut(session.Shortcuts, slot, page)
+
+	c.touch()
+	return nil
+}
+
+// Say speaks to the players around the character, and returns once the game server echoes the message
+func (c *Client) Say(text string) error {
+	if err := c.requireInGame("chat"); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientSay2, newSay2Payload(text, ChatAll, "")); err != nil {
+		return c.fail(err)
+	}
+
+	session := c.sessions.GameSession()
+	for {
+		_, data, err := c.receiveGame(opcodes.GameServerCreatureSay)
+		if err != nil {
+			return c.fail(err)
+		}
+
+		objectID, chatType, _, said, err := parseCreatureSayPayload(data)
+		if err != nil {
+			return c.fail(err)
+		}
+
+		// The other players around speak too
+		if objectID == session.SelectedChar.ID && chatType == ChatAll && said == text {
+			c.touch()
+			return nil
+		}
+	}
+}
+
+// MoveTo walks the character towards a location, and returns once the game server shows the move.
+// The character is then taken to stand at its destination.
+func (c *Client) MoveTo(x, y, z int) error {
+	if err := c.requireInGame("move"); err != nil {
+		return err
+	}
+
+	session := c.sessions.GameSession()
+	destination := &CharacterLocation{X: x, Y: y, Z: z}
+	if err := c.sendGame(opcodes.GameClientMoveBackwardToLocation, newMoveBackwardToLocationPayload(destination, session.SelectedChar.Location)); err != nil {
+		return c.fail(err)
+	}
+
+	for {
+		_, data, err := c.receiveGame(opcodes.GameServerMoveToLocation)
+		if err != nil {
+			return c.fail(err)
+		}
+
+		objectID, location, err := parseMoveToLocationPayload(data)
+		if err != nil {
+			return c.fail(err)
+		}
+
+		// The other creatures around move too
+		if objectID == session.SelectedChar.ID {
+			session.SelectedChar.Location = location
+			c.touch()
+			return nil
+		}
+	}
+}
+
+// requireInGame returns an error if the client hasn't entered the world, or only watches it
+func (c *Client) requireInGame(action string) error {
+	if c.config.Spectator {
+		return fmt.Errorf("%w: cannot %s", ErrSpectator, action)
+	}
+	if state := c.GetState(); state != StateInGame {
+		return fmt.Errorf("%w: cannot %s while %s", ErrInvalidState, action, state)
+	}
+	return nil
+}
+
+// touch records the last time the client did something in the world
+func (c *Client) touch() {
+	c.sessions.GameSession().GameState.LastUpdate = time.Now()
+}
+
+func validShortcutSlot(slot, page int) error {
+	if slot < 0 || slot >= ShortcutSlots || page < 0 || page >= ShortcutPages {
+		return fmt.Errorf("%w: slot %d of page %d", ErrInvalidShortcut, slot, page)
+	}
+	return nil
+}
+
+func removeShortcut(shortcuts []Shortcut, slot, page int) []Shortcut {
+	kept := shortcuts[:0]
+	for _, shortcut := range shortcuts {
+		if shortcut.Slot != slot || shortcut.Page != page {
+			kept = append(kept, shortcut)
+		}
+	}
+	return kept
+}
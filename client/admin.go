@@ -0,0 +1,92 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// AdminCommand sends a command as a GM types it in the chat, with or without its leading //.
+// The game server only runs the commands the access level of the account grants.
+func (c *Client) AdminCommand(command string) error {
+	if err := c.requireInGame("send an admin command"); err != nil {
+		return err
+	}
+
+	command = strings.TrimPrefix(strings.TrimSpace(command), "//")
+	if err := c.sendGame(opcodes.GameClientSendBypassBuildCmd, newBypassPayload(command)); err != nil {
+		return c.fail(err)
+	}
+
+	c.touch()
+	return nil
+}
+
+// GiveItem gives items to the character with the //give_item command, so that a test can equip it
+// before a scenario. It returns once the inventory holds them, or ErrAdminCommandRefused with the
+// reason given by the game server.
+func (c *Client) GiveItem(itemID int, count uint64) error {
+	if err := c.AdminCommand(fmt.Sprintf("give_item %d %d", itemID, count)); err != nil {
+		return err
+	}
+
+	data, err := c.receiveAdminAnswer(opcodes.GameServerItemList, 0)
+	if err != nil {
+		return err
+	}
+
+	items, err := reassemble(data, c.nextGamePage(opcodes.GameServerItemList), parseItemListPayload, mergeCounts)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	c.sessions.GameSession().Inventory = items
+	c.touch()
+	return nil
+}
+
+// Enchant sets the enchant level of an item of the character with the //enchant command. It returns
+// once the game server confirmed it, or ErrAdminCommandRefused with the reason it gave.
+func (c *Client) Enchant(itemID, level int) error {
+	if err := c.AdminCommand(fmt.Sprintf("enchant %d %d", itemID, level)); err != nil {
+		return err
+	}
+
+	if _, err := c.receiveAdminAnswer(opcodes.GameServerSystemMessage, SystemMessageEnchanted); err != nil {
+		return err
+	}
+
+	session := c.sessions.GameSession()
+	if session.Enchants == nil {
+		session.Enchants = make(map[int]int)
+	}
+	session.Enchants[itemID] = level
+	c.touch()
+	return nil
+}
+
+// receiveAdminAnswer waits for the packet answering an admin command, a system message of the given
+// id when expected is SystemMessage, or for the system message telling why the command was refused
+func (c *Client) receiveAdminAnswer(expected byte, messageID uint32) ([]byte, error) {
+	for {
+		opcode, data, err := c.receiveGame(expected, opcodes.GameServerSystemMessage)
+		if err != nil {
+			return nil, c.fail(err)
+		}
+		if opcode != opcodes.GameServerSystemMessage {
+			return data, nil
+		}
+
+		id, params, err := parseSystemMessagePayload(data)
+		if err != nil {
+			return nil, c.fail(err)
+		}
+		switch {
+		case expected == opcodes.GameServerSystemMessage && id == messageID:
+			return data, nil
+		case id == SystemMessageText:
+			return nil, fmt.Errorf("%w: %s", ErrAdminCommandRefused, strings.Join(params, " "))
+		}
+	}
+}
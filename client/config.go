@@ -2,10 +2,13 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/frostwind/l2go/config"
 )
 
 // ToolkitConfig represents the complete configuration for the client toolkit
@@ -15,6 +18,23 @@ type ToolkitConfig struct {
 	LoadTest LoadTestConfig `json:"loadTest"`
 	Logging  LoggingConfig  `json:"logging"`
 	Profiles ProfilesConfig `json:"profiles"`
+	Events   EventsConfig   `json:"events"`
+
+	// Fleets run side by side by the same process instead of the single fleet of Manager and LoadTest
+	Fleets []FleetConfig `json:"fleets,omitempty"`
+}
+
+// FleetConfig is a fleet of clients run alongside the other fleets of the process, isolated from them:
+// it has its own manager, with its metrics and its event bus, its accounts and its load test, so that
+// the servers can be hit by differentiated workloads at once
+type FleetConfig struct {
+	Name     string         `json:"name"`
+	Profile  string         `json:"profile,omitempty"`  // Environment profile the clients connect with, the client configuration when empty
+	Username string         `json:"username,omitempty"` // Account of the clients, or prefix of the accounts of the pool, the one of the profile when empty
+	Password string         `json:"password,omitempty"`
+	Accounts int            `json:"accounts,omitempty"` // Accounts cycled through, the username followed by an index, 0 for the username alone
+	Manager  ManagerConfig  `json:"manager"`
+	LoadTest LoadTestConfig `json:"loadTest"`
 }
 
 // ManagerConfig holds configuration for the client manager
@@ -24,6 +44,38 @@ type ManagerConfig struct {
 	HealthCheck     time.Duration `json:"healthCheck"`
 	RetryAttempts   int           `json:"retryAttempts"`
 	RetryDelay      time.Duration `json:"retryDelay"`
+
+	// Name the metrics are published under in the diagnostics, "manager" when empty
+	Namespace string `json:"namespace,omitempty"`
+
+	// Time after which the health check reports a client still in the same state as stuck, by state name
+	StuckAfter map[string]time.Duration `json:"stuckAfter,omitempty"`
+
+	// Serves the pprof profiles and the expvar variables of the toolkit, and dumps its goroutines on SIGUSR1
+	Diagnostics config.DiagnosticsType `json:"diagnostics,omitzero"`
+
+	// Highest share of the actions of the clients failing in each category of errors, the manager being
+	// unhealthy while a category is over its budget
+	ErrorBudgets ErrorBudgetConfig `json:"errorBudgets,omitzero"`
+
+	// File the summary of the clients is written to as json on shutdown, on top of being logged, none when empty
+	SummaryFile string `json:"summaryFile,omitempty"`
+
+	// Accounts the clients borrow as they connect, each held by a single client at a time, instead of
+	// logging in with the account of their configuration. Disabled when it lists no account.
+	AccountPool AccountPoolConfig `json:"accountPool,omitzero"`
+
+	// Local addresses the connections of all the clients are bound to in turn, replacing the source
+	// addresses of their configuration, with the connections of each address counted in the metrics
+	SourceAddresses []SourceAddress `json:"sourceAddresses,omitempty"`
+	ReusePort       bool            `json:"reusePort,omitempty"` // Set SO_REUSEPORT on the bound sockets, where the system supports it
+
+	// How the game connections of the spectators wait for the packets the game server sends them
+	// unprompted: goroutine, the default, blocks a goroutine on every connection, reactor multiplexes
+	// them on a few goroutines waiting with epoll, on Linux only. The other connections are read by
+	// the call awaiting the answer of the servers, with either backend.
+	ConnectionBackend string `json:"connectionBackend,omitempty"`
+	ReactorWorkers    int    `json:"reactorWorkers,omitempty"` // Goroutines of the reactor reading the packets, DefaultReactorWorkers when 0
 }
 
 // LoadTestConfig holds configuration for load testing
@@ -33,6 +85,33 @@ type LoadTestConfig struct {
 	DefaultRampUpTime  time.Duration `json:"defaultRampUpTime"`
 	MaxConcurrentTests int           `json:"maxConcurrentTests"`
 	ReportFormat       string        `json:"reportFormat"`
+	Shape              LoadShape     `json:"shape"`
+	SLOs               []SLO         `json:"slos,omitempty"`  // Thresholds deciding whether a run passes
+	Speed              float64       `json:"speed,omitempty"` // Runs the shape and the scenario waits this many times faster, against stub servers, real time when 0 or 1
+
+	// Heap of the process the run should stay under, in MiB, a warning telling when the ramp should stop
+	// as it nears it, none when 0. The report tells the footprint of a client whether it is set or not.
+	MemoryBudgetMB int `json:"memoryBudgetMB,omitempty"`
+}
+
+// EventsConfig holds the external systems the manager events are forwarded to
+type EventsConfig struct {
+	Sinks []SinkConfig `json:"sinks,omitempty"`
+}
+
+// SinkConfig describes an external system events are forwarded to, and how
+type SinkConfig struct {
+	Type          string            `json:"type"`                    // One of webhook, nats and kafka
+	URL           string            `json:"url"`                     // Webhook endpoint, NATS server address or Kafka REST proxy
+	Subject       string            `json:"subject,omitempty"`       // NATS subject prefix or Kafka topic
+	Token         string            `json:"token,omitempty"`         // NATS authentication token
+	Headers       map[string]string `json:"headers,omitempty"`       // Added to the webhook and Kafka requests
+	Topics        []string          `json:"topics"`                  // EventBus topics forwarded
+	BatchSize     int               `json:"batchSize,omitempty"`     // Events sent at once, at most
+	FlushInterval time.Duration     `json:"flushInterval,omitempty"` // Longest time an event waits for its batch to fill
+	MaxRetries    int               `json:"maxRetries,omitempty"`    // Attempts after a failed one, negative for none
+	RetryDelay    time.Duration     `json:"retryDelay,omitempty"`    // Delay before the first retry, doubled at every retry
+	BufferSize    int               `json:"bufferSize,omitempty"`    // Events waiting to be sent, at most
 }
 
 // LoggingConfig holds configuration for logging
@@ -72,6 +151,9 @@ type CredentialsProfile struct {
 	Username   string `json:"username"`
 	Password   string `json:"password"`
 	AutoCreate bool   `json:"autoCreate"`
+
+	// Account policy of the login server, which must accept the credentials when the account is auto-created
+	Policy config.AccountPolicyType `json:"policy,omitzero"`
 }
 
 // DefaultToolkitConfig returns a default configuration
@@ -86,6 +168,11 @@ func DefaultToolkitConfig() *ToolkitConfig {
 			Password:        "testpass",
 			AutoCreate:      true,
 			Timeout:         30 * time.Second,
+			StateTimeouts: map[string]time.Duration{
+				"ConnectingLogin": 10 * time.Second,
+				"Authenticating":  15 * time.Second,
+				"ConnectingGame":  30 * time.Second,
+			},
 		},
 		Manager: ManagerConfig{
 			MaxClients:      1000,
@@ -93,6 +180,11 @@ func DefaultToolkitConfig() *ToolkitConfig {
 			HealthCheck:     5 * time.Second,
 			RetryAttempts:   3,
 			RetryDelay:      1 * time.Second,
+			StuckAfter: map[string]time.Duration{
+				"ConnectingLogin": 10 * time.Second,
+				"Authenticating":  15 * time.Second,
+				"ConnectingGame":  30 * time.Second,
+			},
 		},
 		LoadTest: LoadTestConfig{
 			DefaultClientCount: 10,
@@ -100,6 +192,7 @@ func DefaultToolkitConfig() *ToolkitConfig {
 			DefaultRampUpTime:  10 * time.Second,
 			MaxConcurrentTests: 5,
 			ReportFormat:       "json",
+			Shape:              LoadShape{Type: ShapeLinear},
 		},
 		Logging: LoggingConfig{
 			Level:         "info",
@@ -193,9 +286,72 @@ func (tc *ToolkitConfig) Validate() error {
 		return fmt.Errorf("profiles config validation failed: %w", err)
 	}
 
+	// Validate event sinks configuration
+	for i, sink := range tc.Events.Sinks {
+		if err := sink.Validate(); err != nil {
+			return fmt.Errorf("event sink %d validation failed: %w", i+1, err)
+		}
+	}
+
+	// Validate fleets configuration
+	names := make(map[string]bool, len(tc.Fleets))
+	for i, fleet := range tc.Fleets {
+		if err := tc.validateFleet(fleet); err != nil {
+			return fmt.Errorf("fleet %d validation failed: %w", i+1, err)
+		}
+		if names[fleet.Name] {
+			return fmt.Errorf("fleet %d validation failed: the name %q is taken by another fleet", i+1, fleet.Name)
+		}
+		names[fleet.Name] = true
+	}
+
+	return nil
+}
+
+// validateFleet checks a fleet, whose profile must be one of the toolkit
+func (tc *ToolkitConfig) validateFleet(fleet FleetConfig) error {
+	if fleet.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if fleet.Accounts < 0 {
+		return fmt.Errorf("accounts must be non-negative, got %d", fleet.Accounts)
+	}
+	if _, err := tc.FleetClient(fleet); err != nil {
+		return err
+	}
+	if err := fleet.Manager.Validate(); err != nil {
+		return fmt.Errorf("manager config validation failed: %w", err)
+	}
+	if err := fleet.LoadTest.Validate(); err != nil {
+		return fmt.Errorf("load test config validation failed: %w", err)
+	}
 	return nil
 }
 
+// FleetClient returns the configuration of the clients of a fleet: the client configuration of the
+// toolkit, with the servers and the credentials of the profile of the fleet, then its own credentials
+func (tc *ToolkitConfig) FleetClient(fleet FleetConfig) (ClientConfig, error) {
+	cfg := tc.Client
+	if fleet.Profile != "" {
+		profile, err := tc.Profile(fleet.Profile)
+		if err != nil {
+			return cfg, err
+		}
+		profile.applyTo(&cfg, true)
+	}
+
+	if fleet.Username != "" {
+		cfg.Username = fleet.Username
+	}
+	if fleet.Password != "" {
+		cfg.Password = fleet.Password
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("client config validation failed: %w", err)
+	}
+	return cfg, nil
+}
+
 // Validate validates the manager configuration
 func (mc *ManagerConfig) Validate() error {
 	if mc.MaxClients <= 0 {
@@ -213,6 +369,21 @@ func (mc *ManagerConfig) Validate() error {
 	if mc.RetryDelay < 0 {
 		return fmt.Errorf("retryDelay must be non-negative, got %v", mc.RetryDelay)
 	}
+	if err := validateStateTimeouts(mc.StuckAfter); err != nil {
+		return fmt.Errorf("invalid stuckAfter: %w", err)
+	}
+	if err := mc.ErrorBudgets.Validate(); err != nil {
+		return fmt.Errorf("invalid errorBudgets: %w", err)
+	}
+	if err := mc.AccountPool.Validate(); err != nil {
+		return fmt.Errorf("invalid accountPool: %w", err)
+	}
+	if err := validateSourceAddresses(mc.SourceAddresses, mc.ReusePort); err != nil {
+		return fmt.Errorf("invalid sourceAddresses: %w", err)
+	}
+	if err := validateBackend(mc.ConnectionBackend, mc.ReactorWorkers); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -230,9 +401,46 @@ func (ltc *LoadTestConfig) Validate() error {
 	if ltc.MaxConcurrentTests <= 0 {
 		return fmt.Errorf("maxConcurrentTests must be greater than 0, got %d", ltc.MaxConcurrentTests)
 	}
-	validFormats := map[string]bool{"json": true, "xml": true, "csv": true, "text": true}
+	validFormats := map[string]bool{"json": true, "xml": true, "csv": true, "text": true, "junit": true}
 	if !validFormats[ltc.ReportFormat] {
-		return fmt.Errorf("invalid reportFormat: %s, must be one of: json, xml, csv, text", ltc.ReportFormat)
+		return fmt.Errorf("invalid reportFormat: %s, must be one of: json, xml, csv, text, junit", ltc.ReportFormat)
+	}
+	if ltc.Speed < 0 {
+		return fmt.Errorf("speed must be non-negative, got %v", ltc.Speed)
+	}
+	if ltc.MemoryBudgetMB < 0 {
+		return fmt.Errorf("memoryBudgetMB must be non-negative, got %d", ltc.MemoryBudgetMB)
+	}
+	if err := ltc.Shape.Validate(ltc.DefaultClientCount); err != nil {
+		return fmt.Errorf("shape validation failed: %w", err)
+	}
+	for i, slo := range ltc.SLOs {
+		if err := slo.Validate(); err != nil {
+			return fmt.Errorf("slo %d validation failed: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Validate validates an event sink configuration
+func (sc *SinkConfig) Validate() error {
+	switch sc.Type {
+	case "webhook", "nats":
+	case "kafka":
+		if sc.Subject == "" {
+			return fmt.Errorf("subject must name the kafka topic")
+		}
+	default:
+		return fmt.Errorf("invalid sink type: %s, must be one of: webhook, nats, kafka", sc.Type)
+	}
+	if sc.URL == "" {
+		return fmt.Errorf("url must not be empty")
+	}
+	if len(sc.Topics) == 0 {
+		return fmt.Errorf("topics must list at least one topic")
+	}
+	if sc.BatchSize < 0 || sc.FlushInterval < 0 || sc.RetryDelay < 0 || sc.BufferSize < 0 {
+		return fmt.Errorf("batchSize, flushInterval, retryDelay and bufferSize must be non-negative")
 	}
 	return nil
 }
@@ -312,24 +520,47 @@ func (cp *CredentialsProfile) Validate() error {
 	if cp.Password == "" {
 		return fmt.Errorf("password must not be empty")
 	}
+	if err := cp.Policy.Validate(); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+	if cp.AutoCreate {
+		return cp.Policy.Check(cp.Username, cp.Password)
+	}
 	return nil
 }
 
-// LoadConfig loads configuration from a file
+// ENV_TOOLKIT_PREFIX starts the name of the environment variables overriding the toolkit configuration
+const ENV_TOOLKIT_PREFIX = config.ENV_PREFIX + "TOOLKIT_"
+
+// LoadConfig loads configuration from a file, then applies the L2GO_TOOLKIT_ environment variables.
+// Without a file name nor a file in the default locations, the default configuration is used, so
+// a container can be configured by its environment alone.
 func LoadConfig(filename string) (*ToolkitConfig, error) {
+	return loadConfig(filename, os.LookupEnv)
+}
+
+func loadConfig(filename string, env config.Env) (*ToolkitConfig, error) {
 	// If filename is empty, try default locations
-	if filename == "" {
+	named := filename != ""
+	if !named {
 		filename = findConfigFile()
 	}
 
+	var config ToolkitConfig
 	data, err := os.ReadFile(filename)
-	if err != nil {
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", filename, err)
+		}
+	case named || !errors.Is(err, os.ErrNotExist):
 		return nil, fmt.Errorf("failed to read config file %s: %w", filename, err)
+	default:
+		config = *DefaultToolkitConfig()
 	}
 
-	var config ToolkitConfig
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file %s: %w", filename, err)
+	if err := config.ApplyEnv(env); err != nil {
+		return nil, err
 	}
 
 	if err := config.Validate(); err != nil {
@@ -339,6 +570,29 @@ func LoadConfig(filename string) (*ToolkitConfig, error) {
 	return &config, nil
 }
 
+// ApplyEnv overrides the configuration with the L2GO_TOOLKIT_ environment variables
+func (tc *ToolkitConfig) ApplyEnv(env config.Env) error {
+	prefix := ENV_TOOLKIT_PREFIX
+	return errors.Join(
+		config.FromEnv(env, prefix+"LOGIN_HOST", &tc.Client.LoginServerHost),
+		config.FromEnv(env, prefix+"LOGIN_PORT", &tc.Client.LoginServerPort),
+		config.FromEnv(env, prefix+"GAME_HOST", &tc.Client.GameServerHost),
+		config.FromEnv(env, prefix+"GAME_PORT", &tc.Client.GameServerPort),
+		config.FromEnv(env, prefix+"USERNAME", &tc.Client.Username),
+		config.FromEnv(env, prefix+"PASSWORD", &tc.Client.Password),
+		config.FromEnv(env, prefix+"AUTO_CREATE", &tc.Client.AutoCreate),
+		config.FromEnv(env, prefix+"TIMEOUT", &tc.Client.Timeout),
+		config.FromEnv(env, prefix+"MAX_CLIENTS", &tc.Manager.MaxClients),
+		config.FromEnv(env, prefix+"CLIENTS", &tc.LoadTest.DefaultClientCount),
+		config.FromEnv(env, prefix+"DURATION", &tc.LoadTest.DefaultDuration),
+		config.FromEnv(env, prefix+"RAMP_UP", &tc.LoadTest.DefaultRampUpTime),
+		config.FromEnv(env, prefix+"REPORT_FORMAT", &tc.LoadTest.ReportFormat),
+		config.FromEnv(env, prefix+"SPEED", &tc.LoadTest.Speed),
+		config.FromEnv(env, prefix+"PROFILE", &tc.Profiles.Active),
+		tc.Manager.Diagnostics.ApplyEnv(env, prefix+"DIAGNOSTICS_"),
+	)
+}
+
 // SaveConfig saves configuration to a file
 func SaveConfig(config *ToolkitConfig, filename string) error {
 	if err := config.Validate(); err != nil {
@@ -384,7 +638,12 @@ func findConfigFile() string {
 
 // GetActiveProfile returns the active environment profile
 func (tc *ToolkitConfig) GetActiveProfile() (*EnvironmentProfile, error) {
-	switch tc.Profiles.Active {
+	return tc.Profile(tc.Profiles.Active)
+}
+
+// Profile returns an environment profile by name
+func (tc *ToolkitConfig) Profile(name string) (*EnvironmentProfile, error) {
+	switch name {
 	case "development":
 		if tc.Profiles.Development == nil {
 			return nil, fmt.Errorf("development profile not configured")
@@ -401,7 +660,7 @@ func (tc *ToolkitConfig) GetActiveProfile() (*EnvironmentProfile, error) {
 		}
 		return tc.Profiles.Production, nil
 	default:
-		return nil, fmt.Errorf("unknown active profile: %s", tc.Profiles.Active)
+		return nil, fmt.Errorf("unknown profile: %s", name)
 	}
 }
 
@@ -412,21 +671,26 @@ func (tc *ToolkitConfig) ApplyProfile() error {
 		return err
 	}
 
+	profile.applyTo(&tc.Client, false)
+	return nil
+}
+
+// applyTo sets the servers and the credentials of the profile in a client configuration, keeping
+// the credentials already set unless overridden
+func (ep *EnvironmentProfile) applyTo(cfg *ClientConfig, override bool) {
 	// Apply server settings
-	tc.Client.LoginServerHost = profile.LoginServer.Host
-	tc.Client.LoginServerPort = profile.LoginServer.Port
-	tc.Client.GameServerHost = profile.GameServer.Host
-	tc.Client.GameServerPort = profile.GameServer.Port
-	tc.Client.Timeout = profile.LoginServer.Timeout
+	cfg.LoginServerHost = ep.LoginServer.Host
+	cfg.LoginServerPort = ep.LoginServer.Port
+	cfg.GameServerHost = ep.GameServer.Host
+	cfg.GameServerPort = ep.GameServer.Port
+	cfg.Timeout = ep.LoginServer.Timeout
 
 	// Apply credentials if not already set
-	if tc.Client.Username == "" {
-		tc.Client.Username = profile.Credentials.Username
+	if cfg.Username == "" || (override && ep.Credentials.Username != "") {
+		cfg.Username = ep.Credentials.Username
 	}
-	if tc.Client.Password == "" {
-		tc.Client.Password = profile.Credentials.Password
+	if cfg.Password == "" || (override && ep.Credentials.Password != "") {
+		cfg.Password = ep.Credentials.Password
 	}
-	tc.Client.AutoCreate = profile.Credentials.AutoCreate
-
-	return nil
+	cfg.AutoCreate = ep.Credentials.AutoCreate
 }
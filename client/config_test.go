@@ -1,8 +1,11 @@
 package client
 
 import (
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/frostwind/l2go/config"
 )
 
 func TestClientConfigValidation(t *testing.T) {
@@ -133,6 +136,33 @@ func TestManagerConfigValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "error budgets",
+			config: ManagerConfig{
+				MaxClients:   100,
+				HealthCheck:  5 * time.Second,
+				ErrorBudgets: ErrorBudgetConfig{Window: time.Minute, Budgets: map[string]float64{"network": 5, "auth": 0.5}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown error category",
+			config: ManagerConfig{
+				MaxClients:   100,
+				HealthCheck:  5 * time.Second,
+				ErrorBudgets: ErrorBudgetConfig{Budgets: map[string]float64{"disk": 5}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "error budget over 100 percent",
+			config: ManagerConfig{
+				MaxClients:   100,
+				HealthCheck:  5 * time.Second,
+				ErrorBudgets: ErrorBudgetConfig{Budgets: map[string]float64{"server": 150}},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -144,3 +174,70 @@ func TestManagerConfigValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestCredentialsProfilePolicy(t *testing.T) {
+	policy := config.AccountPolicyType{UsernameCharset: "a-z0-9", MinUsernameLength: 4, MinPasswordLength: 6, PasswordClasses: 2}
+
+	tests := []struct {
+		name    string
+		profile CredentialsProfile
+		want    error
+	}{
+		{name: "follows the policy", profile: CredentialsProfile{Username: "player1", Password: "secret42", AutoCreate: true, Policy: policy}},
+		{name: "forbidden character", profile: CredentialsProfile{Username: "player_1", Password: "secret42", AutoCreate: true, Policy: policy}, want: config.ErrUsernameRefused},
+		{name: "short username", profile: CredentialsProfile{Username: "abc", Password: "secret42", AutoCreate: true, Policy: policy}, want: config.ErrUsernameRefused},
+		{name: "long username", profile: CredentialsProfile{Username: "averyverylonguser", Password: "secret42", AutoCreate: true}, want: config.ErrUsernameRefused},
+		{name: "short password", profile: CredentialsProfile{Username: "player1", Password: "s3cr", AutoCreate: true, Policy: policy}, want: config.ErrPasswordRefused},
+		{name: "single class password", profile: CredentialsProfile{Username: "player1", Password: "secretpass", AutoCreate: true, Policy: policy}, want: config.ErrPasswordRefused},
+		{name: "existing account", profile: CredentialsProfile{Username: "Player_1", Password: "secretpass", Policy: policy}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.profile.Validate(); !errors.Is(err, tt.want) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	invalid := CredentialsProfile{Username: "player1", Password: "secret42", Policy: config.AccountPolicyType{UsernameCharset: "z-a"}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("Validate() accepted a policy with an invalid charset")
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	env := map[string]string{
+		"L2GO_TOOLKIT_LOGIN_HOST": "login",
+		"L2GO_TOOLKIT_GAME_PORT":  "7778",
+		"L2GO_TOOLKIT_USERNAME":   "bot",
+		"L2GO_TOOLKIT_DURATION":   "5m",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	// Without a file, the environment overrides the default configuration
+	cfg, err := loadConfig("", lookup)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if cfg.Client.LoginServerHost != "login" || cfg.Client.GameServerPort != 7778 || cfg.Client.Username != "bot" || cfg.LoadTest.DefaultDuration != 5*time.Minute {
+		t.Errorf("loadConfig() = %+v, the environment wasn't applied", cfg.Client)
+	}
+	if cfg.Client.Password != DefaultToolkitConfig().Client.Password {
+		t.Errorf("password = %q, want the default one", cfg.Client.Password)
+	}
+
+	if _, err := loadConfig("missing.json", lookup); err == nil {
+		t.Error("loadConfig() succeeded without the file it was given")
+	}
+
+	env["L2GO_TOOLKIT_GAME_PORT"] = "seven"
+	if _, err := loadConfig("", lookup); !errors.Is(err, config.ErrInvalidEnv) {
+		t.Errorf("loadConfig() error = %v, want ErrInvalidEnv", err)
+	}
+}
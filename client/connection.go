@@ -0,0 +1,354 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/packets"
+)
+
+// NewLoginConnection creates a login server connection using the given I/O timeout
+func NewLoginConnection(timeout time.Duration) *LoginConnection {
+	return &LoginConnection{timeout: timeout}
+}
+
+// SetSourceBinder binds the next connections to the source addresses of a binder, nil for any address
+func (lc *LoginConnection) SetSourceBinder(binder *SourceBinder) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.source = binder
+}
+
+// Connect establishes the connection
+func (lc *LoginConnection) Connect(host string, port int) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.isConnected {
+		return ErrAlreadyConnected
+	}
+
+	conn, err := dial(host, port, lc.timeout, lc.source)
+	if err != nil {
+		return err
+	}
+
+	lc.conn = conn
+	lc.isConnected = true
+	return nil
+}
+
+// Send sends data over the connection
+func (lc *LoginConnection) Send(data []byte) error {
+	conn, err := lc.activeConn()
+	if err != nil {
+		return err
+	}
+	return writeFrame(conn, data, lc.timeout)
+}
+
+// Receive receives data from the connection
+func (lc *LoginConnection) Receive() ([]byte, error) {
+	conn, err := lc.activeConn()
+	if err != nil {
+		return nil, err
+	}
+	return readFrame(conn, deadline(lc.timeout), int(lc.maxPacketSize.Load()), &lc.violations)
+}
+
+// ReceiveUntil receives data from the connection, failing with ErrOperationTimeout once deadline passed
+func (lc *LoginConnection) ReceiveUntil(deadline time.Time) ([]byte, error) {
+	conn, err := lc.activeConn()
+	if err != nil {
+		return nil, err
+	}
+	return readFrame(conn, deadline, int(lc.maxPacketSize.Load()), &lc.violations)
+}
+
+// SetMaxPacketSize limits the size of the received packets, header included
+func (lc *LoginConnection) SetMaxPacketSize(size int) {
+	lc.maxPacketSize.Store(int32(size))
+}
+
+// Violations returns how many oversized packets the server tried to send
+func (lc *LoginConnection) Violations() uint32 {
+	return lc.violations.Load()
+}
+
+// Close closes the connection
+func (lc *LoginConnection) Close() error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if !lc.isConnected {
+		return nil
+	}
+
+	lc.isConnected = false
+	return lc.conn.Close()
+}
+
+// IsConnected returns whether the connection is active
+func (lc *LoginConnection) IsConnected() bool {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.isConnected
+}
+
+// GetConnection returns the underlying net.Conn
+func (lc *LoginConnection) GetConnection() net.Conn {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return lc.conn
+}
+
+func (lc *LoginConnection) activeConn() (net.Conn, error) {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+
+	if !lc.isConnected {
+		return nil, ErrNotConnected
+	}
+	return lc.conn, nil
+}
+
+// NewGameConnection creates a game server connection using the given I/O timeout
+func NewGameConnection(timeout time.Duration) *GameConnection {
+	return &GameConnection{timeout: timeout}
+}
+
+// SetSourceBinder binds the next connections to the source addresses of a binder, nil for any address
+func (gc *GameConnection) SetSourceBinder(binder *SourceBinder) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.source = binder
+}
+
+// Connect establishes the connection
+func (gc *GameConnection) Connect(host string, port int) error {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if gc.isConnected {
+		return ErrAlreadyConnected
+	}
+
+	conn, err := dial(host, port, gc.timeout, gc.source)
+	if err != nil {
+		return err
+	}
+
+	gc.conn = conn
+	gc.isConnected = true
+	return nil
+}
+
+// Send sends data over the connection
+func (gc *GameConnection) Send(data []byte) error {
+	conn, err := gc.activeConn()
+	if err != nil {
+		return err
+	}
+	return writeFrame(conn, data, gc.timeout)
+}
+
+// Receive receives data from the connection
+func (gc *GameConnection) Receive() ([]byte, error) {
+	conn, err := gc.activeConn()
+	if err != nil {
+		return nil, err
+	}
+	return readFrame(conn, deadline(gc.timeout), int(gc.maxPacketSize.Load()), &gc.violations)
+}
+
+// ReceiveContext receives data from the connection, waiting for as long as it takes until ctx is done.
+// A packet cut short by ctx leaves the connection out of step with the server, to be closed.
+func (gc *GameConnection) ReceiveContext(ctx context.Context) ([]byte, error) {
+	conn, err := gc.activeConn()
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Expiring the read deadline right away unblocks the read once ctx is done
+	conn.SetReadDeadline(time.Time{})
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	data, err := decodeFrame(conn, int(gc.maxPacketSize.Load()), &gc.violations)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return data, err
+}
+
+// Watch has a reactor read the packets of the connection as they arrive, calling handle from one of its
+// workers with every packet, until handle returns false, a packet can't be read or the connection is
+// closed. Nothing else may read from the connection meanwhile.
+func (gc *GameConnection) Watch(reactor *Reactor, handle func(data []byte, err error) bool) error {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if !gc.isConnected {
+		return ErrNotConnected
+	}
+	if gc.unwatch != nil {
+		return fmt.Errorf("%w: the connection is already watched", ErrInvalidState)
+	}
+
+	conn := gc.conn
+	unwatch, err := reactor.Watch(conn, func() bool {
+		// The packet is on its way, its end arriving within the timeout
+		data, err := readFrame(conn, deadline(gc.timeout), int(gc.maxPacketSize.Load()), &gc.violations)
+		return handle(data, err) && err == nil
+	})
+	if err != nil {
+		return err
+	}
+	gc.unwatch = unwatch
+	return nil
+}
+
+// SetMaxPacketSize limits the size of the received packets, header included
+func (gc *GameConnection) SetMaxPacketSize(size int) {
+	gc.maxPacketSize.Store(int32(size))
+}
+
+// Violations returns how many oversized packets the server tried to send
+func (gc *GameConnection) Violations() uint32 {
+	return gc.violations.Load()
+}
+
+// Close closes the connection
+func (gc *GameConnection) Close() error {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if !gc.isConnected {
+		return nil
+	}
+
+	gc.isConnected = false
+	if gc.unwatch != nil {
+		gc.unwatch()
+		gc.unwatch = nil
+	}
+	return gc.conn.Close()
+}
+
+// IsConnected returns whether the connection is active
+func (gc *GameConnection) IsConnected() bool {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+	return gc.isConnected
+}
+
+// GetConnection returns the underlying net.Conn
+func (gc *GameConnection) GetConnection() net.Conn {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+	return gc.conn
+}
+
+func (gc *GameConnection) activeConn() (net.Conn, error) {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+
+	if !gc.isConnected {
+		return nil, ErrNotConnected
+	}
+	return gc.conn, nil
+}
+
+// dial opens a TCP connection to host:port, from a source address of the binder if there is one
+func dial(host string, port int, timeout time.Duration, binder *SourceBinder) (net.Conn, error) {
+	var conn net.Conn
+	var err error
+	if binder != nil {
+		conn, err = binder.dial(host, port, timeout)
+	} else {
+		conn, err = net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	}
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("%w: %v", ErrConnectionTimeout, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	return conn, nil
+}
+
+// writeFrame writes data prefixed with the 2 bytes packet length
+func writeFrame(conn net.Conn, data []byte, timeout time.Duration) error {
+	writer := packets.NewPacketWriter()
+	defer writer.Release()
+
+	frame, err := writer.B(data).Finalize()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPacketTooLarge, err)
+	}
+
+	if timeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+
+	if _, err := conn.Write(frame); err != nil {
+		return mapNetError(err)
+	}
+	return nil
+}
+
+// deadline returns the deadline of an operation starting now, none when timeout isn't positive
+func deadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// readFrame reads a single length prefixed packet and strips its header,
+// counting the packets larger than maxSize as violations
+func readFrame(conn net.Conn, deadline time.Time, maxSize int, violations *atomic.Uint32) ([]byte, error) {
+	conn.SetReadDeadline(deadline)
+	return decodeFrame(conn, maxSize, violations)
+}
+
+// decodeFrame reads a single packet as readFrame, within the read deadline already set
+func decodeFrame(conn net.Conn, maxSize int, violations *atomic.Uint32) ([]byte, error) {
+	data, err := packets.ReadFrame(conn, maxSize)
+	switch {
+	case errors.Is(err, packets.ErrFrameTooLarge):
+		violations.Add(1)
+		return nil, fmt.Errorf("%w: %v", ErrPacketTooLarge, err)
+	case errors.Is(err, packets.ErrFrameTooSmall):
+		return nil, fmt.Errorf("%w: %v", ErrPacketTooSmall, err)
+	case err != nil:
+		return nil, mapNetError(err)
+	}
+
+	return data, nil
+}
+
+// mapNetError converts network errors into the toolkit errors
+func mapNetError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("%w: %v", ErrOperationTimeout, err)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed):
+		return fmt.Errorf("%w: %v", ErrConnectionClosed, err)
+	default:
+		return fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Categories the failures of the clients are bucketed in, and budgeted by
+const (
+	ErrorCategoryNetwork  = "network"  // The connection failed, timed out or was closed
+	ErrorCategoryAuth     = "auth"     // The login server refused the account or its session
+	ErrorCategoryProtocol = "protocol" // A packet couldn't be framed, ciphered or parsed
+	ErrorCategoryServer   = "server"   // The servers are full, under maintenance or failing
+	ErrorCategoryOther    = "other"    // Anything else, such as a client used in the wrong state
+)
+
+// errorCategories lists the errors of each category, the server ones first since the login server
+// wraps its refusals into ErrAuthenticationFailed whatever their reason
+var errorCategories = []struct {
+	category string
+	errs     []error
+}{
+	{ErrorCategoryServer, []error{ErrServerFull, ErrServerMaintenance, ErrResourceExhausted, ErrInternalError, ErrCharacterCreationFailed}},
+	{ErrorCategoryAuth, []error{ErrAuthenticationFailed, ErrInvalidCredentials, ErrAccountNotFound, ErrAccountBanned, ErrAccountInUse,
+		ErrAccountExpired, ErrSessionExpired, ErrInvalidSession, ErrSessionNotFound, ErrMultipleSessions}},
+	{ErrorCategoryProtocol, []error{ErrInvalidPacket, ErrPacketTooLarge, ErrPacketTooSmall, ErrUnsupportedOpcode, ErrEncryptionFailed,
+		ErrDecryptionFailed, ErrChecksumMismatch, ErrNullCryptoMismatch}},
+	{ErrorCategoryNetwork, []error{ErrConnectionFailed, ErrConnectionTimeout, ErrConnectionClosed, ErrNotConnected, ErrOperationTimeout,
+		ErrStateTimeout}},
+}
+
+// ErrorCategories returns the categories of errors, in the order the failures are reported
+func ErrorCategories() []string {
+	return []string{ErrorCategoryNetwork, ErrorCategoryAuth, ErrorCategoryProtocol, ErrorCategoryServer, ErrorCategoryOther}
+}
+
+// ClassifyError returns the category of an error of the toolkit, from the errors it wraps
+func ClassifyError(err error) string {
+	for _, c := range errorCategories {
+		for _, target := range c.errs {
+			if errors.Is(err, target) {
+				return c.category
+			}
+		}
+	}
+	return ErrorCategoryOther
+}
+
+// ErrorBudgetConfig bounds the share of the actions of the clients failing in each category. A category
+// over its budget makes the clients unhealthy, until enough of its failures leave the window.
+type ErrorBudgetConfig struct {
+	Window     time.Duration      `json:"window,omitempty"`     // Only the actions of the last window count, all of them when 0
+	MinActions int                `json:"minActions,omitempty"` // Fewer actions in the window are never over budget
+	Budgets    map[string]float64 `json:"budgets,omitempty"`    // Highest percentage of the actions failing, by category
+}
+
+// Validate validates the error budgets
+func (bc ErrorBudgetConfig) Validate() error {
+	if bc.Window < 0 {
+		return fmt.Errorf("window must be non-negative, got %v", bc.Window)
+	}
+	if bc.MinActions < 0 {
+		return fmt.Errorf("minActions must be non-negative, got %d", bc.MinActions)
+	}
+	for category, budget := range bc.Budgets {
+		if !slices.Contains(ErrorCategories(), category) {
+			return fmt.Errorf("invalid category: %s, must be one of: network, auth, protocol, server, other", category)
+		}
+		if budget < 0 || budget > 100 {
+			return fmt.Errorf("%s budget must be between 0 and 100, got %v", category, budget)
+		}
+	}
+	return nil
+}
+
+// ErrorBudgetStatus is how the actions of the window fared against the budgets
+type ErrorBudgetStatus struct {
+	Healthy   bool               `json:"healthy"`
+	Actions   int                `json:"actions"`             // Counted in the window
+	Failures  map[string]int     `json:"failures,omitempty"`  // By category
+	Rates     map[string]float64 `json:"rates,omitempty"`     // Percentage of the actions failing, by category
+	Exhausted []string           `json:"exhausted,omitempty"` // Categories over their budget, sorted
+}
+
+// clone returns a copy of the status sharing none of its maps and slices
+func (s ErrorBudgetStatus) clone() ErrorBudgetStatus {
+	s.Failures = maps.Clone(s.Failures)
+	s.Rates = maps.Clone(s.Rates)
+	s.Exhausted = slices.Clone(s.Exhausted)
+	return s
+}
+
+// outcome is an action recorded by the tracker, category being empty for the successful ones
+type outcome struct {
+	at       time.Time
+	category string
+}
+
+// ErrorTracker classifies the failures of the actions of clients and checks their rates against the budgets
+type ErrorTracker struct {
+	config   ErrorBudgetConfig
+	actions  int            // In the window
+	failures map[string]int // In the window, by category
+	outcomes []outcome      // Of the window, oldest first, kept only to expire them when there's a window
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+// NewErrorTracker creates a tracker of the given budgets
+func NewErrorTracker(config ErrorBudgetConfig) *ErrorTracker {
+	return &ErrorTracker{config: config, failures: make(map[string]int), now: time.Now}
+}
+
+// Record records an action, successful when err is nil, and returns the category it failed in
+func (t *ErrorTracker) Record(err error) string {
+	var category string
+	if err != nil {
+		category = ClassifyError(err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.actions++
+	if category != "" {
+		t.failures[category]++
+	}
+	if t.config.Window > 0 {
+		t.outcomes = append(t.outcomes, outcome{at: t.now(), category: category})
+		t.expire()
+	}
+	return category
+}
+
+// Status returns how the actions of the window fare against the budgets
+func (t *ErrorTracker) Status() ErrorBudgetStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expire()
+
+	status := ErrorBudgetStatus{Healthy: true, Actions: t.actions}
+	if status.Actions == 0 {
+		return status
+	}
+
+	for category, failures := range t.failures {
+		if failures == 0 {
+			continue
+		}
+		if status.Failures == nil {
+			status.Failures = make(map[string]int)
+			status.Rates = make(map[string]float64)
+		}
+		status.Failures[category] = failures
+		status.Rates[category] = float64(failures) * 100 / float64(status.Actions)
+	}
+	if status.Actions < t.config.MinActions {
+		return status
+	}
+	for category, budget := range t.config.Budgets {
+		if status.Rates[category] > budget {
+			status.Exhausted = append(status.Exhausted, category)
+		}
+	}
+	sort.Strings(status.Exhausted)
+	status.Healthy = len(status.Exhausted) == 0
+	return status
+}
+
+// expire forgets the actions which left the window
+func (t *ErrorTracker) expire() {
+	if t.config.Window <= 0 {
+		return
+	}
+	since := t.now().Add(-t.config.Window)
+	expired := 0
+	for ; expired < len(t.outcomes) && t.outcomes[expired].at.Before(since); expired++ {
+		t.actions--
+		if category := t.outcomes[expired].category; category != "" {
+			t.failures[category]--
+		}
+	}
+	t.outcomes = slices.Delete(t.outcomes, 0, expired)
+}
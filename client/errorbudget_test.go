@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/packets"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "connection refused", err: fmt.Errorf("%w: dial tcp: connection refused", ErrConnectionFailed), want: ErrorCategoryNetwork},
+		{name: "connection closed", err: mapNetError(io.EOF), want: ErrorCategoryNetwork},
+		{name: "handshake timeout", err: fmt.Errorf("%w: no LoginOk from the login server within 1s", ErrOperationTimeout), want: ErrorCategoryNetwork},
+		{name: "wrong password", err: fmt.Errorf("login refused: %w", LoginFailPassWrong.Err()), want: ErrorCategoryAuth},
+		{name: "unknown refusal", err: LoginFailReason(0x42).Err(), want: ErrorCategoryAuth},
+		{name: "server full", err: fmt.Errorf("server 1 refused: %w", LoginFailTooManyPlayers.Err()), want: ErrorCategoryServer},
+		{name: "system error", err: LoginFailSystemError.Err(), want: ErrorCategoryServer},
+		{name: "character creation", err: characterCreateError(0x42), want: ErrorCategoryServer},
+		{name: "checksum", err: fmt.Errorf("%w: bad checksum", ErrChecksumMismatch), want: ErrorCategoryProtocol},
+		{name: "truncated packet", err: fmt.Errorf("%w: %v", ErrInvalidPacket, packets.ErrInsufficientData), want: ErrorCategoryProtocol},
+		{name: "wrong state", err: fmt.Errorf("%w: cannot login while InGame", ErrInvalidState), want: ErrorCategoryOther},
+		{name: "foreign error", err: errors.New("disk full"), want: ErrorCategoryOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorTracker(t *testing.T) {
+	now := time.Now()
+	tracker := NewErrorTracker(ErrorBudgetConfig{
+		Window:     time.Minute,
+		MinActions: 4,
+		Budgets:    map[string]float64{ErrorCategoryNetwork: 20, ErrorCategoryAuth: 0},
+	})
+	tracker.now = func() time.Time { return now }
+
+	// Too few actions to be judged
+	tracker.Record(ErrConnectionClosed)
+	tracker.Record(nil)
+	if status := tracker.Status(); !status.Healthy || status.Rates[ErrorCategoryNetwork] != 50 {
+		t.Fatalf("Status() = %+v, want healthy under the minimum of actions", status)
+	}
+
+	tracker.Record(nil)
+	tracker.Record(nil)
+	tracker.Record(nil)
+	if status := tracker.Status(); !status.Healthy || status.Actions != 5 || status.Failures[ErrorCategoryNetwork] != 1 {
+		t.Fatalf("Status() = %+v, want healthy at 20%% of network errors", status)
+	}
+
+	now = now.Add(30 * time.Second)
+	if category := tracker.Record(fmt.Errorf("login refused: %w", LoginFailAccountInUse.Err())); category != ErrorCategoryAuth {
+		t.Errorf("Record() = %s, want %s", category, ErrorCategoryAuth)
+	}
+	tracker.Record(ErrConnectionTimeout)
+	status := tracker.Status()
+	if want := []string{ErrorCategoryAuth, ErrorCategoryNetwork}; status.Healthy || !slices.Equal(status.Exhausted, want) {
+		t.Fatalf("Status() = %+v, want %v exhausted", status, want)
+	}
+
+	// The first actions leave the window, the failures of the second batch are all that's left
+	now = now.Add(45 * time.Second)
+	for range 20 {
+		tracker.Record(nil)
+	}
+	status = tracker.Status()
+	if status.Actions != 22 || status.Failures[ErrorCategoryNetwork] != 1 || !slices.Equal(status.Exhausted, []string{ErrorCategoryAuth}) {
+		t.Fatalf("Status() = %+v, want 22 actions with the auth budget exhausted", status)
+	}
+
+	now = now.Add(time.Minute + time.Second)
+	if status := tracker.Status(); !status.Healthy || status.Actions != 0 || status.Failures != nil {
+		t.Errorf("Status() = %+v, want an empty healthy window", status)
+	}
+}
@@ -11,6 +11,9 @@ var (
 	ErrInvalidUsername        = errors.New("invalid username: must not be empty")
 	ErrInvalidPassword        = errors.New("invalid password: must not be empty")
 	ErrInvalidTimeout         = errors.New("invalid timeout: must be greater than 0")
+	ErrInvalidVariation       = errors.New("invalid variation profile")
+	ErrNullCryptoRefused      = errors.New("null crypto refused: the servers must be on private addresses")
+	ErrInvalidSourceAddress   = errors.New("invalid source address")
 )
 
 // Connection errors
@@ -29,33 +32,55 @@ var (
 	ErrAccountNotFound      = errors.New("account not found")
 	ErrAccountBanned        = errors.New("account is banned")
 	ErrServerFull           = errors.New("server is full")
+	ErrAccountInUse         = errors.New("account is already in use")
+	ErrAccountExpired       = errors.New("account has expired")
+	ErrServerMaintenance    = errors.New("server is under maintenance")
 )
 
 // Protocol errors
 var (
-	ErrInvalidPacket     = errors.New("invalid packet format")
-	ErrPacketTooLarge    = errors.New("packet too large")
-	ErrPacketTooSmall    = errors.New("packet too small")
-	ErrUnsupportedOpcode = errors.New("unsupported opcode")
-	ErrEncryptionFailed  = errors.New("encryption failed")
-	ErrDecryptionFailed  = errors.New("decryption failed")
-	ErrChecksumMismatch  = errors.New("checksum mismatch")
+	ErrInvalidPacket      = errors.New("invalid packet format")
+	ErrPacketTooLarge     = errors.New("packet too large")
+	ErrPacketTooSmall     = errors.New("packet too small")
+	ErrUnsupportedOpcode  = errors.New("unsupported opcode")
+	ErrEncryptionFailed   = errors.New("encryption failed")
+	ErrDecryptionFailed   = errors.New("decryption failed")
+	ErrChecksumMismatch   = errors.New("checksum mismatch")
+	ErrNullCryptoMismatch = errors.New("null crypto mismatch")
 )
 
 // Client management errors
 var (
-	ErrClientNotFound      = errors.New("client not found")
-	ErrClientAlreadyExists = errors.New("client already exists")
-	ErrMaxClientsReached   = errors.New("maximum number of clients reached")
-	ErrClientManagerClosed = errors.New("client manager is closed")
+	ErrClientNotFound       = errors.New("client not found")
+	ErrClientAlreadyExists  = errors.New("client already exists")
+	ErrMaxClientsReached    = errors.New("maximum number of clients reached")
+	ErrClientManagerClosed  = errors.New("client manager is closed")
+	ErrUnknownClientGroup   = errors.New("unknown client group")
+	ErrClientGroupExists    = errors.New("client group already exists")
+	ErrAccountPoolExhausted = errors.New("account pool exhausted")
+	ErrReactorUnsupported   = errors.New("reactor unsupported")
+	ErrReactorClosed        = errors.New("reactor closed")
 )
 
 // Character management errors
 var (
-	ErrCharacterNotFound    = errors.New("character not found")
-	ErrCharacterNameTaken   = errors.New("character name is already taken")
-	ErrInvalidCharacterName = errors.New("invalid character name")
-	ErrMaxCharactersReached = errors.New("maximum number of characters reached")
+	ErrCharacterNotFound       = errors.New("character not found")
+	ErrCharacterCreationFailed = errors.New("character creation failed")
+	ErrCharacterNameTaken      = errors.New("character name is already taken")
+	ErrInvalidCharacterName    = errors.New("invalid character name")
+	ErrInvalidTemplate         = errors.New("invalid character template")
+	ErrMaxCharactersReached    = errors.New("maximum number of characters reached")
+	ErrInvalidShortcut         = errors.New("invalid shortcut")
+	ErrInvalidEmote            = errors.New("invalid emote")
+	ErrNoDialog                = errors.New("no dialog is open")
+	ErrDialogOptionNotFound    = errors.New("dialog option not found")
+	ErrTeleportRefused         = errors.New("teleport refused")
+	ErrRestartRefused          = errors.New("restart refused")
+	ErrPlayerOffline           = errors.New("player is not online")
+	ErrQuestRefused            = errors.New("quest refused")
+	ErrTradeRefused            = errors.New("trade refused")
+	ErrWarehouseRefused        = errors.New("warehouse refused")
+	ErrAdminCommandRefused     = errors.New("admin command refused")
 )
 
 // Session errors
@@ -70,6 +95,8 @@ var (
 var (
 	ErrInvalidState      = errors.New("invalid client state")
 	ErrOperationTimeout  = errors.New("operation timeout")
+	ErrStateTimeout      = errors.New("state timeout")
+	ErrSpectator         = errors.New("spectators don't act")
 	ErrResourceExhausted = errors.New("resource exhausted")
 	ErrInternalError     = errors.New("internal error")
 )
@@ -0,0 +1,227 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/eventbus"
+)
+
+// Topics of the events published by the clients and the manager
+const (
+	TopicClientConnected       = "client.connected"
+	TopicClientDisconnected    = "client.disconnected"
+	TopicClientError           = "client.error"
+	TopicClientDrained         = "client.drained"
+	TopicClientState           = "client.state"
+	TopicHealthError           = "client.health.error"
+	TopicHealthStuck           = "client.health.stuck"
+	TopicHealthBudget          = "client.health.budget"
+	TopicScenarioStepCompleted = "client.scenario.step"
+	TopicScenarioDone          = "client.scenario.done"
+)
+
+var ErrUnknownTopic = errors.New("unknown event topic")
+
+// Event is a typed payload of the event bus, published on its topic
+type Event = eventbus.Event
+
+// ClientConnected is published once a client reached the world
+type ClientConnected struct {
+	ClientID string    `json:"clientId"`
+	At       time.Time `json:"at"`
+}
+
+// ClientDisconnected is published once a client was stopped
+type ClientDisconnected struct {
+	ClientID string    `json:"clientId"`
+	At       time.Time `json:"at"`
+}
+
+// ClientError is published when an action of a client failed
+type ClientError struct {
+	ClientID string    `json:"clientId"`
+	Action   string    `json:"action"` // What the client was doing: connect, scenario...
+	Error    string    `json:"error"`
+	Err      error     `json:"-"` // The error itself, for errors.Is, lost in serialization
+	At       time.Time `json:"at"`
+}
+
+// ClientDrained is published once a drained client stopped
+type ClientDrained struct {
+	ClientID string    `json:"clientId"`
+	Graceful bool      `json:"graceful"` // Logged out before the deadline rather than closed
+	At       time.Time `json:"at"`
+}
+
+// HealthDegraded is published by the health check for every client in error or stuck in its state
+type HealthDegraded struct {
+	ClientID string        `json:"clientId"`
+	State    ClientState   `json:"state"`
+	Stuck    bool          `json:"stuck"` // In its state for longer than allowed, rather than in error
+	Dwell    time.Duration `json:"dwell"` // Time spent in the state
+	At       time.Time     `json:"at"`
+}
+
+// ErrorBudgetChanged is published when a category of errors goes over its budget or back under it
+type ErrorBudgetChanged struct {
+	Healthy   bool               `json:"healthy"`
+	Exhausted []string           `json:"exhausted"` // Categories over their budget
+	Rates     map[string]float64 `json:"rates"`     // Percentage of the actions failing, by category
+	At        time.Time          `json:"at"`
+}
+
+// ScenarioStepCompleted is published after every step a client ran in a scenario
+type ScenarioStepCompleted struct {
+	ClientID string        `json:"clientId"`
+	Scenario string        `json:"scenario"`
+	Step     int           `json:"step"` // Starting at 1
+	Verb     string        `json:"verb"`
+	Took     time.Duration `json:"took"`
+	At       time.Time     `json:"at"`
+}
+
+// ScenarioDone is published once a client ran every step of a scenario
+type ScenarioDone struct {
+	ClientID string    `json:"clientId"`
+	Scenario string    `json:"scenario"`
+	At       time.Time `json:"at"`
+}
+
+func (ClientConnected) Topic() string       { return TopicClientConnected }
+func (ClientDisconnected) Topic() string    { return TopicClientDisconnected }
+func (ClientError) Topic() string           { return TopicClientError }
+func (ClientDrained) Topic() string         { return TopicClientDrained }
+func (Transition) Topic() string            { return TopicClientState }
+func (ErrorBudgetChanged) Topic() string    { return TopicHealthBudget }
+func (ScenarioStepCompleted) Topic() string { return TopicScenarioStepCompleted }
+func (ScenarioDone) Topic() string          { return TopicScenarioDone }
+
+// Topic is the topic of a client in error, unless it is stuck
+func (e HealthDegraded) Topic() string {
+	if e.Stuck {
+		return TopicHealthStuck
+	}
+	return TopicHealthError
+}
+
+// NewClientError creates the event of a failed action
+func NewClientError(clientID, action string, err error) ClientError {
+	return ClientError{ClientID: clientID, Action: action, Error: err.Error(), Err: err, At: time.Now()}
+}
+
+// EventField is a field of the JSON serialization of an event
+type EventField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// EventSchema describes the payload published on a topic
+type EventSchema struct {
+	Topic       string       `json:"topic"`
+	Type        string       `json:"type"`
+	Description string       `json:"description"`
+	Fields      []EventField `json:"fields"`
+
+	goType reflect.Type
+}
+
+var (
+	eventSchemas   = make(map[string]EventSchema)
+	eventSchemasMu sync.RWMutex
+)
+
+func init() {
+	RegisterEvent(TopicClientConnected, ClientConnected{}, "A client reached the world")
+	RegisterEvent(TopicClientDisconnected, ClientDisconnected{}, "A client was stopped")
+	RegisterEvent(TopicClientError, ClientError{}, "An action of a client failed")
+	RegisterEvent(TopicClientDrained, ClientDrained{}, "A drained client stopped")
+	RegisterEvent(TopicClientState, Transition{}, "A client moved to another state")
+	RegisterEvent(TopicHealthError, HealthDegraded{}, "The health check found a client in error")
+	RegisterEvent(TopicHealthStuck, HealthDegraded{}, "The health check found a client stuck in its state")
+	RegisterEvent(TopicHealthBudget, ErrorBudgetChanged{}, "A category of errors went over its budget or back under it")
+	RegisterEvent(TopicScenarioStepCompleted, ScenarioStepCompleted{}, "A client ran a step of a scenario")
+	RegisterEvent(TopicScenarioDone, ScenarioDone{}, "A client ran every step of a scenario")
+}
+
+// RegisterEvent registers the payload type of a topic, replacing the previous one.
+// The sample is a zero value of the type, its fields are read from its JSON tags.
+func RegisterEvent(topic string, sample interface{}, description string) {
+	goType := reflect.TypeOf(sample)
+	schema := EventSchema{Topic: topic, Type: goType.Name(), Description: description, goType: goType}
+
+	if goType.Kind() == reflect.Struct {
+		for i := 0; i < goType.NumField(); i++ {
+			field := goType.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			schema.Fields = append(schema.Fields, EventField{Name: name, Type: jsonType(field.Type)})
+		}
+	}
+
+	eventSchemasMu.Lock()
+	defer eventSchemasMu.Unlock()
+	eventSchemas[topic] = schema
+}
+
+// EventCatalog returns the schemas of the registered topics, sorted by topic
+func EventCatalog() []EventSchema {
+	eventSchemasMu.RLock()
+	defer eventSchemasMu.RUnlock()
+
+	catalog := make([]EventSchema, 0, len(eventSchemas))
+	for _, schema := range eventSchemas {
+		catalog = append(catalog, schema)
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Topic < catalog[j].Topic })
+	return catalog
+}
+
+// DecodeEvent parses the JSON serialization of an event into the type registered for its topic
+func DecodeEvent(topic string, data []byte) (interface{}, error) {
+	eventSchemasMu.RLock()
+	schema, ok := eventSchemas[topic]
+	eventSchemasMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTopic, topic)
+	}
+
+	event := reflect.New(schema.goType)
+	if err := json.Unmarshal(data, event.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event: %w", topic, err)
+	}
+	return event.Elem().Interface(), nil
+}
+
+// jsonType names the JSON type a Go type serializes to
+func jsonType(goType reflect.Type) string {
+	if goType == reflect.TypeOf(time.Time{}) {
+		return "string"
+	}
+
+	switch goType.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
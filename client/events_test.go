@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventTopics(t *testing.T) {
+	tests := []struct {
+		event Event
+		want  string
+	}{
+		{ClientConnected{}, "client.connected"},
+		{ClientDisconnected{}, "client.disconnected"},
+		{ClientError{}, "client.error"},
+		{ClientDrained{}, "client.drained"},
+		{Transition{}, "client.state"},
+		{HealthDegraded{}, "client.health.error"},
+		{HealthDegraded{Stuck: true}, "client.health.stuck"},
+		{ErrorBudgetChanged{}, "client.health.budget"},
+		{ScenarioStepCompleted{}, "client.scenario.step"},
+		{ScenarioDone{}, "client.scenario.done"},
+	}
+
+	registered := make(map[string]bool)
+	for _, schema := range EventCatalog() {
+		registered[schema.Topic] = true
+	}
+
+	for _, tt := range tests {
+		if topic := tt.event.Topic(); topic != tt.want {
+			t.Errorf("%T.Topic() = %s, want %s", tt.event, topic, tt.want)
+		}
+		if !registered[tt.want] {
+			t.Errorf("%s isn't in the catalog", tt.want)
+		}
+	}
+}
+
+func TestEventSchema(t *testing.T) {
+	var schema EventSchema
+	for _, registered := range EventCatalog() {
+		if registered.Topic == TopicClientError {
+			schema = registered
+		}
+	}
+
+	want := []EventField{
+		{Name: "clientId", Type: "string"},
+		{Name: "action", Type: "string"},
+		{Name: "error", Type: "string"},
+		{Name: "at", Type: "string"},
+	}
+	if schema.Type != "ClientError" || !reflect.DeepEqual(schema.Fields, want) {
+		t.Errorf("schema = %+v, want the fields %+v", schema, want)
+	}
+}
+
+func TestDecodeEvent(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	events := []Event{
+		ClientConnected{ClientID: "client-1", At: at},
+		ScenarioStepCompleted{ClientID: "client-1", Scenario: "idle", Step: 2, Verb: "sit", Took: time.Second, At: at},
+		HealthDegraded{ClientID: "client-1", State: StateAuthenticating, Stuck: true, Dwell: time.Minute, At: at},
+	}
+
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeEvent(event.Topic(), data)
+		if err != nil {
+			t.Fatalf("DecodeEvent(%s) error = %v", event.Topic(), err)
+		}
+		if !reflect.DeepEqual(decoded, event) {
+			t.Errorf("DecodeEvent(%s) = %+v, want %+v", event.Topic(), decoded, event)
+		}
+	}
+
+	// The error itself isn't serialized, only its message
+	failed := NewClientError("client-1", "connect", ErrConnectionFailed)
+	data, _ := json.Marshal(failed)
+	decoded, err := DecodeEvent(TopicClientError, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clientError := decoded.(ClientError); clientError.Error != ErrConnectionFailed.Error() || clientError.Err != nil || !errors.Is(failed.Err, ErrConnectionFailed) {
+		t.Errorf("DecodeEvent() = %+v", decoded)
+	}
+
+	if _, err := DecodeEvent("client.unknown", data); !errors.Is(err, ErrUnknownTopic) {
+		t.Errorf("DecodeEvent() error = %v, want ErrUnknownTopic", err)
+	}
+}
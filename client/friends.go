@@ -0,0 +1,182 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// InviteFriend asks a player to join the friend list of the character. The player answers
+// later, the character getting its new friend list once the invite is accepted
+func (c *Client) InviteFriend(name string) error {
+	if err := c.requireInGame("invite a friend"); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestFriendInvite, newFriendNamePayload(name)); err != nil {
+		return c.fail(err)
+	}
+
+	c.touch()
+	return nil
+}
+
+// WaitFriendInvite waits until a player asks the character to join its friend list, returning its name.
+// The invite is kept in the session until it is answered
+func (c *Client) WaitFriendInvite() (string, error) {
+	if err := c.requireInGame("wait for a friend invite"); err != nil {
+		return "", err
+	}
+
+	_, data, err := c.receiveGame(opcodes.GameServerAskJoinFriend)
+	if err != nil {
+		return "", c.fail(err)
+	}
+
+	name, err := parseAskJoinFriendPayload(data)
+	if err != nil {
+		return "", c.fail(err)
+	}
+
+	c.sessions.GameSession().FriendInvite = name
+	return name, nil
+}
+
+// AnswerFriendInvite accepts or declines the friend invite the character was asked.
+// An accepted invite returns once the friend list holds the player who asked
+func (c *Client) AnswerFriendInvite(accept bool) error {
+	if err := c.requireInGame("answer a friend invite"); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestAnswerFriend, newAnswerFriendInvitePayload(accept)); err != nil {
+		return c.fail(err)
+	}
+
+	session := c.sessions.GameSession()
+	inviter := session.FriendInvite
+	session.FriendInvite = ""
+
+	if accept {
+		_, err := c.receiveFriendList(func(friends []Friend) bool {
+			return inviter == "" || hasFriend(friends, inviter)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.touch()
+	return nil
+}
+
+// FriendList asks the game server for the friend list of the character
+func (c *Client) FriendList() ([]Friend, error) {
+	if err := c.requireInGame("list the friends"); err != nil {
+		return nil, err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestFriendList, nil); err != nil {
+		return nil, c.fail(err)
+	}
+
+	friends, err := c.receiveFriendList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.touch()
+	return friends, nil
+}
+
+// RemoveFriend removes a player from the friend list of the character, returning once the list no longer holds it
+func (c *Client) RemoveFriend(name string) error {
+	if err := c.requireInGame("remove a friend"); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestFriendDel, newFriendNamePayload(name)); err != nil {
+		return c.fail(err)
+	}
+
+	_, err := c.receiveFriendList(func(friends []Friend) bool {
+		return !hasFriend(friends, name)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.touch()
+	return nil
+}
+
+// Whisper sends a private message to a player, wherever it is. It returns once the game server
+// delivered it, or ErrPlayerOffline if the player isn't online
+func (c *Client) Whisper(name, text string) error {
+	if err := c.requireInGame("whisper"); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientSay2, newSay2Payload(text, ChatTell, name)); err != nil {
+		return c.fail(err)
+	}
+
+	for {
+		opcode, data, err := c.receiveGame(opcodes.GameServerCreatureSay, opcodes.GameServerSystemMessage)
+		if err != nil {
+			return c.fail(err)
+		}
+
+		if opcode == opcodes.GameServerSystemMessage {
+			messageID, _, err := parseSystemMessagePayload(data)
+			if err != nil {
+				return c.fail(err)
+			}
+			if messageID == SystemMessageNotLoggedIn {
+				return fmt.Errorf("%w: %s", ErrPlayerOffline, name)
+			}
+			continue
+		}
+
+		// The game server echoes the whisper under the name of the player it was sent to
+		_, chatType, speaker, _, err := parseCreatureSayPayload(data)
+		if err != nil {
+			return c.fail(err)
+		}
+		if chatType == ChatTell && strings.HasPrefix(speaker, "->") {
+			c.touch()
+			return nil
+		}
+	}
+}
+
+// receiveFriendList waits for a friend list of the character matching a condition, if any, keeping
+// the lists in the session. The game server also sends the list when the friends come and go
+func (c *Client) receiveFriendList(until func([]Friend) bool) ([]Friend, error) {
+	for {
+		_, data, err := c.receiveGame(opcodes.GameServerFriendList)
+		if err != nil {
+			return nil, c.fail(err)
+		}
+
+		friends, err := parseFriendListPayload(data)
+		if err != nil {
+			return nil, c.fail(err)
+		}
+
+		c.sessions.GameSession().Friends = friends
+		if until == nil || until(friends) {
+			return friends, nil
+		}
+	}
+}
+
+func hasFriend(friends []Friend, name string) bool {
+	for _, friend := range friends {
+		if strings.EqualFold(friend.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,983 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/loginserver/crypt"
+	"github.com/frostwind/l2go/names"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/protocol"
+)
+
+// Client is the GameClient implementation speaking the L2Go login and game protocols
+type Client struct {
+	id           string
+	config       ClientConfig
+	handler      ProtocolHandler
+	loginConn    *LoginConnection
+	gameConn     *GameConnection
+	sessions     *SessionManager
+	identity     Identity
+	names        *names.Validator
+	machine      *StateMachine
+	lastSent     string // Name of the last packet sent, for the timeout errors
+	lastRecv     string // Name of the last packet received, for the timeout errors
+	timeout      error  // Set when the client aborted after dwelling too long in a state
+	rawCallbacks []func(packet RawPacket)
+	witnessed    []WorldEvent  // What a spectator saw of the world, the latest MaxWorldEvents
+	accounts     *AccountPool  // Lends the account of every connection instead of the configuration, if set
+	source       *SourceBinder // Binds the connections to source addresses, if set
+	mu           sync.RWMutex
+}
+
+var _ GameClient = (*Client)(nil)
+
+// NewClient creates a disconnected client using the given configuration
+func NewClient(id string, config ClientConfig) *Client {
+	source := newClientBinder(id, config)
+	loginConn, gameConn := newConnections(config, source)
+
+	c := &Client{
+		id:        id,
+		config:    config,
+		handler:   newHandler(config),
+		loginConn: loginConn,
+		gameConn:  gameConn,
+		sessions:  NewSessionManager(),
+		identity:  config.Variation.Pick(id),
+		names:     names.Default(),
+		machine:   NewStateMachine(id),
+		source:    source,
+	}
+
+	for name, limit := range config.StateTimeouts {
+		state, err := ParseClientState(name)
+		if err != nil {
+			continue // Refused by ClientConfig.Validate
+		}
+		c.machine.OnEnter(state, func(transition Transition) {
+			c.watchState(transition, limit)
+		})
+	}
+
+	return c
+}
+
+// watchState aborts the client if it is still in the state it just entered once limit has passed
+func (c *Client) watchState(entered Transition, limit time.Duration) {
+	time.AfterFunc(limit, func() {
+		if c.machine.State() != entered.To || !c.machine.EnteredAt(entered.To).Equal(entered.At) {
+			return
+		}
+
+		c.mu.Lock()
+		c.timeout = fmt.Errorf("%w: %s for more than %v, last sent %s, last received %s",
+			ErrStateTimeout, entered.To, limit, orNone(c.lastSent), orNone(c.lastRecv))
+		c.mu.Unlock()
+
+		// The operation blocked on the connections fails right away, with the timeout error
+		c.closeConnections()
+		c.setState(StateError)
+	})
+}
+
+// TimeoutError returns the error recorded when the client last aborted after dwelling too long
+// in a state, since its last login attempt. It is nil if the client never did.
+func (c *Client) TimeoutError() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.timeout
+}
+
+// recordPacket remembers the last packet exchanged, for the timeout errors
+func (c *Client) recordPacket(protocol opcodes.Protocol, direction opcodes.Direction, opcode byte) {
+	name := protocol.String() + " " + opcodes.Name(protocol, direction, opcode)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if direction == opcodes.ClientToServer {
+		c.lastSent = name
+	} else {
+		c.lastRecv = name
+	}
+}
+
+func orNone(name string) string {
+	if name == "" {
+		return "none"
+	}
+	return name
+}
+
+// SetNameValidator replaces the validator checking the character names before they are sent.
+// Clients can share a validator watching a blocklist file.
+func (c *Client) SetNameValidator(validator *names.Validator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.names = validator
+}
+
+// SetAccountPool has the client borrow the account of every connection from a pool shared with other
+// clients, instead of logging in with the account of its configuration. The account is given back as
+// the client disconnects.
+func (c *Client) SetAccountPool(pool *AccountPool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.accounts = pool
+}
+
+// newHandler creates the protocol handler of a connection attempt
+func newHandler(config ClientConfig) *protocol.Handler {
+	handler := protocol.NewHandler()
+	if config.LenientChecksum {
+		handler.SetLoginChecksumMode(protocol.ChecksumLenient)
+	}
+	return handler
+}
+
+// newConnections creates the login and game connections of a connection attempt
+func newConnections(config ClientConfig, source *SourceBinder) (*LoginConnection, *GameConnection) {
+	loginConn := NewLoginConnection(config.Timeout)
+	loginConn.SetMaxPacketSize(config.MaxPacketSize)
+	loginConn.SetSourceBinder(source)
+
+	gameConn := NewGameConnection(config.Timeout)
+	gameConn.SetMaxPacketSize(config.MaxPacketSize)
+	gameConn.SetSourceBinder(source)
+
+	return loginConn, gameConn
+}
+
+// Connect initiates the full connection sequence (login -> server selection -> game)
+// and enters the world with the first character of the account, if any. With an account pool, the
+// account is borrowed from it for as long as the client stays connected.
+func (c *Client) Connect() error {
+	c.mu.RLock()
+	pool := c.accounts
+	c.mu.RUnlock()
+
+	account := Account{Username: c.config.Username, Password: c.config.Password}
+	if pool != nil {
+		var err error
+		if account, err = pool.Acquire(c.id); err != nil {
+			return err
+		}
+	}
+
+	if err := c.connect(account); err != nil {
+		if pool != nil {
+			pool.Release(c.id)
+		}
+		return err
+	}
+	return nil
+}
+
+// connect runs the connection sequence with an account
+func (c *Client) connect(account Account) error {
+	if err := c.Login(account.Username, account.Password); err != nil {
+		return err
+	}
+
+	serverID := 1
+	if servers := c.sessions.LoginSession().ServerList; len(servers) > 0 {
+		serverID = servers[0].ID
+	}
+
+	if err := c.SelectServer(serverID); err != nil {
+		return err
+	}
+
+	if err := c.ConnectToGame(); err != nil {
+		return err
+	}
+
+	if len(c.sessions.GameSession().Characters) == 0 {
+		return nil
+	}
+
+	return c.SelectCharacter(0)
+}
+
+// Login authenticates with the login server and retrieves the server list. A handshake whose wait for
+// Init, LoginOk or ServerList expires is attempted again, up to the retries of the configuration.
+func (c *Client) Login(username, password string) error {
+	retries, delay := c.config.Handshake.Retries, c.config.Handshake.RetryDelay
+
+	for attempt := 1; ; attempt++ {
+		err := c.login(username, password)
+		if err == nil || !errors.Is(err, ErrOperationTimeout) {
+			return err
+		}
+		if attempt > retries {
+			if retries > 0 {
+				return fmt.Errorf("%w, %d handshakes attempted", err, attempt)
+			}
+			return err
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+
+		// Disconnected while waiting
+		if c.GetState() != StateError {
+			return err
+		}
+	}
+}
+
+// login runs a login handshake
+func (c *Client) login(username, password string) error {
+	if state := c.GetState(); state != StateDisconnected && state != StateError {
+		return fmt.Errorf("%w: cannot login while %s", ErrInvalidState, state)
+	}
+
+	c.mu.Lock()
+	c.handler.Wipe()
+	c.handler = newHandler(c.config)
+	c.loginConn, c.gameConn = newConnections(c.config, c.source)
+	c.lastSent, c.lastRecv, c.timeout = "", "", nil
+	c.mu.Unlock()
+	c.sessions.Reset()
+
+	if err := c.setState(StateConnectingLogin); err != nil {
+		return err
+	}
+
+	if err := c.loginConn.Connect(c.config.LoginServerHost, c.config.LoginServerPort); err != nil {
+		return c.fail(err)
+	}
+
+	// The Init packet is the only one sent in clear text
+	_, data, err := c.awaitLogin(HandshakeInit, opcodes.LoginServerInit)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	sessionID, _, err := parseInitPayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if err := c.negotiateNullCrypto(protocol.PhaseLogin, announcesNullCrypto(data[8:])); err != nil {
+		return c.fail(err)
+	}
+
+	if err := c.handler.InitializeBlowfish(crypt.StaticBlowfishKey); err != nil {
+		return c.fail(fmt.Errorf("%w: %v", ErrEncryptionFailed, err))
+	}
+
+	if c.config.Handshake.GameGuard {
+		if err := c.authenticateGameGuard(sessionID); err != nil {
+			return c.fail(err)
+		}
+	}
+
+	if err := c.setState(StateAuthenticating); err != nil {
+		return c.fail(err)
+	}
+
+	payload, err := newRequestAuthLoginPayload(username, password)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if err := c.sendLogin(opcodes.LoginClientRequestAuthLogin, payload); err != nil {
+		return c.fail(err)
+	}
+
+	opcode, data, err := c.awaitLogin(HandshakeLoginOk, opcodes.LoginServerLoginFail, opcodes.LoginServerLoginOk)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if opcode == opcodes.LoginServerLoginFail {
+		loginFail, err := parseLoginFailPayload(data)
+		if err != nil {
+			return c.fail(err)
+		}
+		return c.fail(fmt.Errorf("login refused: %w", loginFail.Reason.Err()))
+	}
+
+	loginOk, err := parseLoginOkPayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+	sessionKey := loginOk.SessionKey()
+
+	if err := c.sendLogin(opcodes.LoginClientRequestServerList, newRequestServerListPayload(sessionKey)); err != nil {
+		return c.fail(err)
+	}
+
+	opcode, data, err = c.awaitLogin(HandshakeServerList, opcodes.LoginServerLoginFail, opcodes.LoginServerServerList)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if opcode == opcodes.LoginServerLoginFail {
+		loginFail, err := parseLoginFailPayload(data)
+		if err != nil {
+			return c.fail(err)
+		}
+		return c.fail(fmt.Errorf("%w: server list refused: %w", ErrInvalidSession, loginFail.Reason.Err()))
+	}
+
+	nextPage := func() ([]byte, error) {
+		_, data, err := c.awaitLogin(HandshakeServerList, opcodes.LoginServerServerList)
+		return data, err
+	}
+	servers, err := reassemble(data, nextPage, parseServerListPayload, appendPage)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	c.sessions.SetLoginSession(&LoginSession{
+		SessionID: sessionKey,
+		AccountInfo: &AccountInfo{
+			Username:  username,
+			LastLogin: time.Now(),
+		},
+		ServerList: servers,
+	})
+
+	if err := c.setState(StateSelectingServer); err != nil {
+		return c.fail(err)
+	}
+	return nil
+}
+
+// SelectServer selects a game server from the available list
+func (c *Client) SelectServer(serverID int) error {
+	if state := c.GetState(); state != StateSelectingServer {
+		return fmt.Errorf("%w: cannot select a server while %s", ErrInvalidState, state)
+	}
+
+	session := c.sessions.LoginSession()
+
+	if err := c.sendLogin(opcodes.LoginClientRequestPlay, newRequestPlayPayload(session.SessionID, serverID)); err != nil {
+		return c.fail(err)
+	}
+
+	opcode, data, err := c.receiveLogin(opcodes.LoginServerLoginFail, opcodes.LoginServerPlayFail, opcodes.LoginServerPlayOk)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if opcode != opcodes.LoginServerPlayOk {
+		playFail, err := parseLoginFailPayload(data)
+		if err != nil {
+			return c.fail(err)
+		}
+		return c.fail(fmt.Errorf("server %d refused: %w", serverID, playFail.Reason.Err()))
+	}
+
+	playKey, err := parseSessionKeyPayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	session.PlayKey = playKey
+	for i := range session.ServerList {
+		if session.ServerList[i].ID == serverID {
+			session.SelectedServer = &session.ServerList[i]
+		}
+	}
+
+	// The login server isn't needed anymore
+	c.loginConn.Close()
+
+	return nil
+}
+
+// ConnectToGame connects to the selected game server and retrieves the character list
+func (c *Client) ConnectToGame() error {
+	session := c.sessions.LoginSession()
+	if session == nil || session.PlayKey == nil {
+		return fmt.Errorf("%w: no game server selected", ErrInvalidState)
+	}
+
+	if err := c.setState(StateConnectingGame); err != nil {
+		return err
+	}
+
+	if err := c.gameConn.Connect(c.config.GameServerHost, c.config.GameServerPort); err != nil {
+		return c.fail(err)
+	}
+
+	// Protocol version and CryptInit are exchanged in clear text
+	if err := c.sendGame(opcodes.GameClientProtocolVersion, newProtocolVersionPayload(c.identity.Revision, c.gameCompression())); err != nil {
+		return c.fail(err)
+	}
+
+	_, data, err := c.receiveGame(opcodes.GameServerCryptInit)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	key, err := parseCryptInitPayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if err := c.negotiateNullCrypto(protocol.PhaseGame, announcesNullCrypto(data[9:])); err != nil {
+		return c.fail(err)
+	}
+
+	if err := c.handler.InitializeXOR(key); err != nil {
+		return c.fail(fmt.Errorf("%w: %v", ErrEncryptionFailed, err))
+	}
+
+	// The game server answers the offer of ProtocolVersion in CryptInit, compressing what follows if both ends offered it
+	c.handler.NegotiateGameCompression(c.gameCompression(), protocol.ParseCompressionTrailer(data[9:]))
+
+	// The game server sets the time of the world before listing the characters
+	game := &GameSession{GameState: &GameState{LastUpdate: time.Now()}}
+	c.sessions.SetGameSession(game)
+
+	if err := c.sendGame(opcodes.GameClientAuthLogin, newAuthLoginPayload(session.AccountInfo.Username, session.SessionID, session.PlayKey, c.identity.Language)); err != nil {
+		return c.fail(err)
+	}
+
+	_, data, err = c.receiveGame(opcodes.GameServerCharList)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	game.Characters, game.SlotsLeft, err = parseCharListPayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	return nil
+}
+
+// CreateCharacter creates a new character with the given template
+func (c *Client) CreateCharacter(name string, template *CharacterTemplate) error {
+	if state := c.GetState(); state != StateConnectingGame {
+		return fmt.Errorf("%w: cannot create a character while %s", ErrInvalidState, state)
+	}
+
+	// Don't bother the game server with a name it would refuse
+	c.mu.RLock()
+	validator := c.names
+	c.mu.RUnlock()
+
+	if err := validator.Validate(name); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCharacterName, err)
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestNewCharacter, nil); err != nil {
+		return c.fail(err)
+	}
+
+	_, data, err := c.receiveGame(opcodes.GameServerCharTemplate)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	offered, err := parseCharTemplatePayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	template, err = pickTemplate(offered, template)
+	if err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientCharacterCreate, newCharacterCreatePayload(name, template)); err != nil {
+		return c.fail(err)
+	}
+
+	opcode, data, err := c.receiveGame(opcodes.GameServerCharCreateOk, opcodes.GameServerCharCreateFail)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if opcode == opcodes.GameServerCharCreateFail {
+		reason, err := parseReasonPayload(data)
+		if err != nil {
+			return c.fail(err)
+		}
+		return characterCreateError(reason)
+	}
+
+	_, data, err = c.receiveGame(opcodes.GameServerCharList)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	characters, slotsLeft, err := parseCharListPayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	session := c.sessions.GameSession()
+	session.Characters, session.SlotsLeft = characters, slotsLeft
+	return nil
+}
+
+// pickTemplate returns the template to create a character with: the wanted one with the
+// stats the game server offers for its race and class, or the first offered when none is wanted
+func pickTemplate(offered []CharacterTemplate, wanted *CharacterTemplate) (*CharacterTemplate, error) {
+	if len(offered) == 0 {
+		return nil, fmt.Errorf("%w: the game server offers no template", ErrInvalidTemplate)
+	}
+	if wanted == nil {
+		return &offered[0], nil
+	}
+
+	for _, template := range offered {
+		if template.Race == wanted.Race && template.Class == wanted.Class {
+			picked := *wanted
+			picked.STR, picked.DEX, picked.CON = template.STR, template.DEX, template.CON
+			picked.INT, picked.WIT, picked.MEN = template.INT, template.WIT, template.MEN
+			return &picked, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: the game server doesn't offer race %d with class %d", ErrInvalidTemplate, wanted.Race, wanted.Class)
+}
+
+// characterCreateError maps the CharCreateFail reasons to the toolkit errors
+func characterCreateError(reason uint32) error {
+	switch reason {
+	case 0x01:
+		return ErrMaxCharactersReached
+	case 0x02:
+		return ErrCharacterNameTaken
+	case 0x03:
+		return ErrInvalidCharacterName
+	default:
+		return fmt.Errorf("%w: reason %#x", ErrCharacterCreationFailed, reason)
+	}
+}
+
+// SelectCharacter selects the character in the given slot of the character list and enters the world
+func (c *Client) SelectCharacter(characterID int) error {
+	if state := c.GetState(); state != StateConnectingGame {
+		return fmt.Errorf("%w: cannot select a character while %s", ErrInvalidState, state)
+	}
+
+	session := c.sessions.GameSession()
+	if characterID < 0 || characterID >= len(session.Characters) {
+		return ErrCharacterNotFound
+	}
+
+	if err := c.sendGame(opcodes.GameClientCharacterSelected, newCharacterSelectedPayload(characterID)); err != nil {
+		return c.fail(err)
+	}
+
+	if _, _, err := c.receiveGame(opcodes.GameServerCharSelected); err != nil {
+		return c.fail(err)
+	}
+
+	if err := c.sendGame(opcodes.GameClientEnterWorld, nil); err != nil {
+		return c.fail(err)
+	}
+
+	_, data, err := c.receiveGame(opcodes.GameServerUserInfo)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	location, err := parseUserInfoPayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	// Their answers are skipped like any packet nobody waits for
+	for _, opcode := range c.identity.Optional {
+		if err := c.sendGame(opcode, nil); err != nil {
+			return c.fail(err)
+		}
+	}
+
+	selected := session.Characters[characterID]
+	selected.Location = location
+	session.SelectedChar = &selected
+	session.GameState.IsInGame = true
+	session.GameState.IsSitting = false
+	session.GameState.IsRunning = true
+	session.GameState.LastUpdate = time.Now()
+
+	if err := c.setState(StateInGame); err != nil {
+		return c.fail(err)
+	}
+	return nil
+}
+
+// GetCharacterList retrieves the list of characters for the account
+func (c *Client) GetCharacterList() ([]CharacterInfo, error) {
+	session := c.sessions.GameSession()
+	if session == nil {
+		return nil, fmt.Errorf("%w: not connected to a game server", ErrInvalidState)
+	}
+
+	characters := make([]CharacterInfo, len(session.Characters))
+	copy(characters, session.Characters)
+
+	return characters, nil
+}
+
+// CharacterSlotsLeft returns how many more characters the account can create on the game server,
+// -1 when the game server doesn't tell
+func (c *Client) CharacterSlotsLeft() (int, error) {
+	session := c.sessions.GameSession()
+	if session == nil {
+		return 0, fmt.Errorf("%w: not connected to a game server", ErrInvalidState)
+	}
+	return session.SlotsLeft, nil
+}
+
+// Disconnect gracefully disconnects from all servers
+func (c *Client) Disconnect() error {
+	c.closeConnections()
+	c.handler.Wipe()
+	c.sessions.Reset()
+	c.setState(StateDisconnected)
+
+	c.mu.RLock()
+	pool := c.accounts
+	c.mu.RUnlock()
+	if pool != nil {
+		pool.Release(c.id)
+	}
+	return nil
+}
+
+// Logout leaves the world cleanly, waiting for the game server to confirm, then disconnects.
+// A client which isn't in the world is only disconnected
+func (c *Client) Logout() error {
+	if c.GetState() != StateInGame {
+		return c.Disconnect()
+	}
+
+	err := c.sendGame(opcodes.GameClientLogout, nil)
+	if err == nil {
+		_, _, err = c.receiveGame(opcodes.GameServerLogoutOk)
+	}
+
+	c.Disconnect()
+	return err
+}
+
+// Restart leaves the world back to the character selection, staying connected to the game server.
+// It returns ErrRestartRefused if the game server keeps the character in the world.
+func (c *Client) Restart() error {
+	if state := c.GetState(); state != StateInGame {
+		return fmt.Errorf("%w: cannot restart while %s", ErrInvalidState, state)
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestRestart, nil); err != nil {
+		return c.fail(err)
+	}
+
+	_, data, err := c.receiveGame(opcodes.GameServerRestartResponse)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	accepted, err := parseRestartResponsePayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+	if !accepted {
+		return ErrRestartRefused
+	}
+
+	_, data, err = c.receiveGame(opcodes.GameServerCharList)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	characters, slotsLeft, err := parseCharListPayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	session := c.sessions.GameSession()
+	session.Characters, session.SlotsLeft = characters, slotsLeft
+	session.SelectedChar = nil
+	session.Shortcuts = nil
+	session.Friends = nil
+	session.FriendInvite = ""
+	session.Quests = nil
+	session.Inventory = nil
+	session.Enchants = nil
+	session.Dialog = nil
+	// The time of the world goes on regardless of the character
+	session.GameState = &GameState{LastUpdate: time.Now(), ServerTime: session.GameState.ServerTime, IsNight: session.GameState.IsNight}
+
+	if err := c.setState(StateConnectingGame); err != nil {
+		return c.fail(err)
+	}
+	return nil
+}
+
+// SwitchCharacter leaves the world back to the character selection, then enters it again with
+// the character in the given slot of the character list
+func (c *Client) SwitchCharacter(characterID int) error {
+	if err := c.Restart(); err != nil {
+		return err
+	}
+	return c.SelectCharacter(characterID)
+}
+
+// GetState returns the current client state
+func (c *Client) GetState() ClientState {
+	return c.machine.State()
+}
+
+// CryptoStats returns the metrics of the encryption of the last connection attempt, and whether its keys
+// were static or dynamic, to tell where the handshakes of a storm of connections break
+func (c *Client) CryptoStats() protocol.CryptoStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.handler.CryptoStats()
+}
+
+// CompressionStats returns the compression metrics of the game connection, its ratio telling how much the
+// large packets shrank, nothing while it isn't compressed
+func (c *Client) CompressionStats() protocol.CompressionStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.handler.GameCompressionStats()
+}
+
+// gameCompression returns what the client offers the game server, the packets it inflates being bounded by
+// its packet size limit
+func (c *Client) gameCompression() protocol.CompressionOptions {
+	options := c.config.Compression
+	if c.config.MaxPacketSize > 0 {
+		options.MaxSize = c.config.MaxPacketSize
+	}
+	return options
+}
+
+// StateMachine returns the state machine tracking the state of the client
+func (c *Client) StateMachine() *StateMachine {
+	return c.machine
+}
+
+// Identity returns how the client shows itself to the servers
+func (c *Client) Identity() Identity {
+	return c.identity
+}
+
+// GetID returns the unique client identifier
+func (c *Client) GetID() string {
+	return c.id
+}
+
+// Sessions returns the login and game sessions of the client
+func (c *Client) Sessions() *SessionManager {
+	return c.sessions
+}
+
+// setState moves the client into the state, refusing the transitions the state machine doesn't allow
+func (c *Client) setState(state ClientState) error {
+	return c.machine.Transition(state)
+}
+
+// fail moves the client into the error state and releases its connections.
+// A client which was already disconnected stays so. When the client aborted
+// because it dwelled too long in a state, the timeout error replaces err.
+func (c *Client) fail(err error) error {
+	c.closeConnections()
+	c.handler.Wipe()
+	c.setState(StateError)
+
+	if timeout := c.TimeoutError(); timeout != nil {
+		return timeout
+	}
+	return err
+}
+
+func (c *Client) closeConnections() {
+	c.loginConn.Close()
+	c.gameConn.Close()
+}
+
+// sendLogin encodes and sends a packet to the login server
+func (c *Client) sendLogin(opcode byte, payload []byte) error {
+	packet, err := c.handler.EncodeLoginPacket(opcode, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEncryptionFailed, err)
+	}
+	c.recordPacket(opcodes.Login, opcodes.ClientToServer, opcode)
+	c.rawPacket(opcodes.Login, opcodes.ClientToServer, opcode, payload)
+	return c.loginConn.Send(packet)
+}
+
+// receiveLogin waits for one of the expected packets, skipping the others
+func (c *Client) receiveLogin(expected ...byte) (byte, []byte, error) {
+	return c.receiveLoginUntil(time.Time{}, expected...)
+}
+
+// authenticateGameGuard sends RequestGGAuth with the session id of Init and waits for GGAuth, which the
+// login server sends back with the same session id
+func (c *Client) authenticateGameGuard(sessionID []byte) error {
+	if err := c.sendLogin(opcodes.LoginClientRequestGGAuth, newRequestGGAuthPayload(sessionID)); err != nil {
+		return err
+	}
+
+	opcode, data, err := c.awaitLogin(HandshakeGGAuth, opcodes.LoginServerLoginFail, opcodes.LoginServerGGAuth)
+	if err != nil {
+		return err
+	}
+
+	if opcode == opcodes.LoginServerLoginFail {
+		loginFail, err := parseLoginFailPayload(data)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("GameGuard authentication refused: %w", loginFail.Reason.Err())
+	}
+
+	response, err := parseGGAuthPayload(data)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(response, sessionID) {
+		return fmt.Errorf("%w: GGAuth answered for the session %X, not %X", ErrInvalidSession, response, sessionID)
+	}
+	return nil
+}
+
+// awaitLogin waits for one of the expected packets of a phase of the handshake, skipping the others,
+// for no longer than the configuration allows however many packets are skipped
+func (c *Client) awaitLogin(phase string, expected ...byte) (byte, []byte, error) {
+	wait := c.config.Handshake.wait(phase, c.config.Timeout)
+	opcode, data, err := c.receiveLoginUntil(deadline(wait), expected...)
+	if errors.Is(err, ErrOperationTimeout) {
+		c.mu.RLock()
+		lastRecv := c.lastRecv
+		c.mu.RUnlock()
+		return 0, nil, fmt.Errorf("%w: no %s from the login server within %v, last received %s", ErrOperationTimeout, phase, wait, orNone(lastRecv))
+	}
+	return opcode, data, err
+}
+
+// receiveLoginUntil waits for one of the expected packets until deadline, skipping the others.
+// Every packet is waited for the connection timeout when the deadline is zero.
+func (c *Client) receiveLoginUntil(deadline time.Time, expected ...byte) (byte, []byte, error) {
+	for {
+		var raw []byte
+		var err error
+		if deadline.IsZero() {
+			raw, err = c.loginConn.Receive()
+		} else {
+			raw, err = c.loginConn.ReceiveUntil(deadline)
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		opcode, data, err := c.handler.DecodeLoginPacket(raw)
+		if errors.Is(err, protocol.ErrChecksumMismatch) {
+			return 0, nil, fmt.Errorf("%w: %v", ErrChecksumMismatch, err)
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+		}
+		c.recordPacket(opcodes.Login, opcodes.ServerToClient, opcode)
+		c.rawPacket(opcodes.Login, opcodes.ServerToClient, opcode, data)
+
+		if containsOpcode(expected, opcode) {
+			return opcode, data, nil
+		}
+	}
+}
+
+// sendGame encodes and sends a packet to the game server
+func (c *Client) sendGame(opcode byte, payload []byte) error {
+	packet, err := c.handler.EncodeGamePacket(opcode, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrEncryptionFailed, err)
+	}
+	c.recordPacket(opcodes.Game, opcodes.ClientToServer, opcode)
+	c.rawPacket(opcodes.Game, opcodes.ClientToServer, opcode, payload)
+	return c.gameConn.Send(packet)
+}
+
+// receiveGame waits for one of the expected packets, skipping the others
+func (c *Client) receiveGame(expected ...byte) (byte, []byte, error) {
+	for {
+		raw, err := c.gameConn.Receive()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		opcode, data, err := c.decodeGame(raw)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if containsOpcode(expected, opcode) {
+			return opcode, data, nil
+		}
+	}
+}
+
+// decodeGame decrypts a packet of the game server, recording it and keeping up with the world
+func (c *Client) decodeGame(raw []byte) (byte, []byte, error) {
+	opcode, data, err := c.handler.DecodeGamePacket(raw)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+	}
+	c.recordPacket(opcodes.Game, opcodes.ServerToClient, opcode)
+	c.rawPacket(opcodes.Game, opcodes.ServerToClient, opcode, data)
+	c.observeWorld(opcode, data)
+	if c.config.Spectator {
+		c.witness(opcode, data)
+	}
+	return opcode, data, nil
+}
+
+// observeWorld keeps up with what the game server pushes without being asked, such as the time of
+// the world or the weight the character carries, whichever packet the client is waiting for
+func (c *Client) observeWorld(opcode byte, data []byte) {
+	session := c.sessions.GameSession()
+	if session == nil || session.GameState == nil {
+		return
+	}
+
+	switch opcode {
+	case opcodes.GameServerClientSetTime:
+		if minutes, err := parseClientSetTimePayload(data); err == nil {
+			session.GameState.ServerTime = minutes
+		}
+	case opcodes.GameServerSunRise:
+		session.GameState.IsNight = false
+	case opcodes.GameServerSunSet:
+		session.GameState.IsNight = true
+	case opcodes.GameServerStatusUpdate:
+		attributes, err := parseStatusUpdatePayload(data)
+		if err != nil {
+			return
+		}
+		if load, ok := attributes[StatusCurLoad]; ok {
+			session.GameState.Load = int(load)
+		}
+		if maxLoad, ok := attributes[StatusMaxLoad]; ok {
+			session.GameState.MaxLoad = int(maxLoad)
+		}
+	}
+}
+
+func containsOpcode(opcodes []byte, opcode byte) bool {
+	for _, candidate := range opcodes {
+		if candidate == opcode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1369 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/protocol"
+	"github.com/frostwind/l2go/testserver"
+)
+
+// startStubs boots a stub login server advertising a stub game server
+// startStubs starts a stub login server and a stub game server, configured by configure before they
+// serve their first client
+func startStubs(t *testing.T, configure ...func(*testserver.LoginServer, *testserver.GameServer)) (*testserver.LoginServer, *testserver.GameServer, ClientConfig) {
+	t.Helper()
+
+	gameServer := testserver.NewGameServer(testserver.Character{ObjectID: 0x10000001, Name: "Tester", Level: 1, X: 10, Y: 20, Z: -30})
+	loginServer := testserver.NewLoginServer()
+	loginServer.VerifyChecksum = true
+	for _, apply := range configure {
+		apply(loginServer, gameServer)
+	}
+
+	if err := gameServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { gameServer.Close() })
+
+	loginServer.AddGameServer(1, gameServer.Addr())
+	if err := loginServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { loginServer.Close() })
+
+	config := ClientConfig{
+		LoginServerHost: "127.0.0.1",
+		LoginServerPort: loginServer.Addr().Port,
+		GameServerHost:  "127.0.0.1",
+		GameServerPort:  gameServer.Addr().Port,
+		Username:        "testuser",
+		Password:        "testpass",
+		Timeout:         time.Second,
+	}
+
+	return loginServer, gameServer, config
+}
+
+func TestClientConnect(t *testing.T) {
+	_, _, config := startStubs(t)
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	if state := c.GetState(); state != StateInGame {
+		t.Fatalf("GetState() = %v, want %v", state, StateInGame)
+	}
+
+	selected := c.Sessions().GameSession().SelectedChar
+	if selected == nil || selected.Name != "Tester" {
+		t.Fatalf("unexpected selected character %+v", selected)
+	}
+	if selected.Location == nil || selected.Location.Z != -30 {
+		t.Errorf("unexpected location %+v", selected.Location)
+	}
+}
+
+func TestClientLoginErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		opcode int
+		script testserver.Script
+		want   error
+	}{
+		{
+			name:   "login rejected",
+			opcode: int(opcodes.LoginClientRequestAuthLogin),
+			script: testserver.RejectLogin(0x03),
+			want:   ErrAuthenticationFailed,
+		},
+		{
+			name:   "play rejected",
+			opcode: int(opcodes.LoginClientRequestPlay),
+			script: testserver.RejectPlay(0x04),
+			want:   ErrAuthenticationFailed,
+		},
+		{
+			name:   "wrong password",
+			opcode: int(opcodes.LoginClientRequestAuthLogin),
+			script: testserver.RejectLogin(0x03),
+			want:   ErrInvalidCredentials,
+		},
+		{
+			name:   "account banned",
+			opcode: int(opcodes.LoginClientRequestAuthLogin),
+			script: testserver.RejectLogin(0x04),
+			want:   ErrAccountBanned,
+		},
+		{
+			name:   "account in use",
+			opcode: int(opcodes.LoginClientRequestAuthLogin),
+			script: testserver.RejectLogin(0x07),
+			want:   ErrAccountInUse,
+		},
+		{
+			name:   "server full",
+			opcode: int(opcodes.LoginClientRequestPlay),
+			script: testserver.RejectPlay(0x0f),
+			want:   ErrServerFull,
+		},
+		{
+			name:   "unknown reason",
+			opcode: int(opcodes.LoginClientRequestAuthLogin),
+			script: testserver.RejectLogin(0x42),
+			want:   ErrAuthenticationFailed,
+		},
+		{
+			name:   "connection dropped",
+			opcode: int(opcodes.LoginClientRequestAuthLogin),
+			script: testserver.Drop(),
+			want:   ErrConnectionClosed,
+		},
+		{
+			name:   "server list timeout",
+			opcode: int(opcodes.LoginClientRequestServerList),
+			script: testserver.Silence(),
+			want:   ErrOperationTimeout,
+		},
+		{
+			name:   "tampered checksum",
+			opcode: int(opcodes.LoginClientRequestAuthLogin),
+			script: testserver.CorruptChecksum(testserver.RejectLogin(0x03)),
+			want:   ErrChecksumMismatch,
+		},
+		{
+			name:   "init too slow",
+			opcode: testserver.OpcodeConnect,
+			script: testserver.Delay(500*time.Millisecond, testserver.Silence()),
+			want:   ErrOperationTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loginServer, _, config := startStubs(t)
+			config.Timeout = 200 * time.Millisecond
+			loginServer.On(tt.opcode, tt.script)
+
+			c := NewClient("client-1", config)
+			err := c.Connect()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Connect() error = %v, want %v", err, tt.want)
+			}
+			if state := c.GetState(); state != StateError {
+				t.Errorf("GetState() = %v, want %v", state, StateError)
+			}
+		})
+	}
+}
+
+func TestClientStateTimeouts(t *testing.T) {
+	loginServer, _, config := startStubs(t)
+	config.Timeout = 5 * time.Second
+	config.StateTimeouts = map[string]time.Duration{"Authenticating": 200 * time.Millisecond}
+	loginServer.On(int(opcodes.LoginClientRequestAuthLogin), testserver.Silence())
+
+	c := NewClient("client-1", config)
+	start := time.Now()
+	err := c.Connect()
+	if !errors.Is(err, ErrStateTimeout) {
+		t.Fatalf("Connect() error = %v, want %v", err, ErrStateTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Connect() took %v to abort", elapsed)
+	}
+	if want := "Authenticating for more than 200ms, last sent Login RequestAuthLogin, last received Login Init"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Connect() error = %v, want it to contain %q", err, want)
+	}
+	if state := c.GetState(); state != StateError {
+		t.Errorf("GetState() = %v, want %v", state, StateError)
+	}
+	if c.TimeoutError() != err {
+		t.Errorf("TimeoutError() = %v", c.TimeoutError())
+	}
+
+	// The next attempt starts afresh, failing for its own reason
+	loginServer.On(int(opcodes.LoginClientRequestAuthLogin), testserver.RejectLogin(0x03))
+	if err := c.Connect(); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Connect() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if c.TimeoutError() != nil {
+		t.Errorf("TimeoutError() = %v after a new login", c.TimeoutError())
+	}
+}
+
+func TestParseLoginOk(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, 0, 0xea, 0x03, 0, 0}
+
+	loginOk, err := parseLoginOkPayload(data)
+	if err != nil {
+		t.Fatalf("parseLoginOkPayload() error = %v", err)
+	}
+	if !bytes.Equal(loginOk.SessionKey1, []byte{1, 2, 3, 4}) || !bytes.Equal(loginOk.SessionKey2, []byte{5, 6, 7, 8}) {
+		t.Errorf("session key halves = %X %X", loginOk.SessionKey1, loginOk.SessionKey2)
+	}
+	if !bytes.Equal(loginOk.SessionKey(), data[:8]) || loginOk.Unknown3 != 0x3ea {
+		t.Errorf("SessionKey() = %X, Unknown3 = %#x", loginOk.SessionKey(), loginOk.Unknown3)
+	}
+
+	if _, err := parseLoginOkPayload(data[:6]); !errors.Is(err, ErrPacketTooSmall) {
+		t.Errorf("parseLoginOkPayload() error = %v, want %v", err, ErrPacketTooSmall)
+	}
+}
+
+func TestClientLenientChecksum(t *testing.T) {
+	loginServer, _, config := startStubs(t)
+	config.LenientChecksum = true
+	loginServer.On(int(opcodes.LoginClientRequestAuthLogin), testserver.CorruptChecksum(testserver.RejectLogin(0x03)))
+
+	c := NewClient("client-1", config)
+	err := c.Login(config.Username, config.Password)
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("Login() error = %v, want %v", err, ErrAuthenticationFailed)
+	}
+	if stats := c.handler.(*protocol.Handler).LoginChecksumStats(); stats.Mismatches != 1 {
+		t.Errorf("Mismatches = %d, want 1", stats.Mismatches)
+	}
+}
+
+func TestClientEmote(t *testing.T) {
+	_, _, config := startStubs(t)
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	if err := c.Emote(EmoteBow); err != nil {
+		t.Fatalf("Emote() error = %v", err)
+	}
+
+	// The emotes of a sitting character are refused, it stands up first
+	if err := c.Sit(); err != nil {
+		t.Fatalf("Sit() error = %v", err)
+	}
+	if err := c.Emote(EmoteDance); err != nil {
+		t.Fatalf("Emote() error = %v while sitting", err)
+	}
+	if c.Sessions().GameSession().GameState.IsSitting {
+		t.Error("the character is still sitting")
+	}
+
+	for _, actionID := range []int{0, EmoteGreeting - 1, EmoteSorrow + 1} {
+		if err := c.Emote(actionID); !errors.Is(err, ErrInvalidEmote) {
+			t.Errorf("Emote(%d) error = %v, want %v", actionID, err, ErrInvalidEmote)
+		}
+	}
+}
+
+func TestClientGameTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		minutes uint32
+		night   bool
+	}{
+		{name: "day", minutes: 3*24*60 + 12*60},
+		{name: "night", minutes: 2*24*60 + 5*60, night: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, gameServer, config := startStubs(t)
+			gameServer.GameTime = tt.minutes
+
+			c := NewClient("client-1", config)
+			if err := c.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			defer c.Disconnect()
+
+			state := c.Sessions().GameSession().GameState
+			if state.ServerTime != int64(tt.minutes) || state.IsNight != tt.night {
+				t.Errorf("ServerTime = %d and IsNight = %t, want %d and %t", state.ServerTime, state.IsNight, tt.minutes, tt.night)
+			}
+		})
+	}
+
+	if _, err := parseClientSetTimePayload(make([]byte, 4)); !errors.Is(err, ErrPacketTooSmall) {
+		t.Errorf("parseClientSetTimePayload() error = %v, want %v", err, ErrPacketTooSmall)
+	}
+}
+
+func TestClientMaxPacketSize(t *testing.T) {
+	loginServer, _, config := startStubs(t)
+	config.MaxPacketSize = 1024
+	loginServer.On(testserver.OpcodeConnect, testserver.Reply(make([]byte, 4096)))
+
+	c := NewClient("client-1", config)
+	err := c.Login(config.Username, config.Password)
+	if !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("Login() error = %v, want %v", err, ErrPacketTooLarge)
+	}
+	if violations := c.loginConn.Violations(); violations != 1 {
+		t.Errorf("Violations() = %d, want 1", violations)
+	}
+}
+
+func TestClientActions(t *testing.T) {
+	_, _, config := startStubs(t)
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	state := c.Sessions().GameSession().GameState
+
+	if err := c.Sit(); err != nil {
+		t.Fatalf("Sit() error = %v", err)
+	}
+	if !state.IsSitting {
+		t.Error("the character isn't sitting")
+	}
+	if err := c.Stand(); err != nil {
+		t.Fatalf("Stand() error = %v", err)
+	}
+	if state.IsSitting {
+		t.Error("the character is still sitting")
+	}
+
+	if err := c.Walk(); err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if state.IsRunning {
+		t.Error("the character is still running")
+	}
+	if err := c.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !state.IsRunning {
+		t.Error("the character isn't running")
+	}
+
+	if err := c.UseAction(1000, true, false); err != nil {
+		t.Fatalf("UseAction() error = %v", err)
+	}
+
+	shortcut := Shortcut{Type: ShortcutSkill, Slot: 3, Page: 1, ID: 56, Level: 2}
+	if err := c.RegisterShortcut(shortcut); err != nil {
+		t.Fatalf("RegisterShortcut() error = %v", err)
+	}
+	if shortcuts := c.Sessions().GameSession().Shortcuts; len(shortcuts) != 1 || shortcuts[0] != shortcut {
+		t.Fatalf("Shortcuts = %+v, want [%+v]", shortcuts, shortcut)
+	}
+	if err := c.DeleteShortcut(3, 1); err != nil {
+		t.Fatalf("DeleteShortcut() error = %v", err)
+	}
+	if shortcuts := c.Sessions().GameSession().Shortcuts; len(shortcuts) != 0 {
+		t.Errorf("Shortcuts = %+v, want none", shortcuts)
+	}
+
+	if err := c.RegisterShortcut(Shortcut{Type: ShortcutAction, Slot: ShortcutSlots}); !errors.Is(err, ErrInvalidShortcut) {
+		t.Errorf("RegisterShortcut() error = %v, want %v", err, ErrInvalidShortcut)
+	}
+}
+
+func TestClientNpcDialog(t *testing.T) {
+	_, gameServer, config := startStubs(t)
+	gameServer.AddNPC(testserver.NPC{
+		ObjectID: 0x20000001,
+		HTML:     `<html><body>Gatekeeper:<br><a action="bypass -h npc_%objectId%_Chat 1">Teleport</a><br><a action="bypass -h npc_%objectId%_Quest">Quest</a></body></html>`,
+		Bypasses: map[string]string{
+			"npc_%objectId%_Chat 1": `<html><body><a action="bypass -h npc_%objectId%_teleport 1">Gludio</a></body></html>`,
+		},
+	})
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	if _, err := c.ChooseDialogOption(0); !errors.Is(err, ErrNoDialog) {
+		t.Fatalf("ChooseDialogOption() error = %v, want %v", err, ErrNoDialog)
+	}
+
+	dialog, err := c.Interact(0x20000001)
+	if err != nil {
+		t.Fatalf("Interact() error = %v", err)
+	}
+	if target := c.Sessions().GameSession().GameState.TargetID; target != 0x20000001 {
+		t.Errorf("TargetID = %#x, want %#x", target, 0x20000001)
+	}
+	if len(dialog.Links) != 2 || dialog.Links[0].Command != "npc_%objectId%_Chat 1" {
+		t.Fatalf("unexpected dialog links %+v", dialog.Links)
+	}
+
+	index, err := dialog.Option("teleport")
+	if err != nil {
+		t.Fatalf("Option() error = %v", err)
+	}
+	dialog, err = c.ChooseDialogOption(index)
+	if err != nil {
+		t.Fatalf("ChooseDialogOption() error = %v", err)
+	}
+	if len(dialog.Links) != 1 || dialog.Links[0].Text != "Gludio" {
+		t.Fatalf("unexpected dialog links %+v", dialog.Links)
+	}
+	if _, err := dialog.Option("Dion"); !errors.Is(err, ErrDialogOptionNotFound) {
+		t.Errorf("Option() error = %v, want %v", err, ErrDialogOptionNotFound)
+	}
+
+	if err := c.CancelTarget(); err != nil {
+		t.Fatalf("CancelTarget() error = %v", err)
+	}
+	if c.Sessions().GameSession().GameState.TargetID != 0 || c.Sessions().GameSession().Dialog != nil {
+		t.Error("the target and its dialog are still selected")
+	}
+}
+
+func TestClientTeleportVia(t *testing.T) {
+	_, gameServer, config := startStubs(t)
+	gameServer.AddNPC(testserver.NPC{
+		ObjectID: 0x20000002,
+		HTML:     `<html><body><a action="bypass -h npc_%objectId%_teleport 1">Gludio - 7300 Adena</a><br><a action="bypass -h npc_%objectId%_teleport 2">Dion - 11000 Adena</a></body></html>`,
+		Bypasses: map[string]string{
+			"npc_%objectId%_teleport 2": `<html><body>You don't have enough adena.</body></html>`,
+		},
+		Teleports: map[string]testserver.Location{
+			"npc_%objectId%_teleport 1": {X: -12672, Y: 122776, Z: -3116},
+		},
+	})
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	if err := c.TeleportVia(0x20000002, "dion"); !errors.Is(err, ErrTeleportRefused) {
+		t.Fatalf("TeleportVia() error = %v, want %v", err, ErrTeleportRefused)
+	}
+
+	if err := c.TeleportVia(0x20000002, "gludio"); err != nil {
+		t.Fatalf("TeleportVia() error = %v", err)
+	}
+	session := c.Sessions().GameSession()
+	if location := *session.SelectedChar.Location; location != (CharacterLocation{X: -12672, Y: 122776, Z: -3116}) {
+		t.Errorf("Location = %+v after teleporting", location)
+	}
+	if session.GameState.TargetID != 0 || session.Dialog != nil {
+		t.Error("the gatekeeper is still selected after teleporting")
+	}
+
+	if err := c.TeleportVia(0x20000002, "Giran"); !errors.Is(err, ErrDialogOptionNotFound) {
+		t.Errorf("TeleportVia() error = %v, want %v", err, ErrDialogOptionNotFound)
+	}
+}
+
+func TestClientSwitchCharacter(t *testing.T) {
+	_, _, config := startStubs(t)
+
+	// A second game server advertising two characters on the account
+	gameServer := testserver.NewGameServer(
+		testserver.Character{ObjectID: 0x10000001, Name: "Tester", Level: 1, X: 10, Y: 20, Z: -30},
+		testserver.Character{ObjectID: 0x10000002, Name: "Alter", Level: 20, X: -12672, Y: 122776, Z: -3116},
+	)
+	if err := gameServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer gameServer.Close()
+	config.GameServerPort = gameServer.Addr().Port
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	if err := c.Sit(); err != nil {
+		t.Fatalf("Sit() error = %v", err)
+	}
+
+	if err := c.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	session := c.Sessions().GameSession()
+	if state := c.GetState(); state != StateConnectingGame || session.SelectedChar != nil || session.GameState.IsInGame {
+		t.Fatalf("state %v with %+v selected after Restart()", state, session.SelectedChar)
+	}
+	if err := c.Restart(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Restart() error = %v at the character selection, want %v", err, ErrInvalidState)
+	}
+
+	if err := c.SelectCharacter(1); err != nil {
+		t.Fatalf("SelectCharacter() error = %v", err)
+	}
+	if selected := session.SelectedChar; selected.Name != "Alter" || *selected.Location != (CharacterLocation{X: -12672, Y: 122776, Z: -3116}) {
+		t.Errorf("SelectedChar = %+v", selected)
+	}
+
+	if err := c.SwitchCharacter(0); err != nil {
+		t.Fatalf("SwitchCharacter() error = %v", err)
+	}
+	if state := c.GetState(); state != StateInGame || session.SelectedChar.Name != "Tester" || session.GameState.IsSitting {
+		t.Errorf("state %v with %+v selected after SwitchCharacter()", state, session.SelectedChar)
+	}
+
+	if err := c.Logout(); err != nil {
+		t.Errorf("Logout() error = %v", err)
+	}
+	if state := c.GetState(); state != StateDisconnected {
+		t.Errorf("GetState() = %v after Logout()", state)
+	}
+}
+
+func TestClientCharacterAppearance(t *testing.T) {
+	_, _, config := startStubs(t)
+
+	lastAccess := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	veteran := testserver.Character{ObjectID: 0x10000001, Name: "Veteran", Level: 20, Karma: 240, HairStyle: 3, HairColor: 2, Face: 1, LastAccess: lastAccess}
+	veteran.Paperdoll[PaperdollRightHand], veteran.Paperdoll[PaperdollChest] = 2369, 1146
+	gameServer := testserver.NewGameServer(veteran, testserver.Character{ObjectID: 0x10000002, Name: "Rookie", Level: 1})
+	if err := gameServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer gameServer.Close()
+	config.GameServerPort = gameServer.Addr().Port
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	characters, err := c.GetCharacterList()
+	if err != nil || len(characters) != 2 {
+		t.Fatalf("GetCharacterList() = %+v, %v, want 2 characters", characters, err)
+	}
+	got := characters[0]
+	if got.Karma != 240 || got.HairStyle != 3 || got.HairColor != 2 || got.Face != 1 || !got.LastAccess.Equal(lastAccess) {
+		t.Errorf("characters[0] = %+v, want the appearance, the karma and the last access of Veteran", got)
+	}
+	if len(got.Paperdoll) != PaperdollSlots || got.Paperdoll[PaperdollRightHand] != 2369 || got.Paperdoll[PaperdollChest] != 1146 {
+		t.Errorf("characters[0].Paperdoll = %v, want a sword and a shirt worn", got.Paperdoll)
+	}
+	if rookie := characters[1]; rookie.Name != "Rookie" || !rookie.LastAccess.IsZero() || slices.ContainsFunc(rookie.Paperdoll, func(itemID int) bool { return itemID != 0 }) {
+		t.Errorf("characters[1] = %+v, want Rookie never saved and wearing nothing", rookie)
+	}
+}
+
+func TestClientFriendsAndWhispers(t *testing.T) {
+	_, _, config := startStubs(t)
+
+	gameServer := testserver.NewGameServer(
+		testserver.Character{ObjectID: 0x10000001, Name: "Tester", Level: 1},
+		testserver.Character{ObjectID: 0x10000002, Name: "Alter", Level: 20},
+	)
+	if err := gameServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer gameServer.Close()
+	config.GameServerPort = gameServer.Addr().Port
+
+	// Both clients enter with the first character, the second one switching before the first connects
+	alter := NewClient("client-2", config)
+	if err := alter.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer alter.Disconnect()
+	if err := alter.SwitchCharacter(1); err != nil {
+		t.Fatalf("SwitchCharacter() error = %v", err)
+	}
+
+	tester := NewClient("client-1", config)
+	if err := tester.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer tester.Disconnect()
+
+	if err := tester.Whisper("Nobody", "hello"); !errors.Is(err, ErrPlayerOffline) {
+		t.Errorf("Whisper() error = %v to an offline player, want %v", err, ErrPlayerOffline)
+	}
+	if err := tester.Whisper("alter", "hello"); err != nil {
+		t.Fatalf("Whisper() error = %v", err)
+	}
+
+	if err := tester.InviteFriend("Alter"); err != nil {
+		t.Fatalf("InviteFriend() error = %v", err)
+	}
+	if name, err := alter.WaitFriendInvite(); err != nil || name != "Tester" {
+		t.Fatalf("WaitFriendInvite() = %q, %v", name, err)
+	}
+	if err := alter.AnswerFriendInvite(true); err != nil {
+		t.Fatalf("AnswerFriendInvite() error = %v", err)
+	}
+	want := []Friend{{ObjectID: 0x10000001, Name: "Tester", Online: true}}
+	if friends := alter.Sessions().GameSession().Friends; !slices.Equal(friends, want) {
+		t.Errorf("Friends = %+v after accepting, want %+v", friends, want)
+	}
+
+	friends, err := tester.FriendList()
+	want = []Friend{{ObjectID: 0x10000002, Name: "Alter", Online: true}}
+	if err != nil || !slices.Equal(friends, want) {
+		t.Errorf("FriendList() = %+v, %v, want %+v", friends, err, want)
+	}
+
+	if err := tester.RemoveFriend("alter"); err != nil {
+		t.Fatalf("RemoveFriend() error = %v", err)
+	}
+	if friends := tester.Sessions().GameSession().Friends; len(friends) != 0 {
+		t.Errorf("Friends = %+v after RemoveFriend()", friends)
+	}
+
+	if err := alter.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	if _, err := alter.FriendList(); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("FriendList() error = %v at the character selection, want %v", err, ErrInvalidState)
+	}
+	if err := tester.Whisper("Alter", "still there?"); !errors.Is(err, ErrPlayerOffline) {
+		t.Errorf("Whisper() error = %v once the player left, want %v", err, ErrPlayerOffline)
+	}
+}
+
+func TestClientQuests(t *testing.T) {
+	_, gameServer, config := startStubs(t)
+	gameServer.AddNPC(testserver.NPC{ObjectID: 0x20000001, HTML: "<html><body>Elder</body></html>"})
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	if quests, err := c.QuestList(); err != nil || len(quests) != 0 {
+		t.Fatalf("QuestList() = %+v, %v", quests, err)
+	}
+
+	if err := c.AcceptQuest(0x20000001, 7); err != nil {
+		t.Fatalf("AcceptQuest() error = %v", err)
+	}
+	if target := c.Sessions().GameSession().GameState.TargetID; target != 0x20000001 {
+		t.Errorf("TargetID = %#x, want the NPC", target)
+	}
+
+	dialog, err := c.TalkQuest(0x20000001, 7)
+	if err != nil {
+		t.Fatalf("TalkQuest() error = %v", err)
+	}
+	if dialog.HTML != "<html><body>Quest 7, step 2</body></html>" {
+		t.Errorf("TalkQuest() dialog = %q", dialog.HTML)
+	}
+	if quest, ok := c.Quest(7); !ok || quest.Cond != 2 {
+		t.Errorf("Quest(7) = %+v, %v, want the step 2", quest, ok)
+	}
+
+	if err := c.AbortQuest(7); err != nil {
+		t.Fatalf("AbortQuest() error = %v", err)
+	}
+	if _, ok := c.Quest(7); ok {
+		t.Error("the quest is still listed once aborted")
+	}
+}
+
+func TestClientShop(t *testing.T) {
+	_, gameServer, config := startStubs(t)
+	gameServer.Adena = 1000
+	gameServer.AddNPC(testserver.NPC{ObjectID: 0x20000002, Goods: map[uint32]uint64{1835: 10, 1060: 40}})
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	list, err := c.OpenBuyList(0x20000002)
+	if err != nil {
+		t.Fatalf("OpenBuyList() error = %v", err)
+	}
+	if list.Adena != 1000 || len(list.Items) != 2 {
+		t.Errorf("OpenBuyList() = %+v", list)
+	}
+	if item, ok := list.Item(1835); !ok || item.Price != 10 {
+		t.Errorf("Item(1835) = %+v, %v", item, ok)
+	}
+
+	if err := c.BuyItem(0x20000002, 1835, 20); err != nil {
+		t.Fatalf("BuyItem() error = %v", err)
+	}
+	if inventory := c.Inventory(); inventory[AdenaID] != 800 || inventory[1835] != 20 {
+		t.Errorf("Inventory() = %v after buying", inventory)
+	}
+
+	if err := c.BuyItem(0x20000002, 1060, 100); !errors.Is(err, ErrTradeRefused) {
+		t.Errorf("BuyItem() without the adena error = %v, want %v", err, ErrTradeRefused)
+	}
+	if err := c.BuyItem(0x20000002, 57, 1); !errors.Is(err, ErrTradeRefused) {
+		t.Errorf("BuyItem() of goods not sold error = %v, want %v", err, ErrTradeRefused)
+	}
+
+	if err := c.SellItem(0x20000002, 1835, 8); err != nil {
+		t.Fatalf("SellItem() error = %v", err)
+	}
+	if inventory := c.Inventory(); inventory[AdenaID] != 840 || inventory[1835] != 12 {
+		t.Errorf("Inventory() = %v after selling", inventory)
+	}
+	if err := c.SellItem(0x20000002, 1835, 13); !errors.Is(err, ErrTradeRefused) {
+		t.Errorf("SellItem() of too many items error = %v, want %v", err, ErrTradeRefused)
+	}
+	if err := c.SellItem(0x20000002, 1060, 1); !errors.Is(err, ErrTradeRefused) {
+		t.Errorf("SellItem() of an item not owned error = %v, want %v", err, ErrTradeRefused)
+	}
+}
+
+func TestClientWeightLimit(t *testing.T) {
+	_, gameServer, config := startStubs(t)
+	gameServer.Adena = 10000
+	gameServer.WeightLimit = 1000
+	gameServer.AddNPC(testserver.NPC{ObjectID: 0x20000002, Goods: map[uint32]uint64{1835: 10, 1060: 40}, Weights: map[uint32]uint32{1835: 5, 1060: 120}})
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	state := c.Sessions().GameSession().GameState
+	if state.Load != 0 || state.MaxLoad != 1000 {
+		t.Fatalf("Load = %d out of %d after entering the world", state.Load, state.MaxLoad)
+	}
+
+	list, err := c.OpenBuyList(0x20000002)
+	if err != nil {
+		t.Fatalf("OpenBuyList() error = %v", err)
+	}
+	if item, _ := list.Item(1060); item.Weight != 120 {
+		t.Errorf("Item(1060) = %+v, want a weight of 120", item)
+	}
+
+	steps := []struct {
+		name   string
+		run    func() error
+		want   error
+		load   int
+		adena  uint64
+		potion uint64
+	}{
+		{name: "buying", run: func() error { return c.BuyItem(0x20000002, 1060, 8) }, load: 960, adena: 9680},
+		{name: "buying over the limit", run: func() error { return c.BuyItem(0x20000002, 1835, 9) }, want: ErrTradeRefused, load: 960, adena: 9680},
+		{name: "buying up to the limit", run: func() error { return c.BuyItem(0x20000002, 1835, 8) }, load: 1000, adena: 9600, potion: 8},
+		{name: "selling", run: func() error { return c.SellItem(0x20000002, 1060, 1) }, load: 880, adena: 9620, potion: 8},
+	}
+	for _, step := range steps {
+		if err := step.run(); !errors.Is(err, step.want) || (step.want == nil && err != nil) {
+			t.Fatalf("%s: error = %v, want %v", step.name, err, step.want)
+		}
+		if load := c.Sessions().GameSession().GameState.Load; load != step.load {
+			t.Errorf("%s: Load = %d, want %d", step.name, load, step.load)
+		}
+		if inventory := c.Inventory(); inventory[AdenaID] != step.adena || inventory[1835] != step.potion {
+			t.Errorf("%s: Inventory() = %v", step.name, inventory)
+		}
+	}
+}
+
+func TestClientWarehouse(t *testing.T) {
+	_, gameServer, config := startStubs(t)
+	gameServer.Adena = 1000
+	gameServer.AddNPC(testserver.NPC{ObjectID: 0x20000003, Warehouse: true})
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	if err := c.Deposit(0x20000003, false, AdenaID, 600); err != nil {
+		t.Fatalf("Deposit() error = %v", err)
+	}
+	if inventory := c.Inventory(); inventory[AdenaID] != 400 {
+		t.Errorf("Inventory() = %v after the deposit", inventory)
+	}
+
+	list, err := c.OpenWithdraw(0x20000003, false)
+	if err != nil {
+		t.Fatalf("OpenWithdraw() error = %v", err)
+	}
+	if item, ok := list.Item(AdenaID); list.ID != WarehousePrivate || !ok || item.Count != 600 {
+		t.Errorf("OpenWithdraw() = %+v", list)
+	}
+
+	// Taking back more than stored would duplicate the adena
+	if err := c.Withdraw(0x20000003, false, AdenaID, 601); !errors.Is(err, ErrWarehouseRefused) {
+		t.Errorf("Withdraw() of more than stored error = %v, want %v", err, ErrWarehouseRefused)
+	}
+	if err := c.Withdraw(0x20000003, false, AdenaID, 600); err != nil {
+		t.Fatalf("Withdraw() error = %v", err)
+	}
+	if inventory := c.Inventory(); inventory[AdenaID] != 1000 {
+		t.Errorf("Inventory() = %v after the withdrawal", inventory)
+	}
+	if err := c.Withdraw(0x20000003, false, AdenaID, 1); !errors.Is(err, ErrWarehouseRefused) {
+		t.Errorf("Withdraw() from an empty warehouse error = %v, want %v", err, ErrWarehouseRefused)
+	}
+
+	if _, err := c.OpenDeposit(0x20000003, true); !errors.Is(err, ErrWarehouseRefused) {
+		t.Errorf("OpenDeposit() of the clan warehouse error = %v, want %v", err, ErrWarehouseRefused)
+	}
+}
+
+func TestClientPagedLists(t *testing.T) {
+	loginServer, gameServer, config := startStubs(t)
+	for id := uint8(2); id <= 5; id++ {
+		loginServer.AddGameServer(id, gameServer.Addr())
+	}
+	loginServer.ServersPerPage = 2
+	gameServer.GM = true
+	gameServer.ItemsPerPage = 2
+	gameServer.AddNPC(testserver.NPC{ObjectID: 0x20000003, Warehouse: true})
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	servers := c.Sessions().LoginSession().ServerList
+	if len(servers) != 5 || servers[0].ID != 1 || servers[4].ID != 5 {
+		t.Errorf("ServerList = %+v, want the 5 servers of the 3 pages", servers)
+	}
+
+	for itemID := 1060; itemID < 1065; itemID++ {
+		if err := c.GiveItem(itemID, uint64(itemID)); err != nil {
+			t.Fatalf("GiveItem(%d) error = %v", itemID, err)
+		}
+	}
+	want := map[int]uint64{AdenaID: 0, 1060: 1060, 1061: 1061, 1062: 1062, 1063: 1063, 1064: 1064}
+	if inventory := c.Inventory(); !reflect.DeepEqual(inventory, want) {
+		t.Errorf("Inventory() = %v, want %v from the 3 pages", inventory, want)
+	}
+
+	list, err := c.OpenDeposit(0x20000003, false)
+	if err != nil {
+		t.Fatalf("OpenDeposit() error = %v", err)
+	}
+	if len(list.Items) != 5 || list.ID != WarehousePrivate {
+		t.Errorf("OpenDeposit() = %+v, want the 5 items of the 3 pages", list)
+	}
+	if err := c.Deposit(0x20000003, false, 1064, 4); err != nil {
+		t.Fatalf("Deposit() error = %v", err)
+	}
+	if inventory := c.Inventory(); inventory[1064] != 1060 || inventory[1060] != 1060 {
+		t.Errorf("Inventory() = %v after the deposit", inventory)
+	}
+}
+
+func TestClientAdminCommands(t *testing.T) {
+	_, gameServer, config := startStubs(t)
+	gameServer.GM = true
+
+	c := NewClient("client-1", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	if err := c.GiveItem(2369, 2); err != nil {
+		t.Fatalf("GiveItem() error = %v", err)
+	}
+	if err := c.GiveItem(AdenaID, 500); err != nil {
+		t.Fatalf("GiveItem() of adena error = %v", err)
+	}
+	if inventory := c.Inventory(); inventory[2369] != 2 || inventory[AdenaID] != 500 {
+		t.Errorf("Inventory() = %v after the items were given", inventory)
+	}
+
+	if err := c.Enchant(2369, 16); err != nil {
+		t.Fatalf("Enchant() error = %v", err)
+	}
+	if level := c.Sessions().GameSession().Enchants[2369]; level != 16 {
+		t.Errorf("Enchants[2369] = %d, want 16", level)
+	}
+	if err := c.Enchant(2370, 16); !errors.Is(err, ErrAdminCommandRefused) {
+		t.Errorf("Enchant() of an item not owned error = %v, want %v", err, ErrAdminCommandRefused)
+	}
+
+	gameServer.GM = false
+	if err := c.GiveItem(2369, 1); !errors.Is(err, ErrAdminCommandRefused) || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("GiveItem() without the access error = %v, want %v", err, ErrAdminCommandRefused)
+	}
+}
+
+func TestClientIdentity(t *testing.T) {
+	_, gameServer, config := startStubs(t)
+	config.Variation = VariationProfile{Revisions: []uint32{419, 422, 428}, Languages: []uint32{0, 1, 2}, OptionalPackets: true}
+
+	var identities []Identity
+	for i := range 8 {
+		c := NewClient(fmt.Sprintf("client-%d", i), config)
+		if err := c.Connect(); err != nil {
+			t.Fatalf("Connect() error = %v", err)
+		}
+		// Answered once the optional packets were read
+		if err := c.Sit(); err != nil {
+			t.Fatalf("Sit() error = %v", err)
+		}
+		c.Disconnect()
+		identities = append(identities, c.Identity())
+	}
+
+	fingerprints := gameServer.Fingerprints()
+	if len(fingerprints) != len(identities) {
+		t.Fatalf("the server saw %d clients, want %d", len(fingerprints), len(identities))
+	}
+	revisions, optional := make(map[uint32]bool), make(map[string]bool)
+	for i, identity := range identities {
+		fingerprint := fingerprints[i]
+		entered := slices.Index(fingerprint.Opcodes, opcodes.GameClientEnterWorld)
+		sent := fingerprint.Opcodes[entered+1 : len(fingerprint.Opcodes)-1]
+		if fingerprint.Revision != identity.Revision || fingerprint.Language != identity.Language || !bytes.Equal(sent, identity.Optional) {
+			t.Errorf("client-%d showed %+v, want %+v", i, fingerprint, identity)
+		}
+		revisions[identity.Revision] = true
+		optional[string(identity.Optional)] = true
+	}
+	if len(revisions) < 2 || len(optional) < 2 {
+		t.Errorf("the clients only showed the revisions %v and the optional packets %v", revisions, optional)
+	}
+
+	// A client id always gets the same identity
+	if again := config.Variation.Pick("client-3"); !reflect.DeepEqual(again, identities[3]) {
+		t.Errorf("Pick() = %+v, then %+v", identities[3], again)
+	}
+	if identity := (VariationProfile{}).Pick("client-3"); identity.Revision != opcodes.GameProtocolRevision || identity.Language != -1 || identity.Optional != nil {
+		t.Errorf("Pick() without variation = %+v", identity)
+	}
+
+	config.Variation.Revisions = []uint32{opcodes.GameProtocolRevision - 1}
+	if err := config.Validate(); !errors.Is(err, ErrInvalidVariation) {
+		t.Errorf("Validate() of an older revision error = %v, want %v", err, ErrInvalidVariation)
+	}
+}
+
+func TestClientRawPackets(t *testing.T) {
+	_, _, config := startStubs(t)
+
+	c := NewClient("client-1", config)
+	if err := c.SendRawGame(opcodes.GameClientSay2, nil); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("SendRawGame() before connecting error = %v, want %v", err, ErrNotConnected)
+	}
+
+	var packets []RawPacket
+	c.OnRawPacket(func(packet RawPacket) { packets = append(packets, packet) })
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	// The echo of the chat is skipped by Sit, yet seen
+	packets = nil
+	payload := newSay2Payload("hello", ChatAll, "")
+	if err := c.SendRawGame(opcodes.GameClientSay2, payload); err != nil {
+		t.Fatalf("SendRawGame() error = %v", err)
+	}
+	if err := c.Sit(); err != nil {
+		t.Fatalf("Sit() error = %v", err)
+	}
+
+	sent := packets[0]
+	if sent.Protocol != opcodes.Game || sent.Direction != opcodes.ClientToServer || sent.Opcode != opcodes.GameClientSay2 || !bytes.Equal(sent.Payload, payload) {
+		t.Fatalf("OnRawPacket() first saw %+v, want the Say2 sent", sent)
+	}
+	if !slices.ContainsFunc(packets, func(packet RawPacket) bool {
+		return packet.Direction == opcodes.ServerToClient && packet.Opcode == opcodes.GameServerCreatureSay
+	}) {
+		t.Errorf("OnRawPacket() didn't see the echo of the chat in %+v", packets)
+	}
+}
+
+func TestPickTemplate(t *testing.T) {
+	offered, err := BundledTemplates("c1")
+	if err != nil {
+		t.Fatalf("BundledTemplates() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		offered   []CharacterTemplate
+		wanted    *CharacterTemplate
+		wantClass int
+		wantSTR   int
+		wantErr   error
+	}{
+		{name: "first offered", offered: offered, wantClass: offered[0].Class, wantSTR: offered[0].STR},
+		{name: "offered stats", offered: offered, wanted: &CharacterTemplate{Race: 3, Class: 49, Face: 2, STR: 99}, wantClass: 49, wantSTR: 27},
+		{name: "not offered", offered: offered, wanted: &CharacterTemplate{Race: 4, Class: 10}, wantErr: ErrInvalidTemplate},
+		{name: "nothing offered", wantErr: ErrInvalidTemplate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked, err := pickTemplate(tt.offered, tt.wanted)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("pickTemplate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if picked.Class != tt.wantClass || picked.STR != tt.wantSTR {
+				t.Errorf("pickTemplate() = %+v", picked)
+			}
+			if tt.wanted != nil && picked.Face != tt.wanted.Face {
+				t.Errorf("the face %d wasn't kept", tt.wanted.Face)
+			}
+		})
+	}
+
+	if _, err := parseCharTemplatePayload([]byte{2, 0, 0, 0, 1}); !errors.Is(err, ErrInvalidPacket) {
+		t.Errorf("parseCharTemplatePayload() error = %v, want %v", err, ErrInvalidPacket)
+	}
+}
+
+func TestClientNullCrypto(t *testing.T) {
+	tests := []struct {
+		name        string
+		loginPlain  bool
+		gamePlain   bool
+		clientPlain bool
+		want        error
+	}{
+		{name: "both ends in clear", loginPlain: true, gamePlain: true, clientPlain: true},
+		{name: "login server in clear", loginPlain: true, want: ErrNullCryptoMismatch},
+		{name: "game server in clear", gamePlain: true, want: ErrNullCryptoMismatch},
+		{name: "client in clear", clientPlain: true, want: ErrNullCryptoMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, config := startStubs(t, func(loginServer *testserver.LoginServer, gameServer *testserver.GameServer) {
+				loginServer.NullCrypto, gameServer.NullCrypto = tt.loginPlain, tt.gamePlain
+			})
+			config.NullCrypto = tt.clientPlain
+
+			c := NewClient("client-1", config)
+			err := c.Connect()
+			defer c.Disconnect()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Connect() error = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				return
+			}
+
+			if stats := c.CryptoStats(); stats.BlowfishKey != protocol.KeyNone || stats.XORKey != protocol.KeyNone || stats.Encryptions != 0 {
+				t.Errorf("CryptoStats() = %+v, want no key and nothing encrypted", stats)
+			}
+		})
+	}
+}
+
+func TestClientNullCryptoRefusedOnPublicHosts(t *testing.T) {
+	config := ClientConfig{
+		LoginServerHost: "127.0.0.1",
+		LoginServerPort: 2106,
+		GameServerHost:  "203.0.113.7",
+		GameServerPort:  7777,
+		Username:        "testuser",
+		Password:        "testpass",
+		NullCrypto:      true,
+	}
+	if err := config.Validate(); !errors.Is(err, ErrNullCryptoRefused) {
+		t.Errorf("Validate() with a public game server = %v, want %v", err, ErrNullCryptoRefused)
+	}
+
+	config.GameServerHost = "192.168.1.2"
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() with private servers = %v", err)
+	}
+}
+
+func TestClientHandshakeTimeouts(t *testing.T) {
+	initPacket := []byte{opcodes.LoginServerInit, 0x9c, 0x77, 0xed, 0x03, 0x5a, 0x78, 0x00, 0x00}
+
+	tests := []struct {
+		name      string
+		opcode    int
+		script    func(loginServer *testserver.LoginServer) testserver.Script
+		handshake HandshakeConfig
+		want      string
+	}{
+		{
+			name:   "hung before Init",
+			opcode: testserver.OpcodeConnect,
+			script: func(*testserver.LoginServer) testserver.Script {
+				return testserver.Delay(300*time.Millisecond, testserver.Reply(initPacket))
+			},
+			handshake: HandshakeConfig{InitTimeout: 50 * time.Millisecond},
+			want:      "no Init from the login server within 50ms",
+		},
+		{
+			name:   "hung before GGAuth",
+			opcode: int(opcodes.LoginClientRequestGGAuth),
+			script: func(*testserver.LoginServer) testserver.Script {
+				return testserver.Silence()
+			},
+			handshake: HandshakeConfig{GameGuard: true, GGAuthTimeout: 50 * time.Millisecond},
+			want:      "no GGAuth from the login server within 50ms, last received Login Init",
+		},
+		{
+			name:   "hung before LoginOk",
+			opcode: int(opcodes.LoginClientRequestAuthLogin),
+			script: func(loginServer *testserver.LoginServer) testserver.Script {
+				return testserver.Delay(300*time.Millisecond, loginServer.AcceptLogin())
+			},
+			handshake: HandshakeConfig{LoginOkTimeout: 50 * time.Millisecond, Retries: 1},
+			want:      "no LoginOk from the login server within 50ms, last received Login Init, 2 handshakes attempted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loginServer, _, config := startStubs(t)
+			loginServer.On(tt.opcode, tt.script(loginServer))
+			config.Handshake = tt.handshake
+
+			c := NewClient("client-1", config)
+			started := time.Now()
+			err := c.Login(config.Username, config.Password)
+			if !errors.Is(err, ErrOperationTimeout) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Login() error = %v, want %v with %q", err, ErrOperationTimeout, tt.want)
+			}
+			if took := time.Since(started); took > 500*time.Millisecond {
+				t.Errorf("Login() took %v, the waits of the handshake weren't bounded", took)
+			}
+			if state := c.GetState(); state != StateError {
+				t.Errorf("GetState() = %v, want %v", state, StateError)
+			}
+		})
+	}
+}
+
+func TestClientGameGuard(t *testing.T) {
+	ggAuth := func(sessionID ...byte) []byte {
+		return append(append([]byte{opcodes.LoginServerGGAuth}, sessionID...), make([]byte, 16)...)
+	}
+
+	tests := []struct {
+		name   string
+		script testserver.Script // Answer to RequestGGAuth, the stub one when nil
+		err    error
+	}{
+		{name: "authenticated"},
+		{name: "other session", script: testserver.Reply(ggAuth(0x01, 0x02, 0x03, 0x04)), err: ErrInvalidSession},
+		{name: "refused", script: testserver.RejectLogin(0x04), err: ErrAccountBanned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loginServer, _, config := startStubs(t)
+			if tt.script != nil {
+				loginServer.On(int(opcodes.LoginClientRequestGGAuth), tt.script)
+			}
+			config.Handshake = HandshakeConfig{GameGuard: true}
+
+			c := NewClient("client-1", config)
+			defer c.Disconnect()
+			if err := c.Login(config.Username, config.Password); !errors.Is(err, tt.err) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestClientHandshakeRetries(t *testing.T) {
+	loginServer, _, config := startStubs(t)
+
+	// The first connection hangs, the next one is answered
+	var connections atomic.Int32
+	initPacket := []byte{opcodes.LoginServerInit, 0x9c, 0x77, 0xed, 0x03, 0x5a, 0x78, 0x00, 0x00}
+	loginServer.On(testserver.OpcodeConnect, func([]byte) testserver.Response {
+		if connections.Add(1) == 1 {
+			return testserver.Response{Delay: 300 * time.Millisecond}
+		}
+		return testserver.Response{Packets: [][]byte{initPacket}}
+	})
+	config.Handshake = HandshakeConfig{InitTimeout: 50 * time.Millisecond, Retries: 2, RetryDelay: 10 * time.Millisecond}
+
+	c := NewClient("client-1", config)
+	if err := c.Login(config.Username, config.Password); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	defer c.Disconnect()
+
+	if state := c.GetState(); state != StateSelectingServer {
+		t.Errorf("GetState() = %v, want %v", state, StateSelectingServer)
+	}
+	if count := connections.Load(); count != 2 {
+		t.Errorf("%d connections to the login server, want 2", count)
+	}
+}
+
+func TestClientSpectator(t *testing.T) {
+	_, _, config := startStubs(t)
+
+	gameServer := testserver.NewGameServer(
+		testserver.Character{ObjectID: 0x10000001, Name: "Tester", Level: 1},
+		testserver.Character{ObjectID: 0x10000002, Name: "Alter", Level: 20},
+	)
+	if err := gameServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer gameServer.Close()
+	config.GameServerPort = gameServer.Addr().Port
+
+	// The spectator watches with the second character, the first one speaking to it
+	spectatorConfig := config
+	spectatorConfig.Spectator = true
+	spectator := NewClient("client-2", spectatorConfig)
+	if err := spectator.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer spectator.Disconnect()
+	if err := spectator.SwitchCharacter(1); err != nil {
+		t.Fatalf("SwitchCharacter() error = %v", err)
+	}
+
+	if err := spectator.Emote(EmoteBow); !errors.Is(err, ErrSpectator) {
+		t.Errorf("Emote() error = %v, want %v", err, ErrSpectator)
+	}
+	if err := spectator.SendRawGame(opcodes.GameClientRequestActionUse, make([]byte, 9)); !errors.Is(err, ErrSpectator) {
+		t.Errorf("SendRawGame() error = %v, want %v", err, ErrSpectator)
+	}
+
+	eventBus := NewEventBus()
+	published := make(chan WorldEvent, MaxWorldEvents)
+	eventBus.Subscribe(TopicWorldEvent, func(event interface{}) error {
+		published <- event.(WorldEvent)
+		return nil
+	})
+	spectator.StateMachine().SetEventBus(eventBus)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- spectator.Spectate(ctx) }()
+
+	tester := NewClient("client-1", config)
+	if err := tester.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer tester.Disconnect()
+	if err := tester.Whisper("Alter", "hello"); err != nil {
+		t.Fatalf("Whisper() error = %v", err)
+	}
+
+	heard := func(event WorldEvent) bool {
+		return event.Kind == WorldChat && event.Name == "Tester" && event.Text == "hello"
+	}
+	var event WorldEvent
+	for !heard(event) {
+		select {
+		case event = <-published:
+		case <-time.After(time.Second):
+			t.Fatal("the whisper wasn't published")
+		}
+	}
+	if event.ClientID != "client-2" || event.Packet != "CreatureSay" || event.ObjectID != 0x10000001 {
+		t.Errorf("published %+v", event)
+	}
+	if missed := Unwitnessed([]*Client{spectator}, heard); missed != nil {
+		t.Errorf("Unwitnessed() = %v, want none", missed)
+	}
+	if missed := Unwitnessed([]*Client{spectator}, func(event WorldEvent) bool { return event.Text == "never said" }); !slices.Equal(missed, []string{"client-2"}) {
+		t.Errorf("Unwitnessed() = %v, want [client-2]", missed)
+	}
+	if !spectator.Witnessed(func(event WorldEvent) bool { return event.Kind == WorldTime }) {
+		t.Errorf("WorldEvents() = %+v, without the time of the world", spectator.WorldEvents())
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Spectate() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Spectate() didn't return once cancelled")
+	}
+	if state := spectator.GetState(); state != StateDisconnected {
+		t.Errorf("state = %s after spectating, want %s", state, StateDisconnected)
+	}
+
+	if err := tester.Spectate(context.Background()); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Spectate() error = %v for a player, want %v", err, ErrInvalidState)
+	}
+}
+
+func TestDecodeWorldEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		want   WorldEvent
+	}{
+		{
+			name:   "player appearing",
+			packet: serverpackets.NewCharInfoPacket(7, "Alter", 1, 2, -3),
+			want:   WorldEvent{Kind: WorldAppear, ObjectID: 7, Name: "Alter", Location: &CharacterLocation{X: 1, Y: 2, Z: -3}},
+		},
+		{
+			name:   "item dropped",
+			packet: serverpackets.NewDropItemPacket(7, 8, 57, 1, 2, 3, 100),
+			want:   WorldEvent{Kind: WorldAppear, ActorID: 7, ObjectID: 8, ItemID: 57, Count: 100, Location: &CharacterLocation{X: 1, Y: 2, Z: 3}},
+		},
+		{
+			name:   "item picked up",
+			packet: serverpackets.NewGetItemPacket(7, 8, 1, 2, 3),
+			want:   WorldEvent{Kind: WorldVanish, ActorID: 7, ObjectID: 8, Location: &CharacterLocation{X: 1, Y: 2, Z: 3}},
+		},
+		{
+			name:   "walk",
+			packet: serverpackets.NewMoveToLocationPacket(7, 10, 20, 30, 1, 2, 3),
+			want:   WorldEvent{Kind: WorldMove, ObjectID: 7, Location: &CharacterLocation{X: 10, Y: 20, Z: 30}},
+		},
+		{
+			name:   "sitting down",
+			packet: serverpackets.NewChangeWaitTypePacket(7, 0, 1, 2, 3),
+			want:   WorldEvent{Kind: WorldPosture, ObjectID: 7, State: "sitting", Location: &CharacterLocation{X: 1, Y: 2, Z: 3}},
+		},
+		{
+			name:   "emote",
+			packet: serverpackets.NewSocialActionPacket(7, EmoteBow),
+			want:   WorldEvent{Kind: WorldSocial, ObjectID: 7, Value: EmoteBow},
+		},
+		{
+			name:   "sunset",
+			packet: serverpackets.NewSunSetPacket(),
+			want:   WorldEvent{Kind: WorldTime, State: "sunset"},
+		},
+		{
+			name:   "other packet",
+			packet: serverpackets.NewLogoutOkPacket(),
+			want:   WorldEvent{Kind: WorldPacket},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeWorldEvent(tt.packet[0], tt.packet[1:])
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeWorldEvent() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := decodeWorldEvent(opcodes.GameServerCreatureSay, make([]byte, 6)); !errors.Is(err, ErrInvalidPacket) {
+		t.Errorf("decodeWorldEvent() error = %v for a truncated packet, want %v", err, ErrInvalidPacket)
+	}
+}
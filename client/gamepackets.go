@@ -0,0 +1,628 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
+)
+
+// newProtocolVersionPayload builds the ProtocolVersion payload, followed by the compression offer if any
+func newProtocolVersionPayload(version uint32, compression protocol.CompressionOptions) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt32(version)
+	buffer.Write(compression.Trailer())
+
+	return buffer.Bytes()
+}
+
+// newAuthLoginPayload builds the game server AuthLogin payload, followed by the language unless it is negative
+func newAuthLoginPayload(account string, sessionKey, playKey []byte, language int) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteString(account)
+	buffer.Write(playKey[4:8])
+	buffer.Write(playKey[:4])
+	buffer.Write(sessionKey[:4])
+	buffer.Write(sessionKey[4:8])
+	if language >= 0 {
+		buffer.WriteUInt32(uint32(language))
+	}
+
+	return buffer.Bytes()
+}
+
+// newCharacterCreatePayload builds the CharacterCreate payload
+func newCharacterCreatePayload(name string, template *CharacterTemplate) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteString(name)
+	buffer.WriteUInt32(uint32(template.Race))
+	buffer.WriteUInt32(uint32(template.Gender))
+	buffer.WriteUInt32(uint32(template.Class))
+	buffer.WriteUInt32(uint32(template.INT))
+	buffer.WriteUInt32(uint32(template.STR))
+	buffer.WriteUInt32(uint32(template.CON))
+	buffer.WriteUInt32(uint32(template.MEN))
+	buffer.WriteUInt32(uint32(template.DEX))
+	buffer.WriteUInt32(uint32(template.WIT))
+	buffer.WriteUInt32(uint32(template.HairStyle))
+	buffer.WriteUInt32(uint32(template.HairColor))
+	buffer.WriteUInt32(uint32(template.Face))
+
+	return buffer.Bytes()
+}
+
+// newCharacterSelectedPayload builds the CharacterSelected payload
+func newCharacterSelectedPayload(slot int) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt32(uint32(slot))
+
+	return buffer.Bytes()
+}
+
+// newActionUsePayload builds the RequestActionUse payload
+func newActionUsePayload(actionID int, ctrl, shift bool) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt32(uint32(actionID))
+	buffer.WriteUInt32(boolToUInt32(ctrl))
+	buffer.WriteBool(shift)
+
+	return buffer.Bytes()
+}
+
+// newSocialActionPayload builds the RequestSocialAction payload
+func newSocialActionPayload(actionID int) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt32(uint32(actionID))
+
+	return buffer.Bytes()
+}
+
+// newShortcutRegPayload builds the RequestShortCutReg payload
+func newShortcutRegPayload(shortcut Shortcut) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt32(uint32(shortcut.Type))
+	buffer.WriteUInt32(uint32(shortcut.Slot + shortcut.Page*ShortcutSlots))
+	buffer.WriteUInt32(uint32(shortcut.ID))
+	buffer.WriteUInt32(uint32(shortcut.Level))
+
+	return buffer.Bytes()
+}
+
+// newShortcutDelPayload builds the RequestShortCutDel payload
+func newShortcutDelPayload(slot, page int) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt32(uint32(slot + page*ShortcutSlots))
+
+	return buffer.Bytes()
+}
+
+// newActionPayload builds the Action payload, sent when clicking an object of the world
+func newActionPayload(objectID int, origin *CharacterLocation, shift bool) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt32(uint32(objectID))
+	buffer.WriteUInt32(uint32(origin.X))
+	buffer.WriteUInt32(uint32(origin.Y))
+	buffer.WriteUInt32(uint32(origin.Z))
+	buffer.WriteBool(shift)
+
+	return buffer.Bytes()
+}
+
+// newBypassPayload builds the RequestBypassToServer payload
+func newBypassPayload(command string) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteString(command)
+
+	return buffer.Bytes()
+}
+
+// newQuestIDPayload builds the RequestQuestAbort payload
+func newQuestIDPayload(questID int) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt32(uint32(questID))
+
+	return buffer.Bytes()
+}
+
+// newTradePayload builds the RequestBuyItem, RequestSellItem and warehouse payloads, moving a single kind of item
+func newTradePayload(listID, itemID int, count uint64) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt32(uint32(listID))
+	buffer.WriteUInt32(1)
+	buffer.WriteUInt32(uint32(itemID))
+	buffer.WriteUInt64(count)
+
+	return buffer.Bytes()
+}
+
+// newTargetCancelPayload builds the RequestTargetCanceld payload
+func newTargetCancelPayload() []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt16(0) // Cancel the target, not the cast
+
+	return buffer.Bytes()
+}
+
+// newSay2Payload builds the Say2 payload, the whispers naming the player they are sent to
+func newSay2Payload(text string, chatType uint32, target string) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteString(text)
+	buffer.WriteUInt32(chatType)
+	if chatType == ChatTell {
+		buffer.WriteString(target)
+	}
+
+	return buffer.Bytes()
+}
+
+// newMoveBackwardToLocationPayload builds the MoveBackwardToLocation payload, sent when clicking the ground
+func newMoveBackwardToLocationPayload(target, origin *CharacterLocation) []byte {
+	buffer := packets.NewBuffer()
+	for _, location := range []*CharacterLocation{target, origin} {
+		buffer.WriteUInt32(uint32(location.X))
+		buffer.WriteUInt32(uint32(location.Y))
+		buffer.WriteUInt32(uint32(location.Z))
+	}
+
+	return buffer.Bytes()
+}
+
+// newFriendNamePayload builds the RequestFriendInvite and RequestFriendDel payloads
+func newFriendNamePayload(name string) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteString(name)
+
+	return buffer.Bytes()
+}
+
+// newAnswerFriendInvitePayload builds the RequestAnswerFriendInvite payload
+func newAnswerFriendInvitePayload(accept bool) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteUInt32(boolToUInt32(accept))
+
+	return buffer.Bytes()
+}
+
+func boolToUInt32(value bool) uint32 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// parseCryptInitPayload extracts the XOR key from the CryptInit packet
+func parseCryptInitPayload(data []byte) ([]byte, error) {
+	if len(data) < 9 {
+		return nil, fmt.Errorf("%w: CryptInit packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	key := make([]byte, 8)
+	copy(key, data[1:9])
+
+	return key, nil
+}
+
+// parseCharTemplatePayload decodes the templates offered by the game server, each
+// stat being framed by two values the toolkit doesn't need
+func parseCharTemplatePayload(data []byte) ([]CharacterTemplate, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: CharTemplate packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	count := int(reader.ReadUInt32())
+	if count*80 > reader.Len() {
+		return nil, fmt.Errorf("%w: CharTemplate announces %d templates in %d bytes", ErrInvalidPacket, count, len(data))
+	}
+
+	offered := make([]CharacterTemplate, 0, count)
+	for i := 0; i < count; i++ {
+		template := CharacterTemplate{Race: int(reader.ReadUInt32()), Class: int(reader.ReadUInt32())}
+		for _, stat := range []*int{&template.STR, &template.DEX, &template.CON, &template.INT, &template.WIT, &template.MEN} {
+			reader.ReadUInt32()
+			*stat = int(reader.ReadUInt32())
+			reader.ReadUInt32()
+		}
+		offered = append(offered, template)
+	}
+
+	return offered, nil
+}
+
+// parseCharListPayload decodes the character list sent by the game server, with how many more
+// characters the account can create, or -1 when the game server doesn't tell
+func parseCharListPayload(data []byte) ([]CharacterInfo, int, error) {
+	if len(data) < 4 {
+		return nil, 0, fmt.Errorf("%w: CharList packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	count := int(reader.ReadUInt32())
+
+	characters := make([]CharacterInfo, 0)
+	for i := 0; i < count; i++ {
+		if reader.Len() == 0 {
+			return nil, 0, fmt.Errorf("%w: CharList announces %d characters", ErrInvalidPacket, count)
+		}
+
+		character := CharacterInfo{Name: reader.ReadString()}
+		character.ID = int(reader.ReadUInt32())
+		character.Gender = int(reader.ReadUInt32())
+		character.Race = int(reader.ReadUInt32())
+		character.Class = int(reader.ReadUInt32())
+		character.Level = int(reader.ReadUInt32())
+		character.Karma = int(int32(reader.ReadUInt32()))
+		character.HairStyle = int(reader.ReadUInt32())
+		character.HairColor = int(reader.ReadUInt32())
+		character.Face = int(reader.ReadUInt32())
+		character.Paperdoll = make([]int, PaperdollSlots)
+		for slot := range character.Paperdoll {
+			character.Paperdoll[slot] = int(reader.ReadUInt32())
+		}
+		if lastAccess := reader.ReadUInt32(); lastAccess != 0 {
+			character.LastAccess = time.Unix(int64(lastAccess), 0)
+		}
+
+		characters = append(characters, character)
+	}
+
+	slotsLeft := -1
+	if reader.Len() >= 4 {
+		slotsLeft = int(reader.ReadUInt32())
+	}
+	return characters, slotsLeft, nil
+}
+
+// parseUserInfoPayload decodes the location part of the UserInfo packet
+func parseUserInfoPayload(data []byte) (*CharacterLocation, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("%w: UserInfo packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	return &CharacterLocation{
+		X: int(int32(reader.ReadUInt32())),
+		Y: int(int32(reader.ReadUInt32())),
+		Z: int(int32(reader.ReadUInt32())),
+	}, nil
+}
+
+// parseChangeWaitTypePayload tells whether the ChangeWaitType packet makes the character sit
+func parseChangeWaitTypePayload(data []byte) (bool, error) {
+	if len(data) < 8 {
+		return false, fmt.Errorf("%w: ChangeWaitType packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	reader.ReadUInt32() // Object id
+
+	return reader.ReadUInt32() == 0, nil
+}
+
+// parseSocialActionPayload decodes the object playing the SocialAction packet and its action
+func parseSocialActionPayload(data []byte) (objectID, actionID int, err error) {
+	if len(data) < 8 {
+		return 0, 0, fmt.Errorf("%w: SocialAction packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	return int(reader.ReadUInt32()), int(reader.ReadUInt32()), nil
+}
+
+// parseClientSetTimePayload decodes the minutes of the world the ClientSetTime packet sets, the speed
+// of the world following them
+func parseClientSetTimePayload(data []byte) (int64, error) {
+	if len(data) < 8 {
+		return 0, fmt.Errorf("%w: ClientSetTime packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	return int64(packets.NewReader(data).ReadUInt32()), nil
+}
+
+// parseStatusUpdatePayload decodes the attributes the StatusUpdate packet sets, by attribute id
+func parseStatusUpdatePayload(data []byte) (map[uint32]uint32, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: StatusUpdate packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	decoder := packets.NewDecoder(data)
+	decoder.U32() // Object id
+	count := decoder.U32()
+
+	attributes := make(map[uint32]uint32)
+	for i := uint32(0); i < count && decoder.Err() == nil; i++ {
+		id, value := decoder.U32(), decoder.U32()
+		attributes[id] = value
+	}
+
+	if err := decoder.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+	return attributes, nil
+}
+
+// parseChangeMoveTypePayload tells whether the ChangeMoveType packet makes the character run
+func parseChangeMoveTypePayload(data []byte) (bool, error) {
+	if len(data) < 8 {
+		return false, fmt.Errorf("%w: ChangeMoveType packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	reader.ReadUInt32() // Object id
+
+	return reader.ReadUInt32() == 1, nil
+}
+
+// parseShortcutRegisterPayload decodes the shortcut confirmed by the ShortCutRegister packet
+func parseShortcutRegisterPayload(data []byte) (Shortcut, error) {
+	if len(data) < 16 {
+		return Shortcut{}, fmt.Errorf("%w: ShortCutRegister packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	shortcut := Shortcut{Type: ShortcutType(reader.ReadUInt32())}
+	position := int(reader.ReadUInt32())
+	shortcut.Slot = position % ShortcutSlots
+	shortcut.Page = position / ShortcutSlots
+	shortcut.ID = int(reader.ReadUInt32())
+	shortcut.Level = int(reader.ReadUInt32())
+
+	return shortcut, nil
+}
+
+// parseMyTargetSelectedPayload extracts the object id from the MyTargetSelected packet
+func parseMyTargetSelectedPayload(data []byte) (int, error) {
+	if len(data) < 4 {
+		return 0, fmt.Errorf("%w: MyTargetSelected packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	return int(packets.NewReader(data).ReadUInt32()), nil
+}
+
+// dialogLinkPattern matches the bypass links of an HTML dialog
+var dialogLinkPattern = regexp.MustCompile(`(?is)<a\s+action="bypass\s+(?:-h\s+)?([^"]+)"\s*>(.*?)</a>`)
+
+// parseNpcHtmlMessagePayload decodes the NpcHtmlMessage packet along with the options of the dialog
+func parseNpcHtmlMessagePayload(data []byte) (*Dialog, error) {
+	if len(data) < 6 {
+		return nil, fmt.Errorf("%w: NpcHtmlMessage packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	dialog := &Dialog{NpcObjectID: int(reader.ReadUInt32())}
+	dialog.HTML = reader.ReadString()
+
+	for _, match := range dialogLinkPattern.FindAllStringSubmatch(dialog.HTML, -1) {
+		dialog.Links = append(dialog.Links, DialogLink{Text: strings.TrimSpace(match[2]), Command: strings.TrimSpace(match[1])})
+	}
+
+	return dialog, nil
+}
+
+// parseTeleportToLocationPayload decodes the object teleported and its destination from the TeleportToLocation packet
+func parseTeleportToLocationPayload(data []byte) (int, *CharacterLocation, error) {
+	if len(data) < 16 {
+		return 0, nil, fmt.Errorf("%w: TeleportToLocation packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	objectID := int(reader.ReadUInt32())
+
+	return objectID, &CharacterLocation{
+		X: int(int32(reader.ReadUInt32())),
+		Y: int(int32(reader.ReadUInt32())),
+		Z: int(int32(reader.ReadUInt32())),
+	}, nil
+}
+
+// parseMoveToLocationPayload decodes the creature walking and its destination from the MoveToLocation packet
+func parseMoveToLocationPayload(data []byte) (int, *CharacterLocation, error) {
+	if len(data) < 16 {
+		return 0, nil, fmt.Errorf("%w: MoveToLocation packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	objectID := int(reader.ReadUInt32())
+
+	return objectID, &CharacterLocation{
+		X: int(int32(reader.ReadUInt32())),
+		Y: int(int32(reader.ReadUInt32())),
+		Z: int(int32(reader.ReadUInt32())),
+	}, nil
+}
+
+// parseReasonPayload extracts the reason code of CharCreateFail
+func parseReasonPayload(data []byte) (uint32, error) {
+	if len(data) < 4 {
+		return 0, fmt.Errorf("%w: reason of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	return packets.NewReader(data).ReadUInt32(), nil
+}
+
+// parseRestartResponsePayload extracts whether the game server accepted a RequestRestart
+func parseRestartResponsePayload(data []byte) (bool, error) {
+	if len(data) < 4 {
+		return false, fmt.Errorf("%w: restart response of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	return packets.NewReader(data).ReadUInt32() != 0, nil
+}
+
+// parseCreatureSayPayload decodes the speaker, the chat type, the name and the text of a CreatureSay packet
+func parseCreatureSayPayload(data []byte) (int, uint32, string, string, error) {
+	if len(data) < 12 {
+		return 0, 0, "", "", fmt.Errorf("%w: CreatureSay packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	objectID := int(reader.ReadUInt32())
+	chatType := reader.ReadUInt32()
+	name := reader.ReadString()
+
+	return objectID, chatType, name, reader.ReadString(), nil
+}
+
+// parseSystemMessagePayload decodes the id of a SystemMessage packet along with its parameters,
+// the numbers being formatted as text
+func parseSystemMessagePayload(data []byte) (uint32, []string, error) {
+	if len(data) < 8 {
+		return 0, nil, fmt.Errorf("%w: SystemMessage packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	decoder := packets.NewDecoder(data)
+	messageID := decoder.U32()
+	count := decoder.U32()
+
+	var params []string
+	for i := uint32(0); i < count && decoder.Err() == nil; i++ {
+		if decoder.U32() == 0 {
+			params = append(params, decoder.S())
+		} else {
+			params = append(params, strconv.FormatUint(uint64(decoder.U32()), 10))
+		}
+	}
+
+	if err := decoder.Err(); err != nil {
+		return 0, nil, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+	return messageID, params, nil
+}
+
+// parseAskJoinFriendPayload extracts the name of the player asking the character to be its friend
+func parseAskJoinFriendPayload(data []byte) (string, error) {
+	if len(data) < 2 {
+		return "", fmt.Errorf("%w: AskJoinFriend packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	return packets.NewReader(data).ReadString(), nil
+}
+
+// parseQuestListPayload decodes the quests of the QuestList packet, leaving out the quest items following them
+func parseQuestListPayload(data []byte) ([]Quest, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("%w: QuestList packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	decoder := packets.NewDecoder(data)
+	quests := make([]Quest, decoder.U16())
+	for i := range quests {
+		quests[i].ID = int(decoder.U32())
+		quests[i].Cond = int(decoder.U32())
+	}
+
+	if err := decoder.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+	return quests, nil
+}
+
+// parseBuyListPayload decodes the goods of a BuyList packet, with the adena of the character
+func parseBuyListPayload(data []byte) (*TradeList, error) {
+	if len(data) < 14 {
+		return nil, fmt.Errorf("%w: BuyList packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	decoder := packets.NewDecoder(data)
+	list := &TradeList{Adena: decoder.U64(), ID: int(decoder.U32())}
+	list.Items = make([]TradeItem, decoder.U16())
+	for i := range list.Items {
+		list.Items[i].ItemID = int(decoder.U32())
+		list.Items[i].Price = decoder.U64()
+		list.Items[i].Weight = int(decoder.U32())
+	}
+
+	if err := decoder.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+	return list, nil
+}
+
+// parseSellListPayload decodes the items of the character a merchant buys back, from a SellList packet
+func parseSellListPayload(data []byte) (*TradeList, error) {
+	if len(data) < 14 {
+		return nil, fmt.Errorf("%w: SellList packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	decoder := packets.NewDecoder(data)
+	list := &TradeList{Adena: decoder.U64(), ID: int(decoder.U32())}
+	list.Items = make([]TradeItem, decoder.U16())
+	for i := range list.Items {
+		list.Items[i].ItemID = int(decoder.U32())
+		list.Items[i].Count = decoder.U64()
+		list.Items[i].Price = decoder.U64()
+	}
+
+	if err := decoder.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+	return list, nil
+}
+
+// parseWarehouseListPayload decodes a page of the items of a warehouse list, along with the adena of the
+// character, telling whether another page follows
+func parseWarehouseListPayload(data []byte) (*TradeList, bool, error) {
+	if len(data) < 13 {
+		return nil, false, fmt.Errorf("%w: warehouse list of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	decoder := packets.NewDecoder(data)
+	more := morePages(decoder.U8())
+	list := &TradeList{ID: int(decoder.U16()), Adena: decoder.U64()}
+	list.Items = make([]TradeItem, decoder.U16())
+	for i := range list.Items {
+		list.Items[i].ItemID = int(decoder.U32())
+		list.Items[i].Count = decoder.U64()
+	}
+
+	if err := decoder.Err(); err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+	return list, more, nil
+}
+
+// parseItemListPayload decodes the counts of the items of a page of an ItemList packet by id, telling
+// whether another page follows
+func parseItemListPayload(data []byte) (map[int]uint64, bool, error) {
+	if len(data) < 3 {
+		return nil, false, fmt.Errorf("%w: ItemList packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	decoder := packets.NewDecoder(data)
+	more := morePages(decoder.U8())
+	count := int(decoder.U16())
+	items := make(map[int]uint64, count)
+	for i := 0; i < count && decoder.Err() == nil; i++ {
+		itemID := int(decoder.U32())
+		items[itemID] += decoder.U64()
+	}
+
+	if err := decoder.Err(); err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+	return items, more, nil
+}
+
+// parseFriendListPayload decodes the friends of the FriendList packet
+func parseFriendListPayload(data []byte) ([]Friend, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("%w: FriendList packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	decoder := packets.NewDecoder(data)
+	friends := make([]Friend, decoder.U16())
+	for i := range friends {
+		friends[i].ObjectID = int(decoder.U32())
+		friends[i].Name = decoder.S()
+		friends[i].Online = decoder.U32() != 0
+	}
+
+	if err := decoder.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+	return friends, nil
+}
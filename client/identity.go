@@ -0,0 +1,65 @@
+package client
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/random"
+)
+
+// optionalPackets are the lists a client may ask for as it enters the world, in any order
+var optionalPackets = []byte{opcodes.GameClientRequestFriendList, opcodes.GameClientRequestQuestList}
+
+// VariationProfile varies how the clients of a fleet show themselves to the servers, within what the
+// servers accept, so that the traffic of a load test isn't a single client signature repeated
+type VariationProfile struct {
+	Revisions       []uint32 `json:"revisions,omitempty"`       // Game protocol revisions picked from, the revision of the toolkit when empty
+	Languages       []uint32 `json:"languages,omitempty"`       // Languages picked from, sent after the keys of AuthLogin, none when empty
+	OptionalPackets bool     `json:"optionalPackets,omitempty"` // Ask for some of the friend and quest lists on entering the world, in any order
+}
+
+// Identity is how a client shows itself to the servers
+type Identity struct {
+	Revision uint32
+	Language int    // Sent after the keys of AuthLogin, -1 for none
+	Optional []byte // Packets sent once in the world, in order
+}
+
+// Validate checks the revisions are ones the servers accept
+func (p VariationProfile) Validate() error {
+	for _, revision := range p.Revisions {
+		if revision < opcodes.GameProtocolRevision {
+			return fmt.Errorf("%w: the revision %d is older than %d", ErrInvalidVariation, revision, opcodes.GameProtocolRevision)
+		}
+	}
+	return nil
+}
+
+// Pick draws the identity of a client from the profile, a client id always getting the same one
+func (p VariationProfile) Pick(clientID string) Identity {
+	hash := fnv.New64a()
+	hash.Write([]byte(clientID))
+	source := random.Seeded(hash.Sum64())
+
+	identity := Identity{Revision: opcodes.GameProtocolRevision, Language: -1}
+	if len(p.Revisions) > 0 {
+		identity.Revision = p.Revisions[source.Int64N(int64(len(p.Revisions)))]
+	}
+	if len(p.Languages) > 0 {
+		identity.Language = int(p.Languages[source.Int64N(int64(len(p.Languages)))])
+	}
+	if p.OptionalPackets {
+		// Every packet is sent or not, then the ones sent are shuffled
+		for _, opcode := range optionalPackets {
+			if source.Int64N(2) == 1 {
+				identity.Optional = append(identity.Optional, opcode)
+			}
+		}
+		for i := len(identity.Optional) - 1; i > 0; i-- {
+			j := source.Int64N(int64(i + 1))
+			identity.Optional[i], identity.Optional[j] = identity.Optional[j], identity.Optional[i]
+		}
+	}
+	return identity
+}
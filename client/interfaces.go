@@ -2,6 +2,8 @@ package client
 
 import (
 	"net"
+
+	"github.com/frostwind/l2go/protocol"
 )
 
 // GameClient represents the main client interface for connecting to L2Go servers
@@ -27,14 +29,35 @@ type GameClient interface {
 	// GetCharacterList retrieves the list of characters for the account
 	GetCharacterList() ([]CharacterInfo, error)
 
+	// CharacterSlotsLeft returns how many more characters the account can create, -1 when unknown
+	CharacterSlotsLeft() (int, error)
+
 	// Disconnect gracefully disconnects from all servers
 	Disconnect() error
 
+	// Logout leaves the world cleanly before disconnecting
+	Logout() error
+
+	// Restart leaves the world back to the character selection, staying connected
+	Restart() error
+
+	// SwitchCharacter leaves the world and enters it again with another character of the account
+	SwitchCharacter(characterID int) error
+
 	// GetState returns the current client state
 	GetState() ClientState
 
 	// GetID returns the unique client identifier
 	GetID() string
+
+	// SendRawLogin sends a packet the client has no typed support for to the login server
+	SendRawLogin(opcode byte, payload []byte) error
+
+	// SendRawGame sends a packet the client has no typed support for to the game server
+	SendRawGame(opcode byte, payload []byte) error
+
+	// OnRawPacket registers a callback run for every packet the client sends or reads
+	OnRawPacket(callback func(packet RawPacket))
 }
 
 // ProtocolHandler manages packet encoding/decoding and protocol operations
@@ -51,13 +74,38 @@ type ProtocolHandler interface {
 	// DecodeGamePacket decodes a packet from the game server
 	DecodeGamePacket(raw []byte) (opcode byte, data []byte, err error)
 
+	// EncodeGameKeyedPacket encodes a packet for the game server, including extended packets
+	EncodeGameKeyedPacket(key protocol.PacketKey, data []byte) ([]byte, error)
+
+	// DecodeGameKeyedPacket decodes a packet from the game server, including extended packets
+	DecodeGameKeyedPacket(raw []byte) (key protocol.PacketKey, data []byte, err error)
+
+	// SetCipher replaces the cipher of a phase
+	SetCipher(phase protocol.Phase, cipher protocol.Cipher)
+
 	// InitializeBlowfish initializes Blowfish encryption for login server
 	InitializeBlowfish(key []byte) error
 
 	// InitializeXOR initializes XOR encryption for game server
 	InitializeXOR(key []byte) error
+
+	// Wipe zeroes all the key material and resets both encryption contexts
+	Wipe()
+
+	// CryptoStats returns the metrics of the encryption, and whether its last keys were static or dynamic
+	CryptoStats() protocol.CryptoStats
+
+	// NegotiateGameCompression enables the compression of the game packets if both ends offered it
+	NegotiateGameCompression(local, remote protocol.CompressionOptions) protocol.CompressionOptions
+
+	// GameCompressionStats returns the compression metrics of the game packets
+	GameCompressionStats() protocol.CompressionStats
 }
 
+// ClientFactory creates the clients of a manager, so that custom implementations such as headless
+// bots, replay clients or chaos clients can be managed
+type ClientFactory func(id string, config ClientConfig) GameClient
+
 // ClientManager manages multiple concurrent client connections
 type ClientManager interface {
 	// CreateClients creates the specified number of clients with the given configuration
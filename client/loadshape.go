@@ -0,0 +1,137 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Load shapes a load test can follow
+const (
+	ShapeLinear   = "linear"   // Ramps up to the client count over the ramp-up time, then holds
+	ShapeStep     = "step"     // Adds a batch of clients at every interval
+	ShapeSpike    = "spike"    // Holds a base population, jumps to the client count and back
+	ShapeSine     = "sine"     // Swings between the base population and the client count
+	ShapeSoak     = "soak"     // Ramps up like linear, then holds while clients reconnect
+	ShapeAdaptive = "adaptive" // Adds clients while the servers keep up, backs off when they don't
+)
+
+// DefaultBackoffPercent is the share of the population the adaptive shape drops on a backoff when unset
+const DefaultBackoffPercent = 20
+
+// LoadShape describes how the population of a load test evolves over time.
+// The client count of the load test is always the peak population.
+type LoadShape struct {
+	Type         string        `json:"type"`                   // One of linear, step, spike, sine, soak and adaptive, linear when empty
+	StepClients  int           `json:"stepClients,omitempty"`  // Clients added at every step, the first step of the adaptive shape
+	StepInterval time.Duration `json:"stepInterval,omitempty"` // Time between two steps, the window the adaptive shape judges the servers over
+	BaseClients  int           `json:"baseClients,omitempty"`  // Population around the spike, at the bottom of the sine wave, or the adaptive shape starts with
+	SpikeAt      time.Duration `json:"spikeAt,omitempty"`      // Time into the test when the spike starts
+	SpikeHold    time.Duration `json:"spikeHold,omitempty"`    // How long the spike lasts
+	Period       time.Duration `json:"period,omitempty"`       // Length of a full sine wave
+	ChurnPercent float64       `json:"churnPercent,omitempty"` // Percentage of the clients disconnecting and reconnecting every minute while soaking
+
+	// The adaptive shape adds a step of clients after every window where the p95 of the logins stayed
+	// under MaxLoginP95 and their error rate under MaxErrorPercent. Otherwise it drops BackoffPercent of
+	// the population and halves its step, narrowing down on the capacity of the servers.
+	MaxLoginP95     time.Duration `json:"maxLoginP95,omitempty"`
+	MaxErrorPercent float64       `json:"maxErrorPercent,omitempty"`
+	BackoffPercent  float64       `json:"backoffPercent,omitempty"` // DefaultBackoffPercent when 0
+}
+
+// Target returns the population the shape wants once elapsed has passed,
+// peaking at clients and ramping up over rampUp for the linear and soak shapes.
+// The adaptive shape starts with its base clients, the load runner steering it from there.
+func (s LoadShape) Target(elapsed time.Duration, clients int, rampUp time.Duration) int {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	var target int
+	switch s.Type {
+	case ShapeStep:
+		target = s.StepClients * int(1+elapsed/s.StepInterval)
+	case ShapeSpike:
+		target = s.BaseClients
+		if elapsed >= s.SpikeAt && elapsed < s.SpikeAt+s.SpikeHold {
+			target = clients
+		}
+	case ShapeAdaptive:
+		target = s.BaseClients
+	case ShapeSine:
+		phase := 2 * math.Pi * float64(elapsed) / float64(s.Period)
+		target = s.BaseClients + int(math.Round(float64(clients-s.BaseClients)*(1-math.Cos(phase))/2))
+	default:
+		target = clients
+		if elapsed < rampUp {
+			target = int(int64(clients) * int64(elapsed) / int64(rampUp))
+		}
+	}
+
+	return min(target, clients)
+}
+
+// Churn returns how many of the live clients the shape reconnects over interval
+func (s LoadShape) Churn(live int, interval time.Duration) float64 {
+	if s.Type != ShapeSoak {
+		return 0
+	}
+	return float64(live) * s.ChurnPercent / 100 * interval.Minutes()
+}
+
+// Validate validates the load shape against the client count of the load test
+func (s LoadShape) Validate(clients int) error {
+	switch s.Type {
+	case "", ShapeLinear:
+	case ShapeStep:
+		if s.StepClients <= 0 {
+			return fmt.Errorf("stepClients must be greater than 0, got %d", s.StepClients)
+		}
+		if s.StepInterval <= 0 {
+			return fmt.Errorf("stepInterval must be greater than 0, got %v", s.StepInterval)
+		}
+	case ShapeSpike:
+		if s.BaseClients < 0 || s.BaseClients > clients {
+			return fmt.Errorf("baseClients must be between 0 and %d, got %d", clients, s.BaseClients)
+		}
+		if s.SpikeAt < 0 {
+			return fmt.Errorf("spikeAt must be non-negative, got %v", s.SpikeAt)
+		}
+		if s.SpikeHold <= 0 {
+			return fmt.Errorf("spikeHold must be greater than 0, got %v", s.SpikeHold)
+		}
+	case ShapeSine:
+		if s.BaseClients < 0 || s.BaseClients > clients {
+			return fmt.Errorf("baseClients must be between 0 and %d, got %d", clients, s.BaseClients)
+		}
+		if s.Period <= 0 {
+			return fmt.Errorf("period must be greater than 0, got %v", s.Period)
+		}
+	case ShapeSoak:
+		if s.ChurnPercent < 0 {
+			return fmt.Errorf("churnPercent must be non-negative, got %v", s.ChurnPercent)
+		}
+	case ShapeAdaptive:
+		if s.StepClients <= 0 {
+			return fmt.Errorf("stepClients must be greater than 0, got %d", s.StepClients)
+		}
+		if s.StepInterval <= 0 {
+			return fmt.Errorf("stepInterval must be greater than 0, got %v", s.StepInterval)
+		}
+		if s.BaseClients < 0 || s.BaseClients > clients {
+			return fmt.Errorf("baseClients must be between 0 and %d, got %d", clients, s.BaseClients)
+		}
+		if s.MaxLoginP95 <= 0 {
+			return fmt.Errorf("maxLoginP95 must be greater than 0, got %v", s.MaxLoginP95)
+		}
+		if s.MaxErrorPercent < 0 || s.MaxErrorPercent > 100 {
+			return fmt.Errorf("maxErrorPercent must be between 0 and 100, got %v", s.MaxErrorPercent)
+		}
+		if s.BackoffPercent < 0 || s.BackoffPercent >= 100 {
+			return fmt.Errorf("backoffPercent must be between 0 and 100, got %v", s.BackoffPercent)
+		}
+	default:
+		return fmt.Errorf("invalid shape: %s, must be one of: linear, step, spike, sine, soak, adaptive", s.Type)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadShapeTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		shape   LoadShape
+		elapsed time.Duration
+		want    int
+	}{
+		{name: "linear start", shape: LoadShape{}, elapsed: 0, want: 0},
+		{name: "linear ramping", shape: LoadShape{Type: ShapeLinear}, elapsed: 5 * time.Second, want: 50},
+		{name: "linear holding", shape: LoadShape{Type: ShapeLinear}, elapsed: time.Minute, want: 100},
+		{name: "soak holding", shape: LoadShape{Type: ShapeSoak, ChurnPercent: 10}, elapsed: time.Minute, want: 100},
+		{name: "first step", shape: LoadShape{Type: ShapeStep, StepClients: 30, StepInterval: 10 * time.Second}, elapsed: 9 * time.Second, want: 30},
+		{name: "third step", shape: LoadShape{Type: ShapeStep, StepClients: 30, StepInterval: 10 * time.Second}, elapsed: 25 * time.Second, want: 90},
+		{name: "last step capped", shape: LoadShape{Type: ShapeStep, StepClients: 30, StepInterval: 10 * time.Second}, elapsed: 35 * time.Second, want: 100},
+		{name: "before spike", shape: LoadShape{Type: ShapeSpike, BaseClients: 20, SpikeAt: 10 * time.Second, SpikeHold: 5 * time.Second}, elapsed: 9 * time.Second, want: 20},
+		{name: "during spike", shape: LoadShape{Type: ShapeSpike, BaseClients: 20, SpikeAt: 10 * time.Second, SpikeHold: 5 * time.Second}, elapsed: 12 * time.Second, want: 100},
+		{name: "after spike", shape: LoadShape{Type: ShapeSpike, BaseClients: 20, SpikeAt: 10 * time.Second, SpikeHold: 5 * time.Second}, elapsed: 15 * time.Second, want: 20},
+		{name: "sine trough", shape: LoadShape{Type: ShapeSine, BaseClients: 20, Period: time.Minute}, elapsed: time.Minute, want: 20},
+		{name: "sine middle", shape: LoadShape{Type: ShapeSine, BaseClients: 20, Period: time.Minute}, elapsed: 15 * time.Second, want: 60},
+		{name: "sine crest", shape: LoadShape{Type: ShapeSine, BaseClients: 20, Period: time.Minute}, elapsed: 30 * time.Second, want: 100},
+		{name: "adaptive start", shape: LoadShape{Type: ShapeAdaptive, BaseClients: 10, StepClients: 5, StepInterval: time.Second, MaxLoginP95: time.Second}, elapsed: time.Minute, want: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.shape.Validate(100); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := tt.shape.Target(tt.elapsed, 100, 10*time.Second); got != tt.want {
+				t.Errorf("Target(%v) = %d, want %d", tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadShapeValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		shape LoadShape
+	}{
+		{name: "unknown type", shape: LoadShape{Type: "square"}},
+		{name: "step without interval", shape: LoadShape{Type: ShapeStep, StepClients: 10}},
+		{name: "spike above the peak", shape: LoadShape{Type: ShapeSpike, BaseClients: 200, SpikeHold: time.Second}},
+		{name: "sine without period", shape: LoadShape{Type: ShapeSine}},
+		{name: "negative churn", shape: LoadShape{Type: ShapeSoak, ChurnPercent: -1}},
+		{name: "adaptive without latency", shape: LoadShape{Type: ShapeAdaptive, StepClients: 5, StepInterval: time.Second}},
+		{name: "adaptive backing off everything", shape: LoadShape{Type: ShapeAdaptive, StepClients: 5, StepInterval: time.Second, MaxLoginP95: time.Second, BackoffPercent: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.shape.Validate(100); err == nil {
+				t.Error("Validate() error = nil")
+			}
+		})
+	}
+
+	if churn := (LoadShape{Type: ShapeSoak, ChurnPercent: 10}).Churn(100, 30*time.Second); churn != 5 {
+		t.Errorf("Churn() = %v, want 5", churn)
+	}
+}
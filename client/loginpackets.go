@@ -0,0 +1,240 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/frostwind/l2go/packets"
+)
+
+// Login server packets are limited to fixed size ASCII credentials
+const loginCredentialSize = 14
+
+// newRequestAuthLoginPayload builds the RequestAuthLogin payload
+func newRequestAuthLoginPayload(username, password string) ([]byte, error) {
+	if len(username) > loginCredentialSize {
+		return nil, fmt.Errorf("%w: username longer than %d bytes", ErrInvalidCredentials, loginCredentialSize)
+	}
+	if len(password) > loginCredentialSize {
+		return nil, fmt.Errorf("%w: password longer than %d bytes", ErrInvalidCredentials, loginCredentialSize)
+	}
+
+	payload := make([]byte, loginCredentialSize*2)
+	copy(payload, username)
+	copy(payload[loginCredentialSize:], password)
+
+	return payload, nil
+}
+
+// newRequestGGAuthPayload builds the RequestGGAuth payload, the GameGuard data left empty
+func newRequestGGAuthPayload(sessionID []byte) []byte {
+	buffer := packets.NewBuffer()
+	buffer.Write(sessionID[:4])
+	buffer.Write(make([]byte, 16))
+
+	return buffer.Bytes()
+}
+
+// parseGGAuthPayload extracts the session id the login server sent back in GGAuth
+func parseGGAuthPayload(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%w: GGAuth packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	response := make([]byte, 4)
+	copy(response, data[:4])
+
+	return response, nil
+}
+
+// newRequestServerListPayload builds the RequestServerList payload
+func newRequestServerListPayload(sessionKey []byte) []byte {
+	buffer := packets.NewBuffer()
+	buffer.Write(sessionKey[:8])
+
+	return buffer.Bytes()
+}
+
+// newRequestPlayPayload builds the RequestPlay payload
+func newRequestPlayPayload(sessionKey []byte, serverID int) []byte {
+	buffer := packets.NewBuffer()
+	buffer.Write(sessionKey[:8])
+	buffer.WriteUInt8(uint8(serverID))
+
+	return buffer.Bytes()
+}
+
+// parseInitPayload extracts the session id and protocol revision from the Init packet
+func parseInitPayload(data []byte) (sessionID []byte, protocolVersion uint32, err error) {
+	if len(data) < 8 {
+		return nil, 0, fmt.Errorf("%w: Init packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	sessionID = reader.ReadBytes(4)
+	protocolVersion = reader.ReadUInt32()
+
+	return sessionID, protocolVersion, nil
+}
+
+// LoginOk is sent by the login server once the credentials are accepted
+type LoginOk struct {
+	SessionKey1 []byte `l2:"bytes:4"` // First half of the login session key
+	SessionKey2 []byte `l2:"bytes:4"` // Second half of the login session key
+	Unknown1    uint32 `l2:"u32"`
+	Unknown2    uint32 `l2:"u32"`
+	Unknown3    uint32 `l2:"u32"` // Always 0x3ea
+	Unknown4    uint32 `l2:"u32"`
+	Unknown5    uint32 `l2:"u32"`
+	Unknown6    uint32 `l2:"u32"`
+}
+
+// SessionKey returns both halves of the login session key, as sent back with RequestServerList and RequestPlay
+func (p LoginOk) SessionKey() []byte {
+	return append(append(make([]byte, 0, 8), p.SessionKey1...), p.SessionKey2...)
+}
+
+// parseLoginOkPayload decodes the LoginOk packet, only the session key is mandatory
+func parseLoginOkPayload(data []byte) (*LoginOk, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: LoginOk packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	var loginOk LoginOk
+	if err := packets.Unmarshal(data, &loginOk); err != nil && !errors.Is(err, packets.ErrInsufficientData) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+
+	return &loginOk, nil
+}
+
+// LoginFailReason is the reason code sent in LoginFail and PlayFail
+type LoginFailReason uint32
+
+const (
+	LoginFailSystemError     LoginFailReason = 0x01
+	LoginFailPassWrong       LoginFailReason = 0x02
+	LoginFailUserOrPassWrong LoginFailReason = 0x03
+	LoginFailAccessFailed    LoginFailReason = 0x04
+	LoginFailInfoWrong       LoginFailReason = 0x05
+	LoginFailAccountInUse    LoginFailReason = 0x07
+	LoginFailTooManyPlayers  LoginFailReason = 0x0f
+	LoginFailMaintenance     LoginFailReason = 0x10
+	LoginFailChangeTempPass  LoginFailReason = 0x11
+	LoginFailExpired         LoginFailReason = 0x12
+	LoginFailNoTimeLeft      LoginFailReason = 0x13
+)
+
+var loginFailReasonNames = map[LoginFailReason]string{
+	LoginFailSystemError:     "system error",
+	LoginFailPassWrong:       "wrong password",
+	LoginFailUserOrPassWrong: "wrong user or password",
+	LoginFailAccessFailed:    "access failed",
+	LoginFailInfoWrong:       "wrong account information",
+	LoginFailAccountInUse:    "account in use",
+	LoginFailTooManyPlayers:  "too many players",
+	LoginFailMaintenance:     "maintenance",
+	LoginFailChangeTempPass:  "temporary password to change",
+	LoginFailExpired:         "account expired",
+	LoginFailNoTimeLeft:      "no game time left",
+}
+
+// loginFailErrors maps the reasons onto the errors callers can branch on,
+// the other reasons are only reported as ErrAuthenticationFailed
+var loginFailErrors = map[LoginFailReason]error{
+	LoginFailSystemError:     ErrInternalError,
+	LoginFailPassWrong:       ErrInvalidCredentials,
+	LoginFailUserOrPassWrong: ErrInvalidCredentials,
+	LoginFailAccessFailed:    ErrAccountBanned,
+	LoginFailInfoWrong:       ErrAccountNotFound,
+	LoginFailAccountInUse:    ErrAccountInUse,
+	LoginFailTooManyPlayers:  ErrServerFull,
+	LoginFailMaintenance:     ErrServerMaintenance,
+	LoginFailExpired:         ErrAccountExpired,
+	LoginFailNoTimeLeft:      ErrAccountExpired,
+}
+
+func (r LoginFailReason) String() string {
+	if name, ok := loginFailReasonNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("reason %#x", uint32(r))
+}
+
+// Err returns the error matching the reason, it always wraps ErrAuthenticationFailed
+func (r LoginFailReason) Err() error {
+	if err, ok := loginFailErrors[r]; ok {
+		return fmt.Errorf("%w: %w (%s)", ErrAuthenticationFailed, err, r)
+	}
+	return fmt.Errorf("%w: %s", ErrAuthenticationFailed, r)
+}
+
+// LoginFail is sent by the login server when it refuses a request, PlayFail shares its layout
+type LoginFail struct {
+	Reason LoginFailReason `l2:"u32"`
+}
+
+// parseLoginFailPayload decodes the LoginFail and PlayFail packets
+func parseLoginFailPayload(data []byte) (*LoginFail, error) {
+	var loginFail LoginFail
+	if err := packets.Unmarshal(data, &loginFail); err != nil {
+		return nil, fmt.Errorf("%w: reason of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	return &loginFail, nil
+}
+
+// parseSessionKeyPayload extracts the 8 bytes key sent in PlayOk
+func parseSessionKeyPayload(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: session key of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	key := make([]byte, 8)
+	copy(key, data[:8])
+
+	return key, nil
+}
+
+// serverListEntrySize is the size of each server entry in the ServerList packet
+const serverListEntrySize = 20
+
+// parseServerListPayload decodes a page of the ServerList packet, telling whether another page follows
+func parseServerListPayload(data []byte) ([]ServerInfo, bool, error) {
+	if len(data) < 2 {
+		return nil, false, fmt.Errorf("%w: ServerList packet of %d bytes", ErrPacketTooSmall, len(data))
+	}
+
+	reader := packets.NewReader(data)
+	count := int(reader.ReadUInt8())
+	more := morePages(reader.ReadUInt8())
+
+	if len(data) < 2+count*serverListEntrySize {
+		return nil, false, fmt.Errorf("%w: ServerList announces %d servers in %d bytes", ErrInvalidPacket, count, len(data))
+	}
+
+	servers := make([]ServerInfo, 0, count)
+	for i := 0; i < count; i++ {
+		id := reader.ReadUInt8()
+		ip := net.IP(reader.ReadBytes(4))
+		port := reader.ReadUInt32()
+		reader.ReadUInt8() // Age limit
+		reader.ReadUInt8() // Is pvp allowed?
+		online := reader.ReadUInt16()
+		maxPlayers := reader.ReadUInt16()
+		status := reader.ReadUInt8()
+		reader.ReadUInt32() // Clock
+
+		servers = append(servers, ServerInfo{
+			ID:         int(id),
+			Host:       ip.String(),
+			Port:       int(port),
+			Status:     int(status),
+			Population: int(online),
+			MaxPlayers: int(maxPlayers),
+		})
+	}
+
+	return servers, more, nil
+}
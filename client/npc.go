@@ -0,0 +1,145 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// Target selects an object of the world, as a first click on it would
+func (c *Client) Target(objectID int) error {
+	if err := c.requireInGame("select a target"); err != nil {
+		return err
+	}
+
+	session := c.sessions.GameSession()
+	if err := c.sendGame(opcodes.GameClientAction, newActionPayload(objectID, session.SelectedChar.Location, false)); err != nil {
+		return c.fail(err)
+	}
+
+	_, data, err := c.receiveGame(opcodes.GameServerMyTargetSelected)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	session.GameState.TargetID, err = parseMyTargetSelectedPayload(data)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	c.touch()
+	return nil
+}
+
+// CancelTarget drops the current target
+func (c *Client) CancelTarget() error {
+	if err := c.requireInGame("cancel the target"); err != nil {
+		return err
+	}
+
+	session := c.sessions.GameSession()
+	if session.GameState.TargetID == 0 {
+		return nil
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestTargetCanceld, newTargetCancelPayload()); err != nil {
+		return c.fail(err)
+	}
+
+	if _, _, err := c.receiveGame(opcodes.GameServerTargetUnselected); err != nil {
+		return c.fail(err)
+	}
+
+	session.GameState.TargetID = 0
+	session.Dialog = nil
+
+	c.touch()
+	return nil
+}
+
+// Interact talks to an NPC, targeting it first if needed, and returns the dialog it opens
+func (c *Client) Interact(objectID int) (*Dialog, error) {
+	if err := c.requireInGame("talk to an NPC"); err != nil {
+		return nil, err
+	}
+
+	session := c.sessions.GameSession()
+	if session.GameState.TargetID != objectID {
+		if err := c.Target(objectID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Clicking the current target again opens its dialog
+	if err := c.sendGame(opcodes.GameClientAction, newActionPayload(objectID, session.SelectedChar.Location, false)); err != nil {
+		return nil, c.fail(err)
+	}
+
+	return c.receiveDialog()
+}
+
+// SendBypass sends a bypass command to the game server without waiting for an answer,
+// not every command opens a dialog
+func (c *Client) SendBypass(command string) error {
+	if err := c.requireInGame("send a bypass"); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestBypassToServer, newBypassPayload(command)); err != nil {
+		return c.fail(err)
+	}
+
+	c.touch()
+	return nil
+}
+
+// ChooseDialogOption answers the open dialog with the option at the given position
+// and returns the dialog the NPC answers with
+func (c *Client) ChooseDialogOption(index int) (*Dialog, error) {
+	dialog := c.sessions.GameSession().Dialog
+	if dialog == nil {
+		return nil, ErrNoDialog
+	}
+
+	if index < 0 || index >= len(dialog.Links) {
+		return nil, fmt.Errorf("%w: option %d out of %d", ErrDialogOptionNotFound, index, len(dialog.Links))
+	}
+
+	if err := c.SendBypass(dialog.Links[index].Command); err != nil {
+		return nil, err
+	}
+
+	return c.receiveDialog()
+}
+
+// receiveDialog waits for the next NpcHtmlMessage and keeps it as the open dialog
+func (c *Client) receiveDialog() (*Dialog, error) {
+	_, data, err := c.receiveGame(opcodes.GameServerNpcHtmlMessage)
+	if err != nil {
+		return nil, c.fail(err)
+	}
+
+	dialog, err := parseNpcHtmlMessagePayload(data)
+	if err != nil {
+		return nil, c.fail(err)
+	}
+
+	c.sessions.GameSession().Dialog = dialog
+
+	c.touch()
+	return dialog, nil
+}
+
+// Option returns the position of the first option whose text contains the given text, ignoring the case
+func (d *Dialog) Option(text string) (int, error) {
+	text = strings.ToLower(text)
+
+	for i, link := range d.Links {
+		if strings.Contains(strings.ToLower(link.Text), text) {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrDialogOptionNotFound, text)
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/protocol"
+)
+
+// announcesNullCrypto reports whether the bytes following the fields of an Init or CryptInit packet
+// announce the null crypto debug mode
+func announcesNullCrypto(trailer []byte) bool {
+	return len(trailer) >= 4 && binary.LittleEndian.Uint32(trailer) == opcodes.NullCryptoMarker
+}
+
+// negotiateNullCrypto leaves a phase in clear when its server announced the null crypto debug mode. Both
+// ends must agree, a client expecting encrypted packets being unable to read plain ones and the other way
+// around, so a mismatch fails the connection with an error telling which end to reconfigure.
+func (c *Client) negotiateNullCrypto(phase protocol.Phase, announced bool) error {
+	switch {
+	case announced && !c.config.NullCrypto:
+		return fmt.Errorf("%w: the %s server sends its packets in clear, which the client isn't configured for", ErrNullCryptoMismatch, phase)
+	case !announced && c.config.NullCrypto:
+		return fmt.Errorf("%w: the %s server encrypts its packets, the client expecting them in clear", ErrNullCryptoMismatch, phase)
+	case announced:
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		c.handler.SetCipher(phase, protocol.NullCipher{})
+	}
+	return nil
+}
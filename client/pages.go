@@ -0,0 +1,63 @@
+package client
+
+import "github.com/frostwind/l2go/packets"
+
+// reassemble decodes a list the server split across several packets into a single result: from the
+// payload of the first page, it receives the next ones with next until the last, folding what parse
+// decodes of each into the first with merge. parse tells whether another page follows.
+func reassemble[T any](first []byte, next func() ([]byte, error), parse func([]byte) (T, bool, error), merge func(T, T) T) (T, error) {
+	result, more, err := parse(first)
+	for err == nil && more {
+		var data []byte
+		if data, err = next(); err != nil {
+			break
+		}
+
+		var page T
+		if page, more, err = parse(data); err == nil {
+			result = merge(result, page)
+		}
+	}
+	return result, err
+}
+
+// nextGamePage returns how to receive the next page of a list sent by the game server
+func (c *Client) nextGamePage(opcode byte) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		_, data, err := c.receiveGame(opcode)
+		return data, err
+	}
+}
+
+// whole adapts the parser of a list the server always sends in a single packet
+func whole[T any](parse func([]byte) (T, error)) func([]byte) (T, bool, error) {
+	return func(data []byte) (T, bool, error) {
+		result, err := parse(data)
+		return result, false, err
+	}
+}
+
+// morePages reads the flag of a page, telling whether another one follows
+func morePages(flag byte) bool {
+	return flag == packets.MorePages
+}
+
+// appendPage appends the entries of a page of a list to the previous pages
+func appendPage[T any](list, page []T) []T {
+	return append(list, page...)
+}
+
+// mergeCounts adds the counts of a page of items by id to the ones of the previous pages
+func mergeCounts(counts, page map[int]uint64) map[int]uint64 {
+	for itemID, count := range page {
+		counts[itemID] += count
+	}
+	return counts
+}
+
+// mergeTradeLists appends the items of a page of a trade list to the previous pages, which the
+// header fields repeated by every page are taken from
+func mergeTradeLists(list, page *TradeList) *TradeList {
+	list.Items = append(list.Items, page.Items...)
+	return list
+}
@@ -0,0 +1,66 @@
+//go:build linux
+
+package client
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reactorSupported tells whether the reactor can wait on the sockets of the clients
+const reactorSupported = true
+
+// poller waits on sockets with epoll, each of them reported once until it is rearmed
+type poller struct {
+	fd     int
+	events []syscall.EpollEvent
+}
+
+// pollerEvents is how many sockets a wait reports at most
+const pollerEvents = 256
+
+func newPoller() (*poller, error) {
+	fd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	return &poller{fd: fd, events: make([]syscall.EpollEvent, pollerEvents)}, nil
+}
+
+// add waits on a socket, reporting it under an id
+func (p *poller) add(fd int, id uint32) error {
+	event := syscall.EpollEvent{Events: syscall.EPOLLIN | syscall.EPOLLRDHUP | syscall.EPOLLONESHOT, Fd: int32(id)}
+	return syscall.EpollCtl(p.fd, syscall.EPOLL_CTL_ADD, fd, &event)
+}
+
+// rearm waits on a socket again once it was reported
+func (p *poller) rearm(fd int, id uint32) error {
+	event := syscall.EpollEvent{Events: syscall.EPOLLIN | syscall.EPOLLRDHUP | syscall.EPOLLONESHOT, Fd: int32(id)}
+	return syscall.EpollCtl(p.fd, syscall.EPOLL_CTL_MOD, fd, &event)
+}
+
+// remove stops waiting on a socket
+func (p *poller) remove(fd int) {
+	syscall.EpollCtl(p.fd, syscall.EPOLL_CTL_DEL, fd, nil)
+}
+
+// wait returns the ids of the sockets with data, waiting for one for up to timeout milliseconds
+func (p *poller) wait(timeout int) ([]uint32, error) {
+	n, err := syscall.EpollWait(p.fd, p.events, timeout)
+	if errors.Is(err, syscall.EINTR) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint32, n)
+	for i, event := range p.events[:n] {
+		ids[i] = uint32(event.Fd)
+	}
+	return ids, nil
+}
+
+func (p *poller) close() error {
+	return syscall.Close(p.fd)
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package client
+
+// reactorSupported tells whether the reactor can wait on the sockets of the clients
+const reactorSupported = false
+
+// poller is missing on this system, the configurations asking for the reactor being refused
+type poller struct{}
+
+func newPoller() (*poller, error) {
+	return nil, ErrReactorUnsupported
+}
+
+func (p *poller) add(fd int, id uint32) error        { return ErrReactorUnsupported }
+func (p *poller) rearm(fd int, id uint32) error      { return ErrReactorUnsupported }
+func (p *poller) remove(fd int)                      {}
+func (p *poller) wait(timeout int) ([]uint32, error) { return nil, ErrReactorUnsupported }
+func (p *poller) close() error                       { return nil }
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// QuestList asks the game server for the quests the character is doing
+func (c *Client) QuestList() ([]Quest, error) {
+	if err := c.requireInGame("list the quests"); err != nil {
+		return nil, err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestQuestList, nil); err != nil {
+		return nil, c.fail(err)
+	}
+
+	quests, err := c.receiveQuestList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.touch()
+	return quests, nil
+}
+
+// TalkQuest asks an NPC about a quest, targeting it first if needed: the NPC giving the quest starts
+// it, the NPC of its current step moves it on. It returns the dialog telling where the quest is,
+// the quest list following it being kept in the session.
+func (c *Client) TalkQuest(npcObjectID, questID int) (*Dialog, error) {
+	if err := c.requireInGame("talk about a quest"); err != nil {
+		return nil, err
+	}
+
+	if c.sessions.GameSession().GameState.TargetID != npcObjectID {
+		if err := c.Target(npcObjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.SendBypass(fmt.Sprintf("npc_%d_Quest %d", npcObjectID, questID)); err != nil {
+		return nil, err
+	}
+
+	dialog, err := c.receiveDialog()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.receiveQuestList(nil); err != nil {
+		return nil, err
+	}
+	return dialog, nil
+}
+
+// AcceptQuest starts a quest with the NPC giving it, or returns ErrQuestRefused along with what
+// the NPC said when the character can't do it
+func (c *Client) AcceptQuest(npcObjectID, questID int) error {
+	dialog, err := c.TalkQuest(npcObjectID, questID)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := c.Quest(questID); !ok {
+		return fmt.Errorf("%w: %s", ErrQuestRefused, dialog.HTML)
+	}
+	return nil
+}
+
+// AbortQuest abandons a quest, returning once the quest list no longer holds it
+func (c *Client) AbortQuest(questID int) error {
+	if err := c.requireInGame("abort a quest"); err != nil {
+		return err
+	}
+
+	if err := c.sendGame(opcodes.GameClientRequestQuestAbort, newQuestIDPayload(questID)); err != nil {
+		return c.fail(err)
+	}
+
+	_, err := c.receiveQuestList(func(quests []Quest) bool {
+		return !hasQuest(quests, questID)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.touch()
+	return nil
+}
+
+// Quest returns the quest of the last quest list with the given id, false once completed or never started
+func (c *Client) Quest(questID int) (Quest, bool) {
+	for _, quest := range c.sessions.GameSession().Quests {
+		if quest.ID == questID {
+			return quest, true
+		}
+	}
+	return Quest{}, false
+}
+
+// receiveQuestList waits for a quest list of the character matching a condition, if any, keeping
+// the lists in the session. The game server also sends the list when a kill moves a quest on
+func (c *Client) receiveQuestList(until func([]Quest) bool) ([]Quest, error) {
+	for {
+		_, data, err := c.receiveGame(opcodes.GameServerQuestList)
+		if err != nil {
+			return nil, c.fail(err)
+		}
+
+		quests, err := parseQuestListPayload(data)
+		if err != nil {
+			return nil, c.fail(err)
+		}
+
+		c.sessions.GameSession().Quests = quests
+		if until == nil || until(quests) {
+			return quests, nil
+		}
+	}
+}
+
+func hasQuest(quests []Quest, questID int) bool {
+	for _, quest := range quests {
+		if quest.ID == questID {
+			return true
+		}
+	}
+	return false
+}
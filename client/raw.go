@@ -0,0 +1,52 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// RawPacket is a packet exchanged with a server, its payload decrypted and without its opcode
+type RawPacket struct {
+	Protocol  opcodes.Protocol  // Login or Game
+	Direction opcodes.Direction // Sent by the client or by the server
+	Opcode    byte
+	Payload   []byte
+}
+
+// SendRawLogin sends a packet the client has no typed support for to the login server, encrypted and
+// checksummed like any other. The answers are only read by the next exchange of the client, OnRawPacket
+// seeing them even when the client skips them.
+func (c *Client) SendRawLogin(opcode byte, payload []byte) error {
+	return c.sendLogin(opcode, payload)
+}
+
+// SendRawGame sends a packet the client has no typed support for to the game server, as SendRawLogin.
+// Spectators refuse to.
+func (c *Client) SendRawGame(opcode byte, payload []byte) error {
+	if c.config.Spectator {
+		return fmt.Errorf("%w: cannot send the game packet %s", ErrSpectator, opcodes.Name(opcodes.Game, opcodes.ClientToServer, opcode))
+	}
+	return c.sendGame(opcode, payload)
+}
+
+// OnRawPacket registers a callback run for every packet the client sends or reads, the ones it skips included.
+// It runs on the goroutine exchanging the packet, which waits for it.
+func (c *Client) OnRawPacket(callback func(packet RawPacket)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rawCallbacks = append(c.rawCallbacks, callback)
+}
+
+// rawPacket runs the OnRawPacket callbacks, each getting its own copy of the payload
+func (c *Client) rawPacket(protocol opcodes.Protocol, direction opcodes.Direction, opcode byte, payload []byte) {
+	c.mu.RLock()
+	callbacks := c.rawCallbacks
+	c.mu.RUnlock()
+
+	for _, callback := range callbacks {
+		callback(RawPacket{Protocol: protocol, Direction: direction, Opcode: opcode, Payload: bytes.Clone(payload)})
+	}
+}
@@ -0,0 +1,218 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Backends of the game connections of the spectators, waiting for the packets the game server sends them unprompted
+const (
+	BackendGoroutine = "goroutine" // A goroutine blocks on every connection
+	BackendReactor   = "reactor"   // A reactor multiplexes the connections on a few goroutines
+)
+
+// DefaultReactorWorkers is how many goroutines of a reactor read the packets when unset
+const DefaultReactorWorkers = 4
+
+// reactorWait is how long the poller of a reactor waits for a socket at once, in milliseconds,
+// before checking whether the reactor was closed
+const reactorWait = 100
+
+// validateBackend checks the connection backend of a configuration, and that the system supports it
+func validateBackend(backend string, workers int) error {
+	switch backend {
+	case "", BackendGoroutine:
+	case BackendReactor:
+		if !reactorSupported {
+			return fmt.Errorf("%w: the reactor isn't supported on this system", ErrReactorUnsupported)
+		}
+	default:
+		return fmt.Errorf("connectionBackend must be one of %s and %s, got %q", BackendGoroutine, BackendReactor, backend)
+	}
+	if workers < 0 {
+		return fmt.Errorf("reactorWorkers must be non-negative, got %d", workers)
+	}
+	return nil
+}
+
+// Reactor multiplexes many idle sockets on a few goroutines: a poller waits for any of them to have
+// data to read, and a handful of workers read it, instead of a goroutine blocked on every socket. A
+// socket is handed to a single worker at a time, so its packets are read in order. It only reads the
+// connections watched with GameConnection.Watch, the ones of the spectators, the requests of the clients
+// being answered on the goroutine which sent them.
+type Reactor struct {
+	poller  *poller
+	ready   chan *watch
+	watches map[uint32]*watch // By id
+	nextID  uint32
+	closed  atomic.Bool
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// watch is a socket a reactor waits on
+type watch struct {
+	id      uint32
+	fd      int
+	handle  func() bool
+	stopped bool
+}
+
+// NewReactor starts a reactor reading the sockets with workers goroutines, DefaultReactorWorkers when 0.
+// It returns ErrReactorUnsupported on the systems without one.
+func NewReactor(workers int) (*Reactor, error) {
+	p, err := newPoller()
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = DefaultReactorWorkers
+	}
+
+	r := &Reactor{
+		poller:  p,
+		ready:   make(chan *watch, workers),
+		watches: make(map[uint32]*watch),
+	}
+
+	r.wg.Add(workers)
+	for range workers {
+		go r.work()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.poll()
+	}()
+	go func() {
+		// The workers stop once the poller can't hand them any socket anymore
+		<-done
+		close(r.ready)
+	}()
+	return r, nil
+}
+
+// Watch has the reactor call handle from one of its workers whenever conn has data to read, until handle
+// returns false or the returned function stops the watch. Stopping it before closing conn is required,
+// the system reusing the descriptors of the closed sockets.
+func (r *Reactor) Watch(conn net.Conn, handle func() bool) (func(), error) {
+	if r.closed.Load() {
+		return nil, ErrReactorClosed
+	}
+
+	fd, err := descriptor(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.nextID++
+	w := &watch{id: r.nextID, fd: fd, handle: handle}
+	r.watches[w.id] = w
+	r.mu.Unlock()
+
+	if err := r.poller.add(fd, w.id); err != nil {
+		r.stop(w)
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	return func() { r.stop(w) }, nil
+}
+
+// Watched returns how many sockets the reactor waits on
+func (r *Reactor) Watched() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.watches)
+}
+
+// Close stops the reactor, waiting for its workers to be done with their sockets. The sockets are left open.
+func (r *Reactor) Close() error {
+	if !r.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	r.wg.Wait()
+
+	r.mu.Lock()
+	clear(r.watches)
+	r.mu.Unlock()
+	return r.poller.close()
+}
+
+// poll hands the sockets with data to the workers until the reactor is closed
+func (r *Reactor) poll() {
+	for !r.closed.Load() {
+		ids, err := r.poller.wait(reactorWait)
+		if err != nil {
+			fmt.Printf("Reactor stopped polling: %v\n", err)
+			return
+		}
+
+		for _, id := range ids {
+			r.mu.Lock()
+			w, ok := r.watches[id]
+			r.mu.Unlock()
+			if ok {
+				r.ready <- w
+			}
+		}
+	}
+}
+
+// work reads the sockets with data, waiting for the next data of a socket once it is done with it
+func (r *Reactor) work() {
+	defer r.wg.Done()
+
+	for w := range r.ready {
+		if !w.handle() {
+			r.stop(w)
+			continue
+		}
+
+		r.mu.Lock()
+		if !w.stopped {
+			if err := r.poller.rearm(w.fd, w.id); err != nil {
+				w.stopped = true
+				delete(r.watches, w.id)
+			}
+		}
+		r.mu.Unlock()
+	}
+}
+
+// stop stops waiting on a socket
+func (r *Reactor) stop(w *watch) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if w.stopped {
+		return
+	}
+	w.stopped = true
+	delete(r.watches, w.id)
+	r.poller.remove(w.fd)
+}
+
+// descriptor returns the file descriptor of a connection
+func descriptor(conn net.Conn) (int, error) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return 0, fmt.Errorf("%w: %T has no file descriptor", ErrReactorUnsupported, conn)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+
+	fd := -1
+	if err := raw.Control(func(f uintptr) { fd = int(f) }); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	if fd < 0 {
+		return 0, fmt.Errorf("%w: %T has no file descriptor", ErrReactorUnsupported, conn)
+	}
+	return fd, nil
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateBackend(t *testing.T) {
+	tests := []struct {
+		backend string
+		workers int
+		wantErr bool
+	}{
+		{backend: ""},
+		{backend: BackendGoroutine},
+		{backend: BackendReactor, workers: 8, wantErr: !reactorSupported},
+		{backend: "io_uring", wantErr: true},
+		{backend: BackendGoroutine, workers: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validateBackend(tt.backend, tt.workers); (err != nil) != tt.wantErr {
+			t.Errorf("validateBackend(%q, %d) error = %v, wantErr %v", tt.backend, tt.workers, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReactor(t *testing.T) {
+	if !reactorSupported {
+		if _, err := NewReactor(1); !errors.Is(err, ErrReactorUnsupported) {
+			t.Fatalf("NewReactor() error = %v, want %v", err, ErrReactorUnsupported)
+		}
+		t.Skip("no reactor on this system")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	reactor, err := NewReactor(2)
+	if err != nil {
+		t.Fatalf("NewReactor() error = %v", err)
+	}
+	defer reactor.Close()
+
+	// Many connections are read by the two workers, every one of them in order
+	const conns, frames = 50, 5
+	servers := make([]net.Conn, conns)
+	watched := make([]*GameConnection, conns)
+	received := make([][]string, conns)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(conns * frames)
+
+	for i := range conns {
+		gc := NewGameConnection(time.Second)
+		if err := gc.Connect("127.0.0.1", listener.Addr().(*net.TCPAddr).Port); err != nil {
+			t.Fatalf("Connect() error = %v", err)
+		}
+		defer gc.Close()
+		if servers[i], err = listener.Accept(); err != nil {
+			t.Fatal(err)
+		}
+		defer servers[i].Close()
+
+		err := gc.Watch(reactor, func(data []byte, err error) bool {
+			if err != nil {
+				return false
+			}
+			mu.Lock()
+			received[i] = append(received[i], string(data))
+			mu.Unlock()
+			wg.Done()
+			return true
+		})
+		if err != nil {
+			t.Fatalf("Watch() error = %v", err)
+		}
+		watched[i] = gc
+	}
+	if err := watched[0].Watch(reactor, func([]byte, error) bool { return true }); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Watch() error = %v twice, want %v", err, ErrInvalidState)
+	}
+	if got := reactor.Watched(); got != conns {
+		t.Errorf("Watched() = %d, want %d", got, conns)
+	}
+
+	for frame := range frames {
+		for i, server := range servers {
+			if err := writeFrame(server, []byte(fmt.Sprintf("%d-%d", i, frame)), time.Second); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the reactor didn't read every packet")
+	}
+
+	mu.Lock()
+	for i := range conns {
+		for frame := range frames {
+			if want := fmt.Sprintf("%d-%d", i, frame); len(received[i]) != frames || received[i][frame] != want {
+				t.Fatalf("connection %d received %v", i, received[i])
+			}
+		}
+	}
+	mu.Unlock()
+
+	// Closing a connection stops its watch
+	watched[0].Close()
+	if got := reactor.Watched(); got != conns-1 {
+		t.Errorf("Watched() = %d after a close, want %d", got, conns-1)
+	}
+
+	reactor.Close()
+	if _, err := reactor.Watch(servers[1], func() bool { return true }); !errors.Is(err, ErrReactorClosed) {
+		t.Errorf("Watch() error = %v on a closed reactor, want %v", err, ErrReactorClosed)
+	}
+}
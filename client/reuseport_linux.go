@@ -0,0 +1,23 @@
+//go:build linux
+
+package client
+
+import "syscall"
+
+// reusePortSupported tells whether the sockets of the clients can set SO_REUSEPORT
+const reusePortSupported = true
+
+// soReusePort is SO_REUSEPORT, which the syscall package doesn't define on Linux
+const soReusePort = 0xf
+
+// reusePort sets SO_REUSEPORT on a socket before it is bound, so that the connections to different
+// servers can share a source port
+func reusePort(network, address string, conn syscall.RawConn) error {
+	var err error
+	if controlErr := conn.Control(func(fd uintptr) {
+		err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	}); controlErr != nil {
+		return controlErr
+	}
+	return err
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package client
+
+import "syscall"
+
+// reusePortSupported tells whether the sockets of the clients can set SO_REUSEPORT
+const reusePortSupported = false
+
+// reusePort is never called, the configurations asking for SO_REUSEPORT being refused
+func reusePort(network, address string, conn syscall.RawConn) error {
+	return nil
+}
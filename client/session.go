@@ -0,0 +1,53 @@
+package client
+
+// NewSessionManager creates an empty session manager
+func NewSessionManager() *SessionManager {
+	return &SessionManager{}
+}
+
+// LoginSession returns the current login session, or nil before authentication
+func (sm *SessionManager) LoginSession() *LoginSession {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.loginSession
+}
+
+// SetLoginSession replaces the current login session
+func (sm *SessionManager) SetLoginSession(session *LoginSession) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.loginSession = session
+}
+
+// GameSession returns the current game session, or nil before entering the game server
+func (sm *SessionManager) GameSession() *GameSession {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.gameSession
+}
+
+// SetGameSession replaces the current game session
+func (sm *SessionManager) SetGameSession(session *GameSession) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.gameSession = session
+}
+
+// Reset wipes and drops both sessions
+func (sm *SessionManager) Reset() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.loginSession != nil {
+		sm.loginSession.Wipe()
+	}
+	sm.loginSession = nil
+	sm.gameSession = nil
+}
+
+// Wipe zeroes the session and play keys
+func (ls *LoginSession) Wipe() {
+	clear(ls.SessionID)
+	clear(ls.PlayKey)
+	ls.SessionID = nil
+	ls.PlayKey = nil
+}
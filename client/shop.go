@@ -0,0 +1,135 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// OpenBuyList asks a merchant for the goods it sells, targeting it first if needed
+func (c *Client) OpenBuyList(npcObjectID int) (*TradeList, error) {
+	return c.openTradeList(npcObjectID, "Buy", opcodes.GameServerBuyList, whole(parseBuyListPayload), ErrTradeRefused)
+}
+
+// OpenSellList asks a merchant for the items of the character it buys back, targeting it first if needed
+func (c *Client) OpenSellList(npcObjectID int) (*TradeList, error) {
+	return c.openTradeList(npcObjectID, "Sell", opcodes.GameServerSellList, whole(parseSellListPayload), ErrTradeRefused)
+}
+
+// BuyItem buys items from a merchant, opening its buy list to learn its id. It returns once
+// the inventory holds them, or ErrTradeRefused with the reason given by the game server.
+func (c *Client) BuyItem(npcObjectID, itemID int, count uint64) error {
+	list, err := c.OpenBuyList(npcObjectID)
+	if err != nil {
+		return err
+	}
+	if _, ok := list.Item(itemID); !ok {
+		return fmt.Errorf("%w: the merchant doesn't sell the item %d", ErrTradeRefused, itemID)
+	}
+
+	return c.moveItems(opcodes.GameClientRequestBuyItem, list.ID, itemID, count, ErrTradeRefused)
+}
+
+// SellItem sells items of the character to a merchant, opening its sell list to learn its id.
+// It returns once the inventory was updated, or ErrTradeRefused with the reason given by the game server.
+func (c *Client) SellItem(npcObjectID, itemID int, count uint64) error {
+	list, err := c.OpenSellList(npcObjectID)
+	if err != nil {
+		return err
+	}
+	if _, ok := list.Item(itemID); !ok {
+		return fmt.Errorf("%w: the merchant doesn't buy the item %d", ErrTradeRefused, itemID)
+	}
+
+	return c.moveItems(opcodes.GameClientRequestSellItem, list.ID, itemID, count, ErrTradeRefused)
+}
+
+// Inventory returns the counts of the items of the character by id, the adena included,
+// as of the last item list the game server sent
+func (c *Client) Inventory() map[int]uint64 {
+	return c.sessions.GameSession().Inventory
+}
+
+// openTradeList opens a list of a merchant or a warehouse keeper with a bypass and waits for all its
+// pages, or for the system message telling why it was refused, returned wrapped in refused
+func (c *Client) openTradeList(npcObjectID int, command string, opcode byte, parse func([]byte) (*TradeList, bool, error), refused error) (*TradeList, error) {
+	if err := c.requireInGame("open a trade list"); err != nil {
+		return nil, err
+	}
+
+	if c.sessions.GameSession().GameState.TargetID != npcObjectID {
+		if err := c.Target(npcObjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.SendBypass(fmt.Sprintf("npc_%d_%s", npcObjectID, command)); err != nil {
+		return nil, err
+	}
+
+	data, err := c.receiveItems(opcode, refused)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := reassemble(data, c.nextGamePage(opcode), parse, mergeTradeLists)
+	if err != nil {
+		return nil, c.fail(err)
+	}
+
+	c.touch()
+	return list, nil
+}
+
+// moveItems sends a trade or warehouse request, then waits for the item list it results in or
+// the system message telling why it was refused, returned wrapped in refused
+func (c *Client) moveItems(requestOpcode byte, listID, itemID int, count uint64, refused error) error {
+	if err := c.sendGame(requestOpcode, newTradePayload(listID, itemID, count)); err != nil {
+		return c.fail(err)
+	}
+
+	data, err := c.receiveItems(opcodes.GameServerItemList, refused)
+	if err != nil {
+		return err
+	}
+
+	items, err := reassemble(data, c.nextGamePage(opcodes.GameServerItemList), parseItemListPayload, mergeCounts)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	c.sessions.GameSession().Inventory = items
+	c.touch()
+	return nil
+}
+
+// receiveItems waits for a packet listing items, or for a system message refusing to move them
+func (c *Client) receiveItems(expected byte, refused error) ([]byte, error) {
+	for {
+		opcode, data, err := c.receiveGame(expected, opcodes.GameServerSystemMessage)
+		if err != nil {
+			return nil, c.fail(err)
+		}
+		if opcode == expected {
+			return data, nil
+		}
+
+		messageID, _, err := parseSystemMessagePayload(data)
+		if err != nil {
+			return nil, c.fail(err)
+		}
+		if reason, ok := itemRefusals[messageID]; ok {
+			return nil, fmt.Errorf("%w: %s", refused, reason)
+		}
+	}
+}
+
+// itemRefusals are the system messages refusing to move items, with the reason they give
+var itemRefusals = map[uint32]string{
+	SystemMessageInventoryFull:  "inventory full",
+	SystemMessageNotClanMember:  "not a clan member",
+	SystemMessageNotEnoughAdena: "not enough adena",
+	SystemMessageIncorrectCount: "incorrect item count",
+	SystemMessageWeightLimit:    "weight limit exceeded",
+	SystemMessageWarehouseFull:  "warehouse full",
+}
@@ -0,0 +1,40 @@
+package client
+
+import "fmt"
+
+// Metrics of a load test run an SLO can bound
+const (
+	SLOLoginP50          = "login_p50"           // Median login time, in milliseconds
+	SLOLoginP95          = "login_p95"           // 95th percentile login time, in milliseconds
+	SLOLoginP99          = "login_p99"           // 99th percentile login time, in milliseconds
+	SLOErrorRate         = "error_rate"          // Failed connections, in percent of the attempts
+	SLODisconnects       = "disconnects"         // Clients dropped by the servers
+	SLOSteadyDisconnects = "steady_disconnects"  // Clients dropped by the servers while the population was steady
+	SLOPeakHeap          = "peak_heap_mb"        // Highest heap in use of the process, in MiB
+	SLOClientFootprint   = "client_footprint_kb" // Approximate heap taken by a client, in KiB
+)
+
+// SLO is a threshold a load test run must stay under to pass
+type SLO struct {
+	Metric string  `json:"metric"` // One of the SLO metrics
+	Max    float64 `json:"max"`    // Highest passing value, in the unit of the metric
+}
+
+// String returns the SLO as it is printed in reports
+func (s SLO) String() string {
+	return fmt.Sprintf("%s <= %g", s.Metric, s.Max)
+}
+
+// Validate validates the SLO
+func (s SLO) Validate() error {
+	switch s.Metric {
+	case SLOLoginP50, SLOLoginP95, SLOLoginP99, SLOErrorRate, SLODisconnects, SLOSteadyDisconnects, SLOPeakHeap, SLOClientFootprint:
+	default:
+		return fmt.Errorf("invalid metric: %s, must be one of: %s, %s, %s, %s, %s, %s, %s, %s", s.Metric,
+			SLOLoginP50, SLOLoginP95, SLOLoginP99, SLOErrorRate, SLODisconnects, SLOSteadyDisconnects, SLOPeakHeap, SLOClientFootprint)
+	}
+	if s.Max < 0 {
+		return fmt.Errorf("max must be non-negative, got %v", s.Max)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// SourceAddress is a local address the connections of the clients are bound to, so that large fleets
+// spread their connections over several source IPs instead of running out of ephemeral ports on one
+type SourceAddress struct {
+	IP      string `json:"ip"`
+	MinPort int    `json:"minPort,omitempty"` // Ports bound in rotation from MinPort to MaxPort, any ephemeral port when 0
+	MaxPort int    `json:"maxPort,omitempty"`
+}
+
+// SourceAddressStats counts the connections bound to a source address
+type SourceAddressStats struct {
+	Active int64 `json:"active"` // Open right now
+	Total  int64 `json:"total"`  // Opened since the start
+	Failed int64 `json:"failed"` // Couldn't be bound or connected
+}
+
+// validateSourceAddresses checks the source addresses of a configuration, and that the system can
+// reuse their ports if asked to
+func validateSourceAddresses(addresses []SourceAddress, reusePort bool) error {
+	seen := make(map[string]bool)
+	for _, address := range addresses {
+		ip := net.ParseIP(address.IP)
+		if ip == nil {
+			return fmt.Errorf("%w: IP %q", ErrInvalidSourceAddress, address.IP)
+		}
+		if seen[ip.String()] {
+			return fmt.Errorf("%w: %s is listed twice", ErrInvalidSourceAddress, ip)
+		}
+		seen[ip.String()] = true
+
+		if address.MinPort == 0 && address.MaxPort == 0 {
+			continue
+		}
+		if address.MinPort <= 0 || address.MaxPort > 65535 || address.MinPort > address.MaxPort {
+			return fmt.Errorf("%w: port range %d-%d of %s", ErrInvalidSourceAddress, address.MinPort, address.MaxPort, ip)
+		}
+	}
+	if reusePort && !reusePortSupported {
+		return fmt.Errorf("%w: SO_REUSEPORT isn't supported on this system", ErrInvalidSourceAddress)
+	}
+	return nil
+}
+
+// SourceBinder binds the connections of clients to its source addresses in turn, counting the
+// connections of each address. The clients of a manager share one.
+type SourceBinder struct {
+	sources   []*source
+	reusePort bool
+	next      atomic.Uint64
+}
+
+// source is a source address of a binder along with its counters
+type source struct {
+	ip       net.IP
+	minPort  int
+	ports    int           // Size of the port range, 0 for any ephemeral port
+	nextPort atomic.Uint64 // Next port of the range to try
+	active   atomic.Int64
+	total    atomic.Int64
+	failed   atomic.Int64
+}
+
+// NewSourceBinder creates a binder of source addresses, which set SO_REUSEPORT on their sockets if
+// reusePort is set. The addresses are expected valid, as checked by the validation of the configurations.
+func NewSourceBinder(addresses []SourceAddress, reusePort bool) *SourceBinder {
+	binder := &SourceBinder{reusePort: reusePort}
+	for _, address := range addresses {
+		s := &source{ip: net.ParseIP(address.IP), minPort: address.MinPort}
+		if address.MinPort > 0 {
+			s.ports = address.MaxPort - address.MinPort + 1
+		}
+		binder.sources = append(binder.sources, s)
+	}
+	return binder
+}
+
+// newClientBinder creates the binder of a client configured with its own source addresses, the
+// rotation starting at an address picked by its id so that the clients spread over them
+func newClientBinder(id string, config ClientConfig) *SourceBinder {
+	if len(config.SourceAddresses) == 0 {
+		return nil
+	}
+
+	binder := NewSourceBinder(config.SourceAddresses, config.ReusePort)
+	hash := fnv.New32a()
+	hash.Write([]byte(id))
+	binder.next.Store(uint64(hash.Sum32()))
+	return binder
+}
+
+// Stats returns the counters of the connections of every source address, by IP
+func (b *SourceBinder) Stats() map[string]SourceAddressStats {
+	stats := make(map[string]SourceAddressStats, len(b.sources))
+	for _, s := range b.sources {
+		stats[s.ip.String()] = SourceAddressStats{Active: s.active.Load(), Total: s.total.Load(), Failed: s.failed.Load()}
+	}
+	return stats
+}
+
+// dial opens a TCP connection to host:port from the next source address. Within a port range, the
+// ports already bound are skipped until one is free.
+func (b *SourceBinder) dial(host string, port int, timeout time.Duration) (net.Conn, error) {
+	s := b.sources[b.next.Add(1)%uint64(len(b.sources))]
+	target := net.JoinHostPort(host, strconv.Itoa(port))
+
+	attempts := max(s.ports, 1)
+	for attempt := 1; ; attempt++ {
+		local := &net.TCPAddr{IP: s.ip}
+		if s.ports > 0 {
+			local.Port = s.minPort + int((s.nextPort.Add(1)-1)%uint64(s.ports))
+		}
+
+		dialer := net.Dialer{Timeout: timeout, LocalAddr: local}
+		if b.reusePort {
+			dialer.Control = reusePort
+		}
+		conn, err := dialer.Dial("tcp", target)
+		if err == nil {
+			s.total.Add(1)
+			s.active.Add(1)
+			return &sourceConn{Conn: conn, source: s}, nil
+		}
+
+		if errors.Is(err, syscall.EADDRINUSE) && attempt < attempts {
+			continue
+		}
+		s.failed.Add(1)
+		return nil, err
+	}
+}
+
+// sourceConn is a connection bound to a source address, counted as active until it is closed
+type sourceConn struct {
+	net.Conn
+	source *source
+	closed atomic.Bool
+}
+
+// SyscallConn returns the socket of the connection, for the reactor to wait on it
+func (c *sourceConn) SyscallConn() (syscall.RawConn, error) {
+	sc, ok := c.Conn.(syscall.Conn)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T has no file descriptor", ErrReactorUnsupported, c.Conn)
+	}
+	return sc.SyscallConn()
+}
+
+func (c *sourceConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		c.source.active.Add(-1)
+	}
+	return c.Conn.Close()
+}
+
+// SetSourceBinder has the client bind its connections with a binder shared with other clients, instead
+// of the source addresses of its configuration. A nil binder lets the system pick the source address.
+func (c *Client) SetSourceBinder(binder *SourceBinder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.source = binder
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestValidateSourceAddresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []SourceAddress
+		wantErr   bool
+	}{
+		{name: "none", addresses: nil},
+		{name: "ips and ranges", addresses: []SourceAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.2", MinPort: 20000, MaxPort: 29999}, {IP: "::1"}}},
+		{name: "invalid ip", addresses: []SourceAddress{{IP: "10.0.0"}}, wantErr: true},
+		{name: "listed twice", addresses: []SourceAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.1", MinPort: 1, MaxPort: 2}}, wantErr: true},
+		{name: "reversed range", addresses: []SourceAddress{{IP: "10.0.0.1", MinPort: 3000, MaxPort: 2000}}, wantErr: true},
+		{name: "range without a start", addresses: []SourceAddress{{IP: "10.0.0.1", MaxPort: 2000}}, wantErr: true},
+		{name: "range past the ports", addresses: []SourceAddress{{IP: "10.0.0.1", MinPort: 60000, MaxPort: 70000}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSourceAddresses(tt.addresses, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateSourceAddresses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSourceAddress) {
+				t.Errorf("validateSourceAddresses() error = %v, want %v", err, ErrInvalidSourceAddress)
+			}
+		})
+	}
+}
+
+func TestSourceBinder(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	// The whole 127.0.0.0/8 block is bound to the loopback interface
+	binder := NewSourceBinder([]SourceAddress{{IP: "127.0.0.2"}, {IP: "127.0.0.3"}}, reusePortSupported)
+	var conns []net.Conn
+	for range 4 {
+		conn, err := dial("127.0.0.1", port, time.Second, binder)
+		if err != nil {
+			t.Fatalf("dial() error = %v", err)
+		}
+		conns = append(conns, conn)
+	}
+
+	// The addresses are bound in turn
+	for i, conn := range conns {
+		local := conn.LocalAddr().(*net.TCPAddr).IP.String()
+		if other := conns[(i+1)%len(conns)].LocalAddr().(*net.TCPAddr).IP.String(); local == other {
+			t.Errorf("connections %d and %d both bound to %s", i, (i+1)%len(conns), local)
+		}
+	}
+
+	conns[0].Close()
+	conns[0].Close()
+	stats := binder.Stats()
+	closed := conns[0].LocalAddr().(*net.TCPAddr).IP.String()
+	for ip, want := range map[string]int64{"127.0.0.2": 2, "127.0.0.3": 2} {
+		active := want
+		if ip == closed {
+			active--
+		}
+		if got := stats[ip]; got.Total != want || got.Active != active || got.Failed != 0 {
+			t.Errorf("Stats()[%s] = %+v, want %d connections, %d active", ip, got, want, active)
+		}
+	}
+	for _, conn := range conns[1:] {
+		conn.Close()
+	}
+}
+
+func TestSourceBinderPortRange(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	// A port of the range is taken, the binder moves on to the next one
+	taken, err := net.Listen("tcp", "127.0.0.4:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	first := taken.Addr().(*net.TCPAddr).Port
+
+	binder := NewSourceBinder([]SourceAddress{{IP: "127.0.0.4", MinPort: first, MaxPort: first + 1}}, false)
+	conn, err := dial("127.0.0.1", listener.Addr().(*net.TCPAddr).Port, time.Second, binder)
+	if err != nil {
+		t.Skipf("the port after %d isn't free: %v", first, err)
+	}
+	defer conn.Close()
+
+	if got := conn.LocalAddr().(*net.TCPAddr).Port; got != first+1 {
+		t.Errorf("bound to port %d, want %d", got, first+1)
+	}
+}
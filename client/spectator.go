@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// TopicWorldEvent is the topic of what the spectators see of the world
+const TopicWorldEvent = "client.world"
+
+// MaxWorldEvents is how many of the latest world events a spectator keeps
+const MaxWorldEvents = 4096
+
+// Kinds of world events
+const (
+	WorldAppear  = "appear"  // A player, an NPC or an item came into sight
+	WorldVanish  = "vanish"  // An object went out of sight, or an item was picked up
+	WorldMove    = "move"    // A creature walked, teleported or had its location corrected
+	WorldChat    = "chat"    // A creature spoke
+	WorldSocial  = "social"  // A creature played an emote
+	WorldPosture = "posture" // A creature sat down, stood up, walked or ran
+	WorldStatus  = "status"  // Attributes of a creature changed
+	WorldTime    = "time"    // The time of the world was set, or the sun rose or set
+	WorldMessage = "message" // The server sent a system message
+	WorldPacket  = "packet"  // Any other packet, only named
+)
+
+// WorldEvent is a packet of the game server a spectator saw, decoded
+type WorldEvent struct {
+	ClientID   string             `json:"clientId"` // The spectator
+	Kind       string             `json:"kind"`
+	Packet     string             `json:"packet"`
+	ObjectID   int                `json:"objectId,omitempty"` // The creature or the item concerned
+	ActorID    int                `json:"actorId,omitempty"`  // The creature dropping or picking up the item
+	Name       string             `json:"name,omitempty"`
+	ItemID     int                `json:"itemId,omitempty"`
+	Count      uint64             `json:"count,omitempty"`
+	ChatType   uint32             `json:"chatType,omitempty"`
+	Text       string             `json:"text,omitempty"`
+	Value      int                `json:"value,omitempty"`      // Emote, NPC template, system message or minutes of the world
+	State      string             `json:"state,omitempty"`      // sitting, standing, walking, running, sunrise or sunset
+	Params     []string           `json:"params,omitempty"`     // Of the system message
+	Location   *CharacterLocation `json:"location,omitempty"`   // Where the object is, or is heading to
+	Attributes map[uint32]uint32  `json:"attributes,omitempty"` // Set by a status update, by attribute id
+	At         time.Time          `json:"at"`
+}
+
+func (WorldEvent) Topic() string { return TopicWorldEvent }
+
+func init() {
+	RegisterEvent(TopicWorldEvent, WorldEvent{}, "A spectator saw a packet of the game server")
+}
+
+// IsSpectator reports whether the client only watches the world
+func (c *Client) IsSpectator() bool {
+	return c.config.Spectator
+}
+
+// Spectate reads what the game server sends a spectator in the world until ctx is done, decoding every
+// packet into a WorldEvent published on the bus of the client. The client is then disconnected, ctx
+// possibly cutting a packet short. Stopping the client ends the watch as well, without error.
+// Nothing else may read from the game server meanwhile. A spectator watches from the character
+// selection as well, the game server of L2Go putting the character in the world as the account logs in.
+func (c *Client) Spectate(ctx context.Context) error {
+	if !c.config.Spectator {
+		return fmt.Errorf("%w: the client isn't a spectator", ErrInvalidState)
+	}
+	if state := c.GetState(); state != StateInGame && state != StateConnectingGame {
+		return fmt.Errorf("%w: cannot spectate while %s", ErrInvalidState, state)
+	}
+
+	for {
+		raw, err := c.gameConn.ReceiveContext(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return c.Disconnect()
+		case err != nil && !c.gameConn.IsConnected():
+			return nil
+		case err != nil:
+			return c.fail(err)
+		}
+
+		if _, _, err := c.decodeGame(raw); err != nil {
+			return c.fail(err)
+		}
+	}
+}
+
+// SpectateOn has a reactor read what the game server sends a spectator in the world, as Spectate does,
+// without blocking a goroutine on the connection. The watch ends as the client disconnects, or fails
+// when a packet can't be read or decoded, the client being then in the error state.
+func (c *Client) SpectateOn(reactor *Reactor) error {
+	if !c.config.Spectator {
+		return fmt.Errorf("%w: the client isn't a spectator", ErrInvalidState)
+	}
+	if state := c.GetState(); state != StateInGame && state != StateConnectingGame {
+		return fmt.Errorf("%w: cannot spectate while %s", ErrInvalidState, state)
+	}
+
+	gameConn := c.gameConn
+	return gameConn.Watch(reactor, func(raw []byte, err error) bool {
+		switch {
+		case err != nil && !gameConn.IsConnected():
+			return false
+		case err != nil:
+			c.fail(err)
+			return false
+		}
+
+		if _, _, err := c.decodeGame(raw); err != nil {
+			c.fail(err)
+			return false
+		}
+		return true
+	})
+}
+
+// WorldEvents returns the latest world events the spectator saw, the oldest first
+func (c *Client) WorldEvents() []WorldEvent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]WorldEvent(nil), c.witnessed...)
+}
+
+// Witnessed reports whether the spectator saw a world event matching
+func (c *Client) Witnessed(match func(event WorldEvent) bool) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, event := range c.witnessed {
+		if match(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwitnessed returns the ids of the spectators which saw no world event matching, such as the
+// observers a chat or a movement wasn't broadcast to
+func Unwitnessed(spectators []*Client, match func(event WorldEvent) bool) []string {
+	var missed []string
+	for _, spectator := range spectators {
+		if !spectator.Witnessed(match) {
+			missed = append(missed, spectator.GetID())
+		}
+	}
+	return missed
+}
+
+// witness records a packet of the game server as a world event and publishes it
+func (c *Client) witness(opcode byte, data []byte) {
+	event, err := decodeWorldEvent(opcode, data)
+	if err != nil {
+		event = WorldEvent{Kind: WorldPacket}
+	}
+	event.ClientID = c.id
+	event.Packet = opcodes.Name(opcodes.Game, opcodes.ServerToClient, opcode)
+	event.At = time.Now()
+
+	c.mu.Lock()
+	if len(c.witnessed) == MaxWorldEvents {
+		c.witnessed = append(c.witnessed[:0], c.witnessed[1:]...)
+	}
+	c.witnessed = append(c.witnessed, event)
+	c.mu.Unlock()
+
+	c.machine.publish(event)
+}
+
+// decodeWorldEvent decodes what a packet of the game server tells of the world, the
+// packets which tell nothing of it being only named
+func decodeWorldEvent(opcode byte, data []byte) (WorldEvent, error) {
+	decoder := packets.NewDecoder(data)
+	location := func() *CharacterLocation {
+		return &CharacterLocation{X: int(int32(decoder.U32())), Y: int(int32(decoder.U32())), Z: int(int32(decoder.U32()))}
+	}
+
+	var event WorldEvent
+	switch opcode {
+	case opcodes.GameServerCharInfo:
+		event = WorldEvent{Kind: WorldAppear, Location: location()}
+		decoder.U32() // Heading
+		event.ObjectID = int(decoder.U32())
+		event.Name = decoder.S()
+	case opcodes.GameServerNpcInfo:
+		event = WorldEvent{Kind: WorldAppear, ObjectID: int(decoder.U32()), Value: int(decoder.U32())}
+		decoder.U32() // Attackable
+		event.Location = location()
+		decoder.U32() // Heading
+		event.Name = decoder.S()
+	case opcodes.GameServerSpawnItem:
+		event = WorldEvent{Kind: WorldAppear, ObjectID: int(decoder.U32()), ItemID: int(decoder.U32()), Location: location()}
+		decoder.U32() // Stackable
+		event.Count = uint64(decoder.U32())
+	case opcodes.GameServerDropItem:
+		event = WorldEvent{Kind: WorldAppear, ActorID: int(decoder.U32()), ObjectID: int(decoder.U32()), ItemID: int(decoder.U32()), Location: location()}
+		decoder.U32() // Stackable
+		event.Count = uint64(decoder.U32())
+	case opcodes.GameServerGetItem:
+		event = WorldEvent{Kind: WorldVanish, ActorID: int(decoder.U32()), ObjectID: int(decoder.U32()), Location: location()}
+	case opcodes.GameServerDeleteObject:
+		event = WorldEvent{Kind: WorldVanish, ObjectID: int(decoder.U32())}
+	case opcodes.GameServerMoveToLocation, opcodes.GameServerValidateLocation, opcodes.GameServerTeleportToLocation:
+		// The destination of a walk comes first, followed by its origin
+		event = WorldEvent{Kind: WorldMove, ObjectID: int(decoder.U32()), Location: location()}
+	case opcodes.GameServerCreatureSay:
+		event = WorldEvent{Kind: WorldChat, ObjectID: int(decoder.U32()), ChatType: decoder.U32(), Name: decoder.S(), Text: decoder.S()}
+	case opcodes.GameServerSocialAction:
+		event = WorldEvent{Kind: WorldSocial, ObjectID: int(decoder.U32()), Value: int(decoder.U32())}
+	case opcodes.GameServerChangeWaitType:
+		event = WorldEvent{Kind: WorldPosture, ObjectID: int(decoder.U32()), State: "standing"}
+		if decoder.U32() == 0 {
+			event.State = "sitting"
+		}
+		event.Location = location()
+	case opcodes.GameServerChangeMoveType:
+		event = WorldEvent{Kind: WorldPosture, ObjectID: int(decoder.U32()), State: "walking"}
+		if decoder.U32() == 1 {
+			event.State = "running"
+		}
+	case opcodes.GameServerStatusUpdate:
+		attributes, err := parseStatusUpdatePayload(data)
+		if err != nil {
+			return WorldEvent{}, err
+		}
+		return WorldEvent{Kind: WorldStatus, ObjectID: int(decoder.U32()), Attributes: attributes}, decoder.Err()
+	case opcodes.GameServerClientSetTime:
+		event = WorldEvent{Kind: WorldTime, Value: int(decoder.U32())}
+	case opcodes.GameServerSunRise:
+		event = WorldEvent{Kind: WorldTime, State: "sunrise"}
+	case opcodes.GameServerSunSet:
+		event = WorldEvent{Kind: WorldTime, State: "sunset"}
+	case opcodes.GameServerSystemMessage:
+		messageID, params, err := parseSystemMessagePayload(data)
+		if err != nil {
+			return WorldEvent{}, err
+		}
+		return WorldEvent{Kind: WorldMessage, Value: int(messageID), Params: params}, nil
+	default:
+		return WorldEvent{Kind: WorldPacket}, nil
+	}
+
+	if err := decoder.Err(); err != nil {
+		return WorldEvent{}, fmt.Errorf("%w: %v", ErrInvalidPacket, err)
+	}
+	return event, nil
+}
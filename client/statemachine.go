@@ -0,0 +1,166 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxStateHistory is how many transitions a state machine remembers
+const MaxStateHistory = 32
+
+// StateTransitions lists the states a client can move to from each state.
+// Staying in the same state is always allowed and isn't a transition.
+var StateTransitions = map[ClientState][]ClientState{
+	StateDisconnected:    {StateConnectingLogin},
+	StateConnectingLogin: {StateAuthenticating, StateError, StateDisconnected},
+	StateAuthenticating:  {StateSelectingServer, StateError, StateDisconnected},
+	StateSelectingServer: {StateConnectingGame, StateError, StateDisconnected},
+	StateConnectingGame:  {StateInGame, StateError, StateDisconnected},
+	StateInGame:          {StateConnectingGame, StateError, StateDisconnected}, // Back to the character selection
+	StateError:           {StateConnectingLogin, StateDisconnected},
+}
+
+// Transition is a move of a client from a state to another
+type Transition struct {
+	ClientID string      `json:"clientId"`
+	From     ClientState `json:"from"`
+	To       ClientState `json:"to"`
+	At       time.Time   `json:"at"`
+}
+
+// TransitionHook is called after a transition, outside of the lock of the state machine
+type TransitionHook func(transition Transition)
+
+// StateMachine tracks the state of a client, refusing the transitions StateTransitions doesn't list.
+// Every transition is published on the event bus, if any, as a "client.state" event.
+type StateMachine struct {
+	clientID string
+	state    ClientState
+	entered  map[ClientState]time.Time
+	history  []Transition
+	onEnter  map[ClientState][]TransitionHook
+	onExit   map[ClientState][]TransitionHook
+	eventBus *EventBus
+	mu       sync.RWMutex
+}
+
+// NewStateMachine creates the state machine of a disconnected client
+func NewStateMachine(clientID string) *StateMachine {
+	return &StateMachine{
+		clientID: clientID,
+		state:    StateDisconnected,
+		entered:  map[ClientState]time.Time{StateDisconnected: time.Now()},
+		onEnter:  make(map[ClientState][]TransitionHook),
+		onExit:   make(map[ClientState][]TransitionHook),
+	}
+}
+
+// SetEventBus sets the bus the transitions are published on, nil to stop publishing them
+func (m *StateMachine) SetEventBus(eventBus *EventBus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eventBus = eventBus
+}
+
+// publish emits an event of the client on the bus the transitions are published on, if any
+func (m *StateMachine) publish(event Event) {
+	m.mu.RLock()
+	eventBus := m.eventBus
+	m.mu.RUnlock()
+
+	if eventBus != nil {
+		eventBus.Emit(event)
+	}
+}
+
+// OnEnter registers a hook called every time the client enters the state
+func (m *StateMachine) OnEnter(state ClientState, hook TransitionHook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEnter[state] = append(m.onEnter[state], hook)
+}
+
+// OnExit registers a hook called every time the client leaves the state
+func (m *StateMachine) OnExit(state ClientState, hook TransitionHook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onExit[state] = append(m.onExit[state], hook)
+}
+
+// State returns the current state
+func (m *StateMachine) State() ClientState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// EnteredAt returns when the client last entered the state, the zero time if it never did
+func (m *StateMachine) EnteredAt(state ClientState) time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.entered[state]
+}
+
+// History returns the last transitions, oldest first
+func (m *StateMachine) History() []Transition {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := make([]Transition, len(m.history))
+	copy(history, m.history)
+	return history
+}
+
+// CanTransition returns whether StateTransitions allows moving from a state to another
+func CanTransition(from, to ClientState) bool {
+	if from == to {
+		return true
+	}
+	for _, allowed := range StateTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the client into the state, then runs the exit hooks of the
+// previous state, the enter hooks of the new one and publishes the transition
+func (m *StateMachine) Transition(to ClientState) error {
+	m.mu.Lock()
+	from := m.state
+	if from == to {
+		m.mu.Unlock()
+		return nil
+	}
+	if !CanTransition(from, to) {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: cannot move from %s to %s", ErrInvalidState, from, to)
+	}
+
+	transition := Transition{ClientID: m.clientID, From: from, To: to, At: time.Now()}
+	m.state = to
+	m.entered[to] = transition.At
+	m.history = append(m.history, transition)
+	if len(m.history) > MaxStateHistory {
+		m.history = m.history[len(m.history)-MaxStateHistory:]
+	}
+
+	onExit := m.onExit[from]
+	onEnter := m.onEnter[to]
+	eventBus := m.eventBus
+	m.mu.Unlock()
+
+	for _, hook := range onExit {
+		hook(transition)
+	}
+	for _, hook := range onEnter {
+		hook(transition)
+	}
+	if eventBus != nil {
+		eventBus.Emit(transition)
+	}
+
+	return nil
+}
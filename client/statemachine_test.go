@@ -0,0 +1,109 @@
+package client
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStateMachineTransitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    []ClientState
+		wantErr string
+	}{
+		{name: "full connection", path: []ClientState{StateConnectingLogin, StateAuthenticating, StateSelectingServer, StateConnectingGame, StateInGame, StateDisconnected}},
+		{name: "retry after an error", path: []ClientState{StateConnectingLogin, StateError, StateConnectingLogin}},
+		{name: "same state", path: []ClientState{StateDisconnected, StateConnectingLogin, StateConnectingLogin}},
+		{name: "skipping the login", path: []ClientState{StateConnectingGame}, wantErr: "cannot move from Disconnected to ConnectingGame"},
+		{name: "error while disconnected", path: []ClientState{StateError}, wantErr: "cannot move from Disconnected to Error"},
+		{name: "back to the character selection", path: []ClientState{StateConnectingLogin, StateAuthenticating, StateSelectingServer, StateConnectingGame, StateInGame, StateConnectingGame, StateInGame}},
+		{name: "back to the server list", path: []ClientState{StateConnectingLogin, StateAuthenticating, StateSelectingServer, StateConnectingGame, StateInGame, StateSelectingServer}, wantErr: "cannot move from InGame to SelectingServer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine := NewStateMachine("client-1")
+
+			var err error
+			for _, state := range tt.path {
+				if err = machine.Transition(state); err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Transition() error = %v", err)
+				}
+				if state := machine.State(); state != tt.path[len(tt.path)-1] {
+					t.Errorf("State() = %v, want %v", state, tt.path[len(tt.path)-1])
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidState) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Transition() error = %v, want %v with %q", err, ErrInvalidState, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStateMachineHooks(t *testing.T) {
+	machine := NewStateMachine("client-1")
+	eventBus := NewEventBus()
+	machine.SetEventBus(eventBus)
+
+	events := make(chan Transition, 4)
+	eventBus.Subscribe("client.state", func(event interface{}) error {
+		events <- event.(Transition)
+		return nil
+	})
+
+	var calls []string
+	machine.OnExit(StateDisconnected, func(transition Transition) {
+		calls = append(calls, "exit "+transition.From.String())
+	})
+	machine.OnEnter(StateConnectingLogin, func(transition Transition) {
+		calls = append(calls, "enter "+transition.To.String())
+		// Hooks run outside of the lock
+		if state := machine.State(); state != StateConnectingLogin {
+			t.Errorf("State() = %v in the enter hook", state)
+		}
+	})
+
+	before := time.Now()
+	if err := machine.Transition(StateConnectingLogin); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"exit Disconnected", "enter ConnectingLogin"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("hooks = %v, want %v", calls, want)
+	}
+	if entered := machine.EnteredAt(StateConnectingLogin); entered.Before(before) {
+		t.Errorf("EnteredAt() = %v, before the transition", entered)
+	}
+	if entered := machine.EnteredAt(StateInGame); !entered.IsZero() {
+		t.Errorf("EnteredAt(InGame) = %v, the client never was in game", entered)
+	}
+
+	select {
+	case event := <-events:
+		if event.ClientID != "client-1" || event.From != StateDisconnected || event.To != StateConnectingLogin {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the transition wasn't published")
+	}
+
+	for i := 0; i < MaxStateHistory; i++ {
+		machine.Transition(StateError)
+		machine.Transition(StateConnectingLogin)
+	}
+	history := machine.History()
+	if len(history) != MaxStateHistory || history[len(history)-1].To != StateConnectingLogin {
+		t.Errorf("History() has %d transitions, the last one being %+v", len(history), history[len(history)-1])
+	}
+}
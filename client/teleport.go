@@ -0,0 +1,60 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// TeleportVia talks to a gatekeeper and picks the destination whose option contains the given text.
+// It returns once the character has been teleported, or ErrTeleportRefused if the gatekeeper
+// answers with a dialog instead, for example when the character can't pay for the trip
+func (c *Client) TeleportVia(npcObjectID int, destination string) error {
+	dialog, err := c.Interact(npcObjectID)
+	if err != nil {
+		return err
+	}
+
+	index, err := dialog.Option(destination)
+	if err != nil {
+		return err
+	}
+
+	if err := c.SendBypass(dialog.Links[index].Command); err != nil {
+		return err
+	}
+
+	session := c.sessions.GameSession()
+	for {
+		opcode, data, err := c.receiveGame(opcodes.GameServerTeleportToLocation, opcodes.GameServerNpcHtmlMessage)
+		if err != nil {
+			return c.fail(err)
+		}
+
+		if opcode == opcodes.GameServerNpcHtmlMessage {
+			refusal, err := parseNpcHtmlMessagePayload(data)
+			if err != nil {
+				return c.fail(err)
+			}
+			session.Dialog = refusal
+			return fmt.Errorf("%w: %s", ErrTeleportRefused, destination)
+		}
+
+		objectID, location, err := parseTeleportToLocationPayload(data)
+		if err != nil {
+			return c.fail(err)
+		}
+
+		// The other players teleporting are broadcast too
+		if objectID != session.SelectedChar.ID {
+			continue
+		}
+
+		session.SelectedChar.Location = location
+		session.GameState.TargetID = 0
+		session.Dialog = nil
+
+		c.touch()
+		return nil
+	}
+}
@@ -1,9 +1,17 @@
 package client
 
 import (
+	"fmt"
+	"maps"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/eventbus"
+	"github.com/frostwind/l2go/protocol"
+	"github.com/frostwind/l2go/templates"
 )
 
 // ClientState represents the current state of a game client
@@ -40,6 +48,29 @@ func (s ClientState) String() string {
 	}
 }
 
+// ParseClientState returns the state of the given name, as returned by String
+func ParseClientState(name string) (ClientState, error) {
+	for state := StateDisconnected; state <= StateError; state++ {
+		if state.String() == name {
+			return state, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown state %s", ErrInvalidState, name)
+}
+
+// validateStateTimeouts checks durations keyed by state name
+func validateStateTimeouts(timeouts map[string]time.Duration) error {
+	for name, timeout := range timeouts {
+		if _, err := ParseClientState(name); err != nil {
+			return err
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("%w: %s timeout is %v", ErrInvalidTimeout, name, timeout)
+		}
+	}
+	return nil
+}
+
 // ClientConfig holds configuration for a game client
 type ClientConfig struct {
 	LoginServerHost string        `json:"loginServerHost"`
@@ -50,6 +81,78 @@ type ClientConfig struct {
 	Password        string        `json:"password"`
 	AutoCreate      bool          `json:"autoCreate"`
 	Timeout         time.Duration `json:"timeout"`
+	LenientChecksum bool          `json:"lenientChecksum"` // Accept login packets with a wrong checksum
+	MaxPacketSize   int           `json:"maxPacketSize"`   // Largest packet accepted from the servers, 0 for no limit
+	NullCrypto      bool          `json:"nullCrypto"`      // Talk in clear to servers in the null crypto debug mode, on private addresses only
+	Spectator       bool          `json:"spectator"`       // Only watch the world once in it, refusing every action, see Client.Spectate
+
+	// Offered to the game server in ProtocolVersion, the packets following CryptInit being compressed in both
+	// directions once the game server offered it too
+	Compression protocol.CompressionOptions `json:"compression,omitzero"`
+
+	// Longest time the client can spend in a state, by state name, before aborting
+	StateTimeouts map[string]time.Duration `json:"stateTimeouts,omitempty"`
+
+	// How the clients vary the way they show themselves to the servers, each client id getting its own identity
+	Variation VariationProfile `json:"variation,omitzero"`
+
+	// Bounds the waits of the login handshake, and how often it is attempted again when one expires
+	Handshake HandshakeConfig `json:"handshake,omitzero"`
+
+	// Local addresses the connections are bound to in turn, the system picking one when empty. The
+	// manager replaces them with its own, shared by all its clients.
+	SourceAddresses []SourceAddress `json:"sourceAddresses,omitempty"`
+	ReusePort       bool            `json:"reusePort,omitempty"` // Set SO_REUSEPORT on the bound sockets, where the system supports it
+}
+
+// Waits of the login handshake, named by the packet awaited in its timeout errors
+const (
+	HandshakeInit       = "Init"
+	HandshakeGGAuth     = "GGAuth"
+	HandshakeLoginOk    = "LoginOk"
+	HandshakeServerList = "ServerList"
+)
+
+// HandshakeConfig bounds the waits of the login handshake. A hung login server then fails the handshake
+// with ErrOperationTimeout, which is attempted again from the connection a bounded number of times.
+type HandshakeConfig struct {
+	InitTimeout       time.Duration `json:"initTimeout,omitempty"`       // Wait for Init once connected, the client timeout when 0
+	GameGuard         bool          `json:"gameGuard,omitempty"`         // Send RequestGGAuth after Init and wait for GGAuth, as the retail clients do
+	GGAuthTimeout     time.Duration `json:"ggAuthTimeout,omitempty"`     // Wait for GGAuth, the client timeout when 0
+	LoginOkTimeout    time.Duration `json:"loginOkTimeout,omitempty"`    // Wait for the answer to the credentials, the client timeout when 0
+	ServerListTimeout time.Duration `json:"serverListTimeout,omitempty"` // Wait for the server list, the client timeout when 0
+	Retries           int           `json:"retries,omitempty"`           // Handshakes attempted again after a wait expired
+	RetryDelay        time.Duration `json:"retryDelay,omitempty"`        // Delay before the first retry, doubled at every retry
+}
+
+// Validate checks that the waits and the retries aren't negative
+func (h HandshakeConfig) Validate() error {
+	if h.InitTimeout < 0 || h.GGAuthTimeout < 0 || h.LoginOkTimeout < 0 || h.ServerListTimeout < 0 || h.RetryDelay < 0 {
+		return fmt.Errorf("%w: the handshake waits can't be negative", ErrInvalidTimeout)
+	}
+	if h.Retries < 0 {
+		return fmt.Errorf("%w: %d handshake retries", ErrInvalidTimeout, h.Retries)
+	}
+	return nil
+}
+
+// wait returns how long the handshake waits for a packet, fallback when it isn't set
+func (h HandshakeConfig) wait(phase string, fallback time.Duration) time.Duration {
+	var wait time.Duration
+	switch phase {
+	case HandshakeInit:
+		wait = h.InitTimeout
+	case HandshakeGGAuth:
+		wait = h.GGAuthTimeout
+	case HandshakeLoginOk:
+		wait = h.LoginOkTimeout
+	case HandshakeServerList:
+		wait = h.ServerListTimeout
+	}
+	if wait <= 0 {
+		return fallback
+	}
+	return wait
 }
 
 // Validate validates the client configuration
@@ -75,16 +178,36 @@ func (c *ClientConfig) Validate() error {
 	if c.Timeout <= 0 {
 		c.Timeout = 30 * time.Second // Default timeout
 	}
+	if err := validateStateTimeouts(c.StateTimeouts); err != nil {
+		return err
+	}
+	if err := c.Variation.Validate(); err != nil {
+		return err
+	}
+	if err := c.Handshake.Validate(); err != nil {
+		return err
+	}
+	if err := validateSourceAddresses(c.SourceAddresses, c.ReusePort); err != nil {
+		return err
+	}
+	if c.NullCrypto && (!config.IsPrivateAddress(c.LoginServerHost) || !config.IsPrivateAddress(c.GameServerHost)) {
+		return ErrNullCryptoRefused
+	}
 	return nil
 }
 
 // ConnectionMetrics holds metrics about client connections
 type ConnectionMetrics struct {
-	TotalConnections   int64         `json:"totalConnections"`
-	ActiveConnections  int64         `json:"activeConnections"`
-	FailedConnections  int64         `json:"failedConnections"`
-	AverageConnectTime time.Duration `json:"averageConnectTime"`
-	LastUpdateTime     time.Time     `json:"lastUpdateTime"`
+	TotalConnections   int64                         `json:"totalConnections"`
+	ActiveConnections  int64                         `json:"activeConnections"`
+	FailedConnections  int64                         `json:"failedConnections"`
+	AverageConnectTime time.Duration                 `json:"averageConnectTime"`
+	GracefulStops      int64                         `json:"gracefulStops"`             // Clients drained with a clean logout
+	ForcedStops        int64                         `json:"forcedStops"`               // Clients closed once the drain deadline passed
+	StuckClients       map[string]int64              `json:"stuckClients,omitempty"`    // Clients past their dwell limit, by state name
+	ErrorBudget        ErrorBudgetStatus             `json:"errorBudget"`               // Failures of the actions by category, against their budgets
+	SourceAddresses    map[string]SourceAddressStats `json:"sourceAddresses,omitempty"` // Connections of the source addresses of the manager, by IP
+	LastUpdateTime     time.Time                     `json:"lastUpdateTime"`
 	mu                 sync.RWMutex
 }
 
@@ -99,6 +222,49 @@ func (m *ConnectionMetrics) Update(total, active, failed int64, avgTime time.Dur
 	m.LastUpdateTime = time.Now()
 }
 
+// AddConnections counts clients added since the last update, without going over all of them
+func (m *ConnectionMetrics) AddConnections(total, active, failed int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.TotalConnections += total
+	m.ActiveConnections += active
+	m.FailedConnections += failed
+	m.LastUpdateTime = time.Now()
+}
+
+// RecordStops counts the clients stopped by a drain
+func (m *ConnectionMetrics) RecordStops(graceful, forced int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.GracefulStops += graceful
+	m.ForcedStops += forced
+	m.LastUpdateTime = time.Now()
+}
+
+// SetStuck replaces the counts of stuck clients by state name
+func (m *ConnectionMetrics) SetStuck(stuck map[string]int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.StuckClients = stuck
+	m.LastUpdateTime = time.Now()
+}
+
+// SetSourceAddresses replaces the counters of the connections of the source addresses
+func (m *ConnectionMetrics) SetSourceAddresses(stats map[string]SourceAddressStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.SourceAddresses = stats
+	m.LastUpdateTime = time.Now()
+}
+
+// SetErrorBudget replaces the status of the error budgets
+func (m *ConnectionMetrics) SetErrorBudget(status ErrorBudgetStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ErrorBudget = status
+	m.LastUpdateTime = time.Now()
+}
+
 // GetSnapshot returns a snapshot of the current metrics
 func (m *ConnectionMetrics) GetSnapshot() ConnectionMetrics {
 	m.mu.RLock()
@@ -108,6 +274,11 @@ func (m *ConnectionMetrics) GetSnapshot() ConnectionMetrics {
 		ActiveConnections:  m.ActiveConnections,
 		FailedConnections:  m.FailedConnections,
 		AverageConnectTime: m.AverageConnectTime,
+		GracefulStops:      m.GracefulStops,
+		ForcedStops:        m.ForcedStops,
+		StuckClients:       maps.Clone(m.StuckClients),
+		ErrorBudget:        m.ErrorBudget.clone(),
+		SourceAddresses:    maps.Clone(m.SourceAddresses),
 		LastUpdateTime:     m.LastUpdateTime,
 	}
 }
@@ -122,7 +293,8 @@ type ClientStatus struct {
 	LastError     string      `json:"lastError"`
 }
 
-// CharacterTemplate represents a character creation template
+// CharacterTemplate represents a character creation template.
+// The starting stats are the ones the game server offers for the race and the class.
 type CharacterTemplate struct {
 	Race      int `json:"race"`
 	Class     int `json:"class"`
@@ -130,20 +302,77 @@ type CharacterTemplate struct {
 	HairStyle int `json:"hairStyle"`
 	HairColor int `json:"hairColor"`
 	Face      int `json:"face"`
+	STR       int `json:"str,omitempty"`
+	DEX       int `json:"dex,omitempty"`
+	CON       int `json:"con,omitempty"`
+	INT       int `json:"int,omitempty"`
+	WIT       int `json:"wit,omitempty"`
+	MEN       int `json:"men,omitempty"`
+}
+
+// BundledTemplates returns the character templates shipped for a chronicle, in the
+// order the game server offers them, ready to be given to CreateCharacter
+func BundledTemplates(chronicle string) ([]CharacterTemplate, error) {
+	set, err := templates.Bundled(chronicle)
+	if err != nil {
+		return nil, err
+	}
+
+	bundled := make([]CharacterTemplate, 0, len(set.Templates))
+	for _, t := range set.Templates {
+		bundled = append(bundled, CharacterTemplate{
+			Race:  t.Race,
+			Class: t.Class,
+			STR:   t.STR,
+			DEX:   t.DEX,
+			CON:   t.CON,
+			INT:   t.INT,
+			WIT:   t.WIT,
+			MEN:   t.MEN,
+		})
+	}
+	return bundled, nil
 }
 
 // CharacterInfo represents character information
 type CharacterInfo struct {
-	ID       int                `json:"id"`
-	Name     string             `json:"name"`
-	Level    int                `json:"level"`
-	Class    int                `json:"class"`
-	Race     int                `json:"race"`
-	Gender   int                `json:"gender"`
-	Location *CharacterLocation `json:"location"`
-	Stats    *CharacterStats    `json:"stats"`
+	ID         int                `json:"id"`
+	Name       string             `json:"name"`
+	Level      int                `json:"level"`
+	Class      int                `json:"class"`
+	Race       int                `json:"race"`
+	Gender     int                `json:"gender"`
+	Karma      int                `json:"karma"`
+	HairStyle  int                `json:"hairStyle"`
+	HairColor  int                `json:"hairColor"`
+	Face       int                `json:"face"`
+	Paperdoll  []int              `json:"paperdoll,omitempty"` // Item ids worn, by paperdoll slot, 0 for the empty slots
+	LastAccess time.Time          `json:"lastAccess,omitzero"` // Zero for a character never saved
+	Location   *CharacterLocation `json:"location"`
+	Stats      *CharacterStats    `json:"stats"`
 }
 
+// Paperdoll slots of the characters, indexing CharacterInfo.Paperdoll
+const (
+	PaperdollUnder = iota
+	PaperdollRightEar
+	PaperdollLeftEar
+	PaperdollNeck
+	PaperdollRightFinger
+	PaperdollLeftFinger
+	PaperdollHead
+	PaperdollRightHand
+	PaperdollLeftHand
+	PaperdollGloves
+	PaperdollChest
+	PaperdollLegs
+	PaperdollFeet
+	PaperdollBack
+	PaperdollTwoHands
+	PaperdollHair
+	PaperdollSlots // Count of the slots
+)
+
 // CharacterLocation represents a character's location
 type CharacterLocation struct {
 	X int `json:"x"`
@@ -151,6 +380,143 @@ type CharacterLocation struct {
 	Z int `json:"z"`
 }
 
+// ShortcutType is the kind of entry bound to a shortcut slot
+type ShortcutType int
+
+const (
+	ShortcutItem   ShortcutType = 1
+	ShortcutSkill  ShortcutType = 2
+	ShortcutAction ShortcutType = 3
+	ShortcutMacro  ShortcutType = 4
+	ShortcutRecipe ShortcutType = 5
+)
+
+const (
+	// ShortcutSlots is the number of slots of a shortcut bar page
+	ShortcutSlots = 12
+
+	// ShortcutPages is the number of shortcut bar pages
+	ShortcutPages = 10
+)
+
+// Shortcut represents an entry of the shortcut bar
+type Shortcut struct {
+	Type  ShortcutType `json:"type"`
+	Slot  int          `json:"slot"`
+	Page  int          `json:"page"`
+	ID    int          `json:"id"`
+	Level int          `json:"level"`
+}
+
+// Dialog represents an HTML dialog opened by an NPC
+type Dialog struct {
+	NpcObjectID int          `json:"npcObjectId"`
+	HTML        string       `json:"html"`
+	Links       []DialogLink `json:"links"`
+}
+
+// DialogLink represents an option of a dialog, answered with its bypass command
+type DialogLink struct {
+	Text    string `json:"text"`
+	Command string `json:"command"`
+}
+
+// Friend represents a player of the friend list of the character
+type Friend struct {
+	ObjectID int    `json:"objectId"` // 0 while the friend is offline
+	Name     string `json:"name"`
+	Online   bool   `json:"online"`
+}
+
+// Quest represents a quest the character is doing, at the step it reached
+type Quest struct {
+	ID   int `json:"id"`
+	Cond int `json:"cond"`
+}
+
+// TradeList is the buy list or the sell list a merchant opened, or the items a warehouse keeper
+// offers to deposit or to withdraw
+type TradeList struct {
+	ID    int         `json:"id"`    // Of the buy list, or the kind of warehouse
+	Adena uint64      `json:"adena"` // Of the character when the list was opened
+	Items []TradeItem `json:"items"`
+}
+
+// TradeItem is an item of a trade list, the price being the one of a single item. The buy lists
+// give the weight of the items, the other lists how many there are.
+type TradeItem struct {
+	ItemID int    `json:"itemId"`
+	Price  uint64 `json:"price"`
+	Weight int    `json:"weight,omitempty"`
+	Count  uint64 `json:"count,omitempty"`
+}
+
+// Item returns the item of the list with the given id
+func (l *TradeList) Item(itemID int) (TradeItem, bool) {
+	for _, item := range l.Items {
+		if item.ItemID == itemID {
+			return item, true
+		}
+	}
+	return TradeItem{}, false
+}
+
+// Types of the chat messages, used through Say2
+const (
+	ChatAll  = 0
+	ChatTell = 2
+)
+
+// System messages the client reacts to
+const (
+	SystemMessageNotLoggedIn    = 3
+	SystemMessageEnchanted      = 63
+	SystemMessageInventoryFull  = 129
+	SystemMessageNotClanMember  = 212
+	SystemMessageNotEnoughAdena = 279
+	SystemMessageIncorrectCount = 351
+	SystemMessageWeightLimit    = 422
+	SystemMessageWarehouseFull  = 1036
+	SystemMessageText           = 1983 // Its only parameter is the text shown
+)
+
+// Attributes of the StatusUpdate packet the client keeps up with
+const (
+	StatusCurLoad = 0x0e
+	StatusMaxLoad = 0x0f
+)
+
+// AdenaID is the item id of the adena
+const AdenaID = 57
+
+// Kinds of warehouse
+const (
+	WarehousePrivate = 1
+	WarehouseClan    = 2
+)
+
+// Actions of the action window, used through RequestActionUse
+const (
+	ActionSitStand = 0
+	ActionWalkRun  = 1
+)
+
+// Emotes of the characters, played through RequestSocialAction
+const (
+	EmoteGreeting = iota + 2
+	EmoteVictory
+	EmoteAdvance
+	EmoteNo
+	EmoteYes
+	EmoteBow
+	EmoteUnaware
+	EmoteWaiting
+	EmoteLaugh
+	EmoteApplaud
+	EmoteDance
+	EmoteSorrow
+)
+
 // CharacterStats represents character statistics
 type CharacterStats struct {
 	HP  int `json:"hp"`
@@ -161,17 +527,26 @@ type CharacterStats struct {
 
 // LoginConnection represents a connection to the login server
 type LoginConnection struct {
-	conn        net.Conn
-	sessionID   []byte
-	isConnected bool
-	mu          sync.RWMutex
+	conn          net.Conn
+	source        *SourceBinder // Binds the connection to a source address, if set
+	sessionID     []byte
+	isConnected   bool
+	timeout       time.Duration
+	maxPacketSize atomic.Int32
+	violations    atomic.Uint32
+	mu            sync.RWMutex
 }
 
 // GameConnection represents a connection to the game server
 type GameConnection struct {
-	conn        net.Conn
-	isConnected bool
-	mu          sync.RWMutex
+	conn          net.Conn
+	source        *SourceBinder // Binds the connection to a source address, if set
+	unwatch       func()        // Stops the reactor reading the connection, if one does
+	isConnected   bool
+	timeout       time.Duration
+	maxPacketSize atomic.Int32
+	violations    atomic.Uint32
+	mu            sync.RWMutex
 }
 
 // SessionManager manages login and game sessions
@@ -184,6 +559,7 @@ type SessionManager struct {
 // LoginSession represents a login server session
 type LoginSession struct {
 	SessionID      []byte       `json:"sessionId"`
+	PlayKey        []byte       `json:"playKey"`
 	AccountInfo    *AccountInfo `json:"accountInfo"`
 	ServerList     []ServerInfo `json:"serverList"`
 	SelectedServer *ServerInfo  `json:"selectedServer"`
@@ -192,8 +568,16 @@ type LoginSession struct {
 // GameSession represents a game server session
 type GameSession struct {
 	Characters   []CharacterInfo `json:"characters"`
+	SlotsLeft    int             `json:"slotsLeft"` // Characters the account can still create, -1 when unknown
 	SelectedChar *CharacterInfo  `json:"selectedChar"`
 	GameState    *GameState      `json:"gameState"`
+	Shortcuts    []Shortcut      `json:"shortcuts"`
+	Dialog       *Dialog         `json:"dialog"`
+	Friends      []Friend        `json:"friends"`
+	FriendInvite string          `json:"friendInvite"` // Player asking the character to be its friend
+	Quests       []Quest         `json:"quests"`
+	Inventory    map[int]uint64  `json:"inventory"` // Counts of the items of the last item list by id, the adena included
+	Enchants     map[int]int     `json:"enchants"`  // Enchant levels set with Enchant, by item id
 }
 
 // AccountInfo represents account information
@@ -218,8 +602,14 @@ type ServerInfo struct {
 // GameState represents the current game state
 type GameState struct {
 	IsInGame    bool      `json:"isInGame"`
+	IsSitting   bool      `json:"isSitting"`
+	IsRunning   bool      `json:"isRunning"`
+	TargetID    int       `json:"targetId"`
 	LastUpdate  time.Time `json:"lastUpdate"`
-	ServerTime  int64     `json:"serverTime"`
+	ServerTime  int64     `json:"serverTime"` // Minutes of the world, as last set by ClientSetTime
+	IsNight     bool      `json:"isNight"`
+	Load        int       `json:"load"`    // Weight of the items carried, as last sent by StatusUpdate
+	MaxLoad     int       `json:"maxLoad"` // Weight the character can carry, 0 until the game server tells
 	PlayerCount int       `json:"playerCount"`
 	WorldStatus string    `json:"worldStatus"`
 }
@@ -233,35 +623,12 @@ type Packet struct {
 }
 
 // EventHandler represents an event handler function
-type EventHandler func(event interface{}) error
+type EventHandler = eventbus.Handler
 
 // EventBus manages event distribution
-type EventBus struct {
-	handlers map[string][]EventHandler
-	mu       sync.RWMutex
-}
+type EventBus = eventbus.Bus
 
 // NewEventBus creates a new event bus
 func NewEventBus() *EventBus {
-	return &EventBus{
-		handlers: make(map[string][]EventHandler),
-	}
-}
-
-// Subscribe adds an event handler for the specified event type
-func (eb *EventBus) Subscribe(eventType string, handler EventHandler) {
-	eb.mu.Lock()
-	defer eb.mu.Unlock()
-	eb.handlers[eventType] = append(eb.handlers[eventType], handler)
-}
-
-// Publish publishes an event to all registered handlers
-func (eb *EventBus) Publish(eventType string, event interface{}) {
-	eb.mu.RLock()
-	handlers := eb.handlers[eventType]
-	eb.mu.RUnlock()
-
-	for _, handler := range handlers {
-		go handler(event) // Execute handlers concurrently
-	}
+	return eventbus.New()
 }
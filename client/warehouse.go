@@ -0,0 +1,55 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// OpenDeposit asks a warehouse keeper for the items of the character it can store in the private
+// warehouse, or in the clan warehouse, targeting it first if needed
+func (c *Client) OpenDeposit(npcObjectID int, clan bool) (*TradeList, error) {
+	return c.openTradeList(npcObjectID, warehouseCommand("Deposit", clan), opcodes.GameServerWarehouseDepositList, parseWarehouseListPayload, ErrWarehouseRefused)
+}
+
+// OpenWithdraw asks a warehouse keeper for the items stored in the private warehouse, or in the
+// clan warehouse, targeting it first if needed
+func (c *Client) OpenWithdraw(npcObjectID int, clan bool) (*TradeList, error) {
+	return c.openTradeList(npcObjectID, warehouseCommand("Withdraw", clan), opcodes.GameServerWarehouseWithdrawList, parseWarehouseListPayload, ErrWarehouseRefused)
+}
+
+// Deposit stores items of the character with a warehouse keeper, opening the deposit list first.
+// It returns once the inventory no longer holds them, or ErrWarehouseRefused with the reason
+// given by the game server.
+func (c *Client) Deposit(npcObjectID int, clan bool, itemID int, count uint64) error {
+	list, err := c.OpenDeposit(npcObjectID, clan)
+	if err != nil {
+		return err
+	}
+	if _, ok := list.Item(itemID); !ok {
+		return fmt.Errorf("%w: the character has no item %d", ErrWarehouseRefused, itemID)
+	}
+
+	return c.moveItems(opcodes.GameClientSendWarehouseDeposit, list.ID, itemID, count, ErrWarehouseRefused)
+}
+
+// Withdraw takes items stored with a warehouse keeper back, opening the withdraw list first.
+// It returns once the inventory holds them, or ErrWarehouseRefused with the reason given by the game server.
+func (c *Client) Withdraw(npcObjectID int, clan bool, itemID int, count uint64) error {
+	list, err := c.OpenWithdraw(npcObjectID, clan)
+	if err != nil {
+		return err
+	}
+	if _, ok := list.Item(itemID); !ok {
+		return fmt.Errorf("%w: the warehouse has no item %d", ErrWarehouseRefused, itemID)
+	}
+
+	return c.moveItems(opcodes.GameClientSendWarehouseWithdraw, list.ID, itemID, count, ErrWarehouseRefused)
+}
+
+func warehouseCommand(command string, clan bool) string {
+	if clan {
+		return command + "Clan"
+	}
+	return command
+}
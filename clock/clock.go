@@ -0,0 +1,205 @@
+// Package clock abstracts the passing of time, so the code waiting or
+// timestamping can run against a simulated clock in deterministic tests
+package clock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and makes timers
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer sends the time on its channel once it expires
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Sleep waits for the duration on the clock, or until the context is done
+func Sleep(ctx context.Context, c Clock, d time.Duration) error {
+	timer := c.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+// Real is the clock of the system
+type Real struct{}
+
+func (Real) Now() time.Time { return time.Now() }
+
+func (Real) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.timer.C }
+func (t realTimer) Stop() bool          { return t.timer.Stop() }
+
+// Scaled is a clock running speed times faster than the system one, from the time it was created
+type Scaled struct {
+	start time.Time
+	speed float64
+}
+
+// NewScaled creates a clock running speed times faster than the system one,
+// a speed of 1 or below running at the pace of the system clock
+func NewScaled(speed float64) *Scaled {
+	return &Scaled{start: time.Now(), speed: max(speed, 1)}
+}
+
+// Speed returns how many times faster than the system clock the clock runs
+func (s *Scaled) Speed() float64 { return s.speed }
+
+func (s *Scaled) Now() time.Time {
+	return s.start.Add(time.Duration(float64(time.Since(s.start)) * s.speed))
+}
+
+func (s *Scaled) NewTimer(d time.Duration) Timer {
+	timer := &scaledTimer{c: make(chan time.Time, 1)}
+	timer.timer = time.AfterFunc(s.Real(d), func() { timer.c <- s.Now() })
+	return timer
+}
+
+// Real returns how long a duration of the clock lasts on the system clock
+func (s *Scaled) Real(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / s.speed)
+}
+
+type scaledTimer struct {
+	timer *time.Timer
+	c     chan time.Time
+}
+
+func (t *scaledTimer) C() <-chan time.Time { return t.c }
+func (t *scaledTimer) Stop() bool          { return t.timer.Stop() }
+
+type contextKey struct{}
+
+// WithContext returns a context carrying the clock
+func WithContext(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the clock of the context, the real one if it carries none
+func FromContext(ctx context.Context) Clock {
+	if c, ok := ctx.Value(contextKey{}).(Clock); ok {
+		return c
+	}
+	return Real{}
+}
+
+// Fake is a simulated clock which only moves when it is advanced
+type Fake struct {
+	now     time.Time
+	timers  []*fakeTimer
+	waiters chan struct{} // Closed and replaced every time a timer is created
+	mu      sync.Mutex
+}
+
+// NewFake creates a simulated clock set at the given time
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now, waiters: make(chan struct{})}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	timer := &fakeTimer{clock: f, deadline: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.c <- f.now
+		return timer
+	}
+
+	f.timers = append(f.timers, timer)
+	close(f.waiters)
+	f.waiters = make(chan struct{})
+	return timer
+}
+
+// Advance moves the clock forward, firing the timers expiring meanwhile in the order of their deadlines
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+
+	sort.SliceStable(f.timers, func(i, j int) bool { return f.timers[i].deadline.Before(f.timers[j].deadline) })
+	pending := f.timers[:0]
+	for _, timer := range f.timers {
+		if timer.deadline.After(f.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- timer.deadline
+	}
+	f.timers = pending
+}
+
+// Pending returns how many timers wait for the clock to move
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// WaitForTimers blocks until at least count timers wait for the clock to move, or until the context is done.
+// Tests call it before advancing the clock, to be sure the code under test started waiting.
+func (f *Fake) WaitForTimers(ctx context.Context, count int) error {
+	for {
+		f.mu.Lock()
+		pending := len(f.timers)
+		waiters := f.waiters
+		f.mu.Unlock()
+
+		if pending >= count {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-waiters:
+		}
+	}
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFakeTimers(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake := NewFake(start)
+
+	late := fake.NewTimer(3 * time.Second)
+	early := fake.NewTimer(time.Second)
+	stopped := fake.NewTimer(2 * time.Second)
+	immediate := fake.NewTimer(0)
+
+	if fired := <-immediate.C(); !fired.Equal(start) {
+		t.Errorf("the immediate timer fired at %v", fired)
+	}
+	if !stopped.Stop() || stopped.Stop() {
+		t.Error("Stop() should only stop a pending timer once")
+	}
+
+	fake.Advance(1500 * time.Millisecond)
+	select {
+	case fired := <-early.C():
+		if !fired.Equal(start.Add(time.Second)) {
+			t.Errorf("the early timer fired at %v", fired)
+		}
+	default:
+		t.Fatal("the early timer didn't fire")
+	}
+	select {
+	case <-late.C():
+		t.Fatal("the late timer fired too soon")
+	default:
+	}
+
+	if pending := fake.Pending(); pending != 1 {
+		t.Errorf("Pending() = %d, want 1", pending)
+	}
+	fake.Advance(10 * time.Second)
+	<-late.C()
+	if now := fake.Now(); !now.Equal(start.Add(11500 * time.Millisecond)) {
+		t.Errorf("Now() = %v", now)
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	fake := NewFake(time.Now())
+	ctx := WithContext(context.Background(), fake)
+
+	done := make(chan error)
+	go func() {
+		done <- Sleep(ctx, FromContext(ctx), time.Hour)
+	}()
+
+	if err := fake.WaitForTimers(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(time.Hour)
+	if err := <-done; err != nil {
+		t.Errorf("Sleep() error = %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := Sleep(cancelled, fake, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Sleep() error = %v, want context.Canceled", err)
+	}
+	if _, ok := FromContext(context.Background()).(Real); !ok {
+		t.Error("FromContext() should default to the real clock")
+	}
+}
+
+func TestScaled(t *testing.T) {
+	scaled := NewScaled(3600)
+
+	start := time.Now()
+	before := scaled.Now()
+	if err := Sleep(context.Background(), scaled, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if took := time.Since(start); took < time.Second || took > 5*time.Second {
+		t.Errorf("an hour at 3600x took %v", took)
+	}
+	if passed := scaled.Now().Sub(before); passed < time.Hour {
+		t.Errorf("the clock moved by %v", passed)
+	}
+	if speed := NewScaled(0).Speed(); speed != 1 {
+		t.Errorf("Speed() = %g, want 1", speed)
+	}
+}
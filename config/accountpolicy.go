@@ -0,0 +1,136 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// MAX_CREDENTIAL_LENGTH is the size of the username and password fields of the login packet
+const MAX_CREDENTIAL_LENGTH = 14
+
+var (
+	ErrUsernameRefused = errors.New("username refused by the account policy")
+	ErrPasswordRefused = errors.New("password refused by the account policy")
+)
+
+// AccountPolicyType is what the accounts AutoCreate creates must look like. The client toolkit
+// checks the credentials of its profiles against the same policy, hence the json tags.
+type AccountPolicyType struct {
+	UsernameCharset   string `json:"usernameCharset,omitempty"`   // Characters and ranges such as a-z allowed in the usernames, any when empty
+	MinUsernameLength int    `json:"minUsernameLength,omitempty"` // 1 when 0
+	MaxUsernameLength int    `json:"maxUsernameLength,omitempty"` // MAX_CREDENTIAL_LENGTH when 0, which it can't exceed
+	MinPasswordLength int    `json:"minPasswordLength,omitempty"` // 1 when 0
+	PasswordClasses   int    `json:"passwordClasses,omitempty"`   // Classes among lower case, upper case, digits and symbols a password mixes at least
+	AccessLevel       int8   `json:"accessLevel,omitempty"`       // Access level of the created accounts, the player one when 0
+}
+
+// Validate checks that the policy can be met
+func (p AccountPolicyType) Validate() error {
+	if p.MinUsernameLength < 0 || p.MaxUsernameLength < 0 || p.MinPasswordLength < 0 {
+		return fmt.Errorf("the lengths of the account policy can't be negative")
+	}
+	if shortest, longest := p.UsernameLengths(); shortest > longest {
+		return fmt.Errorf("the usernames can't be longer than %d characters nor shorter than %d", longest, shortest)
+	}
+	if p.MinPasswordLength > MAX_CREDENTIAL_LENGTH {
+		return fmt.Errorf("the passwords can't be longer than %d characters", MAX_CREDENTIAL_LENGTH)
+	}
+	if p.PasswordClasses < 0 || p.PasswordClasses > 4 {
+		return fmt.Errorf("a password mixes at most 4 classes of characters, not %d", p.PasswordClasses)
+	}
+	if _, err := parseCharset(p.UsernameCharset); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UsernameLengths returns the shortest and the longest username allowed
+func (p AccountPolicyType) UsernameLengths() (shortest, longest int) {
+	shortest, longest = max(p.MinUsernameLength, 1), MAX_CREDENTIAL_LENGTH
+	if p.MaxUsernameLength > 0 {
+		longest = min(p.MaxUsernameLength, MAX_CREDENTIAL_LENGTH)
+	}
+	return shortest, longest
+}
+
+// CheckUsername returns ErrUsernameRefused when a username doesn't follow the policy
+func (p AccountPolicyType) CheckUsername(username string) error {
+	if shortest, longest := p.UsernameLengths(); len(username) < shortest || len(username) > longest {
+		return fmt.Errorf("%w: %q isn't between %d and %d characters long", ErrUsernameRefused, username, shortest, longest)
+	}
+
+	ranges, err := parseCharset(p.UsernameCharset)
+	if err != nil {
+		return err
+	}
+	if len(ranges) == 0 {
+		return nil
+	}
+	for _, char := range username {
+		if !inCharset(ranges, char) {
+			return fmt.Errorf("%w: %q isn't allowed in %q", ErrUsernameRefused, char, username)
+		}
+	}
+	return nil
+}
+
+// CheckPassword returns ErrPasswordRefused when a password doesn't follow the policy
+func (p AccountPolicyType) CheckPassword(password string) error {
+	if minimum := max(p.MinPasswordLength, 1); len(password) < minimum || len(password) > MAX_CREDENTIAL_LENGTH {
+		return fmt.Errorf("%w: it isn't between %d and %d characters long", ErrPasswordRefused, minimum, MAX_CREDENTIAL_LENGTH)
+	}
+
+	var lower, upper, digit, symbol int
+	for _, char := range password {
+		switch {
+		case unicode.IsLower(char):
+			lower = 1
+		case unicode.IsUpper(char):
+			upper = 1
+		case unicode.IsDigit(char):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	if classes := lower + upper + digit + symbol; classes < p.PasswordClasses {
+		return fmt.Errorf("%w: it mixes %d classes of characters out of the %d required", ErrPasswordRefused, classes, p.PasswordClasses)
+	}
+	return nil
+}
+
+// Check returns ErrUsernameRefused or ErrPasswordRefused when the credentials don't follow the policy
+func (p AccountPolicyType) Check(username, password string) error {
+	if err := p.CheckUsername(username); err != nil {
+		return err
+	}
+	return p.CheckPassword(password)
+}
+
+// parseCharset reads the ranges of a charset such as a-zA-Z0-9_, a single character being a range of its own
+func parseCharset(charset string) ([][2]rune, error) {
+	chars := []rune(charset)
+	var ranges [][2]rune
+	for i := 0; i < len(chars); i++ {
+		if i+2 < len(chars) && chars[i+1] == '-' {
+			if chars[i] > chars[i+2] {
+				return nil, fmt.Errorf("invalid range %s in the username charset", string(chars[i:i+3]))
+			}
+			ranges = append(ranges, [2]rune{chars[i], chars[i+2]})
+			i += 2
+			continue
+		}
+		ranges = append(ranges, [2]rune{chars[i], chars[i]})
+	}
+	return ranges, nil
+}
+
+func inCharset(ranges [][2]rune, char rune) bool {
+	for _, r := range ranges {
+		if char >= r[0] && char <= r[1] {
+			return true
+		}
+	}
+	return false
+}
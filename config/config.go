@@ -1,14 +1,16 @@
 package config
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"os/user"
+	"net"
+	"os"
+	"strconv"
+	"time"
 )
 
 var defaultServerConfig = `{
-  "loginserver": {
+  "loginServer": {
     "host": "127.0.0.1",
     "autoCreate": true,
     "database": {
@@ -20,8 +22,9 @@ var defaultServerConfig = `{
     } 
   },
 
-  "gameservers": [
+  "gameServers": [
     {
+      "id": 1,
       "name": "Bartz",
       "secret": "CHANGE_ME_PLEASE",
       "internalIP": "127.0.0.1",
@@ -61,11 +64,13 @@ type GameServerConfigObject struct {
 }
 
 type DatabaseType struct {
-	Name     string
-	Host     string
-	Port     int
-	User     string
-	Password string
+	Driver      string
+	Name        string
+	Host        string
+	Port        int
+	User        string
+	Password    string
+	WaitTimeout time.Duration // Time the server retries to reach the database at startup, as it may start after it
 }
 
 type CacheType struct {
@@ -75,13 +80,78 @@ type CacheType struct {
 }
 
 type LoginServerType struct {
-	Host       string
-	AutoCreate bool
-	Database   DatabaseType
+	Host               string
+	ListenAddress      string
+	GameServersAddress string
+	AutoCreate         bool
+	MaxPacketSize      int
+	ListPacketSize     int // Largest packet of the server list, split across several beyond, 8192 bytes when 0
+	HeartbeatInterval  time.Duration
+	MissedHeartbeats   int
+	AdminAddress       string
+	PreAuthTimeout     time.Duration // Time a client has to log in, negative to wait forever
+	IdleTimeout        time.Duration // Time a logged in client can stay silent, negative to wait forever
+	SessionLifetime    time.Duration // Time a session id can be used to pick a game server once issued, negative for no limit
+	SlowHandler        time.Duration // Time handling a client packet can take before being logged, negative to log none
+	MaxPacketRate      int           // Packets a client can send in a second before being disconnected as a flood, no limit when 0
+	GameGuard          string        // How RequestGGAuth is answered: static, ignore or strict, static when empty
+	AccountCreation    AccountCreationType
+	AccountPolicy      AccountPolicyType // What the usernames and passwords of the accounts AutoCreate creates must look like
+	Mode               string            // Who can log in at startup: normal, gm-only or maintenance
+	AccessTiers        []AccessTierType  // Capabilities of the access levels, the default tiers when empty
+	AdminAuth          bool              // Require the credentials of an account allowed to use the admin API, which is read only otherwise
+	Audit              AuditType
+	Diagnostics        DiagnosticsType
+	Database           DatabaseType
+	NullCrypto         bool            // Sends the packets in clear for debugging, refused unless the clients listener binds a private address
+	Compression        CompressionType // Of the links with the game servers
+}
+
+// AuditType is where the administrative and security events are recorded, and for how long
+type AuditType struct {
+	Path     string        // JSON lines file, the audit log is disabled when empty
+	MaxSize  int64         // Size of the file before it is rotated, in bytes
+	MaxFiles int           // Rotated files kept, negative to keep none
+	MaxAge   time.Duration // Rotated files older than this are removed, 0 to keep them regardless of their age
+}
+
+// DiagnosticsType serves the pprof profiles and the expvar variables, and dumps the goroutines on SIGUSR1,
+// to investigate the performance of a long running process without rebuilding it. The client toolkit
+// configuration has one too, hence the json tags.
+type DiagnosticsType struct {
+	Enabled       bool   `json:"enabled"`
+	Address       string `json:"address,omitempty"`       // Bind address of the endpoints, DEFAULT_DIAGNOSTICS_ADDRESS when empty
+	DumpDirectory string `json:"dumpDirectory,omitempty"` // Where the goroutine dumps are written, the standard error when empty
+}
+
+// CompressionType offers the zlib compression of the large packets to the other end of a link, the
+// packets being compressed once both ends offered it
+type CompressionType struct {
+	Enabled   bool
+	Threshold int // Smallest packet compressed, protocol.DefaultCompressionThreshold when 0
+}
+
+// AccessTierType grants capabilities to the access levels from Level up to the next tier
+type AccessTierType struct {
+	Name         string
+	Level        int8
+	Capabilities []string
+}
+
+// AccountCreationType limits the accounts AutoCreate creates
+type AccountCreationType struct {
+	PerAddress       int           // Accounts an IP address can create per window, 0 for no limit
+	Window           time.Duration // Window of the per address limit
+	PerDay           int           // Accounts created per day in total, 0 for no limit
+	ChallengeURL     string        // Asked before creating an account, which is refused unless it answers with a 2xx status
+	ChallengeToken   string        // Sent as a bearer token to the challenge URL
+	ChallengeTimeout time.Duration
 }
 
 type GameServerType struct {
+	Id         uint8
 	Name       string
+	Secret     string
 	InternalIP string
 	ExternalIP string
 	Port       int
@@ -91,23 +161,459 @@ type GameServerType struct {
 }
 
 type OptionsType struct {
-	MaxPlayers uint16
-	Testing    bool
+	MaxPlayers     uint16
+	MaxCharacters  int // Characters an account can create on this game server, DEFAULT_MAX_CHARACTERS when 0
+	Testing        bool
+	MaxPacketSize  int
+	ListPacketSize int           // Largest packet of the inventories and the warehouses, split across several beyond, 8192 bytes when 0
+	NameBlocklist  string        // File of forbidden words and reserved names, reloaded when it changes
+	DataDirectory  string        // Holds the HTML dialogs and the other game data files
+	Chronicle      string        // Character templates used when the data directory has none, the bundled c1 ones when empty
+	DeathPenalty   float64       // Percentage of the experience of their level the players lose when dying, negative for none
+	AutoLoot       bool          // The drops go straight to the killer instead of the ground
+	LootProtection time.Duration // Time only the killer can pick up its drops, negative for none
+	InventorySlots int           // Kinds of items a player can carry, adena included, the ones of its level in capacity.json when 0
+	WeightLimit    int           // Weight of the items a player can carry, the one of its level in capacity.json when 0
+	ThinkInterval  time.Duration // Time between two decisions of the NPCs, negative to disable their AI
+	TicksPerSecond int           // Rate of the game loop moving the NPCs and running the regeneration and the effects
+	GameTimeEpoch  int64         // Unix time the world was at midnight of its first day, the start of the game server when 0
+	GameTimeSpeed  float64       // Times faster than the real time the world runs, DEFAULT_GAME_TIME_SPEED when 0 or less
+	AIShards       int           // Goroutines sharing the NPCs during a tick of their AI, one per CPU when 0
+	SaveInterval   time.Duration // Time a changed character waits before being saved, negative to only save on logout and shutdown
+	SaveBatchSize  int           // Characters written at most per tick of the game loop
+	MaxMoveSpeed   int           // Fastest a player can move, in units per second, negative to trust the positions of the clients
+	MoveTolerance  int           // Distance a reported position can be off by, for the latency
+	MaxMoveJump    int           // Distance a reported position can't jump by, however long since the previous one
+	SendQueueSize  int           // Packets queued per client before it counts as a slow consumer, negative to write them synchronously
+	SendPolicy     string        // Which packets a saturated send queue sheds first: watermarks, the default, or fifo
+	DropBroadcasts bool          // A slow client misses the packets which aren't critical instead of being disconnected
+	PreAuthTimeout time.Duration // Time a client has to authenticate, negative to wait forever
+	IdleTimeout    time.Duration // Time an authenticated client can stay silent, negative to wait forever
+	SlowHandler    time.Duration // Time handling a client packet can take before being logged, negative to log none
+	AdminAddress   string        // Serves the snapshot of the world for debugging, without authentication, disabled when empty
+	BotDetection   BotDetectionType
+	Diagnostics    DiagnosticsType
+	NullCrypto     bool            // Sends the packets in clear for debugging, only listening on the internal IP, which must be private
+	Compression    CompressionType // Of the connections of the clients and of the link with the login server
 }
 
-func Read() ConfigObject {
-	usr, _ := user.Current()
-	dir := usr.HomeDir
+// BotDetectionType scores the behavior of the players, reporting the ones which look automated
+type BotDetectionType struct {
+	Enabled       bool
+	Action        string  // What happens to a suspected player: flag, the default, jail or kick
+	Threshold     float64 // Score from 0 to 1 a player is suspected from, DEFAULT_BOT_THRESHOLD when 0
+	Window        int     // Last actions and moves of a player scored, DEFAULT_BOT_WINDOW when 0
+	MaxActionRate float64 // Actions per second no human makes, DEFAULT_MAX_ACTION_RATE when 0, negative for no limit
+}
+
+const (
+	DATABASE_DRIVER_MYSQL  = "mysql"
+	DATABASE_DRIVER_MEMORY = "memory"
+
+	DEFAULT_LOGIN_LISTEN_ADDRESS      = ":2106"
+	DEFAULT_LOGIN_GAMESERVERS_ADDRESS = ":9413"
+
+	// Client packets are small, anything bigger is most likely a flood attempt
+	DEFAULT_LOGIN_MAX_PACKET_SIZE = 1024
+	DEFAULT_GAME_MAX_PACKET_SIZE  = 8192
+
+	DEFAULT_HEARTBEAT_INTERVAL = 5 * time.Second
+	DEFAULT_MISSED_HEARTBEATS  = 3
+
+	DEFAULT_NAME_BLOCKLIST_RELOAD_INTERVAL = 30 * time.Second
+
+	DEFAULT_DATA_DIRECTORY = "data"
+
+	// The character selection of the client shows 7 slots
+	DEFAULT_MAX_CHARACTERS = 7
+
+	DEFAULT_DEATH_PENALTY   = 4.0
+	DEFAULT_LOOT_PROTECTION = 15 * time.Second
+	DEFAULT_THINK_INTERVAL  = time.Second
+	DEFAULT_TICK_RATE       = 10
+	DEFAULT_GAME_TIME_SPEED = 6 // A day of the world lasts 4 real hours
+	DEFAULT_SAVE_INTERVAL   = time.Minute
+	DEFAULT_SAVE_BATCH_SIZE = 50
+	DEFAULT_MAX_MOVE_SPEED  = 300
+	DEFAULT_MOVE_TOLERANCE  = 150
+	DEFAULT_MAX_MOVE_JUMP   = 2000
+	DEFAULT_SEND_QUEUE_SIZE = 512
+
+	DEFAULT_BOT_ACTION      = "flag"
+	DEFAULT_BOT_THRESHOLD   = 0.9
+	DEFAULT_BOT_WINDOW      = 32
+	DEFAULT_MAX_ACTION_RATE = 10
+
+	// Half-open connections are dropped, the clients still logging in get less time
+	DEFAULT_PRE_AUTH_TIMEOUT   = 30 * time.Second
+	DEFAULT_LOGIN_IDLE_TIMEOUT = 2 * time.Minute
+	DEFAULT_SESSION_LIFETIME   = 5 * time.Minute
+	DEFAULT_GAME_IDLE_TIMEOUT  = 15 * time.Minute
+
+	// The logins check the password hashes, which is slow by design
+	DEFAULT_LOGIN_SLOW_HANDLER = 500 * time.Millisecond
+	DEFAULT_GAME_SLOW_HANDLER  = 50 * time.Millisecond
+
+	DEFAULT_AUDIT_MAX_SIZE  = 100 * 1024 * 1024
+	DEFAULT_AUDIT_MAX_FILES = 10
+
+	// The profiles expose the internals of the process, they are only served locally unless told otherwise
+	DEFAULT_DIAGNOSTICS_ADDRESS = "127.0.0.1:6060"
+
+	DEFAULT_ACCOUNT_CREATION_WINDOW = time.Hour
+	DEFAULT_CHALLENGE_TIMEOUT       = 5 * time.Second
+)
+
+var ErrNullCryptoRefused = errors.New("null crypto refused")
+
+// DSN returns the data source name of the MySQL driver
+func (d DatabaseType) DSN() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", d.User, d.Password, d.Host, d.Port, d.Name)
+}
+
+// IsMemory reports whether the database lives in memory instead of MySQL
+func (d DatabaseType) IsMemory() bool {
+	return d.Driver == DATABASE_DRIVER_MEMORY
+}
+
+// ClientsAddress returns the address the login server listens on for clients
+func (l LoginServerType) ClientsAddress() string {
+	if l.ListenAddress == "" {
+		return DEFAULT_LOGIN_LISTEN_ADDRESS
+	}
+	return l.ListenAddress
+}
+
+// CheckNullCrypto refuses the null crypto debug mode unless the clients listener binds a private address
+func (l LoginServerType) CheckNullCrypto() error {
+	if !l.NullCrypto || IsPrivateAddress(l.ClientsAddress()) {
+		return nil
+	}
+	return fmt.Errorf("%w: the login server listens for clients on %s, which isn't a private address", ErrNullCryptoRefused, l.ClientsAddress())
+}
+
+// GameServersListenAddress returns the address the login server listens on for game servers
+func (l LoginServerType) GameServersListenAddress() string {
+	if l.GameServersAddress == "" {
+		return DEFAULT_LOGIN_GAMESERVERS_ADDRESS
+	}
+	return l.GameServersAddress
+}
+
+// GameServersDialAddress returns the address game servers use to reach the login server
+func (l LoginServerType) GameServersDialAddress() string {
+	_, port, err := net.SplitHostPort(l.GameServersListenAddress())
+	if err != nil {
+		port = "9413"
+	}
+	return net.JoinHostPort(l.Host, port)
+}
+
+// HeartbeatPeriod returns how often game servers report to the login server
+func (l LoginServerType) HeartbeatPeriod() time.Duration {
+	if l.HeartbeatInterval <= 0 {
+		return DEFAULT_HEARTBEAT_INTERVAL
+	}
+	return l.HeartbeatInterval
+}
+
+// HeartbeatTimeout returns how long a silent game server is considered alive
+func (l LoginServerType) HeartbeatTimeout() time.Duration {
+	missed := l.MissedHeartbeats
+	if missed <= 0 {
+		missed = DEFAULT_MISSED_HEARTBEATS
+	}
+	return time.Duration(missed) * l.HeartbeatPeriod()
+}
+
+// PacketSizeLimit returns the largest packet accepted from a client, header included
+func (l LoginServerType) PacketSizeLimit() int {
+	if l.MaxPacketSize <= 0 {
+		return DEFAULT_LOGIN_MAX_PACKET_SIZE
+	}
+	return l.MaxPacketSize
+}
+
+// ClientPreAuthTimeout returns how long a client can stay silent before logging in, 0 meaning forever
+func (l LoginServerType) ClientPreAuthTimeout() time.Duration {
+	return timeout(l.PreAuthTimeout, DEFAULT_PRE_AUTH_TIMEOUT)
+}
+
+// ClientIdleTimeout returns how long a logged in client can stay silent, 0 meaning forever
+func (l LoginServerType) ClientIdleTimeout() time.Duration {
+	return timeout(l.IdleTimeout, DEFAULT_LOGIN_IDLE_TIMEOUT)
+}
+
+// SlowHandlerThreshold returns how long handling a client packet can take before being logged, 0 meaning never
+func (l LoginServerType) SlowHandlerThreshold() time.Duration {
+	return timeout(l.SlowHandler, DEFAULT_LOGIN_SLOW_HANDLER)
+}
+
+// SessionTTL returns how long a session id is accepted once issued, 0 meaning forever
+func (l LoginServerType) SessionTTL() time.Duration {
+	return timeout(l.SessionLifetime, DEFAULT_SESSION_LIFETIME)
+}
+
+// RotationSize returns the size of the audit log once it is rotated
+func (a AuditType) RotationSize() int64 {
+	if a.MaxSize <= 0 {
+		return DEFAULT_AUDIT_MAX_SIZE
+	}
+	return a.MaxSize
+}
+
+// RotationFiles returns how many rotated audit logs are kept
+func (a AuditType) RotationFiles() int {
+	switch {
+	case a.MaxFiles < 0:
+		return 0
+	case a.MaxFiles == 0:
+		return DEFAULT_AUDIT_MAX_FILES
+	}
+	return a.MaxFiles
+}
+
+// CreationWindow returns the window of the per address account creation limit
+func (a AccountCreationType) CreationWindow() time.Duration {
+	if a.Window <= 0 {
+		return DEFAULT_ACCOUNT_CREATION_WINDOW
+	}
+	return a.Window
+}
+
+// ChallengeDeadline returns how long the challenge URL has to answer
+func (a AccountCreationType) ChallengeDeadline() time.Duration {
+	if a.ChallengeTimeout <= 0 {
+		return DEFAULT_CHALLENGE_TIMEOUT
+	}
+	return a.ChallengeTimeout
+}
 
-	var jsontype ConfigObject
-	file, e := ioutil.ReadFile(dir + "/.l2go/config/server.json")
+// ServerID returns the id of the game server, which defaults to its position in the list (starting at 1)
+func (g GameServerType) ServerID(index int) uint8 {
+	if g.Id != 0 {
+		return g.Id
+	}
+	return uint8(index + 1)
+}
+
+// ClientsAddress returns the address the game server listens on for clients, every interface unless
+// the packets go in clear
+func (g GameServerType) ClientsAddress() string {
+	if g.Options.NullCrypto {
+		return net.JoinHostPort(g.InternalIP, strconv.Itoa(g.Port))
+	}
+	return ":" + strconv.Itoa(g.Port)
+}
+
+// CheckNullCrypto refuses the null crypto debug mode unless the internal IP of the game server is private
+func (g GameServerType) CheckNullCrypto() error {
+	if !g.Options.NullCrypto || IsPrivateAddress(g.ClientsAddress()) {
+		return nil
+	}
+	return fmt.Errorf("%w: the game server would listen for clients on %s, which isn't a private address", ErrNullCryptoRefused, g.ClientsAddress())
+}
+
+// IsPrivateAddress reports whether a host, or the host of an address, only reaches the machine or its
+// private network: localhost, a loopback IP or a private one. An empty host binds every interface.
+func IsPrivateAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
+
+// PacketSizeLimit returns the largest packet accepted from a client, header included
+func (o OptionsType) PacketSizeLimit() int {
+	if o.MaxPacketSize <= 0 {
+		return DEFAULT_GAME_MAX_PACKET_SIZE
+	}
+	return o.MaxPacketSize
+}
 
-	if e != nil {
-		fmt.Println("Couldn't load the server configuration file. Using the default preset.")
-		json.Unmarshal([]byte(defaultServerConfig), &jsontype)
-	} else {
-		json.Unmarshal(file, &jsontype)
+// CharacterSlots returns how many characters an account can create on the game server
+func (o OptionsType) CharacterSlots() int {
+	if o.MaxCharacters <= 0 {
+		return DEFAULT_MAX_CHARACTERS
 	}
+	return o.MaxCharacters
+}
+
+// ClientPreAuthTimeout returns how long a client can stay silent before authenticating, 0 meaning forever
+func (o OptionsType) ClientPreAuthTimeout() time.Duration {
+	return timeout(o.PreAuthTimeout, DEFAULT_PRE_AUTH_TIMEOUT)
+}
+
+// ClientIdleTimeout returns how long an authenticated client can stay silent, 0 meaning forever
+func (o OptionsType) ClientIdleTimeout() time.Duration {
+	return timeout(o.IdleTimeout, DEFAULT_GAME_IDLE_TIMEOUT)
+}
 
-	return jsontype
+// SlowHandlerThreshold returns how long handling a client packet can take before being logged, 0 meaning never
+func (o OptionsType) SlowHandlerThreshold() time.Duration {
+	return timeout(o.SlowHandler, DEFAULT_GAME_SLOW_HANDLER)
+}
+
+// timeout applies the default of an unset timeout, the negative ones being disabled
+func timeout(value, defaultValue time.Duration) time.Duration {
+	switch {
+	case value < 0:
+		return 0
+	case value == 0:
+		return defaultValue
+	}
+	return value
+}
+
+// DataPath returns the directory holding the game data files
+func (o OptionsType) DataPath() string {
+	if o.DataDirectory == "" {
+		return DEFAULT_DATA_DIRECTORY
+	}
+	return o.DataDirectory
+}
+
+// DeathPenaltyPercent returns the percentage of the experience of their level the players lose when dying
+func (o OptionsType) DeathPenaltyPercent() float64 {
+	switch {
+	case o.DeathPenalty < 0:
+		return 0
+	case o.DeathPenalty == 0:
+		return DEFAULT_DEATH_PENALTY
+	}
+	return o.DeathPenalty
+}
+
+// LootProtectionTime returns how long only the killer can pick up its drops, 0 meaning anyone can right away
+func (o OptionsType) LootProtectionTime() time.Duration {
+	return timeout(o.LootProtection, DEFAULT_LOOT_PROTECTION)
+}
+
+// ThinkPeriod returns the time between two decisions of the NPCs, 0 meaning their AI is disabled
+func (o OptionsType) ThinkPeriod() time.Duration {
+	return timeout(o.ThinkInterval, DEFAULT_THINK_INTERVAL)
+}
+
+// TickRate returns how many times per second the game loop ticks
+func (o OptionsType) TickRate() int {
+	if o.TicksPerSecond <= 0 {
+		return DEFAULT_TICK_RATE
+	}
+	return o.TicksPerSecond
+}
+
+// GameTimeMultiplier returns how many times faster than the real time the world runs
+func (o OptionsType) GameTimeMultiplier() float64 {
+	if o.GameTimeSpeed <= 0 {
+		return DEFAULT_GAME_TIME_SPEED
+	}
+	return o.GameTimeSpeed
+}
+
+// SavePeriod returns how long a changed character waits before being saved, 0 meaning only on logout and shutdown
+func (o OptionsType) SavePeriod() time.Duration {
+	return timeout(o.SaveInterval, DEFAULT_SAVE_INTERVAL)
+}
+
+// SaveBatch returns how many characters are written at most per tick of the game loop
+func (o OptionsType) SaveBatch() int {
+	if o.SaveBatchSize <= 0 {
+		return DEFAULT_SAVE_BATCH_SIZE
+	}
+	return o.SaveBatchSize
+}
+
+// MoveSpeedLimit returns the fastest a player can move in units per second, 0 meaning the positions of the clients are trusted
+func (o OptionsType) MoveSpeedLimit() int {
+	if o.MaxMoveSpeed < 0 {
+		return 0
+	}
+	if o.MaxMoveSpeed == 0 {
+		return DEFAULT_MAX_MOVE_SPEED
+	}
+	return o.MaxMoveSpeed
+}
+
+// MoveToleranceDistance returns the distance a reported position can be off by
+func (o OptionsType) MoveToleranceDistance() int {
+	if o.MoveTolerance <= 0 {
+		return DEFAULT_MOVE_TOLERANCE
+	}
+	return o.MoveTolerance
+}
+
+// MoveJumpLimit returns the distance a reported position can't jump by
+func (o OptionsType) MoveJumpLimit() int {
+	if o.MaxMoveJump <= 0 {
+		return DEFAULT_MAX_MOVE_JUMP
+	}
+	return o.MaxMoveJump
+}
+
+// SendQueueCapacity returns how many packets are queued per client, 0 meaning they are written synchronously
+func (o OptionsType) SendQueueCapacity() int {
+	if o.SendQueueSize < 0 {
+		return 0
+	}
+	if o.SendQueueSize == 0 {
+		return DEFAULT_SEND_QUEUE_SIZE
+	}
+	return o.SendQueueSize
+}
+
+// SuspectAction returns what happens to a suspected player
+func (b BotDetectionType) SuspectAction() string {
+	if b.Action == "" {
+		return DEFAULT_BOT_ACTION
+	}
+	return b.Action
+}
+
+// SuspectThreshold returns the score a player is suspected from
+func (b BotDetectionType) SuspectThreshold() float64 {
+	if b.Threshold <= 0 {
+		return DEFAULT_BOT_THRESHOLD
+	}
+	return b.Threshold
+}
+
+// HistoryWindow returns how many of the last actions and moves of a player are scored
+func (b BotDetectionType) HistoryWindow() int {
+	if b.Window <= 0 {
+		return DEFAULT_BOT_WINDOW
+	}
+	return b.Window
+}
+
+// ActionRateLimit returns the actions per second no human makes, 0 meaning no limit
+func (b BotDetectionType) ActionRateLimit() float64 {
+	switch {
+	case b.MaxActionRate < 0:
+		return 0
+	case b.MaxActionRate == 0:
+		return DEFAULT_MAX_ACTION_RATE
+	}
+	return b.MaxActionRate
+}
+
+// ListenAddress returns the bind address of the diagnostics endpoints
+func (d DiagnosticsType) ListenAddress() string {
+	if d.Address == "" {
+		return DEFAULT_DIAGNOSTICS_ADDRESS
+	}
+	return d.Address
+}
+
+// Read loads the configuration of the process, see Load
+func Read() ConfigObject {
+	cfg, err := Load(os.LookupEnv)
+	if err != nil {
+		fmt.Println(err)
+	}
+	return cfg
 }
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckNullCrypto(t *testing.T) {
+	tests := []struct {
+		name    string
+		login   LoginServerType
+		game    GameServerType
+		refused bool
+	}{
+		{name: "disabled on every interface", login: LoginServerType{ListenAddress: ":2106"}, game: GameServerType{Port: 7777}},
+		{name: "loopback", login: LoginServerType{ListenAddress: "127.0.0.1:2106", NullCrypto: true}, game: GameServerType{InternalIP: "127.0.0.1", Port: 7777, Options: OptionsType{NullCrypto: true}}},
+		{name: "private network", login: LoginServerType{ListenAddress: "10.0.0.5:2106", NullCrypto: true}, game: GameServerType{InternalIP: "192.168.1.2", Port: 7777, Options: OptionsType{NullCrypto: true}}},
+		{name: "localhost", login: LoginServerType{ListenAddress: "localhost:2106", NullCrypto: true}, game: GameServerType{InternalIP: "localhost", Port: 7777, Options: OptionsType{NullCrypto: true}}},
+		{name: "every interface", login: LoginServerType{NullCrypto: true}, game: GameServerType{Port: 7777, Options: OptionsType{NullCrypto: true}}, refused: true},
+		{name: "public", login: LoginServerType{ListenAddress: "203.0.113.7:2106", NullCrypto: true}, game: GameServerType{InternalIP: "203.0.113.7", Port: 7777, Options: OptionsType{NullCrypto: true}}, refused: true},
+		{name: "host name", login: LoginServerType{ListenAddress: "login.example.com:2106", NullCrypto: true}, game: GameServerType{InternalIP: "game.example.com", Port: 7777, Options: OptionsType{NullCrypto: true}}, refused: true},
+	}
+
+	for _, tt := range tests {
+		for _, err := range []error{tt.login.CheckNullCrypto(), tt.game.CheckNullCrypto()} {
+			if refused := errors.Is(err, ErrNullCryptoRefused); refused != tt.refused {
+				t.Errorf("%s: CheckNullCrypto() = %v, want refused %v", tt.name, err, tt.refused)
+			}
+		}
+	}
+
+	game := GameServerType{InternalIP: "127.0.0.1", Port: 7777}
+	if address := game.ClientsAddress(); address != ":7777" {
+		t.Errorf("ClientsAddress() = %s, want every interface", address)
+	}
+	game.Options.NullCrypto = true
+	if address := game.ClientsAddress(); address != "127.0.0.1:7777" {
+		t.Errorf("ClientsAddress() in clear = %s, want the internal IP only", address)
+	}
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ENV_PREFIX starts the name of every environment variable overriding the configuration
+const ENV_PREFIX = "L2GO_"
+
+// ENV_CONFIG_FILE names the configuration file to read instead of ~/.l2go/config/server.json
+const ENV_CONFIG_FILE = ENV_PREFIX + "CONFIG_FILE"
+
+var ErrInvalidEnv = errors.New("invalid environment variable")
+
+// Env looks an environment variable up, os.LookupEnv outside the tests
+type Env func(name string) (string, bool)
+
+// FromEnv sets a field from an environment variable when it is set, parsing it as the type of the field
+func FromEnv[T string | bool | int | int64 | uint8 | uint16 | float64 | time.Duration](env Env, name string, field *T) error {
+	value, ok := env(name)
+	if !ok {
+		return nil
+	}
+
+	var err error
+	switch field := any(field).(type) {
+	case *string:
+		*field = value
+	case *bool:
+		*field, err = strconv.ParseBool(value)
+	case *int:
+		*field, err = strconv.Atoi(value)
+	case *int64:
+		*field, err = strconv.ParseInt(value, 10, 64)
+	case *uint8:
+		var parsed uint64
+		parsed, err = strconv.ParseUint(value, 10, 8)
+		*field = uint8(parsed)
+	case *uint16:
+		var parsed uint64
+		parsed, err = strconv.ParseUint(value, 10, 16)
+		*field = uint16(parsed)
+	case *float64:
+		*field, err = strconv.ParseFloat(value, 64)
+	case *time.Duration:
+		*field, err = time.ParseDuration(value)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s=%q: %v", ErrInvalidEnv, name, value, err)
+	}
+	return nil
+}
+
+// ApplyEnv overrides the configuration with the L2GO_ environment variables, so a container can be
+// configured without a file. L2GO_GAMESERVERS lists the ids of the game servers, adding the ones
+// the configuration lacks, and L2GO_GAMESERVER_<id>_ prefixes the variables of each of them.
+func (c *ConfigObject) ApplyEnv(env Env) error {
+	errs := []error{c.LoginServer.ApplyEnv(env)}
+
+	if ids, ok := env(ENV_PREFIX + "GAMESERVERS"); ok {
+		for _, field := range strings.Split(ids, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(field), 10, 8)
+			if err != nil || id == 0 {
+				errs = append(errs, fmt.Errorf("%w: %sGAMESERVERS=%q: %q isn't a game server id", ErrInvalidEnv, ENV_PREFIX, ids, field))
+				continue
+			}
+			if c.gameServer(uint8(id)) < 0 {
+				c.GameServers = append(c.GameServers, GameServerType{Id: uint8(id)})
+			}
+		}
+	}
+
+	for index := range c.GameServers {
+		prefix := fmt.Sprintf("%sGAMESERVER_%d_", ENV_PREFIX, c.GameServers[index].ServerID(index))
+		errs = append(errs, c.GameServers[index].ApplyEnv(env, prefix))
+	}
+	return errors.Join(errs...)
+}
+
+// gameServer returns the index of the game server with the given id, or -1
+func (c *ConfigObject) gameServer(id uint8) int {
+	for index, gameserver := range c.GameServers {
+		if gameserver.ServerID(index) == id {
+			return index
+		}
+	}
+	return -1
+}
+
+// ApplyEnv overrides the login server configuration with the L2GO_LOGIN_ environment variables
+func (l *LoginServerType) ApplyEnv(env Env) error {
+	prefix := ENV_PREFIX + "LOGIN_"
+	return errors.Join(
+		FromEnv(env, prefix+"HOST", &l.Host),
+		FromEnv(env, prefix+"LISTEN_ADDRESS", &l.ListenAddress),
+		FromEnv(env, prefix+"GAMESERVERS_ADDRESS", &l.GameServersAddress),
+		FromEnv(env, prefix+"ADMIN_ADDRESS", &l.AdminAddress),
+		FromEnv(env, prefix+"AUTO_CREATE", &l.AutoCreate),
+		FromEnv(env, prefix+"MODE", &l.Mode),
+		FromEnv(env, prefix+"ADMIN_AUTH", &l.AdminAuth),
+		FromEnv(env, prefix+"AUDIT_PATH", &l.Audit.Path),
+		FromEnv(env, prefix+"NULL_CRYPTO", &l.NullCrypto),
+		FromEnv(env, prefix+"COMPRESSION", &l.Compression.Enabled),
+		l.Diagnostics.ApplyEnv(env, prefix+"DIAGNOSTICS_"),
+		l.Database.ApplyEnv(env, prefix+"DB_"),
+	)
+}
+
+// ApplyEnv overrides the configuration of a game server with the environment variables starting with prefix.
+// EXTERNAL_IP overrides the address advertised to the clients, which differs from the one of the container.
+func (g *GameServerType) ApplyEnv(env Env, prefix string) error {
+	return errors.Join(
+		FromEnv(env, prefix+"NAME", &g.Name),
+		FromEnv(env, prefix+"SECRET", &g.Secret),
+		FromEnv(env, prefix+"INTERNAL_IP", &g.InternalIP),
+		FromEnv(env, prefix+"EXTERNAL_IP", &g.ExternalIP),
+		FromEnv(env, prefix+"PORT", &g.Port),
+		FromEnv(env, prefix+"MAX_PLAYERS", &g.Options.MaxPlayers),
+		FromEnv(env, prefix+"TESTING", &g.Options.Testing),
+		FromEnv(env, prefix+"DATA_DIRECTORY", &g.Options.DataDirectory),
+		FromEnv(env, prefix+"ADMIN_ADDRESS", &g.Options.AdminAddress),
+		FromEnv(env, prefix+"BOT_DETECTION", &g.Options.BotDetection.Enabled),
+		FromEnv(env, prefix+"BOT_ACTION", &g.Options.BotDetection.Action),
+		FromEnv(env, prefix+"NULL_CRYPTO", &g.Options.NullCrypto),
+		FromEnv(env, prefix+"COMPRESSION", &g.Options.Compression.Enabled),
+		g.Options.Diagnostics.ApplyEnv(env, prefix+"DIAGNOSTICS_"),
+		g.Database.ApplyEnv(env, prefix+"DB_"),
+	)
+}
+
+// ApplyEnv overrides a database configuration with the environment variables starting with prefix
+func (d *DatabaseType) ApplyEnv(env Env, prefix string) error {
+	return errors.Join(
+		FromEnv(env, prefix+"DRIVER", &d.Driver),
+		FromEnv(env, prefix+"NAME", &d.Name),
+		FromEnv(env, prefix+"HOST", &d.Host),
+		FromEnv(env, prefix+"PORT", &d.Port),
+		FromEnv(env, prefix+"USER", &d.User),
+		FromEnv(env, prefix+"PASSWORD", &d.Password),
+		FromEnv(env, prefix+"WAIT", &d.WaitTimeout),
+	)
+}
+
+// ApplyEnv overrides the diagnostics configuration with the environment variables starting with prefix
+func (d *DiagnosticsType) ApplyEnv(env Env, prefix string) error {
+	return errors.Join(
+		FromEnv(env, prefix+"ENABLED", &d.Enabled),
+		FromEnv(env, prefix+"ADDRESS", &d.Address),
+		FromEnv(env, prefix+"DUMP_DIRECTORY", &d.DumpDirectory),
+	)
+}
+
+// Load reads the configuration file named by L2GO_CONFIG_FILE, or ~/.l2go/config/server.json, falling
+// back to the default preset when there is none, then applies the environment variables
+func Load(env Env) (ConfigObject, error) {
+	var cfg ConfigObject
+
+	filename, named := env(ENV_CONFIG_FILE)
+	if !named {
+		if home, err := os.UserHomeDir(); err == nil {
+			filename = home + "/.l2go/config/server.json"
+		}
+	}
+
+	file, err := os.ReadFile(filename)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(file, &cfg); err != nil {
+			return cfg, fmt.Errorf("couldn't parse the configuration file %s: %w", filename, err)
+		}
+	case named:
+		return cfg, fmt.Errorf("couldn't read the configuration file: %w", err)
+	default:
+		fmt.Println("Couldn't load the server configuration file. Using the default preset.")
+		json.Unmarshal([]byte(defaultServerConfig), &cfg)
+	}
+
+	if err := cfg.ApplyEnv(env); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
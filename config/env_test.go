@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	cfg := ConfigObject{GameServers: []GameServerType{{Id: 1, Name: "Bartz", ExternalIP: "192.168.1.2", Port: 7777}}}
+	env := map[string]string{
+		"L2GO_LOGIN_HOST":                       "login",
+		"L2GO_LOGIN_DB_HOST":                    "mysql",
+		"L2GO_LOGIN_DB_WAIT":                    "1m",
+		"L2GO_GAMESERVERS":                      "1, 2",
+		"L2GO_GAMESERVER_1_EXTERNAL_IP":         "203.0.113.7",
+		"L2GO_GAMESERVER_1_BOT_DETECTION":       "true",
+		"L2GO_GAMESERVER_1_BOT_ACTION":          "kick",
+		"L2GO_GAMESERVER_2_NAME":                "Sieghardt",
+		"L2GO_GAMESERVER_2_PORT":                "7778",
+		"L2GO_GAMESERVER_2_MAX_PLAYERS":         "500",
+		"L2GO_GAMESERVER_2_DB_DRIVER":           DATABASE_DRIVER_MEMORY,
+		"L2GO_GAMESERVER_2_DIAGNOSTICS_ENABLED": "true",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	if err := cfg.ApplyEnv(lookup); err != nil {
+		t.Fatalf("ApplyEnv() error = %v", err)
+	}
+
+	login := cfg.LoginServer
+	if login.Host != "login" || login.Database.Host != "mysql" || login.Database.WaitTimeout.Minutes() != 1 {
+		t.Errorf("login server = %+v", login)
+	}
+	if len(cfg.GameServers) != 2 {
+		t.Fatalf("got %d game servers, want the configured one and the one of the environment", len(cfg.GameServers))
+	}
+	if first := cfg.GameServers[0]; first.Name != "Bartz" || first.ExternalIP != "203.0.113.7" || first.Port != 7777 || !first.Options.BotDetection.Enabled || first.Options.BotDetection.Action != "kick" {
+		t.Errorf("first game server = %+v", first)
+	}
+	second := cfg.GameServers[1]
+	if second.Id != 2 || second.Name != "Sieghardt" || second.Port != 7778 || second.Options.MaxPlayers != 500 || !second.Database.IsMemory() || !second.Options.Diagnostics.Enabled {
+		t.Errorf("second game server = %+v", second)
+	}
+
+	for name, value := range map[string]string{
+		"L2GO_GAMESERVER_1_PORT":        "port",
+		"L2GO_GAMESERVER_1_MAX_PLAYERS": "70000",
+		"L2GO_LOGIN_AUTO_CREATE":        "maybe",
+		"L2GO_GAMESERVERS":              "1,x",
+	} {
+		env := map[string]string{name: value}
+		err := cfg.ApplyEnv(func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		})
+		if !errors.Is(err, ErrInvalidEnv) {
+			t.Errorf("ApplyEnv() with %s=%q error = %v, want ErrInvalidEnv", name, value, err)
+		}
+	}
+}
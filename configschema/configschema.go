@@ -0,0 +1,258 @@
+// Package configschema describes the configuration files of the client toolkit and of the servers as JSON
+// Schemas, so that editors complete and validate them. The schemas are generated from the Go structs the
+// files are decoded into, and the copies next to this file are kept in sync with them by go generate:
+//
+//	go generate github.com/frostwind/l2go/configschema
+package configschema
+
+//go:generate go run github.com/frostwind/l2go config schema -o toolkit.schema.json toolkit
+//go:generate go run github.com/frostwind/l2go config schema -o server.schema.json server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/config"
+)
+
+// DRAFT is the version of JSON Schema the schemas follow
+const DRAFT = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema, limited to the keywords the configurations need
+type Schema struct {
+	Draft                string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 any                `json:"type,omitempty"` // A type name, or a list of them
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"` // false, or the schema of the values of a map
+	PropertyNames        *Schema            `json:"propertyNames,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Write writes a schema as indented json
+func (s *Schema) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// enumKey names a field of a struct whose values, or whose keys for a map, are restricted
+type enumKey struct {
+	typ   reflect.Type
+	field string
+}
+
+// stateNames returns the names of the states of the clients, which key their timeouts
+func stateNames() []string {
+	var names []string
+	for state := client.StateDisconnected; state <= client.StateError; state++ {
+		names = append(names, state.String())
+	}
+	return names
+}
+
+// enums lists the values the fields of the configurations accept, the empty string standing for the
+// fields which fall back to a default when unset
+var enums = map[enumKey][]string{
+	{reflect.TypeFor[client.LoadTestConfig](), "ReportFormat"}: {"json", "xml", "csv", "text", "junit"},
+	{reflect.TypeFor[client.LoggingConfig](), "Level"}:         {"debug", "info", "warn", "error"},
+	{reflect.TypeFor[client.LoggingConfig](), "Format"}:        {"json", "text"},
+	{reflect.TypeFor[client.ProfilesConfig](), "Active"}:       {"development", "testing", "production"},
+	{reflect.TypeFor[client.FleetConfig](), "Profile"}:         {"", "development", "testing", "production"},
+	{reflect.TypeFor[client.LoadShape](), "Type"}: {"", client.ShapeLinear, client.ShapeStep, client.ShapeSpike,
+		client.ShapeSine, client.ShapeSoak, client.ShapeAdaptive},
+	{reflect.TypeFor[client.SLO](), "Metric"}: {client.SLOLoginP50, client.SLOLoginP95, client.SLOLoginP99,
+		client.SLOErrorRate, client.SLODisconnects, client.SLOSteadyDisconnects, client.SLOPeakHeap, client.SLOClientFootprint},
+	{reflect.TypeFor[client.SinkConfig](), "Type"}:            {"webhook", "nats", "kafka"},
+	{reflect.TypeFor[client.ErrorBudgetConfig](), "Budgets"}:  client.ErrorCategories(),
+	{reflect.TypeFor[client.ClientConfig](), "StateTimeouts"}: stateNames(),
+	{reflect.TypeFor[client.ManagerConfig](), "StuckAfter"}:   stateNames(),
+
+	{reflect.TypeFor[config.DatabaseType](), "Driver"}:       {"", config.DATABASE_DRIVER_MYSQL, config.DATABASE_DRIVER_MEMORY},
+	{reflect.TypeFor[config.LoginServerType](), "Mode"}:      {"", "normal", "gm-only", "maintenance"},
+	{reflect.TypeFor[config.LoginServerType](), "GameGuard"}: {"", "static", "ignore", "strict"},
+	{reflect.TypeFor[config.OptionsType](), "SendPolicy"}:    {"", "watermarks", "fifo"},
+	{reflect.TypeFor[config.BotDetectionType](), "Action"}:   {"", "flag", "jail", "kick"},
+}
+
+// Toolkit returns the schema of the configuration file of the client toolkit
+func Toolkit() *Schema {
+	return generate("L2Go client toolkit configuration", reflect.TypeFor[client.ToolkitConfig]())
+}
+
+// Server returns the schema of the configuration file of the login and game servers
+func Server() *Schema {
+	return generate("L2Go server configuration", reflect.TypeFor[config.ConfigObject]())
+}
+
+// ByName returns the schema of a configuration file, toolkit or server
+func ByName(name string) (*Schema, error) {
+	switch name {
+	case "toolkit":
+		return Toolkit(), nil
+	case "server":
+		return Server(), nil
+	default:
+		return nil, fmt.Errorf("unknown configuration: %s, must be one of: toolkit, server", name)
+	}
+}
+
+// generator turns the types of a configuration into schemas, the structs being defined once in $defs
+type generator struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+func generate(title string, root reflect.Type) *Schema {
+	g := &generator{defs: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+	schema := g.structSchema(root)
+	schema.Properties["$schema"] = &Schema{Type: "string"} // Lets a file name its schema for the editors
+	schema.Draft = DRAFT
+	schema.Title = title
+	schema.Defs = g.defs
+	return schema
+}
+
+// schemaOf returns the schema of a type, a reference for the structs
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	if t == reflect.TypeFor[time.Duration]() {
+		return &Schema{Type: "integer", Description: "Duration in nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.nullable(g.schemaOf(t.Elem()))
+	case reflect.Struct:
+		return &Schema{Ref: "#/$defs/" + g.define(t)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		bits := t.Bits() - 1
+		return &Schema{Type: "integer", Minimum: bound(-math.Exp2(float64(bits))), Maximum: bound(math.Exp2(float64(bits)) - 1)}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Minimum: bound(0), Maximum: bound(math.Exp2(float64(t.Bits())) - 1)}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: bound(0)}
+	default:
+		return &Schema{}
+	}
+}
+
+// nullable lets a schema accept null, as the pointers left nil are encoded
+func (g *generator) nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+	}
+	if name, ok := schema.Type.(string); ok {
+		schema.Type = []string{name, "null"}
+	}
+	return schema
+}
+
+// define adds a struct to $defs once and returns its name there, qualified by its package when
+// another package has a struct of the same name
+func (g *generator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.defs[name]; taken {
+		name = t.String()
+	}
+	g.names[t] = name
+	g.defs[name] = nil // Reserved before the fields, which may refer to the struct
+	g.defs[name] = g.structSchema(t)
+	return name
+}
+
+// structSchema returns the schema of the fields of a struct, named as encoding/json names them. The
+// fields without a json tag are named in lower camel case, as the configuration files write them:
+// encoding/json matches them case insensitively, but the schemas can't.
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+	for _, field := range fields(t) {
+		name, tagged := field.Tag.Lookup("json")
+		name, _, _ = strings.Cut(name, ",")
+		if name == "-" {
+			continue
+		}
+		if !tagged || name == "" {
+			name = lowerCamel(field.Name)
+		}
+
+		property := g.schemaOf(field.Type)
+		if values, ok := enums[enumKey{t, field.Name}]; ok {
+			if field.Type.Kind() == reflect.Map {
+				property.PropertyNames = &Schema{Enum: values}
+			} else {
+				property.Enum = values
+			}
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// fields returns the exported fields of a struct, the ones of its embedded structs included as
+// encoding/json flattens them
+func fields(t reflect.Type) []reflect.StructField {
+	var exported []reflect.StructField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		switch {
+		case field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "":
+			exported = append(exported, fields(field.Type)...)
+		case field.IsExported():
+			exported = append(exported, field)
+		}
+	}
+	return exported
+}
+
+// lowerCamel lowers the first letter of a field name, or the whole of the acronym starting it:
+// InternalIP becomes internalIP, Id id and AIShards aiShards
+func lowerCamel(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) {
+		upper-- // The last capital starts the next word
+	}
+	for i := range max(upper, 1) {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+func bound(value float64) *float64 {
+	return &value
+}
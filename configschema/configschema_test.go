@@ -0,0 +1,207 @@
+package configschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/frostwind/l2go/client"
+)
+
+func TestSchemasUpToDate(t *testing.T) {
+	for _, name := range []string{"toolkit", "server"} {
+		schema, err := ByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var generated bytes.Buffer
+		if err := schema.Write(&generated); err != nil {
+			t.Fatal(err)
+		}
+		committed, err := os.ReadFile(name + ".schema.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(generated.Bytes(), committed) {
+			t.Errorf("%s.schema.json is out of date, run go generate github.com/frostwind/l2go/configschema", name)
+		}
+	}
+}
+
+func TestConfigurationsMatchSchemas(t *testing.T) {
+	defaults, err := json.Marshal(client.DefaultToolkitConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := os.ReadFile("../extra/default_config_file/server.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		schema *Schema
+		config string
+		errors []string
+	}{
+		{name: "default toolkit", schema: Toolkit(), config: string(defaults)},
+		{name: "default server", schema: Server(), config: string(server)},
+		{name: "named schema", schema: Toolkit(), config: `{"$schema": "toolkit.schema.json"}`},
+		{
+			name:   "report format",
+			schema: Toolkit(),
+			config: `{"loadTest": {"reportFormat": "yaml"}, "logging": {"level": "trace"}}`,
+			errors: []string{"/loadTest/reportFormat: \"yaml\" isn't one of", "/logging/level: \"trace\" isn't one of"},
+		},
+		{
+			name:   "profiles",
+			schema: Toolkit(),
+			config: `{"profiles": {"active": "staging", "testing": null}, "fleets": [{"profile": "staging"}]}`,
+			errors: []string{"/profiles/active: \"staging\" isn't one of", "/fleets/0/profile: \"staging\" isn't one of"},
+		},
+		{
+			name:   "map keys",
+			schema: Toolkit(),
+			config: `{"manager": {"stuckAfter": {"Lost": 1}, "errorBudgets": {"budgets": {"auth": 5, "disk": 5}}}}`,
+			errors: []string{"/manager/stuckAfter: key \"Lost\" isn't one of", "/manager/errorBudgets/budgets: key \"disk\" isn't one of"},
+		},
+		{
+			name:   "unknown property",
+			schema: Server(),
+			config: `{"loginServer": {"autoCreate": "yes", "listen": ":2106"}}`,
+			errors: []string{"/loginServer/autoCreate: string isn't a boolean", "/loginServer: unknown property listen"},
+		},
+		{
+			name:   "bounds",
+			schema: Server(),
+			config: `{"gameServers": [{"id": 256, "options": {"maxPlayers": -1, "sendPolicy": "lifo"}}]}`,
+			errors: []string{"/gameServers/0/id: 256 is above 255", "/gameServers/0/options/maxPlayers: -1 is below 0",
+				"/gameServers/0/options/sendPolicy: \"lifo\" isn't one of"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value any
+			if err := json.Unmarshal([]byte(tt.config), &value); err != nil {
+				t.Fatal(err)
+			}
+			errors := check(tt.schema, tt.schema, value, "")
+			slices.Sort(errors)
+			want := slices.Sorted(slices.Values(tt.errors))
+			if len(errors) != len(want) {
+				t.Fatalf("errors = %q, want %q", errors, want)
+			}
+			for i := range want {
+				if !strings.HasPrefix(errors[i], want[i]) {
+					t.Errorf("errors[%d] = %q, want it to start with %q", i, errors[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLowerCamel(t *testing.T) {
+	for name, want := range map[string]string{
+		"LoginServer": "loginServer",
+		"InternalIP":  "internalIP",
+		"Id":          "id",
+		"AIShards":    "aiShards",
+		"ID":          "id",
+	} {
+		if got := lowerCamel(name); got != want {
+			t.Errorf("lowerCamel(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// check validates a decoded json value against the keywords the schemas use, returning the errors
+func check(root, schema *Schema, value any, path string) []string {
+	if schema.Ref != "" {
+		return check(root, root.Defs[strings.TrimPrefix(schema.Ref, "#/$defs/")], value, path)
+	}
+	if schema.AnyOf != nil {
+		var errors []string
+		for _, option := range schema.AnyOf {
+			matched := check(root, option, value, path)
+			if len(matched) == 0 {
+				return nil
+			}
+			errors = append(errors, matched...)
+		}
+		return errors
+	}
+
+	valueType := jsonType(value)
+	if valueType == "integer" && slices.Contains(typesOf(schema), "number") {
+		valueType = "number"
+	}
+	if types := typesOf(schema); types != nil && !slices.Contains(types, valueType) {
+		return []string{fmt.Sprintf("%s: %s isn't a %s", path, valueType, strings.Join(types, " or "))}
+	}
+	var errors []string
+	switch value := value.(type) {
+	case string:
+		if schema.Enum != nil && !slices.Contains(schema.Enum, value) {
+			errors = append(errors, fmt.Sprintf("%s: %q isn't one of %q", path, value, schema.Enum))
+		}
+	case float64:
+		if schema.Minimum != nil && value < *schema.Minimum {
+			errors = append(errors, fmt.Sprintf("%s: %v is below %v", path, value, *schema.Minimum))
+		}
+		if schema.Maximum != nil && value > *schema.Maximum {
+			errors = append(errors, fmt.Sprintf("%s: %v is above %v", path, value, *schema.Maximum))
+		}
+	case []any:
+		for i, item := range value {
+			errors = append(errors, check(root, schema.Items, item, fmt.Sprintf("%s/%d", path, i))...)
+		}
+	case map[string]any:
+		for key, item := range value {
+			if schema.PropertyNames != nil && !slices.Contains(schema.PropertyNames.Enum, key) {
+				errors = append(errors, fmt.Sprintf("%s: key %q isn't one of %q", path, key, schema.PropertyNames.Enum))
+			}
+			switch property, known := schema.Properties[key]; {
+			case known:
+				errors = append(errors, check(root, property, item, path+"/"+key)...)
+			case schema.AdditionalProperties == false:
+				errors = append(errors, fmt.Sprintf("%s: unknown property %s", path, key))
+			case schema.AdditionalProperties != nil:
+				errors = append(errors, check(root, schema.AdditionalProperties.(*Schema), item, path+"/"+key)...)
+			}
+		}
+	}
+	return errors
+}
+
+func typesOf(schema *Schema) []string {
+	switch types := schema.Type.(type) {
+	case string:
+		return []string{types}
+	case []string:
+		return types
+	}
+	return nil
+}
+
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if value == float64(int64(value)) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
@@ -0,0 +1,323 @@
+// Package conformance replays recorded sessions through the encoders and decoders of the client toolkit
+// and of the servers, checking that every packet comes out of the wire as it went in and that the packets
+// of a known layout decode and encode back to the same bytes. A corpus is a directory of captures in the
+// format of packetdiff, so the sessions recorded with OnRawPacket or exported from a retail client can be
+// replayed nightly against the current protocol stack.
+package conformance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/crypt/xor"
+	gamemodels "github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/junit"
+	"github.com/frostwind/l2go/loginserver/crypt"
+	loginmodels "github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packetdiff"
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
+)
+
+// CAPTURE_EXTENSION is the extension of the captures of a corpus
+const CAPTURE_EXTENSION = ".txt"
+
+// Stages a packet can fail at
+const (
+	STAGE_WIRE   = "wire"   // Encrypted and framed by one end, read and decrypted by the other
+	STAGE_LAYOUT = "layout" // Decoded into its layout and encoded back
+)
+
+var ErrEmptyCorpus = errors.New("no capture in the corpus")
+
+// Mismatch is a packet of a session which didn't make it through a stage unchanged
+type Mismatch struct {
+	Line   int    // Line of the packet in the capture
+	Packet string // Protocol, direction and name of the packet
+	Stage  string
+	Detail string
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("line %d, %s, %s: %s", m.Line, m.Packet, m.Stage, m.Detail)
+}
+
+// Result is the replay of a session
+type Result struct {
+	Session    string // Path of the capture, relative to the corpus
+	Packets    int    // Packets replayed
+	Layouts    int    // Packets of a known layout, decoded and encoded back
+	Mismatches []Mismatch
+	Err        error // Set when the capture couldn't be read
+	Took       time.Duration
+}
+
+// Passed reports whether every packet of the session made it through unchanged
+func (r Result) Passed() bool {
+	return r.Err == nil && len(r.Mismatches) == 0
+}
+
+// Report is the replay of a corpus
+type Report struct {
+	Corpus   string
+	Started  time.Time
+	Sessions []Result
+}
+
+// Passed reports whether every session of the corpus passed
+func (r *Report) Passed() bool {
+	for _, session := range r.Sessions {
+		if !session.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Run replays every capture of a corpus directory, its subdirectories included, in the order of their paths
+func Run(corpus string) (*Report, error) {
+	var paths []string
+	err := filepath.WalkDir(corpus, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && filepath.Ext(path) == CAPTURE_EXTENSION {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus %s: %w", corpus, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%w: %s holds no %s file", ErrEmptyCorpus, corpus, CAPTURE_EXTENSION)
+	}
+	slices.Sort(paths)
+
+	report := &Report{Corpus: corpus, Started: time.Now()}
+	for _, path := range paths {
+		name, _ := filepath.Rel(corpus, path)
+		captured, err := packetdiff.ReadCapture(path)
+		if err != nil {
+			report.Sessions = append(report.Sessions, Result{Session: name, Err: err})
+			continue
+		}
+		report.Sessions = append(report.Sessions, Replay(name, captured))
+	}
+	return report, nil
+}
+
+// Replay sends the packets of a session, in order, between the stacks of the client toolkit and of the
+// servers, as if the keys were already exchanged: the login packets are all encrypted with the static
+// Blowfish key and the game packets with the XOR keys rolling along the session, in each direction
+func Replay(name string, captured []packetdiff.Packet) Result {
+	started := time.Now()
+	result := Result{Session: name, Packets: len(captured)}
+	r := newReplayer()
+
+	for _, packet := range captured {
+		mismatch := func(stage, format string, args ...interface{}) {
+			result.Mismatches = append(result.Mismatches, Mismatch{
+				Line:   packet.Line,
+				Packet: fmt.Sprintf("%s %s %s", packet.Protocol, packet.Direction, packet.Name()),
+				Stage:  stage,
+				Detail: fmt.Sprintf(format, args...),
+			})
+		}
+
+		received, err := r.transmit(packet)
+		switch {
+		case err != nil:
+			mismatch(STAGE_WIRE, "%v", err)
+		case packet.Protocol == opcodes.Login && !bytes.HasPrefix(received, packet.Bytes()):
+			// The login packets come out padded and checksummed, which isn't part of them
+			mismatch(STAGE_WIRE, "sent % x, received % x", packet.Bytes(), received)
+		case packet.Protocol == opcodes.Game && !bytes.Equal(received, packet.Bytes()):
+			mismatch(STAGE_WIRE, "sent % x, received % x", packet.Bytes(), received)
+		}
+
+		layout, ok := packetdiff.Layout(packet)
+		if !ok {
+			continue
+		}
+		result.Layouts++
+		value := reflect.New(layout)
+		if err := packets.Unmarshal(packet.Payload, value.Interface()); err != nil {
+			mismatch(STAGE_LAYOUT, "doesn't decode as %s: %v", layout.Name(), err)
+			continue
+		}
+		encoded, err := packets.Marshal(value.Interface())
+		if err != nil {
+			mismatch(STAGE_LAYOUT, "doesn't encode back from %s: %v", layout.Name(), err)
+			continue
+		}
+		if !bytes.Equal(encoded, packet.Payload) {
+			mismatch(STAGE_LAYOUT, "%s encodes back % x from % x", layout.Name(), encoded, packet.Payload)
+		}
+	}
+
+	result.Took = time.Since(started)
+	return result
+}
+
+// wire is the connection between the two ends of a replay, what one end writes being read by the other
+type wire struct {
+	net.Conn // Never used beyond reading and writing
+	buffer   bytes.Buffer
+}
+
+func (w *wire) Read(p []byte) (int, error) {
+	return w.buffer.Read(p)
+}
+
+func (w *wire) Write(p []byte) (int, error) {
+	return w.buffer.Write(p)
+}
+
+// replayer holds both ends of a session: the protocol handler of the client toolkit, and the clients
+// the login server and the game server keep for the toolkit
+type replayer struct {
+	handler     *protocol.Handler
+	loginServer *loginmodels.Client
+	gameServer  *gamemodels.Client
+	wire        *wire
+}
+
+func newReplayer() *replayer {
+	r := &replayer{handler: protocol.NewHandler(), wire: &wire{}}
+	r.handler.InitializeBlowfish(crypt.StaticBlowfishKey)
+	r.handler.InitializeXOR(xor.NewCipher().InputKey)
+	r.loginServer = &loginmodels.Client{Socket: r.wire}
+	r.gameServer = gamemodels.NewClient()
+	r.gameServer.Socket = r.wire
+	return r
+}
+
+// transmit sends a packet from the end of its direction to the other, returning it as received, opcode first
+func (r *replayer) transmit(packet packetdiff.Packet) ([]byte, error) {
+	r.wire.buffer.Reset()
+
+	if packet.Direction == opcodes.ServerToClient {
+		return r.toClient(packet)
+	}
+
+	var raw []byte
+	var err error
+	switch {
+	case packet.Protocol == opcodes.Login:
+		raw, err = r.handler.EncodeLoginPacket(packet.Opcode, packet.Payload)
+	case opcodes.IsExtended(opcodes.Game, opcodes.ClientToServer, packet.Opcode) && len(packet.Payload) >= 2:
+		raw, err = r.handler.EncodeGameKeyedPacket(packet.Key(), packet.Payload[2:])
+	default:
+		raw, err = r.handler.EncodeGamePacket(packet.Opcode, packet.Payload)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the client failed to encode it: %w", err)
+	}
+	writer := packets.NewPacketWriter()
+	defer writer.Release()
+	frame, err := writer.B(raw).Finalize()
+	if err != nil {
+		return nil, fmt.Errorf("the client failed to frame it: %w", err)
+	}
+	r.wire.Write(frame)
+
+	var opcode byte
+	var data []byte
+	if packet.Protocol == opcodes.Login {
+		opcode, data, err = r.loginServer.Receive()
+	} else {
+		opcode, data, err = r.gameServer.Receive()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the server failed to read it: %w", err)
+	}
+	return append([]byte{opcode}, data...), nil
+}
+
+// toClient sends a packet of a server to the client toolkit
+func (r *replayer) toClient(packet packetdiff.Packet) ([]byte, error) {
+	var err error
+	if packet.Protocol == opcodes.Login {
+		err = r.loginServer.Send(packet.Bytes())
+	} else {
+		err = r.gameServer.Send(packet.Bytes())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the server failed to send it: %w", err)
+	}
+
+	raw, err := packets.ReadFrame(&r.wire.buffer, 0)
+	if err != nil {
+		return nil, fmt.Errorf("the client failed to read it: %w", err)
+	}
+	if packet.Protocol == opcodes.Login {
+		opcode, data, err := r.handler.DecodeLoginPacket(raw)
+		if err != nil {
+			return nil, fmt.Errorf("the client failed to decode it: %w", err)
+		}
+		return append([]byte{opcode}, data...), nil
+	}
+
+	key, data, err := r.handler.DecodeGameKeyedPacket(raw)
+	if err != nil {
+		return nil, fmt.Errorf("the client failed to decode it: %w", err)
+	}
+	received := []byte{key.Opcode}
+	if opcodes.IsExtended(opcodes.Game, opcodes.ServerToClient, key.Opcode) {
+		received = append(received, byte(key.SubOpcode), byte(key.SubOpcode>>8))
+	}
+	return append(received, data...), nil
+}
+
+// Write writes the replay of a corpus as text, the mismatches of the sessions which failed followed by a summary
+func (r *Report) Write(w io.Writer) {
+	var passed, packetCount, layouts int
+	for _, session := range r.Sessions {
+		packetCount += session.Packets
+		layouts += session.Layouts
+		switch {
+		case session.Err != nil:
+			fmt.Fprintf(w, "ERROR %s: %v\n", session.Session, session.Err)
+		case session.Passed():
+			passed++
+			fmt.Fprintf(w, "ok    %s, %d packets\n", session.Session, session.Packets)
+		default:
+			fmt.Fprintf(w, "FAIL  %s, %d of %d packets\n", session.Session, len(session.Mismatches), session.Packets)
+			for _, mismatch := range session.Mismatches {
+				fmt.Fprintf(w, "      %s\n", mismatch)
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d of %d sessions passed, %d packets replayed, %d of them through their layout\n", passed, len(r.Sessions), packetCount, layouts)
+}
+
+// JUnitSuite returns the replay of a corpus as a JUnit suite, a case per session
+func (r *Report) JUnitSuite() *junit.Suite {
+	suite := junit.NewSuite("conformance", r.Started)
+	suite.AddProperty("corpus", r.Corpus)
+	for _, session := range r.Sessions {
+		switch {
+		case session.Err != nil:
+			suite.Error(session.Session, session.Took, session.Err)
+		case session.Passed():
+			suite.Pass(session.Session, session.Took)
+		default:
+			var details bytes.Buffer
+			for _, mismatch := range session.Mismatches {
+				fmt.Fprintln(&details, mismatch)
+			}
+			suite.Fail(session.Session, session.Took, fmt.Sprintf("%d of %d packets mismatched", len(session.Mismatches), session.Packets), details.String())
+		}
+	}
+	return suite
+}
@@ -0,0 +1,144 @@
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/packetdiff"
+	"github.com/frostwind/l2go/testserver"
+)
+
+// recordSession records the packets of a client logging in, chatting and sitting against the stub servers
+func recordSession(t *testing.T) string {
+	t.Helper()
+
+	gameServer := testserver.NewGameServer(testserver.Character{ObjectID: 0x10000001, Name: "Tester", Level: 1, X: 10, Y: 20, Z: -30})
+	if err := gameServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { gameServer.Close() })
+	loginServer := testserver.NewLoginServer()
+	loginServer.AddGameServer(1, gameServer.Addr())
+	if err := loginServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { loginServer.Close() })
+
+	c := client.NewClient("recorder", client.ClientConfig{
+		LoginServerHost: "127.0.0.1",
+		LoginServerPort: loginServer.Addr().Port,
+		GameServerHost:  "127.0.0.1",
+		GameServerPort:  gameServer.Addr().Port,
+		Username:        "testuser",
+		Password:        "testpass",
+		Timeout:         time.Second,
+	})
+	var capture strings.Builder
+	var mu sync.Mutex
+	c.OnRawPacket(func(packet client.RawPacket) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintln(&capture, packetdiff.Packet{Protocol: packet.Protocol, Direction: packet.Direction, Opcode: packet.Opcode, Payload: packet.Payload})
+	})
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer c.Disconnect()
+	if err := c.Whisper("Nobody", "hello"); !errors.Is(err, client.ErrPlayerOffline) {
+		t.Fatalf("Whisper() error = %v, want %v", err, client.ErrPlayerOffline)
+	}
+	if err := c.Sit(); err != nil {
+		t.Fatalf("Sit() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return capture.String()
+}
+
+func TestRun(t *testing.T) {
+	session := recordSession(t)
+	corpus := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(corpus, "toolkit"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, capture string) {
+		if err := os.WriteFile(filepath.Join(corpus, name), []byte(capture), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("toolkit/session.txt", session)
+	// A CreatureSay holding a byte past its last field doesn't encode back the same
+	write("trailing.txt", "Game S->C 4a 01000010 00000000 5400000000 6800000000 ff\n")
+	write("broken.txt", "Game C->S 0\n")
+	write("notes.md", "not a capture")
+
+	report, err := Run(corpus)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(report.Sessions) != 3 {
+		t.Fatalf("Run() replayed %d sessions, want 3", len(report.Sessions))
+	}
+	broken, recorded, trailing := report.Sessions[0], report.Sessions[1], report.Sessions[2]
+
+	if !recorded.Passed() || recorded.Packets < 10 || recorded.Layouts == 0 {
+		t.Errorf("the recorded session replayed %d packets, %d through their layout: %v", recorded.Packets, recorded.Layouts, recorded.Mismatches)
+	}
+	for _, side := range []string{"Login C->S", "Login S->C", "Game C->S", "Game S->C"} {
+		if !strings.Contains(session, side) {
+			t.Errorf("the recorded session holds no %s packet", side)
+		}
+	}
+	if !errors.Is(broken.Err, packetdiff.ErrInvalidCapture) {
+		t.Errorf("the broken capture error = %v, want %v", broken.Err, packetdiff.ErrInvalidCapture)
+	}
+	if len(trailing.Mismatches) != 1 || trailing.Mismatches[0].Stage != STAGE_LAYOUT {
+		t.Errorf("the trailing byte mismatched %v, want at the layout", trailing.Mismatches)
+	}
+	if report.Passed() {
+		t.Error("Passed() = true for a corpus with failures")
+	}
+
+	var text strings.Builder
+	report.Write(&text)
+	if !strings.Contains(text.String(), "1 of 3 sessions passed") {
+		t.Errorf("Write() = %s", text.String())
+	}
+	if suite := report.JUnitSuite(); suite.Tests != 3 || suite.Failures != 1 || suite.Errors != 1 {
+		t.Errorf("JUnitSuite() = %d tests, %d failures, %d errors", suite.Tests, suite.Failures, suite.Errors)
+	}
+
+	if _, err := Run(t.TempDir()); !errors.Is(err, ErrEmptyCorpus) {
+		t.Errorf("Run() of an empty corpus error = %v, want %v", err, ErrEmptyCorpus)
+	}
+}
+
+// TestCorpus replays the corpus named by L2GO_CONFORMANCE_CORPUS, as the nightly runs do
+func TestCorpus(t *testing.T) {
+	corpus, ok := os.LookupEnv(config.ENV_PREFIX + "CONFORMANCE_CORPUS")
+	if !ok {
+		t.Skip("no corpus, set " + config.ENV_PREFIX + "CONFORMANCE_CORPUS to replay one")
+	}
+
+	report, err := Run(corpus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, session := range report.Sessions {
+		if session.Err != nil {
+			t.Errorf("%s: %v", session.Session, session.Err)
+		}
+		for _, mismatch := range session.Mismatches {
+			t.Errorf("%s: %s", session.Session, mismatch)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Package database connects the servers to their MySQL database
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/frostwind/l2go/config"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// RETRY_INTERVAL is the time between two attempts to reach a database which isn't up yet
+const RETRY_INTERVAL = 2 * time.Second
+
+// Open connects to a MySQL database, retrying for the wait timeout of the configuration, since
+// in a docker-compose or kubernetes deployment the database may come up after the servers
+func Open(cfg config.DatabaseType) (*sql.DB, error) {
+	database, err := sql.Open("mysql", cfg.DSN())
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(cfg.WaitTimeout)
+	for attempt := 1; ; attempt++ {
+		err = database.Ping()
+		if err == nil {
+			return database, nil
+		}
+		if time.Now().Add(RETRY_INTERVAL).After(deadline) {
+			database.Close()
+			return nil, fmt.Errorf("couldn't reach the database %s:%d after %d attempts: %w", cfg.Host, cfg.Port, attempt, err)
+		}
+
+		fmt.Printf("Waiting for the database %s:%d: %v\n", cfg.Host, cfg.Port, err)
+		time.Sleep(RETRY_INTERVAL)
+	}
+}
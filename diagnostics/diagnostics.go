@@ -0,0 +1,158 @@
+// Package diagnostics serves the runtime profiles of net/http/pprof and the variables
+// of expvar, and dumps the stacks of every goroutine on SIGUSR1, so the servers and the
+// client toolkit can be investigated during a long soak test without being rebuilt
+package diagnostics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"path/filepath"
+	runtimepprof "runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/config"
+)
+
+// VARIABLE is the expvar variable holding the values published by the processes
+const VARIABLE = "l2go"
+
+var (
+	published     = make(map[string]func() any)
+	publishedMu   sync.Mutex
+	publishedOnce sync.Once
+)
+
+// Publish makes a value read on every request part of the expvar variables, under VARIABLE.
+// A value published again under the same name replaces the previous one.
+func Publish(name string, value func() any) {
+	publishedOnce.Do(func() {
+		expvar.Publish(VARIABLE, expvar.Func(func() any {
+			publishedMu.Lock()
+			defer publishedMu.Unlock()
+
+			values := make(map[string]any, len(published))
+			for name, value := range published {
+				values[name] = value()
+			}
+			return values
+		}))
+	})
+
+	publishedMu.Lock()
+	defer publishedMu.Unlock()
+	published[name] = value
+}
+
+// Handler serves the pprof profiles under /debug/pprof/ and the expvar variables at /debug/vars
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// WriteGoroutines writes the stacks of every goroutine, in the format of a panic
+func WriteGoroutines(w io.Writer) error {
+	return runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// Server serves the diagnostics of a process and dumps its goroutines when it is signaled
+type Server struct {
+	name      string
+	config    config.DiagnosticsType
+	listener  net.Listener
+	server    *http.Server
+	signals   chan os.Signal
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Start serves the diagnostics of the process named name, if enabled. It returns nil when they are disabled.
+func Start(name string, cfg config.DiagnosticsType) (*Server, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", cfg.ListenAddress())
+	if err != nil {
+		return nil, fmt.Errorf("couldn't listen for the diagnostics: %w", err)
+	}
+
+	s := &Server{
+		name:     name,
+		config:   cfg,
+		listener: listener,
+		server:   &http.Server{Handler: Handler()},
+		signals:  make(chan os.Signal, 1),
+		done:     make(chan struct{}),
+	}
+	go s.server.Serve(listener)
+
+	if len(dumpSignals) > 0 {
+		signal.Notify(s.signals, dumpSignals...)
+	}
+	go s.dumpOnSignal()
+
+	return s, nil
+}
+
+// Addr returns the address the diagnostics are served on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops serving the diagnostics and dumping the goroutines
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() {
+		signal.Stop(s.signals)
+		close(s.done)
+	})
+	return s.server.Close()
+}
+
+// Dump writes the stacks of every goroutine to a new file of the dump directory, or to the standard
+// error when there is none, and returns the path of the file
+func (s *Server) Dump() (string, error) {
+	if s.config.DumpDirectory == "" {
+		return "", WriteGoroutines(os.Stderr)
+	}
+
+	path := filepath.Join(s.config.DumpDirectory, fmt.Sprintf("%s-goroutines-%s.txt", s.name, time.Now().Format("20060102-150405.000")))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := WriteGoroutines(file); err != nil {
+		return "", err
+	}
+	return path, file.Close()
+}
+
+// dumpOnSignal dumps the goroutines every time the process is signaled, until the server is closed
+func (s *Server) dumpOnSignal() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.signals:
+			if path, err := s.Dump(); err != nil {
+				fmt.Printf("Couldn't dump the goroutines: %v\n", err)
+			} else if path != "" {
+				fmt.Printf("Dumped the goroutines to %s\n", path)
+			}
+		}
+	}
+}
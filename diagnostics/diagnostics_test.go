@@ -0,0 +1,89 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/frostwind/l2go/config"
+)
+
+func TestStartDisabled(t *testing.T) {
+	s, err := Start("test", config.DiagnosticsType{Address: "127.0.0.1:0"})
+	if s != nil || err != nil {
+		t.Errorf("Start() = %v, %v while disabled", s, err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	s, err := Start("test", config.DiagnosticsType{Enabled: true, Address: "127.0.0.1:0", DumpDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Close()
+
+	Publish("test", func() any { return map[string]int{"answer": 42} })
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/debug/pprof/", "goroutine"},
+		{"/debug/pprof/goroutine?debug=1", "diagnostics.TestServer"},
+		{"/debug/pprof/cmdline", os.Args[0]},
+		{"/debug/vars", `"answer":42`},
+	}
+	for _, tt := range tests {
+		response, err := http.Get("http://" + s.Addr().String() + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", tt.path, err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+
+		if response.StatusCode != http.StatusOK || !strings.Contains(string(body), tt.want) {
+			t.Errorf("GET %s = %d, want %q in %.200s", tt.path, response.StatusCode, tt.want, body)
+		}
+	}
+
+	// The published values are read on every request
+	Publish("test", func() any { return "replaced" })
+	response, err := http.Get("http://" + s.Addr().String() + "/debug/vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var vars struct {
+		L2go map[string]any `json:"l2go"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.L2go["test"] != "replaced" {
+		t.Errorf("l2go = %v, want the value published last", vars.L2go)
+	}
+}
+
+func TestDump(t *testing.T) {
+	s, err := Start("test", config.DiagnosticsType{Enabled: true, Address: "127.0.0.1:0", DumpDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer s.Close()
+
+	path, err := s.Dump()
+	if err != nil {
+		t.Fatalf("Dump() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "diagnostics.TestDump") {
+		t.Errorf("the dump doesn't show the goroutine of the test:\n%.500s", data)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package diagnostics
+
+import "os"
+
+// dumpSignals is empty where there is no SIGUSR1, the goroutines are only dumped through Dump and pprof
+var dumpSignals []os.Signal
@@ -0,0 +1,11 @@
+//go:build unix
+
+package diagnostics
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignals make the goroutines dumped, SIGQUIT already dumping them but killing the process
+var dumpSignals = []os.Signal{syscall.SIGUSR1}
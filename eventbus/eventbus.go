@@ -0,0 +1,49 @@
+// Package eventbus distributes events to the handlers subscribed to their topic.
+// It is shared by the client toolkit and the servers, so integrations listen to both the same way.
+package eventbus
+
+import "sync"
+
+// Handler represents an event handler function
+type Handler func(event interface{}) error
+
+// Event is a typed payload, published on its topic
+type Event interface {
+	Topic() string
+}
+
+// Bus manages event distribution
+type Bus struct {
+	handlers map[string][]Handler
+	mu       sync.RWMutex
+}
+
+// New creates a new event bus
+func New() *Bus {
+	return &Bus{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Subscribe adds an event handler for the specified event type
+func (eb *Bus) Subscribe(eventType string, handler Handler) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	eb.handlers[eventType] = append(eb.handlers[eventType], handler)
+}
+
+// Publish publishes an event to all registered handlers
+func (eb *Bus) Publish(eventType string, event interface{}) {
+	eb.mu.RLock()
+	handlers := eb.handlers[eventType]
+	eb.mu.RUnlock()
+
+	for _, handler := range handlers {
+		go handler(event) // Execute handlers concurrently
+	}
+}
+
+// Emit publishes a typed event on its topic
+func (eb *Bus) Emit(event Event) {
+	eb.Publish(event.Topic(), event)
+}
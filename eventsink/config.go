@@ -0,0 +1,49 @@
+package eventsink
+
+import (
+	"fmt"
+
+	"github.com/frostwind/l2go/client"
+)
+
+// New creates a forwarder sending to the sink the configuration describes.
+// The forwarder still has to be attached to an EventBus, on the configured topics.
+func New(config client.SinkConfig) (*Forwarder, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var sink Sink
+	switch config.Type {
+	case "webhook":
+		sink = &WebhookSink{URL: config.URL, Headers: config.Headers}
+	case "nats":
+		sink = &NATSSink{Address: config.URL, Subject: config.Subject, Token: config.Token}
+	case "kafka":
+		sink = &KafkaSink{URL: config.URL, Topic: config.Subject, Headers: config.Headers}
+	default:
+		return nil, fmt.Errorf("invalid sink type: %s", config.Type)
+	}
+
+	return NewForwarder(sink, Options{
+		BatchSize:     config.BatchSize,
+		FlushInterval: config.FlushInterval,
+		MaxRetries:    config.MaxRetries,
+		RetryDelay:    config.RetryDelay,
+		BufferSize:    config.BufferSize,
+	}), nil
+}
+
+// Bridge forwards the topics of the bus to every configured sink, returning the forwarders to close
+func Bridge(eventBus *client.EventBus, config client.EventsConfig) ([]*Forwarder, error) {
+	var forwarders []*Forwarder
+	for i, sinkConfig := range config.Sinks {
+		forwarder, err := New(sinkConfig)
+		if err != nil {
+			return forwarders, fmt.Errorf("event sink %d: %w", i+1, err)
+		}
+		forwarder.Attach(eventBus, sinkConfig.Topics...)
+		forwarders = append(forwarders, forwarder)
+	}
+	return forwarders, nil
+}
@@ -0,0 +1,227 @@
+// Package eventsink forwards the events of a client EventBus to external
+// systems (an HTTP webhook, NATS or Kafka), in batches and with retries,
+// so the events of big fleets can feed alerting and analytics pipelines
+package eventsink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/clock"
+)
+
+// Default forwarding settings
+const (
+	DEFAULT_BATCH_SIZE     = 100
+	DEFAULT_FLUSH_INTERVAL = time.Second
+	DEFAULT_MAX_RETRIES    = 3
+	DEFAULT_RETRY_DELAY    = 500 * time.Millisecond
+	DEFAULT_BUFFER_SIZE    = 10000
+)
+
+var ErrForwarderClosed = errors.New("event forwarder is closed")
+
+// Event is an EventBus event as it is sent to the sinks
+type Event struct {
+	Topic string      `json:"topic"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+// Sink delivers batches of events to an external system
+type Sink interface {
+	// Send delivers a batch, failing as a whole
+	Send(ctx context.Context, events []Event) error
+
+	// Close releases the resources of the sink
+	Close() error
+}
+
+// Options tunes how a forwarder batches and retries
+type Options struct {
+	BatchSize     int           // Events sent at once, at most
+	FlushInterval time.Duration // Longest time an event waits for its batch to fill
+	MaxRetries    int           // Attempts after the first failed one before giving up on a batch, negative for none
+	RetryDelay    time.Duration // Delay before the first retry, doubled at every retry
+	BufferSize    int           // Events waiting to be sent, at most; the newer ones are dropped
+	Clock         clock.Clock   // Times the retries, the real clock when nil
+}
+
+// withDefaults fills the unset options
+func (o Options) withDefaults() Options {
+	if o.BatchSize <= 0 {
+		o.BatchSize = DEFAULT_BATCH_SIZE
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = DEFAULT_FLUSH_INTERVAL
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = DEFAULT_MAX_RETRIES
+	} else if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = DEFAULT_RETRY_DELAY
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = DEFAULT_BUFFER_SIZE
+	}
+	if o.Clock == nil {
+		o.Clock = clock.Real{}
+	}
+	return o
+}
+
+// Stats counts the events a forwarder handled
+type Stats struct {
+	Sent    int64 `json:"sent"`    // Delivered to the sink
+	Dropped int64 `json:"dropped"` // Refused because the buffer was full
+	Failed  int64 `json:"failed"`  // Given up on after the last retry
+	Retries int64 `json:"retries"` // Failed attempts which were retried
+}
+
+// Forwarder batches the events of EventBus topics and sends them to a sink
+type Forwarder struct {
+	sink    Sink
+	options Options
+	events  chan Event
+	done    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	closed  bool
+	mu      sync.RWMutex // Guards closed, so no event is queued once the queue is closed
+
+	sent, dropped, failed, retries atomic.Int64
+}
+
+// NewForwarder starts forwarding the events it is given to the sink
+func NewForwarder(sink Sink, options Options) *Forwarder {
+	options = options.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	f := &Forwarder{
+		sink:    sink,
+		options: options,
+		events:  make(chan Event, options.BufferSize),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	go f.run()
+
+	return f
+}
+
+// Attach forwards the events the bus publishes on the given topics
+func (f *Forwarder) Attach(eventBus *client.EventBus, topics ...string) {
+	for _, topic := range topics {
+		eventBus.Subscribe(topic, func(data interface{}) error {
+			return f.Forward(Event{Topic: topic, Time: time.Now(), Data: data})
+		})
+	}
+}
+
+// Forward queues an event without blocking, dropping it when the buffer is full
+func (f *Forwarder) Forward(event Event) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.closed {
+		return ErrForwarderClosed
+	}
+
+	select {
+	case f.events <- event:
+		return nil
+	default:
+		f.dropped.Add(1)
+		return client.ErrResourceExhausted
+	}
+}
+
+// Stats returns the counts of the events handled so far
+func (f *Forwarder) Stats() Stats {
+	return Stats{
+		Sent:    f.sent.Load(),
+		Dropped: f.dropped.Load(),
+		Failed:  f.failed.Load(),
+		Retries: f.retries.Load(),
+	}
+}
+
+// Close sends the queued events, giving up on the retries once the context is done, then closes the sink
+func (f *Forwarder) Close(ctx context.Context) error {
+	f.mu.Lock()
+	if !f.closed {
+		f.closed = true
+		close(f.events)
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		f.cancel()
+		<-f.done
+	}
+	f.cancel()
+
+	return f.sink.Close()
+}
+
+// run batches the queued events until the forwarder is closed
+func (f *Forwarder) run() {
+	defer close(f.done)
+
+	ticker := time.NewTicker(f.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, f.options.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			f.send(batch)
+			batch = make([]Event, 0, f.options.BatchSize)
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-f.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= f.options.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// send delivers a batch, retrying with an exponential backoff
+func (f *Forwarder) send(batch []Event) {
+	delay := f.options.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := f.sink.Send(f.ctx, batch)
+		if err == nil {
+			f.sent.Add(int64(len(batch)))
+			return
+		}
+
+		if attempt >= f.options.MaxRetries || f.ctx.Err() != nil {
+			f.failed.Add(int64(len(batch)))
+			return
+		}
+		f.retries.Add(1)
+
+		clock.Sleep(f.ctx, f.options.Clock, delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,191 @@
+package eventsink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/clock"
+)
+
+// recordingSink keeps the batches it is sent, failing the first attempts
+type recordingSink struct {
+	failures int
+	attempts int
+	batches  [][]Event
+	closed   bool
+	mu       sync.Mutex
+}
+
+func (s *recordingSink) Send(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	if s.attempts <= s.failures {
+		return errors.New("unavailable")
+	}
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestForwarder(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     Options
+		failures    int
+		events      int
+		wantBatches []int
+		wantStats   Stats
+	}{
+		{name: "full batches", options: Options{BatchSize: 2, FlushInterval: time.Hour}, events: 5, wantBatches: []int{2, 2, 1}, wantStats: Stats{Sent: 5}},
+		{name: "retried", options: Options{BatchSize: 3, FlushInterval: time.Hour, RetryDelay: time.Millisecond}, failures: 2, events: 3, wantBatches: []int{3}, wantStats: Stats{Sent: 3, Retries: 2}},
+		{name: "given up", options: Options{BatchSize: 3, FlushInterval: time.Hour, MaxRetries: 1, RetryDelay: time.Millisecond}, failures: 2, events: 3, wantStats: Stats{Failed: 3, Retries: 1}},
+		{name: "no retries", options: Options{BatchSize: 3, FlushInterval: time.Hour, MaxRetries: -1}, failures: 1, events: 3, wantStats: Stats{Failed: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{failures: tt.failures}
+			forwarder := NewForwarder(sink, tt.options)
+
+			for i := 0; i < tt.events; i++ {
+				if err := forwarder.Forward(Event{Topic: "client.connected", Data: i}); err != nil {
+					t.Fatalf("Forward() error = %v", err)
+				}
+			}
+
+			if err := forwarder.Close(context.Background()); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if err := forwarder.Forward(Event{}); !errors.Is(err, ErrForwarderClosed) {
+				t.Errorf("Forward() error = %v after Close, want %v", err, ErrForwarderClosed)
+			}
+
+			var sizes []int
+			for _, batch := range sink.batches {
+				sizes = append(sizes, len(batch))
+			}
+			if len(sizes) != len(tt.wantBatches) {
+				t.Fatalf("batches = %v, want %v", sizes, tt.wantBatches)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.wantBatches[i] {
+					t.Errorf("batches = %v, want %v", sizes, tt.wantBatches)
+				}
+			}
+			if stats := forwarder.Stats(); stats != tt.wantStats {
+				t.Errorf("Stats() = %+v, want %+v", stats, tt.wantStats)
+			}
+			if !sink.closed {
+				t.Error("the sink wasn't closed")
+			}
+		})
+	}
+}
+
+func TestForwarderBackoff(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	sink := &recordingSink{failures: 2}
+	forwarder := NewForwarder(sink, Options{BatchSize: 1, FlushInterval: time.Hour, RetryDelay: time.Second, Clock: fake})
+
+	if err := forwarder.Forward(Event{Topic: "client.connected"}); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+
+	// The retries wait 1s then 2s
+	for _, delay := range []time.Duration{time.Second, 2 * time.Second} {
+		if err := fake.WaitForTimers(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+		fake.Advance(delay - time.Millisecond)
+		if pending := fake.Pending(); pending != 1 {
+			t.Fatalf("the retry after %v didn't wait, %d timers pending", delay, pending)
+		}
+		fake.Advance(time.Millisecond)
+	}
+
+	if err := forwarder.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if stats := forwarder.Stats(); stats != (Stats{Sent: 1, Retries: 2}) {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestForwarderAttach(t *testing.T) {
+	sink := &recordingSink{}
+	forwarder := NewForwarder(sink, Options{BatchSize: 10, FlushInterval: 10 * time.Millisecond, BufferSize: 1})
+	defer forwarder.Close(context.Background())
+
+	eventBus := client.NewEventBus()
+	forwarder.Attach(eventBus, "client.connected", "client.error")
+	eventBus.Publish("client.connected", map[string]interface{}{"clientID": "client-1"})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		sink.mu.Lock()
+		delivered := len(sink.batches)
+		sink.mu.Unlock()
+		if delivered > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the event was not flushed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	sink.mu.Lock()
+	event := sink.batches[0][0]
+	sink.mu.Unlock()
+	if event.Topic != "client.connected" || event.Time.IsZero() || event.Data.(map[string]interface{})["clientID"] != "client-1" {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func TestForwarderDropsWhenFull(t *testing.T) {
+	// A sink blocking until the test ends keeps the buffer full
+	blocked := make(chan struct{})
+	sink := &blockingSink{blocked: blocked}
+
+	forwarder := NewForwarder(sink, Options{BatchSize: 1, FlushInterval: time.Hour, BufferSize: 1})
+	defer forwarder.Close(context.Background())
+	defer close(blocked)
+
+	dropped := 0
+	for i := 0; i < 10; i++ {
+		if errors.Is(forwarder.Forward(Event{Topic: "client.error"}), client.ErrResourceExhausted) {
+			dropped++
+		}
+	}
+	if dropped < 8 || forwarder.Stats().Dropped != int64(dropped) {
+		t.Errorf("%d events dropped, Stats() = %+v", dropped, forwarder.Stats())
+	}
+}
+
+type blockingSink struct {
+	blocked chan struct{}
+}
+
+func (s *blockingSink) Send(ctx context.Context, events []Event) error {
+	select {
+	case <-s.blocked:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	return nil
+}
@@ -0,0 +1,49 @@
+package eventsink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// KafkaSink produces the events to a Kafka topic through a Kafka REST proxy,
+// keyed by their EventBus topic so the events of a topic stay ordered
+type KafkaSink struct {
+	URL     string // Base URL of the REST proxy
+	Topic   string // Kafka topic
+	Headers map[string]string
+	Client  *http.Client // Defaults to http.DefaultClient
+}
+
+// kafkaRecords is the body of a produce request of the REST proxy v2 API
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value Event  `json:"value"`
+}
+
+// Send produces the batch in a single request
+func (s *KafkaSink) Send(ctx context.Context, events []Event) error {
+	records := kafkaRecords{Records: make([]kafkaRecord, len(events))}
+	for i, event := range events {
+		records.Records[i] = kafkaRecord{Key: event.Topic, Value: event}
+	}
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimSuffix(s.URL, "/") + "/topics/" + url.PathEscape(s.Topic)
+	return post(ctx, s.Client, endpoint, "application/vnd.kafka.json.v2+json", s.Headers, body)
+}
+
+// Close does nothing, the requests don't hold any connection of their own
+func (s *KafkaSink) Close() error {
+	return nil
+}
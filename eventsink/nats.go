@@ -0,0 +1,213 @@
+package eventsink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DEFAULT_NATS_TIMEOUT bounds connecting to NATS and waiting for it to acknowledge a batch
+const DEFAULT_NATS_TIMEOUT = 5 * time.Second
+
+var ErrNATSClosed = errors.New("nats connection closed")
+
+// NATSSink publishes every event to NATS, speaking the core text protocol, on the
+// subject made of the prefix and the EventBus topic (l2go.client.connected for example)
+type NATSSink struct {
+	Address string        // host:port of the server, nats:// prefix allowed
+	Subject string        // Prefix of the subjects
+	Token   string        // Authentication token, if the server requires one
+	Timeout time.Duration // Defaults to DEFAULT_NATS_TIMEOUT
+
+	conn *natsConn
+	mu   sync.Mutex
+}
+
+// natsConn is a connection to a NATS server, answering its pings in the background
+type natsConn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	pongs   chan error // An acknowledgement of our pings, or the error the server sent instead
+	dead    chan struct{}
+	err     error // Why the connection died, set before dead is closed
+}
+
+// Send publishes the batch then waits for the server to acknowledge it,
+// reconnecting first if the previous connection was lost
+func (s *NATSSink) Send(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	var buffer strings.Builder
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buffer, "PUB %s %d\r\n%s\r\n", s.subject(event.Topic), len(payload), payload)
+	}
+
+	if err := s.conn.write(buffer.String()); err != nil {
+		s.drop()
+		return err
+	}
+	if err := s.conn.ping(ctx, s.timeout()); err != nil {
+		s.drop()
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection, if any
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.drop()
+	return nil
+}
+
+func (s *NATSSink) subject(topic string) string {
+	if s.Subject == "" {
+		return topic
+	}
+	return strings.TrimSuffix(s.Subject, ".") + "." + topic
+}
+
+func (s *NATSSink) timeout() time.Duration {
+	if s.Timeout <= 0 {
+		return DEFAULT_NATS_TIMEOUT
+	}
+	return s.Timeout
+}
+
+func (s *NATSSink) drop() {
+	if s.conn != nil {
+		s.conn.conn.Close()
+		s.conn = nil
+	}
+}
+
+// dial connects and authenticates, waiting for the server to accept the connection
+func (s *NATSSink) dial(ctx context.Context) (*natsConn, error) {
+	dialer := net.Dialer{Timeout: s.timeout()}
+	conn, err := dialer.DialContext(ctx, "tcp", strings.TrimPrefix(s.Address, "nats://"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The server greets with its INFO
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(s.timeout()))
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected nats greeting %q", strings.TrimSpace(line))
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "name": "l2go", "lang": "go"}
+	if s.Token != "" {
+		options["auth_token"] = s.Token
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &natsConn{conn: conn, pongs: make(chan error, 16), dead: make(chan struct{})}
+	go c.read(reader)
+
+	if err := c.write("CONNECT " + string(connect) + "\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := c.ping(ctx, s.timeout()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *natsConn) write(data string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := c.conn.Write([]byte(data))
+	return err
+}
+
+// ping waits for the server to have processed everything sent before
+func (c *natsConn) ping(ctx context.Context, timeout time.Duration) error {
+	if err := c.write("PING\r\n"); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-c.pongs:
+		return err
+	case <-c.dead:
+		return c.err
+	case <-timer.C:
+		return fmt.Errorf("nats server did not answer within %v", timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// read handles the messages of the server until the connection dies
+func (c *natsConn) read(reader *bufio.Reader) {
+	defer close(c.dead)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			c.err = fmt.Errorf("%w: %v", ErrNATSClosed, err)
+			return
+		}
+
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			if err := c.write("PONG\r\n"); err != nil {
+				c.err = fmt.Errorf("%w: %v", ErrNATSClosed, err)
+				return
+			}
+		case line == "PONG":
+			c.acknowledge(nil)
+		case strings.HasPrefix(line, "-ERR"):
+			c.acknowledge(fmt.Errorf("nats server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR"))))
+		}
+	}
+}
+
+// acknowledge hands the answer to a ping over, dropping it if nobody waits for it anymore
+func (c *natsConn) acknowledge(err error) {
+	select {
+	case c.pongs <- err:
+	default:
+	}
+}
@@ -0,0 +1,245 @@
+package eventsink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+func TestHTTPSinks(t *testing.T) {
+	events := []Event{
+		{Topic: "client.connected", Time: time.Unix(1700000000, 0).UTC(), Data: map[string]interface{}{"clientID": "client-1"}},
+		{Topic: "client.error", Time: time.Unix(1700000001, 0).UTC(), Data: map[string]interface{}{"clientID": "client-2"}},
+	}
+
+	tests := []struct {
+		name            string
+		sink            func(url string) Sink
+		wantPath        string
+		wantContentType string
+		check           func(t *testing.T, body []byte)
+	}{
+		{
+			name: "webhook",
+			sink: func(url string) Sink {
+				return &WebhookSink{URL: url + "/hook", Headers: map[string]string{"Authorization": "Bearer secret"}}
+			},
+			wantPath:        "/hook",
+			wantContentType: "application/json",
+			check: func(t *testing.T, body []byte) {
+				var decoded []Event
+				if err := json.Unmarshal(body, &decoded); err != nil {
+					t.Fatal(err)
+				}
+				if len(decoded) != 2 || decoded[1].Topic != "client.error" || !decoded[0].Time.Equal(events[0].Time) {
+					t.Errorf("unexpected body %s", body)
+				}
+			},
+		},
+		{
+			name: "kafka",
+			sink: func(url string) Sink {
+				return &KafkaSink{URL: url + "/", Topic: "l2go-events", Headers: map[string]string{"Authorization": "Bearer secret"}}
+			},
+			wantPath:        "/topics/l2go-events",
+			wantContentType: "application/vnd.kafka.json.v2+json",
+			check: func(t *testing.T, body []byte) {
+				var decoded kafkaRecords
+				if err := json.Unmarshal(body, &decoded); err != nil {
+					t.Fatal(err)
+				}
+				if len(decoded.Records) != 2 || decoded.Records[0].Key != "client.connected" || decoded.Records[1].Value.Topic != "client.error" {
+					t.Errorf("unexpected body %s", body)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := http.StatusServiceUnavailable
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.wantPath || r.Header.Get("Content-Type") != tt.wantContentType || r.Header.Get("Authorization") != "Bearer secret" {
+					t.Errorf("unexpected request %s %s (%s)", r.Method, r.URL.Path, r.Header.Get("Content-Type"))
+				}
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			sink := tt.sink(server.URL)
+			defer sink.Close()
+
+			if err := sink.Send(context.Background(), events); err == nil || !strings.Contains(err.Error(), "503") {
+				t.Errorf("Send() error = %v, want the 503 status", err)
+			}
+
+			status = http.StatusOK
+			if err := sink.Send(context.Background(), events); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			tt.check(t, body)
+		})
+	}
+}
+
+// fakeNATS accepts one connection at a time, replying to the pings and collecting the published messages
+type fakeNATS struct {
+	listener  net.Listener
+	published chan string
+	reject    string // Sent as an -ERR instead of the next PONG, when set
+}
+
+func startFakeNATS(t *testing.T) *fakeNATS {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeNATS{listener: listener, published: make(chan string, 16)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeNATS) serve(conn net.Conn) {
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"max_payload\":1048576}\r\n")
+	reader := bufio.NewReader(conn)
+
+	// The server pings first, the client must answer
+	fmt.Fprintf(conn, "PING\r\n")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "CONNECT":
+			if !strings.Contains(line, `"auth_token":"secret"`) {
+				fmt.Fprintf(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PUB":
+			size, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			s.published <- fields[1] + " " + string(payload[:size])
+		case "PING":
+			if s.reject != "" {
+				fmt.Fprintf(conn, "-ERR '%s'\r\n", s.reject)
+				s.reject = ""
+				return
+			}
+			fmt.Fprintf(conn, "PONG\r\n")
+		}
+	}
+}
+
+func TestNATSSink(t *testing.T) {
+	server := startFakeNATS(t)
+
+	sink := &NATSSink{Address: "nats://" + server.listener.Addr().String(), Subject: "l2go.", Token: "secret", Timeout: time.Second}
+	defer sink.Close()
+
+	events := []Event{
+		{Topic: "client.connected", Data: "client-1"},
+		{Topic: "client.error", Data: "client-2"},
+	}
+	if err := sink.Send(context.Background(), events); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	for _, wantSubject := range []string{"l2go.client.connected", "l2go.client.error"} {
+		message := <-server.published
+		subject, payload, _ := strings.Cut(message, " ")
+		var event Event
+		if err := json.Unmarshal([]byte(payload), &event); err != nil || subject != wantSubject {
+			t.Errorf("published %q, want an event on %s", message, wantSubject)
+		}
+	}
+
+	// A batch the server refuses fails, the next one goes through a new connection
+	server.reject = "Maximum Payload Violation"
+	if err := sink.Send(context.Background(), events[:1]); err == nil || !strings.Contains(err.Error(), "Maximum Payload Violation") {
+		t.Errorf("Send() error = %v, want the server error", err)
+	}
+	<-server.published
+
+	if err := sink.Send(context.Background(), events[1:]); err != nil {
+		t.Fatalf("Send() error = %v after a reconnection", err)
+	}
+	if message := <-server.published; !strings.HasPrefix(message, "l2go.client.error ") {
+		t.Errorf("published %q", message)
+	}
+	sink.Close()
+
+	wrongToken := &NATSSink{Address: server.listener.Addr().String(), Token: "wrong", Timeout: time.Second}
+	if err := wrongToken.Send(context.Background(), events); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("Send() error = %v, want the authorization error", err)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	received := make(chan []Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var events []Event
+		json.NewDecoder(r.Body).Decode(&events)
+		received <- events
+	}))
+	defer server.Close()
+
+	eventBus := client.NewEventBus()
+	forwarders, err := Bridge(eventBus, client.EventsConfig{Sinks: []client.SinkConfig{
+		{Type: "webhook", URL: server.URL, Topics: []string{"client.error"}, FlushInterval: 10 * time.Millisecond},
+	}})
+	if err != nil {
+		t.Fatalf("Bridge() error = %v", err)
+	}
+	defer forwarders[0].Close(context.Background())
+
+	eventBus.Publish("client.connected", "ignored")
+	eventBus.Publish("client.error", "forwarded")
+
+	select {
+	case events := <-received:
+		if len(events) != 1 || events[0].Topic != "client.error" || events[0].Data != "forwarded" {
+			t.Errorf("received %+v", events)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nothing was forwarded")
+	}
+
+	if _, err := Bridge(eventBus, client.EventsConfig{Sinks: []client.SinkConfig{{Type: "kafka", URL: server.URL, Topics: []string{"client.error"}}}}); err == nil {
+		t.Error("Bridge() error = nil for a kafka sink without a topic")
+	}
+}
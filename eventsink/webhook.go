@@ -0,0 +1,59 @@
+package eventsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WebhookSink posts every batch to an HTTP endpoint, as a json array of events
+type WebhookSink struct {
+	URL     string
+	Headers map[string]string // Added to every request, an authorization for example
+	Client  *http.Client      // Defaults to http.DefaultClient
+}
+
+// Send posts the batch, failing unless the endpoint answers with a 2xx status
+func (s *WebhookSink) Send(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.Client, s.URL, "application/json", s.Headers, body)
+}
+
+// Close does nothing, the requests don't hold any connection of their own
+func (s *WebhookSink) Close() error {
+	return nil
+}
+
+// post sends a body to an HTTP endpoint, turning the non 2xx answers into errors
+func post(ctx context.Context, httpClient *http.Client, url, contentType string, headers map[string]string, body []byte) error {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 4096))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", url, response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package gameserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/frostwind/l2go/packettiming"
+)
+
+// PlayerSnapshot is a client connected to the game server, as dumped by the snapshot
+type PlayerSnapshot struct {
+	ObjectID       uint32  `json:"objectId"`
+	Account        string  `json:"account,omitempty"` // Empty until the client authenticates
+	Address        string  `json:"address"`
+	InWorld        bool    `json:"inWorld"`
+	X              int32   `json:"x"`
+	Y              int32   `json:"y"`
+	Z              int32   `json:"z"`
+	Level          int     `json:"level"`
+	HP             int     `json:"hp"`
+	MaxHP          int     `json:"maxHp"`
+	MP             int     `json:"mp"`
+	MaxMP          int     `json:"maxMp"`
+	CP             int     `json:"cp"`
+	MaxCP          int     `json:"maxCp"`
+	SendQueueDepth int     `json:"sendQueueDepth"`
+	BotScore       float64 `json:"botScore,omitempty"` // Last score of the bot detection, when it is enabled
+}
+
+// Snapshot is the state of the running game server, for the post-mortem analysis of a stuck load test
+type Snapshot struct {
+	TakenAt     time.Time        `json:"takenAt"`
+	Stats       Stats            `json:"stats"`
+	Players     []PlayerSnapshot `json:"players"` // By object id
+	Npcs        int              `json:"npcs"`
+	GroundItems int              `json:"groundItems"`
+}
+
+// Snapshot returns the clients connected to the game server, with the positions of the players in the world
+func (g *GameServer) Snapshot() Snapshot {
+	g.clientsMutex.Lock()
+	players := make([]PlayerSnapshot, 0, len(g.clients))
+	for _, client := range g.clients {
+		player := PlayerSnapshot{ObjectID: client.ObjectID, Account: client.Account, InWorld: client.Effects != nil}
+		if client.Socket != nil {
+			player.Address = client.Socket.RemoteAddr().String()
+		}
+		if queue := client.SendQueue(); queue != nil {
+			player.SendQueueDepth = queue.Stats().Depth
+		}
+		if client.Behavior != nil {
+			player.BotScore = client.Behavior.Verdict().Score
+		}
+		players = append(players, player)
+	}
+	g.clientsMutex.Unlock()
+
+	for i := range players {
+		// The grid knows where the players in the world stand, their clients moving them concurrently
+		if object, ok := g.interest.Object(players[i].ObjectID); ok {
+			players[i].X, players[i].Y, players[i].Z = object.X, object.Y, object.Z
+		}
+		if client, ok := g.Player(players[i].ObjectID); ok {
+			g.progressMutex.Lock()
+			players[i].Level, players[i].HP, players[i].MaxHP = client.Level, client.HP, client.MaxHP
+			players[i].MP, players[i].MaxMP, players[i].CP, players[i].MaxCP = client.MP, client.MaxMP, client.CP, client.MaxCP
+			g.progressMutex.Unlock()
+		}
+	}
+	sort.Slice(players, func(i, j int) bool { return players[i].ObjectID < players[j].ObjectID })
+
+	g.npcsMutex.RLock()
+	npcs := len(g.npcs)
+	g.npcsMutex.RUnlock()
+
+	g.itemsMutex.Lock()
+	groundItems := len(g.groundItems)
+	g.itemsMutex.Unlock()
+
+	return Snapshot{
+		TakenAt:     g.clock.Now(),
+		Stats:       g.Stats(),
+		Players:     players,
+		Npcs:        npcs,
+		GroundItems: groundItems,
+	}
+}
+
+// HandlerStats returns how long the packets of the clients took to be handled, by opcode
+func (g *GameServer) HandlerStats() packettiming.Stats {
+	return g.handlerTimes.Stats()
+}
+
+// AdminHandler serves the admin API, which only reads the state of the game server
+func (g *GameServer) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g.Stats())
+	})
+	mux.HandleFunc("/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g.Snapshot())
+	})
+	mux.HandleFunc("/handlers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g.HandlerStats())
+	})
+	return mux
+}
@@ -0,0 +1,132 @@
+package gameserver
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/frostwind/l2go/access"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// MAX_ENCHANT_LEVEL is the highest level the //enchant command sets
+const MAX_ENCHANT_LEVEL = 65535
+
+var (
+	ErrAdminCommandDenied  = errors.New("admin command denied")
+	ErrUnknownAdminCommand = errors.New("unknown admin command")
+	ErrInvalidAdminCommand = errors.New("invalid admin command")
+	ErrItemNotOwned        = errors.New("item not owned")
+)
+
+// adminCommands are the commands a GM types after //, along with the capability they need
+var adminCommands = map[string]struct {
+	capability string
+	usage      string
+}{
+	"give_item": {capability: access.GM_GIVE_ITEM, usage: "give_item <item id> [count]"},
+	"enchant":   {capability: access.GM_ENCHANT, usage: "enchant <item id> <level>"},
+}
+
+// AdminCommand runs a command a GM typed after //, as long as the access level of its account grants it.
+// The player is told why a command was refused.
+func (g *GameServer) AdminCommand(client *models.Client, command string) error {
+	err := g.adminCommand(client, strings.Fields(command))
+	if err != nil {
+		if sendErr := client.Send(serverpackets.NewSystemMessagePacket(serverpackets.SYSTEM_MESSAGE_S1, err.Error())); sendErr != nil {
+			fmt.Println(sendErr)
+		}
+	}
+	return err
+}
+
+func (g *GameServer) adminCommand(client *models.Client, fields []string) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: empty command", ErrInvalidAdminCommand)
+	}
+	name, args := fields[0], fields[1:]
+	command, ok := adminCommands[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAdminCommand, name)
+	}
+	if !g.access.Can(client.AccessLevel, command.capability) {
+		return fmt.Errorf("%w: %s needs %s", ErrAdminCommandDenied, name, command.capability)
+	}
+
+	numbers := make([]uint64, len(args))
+	for i, arg := range args {
+		number, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: usage: %s", ErrInvalidAdminCommand, command.usage)
+		}
+		numbers[i] = number
+	}
+
+	switch {
+	case name == "give_item" && (len(numbers) == 1 || len(numbers) == 2):
+		count := uint64(1)
+		if len(numbers) == 2 {
+			count = numbers[1]
+		}
+		return g.AdminGiveItem(client, int(min(numbers[0], 1<<31-1)), count)
+	case name == "enchant" && len(numbers) == 2:
+		return g.Enchant(client, int(min(numbers[0], 1<<31-1)), int(min(numbers[1], MAX_ENCHANT_LEVEL+1)))
+	}
+	return fmt.Errorf("%w: usage: %s", ErrInvalidAdminCommand, command.usage)
+}
+
+// AdminGiveItem gives items to a player out of nowhere, as the //give_item command does, and sends it
+// its inventory
+func (g *GameServer) AdminGiveItem(client *models.Client, itemID int, count uint64) error {
+	if itemID <= 0 || count == 0 {
+		return fmt.Errorf("%w: %d of the item %d", ErrInvalidAdminCommand, count, itemID)
+	}
+
+	g.GiveItem(client, itemID, count)
+
+	fmt.Printf("Player %d was given %d of the item %d\n", client.ObjectID, count, itemID)
+	g.sendItemList(client)
+	return nil
+}
+
+// Enchant sets the enchant level of an item of a player, the whole stack of the item sharing it.
+// The enchant levels aren't stored with the characters yet, they are lost when the player leaves.
+func (g *GameServer) Enchant(client *models.Client, itemID int, level int) error {
+	if level < 0 || level > MAX_ENCHANT_LEVEL {
+		return fmt.Errorf("%w: level %d out of 0-%d", ErrInvalidAdminCommand, level, MAX_ENCHANT_LEVEL)
+	}
+
+	g.itemsMutex.Lock()
+	owned := client.Items[itemID] > 0
+	if owned {
+		if client.Enchants == nil {
+			client.Enchants = make(map[int]int)
+		}
+		client.Enchants[itemID] = level
+	}
+	g.itemsMutex.Unlock()
+
+	if !owned {
+		return fmt.Errorf("%w: %d", ErrItemNotOwned, itemID)
+	}
+
+	fmt.Printf("Player %d enchanted the item %d to +%d\n", client.ObjectID, itemID, level)
+	message := serverpackets.NewSystemMessagePacket(serverpackets.SYSTEM_MESSAGE_ENCHANTED, strconv.Itoa(level), strconv.Itoa(itemID))
+	if err := client.Send(message); err != nil {
+		fmt.Println(err)
+	}
+	return nil
+}
+
+// EnchantLevel returns the enchant level of an item of a player, 0 for the items it doesn't own
+func (g *GameServer) EnchantLevel(client *models.Client, itemID int) int {
+	g.itemsMutex.Lock()
+	defer g.itemsMutex.Unlock()
+
+	if client.Items[itemID] == 0 {
+		return 0
+	}
+	return client.Enchants[itemID]
+}
@@ -0,0 +1,213 @@
+// Package ai drives the NPCs of the world: each one runs a small state machine
+// deciding whether it idles, wanders around its spawn, chases and attacks a
+// player or walks back home, and a sharded scheduler lets them all think at a
+// fixed interval, skipping the regions no player is close to
+package ai
+
+import (
+	"math"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/interest"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/random"
+)
+
+// State is what an NPC is busy with
+type State string
+
+const (
+	IDLE   State = "idle"
+	WANDER State = "wander" // Walking to a random spot around its spawn
+	AGGRO  State = "aggro"  // Chasing its target
+	ATTACK State = "attack" // Close enough to hit its target
+	RETURN State = "return" // Walking back to its spawn, ignoring the players
+)
+
+const (
+	DEFAULT_SPEED        = 80   // Units walked per second
+	DEFAULT_ATTACK_RANGE = 40   // Melee range
+	DEFAULT_LEASH_RANGE  = 2000 // How far from its spawn an NPC chases a player
+
+	// WANDER_CHANCE is the percentage of chance an idle NPC starts wandering when it thinks
+	WANDER_CHANCE = 20
+)
+
+// Profile is how an NPC behaves, read along with its spawn
+type Profile struct {
+	Aggressive   bool  `json:"aggressive"`             // Attacks the players coming close, otherwise only the ones provoking it
+	AggroRange   int32 `json:"aggroRange,omitempty"`   // How close the players come before an aggressive NPC attacks, at most interest.REGION_SIZE
+	AttackRange  int32 `json:"attackRange,omitempty"`  // 0 for DEFAULT_ATTACK_RANGE
+	WanderRadius int32 `json:"wanderRadius,omitempty"` // How far from its spawn it wanders, 0 to stay put
+	LeashRange   int32 `json:"leashRange,omitempty"`   // 0 for DEFAULT_LEASH_RANGE
+	Speed        int32 `json:"speed,omitempty"`        // Units walked per second, 0 for DEFAULT_SPEED
+}
+
+// Target is a player as seen by the NPCs
+type Target struct {
+	ObjectID uint32
+	X, Y, Z  int32
+}
+
+// World is what the NPCs see of the game server and how they act on it
+type World interface {
+	// Players returns where the players of the world are
+	Players() []Target
+
+	// Walk shows an NPC starting to walk from where it stands to a location
+	Walk(npc *models.Npc, x, y, z int32)
+
+	// Place updates the location of an NPC as it walks
+	Place(npc *models.Npc, x, y, z int32)
+
+	// Attack hits a player in range of an NPC
+	Attack(npc *models.Npc, target Target)
+}
+
+// mind is the state machine of an NPC. It is only used by the shard owning it,
+// which holds its lock, so it keeps its own copy of the location of the NPC.
+type mind struct {
+	npc                 *models.Npc
+	profile             Profile
+	state               State
+	target              uint32
+	x, y, z             int32
+	homeX, homeY, homeZ int32
+	moving              bool
+	destX, destY, destZ int32 // Where it walks to
+}
+
+func newMind(npc *models.Npc, profile Profile) *mind {
+	if profile.AttackRange <= 0 {
+		profile.AttackRange = DEFAULT_ATTACK_RANGE
+	}
+	if profile.LeashRange <= 0 {
+		profile.LeashRange = DEFAULT_LEASH_RANGE
+	}
+	if profile.Speed <= 0 {
+		profile.Speed = DEFAULT_SPEED
+	}
+	profile.AggroRange = min(profile.AggroRange, interest.REGION_SIZE)
+
+	return &mind{
+		npc:     npc,
+		profile: profile,
+		state:   IDLE,
+		x:       npc.X,
+		y:       npc.Y,
+		z:       npc.Z,
+		homeX:   npc.X,
+		homeY:   npc.Y,
+		homeZ:   npc.Z,
+	}
+}
+
+// think makes the NPC take its next decision, its walks being advanced by the scheduler meanwhile
+func (m *mind) think(world World, players *view, source random.Source) {
+	switch m.state {
+	case IDLE, WANDER:
+		if m.profile.Aggressive {
+			if target, ok := players.nearest(m.x, m.y, m.profile.AggroRange); ok {
+				m.state, m.target = AGGRO, target.ObjectID
+				m.chase(world, players)
+				return
+			}
+		}
+
+		if m.state == WANDER {
+			if !m.moving {
+				m.state = IDLE
+			}
+			return
+		}
+		if m.profile.WanderRadius > 0 && source.Int64N(100) < WANDER_CHANCE {
+			x, y := m.wanderSpot(source)
+			m.walk(world, x, y, m.homeZ)
+			m.state = WANDER
+		}
+	case AGGRO, ATTACK:
+		m.chase(world, players)
+	case RETURN:
+		if !m.moving {
+			m.state = IDLE
+		}
+	}
+}
+
+// chase walks to the target until it is in range and attacks it, giving up once it
+// left the world or led the NPC too far from its spawn
+func (m *mind) chase(world World, players *view) {
+	target, ok := players.byID[m.target]
+	if !ok || distance(m.homeX, m.homeY, target.X, target.Y) > int64(m.profile.LeashRange) {
+		m.state, m.target = RETURN, 0
+		m.walk(world, m.homeX, m.homeY, m.homeZ)
+		if !m.moving {
+			m.state = IDLE
+		}
+		return
+	}
+
+	d := distance(m.x, m.y, target.X, target.Y)
+	if d <= int64(m.profile.AttackRange) {
+		m.state, m.moving = ATTACK, false
+		world.Attack(m.npc, target)
+		return
+	}
+
+	// Stops once in range rather than on the target
+	m.state = AGGRO
+	short := d - int64(m.profile.AttackRange)
+	m.walk(world,
+		m.x+int32((int64(target.X)-int64(m.x))*short/d),
+		m.y+int32((int64(target.Y)-int64(m.y))*short/d),
+		target.Z)
+}
+
+// walk starts walking to a location, unless the NPC already stands or walks there
+func (m *mind) walk(world World, x, y, z int32) {
+	if m.x == x && m.y == y {
+		m.moving = false
+		return
+	}
+	if m.moving && m.destX == x && m.destY == y && m.destZ == z {
+		return
+	}
+
+	m.moving = true
+	m.destX, m.destY, m.destZ = x, y, z
+	world.Walk(m.npc, x, y, z)
+}
+
+// advance moves a walking NPC along its way for the time elapsed
+func (m *mind) advance(world World, elapsed time.Duration) {
+	if !m.moving {
+		return
+	}
+
+	step := max(int64(m.profile.Speed)*int64(elapsed)/int64(time.Second), 1)
+	left := distance(m.x, m.y, m.destX, m.destY)
+	if left <= step {
+		m.x, m.y, m.z = m.destX, m.destY, m.destZ
+		m.moving = false
+	} else {
+		m.x += int32((int64(m.destX) - int64(m.x)) * step / left)
+		m.y += int32((int64(m.destY) - int64(m.y)) * step / left)
+	}
+	world.Place(m.npc, m.x, m.y, m.z)
+}
+
+// wanderSpot picks a random spot within the wander radius of the spawn
+func (m *mind) wanderSpot(source random.Source) (int32, int32) {
+	radius := int64(m.profile.WanderRadius)
+	for {
+		dx, dy := source.Int64N(2*radius+1)-radius, source.Int64N(2*radius+1)-radius
+		if dx*dx+dy*dy <= radius*radius {
+			return m.homeX + int32(dx), m.homeY + int32(dy)
+		}
+	}
+}
+
+// distance returns the distance between two locations on the ground, rounded down
+func distance(x1, y1, x2, y2 int32) int64 {
+	return int64(math.Hypot(float64(x2)-float64(x1), float64(y2)-float64(y1)))
+}
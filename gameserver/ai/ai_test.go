@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/interest"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/random"
+)
+
+// round lets the NPCs think, then walk for a second
+func round(scheduler *Scheduler) {
+	scheduler.Tick()
+	scheduler.Advance(time.Second)
+}
+
+type fakeWorld struct {
+	players []Target
+	attacks int
+	mu      sync.Mutex
+}
+
+func (w *fakeWorld) Players() []Target {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Target(nil), w.players...)
+}
+
+func (w *fakeWorld) Walk(npc *models.Npc, x, y, z int32) {}
+
+func (w *fakeWorld) Place(npc *models.Npc, x, y, z int32) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	npc.X, npc.Y, npc.Z = x, y, z
+}
+
+func (w *fakeWorld) Attack(npc *models.Npc, target Target) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.attacks++
+}
+
+func TestBehaviors(t *testing.T) {
+	// The NPC walks 100 units per round
+	profile := Profile{AggroRange: 300, AttackRange: 40, LeashRange: 1000, Speed: 100}
+
+	tests := []struct {
+		name       string
+		aggressive bool
+		player     Target
+		rounds     int
+		want       State
+		wantX      int32
+		wantAttack bool
+	}{
+		{name: "passive ignores the players", player: Target{ObjectID: 1, X: 200}, rounds: 3, want: IDLE},
+		{name: "out of the aggro range", aggressive: true, player: Target{ObjectID: 1, X: 400}, rounds: 3, want: IDLE},
+		{name: "chases a player in range", aggressive: true, player: Target{ObjectID: 1, X: 250}, rounds: 1, want: AGGRO, wantX: 100},
+		{name: "attacks once in range", aggressive: true, player: Target{ObjectID: 1, X: 250}, rounds: 4, want: ATTACK, wantX: 210, wantAttack: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			world := &fakeWorld{players: []Target{tt.player}}
+			scheduler := NewScheduler(world, random.Seeded(1), 1)
+
+			npc := &models.Npc{ObjectID: 10}
+			npcProfile := profile
+			npcProfile.Aggressive = tt.aggressive
+			scheduler.Add(npc, npcProfile)
+
+			for i := 0; i < tt.rounds; i++ {
+				round(scheduler)
+			}
+
+			if state, _ := scheduler.State(npc.ObjectID); state != tt.want || npc.X != tt.wantX || (world.attacks > 0) != tt.wantAttack {
+				t.Errorf("state %s at x %d with %d attacks, want %s at x %d", state, npc.X, world.attacks, tt.want, tt.wantX)
+			}
+		})
+	}
+}
+
+func TestReturnsHome(t *testing.T) {
+	world := &fakeWorld{players: []Target{{ObjectID: 1, X: 290}}}
+	scheduler := NewScheduler(world, random.Seeded(1), 1)
+
+	npc := &models.Npc{ObjectID: 10}
+	scheduler.Add(npc, Profile{Aggressive: true, AggroRange: 300, LeashRange: 1000, Speed: 100})
+	round(scheduler)
+	round(scheduler)
+
+	// The player runs beyond the leash, the NPC gives up and walks back even though it is still close
+	world.players[0].X = 1200
+	round(scheduler)
+	if state, _ := scheduler.State(npc.ObjectID); state != RETURN || npc.X != 100 {
+		t.Fatalf("state %s at x %d once the player ran away", state, npc.X)
+	}
+
+	if scheduler.Provoke(npc.ObjectID, 1) {
+		t.Error("Provoke() = true while walking back")
+	}
+
+	world.players[0].X = 200
+	round(scheduler)
+	round(scheduler)
+	if state, _ := scheduler.State(npc.ObjectID); state != IDLE {
+		t.Errorf("state %s once back home", state)
+	}
+}
+
+func TestProvoke(t *testing.T) {
+	world := &fakeWorld{players: []Target{{ObjectID: 1, X: 150}}}
+	scheduler := NewScheduler(world, random.Seeded(1), 1)
+
+	npc := &models.Npc{ObjectID: 10}
+	scheduler.Add(npc, Profile{Speed: 100})
+	if !scheduler.Provoke(npc.ObjectID, 1) {
+		t.Fatal("Provoke() = false")
+	}
+
+	for i := 0; i < 3; i++ {
+		round(scheduler)
+	}
+	if state, _ := scheduler.State(npc.ObjectID); state != ATTACK || world.attacks != 1 {
+		t.Errorf("state %s with %d attacks once provoked", state, world.attacks)
+	}
+}
+
+func TestWander(t *testing.T) {
+	world := &fakeWorld{players: []Target{{ObjectID: 1, X: 5000, Y: 5000}}}
+	scheduler := NewScheduler(world, random.Seeded(1), 1)
+
+	npc := &models.Npc{ObjectID: 10, X: 4000, Y: 4000}
+	scheduler.Add(npc, Profile{WanderRadius: 300, Speed: 100})
+
+	moved := false
+	for i := 0; i < 200; i++ {
+		round(scheduler)
+		if npc.X != 4000 || npc.Y != 4000 {
+			moved = true
+		}
+		if d := distance(4000, 4000, npc.X, npc.Y); d > 300 {
+			t.Fatalf("wandered %d units away from the spawn", d)
+		}
+	}
+	if !moved {
+		t.Error("never wandered")
+	}
+}
+
+func TestPausedRegions(t *testing.T) {
+	world := &fakeWorld{players: []Target{{ObjectID: 1, X: 100, Y: 100}}}
+	scheduler := NewScheduler(world, random.Seeded(1), 4)
+
+	// One NPC in each region around the player, and some farther away
+	for i := uint32(0); i < 9; i++ {
+		scheduler.Add(&models.Npc{ObjectID: i + 1, X: (int32(i%3) - 1) * interest.REGION_SIZE, Y: (int32(i/3) - 1) * interest.REGION_SIZE}, Profile{})
+	}
+	scheduler.Add(&models.Npc{ObjectID: 20, X: 3 * interest.REGION_SIZE}, Profile{Aggressive: true, AggroRange: 1000})
+	scheduler.Add(&models.Npc{ObjectID: 21, X: -80000, Y: 120000}, Profile{})
+
+	if stats := scheduler.Tick(); stats != (TickStats{Thinking: 9, Paused: 2}) {
+		t.Errorf("Tick() = %+v", stats)
+	}
+
+	scheduler.Remove(21)
+	world.players = nil
+	if stats := scheduler.Tick(); stats != (TickStats{Paused: 10}) {
+		t.Errorf("Tick() = %+v without players", stats)
+	}
+}
@@ -0,0 +1,201 @@
+package ai
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/interest"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/random"
+)
+
+// TickStats counts the NPCs which thought during a tick, and the ones paused for having no player nearby
+type TickStats struct {
+	Thinking int
+	Paused   int
+}
+
+// Scheduler makes the NPCs think at every tick. They are spread across shards,
+// each shard running its NPCs on its own goroutine during a tick. The NPCs of a region
+// only think when a player stands in it or in one of the eight around it.
+type Scheduler struct {
+	world  World
+	random random.Source
+	shards []*shard
+}
+
+type shard struct {
+	minds map[uint32]*mind // By object id
+	mu    sync.Mutex
+}
+
+// NewScheduler creates a scheduler of the NPCs of a world.
+// A number of shards below 1 uses one per CPU.
+func NewScheduler(world World, source random.Source, shards int) *Scheduler {
+	if shards < 1 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	s := &Scheduler{world: world, random: source, shards: make([]*shard, shards)}
+	for i := range s.shards {
+		s.shards[i] = &shard{minds: make(map[uint32]*mind)}
+	}
+	return s
+}
+
+// Add gives a spawned NPC a behavior, it starts idle where it stands
+func (s *Scheduler) Add(npc *models.Npc, profile Profile) {
+	shard := s.shard(npc.ObjectID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.minds[npc.ObjectID] = newMind(npc, profile)
+}
+
+// Remove stops the behavior of an NPC, returning whether it had one
+func (s *Scheduler) Remove(objectID uint32) bool {
+	shard := s.shard(objectID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	_, ok := shard.minds[objectID]
+	delete(shard.minds, objectID)
+	return ok
+}
+
+// State returns what an NPC is busy with, false if it has no behavior
+func (s *Scheduler) State(objectID uint32) (State, bool) {
+	shard := s.shard(objectID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	mind, ok := shard.minds[objectID]
+	if !ok {
+		return "", false
+	}
+	return mind.state, true
+}
+
+// Provoke makes an NPC chase a player attacking it, aggressive or not, unless it is walking back
+// to its spawn. It returns whether the NPC took the player as its target.
+func (s *Scheduler) Provoke(objectID, playerID uint32) bool {
+	shard := s.shard(objectID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	mind, ok := shard.minds[objectID]
+	if !ok || mind.state == RETURN {
+		return false
+	}
+	if mind.state != AGGRO && mind.state != ATTACK {
+		mind.state, mind.target = AGGRO, playerID
+	}
+	return true
+}
+
+// Tick lets every NPC close to a player take its next decision, the shards running in parallel
+func (s *Scheduler) Tick() TickStats {
+	players := newView(s.world.Players())
+
+	stats := make([]TickStats, len(s.shards))
+	var wg sync.WaitGroup
+	for i, shard := range s.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stats[i] = shard.tick(s.world, players, s.random)
+		}()
+	}
+	wg.Wait()
+
+	var total TickStats
+	for _, shard := range stats {
+		total.Thinking += shard.Thinking
+		total.Paused += shard.Paused
+	}
+	return total
+}
+
+// Advance moves the walking NPCs along their way for the time elapsed, the shards running in parallel.
+// The NPCs of the paused regions keep walking, so the ones going back home get there.
+func (s *Scheduler) Advance(elapsed time.Duration) {
+	var wg sync.WaitGroup
+	for _, shard := range s.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			shard.mu.Lock()
+			defer shard.mu.Unlock()
+			for _, mind := range shard.minds {
+				mind.advance(s.world, elapsed)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) shard(objectID uint32) *shard {
+	return s.shards[objectID%uint32(len(s.shards))]
+}
+
+// tick runs the NPCs of the shard standing in an active region
+func (sh *shard) tick(world World, players *view, source random.Source) TickStats {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	var stats TickStats
+	for _, mind := range sh.minds {
+		if !players.active[interest.RegionOf(mind.x, mind.y)] {
+			stats.Paused++
+			continue
+		}
+
+		mind.think(world, players, source)
+		stats.Thinking++
+	}
+	return stats
+}
+
+// view is where the players stand during a tick, shared by the shards
+type view struct {
+	byID     map[uint32]Target
+	byRegion map[interest.Region][]Target
+	active   map[interest.Region]bool // Regions holding a player or next to one
+}
+
+func newView(players []Target) *view {
+	v := &view{
+		byID:     make(map[uint32]Target, len(players)),
+		byRegion: make(map[interest.Region][]Target),
+		active:   make(map[interest.Region]bool),
+	}
+
+	for _, player := range players {
+		v.byID[player.ObjectID] = player
+
+		region := interest.RegionOf(player.X, player.Y)
+		v.byRegion[region] = append(v.byRegion[region], player)
+		for _, around := range region.Around() {
+			v.active[around] = true
+		}
+	}
+	return v
+}
+
+// nearest returns the closest player within a radius of a location, the radius being at most interest.REGION_SIZE
+func (v *view) nearest(x, y, radius int32) (Target, bool) {
+	var closest Target
+	best := int64(-1)
+
+	for _, around := range interest.RegionOf(x, y).Around() {
+		for _, player := range v.byRegion[around] {
+			d := distance(x, y, player.X, player.Y)
+			if d <= int64(radius) && (best < 0 || d < best) {
+				closest, best = player, d
+			}
+		}
+	}
+	return closest, best >= 0
+}
@@ -0,0 +1,243 @@
+// Package behavior scores how the clients play, looking for what gives the bots away: actions
+// sent at a steady pace, the same moves over and over, and more actions than a human could make.
+// The heuristics are pluggable, the game server deciding what happens to the suspected players.
+package behavior
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// What happens to a suspected player
+const (
+	ACTION_FLAG = "flag" // Only reported
+	ACTION_JAIL = "jail" // Sent to the jail, which it can't teleport out of until it relogs
+	ACTION_KICK = "kick" // Disconnected
+)
+
+const (
+	// The timing and the moves are only scored once this many were observed
+	MIN_INTERVALS = 16
+	MIN_MOVES     = 8
+
+	// Below REGULAR_VARIATION the spread of the intervals between the actions scores 1, above HUMAN_VARIATION 0.
+	// The variation is the standard deviation of the intervals over their mean: a human is well above 0.3.
+	REGULAR_VARIATION = 0.02
+	HUMAN_VARIATION   = 0.2
+)
+
+// Move is how far a player asked to go at once, the destination minus the origin
+type Move struct {
+	DX, DY, DZ int32
+}
+
+// History is what a player did lately, oldest first, at most the window of the analyzer of each
+type History struct {
+	Actions []time.Time
+	Moves   []Move
+}
+
+// Heuristic scores a history from 0, human, to 1, certainly automated. It returns 0 while the history
+// is too short to tell.
+type Heuristic interface {
+	Name() string
+	Score(history History) float64
+}
+
+// Verdict is the score of a player, the highest of the heuristics, and the heuristic which gave it
+type Verdict struct {
+	Score     float64
+	Heuristic string
+}
+
+func (v Verdict) String() string {
+	return fmt.Sprintf("%.2f (%s)", v.Score, v.Heuristic)
+}
+
+// Analyzer holds the heuristics the players are scored with, and the score they are suspected from
+type Analyzer struct {
+	window     int
+	threshold  float64
+	heuristics []Heuristic
+	mu         sync.RWMutex
+}
+
+// NewAnalyzer creates an analyzer keeping the last window actions and moves of the players
+func NewAnalyzer(window int, threshold float64, heuristics ...Heuristic) *Analyzer {
+	return &Analyzer{window: window, threshold: threshold, heuristics: heuristics}
+}
+
+// Default creates an analyzer with the built-in heuristics
+func Default(window int, threshold, maxActionRate float64) *Analyzer {
+	return NewAnalyzer(window, threshold, TimingRegularity{}, RepeatedMoves{}, ActionRate{Max: maxActionRate})
+}
+
+// Add plugs another heuristic in, the players being scored with it from their next action
+func (a *Analyzer) Add(heuristic Heuristic) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.heuristics = append(a.heuristics, heuristic)
+}
+
+// Evaluate scores a history with every heuristic
+func (a *Analyzer) Evaluate(history History) Verdict {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var verdict Verdict
+	for _, heuristic := range a.heuristics {
+		if score := heuristic.Score(history); score > verdict.Score {
+			verdict = Verdict{Score: score, Heuristic: heuristic.Name()}
+		}
+	}
+	return verdict
+}
+
+// NewTracker creates the tracker of a player who did nothing yet
+func (a *Analyzer) NewTracker() *Tracker {
+	return &Tracker{analyzer: a}
+}
+
+// Tracker records what a player does and scores it
+type Tracker struct {
+	analyzer  *Analyzer
+	history   History
+	verdict   Verdict
+	suspected bool
+	jailed    bool
+	mu        sync.Mutex
+}
+
+// Observe records an action of the player, along with its move when it asked to go somewhere, returning
+// its verdict and whether the player just became suspected. A player is only reported once.
+func (t *Tracker) Observe(now time.Time, move *Move) (Verdict, bool) {
+	t.mu.Lock()
+	t.history.Actions = keep(append(t.history.Actions, now), t.analyzer.window)
+	if move != nil {
+		t.history.Moves = keep(append(t.history.Moves, *move), t.analyzer.window)
+	}
+	history := History{Actions: append([]time.Time(nil), t.history.Actions...), Moves: append([]Move(nil), t.history.Moves...)}
+	t.mu.Unlock()
+
+	verdict := t.analyzer.Evaluate(history)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.verdict = verdict
+	if t.suspected || verdict.Score < t.analyzer.threshold {
+		return verdict, false
+	}
+	t.suspected = true
+	return verdict, true
+}
+
+// Verdict returns the last score of the player
+func (t *Tracker) Verdict() Verdict {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.verdict
+}
+
+// Jail keeps the player in the jail
+func (t *Tracker) Jail() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.jailed = true
+}
+
+// Jailed reports whether the player was sent to the jail
+func (t *Tracker) Jailed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.jailed
+}
+
+// keep drops the oldest entries past the window
+func keep[T any](entries []T, window int) []T {
+	if window > 0 && len(entries) > window {
+		return append(entries[:0], entries[len(entries)-window:]...)
+	}
+	return entries
+}
+
+// TimingRegularity scores the actions sent at a steady pace, as a bot waiting a fixed time between them does
+type TimingRegularity struct{}
+
+func (TimingRegularity) Name() string { return "timing" }
+
+func (TimingRegularity) Score(history History) float64 {
+	if len(history.Actions) < MIN_INTERVALS+1 {
+		return 0
+	}
+
+	intervals := make([]float64, len(history.Actions)-1)
+	var sum float64
+	for i := range intervals {
+		intervals[i] = history.Actions[i+1].Sub(history.Actions[i]).Seconds()
+		sum += intervals[i]
+	}
+	mean := sum / float64(len(intervals))
+	if mean <= 0 {
+		return 1
+	}
+
+	var squares float64
+	for _, interval := range intervals {
+		squares += (interval - mean) * (interval - mean)
+	}
+	variation := math.Sqrt(squares/float64(len(intervals))) / mean
+	return clamp((HUMAN_VARIATION - variation) / (HUMAN_VARIATION - REGULAR_VARIATION))
+}
+
+// RepeatedMoves scores the players repeating the same moves, as a bot walking a route does. A player
+// never asking twice for the same move scores 0, one going back and forth between two points nearly 1.
+type RepeatedMoves struct{}
+
+func (RepeatedMoves) Name() string { return "moves" }
+
+func (RepeatedMoves) Score(history History) float64 {
+	if len(history.Moves) < MIN_MOVES {
+		return 0
+	}
+
+	distinct := make(map[Move]bool, len(history.Moves))
+	for _, move := range history.Moves {
+		distinct[move] = true
+	}
+	return clamp(1 - float64(len(distinct)-1)/float64(len(history.Moves)-1))
+}
+
+// ActionRate scores 1 the players who made more than Max actions within a second, which no human does
+type ActionRate struct {
+	Max float64
+}
+
+func (ActionRate) Name() string { return "rate" }
+
+func (r ActionRate) Score(history History) float64 {
+	if r.Max <= 0 {
+		return 0
+	}
+
+	// The actions are in order, the window slides over them
+	first := 0
+	for last, at := range history.Actions {
+		for at.Sub(history.Actions[first]) >= time.Second {
+			first++
+		}
+		if float64(last-first+1) > r.Max {
+			return 1
+		}
+	}
+	return 0
+}
+
+func clamp(score float64) float64 {
+	return math.Max(0, math.Min(1, score))
+}
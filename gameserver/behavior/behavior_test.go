@@ -0,0 +1,125 @@
+package behavior
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// actions returns actions separated by the given intervals
+func actions(intervals ...time.Duration) []time.Time {
+	times := []time.Time{start}
+	for _, interval := range intervals {
+		times = append(times, times[len(times)-1].Add(interval))
+	}
+	return times
+}
+
+// every returns count intervals of the same duration
+func every(count int, interval time.Duration) []time.Duration {
+	intervals := make([]time.Duration, count)
+	for i := range intervals {
+		intervals[i] = interval
+	}
+	return intervals
+}
+
+// between returns count intervals drawn between min and max
+func between(count int, min, max time.Duration) []time.Duration {
+	source := rand.New(rand.NewSource(1))
+	intervals := make([]time.Duration, count)
+	for i := range intervals {
+		intervals[i] = min + time.Duration(source.Int63n(int64(max-min)))
+	}
+	return intervals
+}
+
+func TestHeuristics(t *testing.T) {
+	route := []Move{{DX: 500}, {DY: 500}, {DX: -500}, {DY: -500}}
+	var patrol, wander []Move
+	for i := range 16 {
+		patrol = append(patrol, route[i%len(route)])
+		wander = append(wander, Move{DX: int32(100 + i*37), DY: int32(-50 + i*11)})
+	}
+
+	tests := []struct {
+		name      string
+		heuristic Heuristic
+		history   History
+		want      float64
+	}{
+		{"steady actions", TimingRegularity{}, History{Actions: actions(every(20, 2*time.Second)...)}, 1},
+		{"random think-time", TimingRegularity{}, History{Actions: actions(between(20, time.Second, 3*time.Second)...)}, 0},
+		{"too few actions to tell", TimingRegularity{}, History{Actions: actions(every(MIN_INTERVALS-1, time.Second)...)}, 0},
+		{"actions at once", TimingRegularity{}, History{Actions: actions(every(20, 0)...)}, 1},
+		{"walking a route", RepeatedMoves{}, History{Moves: patrol}, 1 - 3.0/15},
+		{"walking around", RepeatedMoves{}, History{Moves: wander}, 0},
+		{"too few moves to tell", RepeatedMoves{}, History{Moves: patrol[:MIN_MOVES-1]}, 0},
+		{"more actions than possible", ActionRate{Max: 10}, History{Actions: actions(every(12, 50*time.Millisecond)...)}, 1},
+		{"as many actions as possible", ActionRate{Max: 10}, History{Actions: actions(every(30, 100*time.Millisecond)...)}, 0},
+		{"no limit", ActionRate{}, History{Actions: actions(every(30, time.Millisecond)...)}, 0},
+	}
+
+	for _, test := range tests {
+		if got := test.heuristic.Score(test.history); got < test.want-0.001 || got > test.want+0.001 {
+			t.Errorf("%s: %s scored %.3f, want %.3f", test.name, test.heuristic.Name(), got, test.want)
+		}
+	}
+}
+
+// alwaysBot scores every player as a bot once it made an action
+type alwaysBot struct{}
+
+func (alwaysBot) Name() string { return "always" }
+
+func (alwaysBot) Score(history History) float64 {
+	if len(history.Actions) == 0 {
+		return 0
+	}
+	return 1
+}
+
+func TestTracker(t *testing.T) {
+	analyzer := Default(32, 0.9, 10)
+	tracker := analyzer.NewTracker()
+
+	// A human pace is never suspected, however long it plays
+	now := start
+	for _, interval := range between(100, time.Second, 3*time.Second) {
+		now = now.Add(interval)
+		if verdict, suspected := tracker.Observe(now, nil); suspected {
+			t.Fatalf("Observe() suspected a human pace: %v", verdict)
+		}
+	}
+	if len(tracker.history.Actions) != 32 {
+		t.Errorf("the tracker kept %d actions, want the window of 32", len(tracker.history.Actions))
+	}
+
+	// A steady pace is suspected once, as soon as the window tells
+	var reports int
+	for range 40 {
+		now = now.Add(2 * time.Second)
+		if verdict, suspected := tracker.Observe(now, nil); suspected {
+			reports++
+			if verdict.Heuristic != "timing" {
+				t.Errorf("Observe() suspected the player for %v, want the timing", verdict)
+			}
+		}
+	}
+	if reports != 1 || tracker.Verdict().Score != 1 {
+		t.Errorf("the steady pace was reported %d times, scoring %v", reports, tracker.Verdict())
+	}
+
+	// A heuristic plugged in scores the players from their next action
+	analyzer = NewAnalyzer(32, 0.9)
+	tracker = analyzer.NewTracker()
+	if _, suspected := tracker.Observe(start, nil); suspected {
+		t.Fatal("Observe() suspected a player without heuristic")
+	}
+	analyzer.Add(alwaysBot{})
+	if verdict, suspected := tracker.Observe(start, &Move{DX: 10}); !suspected || verdict.Heuristic != "always" {
+		t.Errorf("Observe() = %v, %t once the heuristic was added", verdict, suspected)
+	}
+}
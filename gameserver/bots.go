@@ -0,0 +1,151 @@
+package gameserver
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/eventbus"
+	"github.com/frostwind/l2go/gameserver/behavior"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// Topics of the events published by the game server
+const (
+	TOPIC_BOT_SUSPECTED = "game.bot"
+)
+
+// The jail of Talking Island, where the suspected players are sent
+const (
+	JAIL_X = -114356
+	JAIL_Y = -249645
+	JAIL_Z = -2984
+)
+
+// playerActions are the packets a client only sends when its player does something, unlike
+// the positions and the lists it asks for by itself
+var playerActions = map[byte]bool{
+	opcodes.GameClientMoveBackwardToLocation: true,
+	opcodes.GameClientAction:                 true,
+	opcodes.GameClientRequestBypassToServer:  true,
+	opcodes.GameClientSendBypassBuildCmd:     true,
+	opcodes.GameClientSay2:                   true,
+	opcodes.GameClientRequestActionUse:       true,
+	opcodes.GameClientRequestSocialAction:    true,
+	opcodes.GameClientRequestTargetCanceld:   true,
+	opcodes.GameClientRequestBuyItem:         true,
+	opcodes.GameClientRequestSellItem:        true,
+	opcodes.GameClientSendWarehouseDeposit:   true,
+	opcodes.GameClientSendWarehouseWithdraw:  true,
+	opcodes.GameClientRequestFriendInvite:    true,
+	opcodes.GameClientRequestAnswerFriend:    true,
+	opcodes.GameClientRequestFriendDel:       true,
+	opcodes.GameClientRequestQuestAbort:      true,
+}
+
+// BotSuspected is published when the behavior of a player scores past the threshold of the bot detection,
+// Heuristic naming what gave it away and Action what was done about it
+type BotSuspected struct {
+	ObjectID  uint32    `json:"objectId"`
+	Account   string    `json:"account"`
+	Address   string    `json:"address"`
+	Score     float64   `json:"score"`
+	Heuristic string    `json:"heuristic"`
+	Action    string    `json:"action"`
+	At        time.Time `json:"at"`
+}
+
+func (BotSuspected) Topic() string { return TOPIC_BOT_SUSPECTED }
+
+// Events returns the bus the game server publishes its events on
+func (g *GameServer) Events() *eventbus.Bus {
+	return g.events
+}
+
+// OnBotSuspected registers a callback run for every player suspected of being a bot
+func (g *GameServer) OnBotSuspected(callback func(event BotSuspected)) {
+	g.events.Subscribe(TOPIC_BOT_SUSPECTED, func(event interface{}) error {
+		callback(event.(BotSuspected))
+		return nil
+	})
+}
+
+// Behavior returns the analyzer scoring the players, to plug heuristics in, or nil if the bot detection is disabled
+func (g *GameServer) Behavior() *behavior.Analyzer {
+	return g.behavior
+}
+
+// newBehavior creates the analyzer of the bot detection, nil when it is disabled
+func (g *GameServer) newBehavior() *behavior.Analyzer {
+	detection := g.config.GameServer.Options.BotDetection
+	if !detection.Enabled {
+		return nil
+	}
+	return behavior.Default(detection.HistoryWindow(), detection.SuspectThreshold(), detection.ActionRateLimit())
+}
+
+// checkBotAction fails on an action of the bot detection the game server doesn't know
+func (g *GameServer) checkBotAction() error {
+	switch action := g.config.GameServer.Options.BotDetection.SuspectAction(); action {
+	case behavior.ACTION_FLAG, behavior.ACTION_JAIL, behavior.ACTION_KICK:
+		return nil
+	default:
+		return fmt.Errorf("invalid bot detection action: %s, must be one of: flag, jail, kick", action)
+	}
+}
+
+// observe scores a packet of a player, along with its move, returning whether the client must be kicked for it
+func (g *GameServer) observe(client *models.Client, opcode byte, move *behavior.Move) bool {
+	if !playerActions[opcode] {
+		return false
+	}
+	return g.ObserveAction(client, move)
+}
+
+// ObserveAction scores an action of a player, along with its move when it asked to go somewhere, when the
+// bot detection is enabled. A player whose behavior looks automated is reported once, then flagged, jailed
+// or kicked as configured. It returns whether the client must be kicked.
+func (g *GameServer) ObserveAction(client *models.Client, move *behavior.Move) bool {
+	if client.Behavior == nil {
+		return false
+	}
+
+	verdict, suspected := client.Behavior.Observe(g.clock.Now(), move)
+	if !suspected {
+		return false
+	}
+	return g.suspect(client, verdict)
+}
+
+// suspect reports a player whose behavior looks automated and applies the action of the configuration,
+// returning whether the client must be kicked
+func (g *GameServer) suspect(client *models.Client, verdict behavior.Verdict) bool {
+	action := g.config.GameServer.Options.BotDetection.SuspectAction()
+	atomic.AddUint32(&g.status.suspectedBots, 1)
+	fmt.Printf("Player %d (%s) looks like a bot, scoring %v: %s\n", client.ObjectID, client.Account, verdict, action)
+
+	event := BotSuspected{ObjectID: client.ObjectID, Account: client.Account, Score: verdict.Score, Heuristic: verdict.Heuristic, Action: action, At: g.clock.Now()}
+	if client.Socket != nil {
+		event.Address = client.Socket.RemoteAddr().String()
+	}
+	g.events.Emit(event)
+
+	switch action {
+	case behavior.ACTION_JAIL:
+		client.Behavior.Jail()
+		g.Teleport(client, JAIL_X, JAIL_Y, JAIL_Z)
+	case behavior.ACTION_KICK:
+		return true
+	}
+	return false
+}
+
+// travel teleports a player for a gatekeeper, unless it was sent to the jail
+func (g *GameServer) travel(client *models.Client, x, y, z int32) {
+	if client.Behavior != nil && client.Behavior.Jailed() {
+		fmt.Printf("Player %d can't teleport out of the jail\n", client.ObjectID)
+		return
+	}
+	g.Teleport(client, x, y, z)
+}
@@ -0,0 +1,113 @@
+package gameserver
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/gameserver/effects"
+	"github.com/frostwind/l2go/gameserver/loop"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/repository"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/gameserver/vitals"
+)
+
+// SetClock replaces the clock the game loop, the effects, the saves and the time of the world run on, to run them on a simulated one in tests.
+// It must be called before the game server starts.
+func (g *GameServer) SetClock(c clock.Clock) {
+	g.clock = c
+	g.loop = loop.New(c, g.config.GameServer.Options.TickRate())
+	g.gameTime = g.newGameTime()
+	g.persistence = g.newPersistence()
+}
+
+// AddEffect applies a buff or a debuff to a player, following the stacking rules
+func (g *GameServer) AddEffect(client *models.Client, effect effects.Effect) error {
+	if client.Effects == nil {
+		return fmt.Errorf("%w: the player %d isn't authenticated", effects.ErrInvalidEffect, client.ObjectID)
+	}
+	return client.Effects.Add(effect)
+}
+
+// startEffects gives an authenticated player its list of effects, restoring the ones it had when it left
+func (g *GameServer) startEffects(client *models.Client) {
+	list := effects.NewList(g.clock, effects.Hooks{
+		OnTick: func(effect effects.Effect) {
+			g.changeHP(client, effect.TickHP)
+		},
+		OnChange: func(active []effects.Active) {
+			g.sendEffects(client, active)
+		},
+	})
+
+	// The game loop updates the effects of every player
+	g.clientsMutex.Lock()
+	client.Effects = list
+	g.clientsMutex.Unlock()
+
+	saved, err := g.effectRepository.Load(client.Account)
+	if err != nil {
+		fmt.Printf("Couldn't load the effects of %s: %v\n", client.Account, err)
+		return
+	}
+	for _, effect := range saved {
+		if err := client.Effects.Restore(effect.Effect, effect.Remaining); err != nil {
+			fmt.Printf("Couldn't restore the skill %d on %s: %v\n", effect.SkillID, client.Account, err)
+		}
+	}
+}
+
+// saveEffects keeps the effects of a leaving player for its next session
+func (g *GameServer) saveEffects(client *models.Client) {
+	if client.Effects == nil {
+		return
+	}
+
+	now := g.clock.Now()
+	var saved []repository.SavedEffect
+	for _, active := range client.Effects.Active() {
+		saved = append(saved, repository.SavedEffect{Effect: active.Effect, Remaining: active.Remaining(now)})
+	}
+
+	if err := g.effectRepository.Save(client.Account, saved); err != nil {
+		fmt.Printf("Couldn't save the effects of %s: %v\n", client.Account, err)
+	}
+}
+
+// changeHP heals or damages a player over time, the damage over time never killing it
+func (g *GameServer) changeHP(client *models.Client, amount int) {
+	g.progressMutex.Lock()
+	client.HP = min(max(client.HP+amount, 1), client.MaxHP)
+	hp := client.HP
+	g.progressMutex.Unlock()
+	g.markDirty(client)
+
+	if client.Vitals != nil {
+		client.Vitals.Told(vitals.HP, hp)
+	}
+
+	err := client.Send(serverpackets.NewStatusUpdatePacket(client.ObjectID,
+		serverpackets.StatusAttribute{ID: serverpackets.STATUS_CUR_HP, Value: uint32(hp)},
+	))
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// sendEffects shows a player the icons of its effects
+func (g *GameServer) sendEffects(client *models.Client, active []effects.Active) {
+	now := g.clock.Now()
+	icons := make([]serverpackets.AbnormalEffect, 0, len(active))
+	for _, effect := range active {
+		remaining := int32(-1)
+		if seconds := effect.Remaining(now).Seconds(); seconds >= 0 {
+			remaining = int32(min(math.Ceil(seconds), math.MaxInt32))
+		}
+		icons = append(icons, serverpackets.AbnormalEffect{SkillID: uint32(effect.SkillID), Level: uint16(effect.Level), Remaining: remaining})
+	}
+
+	if err := client.Send(serverpackets.NewAbnormalStatusUpdatePacket(icons)); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,117 @@
+// Package capacity holds how much the players carry by level, read from a data
+// file: the weight of their items and the kinds of items, the adena included.
+// The players carrying close to their weight limit are overloaded, regenerating
+// slower the closer they get, and not at all once they reach it.
+package capacity
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MAX_PENALTY is the overload of a player carrying its weight limit
+const MAX_PENALTY = 4
+
+var ErrInvalidTable = errors.New("invalid capacity table")
+
+//go:embed capacity.json
+var bundled []byte
+
+// Percentages of the weight limit each overload level starts from
+var thresholds = [MAX_PENALTY]float64{50, 66.6, 80, 100}
+
+// Regeneration rates of the overloaded players, by overload level
+var regeneration = [MAX_PENALTY + 1]float64{1, 0.75, 0.5, 0.25, 0}
+
+// Limits are what a player can carry
+type Limits struct {
+	Level  int    `json:"level"`  // Reached to carry that much
+	Weight uint64 `json:"weight"` // Of the items
+	Slots  int    `json:"slots"`  // Kinds of items
+}
+
+// Table gives the limits of every level. The limits of a level hold until the next level listed.
+type Table struct {
+	limits []Limits // By level
+}
+
+// Parse reads a capacity table, a JSON array of Limits listing level 1, then higher levels in order
+func Parse(r io.Reader) (*Table, error) {
+	var limits []Limits
+	if err := json.NewDecoder(r).Decode(&limits); err != nil {
+		return nil, err
+	}
+	if len(limits) == 0 || limits[0].Level != 1 {
+		return nil, fmt.Errorf("%w: level 1 isn't listed first", ErrInvalidTable)
+	}
+
+	for i, level := range limits {
+		if level.Weight == 0 || level.Slots <= 0 {
+			return nil, fmt.Errorf("%w: level %d carries %d weight in %d slots", ErrInvalidTable, level.Level, level.Weight, level.Slots)
+		}
+		if i > 0 && level.Level <= limits[i-1].Level {
+			return nil, fmt.Errorf("%w: level %d listed after level %d", ErrInvalidTable, level.Level, limits[i-1].Level)
+		}
+	}
+
+	return &Table{limits: limits}, nil
+}
+
+// Load reads a capacity table from a file
+func Load(path string) (*Table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Default returns the bundled capacity table, up to level 40
+func Default() *Table {
+	table, err := Parse(bytes.NewReader(bundled))
+	if err != nil {
+		panic(err)
+	}
+	return table
+}
+
+// At returns the limits of a level, the ones of level 1 below it
+func (t *Table) At(level int) Limits {
+	limits := t.limits[0]
+	for _, next := range t.limits[1:] {
+		if next.Level > level {
+			break
+		}
+		limits = next
+	}
+	return limits
+}
+
+// Penalty returns the overload level of a player carrying a weight, from 0 to MAX_PENALTY
+func Penalty(weight, limit uint64) int {
+	if limit == 0 {
+		if weight > 0 {
+			return MAX_PENALTY
+		}
+		return 0
+	}
+
+	percent := float64(weight) * 100 / float64(limit)
+	penalty := 0
+	for penalty < MAX_PENALTY && percent >= thresholds[penalty] {
+		penalty++
+	}
+	return penalty
+}
+
+// Regeneration returns how fast an overloaded player regenerates, 1 being the normal rate
+func Regeneration(penalty int) float64 {
+	return regeneration[min(max(penalty, 0), MAX_PENALTY)]
+}
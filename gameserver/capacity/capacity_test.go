@@ -0,0 +1,77 @@
+package capacity
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	table := Default()
+
+	tests := []struct {
+		level  int
+		weight uint64
+		slots  int
+	}{
+		{level: 0, weight: 69000, slots: 80},
+		{level: 1, weight: 69000, slots: 80},
+		{level: 9, weight: 69000, slots: 80},
+		{level: 10, weight: 72000, slots: 80},
+		{level: 35, weight: 80000, slots: 100},
+		{level: 80, weight: 85000, slots: 100},
+	}
+	for _, tt := range tests {
+		if limits := table.At(tt.level); limits.Weight != tt.weight || limits.Slots != tt.slots {
+			t.Errorf("At(%d) = %+v, want %d weight in %d slots", tt.level, limits, tt.weight, tt.slots)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "no level", data: `[]`},
+		{name: "level 1 missing", data: `[{"level": 2, "weight": 100, "slots": 10}]`},
+		{name: "levels out of order", data: `[{"level": 1, "weight": 100, "slots": 10}, {"level": 5, "weight": 100, "slots": 10}, {"level": 5, "weight": 200, "slots": 10}]`},
+		{name: "no weight", data: `[{"level": 1, "weight": 0, "slots": 10}]`},
+		{name: "no slot", data: `[{"level": 1, "weight": 100, "slots": 0}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.data)); !errors.Is(err, ErrInvalidTable) {
+				t.Errorf("Parse() = %v, want %v", err, ErrInvalidTable)
+			}
+		})
+	}
+}
+
+func TestPenalty(t *testing.T) {
+	tests := []struct {
+		weight, limit uint64
+		want          int
+		regeneration  float64
+	}{
+		{weight: 0, limit: 1000, want: 0, regeneration: 1},
+		{weight: 499, limit: 1000, want: 0, regeneration: 1},
+		{weight: 500, limit: 1000, want: 1, regeneration: 0.75},
+		{weight: 666, limit: 1000, want: 2, regeneration: 0.5},
+		{weight: 800, limit: 1000, want: 3, regeneration: 0.25},
+		{weight: 999, limit: 1000, want: 3, regeneration: 0.25},
+		{weight: 1000, limit: 1000, want: MAX_PENALTY, regeneration: 0},
+		{weight: 5000, limit: 1000, want: MAX_PENALTY, regeneration: 0},
+		{weight: 0, limit: 0, want: 0, regeneration: 1},
+		{weight: 1, limit: 0, want: MAX_PENALTY, regeneration: 0},
+	}
+	for _, tt := range tests {
+		penalty := Penalty(tt.weight, tt.limit)
+		if penalty != tt.want {
+			t.Errorf("Penalty(%d, %d) = %d, want %d", tt.weight, tt.limit, penalty, tt.want)
+		}
+		if regeneration := Regeneration(penalty); regeneration != tt.regeneration {
+			t.Errorf("Regeneration(%d) = %v, want %v", penalty, regeneration, tt.regeneration)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package gameserver
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/persistence"
+	"github.com/frostwind/l2go/gameserver/repository"
+)
+
+// Persistence returns the scheduler saving the characters, whose stats show the queue and the write latency
+func (g *GameServer) Persistence() *persistence.Scheduler {
+	return g.persistence
+}
+
+// Storage returns the repositories of the characters, of their warehouses and of their friends, for the
+// tools seeding them, once the server is initialized
+func (g *GameServer) Storage() (repository.CharacterRepository, repository.WarehouseRepository, repository.FriendRepository) {
+	return g.characterRepository, g.warehouseRepository, g.friendRepository
+}
+
+// newPersistence creates the scheduler saving the characters to the repository, on the clock of the game server
+func (g *GameServer) newPersistence() *persistence.Scheduler {
+	options := g.config.GameServer.Options
+	return persistence.New(g.characterRepository, g.snapshot, g.clock, options.SavePeriod(), options.SaveBatch())
+}
+
+// loadCharacter gives an authenticated player the progress, the inventory and the location it had when it left
+func (g *GameServer) loadCharacter(client *models.Client) {
+	character, ok, err := g.characterRepository.Load(client.Account)
+	if err != nil {
+		fmt.Printf("Couldn't load the character of %s: %v\n", client.Account, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	g.progressMutex.Lock()
+	client.Level, client.Exp, client.Karma = character.Level, character.Exp, character.Karma
+	client.HP = min(max(character.HP, 1), client.MaxHP)
+	g.progressMutex.Unlock()
+
+	g.itemsMutex.Lock()
+	client.Adena, client.Items, client.Paperdoll = character.Adena, character.Items, character.Paperdoll
+	g.itemsMutex.Unlock()
+
+	client.LastAccess = character.LastAccess
+	client.Clan = character.Clan
+
+	client.SetPosition(character.X, character.Y, character.Z)
+}
+
+// snapshot returns the character of an online player as it is now
+func (g *GameServer) snapshot(objectID uint32) (repository.Character, bool) {
+	client, ok := g.Player(objectID)
+	if !ok {
+		return repository.Character{}, false
+	}
+	account, x, y, z := client.Whereabouts()
+	if account == "" {
+		return repository.Character{}, false
+	}
+
+	character := repository.Character{Account: account, Clan: client.Clan, X: x, Y: y, Z: z}
+
+	g.progressMutex.Lock()
+	character.Level, character.Exp, character.HP, character.Karma = client.Level, client.Exp, client.HP, client.Karma
+	g.progressMutex.Unlock()
+
+	g.itemsMutex.Lock()
+	character.Adena, character.Items, character.Paperdoll = client.Adena, maps.Clone(client.Items), maps.Clone(client.Paperdoll)
+	g.itemsMutex.Unlock()
+
+	return character, true
+}
+
+// markDirty queues the character of an authenticated player for the next save
+func (g *GameServer) markDirty(client *models.Client) {
+	if client.Account != "" {
+		g.persistence.MarkDirty(client.ObjectID)
+	}
+}
+
+// saveCharacter writes the character of a leaving player if it changed, before it leaves the world
+func (g *GameServer) saveCharacter(client *models.Client) {
+	if err := g.persistence.Save(client.ObjectID); err != nil {
+		fmt.Printf("Couldn't save the character of %s: %v\n", client.Account, err)
+	}
+}
+
+// saveAll writes every changed character as the game server shuts down
+func (g *GameServer) saveAll() {
+	if err := g.persistence.Flush(); err != nil {
+		fmt.Printf("Couldn't save the characters: %v\n", err)
+	}
+}
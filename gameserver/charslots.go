@@ -0,0 +1,96 @@
+package gameserver
+
+import (
+	"sync"
+
+	"github.com/frostwind/l2go/gameserver/clientpackets"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// characterSlots holds the characters every account created on the game server, which can't
+// outnumber the slots of an account
+type characterSlots struct {
+	characters map[string][]clientpackets.Character
+	mu         sync.Mutex
+}
+
+func newCharacterSlots() *characterSlots {
+	return &characterSlots{characters: make(map[string][]clientpackets.Character)}
+}
+
+// Claim gives a slot of the account to a character, reporting false when none is left
+func (s *characterSlots) Claim(account string, character clientpackets.Character, slots int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.characters[account]) >= slots {
+		return false
+	}
+	s.characters[account] = append(s.characters[account], character)
+	return true
+}
+
+// Used returns how many slots of the account hold a character
+func (s *characterSlots) Used(account string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.characters[account])
+}
+
+// First returns the character the account created first, reporting false when it has none
+func (s *characterSlots) First(account string) (clientpackets.Character, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.characters[account]) == 0 {
+		return clientpackets.Character{}, false
+	}
+	return s.characters[account][0], true
+}
+
+// List returns the characters of the account, in the order they were created
+func (s *characterSlots) List(account string) []serverpackets.CharListEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]serverpackets.CharListEntry, 0, len(s.characters[account]))
+	for _, character := range s.characters[account] {
+		entries = append(entries, serverpackets.CharListEntry{
+			Name:      character.Name,
+			Sex:       character.Sex,
+			Race:      character.Race,
+			ClassID:   character.ClassID,
+			HairStyle: character.HairStyle,
+			HairColor: character.HairColor,
+			Face:      character.Face,
+		})
+	}
+	return entries
+}
+
+// charListPacket lists the characters of the account of a client, with the slots it has left. The
+// characters share the progress, the karma and the items worn the account keeps, until they are stored.
+func (g *GameServer) charListPacket(client *models.Client) []byte {
+	characters := g.characterSlots.List(client.Account)
+
+	g.progressMutex.Lock()
+	level, karma := uint32(max(client.Level, 1)), uint32(max(client.Karma, 0))
+	g.progressMutex.Unlock()
+
+	var paperdoll [serverpackets.PAPERDOLL_SLOTS]uint32
+	g.itemsMutex.Lock()
+	for slot, itemID := range client.Paperdoll {
+		if slot >= 0 && slot < serverpackets.PAPERDOLL_SLOTS && itemID > 0 {
+			paperdoll[slot] = uint32(itemID)
+		}
+	}
+	g.itemsMutex.Unlock()
+
+	for i := range characters {
+		characters[i].Level, characters[i].Karma = level, karma
+		characters[i].Paperdoll, characters[i].LastAccess = paperdoll, client.LastAccess
+	}
+	return serverpackets.NewCharListPacket(characters, g.config.GameServer.Options.CharacterSlots()-len(characters))
+}
@@ -0,0 +1,23 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+//go:generate go run github.com/frostwind/l2go/packets/packetgen -type Action
+
+// Action is sent for every click on an object of the world, it is decoded by generated code
+type Action struct {
+	ObjectID uint32 `l2:"u32"`
+	OriginX  int32  `l2:"u32"`
+	OriginY  int32  `l2:"u32"`
+	OriginZ  int32  `l2:"u32"`
+	Shift    bool   `l2:"bool"`
+}
+
+func NewAction(request []byte) (Action, error) {
+	var a Action
+	err := packets.Unmarshal(request, &a)
+
+	return a, err
+}
@@ -0,0 +1,36 @@
+package clientpackets
+
+import (
+	"errors"
+
+	"github.com/frostwind/l2go/packets"
+)
+
+const authLoginKeysSize = 16
+
+// ErrAuthLoginTooShort is returned when the AuthLogin packet doesn't hold the session keys
+var ErrAuthLoginTooShort = errors.New("AuthLogin packet too short")
+
+type AuthLogin struct {
+	Account  string
+	PlayKey  []byte
+	LoginKey []byte
+}
+
+func NewAuthLogin(request []byte) (AuthLogin, error) {
+	var packet = packets.NewReader(request)
+	var result AuthLogin
+
+	result.Account = packet.ReadString()
+	if result.Account == "" || packet.Len() < authLoginKeysSize {
+		return result, ErrAuthLoginTooShort
+	}
+
+	// The client sends the second half of the play key first
+	playKey2 := packet.ReadBytes(4)
+	playKey1 := packet.ReadBytes(4)
+	result.PlayKey = append(playKey1, playKey2...)
+	result.LoginKey = packet.ReadBytes(8)
+
+	return result, nil
+}
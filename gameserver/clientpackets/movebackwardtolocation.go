@@ -0,0 +1,22 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+// MoveBackwardToLocation is sent when a player clicks the ground to walk there
+type MoveBackwardToLocation struct {
+	TargetX int32 `l2:"u32"`
+	TargetY int32 `l2:"u32"`
+	TargetZ int32 `l2:"u32"`
+	OriginX int32 `l2:"u32"` // Where the client thinks its player stands
+	OriginY int32 `l2:"u32"`
+	OriginZ int32 `l2:"u32"`
+}
+
+func NewMoveBackwardToLocation(request []byte) (MoveBackwardToLocation, error) {
+	var m MoveBackwardToLocation
+	err := packets.Unmarshal(request, &m)
+
+	return m, err
+}
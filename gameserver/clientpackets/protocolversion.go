@@ -5,14 +5,12 @@ import (
 )
 
 type ProtocolVersion struct {
-	Version uint32
+	Version uint32 `l2:"u32"`
 }
 
-func NewProtocolVersion(request []byte) ProtocolVersion {
-	var packet = packets.NewReader(request)
+func NewProtocolVersion(request []byte) (ProtocolVersion, error) {
 	var p ProtocolVersion
+	err := packets.Unmarshal(request, &p)
 
-	p.Version = packet.ReadUInt32()
-
-	return p
+	return p, err
 }
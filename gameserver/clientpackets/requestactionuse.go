@@ -0,0 +1,19 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+// RequestActionUse is a click on an action of the action window, or on its shortcut
+type RequestActionUse struct {
+	ActionID uint32 `l2:"u32"`
+	Ctrl     uint32 `l2:"u32"` // Not 0 when the control key was held
+	Shift    bool   `l2:"bool"`
+}
+
+func NewRequestActionUse(request []byte) (RequestActionUse, error) {
+	var r RequestActionUse
+	err := packets.Unmarshal(request, &r)
+
+	return r, err
+}
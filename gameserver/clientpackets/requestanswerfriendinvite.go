@@ -0,0 +1,17 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+// RequestAnswerFriendInvite accepts or declines the friend invite a player was asked
+type RequestAnswerFriendInvite struct {
+	Response uint32 `l2:"u32"` // 1 to accept
+}
+
+func NewRequestAnswerFriendInvite(request []byte) (RequestAnswerFriendInvite, error) {
+	var r RequestAnswerFriendInvite
+	err := packets.Unmarshal(request, &r)
+
+	return r, err
+}
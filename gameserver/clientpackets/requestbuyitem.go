@@ -0,0 +1,59 @@
+package clientpackets
+
+import (
+	"fmt"
+
+	"github.com/frostwind/l2go/packets"
+)
+
+// ITEM_COUNT_SIZE is the size of an item of a trade or warehouse request: its id then its count
+const ITEM_COUNT_SIZE = 4 + 8
+
+// ItemCount is an item a player trades or stores, and how many of it
+type ItemCount struct {
+	ItemID uint32
+	Count  uint64
+}
+
+// RequestBuyItem buys items of the buy list of the merchant the player is talking to
+type RequestBuyItem struct {
+	ListID uint32
+	Items  []ItemCount
+}
+
+func NewRequestBuyItem(request []byte) (RequestBuyItem, error) {
+	listID, items, err := decodeItemCounts(request)
+	return RequestBuyItem{ListID: listID, Items: items}, err
+}
+
+// RequestSellItem sells items of the player to the merchant it is talking to
+type RequestSellItem struct {
+	ListID uint32
+	Items  []ItemCount
+}
+
+func NewRequestSellItem(request []byte) (RequestSellItem, error) {
+	listID, items, err := decodeItemCounts(request)
+	return RequestSellItem{ListID: listID, Items: items}, err
+}
+
+// decodeItemCounts reads a list id or a warehouse followed by the items moved, checking their count
+// against the size of the packet before allocating them
+func decodeItemCounts(request []byte) (uint32, []ItemCount, error) {
+	decoder := packets.NewDecoder(request)
+	listID := decoder.U32()
+	count := decoder.U32()
+	if err := decoder.Err(); err != nil {
+		return 0, nil, err
+	}
+	if uint64(count)*ITEM_COUNT_SIZE > uint64(decoder.Remaining()) {
+		return 0, nil, fmt.Errorf("%w: %d items in %d bytes", packets.ErrInsufficientData, count, decoder.Remaining())
+	}
+
+	items := make([]ItemCount, count)
+	for i := range items {
+		items[i].ItemID = decoder.U32()
+		items[i].Count = decoder.U64()
+	}
+	return listID, items, decoder.Err()
+}
@@ -0,0 +1,16 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+type RequestBypassToServer struct {
+	Command string `l2:"string"`
+}
+
+func NewRequestBypassToServer(request []byte) (RequestBypassToServer, error) {
+	var r RequestBypassToServer
+	err := packets.Unmarshal(request, &r)
+
+	return r, err
+}
@@ -0,0 +1,17 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+// RequestFriendDel removes a player from the friend list of the one sending it
+type RequestFriendDel struct {
+	Name string `l2:"string"`
+}
+
+func NewRequestFriendDel(request []byte) (RequestFriendDel, error) {
+	var r RequestFriendDel
+	err := packets.Unmarshal(request, &r)
+
+	return r, err
+}
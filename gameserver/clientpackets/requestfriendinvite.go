@@ -0,0 +1,17 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+// RequestFriendInvite asks a player to join the friend list of the one sending it
+type RequestFriendInvite struct {
+	Name string `l2:"string"`
+}
+
+func NewRequestFriendInvite(request []byte) (RequestFriendInvite, error) {
+	var r RequestFriendInvite
+	err := packets.Unmarshal(request, &r)
+
+	return r, err
+}
@@ -0,0 +1,17 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+// RequestQuestAbort abandons a quest of the one sending it
+type RequestQuestAbort struct {
+	QuestID uint32 `l2:"u32"`
+}
+
+func NewRequestQuestAbort(request []byte) (RequestQuestAbort, error) {
+	var r RequestQuestAbort
+	err := packets.Unmarshal(request, &r)
+
+	return r, err
+}
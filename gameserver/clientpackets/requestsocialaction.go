@@ -0,0 +1,17 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+// RequestSocialAction is an emote of the one sending it, such as a bow or a dance
+type RequestSocialAction struct {
+	ActionID uint32 `l2:"u32"`
+}
+
+func NewRequestSocialAction(request []byte) (RequestSocialAction, error) {
+	var r RequestSocialAction
+	err := packets.Unmarshal(request, &r)
+
+	return r, err
+}
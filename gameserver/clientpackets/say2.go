@@ -0,0 +1,45 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+// Say2 is a chat message sent by a player
+type Say2 struct {
+	Text     string `l2:"string"`
+	ChatType uint32 `l2:"u32"`
+	Target   string // The player a whisper is sent to, empty for the other chats
+}
+
+func NewSay2(request []byte) (Say2, error) {
+	var s Say2
+	err := packets.Unmarshal(request, &s)
+
+	return s, err
+}
+
+// AppendPacket encodes the Say2 packet behind dst, the target only when there is one
+func (s Say2) AppendPacket(dst []byte) ([]byte, error) {
+	w := packets.NewPacketWriter()
+	defer w.Release()
+
+	w.S(s.Text).U32(s.ChatType)
+	if s.Target != "" {
+		w.S(s.Target)
+	}
+
+	return append(dst, w.Payload()...), nil
+}
+
+// UnmarshalPacket decodes the Say2 packet, only the whispers being followed by the name of their recipient
+func (s *Say2) UnmarshalPacket(data []byte) error {
+	d := packets.NewDecoder(data)
+
+	s.Text = d.S()
+	s.ChatType = d.U32()
+	if d.Err() == nil && d.Remaining() > 0 {
+		s.Target = d.S()
+	}
+
+	return d.Err()
+}
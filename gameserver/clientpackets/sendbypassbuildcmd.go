@@ -0,0 +1,16 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+type SendBypassBuildCmd struct {
+	Command string `l2:"string"`
+}
+
+func NewSendBypassBuildCmd(request []byte) (SendBypassBuildCmd, error) {
+	var r SendBypassBuildCmd
+	err := packets.Unmarshal(request, &r)
+
+	return r, err
+}
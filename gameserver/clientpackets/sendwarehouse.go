@@ -0,0 +1,23 @@
+package clientpackets
+
+// SendWarehouseDeposit stores items of the player in one of the warehouses
+type SendWarehouseDeposit struct {
+	Kind  uint32 // Private or clan warehouse
+	Items []ItemCount
+}
+
+func NewSendWarehouseDeposit(request []byte) (SendWarehouseDeposit, error) {
+	kind, items, err := decodeItemCounts(request)
+	return SendWarehouseDeposit{Kind: kind, Items: items}, err
+}
+
+// SendWarehouseWithdraw takes items of one of the warehouses back to the inventory of the player
+type SendWarehouseWithdraw struct {
+	Kind  uint32 // Private or clan warehouse
+	Items []ItemCount
+}
+
+func NewSendWarehouseWithdraw(request []byte) (SendWarehouseWithdraw, error) {
+	kind, items, err := decodeItemCounts(request)
+	return SendWarehouseWithdraw{Kind: kind, Items: items}, err
+}
@@ -0,0 +1,20 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+// ValidatePosition is sent regularly by a moving client to report where its player stands
+type ValidatePosition struct {
+	X       int32 `l2:"u32"`
+	Y       int32 `l2:"u32"`
+	Z       int32 `l2:"u32"`
+	Heading int32 `l2:"u32"`
+}
+
+func NewValidatePosition(request []byte) (ValidatePosition, error) {
+	var v ValidatePosition
+	err := packets.Unmarshal(request, &v)
+
+	return v, err
+}
@@ -0,0 +1,34 @@
+package gameserver
+
+import (
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
+)
+
+// clientCompression returns what the game server offers a client, the packets it inflates being
+// bounded by the packet size limit of the client
+func (g *GameServer) clientCompression(client *models.Client) protocol.CompressionOptions {
+	return protocol.CompressionOptions{
+		Enabled:   g.config.GameServer.Options.Compression.Enabled,
+		Threshold: g.config.GameServer.Options.Compression.Threshold,
+		MaxSize:   client.MaxPacketSize,
+	}
+}
+
+// LinkCompressionStats returns the compression metrics of the link with the login server, nothing while it isn't compressed
+func (g *GameServer) LinkCompressionStats() protocol.CompressionStats {
+	if compressor := g.linkCompressor.Load(); compressor != nil {
+		return compressor.Stats()
+	}
+	return protocol.CompressionStats{}
+}
+
+// linkCompression returns what the game server offers the login server for their link
+func (g *GameServer) linkCompression() protocol.CompressionOptions {
+	return protocol.CompressionOptions{
+		Enabled:   g.config.GameServer.Options.Compression.Enabled,
+		Threshold: g.config.GameServer.Options.Compression.Threshold,
+		MaxSize:   packets.MaxFrameSize,
+	}
+}
@@ -1,5 +1,10 @@
 package xor
 
+import "encoding/binary"
+
+// BROADCAST spreads a byte over the 8 bytes of a word
+const BROADCAST = 0x0101010101010101
+
 type Cipher struct {
 	InputKey  []byte
 	OutputKey []byte
@@ -10,60 +15,54 @@ func NewCipher() *Cipher {
 		OutputKey: []byte{0x94, 0x35, 0x00, 0x00, 0xa1, 0x6c, 0x54, 0x87}}
 }
 
+// Decrypt deciphers raw in place: every byte is xored with the key and with the previous byte of the
+// ciphertext. The bytes only depend on the ciphertext, so they are deciphered 8 at a time, a word xored
+// with itself shifted by a byte.
 func Decrypt(raw, key []byte) {
-	temp := 0
-	j := 0
-	length := len(raw)
-
-	for i := 0; i < length; i++ {
-		temp2 := int(raw[i])
-		raw[i] = byte(temp2) ^ (key[j]) ^ byte(temp)
-		j = j + 1
-		temp = temp2
+	key64 := binary.LittleEndian.Uint64(key)
+	var previous uint64 // Last byte of the ciphertext deciphered so far
 
-		if j > 7 {
-			j = 0
-		}
+	i := 0
+	for ; i+8 <= len(raw); i += 8 {
+		word := binary.LittleEndian.Uint64(raw[i:])
+		binary.LittleEndian.PutUint64(raw[i:], word^key64^(word<<8|previous))
+		previous = word >> 56
+	}
+	for ; i < len(raw); i++ {
+		cipher := raw[i]
+		raw[i] = cipher ^ key[i&7] ^ byte(previous)
+		previous = uint64(cipher)
 	}
 
-	var old int = int(key[0])
-	old |= int(key[1]) << 8
-	old |= int(key[2]) << 0x10
-	old |= int(key[3]) << 0x18
-
-	old += len(raw)
-
-	key[0] = byte(old)
-	key[1] = byte(old >> 0x08)
-	key[2] = byte(old >> 0x10)
-	key[3] = byte(old >> 0x18)
+	rollKey(key, len(raw))
 }
 
+// Encrypt ciphers raw in place: every byte is xored with the key and with the previous byte of the
+// ciphertext. Over a word, that makes every byte the xor of the plaintext and the key of the bytes up to
+// it, a prefix xor computed in three shifts, and of the last byte of the previous word.
 func Encrypt(raw, key []byte) {
-	temp := 0
-	j := 0
-	length := len(raw)
-
-	for i := 0; i < length; i++ {
-		temp2 := int(raw[i])
-		raw[i] = byte(temp2) ^ (key[j]) ^ byte(temp)
-		j = j + 1
-		temp = int(raw[i])
+	key64 := binary.LittleEndian.Uint64(key)
+	var previous uint64 // Last byte of the ciphertext so far
 
-		if j > 7 {
-			j = 0
-		}
+	i := 0
+	for ; i+8 <= len(raw); i += 8 {
+		word := binary.LittleEndian.Uint64(raw[i:]) ^ key64
+		word ^= word << 8
+		word ^= word << 16
+		word ^= word << 32
+		word ^= previous * BROADCAST
+		binary.LittleEndian.PutUint64(raw[i:], word)
+		previous = word >> 56
+	}
+	for ; i < len(raw); i++ {
+		raw[i] ^= key[i&7] ^ byte(previous)
+		previous = uint64(raw[i])
 	}
 
-	var old int = int(key[0])
-	old |= int(key[1]) << 8
-	old |= int(key[2]) << 0x10
-	old |= int(key[3]) << 0x18
-
-	old += len(raw)
+	rollKey(key, len(raw))
+}
 
-	key[0] = byte(old)
-	key[1] = byte(old >> 0x08)
-	key[2] = byte(old >> 0x10)
-	key[3] = byte(old >> 0x18)
+// rollKey adds the size of a packet to the first 4 bytes of the key, so that no two packets share it
+func rollKey(key []byte, size int) {
+	binary.LittleEndian.PutUint32(key, binary.LittleEndian.Uint32(key)+uint32(size))
 }
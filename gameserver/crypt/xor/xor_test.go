@@ -0,0 +1,97 @@
+package xor
+
+import (
+	"bytes"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// decryptBytewise and encryptBytewise are the byte at a time ciphers the word ones must match
+func decryptBytewise(raw, key []byte) {
+	var previous byte
+	for i := range raw {
+		cipher := raw[i]
+		raw[i] = cipher ^ key[i%8] ^ previous
+		previous = cipher
+	}
+	rollKeyBytewise(key, len(raw))
+}
+
+func encryptBytewise(raw, key []byte) {
+	var previous byte
+	for i := range raw {
+		raw[i] ^= key[i%8] ^ previous
+		previous = raw[i]
+	}
+	rollKeyBytewise(key, len(raw))
+}
+
+func rollKeyBytewise(key []byte, size int) {
+	old := int(key[0]) | int(key[1])<<8 | int(key[2])<<0x10 | int(key[3])<<0x18
+	old += size
+	key[0], key[1], key[2], key[3] = byte(old), byte(old>>0x08), byte(old>>0x10), byte(old>>0x18)
+}
+
+func TestCipher(t *testing.T) {
+	source := rand.New(rand.NewSource(1))
+	// The key is about to wrap around its 32 bits
+	wordKey := []byte{0xf0, 0xff, 0xff, 0xff, 0xa1, 0x6c, 0x54, 0x87}
+	bytewiseKey := bytes.Clone(wordKey)
+	decryptKey := bytes.Clone(wordKey)
+
+	// Every length around the size of a word, then packets of any size, the key rolling between them
+	var sizes []int
+	for size := range 26 {
+		sizes = append(sizes, size)
+	}
+	for range 50 {
+		sizes = append(sizes, source.Intn(1500))
+	}
+
+	for _, size := range sizes {
+		plaintext := make([]byte, size)
+		source.Read(plaintext)
+
+		encrypted, want := bytes.Clone(plaintext), bytes.Clone(plaintext)
+		Encrypt(encrypted, wordKey)
+		encryptBytewise(want, bytewiseKey)
+		if !bytes.Equal(encrypted, want) || !bytes.Equal(wordKey, bytewiseKey) {
+			t.Fatalf("Encrypt() of %d bytes = % x with the key % x, want % x with % x", size, encrypted, wordKey, want, bytewiseKey)
+		}
+
+		decrypted, reference := bytes.Clone(encrypted), bytes.Clone(encrypted)
+		referenceKey := bytes.Clone(decryptKey)
+		Decrypt(decrypted, decryptKey)
+		decryptBytewise(reference, referenceKey)
+		if !bytes.Equal(decrypted, plaintext) || !bytes.Equal(decrypted, reference) || !bytes.Equal(decryptKey, referenceKey) {
+			t.Fatalf("Decrypt() of %d bytes = % x with the key % x, want % x with % x", size, decrypted, decryptKey, plaintext, referenceKey)
+		}
+	}
+}
+
+// benchmarkCipher ciphers a packet of size bytes, rolling the key as a connection does
+func benchmarkCipher(b *testing.B, cipher func(raw, key []byte), size int) {
+	raw := make([]byte, size)
+	key := NewCipher().OutputKey
+
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cipher(raw, key)
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	for _, size := range []int{16, 64, 512, 4096} {
+		b.Run("words/"+strconv.Itoa(size), func(b *testing.B) { benchmarkCipher(b, Encrypt, size) })
+		b.Run("bytes/"+strconv.Itoa(size), func(b *testing.B) { benchmarkCipher(b, encryptBytewise, size) })
+	}
+}
+
+func BenchmarkDecrypt(b *testing.B) {
+	for _, size := range []int{16, 64, 512, 4096} {
+		b.Run("words/"+strconv.Itoa(size), func(b *testing.B) { benchmarkCipher(b, Decrypt, size) })
+		b.Run("bytes/"+strconv.Itoa(size), func(b *testing.B) { benchmarkCipher(b, decryptBytewise, size) })
+	}
+}
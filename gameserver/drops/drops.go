@@ -0,0 +1,126 @@
+// Package drops holds the drop tables of the NPCs, read from a data file, and
+// rolls the items a killed NPC leaves on the ground
+package drops
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/frostwind/l2go/random"
+)
+
+// ADENA_ID is the item id of the adena
+const ADENA_ID = 57
+
+// CHANCE_PRECISION is the number of possible outcomes of a roll, the chances
+// being percentages with up to four decimals
+const CHANCE_PRECISION = 100 * 10000
+
+var (
+	ErrInvalidEntry   = errors.New("invalid drop entry")
+	ErrDuplicateTable = errors.New("duplicate drop table")
+)
+
+// Entry is an item an NPC can drop
+type Entry struct {
+	ItemID int     `json:"itemId"`
+	Chance float64 `json:"chance"` // Percentage, between 0 and 100
+	Min    uint64  `json:"min"`
+	Max    uint64  `json:"max"`
+}
+
+// Validate checks the chance is a percentage and the counts a range
+func (e Entry) Validate() error {
+	if e.Chance < 0 || e.Chance > 100 {
+		return fmt.Errorf("%w: the item %d has a chance of %v%%", ErrInvalidEntry, e.ItemID, e.Chance)
+	}
+	if e.Min == 0 || e.Max < e.Min {
+		return fmt.Errorf("%w: the item %d drops between %d and %d", ErrInvalidEntry, e.ItemID, e.Min, e.Max)
+	}
+	return nil
+}
+
+// List holds the drops of an NPC template
+type List struct {
+	NpcID int     `json:"npcId"`
+	Drops []Entry `json:"drops"`
+}
+
+// Drop is an item rolled out of a drop table
+type Drop struct {
+	ItemID int
+	Count  uint64
+}
+
+// Tables holds the drops of every NPC template
+type Tables struct {
+	lists map[int][]Entry
+}
+
+// Parse reads the drop tables, a JSON array of List
+func Parse(r io.Reader) (*Tables, error) {
+	var lists []List
+	if err := json.NewDecoder(r).Decode(&lists); err != nil {
+		return nil, err
+	}
+
+	tables := &Tables{lists: make(map[int][]Entry)}
+	for _, list := range lists {
+		if _, ok := tables.lists[list.NpcID]; ok {
+			return nil, fmt.Errorf("%w: NPC %d", ErrDuplicateTable, list.NpcID)
+		}
+		for _, entry := range list.Drops {
+			if err := entry.Validate(); err != nil {
+				return nil, fmt.Errorf("NPC %d: %w", list.NpcID, err)
+			}
+		}
+		tables.lists[list.NpcID] = list.Drops
+	}
+
+	return tables, nil
+}
+
+// Load reads the drop tables from a file
+func Load(path string) (*Tables, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Entries returns the drops of an NPC template
+func (t *Tables) Entries(npcID int) []Entry {
+	if t == nil {
+		return nil
+	}
+	return t.lists[npcID]
+}
+
+// Roll rolls the drops of an NPC template
+func (t *Tables) Roll(source random.Source, npcID int) []Drop {
+	return Roll(source, t.Entries(npcID))
+}
+
+// Roll rolls every entry on its own: each one drops with its chance, a count
+// picked evenly between its minimum and its maximum
+func Roll(source random.Source, entries []Entry) []Drop {
+	var dropped []Drop
+	for _, entry := range entries {
+		if source.Int64N(CHANCE_PRECISION) >= int64(entry.Chance*CHANCE_PRECISION/100) {
+			continue
+		}
+
+		count := entry.Min
+		if entry.Max > entry.Min {
+			count += uint64(source.Int64N(int64(entry.Max-entry.Min) + 1))
+		}
+		dropped = append(dropped, Drop{ItemID: entry.ItemID, Count: count})
+	}
+	return dropped
+}
@@ -0,0 +1,79 @@
+package drops
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/frostwind/l2go/random"
+)
+
+func TestRollBounds(t *testing.T) {
+	entries := []Entry{
+		{ItemID: ADENA_ID, Chance: 70, Min: 10, Max: 20},
+		{ItemID: 1864, Chance: 2.5, Min: 1, Max: 1},
+		{ItemID: 1865, Chance: 0, Min: 1, Max: 1},
+		{ItemID: 1866, Chance: 100, Min: 3, Max: 3},
+	}
+
+	const rolls = 100000
+	source := random.Seeded(1)
+	dropped := make(map[int]int)
+	for i := 0; i < rolls; i++ {
+		for _, drop := range Roll(source, entries) {
+			dropped[drop.ItemID]++
+			for _, entry := range entries {
+				if entry.ItemID == drop.ItemID && (drop.Count < entry.Min || drop.Count > entry.Max) {
+					t.Fatalf("%d of the item %d dropped, want between %d and %d", drop.Count, drop.ItemID, entry.Min, entry.Max)
+				}
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		rate := float64(dropped[entry.ItemID]) * 100 / rolls
+		// Four standard deviations of the binomial distribution
+		tolerance := 4 * math.Sqrt(entry.Chance*(100-entry.Chance)/rolls)
+		if math.Abs(rate-entry.Chance) > tolerance {
+			t.Errorf("the item %d dropped %.3f%% of the time, want %v%% ± %.3f", entry.ItemID, rate, entry.Chance, tolerance)
+		}
+	}
+
+	// The same seed rolls the same drops
+	first, second := Roll(random.Seeded(7), entries), Roll(random.Seeded(7), entries)
+	if len(first) != len(second) {
+		t.Fatalf("Roll() = %v and %v with the same seed", first, second)
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Errorf("Roll() = %v and %v with the same seed", first, second)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		tables string
+		want   error
+	}{
+		{"valid", `[{"npcId": 20001, "drops": [{"itemId": 57, "chance": 70, "min": 10, "max": 20}]}]`, nil},
+		{"chance above 100", `[{"npcId": 20001, "drops": [{"itemId": 57, "chance": 101, "min": 1, "max": 1}]}]`, ErrInvalidEntry},
+		{"no count", `[{"npcId": 20001, "drops": [{"itemId": 57, "chance": 50, "min": 0, "max": 0}]}]`, ErrInvalidEntry},
+		{"reversed range", `[{"npcId": 20001, "drops": [{"itemId": 57, "chance": 50, "min": 5, "max": 2}]}]`, ErrInvalidEntry},
+		{"duplicate", `[{"npcId": 20001, "drops": []}, {"npcId": 20001, "drops": []}]`, ErrDuplicateTable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables, err := Parse(strings.NewReader(tt.tables))
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.want)
+			}
+			if err == nil && len(tables.Entries(20001)) != 1 {
+				t.Errorf("Entries() = %v", tables.Entries(20001))
+			}
+		})
+	}
+}
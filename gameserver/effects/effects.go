@@ -0,0 +1,233 @@
+// Package effects implements the buffs and the debuffs of the players: their
+// expiry, the stacking rules deciding which ones are kept and the periodic
+// ticks of the damage and the healing over time
+package effects
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+)
+
+// Kinds of effects, each kind having its own number of slots
+const (
+	BUFF   = "buff"
+	DEBUFF = "debuff"
+)
+
+// Slots of each kind, the oldest effect making room for a new one once they are all taken
+const (
+	MAX_BUFFS   = 20
+	MAX_DEBUFFS = 10
+)
+
+var (
+	ErrInvalidEffect = errors.New("invalid effect")
+	ErrWeakerEffect  = errors.New("a stronger effect is already active")
+)
+
+// Effect is a buff or a debuff given by a skill
+type Effect struct {
+	SkillID   int           `json:"skillId"`
+	Level     int           `json:"level"`
+	Kind      string        `json:"kind"`
+	StackType string        `json:"stackType,omitempty"` // The effects sharing a stack type replace each other, the highest level staying
+	Duration  time.Duration `json:"duration"`            // 0 for an effect lasting until it is removed
+	Tick      time.Duration `json:"tick,omitempty"`      // Time between two ticks, 0 for none
+	TickHP    int           `json:"tickHp,omitempty"`    // HP given at every tick, negative for the damage over time
+}
+
+// Validate checks the kind, the duration and the ticks of the effect
+func (e Effect) Validate() error {
+	if e.Kind != BUFF && e.Kind != DEBUFF {
+		return fmt.Errorf("%w: the skill %d has the kind %q, must be one of: %s, %s", ErrInvalidEffect, e.SkillID, e.Kind, BUFF, DEBUFF)
+	}
+	if e.Duration < 0 {
+		return fmt.Errorf("%w: the skill %d lasts %v", ErrInvalidEffect, e.SkillID, e.Duration)
+	}
+	if e.Tick < 0 || (e.TickHP != 0 && e.Tick == 0) {
+		return fmt.Errorf("%w: the skill %d ticks every %v", ErrInvalidEffect, e.SkillID, e.Tick)
+	}
+	return nil
+}
+
+// Active is an effect applied to a player
+type Active struct {
+	Effect
+	Expires  time.Time // Zero for an effect lasting until it is removed
+	nextTick time.Time
+}
+
+// Remaining returns how long the effect still lasts, a negative duration when it lasts until removed
+func (a Active) Remaining(now time.Time) time.Duration {
+	if a.Expires.IsZero() {
+		return -1
+	}
+	return max(a.Expires.Sub(now), 0)
+}
+
+// Hooks are told about the ticks and the changes of the effects of a list.
+// They are called from the goroutine updating the list or adding the effects,
+// without holding its lock.
+type Hooks struct {
+	OnTick   func(effect Effect)   // Called for every tick of an effect
+	OnChange func(active []Active) // Called with the remaining effects once some were added, removed or expired
+}
+
+// List holds the effects of a player
+type List struct {
+	clock  clock.Clock
+	hooks  Hooks
+	active []*Active // In the order they were applied
+	mu     sync.Mutex
+}
+
+// NewList creates an empty list of effects timed by the clock
+func NewList(c clock.Clock, hooks Hooks) *List {
+	return &List{clock: c, hooks: hooks}
+}
+
+// Add applies an effect for its whole duration
+func (l *List) Add(effect Effect) error {
+	return l.Restore(effect, effect.Duration)
+}
+
+// Restore applies an effect for the remaining part of its duration, as saved when the player left
+func (l *List) Restore(effect Effect, remaining time.Duration) error {
+	if err := effect.Validate(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	now := l.clock.Now()
+
+	// The same skill, or another one of the same stack type, is replaced unless it is stronger
+	kept := l.active[:0]
+	for _, active := range l.active {
+		stacks := active.SkillID == effect.SkillID || (effect.StackType != "" && active.StackType == effect.StackType)
+		if stacks && active.Level > effect.Level {
+			l.mu.Unlock()
+			return fmt.Errorf("%w: level %d of the skill %d", ErrWeakerEffect, active.Level, active.SkillID)
+		}
+		if !stacks {
+			kept = append(kept, active)
+		}
+	}
+	l.active = kept
+
+	l.makeRoom(effect.Kind)
+
+	added := &Active{Effect: effect}
+	if effect.Duration > 0 {
+		added.Expires = now.Add(remaining)
+	}
+	if effect.Tick > 0 {
+		added.nextTick = now.Add(effect.Tick)
+	}
+	l.active = append(l.active, added)
+	active := l.snapshot()
+	l.mu.Unlock()
+
+	if l.hooks.OnChange != nil {
+		l.hooks.OnChange(active)
+	}
+	return nil
+}
+
+// Remove cancels the effect of a skill, returning whether it was active
+func (l *List) Remove(skillID int) bool {
+	l.mu.Lock()
+	removed := false
+	kept := l.active[:0]
+	for _, active := range l.active {
+		if active.SkillID == skillID {
+			removed = true
+			continue
+		}
+		kept = append(kept, active)
+	}
+	l.active = kept
+	active := l.snapshot()
+	l.mu.Unlock()
+
+	if removed && l.hooks.OnChange != nil {
+		l.hooks.OnChange(active)
+	}
+	return removed
+}
+
+// Active returns the effects currently applied, in the order they were
+func (l *List) Active() []Active {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.snapshot()
+}
+
+// Update runs the ticks due by now and drops the expired effects, the game loop calling it at every tick
+func (l *List) Update(now time.Time) {
+	l.mu.Lock()
+	var ticks []Effect
+	changed := false
+	kept := l.active[:0]
+	for _, active := range l.active {
+		for active.Tick > 0 && !active.nextTick.After(now) && (active.Expires.IsZero() || !active.nextTick.After(active.Expires)) {
+			ticks = append(ticks, active.Effect)
+			active.nextTick = active.nextTick.Add(active.Tick)
+		}
+
+		if !active.Expires.IsZero() && !now.Before(active.Expires) {
+			changed = true
+			continue
+		}
+		kept = append(kept, active)
+	}
+	l.active = kept
+	active := l.snapshot()
+	l.mu.Unlock()
+
+	if l.hooks.OnTick != nil {
+		for _, effect := range ticks {
+			l.hooks.OnTick(effect)
+		}
+	}
+	if changed && l.hooks.OnChange != nil {
+		l.hooks.OnChange(active)
+	}
+}
+
+// makeRoom drops the oldest effects of a kind until there is a free slot, the lock being held
+func (l *List) makeRoom(kind string) {
+	slots := MAX_BUFFS
+	if kind == DEBUFF {
+		slots = MAX_DEBUFFS
+	}
+
+	count := 0
+	for _, active := range l.active {
+		if active.Kind == kind {
+			count++
+		}
+	}
+
+	kept := l.active[:0]
+	for _, active := range l.active {
+		if active.Kind == kind && count >= slots {
+			count--
+			continue
+		}
+		kept = append(kept, active)
+	}
+	l.active = kept
+}
+
+func (l *List) snapshot() []Active {
+	active := make([]Active, 0, len(l.active))
+	for _, effect := range l.active {
+		active = append(active, *effect)
+	}
+	return active
+}
@@ -0,0 +1,109 @@
+package effects
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+)
+
+func TestStacking(t *testing.T) {
+	might := Effect{SkillID: 1068, Level: 2, Kind: BUFF, StackType: "pAtk", Duration: time.Minute}
+
+	tests := []struct {
+		name       string
+		add        Effect
+		wantErr    error
+		wantSkills []int
+		wantLevel  int
+	}{
+		{name: "other stack type", add: Effect{SkillID: 1040, Level: 1, Kind: BUFF, StackType: "pDef", Duration: time.Minute}, wantSkills: []int{1068, 1040}, wantLevel: 2},
+		{name: "same skill, higher level", add: Effect{SkillID: 1068, Level: 3, Kind: BUFF, StackType: "pAtk", Duration: time.Minute}, wantSkills: []int{1068}, wantLevel: 3},
+		{name: "same skill, same level", add: might, wantSkills: []int{1068}, wantLevel: 2},
+		{name: "same stack type, lower level", add: Effect{SkillID: 1086, Level: 1, Kind: BUFF, StackType: "pAtk", Duration: time.Minute}, wantErr: ErrWeakerEffect, wantSkills: []int{1068}, wantLevel: 2},
+		{name: "same stack type, higher level", add: Effect{SkillID: 1086, Level: 5, Kind: BUFF, StackType: "pAtk", Duration: time.Minute}, wantSkills: []int{1086}, wantLevel: 5},
+		{name: "invalid kind", add: Effect{SkillID: 1, Kind: "aura"}, wantErr: ErrInvalidEffect, wantSkills: []int{1068}, wantLevel: 2},
+		{name: "ticks without interval", add: Effect{SkillID: 1, Kind: DEBUFF, TickHP: -5}, wantErr: ErrInvalidEffect, wantSkills: []int{1068}, wantLevel: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := NewList(clock.NewFake(time.Now()), Hooks{})
+			if err := list.Add(might); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := list.Add(tt.add); !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Add() error = %v, want %v", err, tt.wantErr)
+			}
+
+			active := list.Active()
+			if len(active) != len(tt.wantSkills) {
+				t.Fatalf("Active() = %+v, want the skills %v", active, tt.wantSkills)
+			}
+			for i, skillID := range tt.wantSkills {
+				if active[i].SkillID != skillID {
+					t.Errorf("Active() = %+v, want the skills %v", active, tt.wantSkills)
+				}
+			}
+			if active[0].Level != tt.wantLevel {
+				t.Errorf("level %d, want %d", active[0].Level, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestSlots(t *testing.T) {
+	list := NewList(clock.NewFake(time.Now()), Hooks{})
+	for skillID := 1; skillID <= MAX_BUFFS+1; skillID++ {
+		if err := list.Add(Effect{SkillID: skillID, Level: 1, Kind: BUFF}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := list.Add(Effect{SkillID: 1000, Level: 1, Kind: DEBUFF}); err != nil {
+		t.Fatal(err)
+	}
+
+	active := list.Active()
+	if len(active) != MAX_BUFFS+1 || active[0].SkillID != 2 || active[len(active)-1].SkillID != 1000 {
+		t.Errorf("Active() = %d effects, from the skill %d to %d", len(active), active[0].SkillID, active[len(active)-1].SkillID)
+	}
+}
+
+func TestTicksAndExpiry(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+
+	var ticks []int
+	var changes [][]Active
+	list := NewList(fake, Hooks{
+		OnTick:   func(effect Effect) { ticks = append(ticks, effect.TickHP) },
+		OnChange: func(active []Active) { changes = append(changes, active) },
+	})
+
+	poison := Effect{SkillID: 4035, Level: 1, Kind: DEBUFF, Duration: 10 * time.Second, Tick: 3 * time.Second, TickHP: -7}
+	if err := list.Add(poison); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0][0].Remaining(fake.Now()) != 10*time.Second {
+		t.Fatalf("OnChange(%+v) once added", changes)
+	}
+
+	// Ticks at 3s, 6s and 9s, then expires at 10s; a late update catches up with the ticks missed
+	fake.Advance(2 * time.Second)
+	list.Update(fake.Now())
+	fake.Advance(5 * time.Second)
+	list.Update(fake.Now())
+	if len(ticks) != 2 || len(changes) != 1 {
+		t.Fatalf("%d ticks and %d changes after 7s", len(ticks), len(changes))
+	}
+
+	fake.Advance(3 * time.Second)
+	list.Update(fake.Now())
+	if len(ticks) != 3 || ticks[2] != -7 {
+		t.Errorf("ticks = %v, want 3 of -7", ticks)
+	}
+	if len(changes) != 2 || len(changes[1]) != 0 || len(list.Active()) != 0 {
+		t.Errorf("OnChange(%+v) once expired", changes)
+	}
+}
@@ -0,0 +1,116 @@
+// Package experience holds the experience table deciding the level of the
+// players, read from a data file, along with the experience they lose when dying
+package experience
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrInvalidTable = errors.New("invalid experience table")
+
+//go:embed experience.json
+var bundled []byte
+
+// Level is an entry of the experience table
+type Level struct {
+	Level int    `json:"level"`
+	Exp   uint64 `json:"exp"` // Experience needed to reach the level
+}
+
+// Table gives the level matching an amount of experience.
+// Its last level is the highest one, the experience stops growing there.
+type Table struct {
+	exp []uint64 // Experience needed to reach each level, starting at level 1
+}
+
+// Parse reads an experience table, a JSON array of Level listing every level from 1 on
+func Parse(r io.Reader) (*Table, error) {
+	var levels []Level
+	if err := json.NewDecoder(r).Decode(&levels); err != nil {
+		return nil, err
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("%w: no level", ErrInvalidTable)
+	}
+
+	table := &Table{exp: make([]uint64, 0, len(levels))}
+	for i, level := range levels {
+		if level.Level != i+1 {
+			return nil, fmt.Errorf("%w: level %d listed in place of level %d", ErrInvalidTable, level.Level, i+1)
+		}
+		if i == 0 && level.Exp != 0 {
+			return nil, fmt.Errorf("%w: level 1 needs %d experience instead of none", ErrInvalidTable, level.Exp)
+		}
+		if i > 0 && level.Exp <= table.exp[i-1] {
+			return nil, fmt.Errorf("%w: level %d needs less experience than level %d", ErrInvalidTable, level.Level, i)
+		}
+		table.exp = append(table.exp, level.Exp)
+	}
+
+	return table, nil
+}
+
+// Load reads an experience table from a file
+func Load(path string) (*Table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Default returns the bundled experience table, up to level 40
+func Default() *Table {
+	table, err := Parse(bytes.NewReader(bundled))
+	if err != nil {
+		panic(err)
+	}
+	return table
+}
+
+// MaxLevel returns the highest level of the table
+func (t *Table) MaxLevel() int {
+	return len(t.exp)
+}
+
+// ForLevel returns the experience needed to reach a level, clamped to the levels of the table
+func (t *Table) ForLevel(level int) uint64 {
+	level = min(max(level, 1), t.MaxLevel())
+	return t.exp[level-1]
+}
+
+// LevelOf returns the level reached with an amount of experience
+func (t *Table) LevelOf(exp uint64) int {
+	level := 1
+	for level < t.MaxLevel() && exp >= t.exp[level] {
+		level++
+	}
+	return level
+}
+
+// Cap returns the experience clamped to the one of the highest level
+func (t *Table) Cap(exp uint64) uint64 {
+	return min(exp, t.exp[len(t.exp)-1])
+}
+
+// DeathPenalty returns the experience lost by a player of the given level when dying:
+// the percentage of the experience between its level and the next one
+func (t *Table) DeathPenalty(level int, percent float64) uint64 {
+	if percent <= 0 || t.MaxLevel() < 2 {
+		return 0
+	}
+
+	// The players of the highest level lose a share of the span leading to it
+	level = min(max(level, 1), t.MaxLevel()-1)
+
+	span := t.ForLevel(level+1) - t.ForLevel(level)
+	return uint64(float64(span) * percent / 100)
+}
@@ -0,0 +1,65 @@
+package experience
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	table := Default()
+	if table.MaxLevel() != 40 {
+		t.Fatalf("MaxLevel() = %d, want 40", table.MaxLevel())
+	}
+
+	tests := []struct {
+		exp  uint64
+		want int
+	}{
+		{0, 1},
+		{67, 1},
+		{68, 2},
+		{400, 3},
+		{15422850, 39},
+		{15422851, 40},
+		{1 << 40, 40},
+	}
+	for _, tt := range tests {
+		if level := table.LevelOf(tt.exp); level != tt.want {
+			t.Errorf("LevelOf(%d) = %d, want %d", tt.exp, level, tt.want)
+		}
+	}
+
+	if exp := table.Cap(1 << 40); exp != 15422851 {
+		t.Errorf("Cap() = %d", exp)
+	}
+	if lost := table.DeathPenalty(2, 10); lost != (363-68)/10 {
+		t.Errorf("DeathPenalty(2) = %d, want %d", lost, (363-68)/10)
+	}
+	if lost := table.DeathPenalty(40, 10); lost != table.DeathPenalty(39, 10) {
+		t.Errorf("DeathPenalty(40) = %d, want the one of level 39", lost)
+	}
+	if lost := table.DeathPenalty(20, 0); lost != 0 {
+		t.Errorf("DeathPenalty() = %d without a penalty", lost)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+	}{
+		{"empty", `[]`},
+		{"level skipped", `[{"level": 1, "exp": 0}, {"level": 3, "exp": 68}]`},
+		{"level 1 needs experience", `[{"level": 1, "exp": 5}]`},
+		{"not growing", `[{"level": 1, "exp": 0}, {"level": 2, "exp": 68}, {"level": 3, "exp": 68}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.table)); !errors.Is(err, ErrInvalidTable) {
+				t.Errorf("Parse() error = %v, want %v", err, ErrInvalidTable)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package gameserver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+var (
+	ErrPlayerOffline  = errors.New("the player isn't online")
+	ErrAlreadyFriends = errors.New("the players are already friends")
+	ErrInviteSelf     = errors.New("a player can't befriend itself")
+	ErrNoFriendInvite = errors.New("no friend invite is pending")
+)
+
+// InviteFriend asks a player in the world to join the friend list of another one in the world. The one inviting
+// is told by a system message when the player is offline or already one of its friends.
+func (g *GameServer) InviteFriend(client *models.Client, name string) error {
+	if !g.playing(client) {
+		return ErrNotInWorld
+	}
+	if strings.EqualFold(name, client.Account) {
+		return ErrInviteSelf
+	}
+
+	target, ok := g.playerByName(name)
+	if !ok {
+		g.sendSystemMessage(client, serverpackets.SYSTEM_MESSAGE_NOT_LOGGED_IN, name)
+		return fmt.Errorf("%w: %s", ErrPlayerOffline, name)
+	}
+
+	friends, err := g.friendRepository.List(client.Account)
+	if err != nil {
+		return err
+	}
+	if _, ok := findFriend(friends, target.Account); ok {
+		g.sendSystemMessage(client, serverpackets.SYSTEM_MESSAGE_ALREADY_FRIEND, target.Account)
+		return fmt.Errorf("%w: %s and %s", ErrAlreadyFriends, client.Account, target.Account)
+	}
+
+	g.friendsMutex.Lock()
+	g.friendInvites[target.ObjectID] = client.ObjectID
+	g.friendsMutex.Unlock()
+
+	return target.Send(serverpackets.NewAskJoinFriendPacket(client.Account))
+}
+
+// AnswerFriendInvite accepts or declines the invite a player was asked. Once it is accepted,
+// both players are told and get their new friend list.
+func (g *GameServer) AnswerFriendInvite(client *models.Client, accept bool) error {
+	if !g.playing(client) {
+		return ErrNotInWorld
+	}
+	g.friendsMutex.Lock()
+	inviterID, ok := g.friendInvites[client.ObjectID]
+	delete(g.friendInvites, client.ObjectID)
+	g.friendsMutex.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrNoFriendInvite, client.ObjectID)
+	}
+	if !accept {
+		return nil
+	}
+
+	inviter, ok := g.Player(inviterID)
+	if !ok || !g.inWorld(inviter) {
+		return fmt.Errorf("%w: %d", ErrPlayerOffline, inviterID)
+	}
+
+	if err := g.friendRepository.Add(inviter.Account, client.Account); err != nil {
+		return err
+	}
+
+	g.sendSystemMessage(inviter, serverpackets.SYSTEM_MESSAGE_FRIEND_ADDED, client.Account)
+	g.sendSystemMessage(client, serverpackets.SYSTEM_MESSAGE_FRIEND_ADDED, inviter.Account)
+	g.sendFriendList(inviter)
+	g.sendFriendList(client)
+	return nil
+}
+
+// RemoveFriend ends the friendship of a player with another. The player gets its friend list
+// back whether or not the other was on it, the former friend getting its own if it is online.
+func (g *GameServer) RemoveFriend(client *models.Client, name string) error {
+	if !g.playing(client) {
+		return ErrNotInWorld
+	}
+	friends, err := g.friendRepository.List(client.Account)
+	if err != nil {
+		return err
+	}
+
+	if friend, ok := findFriend(friends, name); ok {
+		if err := g.friendRepository.Remove(client.Account, friend); err != nil {
+			return err
+		}
+
+		g.sendSystemMessage(client, serverpackets.SYSTEM_MESSAGE_FRIEND_REMOVED, friend)
+		if other, ok := g.playerByName(friend); ok {
+			g.sendFriendList(other)
+		}
+	}
+
+	g.sendFriendList(client)
+	return nil
+}
+
+// Friends returns the friend list of a player, telling which of its friends are in the world
+func (g *GameServer) Friends(client *models.Client) ([]serverpackets.Friend, error) {
+	names, err := g.friendRepository.List(client.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	friends := make([]serverpackets.Friend, 0, len(names))
+	for _, name := range names {
+		friend := serverpackets.Friend{Name: name}
+		if player, ok := g.playerByName(name); ok {
+			friend.ObjectID, friend.Online = player.ObjectID, true
+		}
+		friends = append(friends, friend)
+	}
+	return friends, nil
+}
+
+// Whisper sends a private message of a player to another, wherever it is in the world. The one
+// whispering sees its message too, and is told by a system message when the other is offline.
+func (g *GameServer) Whisper(client *models.Client, name, text string) error {
+	if !g.playing(client) {
+		return ErrNotInWorld
+	}
+	target, ok := g.playerByName(name)
+	if !ok {
+		g.sendSystemMessage(client, serverpackets.SYSTEM_MESSAGE_NOT_LOGGED_IN, name)
+		return fmt.Errorf("%w: %s", ErrPlayerOffline, name)
+	}
+
+	// Characters aren't stored yet, the players whisper under the name of their account
+	if err := target.Send(serverpackets.NewCreatureSayPacket(client.ObjectID, serverpackets.CHAT_TELL, client.Account, text)); err != nil {
+		return err
+	}
+	return client.Send(serverpackets.NewCreatureSayPacket(client.ObjectID, serverpackets.CHAT_TELL, "->"+target.Account, text))
+}
+
+// sendFriendList sends a player its friend list
+func (g *GameServer) sendFriendList(client *models.Client) {
+	friends, err := g.Friends(client)
+	if err != nil {
+		fmt.Printf("Couldn't list the friends of %s: %v\n", client.Account, err)
+		return
+	}
+
+	if err := client.Send(serverpackets.NewFriendListPacket(friends)); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// notifyFriends refreshes the friend lists of the online friends of a player entering or leaving
+// the world, the ones it enters telling them it logged in
+func (g *GameServer) notifyFriends(client *models.Client, entered bool) {
+	friends, err := g.friendRepository.List(client.Account)
+	if err != nil {
+		fmt.Printf("Couldn't list the friends of %s: %v\n", client.Account, err)
+		return
+	}
+
+	for _, name := range friends {
+		friend, ok := g.playerByName(name)
+		if !ok {
+			continue
+		}
+		if entered {
+			g.sendSystemMessage(friend, serverpackets.SYSTEM_MESSAGE_FRIEND_LOGGED_IN, client.Account)
+		}
+		g.sendFriendList(friend)
+	}
+}
+
+// forgetFriendInvite drops the invite a leaving player was asked
+func (g *GameServer) forgetFriendInvite(client *models.Client) {
+	g.friendsMutex.Lock()
+	defer g.friendsMutex.Unlock()
+
+	delete(g.friendInvites, client.ObjectID)
+}
+
+// playerByName returns the player in the world going by a name, whatever its case
+func (g *GameServer) playerByName(name string) (*models.Client, bool) {
+	g.clientsMutex.Lock()
+	defer g.clientsMutex.Unlock()
+
+	for _, client := range g.clients {
+		if client.Effects != nil && strings.EqualFold(client.Account, name) {
+			return client, true
+		}
+	}
+	return nil, false
+}
+
+// sendSystemMessage shows a system message to a player
+func (g *GameServer) sendSystemMessage(client *models.Client, messageID uint32, params ...string) {
+	if err := client.Send(serverpackets.NewSystemMessagePacket(messageID, params...)); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// findFriend returns the name a friend goes by on a friend list, whatever the case of the one given
+func findFriend(friends []string, name string) (string, bool) {
+	for _, friend := range friends {
+		if strings.EqualFold(friend, name) {
+			return friend, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,89 @@
+package gameserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/effects"
+	"github.com/frostwind/l2go/gameserver/loop"
+	"github.com/frostwind/l2go/gameserver/models"
+)
+
+// Loop returns the game loop, whose stats show how long each system takes
+func (g *GameServer) Loop() *loop.Loop {
+	return g.loop
+}
+
+// startLoop runs the systems of the world on the game loop until the game server stops.
+// The NPCs only walk and think when their AI is enabled.
+func (g *GameServer) startLoop() {
+	if thinkInterval := g.config.GameServer.Options.ThinkPeriod(); thinkInterval > 0 {
+		g.loop.Add(loop.System{Name: "movement", Run: func(now time.Time, elapsed time.Duration) {
+			g.ai.Advance(elapsed)
+		}})
+		g.loop.Add(loop.System{Name: "ai", Every: thinkInterval, Run: func(now time.Time, elapsed time.Duration) {
+			g.ai.Tick()
+		}})
+	}
+	g.loop.Add(loop.System{Name: "regen", Every: REGEN_INTERVAL, Run: func(now time.Time, elapsed time.Duration) {
+		g.regenerate(now)
+	}})
+	g.night.Store(g.gameTime.IsNight(g.clock.Now()))
+	g.loop.Add(loop.System{Name: "sun", Every: SUN_CHECK_INTERVAL, Run: func(now time.Time, elapsed time.Duration) {
+		g.updateSun(now)
+	}})
+	g.loop.Add(loop.System{Name: "effects", Run: func(now time.Time, elapsed time.Duration) {
+		for _, list := range g.effectLists() {
+			list.Update(now)
+		}
+	}})
+
+	// The characters are written off the loop, which doesn't wait for the database
+	g.loop.Add(loop.System{Name: "persistence", Run: func(now time.Time, elapsed time.Duration) {
+		if !g.saving.CompareAndSwap(false, true) {
+			return
+		}
+		go func() {
+			defer g.saving.Store(false)
+			if err := g.persistence.Tick(now); err != nil {
+				fmt.Printf("Couldn't save the characters: %v\n", err)
+			}
+		}()
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-g.stop
+		cancel()
+	}()
+	go g.loop.Run(ctx)
+}
+
+// effectLists returns the lists of effects of the players in the world
+func (g *GameServer) effectLists() []*effects.List {
+	g.clientsMutex.Lock()
+	defer g.clientsMutex.Unlock()
+
+	lists := make([]*effects.List, 0, len(g.clients))
+	for _, client := range g.clients {
+		if client.Effects != nil {
+			lists = append(lists, client.Effects)
+		}
+	}
+	return lists
+}
+
+// authenticated returns the players in the world, the ones having a list of effects
+func (g *GameServer) authenticated() []*models.Client {
+	g.clientsMutex.Lock()
+	defer g.clientsMutex.Unlock()
+
+	clients := make([]*models.Client, 0, len(g.clients))
+	for _, client := range g.clients {
+		if client.Effects != nil {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
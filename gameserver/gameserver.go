@@ -1,61 +1,191 @@
 package gameserver
 
 import (
-	"bytes"
 	"database/sql"
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/frostwind/l2go/access"
+	"github.com/frostwind/l2go/clock"
 	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/database"
+	"github.com/frostwind/l2go/diagnostics"
+	"github.com/frostwind/l2go/eventbus"
+	"github.com/frostwind/l2go/gameserver/ai"
+	"github.com/frostwind/l2go/gameserver/behavior"
+	"github.com/frostwind/l2go/gameserver/capacity"
 	"github.com/frostwind/l2go/gameserver/clientpackets"
+	"github.com/frostwind/l2go/gameserver/drops"
+	"github.com/frostwind/l2go/gameserver/experience"
+	"github.com/frostwind/l2go/gameserver/gametime"
+	"github.com/frostwind/l2go/gameserver/html"
+	"github.com/frostwind/l2go/gameserver/interest"
+	"github.com/frostwind/l2go/gameserver/linkpackets"
+	"github.com/frostwind/l2go/gameserver/loop"
 	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/movement"
+	"github.com/frostwind/l2go/gameserver/persistence"
+	"github.com/frostwind/l2go/gameserver/quests"
+	"github.com/frostwind/l2go/gameserver/repository"
+	"github.com/frostwind/l2go/gameserver/sendqueue"
 	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/gameserver/shop"
+	"github.com/frostwind/l2go/gameserver/teleport"
+	"github.com/frostwind/l2go/gameserver/vitals"
+	"github.com/frostwind/l2go/gameserver/warehouse"
+	"github.com/frostwind/l2go/names"
+	"github.com/frostwind/l2go/opcodes"
 	"github.com/frostwind/l2go/packets"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/frostwind/l2go/packettiming"
+	"github.com/frostwind/l2go/protocol"
+	"github.com/frostwind/l2go/random"
+	"github.com/frostwind/l2go/templates"
 )
 
 type GameServer struct {
-	clients           []*models.Client
-	database          *sql.DB
-	config            config.GameServerConfigObject
-	status            gameServerStatus
-	clientListener    net.Listener
-	loginServerSocket net.Conn
+	clients             []*models.Client
+	players             map[uint32]*models.Client // The same clients, by object id
+	clientsMutex        sync.Mutex
+	interest            *interest.Grid
+	nextPlayerID        uint32
+	database            *sql.DB
+	config              config.GameServerConfigObject
+	status              gameServerStatus
+	sendCounters        sendqueue.Counters // Shared by the send queues of the clients
+	sendPolicy          sendqueue.Policy
+	handlerTimes        *packettiming.Recorder
+	clientListener      net.Listener
+	adminListener       net.Listener
+	adminServer         *http.Server
+	diagnostics         *diagnostics.Server
+	loginServerSocket   net.Conn
+	linkCompressor      atomic.Pointer[protocol.Compressor] // Set once the login server accepted to compress the link, before the heartbeats start
+	pendingPlayers      *pendingPlayers
+	access              *access.Model
+	names               *names.Validator
+	reservedNames       *names.Reservations
+	characterSlots      *characterSlots
+	dialogs             *html.Dialogs
+	templates           *templates.Set
+	experience          *experience.Table
+	vitals              *vitals.Table
+	capacity            *capacity.Table
+	progressMutex       sync.Mutex
+	drops               *drops.Tables
+	groundItems         map[uint32]*models.GroundItem
+	itemsMutex          sync.Mutex // Guards the ground items and the inventories
+	random              random.Source
+	clock               clock.Clock
+	effectRepository    repository.EffectRepository
+	characterRepository repository.CharacterRepository
+	friendRepository    repository.FriendRepository
+	friendInvites       map[uint32]uint32 // Players asked to join a friend list, to the player who asked
+	friendsMutex        sync.Mutex
+	quests              *quests.Quests
+	questRepository     repository.QuestRepository
+	questsMutex         sync.Mutex // Serializes the changes of the quests of the players
+	shops               *shop.Shops
+	warehouseRepository repository.WarehouseRepository
+	persistence         *persistence.Scheduler
+	saving              atomic.Bool // A batch of characters is being written off the game loop
+	ai                  *ai.Scheduler
+	behavior            *behavior.Analyzer // Scores the players for the bot detection, nil when it is disabled
+	events              *eventbus.Bus
+	loop                *loop.Loop
+	gameTime            *gametime.Clock
+	night               atomic.Bool // The players were last told the sun set
+	npcs                map[uint32]*models.Npc
+	npcsMutex           sync.RWMutex
+	nextObjectID        uint32
+	stop                chan struct{}
+	stopOnce            sync.Once
 }
 
+const (
+	// Object ids given to the players and to the NPCs, they never overlap
+	FIRST_PLAYER_OBJECT_ID = 0x10000000
+	FIRST_NPC_OBJECT_ID    = 0x20000000
+
+	// Characters aren't stored yet, every player enters the world with these amounts
+	STARTING_ADENA = 100000
+	STARTING_HP    = 100
+	STARTING_MP    = 60
+	STARTING_CP    = 80
+
+	// The players regenerate their vitals every REGEN_INTERVAL, at the rates of their class
+	REGEN_INTERVAL = time.Second
+
+	// A kicked client has SEND_FLUSH_TIMEOUT to read the packets still queued
+	SEND_FLUSH_TIMEOUT = time.Second
+)
+
 type gameServerStatus struct {
-	onlinePlayers uint32
-	hackAttempts  uint32
+	onlinePlayers      uint32
+	hackAttempts       uint32
+	reapedPreAuth      uint32
+	reapedPostAuth     uint32
+	movementViolations uint32
+	suspectedBots      uint32
 }
 
-func (g *GameServer) Receive() (opcode byte, data []byte, e error) {
-	// Read the first two bytes to define the packet size
-	header := make([]byte, 2)
-	n, err := g.loginServerSocket.Read(header)
+// Stats is a snapshot of the game server counters
+type Stats struct {
+	OnlinePlayers      uint32 `json:"onlinePlayers"`
+	HackAttempts       uint32 `json:"hackAttempts"`
+	ReapedPreAuth      uint32 `json:"reapedPreAuth"`      // Clients dropped for not authenticating in time
+	ReapedPostAuth     uint32 `json:"reapedPostAuth"`     // Authenticated clients dropped for staying idle
+	MovementViolations uint32 `json:"movementViolations"` // Positions refused for being out of reach of the players
+	SuspectedBots      uint32 `json:"suspectedBots"`      // Players whose behavior looked automated to the bot detection
+	SlowClients        uint64 `json:"slowClients"`        // Clients disconnected for filling their send queue
+	DroppedNormal      uint64 `json:"droppedNormal"`      // Packets of the normal priority shed for slow clients, the movement around them
+	DroppedLow         uint64 `json:"droppedLow"`         // Packets of the low priority shed for slow clients, the chat and the animations around them
+	SendQueueDepth     int    `json:"sendQueueDepth"`     // Packets waiting in the send queues of the online clients
+	MaxSendQueueDepth  int    `json:"maxSendQueueDepth"`  // Deepest the send queue of a client has been, the slow clients disconnected included
+}
 
-	if n != 2 || err != nil {
-		return 0x00, nil, errors.New("An error occured while reading the packet header.")
+// Stats returns the game server counters
+func (g *GameServer) Stats() Stats {
+	return Stats{
+		OnlinePlayers:      atomic.LoadUint32(&g.status.onlinePlayers),
+		HackAttempts:       atomic.LoadUint32(&g.status.hackAttempts),
+		ReapedPreAuth:      atomic.LoadUint32(&g.status.reapedPreAuth),
+		ReapedPostAuth:     atomic.LoadUint32(&g.status.reapedPostAuth),
+		MovementViolations: atomic.LoadUint32(&g.status.movementViolations),
+		SuspectedBots:      atomic.LoadUint32(&g.status.suspectedBots),
+		SlowClients:        g.sendCounters.Overflows.Load(),
+		DroppedNormal:      g.sendCounters.Dropped[sendqueue.NORMAL].Load(),
+		DroppedLow:         g.sendCounters.Dropped[sendqueue.LOW].Load(),
+		SendQueueDepth:     g.sendQueueDepth(),
+		MaxSendQueueDepth:  int(g.sendCounters.MaxDepth.Load()),
 	}
+}
 
-	// Calculate the packet size
-	size := 0
-	size = size + int(header[0])
-	size = size + int(header[1])*256
+func (g *GameServer) Receive() (opcode byte, data []byte, e error) {
+	data, err := packets.ReadFrame(g.loginServerSocket, packets.MaxFrameSize)
 
-	// Allocate the appropriate size for our data (size - 2 bytes used for the length
-	data = make([]byte, size-2)
+	if err != nil {
+		return 0x00, nil, errors.New("An error occured while reading the packet.")
+	}
 
-	// Read the encrypted part of the packet
-	n, err = g.loginServerSocket.Read(data)
+	if compressor := g.linkCompressor.Load(); compressor != nil {
+		data, err = compressor.Decompress(data)
 
-	if n != size-2 || err != nil {
-		return 0x00, nil, errors.New("An error occured while reading the packet data.")
+		if err != nil {
+			return 0x00, nil, err
+		}
 	}
 
-	// Print the raw packet
-	fmt.Printf("Raw packet : %X%X\n", header, data)
+	if len(data) == 0 {
+		return 0x00, nil, errors.New("An error occured while reading the packet.")
+	}
 
 	// Extract the op code
 	opcode = data[0]
@@ -65,15 +195,26 @@ func (g *GameServer) Receive() (opcode byte, data []byte, e error) {
 }
 
 func (g *GameServer) Send(data []byte) error {
-	// Calculate the packet length
-	length := uint16(len(data) + 2)
+	if compressor := g.linkCompressor.Load(); compressor != nil {
+		compressed, err := compressor.Compress(data)
+
+		if err != nil {
+			return err
+		}
+		data = compressed
+	}
 
 	// Put everything together
-	buffer := packets.NewBuffer()
-	buffer.WriteUInt16(length)
-	buffer.Write(data)
+	writer := packets.NewPacketWriter()
+	defer writer.Release()
 
-	_, err := g.loginServerSocket.Write(buffer.Bytes())
+	frame, err := writer.B(data).Finalize()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = g.loginServerSocket.Write(frame)
 
 	if err != nil {
 		return errors.New("The packet couldn't be sent.")
@@ -83,88 +224,281 @@ func (g *GameServer) Send(data []byte) error {
 }
 
 func New(cfg config.GameServerConfigObject) *GameServer {
-	return &GameServer{config: cfg}
+	characters := repository.NewMemoryCharacterRepository()
+	g := &GameServer{
+		config:              cfg,
+		players:             make(map[uint32]*models.Client),
+		interest:            interest.NewGrid(),
+		pendingPlayers:      newPendingPlayers(),
+		access:              access.DefaultModel(),
+		names:               names.NewValidator(),
+		reservedNames:       names.NewReservations(),
+		characterSlots:      newCharacterSlots(),
+		dialogs:             html.NewDialogs(html.NewCache(filepath.Join(cfg.GameServer.Options.DataPath(), "html"))),
+		templates:           templates.Default(),
+		experience:          experience.Default(),
+		vitals:              vitals.Default(),
+		capacity:            capacity.Default(),
+		drops:               &drops.Tables{},
+		groundItems:         make(map[uint32]*models.GroundItem),
+		random:              random.Crypto(),
+		clock:               clock.Real{},
+		effectRepository:    repository.NewMemoryEffectRepository(),
+		characterRepository: characters,
+		warehouseRepository: repository.NewMemoryWarehouseRepository(characters),
+		friendRepository:    repository.NewMemoryFriendRepository(),
+		friendInvites:       make(map[uint32]uint32),
+		quests:              quests.New(),
+		questRepository:     repository.NewMemoryQuestRepository(),
+		shops:               shop.New(),
+		npcs:                make(map[uint32]*models.Npc),
+		nextPlayerID:        FIRST_PLAYER_OBJECT_ID,
+		nextObjectID:        FIRST_NPC_OBJECT_ID,
+		sendPolicy:          sendqueue.Watermarks,
+		events:              eventbus.New(),
+		stop:                make(chan struct{}),
+	}
+	g.handlerTimes = packettiming.New("game server", cfg.GameServer.Options.SlowHandlerThreshold(), func(opcode byte) string {
+		return opcodes.Name(opcodes.Game, opcodes.ClientToServer, opcode)
+	})
+	g.ai = ai.NewScheduler(aiWorld{g}, random.Crypto(), cfg.GameServer.Options.AIShards)
+	g.loop = loop.New(g.clock, cfg.GameServer.Options.TickRate())
+	g.gameTime = g.newGameTime()
+	g.persistence = g.newPersistence()
+	g.behavior = g.newBehavior()
+	if policy, err := sendqueue.Lookup(cfg.GameServer.Options.SendPolicy); err == nil {
+		g.sendPolicy = policy
+	}
+
+	return g
 }
 
 func (g *GameServer) Init() {
 	var err error
 
-	// Connect to MySQL database
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-		g.config.GameServer.Database.User,
-		g.config.GameServer.Database.Password,
-		g.config.GameServer.Database.Host,
-		g.config.GameServer.Database.Port,
-		g.config.GameServer.Database.Name)
+	if g.config.GameServer.Database.IsMemory() {
+		fmt.Println("Using the in-memory storage")
+	} else {
+		// Connect to MySQL database, which may still be starting
+		g.database, err = database.Open(g.config.GameServer.Database)
+		if err != nil {
+			panic("Couldn't connect to the database server: " + err.Error())
+		}
 
-	g.database, err = sql.Open("mysql", dsn)
-	if err != nil {
-		panic("Couldn't connect to the database server: " + err.Error())
+		fmt.Println("Successfully connected to the MySQL database server")
+		g.effectRepository = repository.NewMySQLEffectRepository(g.database)
+		g.characterRepository = repository.NewMySQLCharacterRepository(g.database)
+		g.friendRepository = repository.NewMySQLFriendRepository(g.database)
+		g.questRepository = repository.NewMySQLQuestRepository(g.database)
+		g.warehouseRepository = repository.NewMySQLWarehouseRepository(g.database)
+		g.persistence = g.newPersistence()
 	}
 
-	// Test the connection
-	err = g.database.Ping()
+	if g.config.GameServer.Options.NameBlocklist != "" {
+		err = g.names.LoadBlocklist(g.config.GameServer.Options.NameBlocklist)
+		if err != nil {
+			panic("Couldn't load the name blocklist: " + err.Error())
+		}
+	}
+
+	g.access, err = access.NewModel(g.config.LoginServer.AccessTiers)
 	if err != nil {
-		panic("Couldn't ping the database server: " + err.Error())
+		panic("Couldn't load the access tiers: " + err.Error())
+	}
+
+	if _, err := sendqueue.Lookup(g.config.GameServer.Options.SendPolicy); err != nil {
+		panic("Couldn't set up the send queues: " + err.Error())
+	}
+
+	if err := g.config.GameServer.CheckNullCrypto(); err != nil {
+		panic("Couldn't disable the encryption: " + err.Error())
+	}
+	if g.config.GameServer.Options.NullCrypto {
+		fmt.Println("WARNING: the Game Server sends its packets in clear, for local testing only")
+	}
+
+	if err := g.checkBotAction(); err != nil {
+		panic("Couldn't set up the bot detection: " + err.Error())
 	}
 
-	fmt.Println("Successfully connected to the MySQL database server")
+	// Load the world: the gatekeepers destinations and the NPCs
+	err = g.loadWorld()
+	if err != nil {
+		panic("Couldn't load the game data: " + err.Error())
+	}
 
 	// Connect to the login server
-	g.loginServerSocket, err = net.Dial("tcp", g.config.LoginServer.Host+":9413")
+	loginServerAddress := g.config.LoginServer.GameServersDialAddress()
+	g.loginServerSocket, err = net.Dial("tcp", loginServerAddress)
 	if err != nil {
 		fmt.Println("Couldn't connect to the Login Server")
 	} else {
-		fmt.Printf("Successfully connected to the Login Server at %s:9413\n", g.config.LoginServer.Host)
+		fmt.Printf("Successfully connected to the Login Server at %s\n", loginServerAddress)
 	}
 
 	// Listen for client connections
-	g.clientListener, err = net.Listen("tcp", ":"+strconv.Itoa(g.config.GameServer.Port))
+	g.clientListener, err = net.Listen("tcp", g.config.GameServer.ClientsAddress())
 	if err != nil {
 		fmt.Println("Couldn't initialize the Game Server")
 	} else {
-		fmt.Printf("Game Server listening on port %s\n", strconv.Itoa(g.config.GameServer.Port))
+		fmt.Printf("Game Server listening on %s\n", g.clientListener.Addr())
+	}
+
+	// Listen for the admin API, if enabled
+	if g.config.GameServer.Options.AdminAddress != "" {
+		g.adminListener, err = net.Listen("tcp", g.config.GameServer.Options.AdminAddress)
+		if err != nil {
+			fmt.Println("Couldn't initialize the Game Server (Admin listener)")
+		} else {
+			g.adminServer = &http.Server{Handler: g.AdminHandler()}
+			fmt.Printf("Game Server listening for admin requests on %s\n", g.adminListener.Addr())
+		}
+	}
+
+	// Serve the diagnostics, if enabled
+	g.diagnostics, err = diagnostics.Start("gameserver", g.config.GameServer.Options.Diagnostics)
+	if err != nil {
+		fmt.Printf("Couldn't initialize the Game Server (Diagnostics listener): %v\n", err)
+	} else if g.diagnostics != nil {
+		diagnostics.Publish("gameserver", func() any { return g.Stats() })
+		diagnostics.Publish("gameloop", func() any { return g.loop.Stats() })
+		diagnostics.Publish("gameserver.handlers", func() any { return g.HandlerStats() })
+		fmt.Printf("Game Server serving its diagnostics on %s\n", g.diagnostics.Addr())
+	}
+}
+
+// DiagnosticsAddr returns the address the diagnostics are served on, or nil if they are disabled
+func (g *GameServer) DiagnosticsAddr() net.Addr {
+	if g.diagnostics == nil {
+		return nil
+	}
+	return g.diagnostics.Addr()
+}
+
+// AdminAddr returns the address of the admin API listener, or nil if it isn't listening
+func (g *GameServer) AdminAddr() net.Addr {
+	if g.adminListener == nil {
+		return nil
 	}
+	return g.adminListener.Addr()
+}
+
+// Addr returns the address of the clients listener, or nil if it isn't listening
+func (g *GameServer) Addr() net.Addr {
+	if g.clientListener == nil {
+		return nil
+	}
+	return g.clientListener.Addr()
 }
 
 func (g *GameServer) Start() {
-	defer g.database.Close()
+	if g.database != nil {
+		defer g.database.Close()
+	}
 	defer g.clientListener.Close()
 
-	done := make(chan bool)
+	done := make(chan bool, 2)
 
-	go func() {
-		g.Send([]byte{00, 01, 02})
+	if g.config.GameServer.Options.NameBlocklist != "" {
+		go g.names.WatchBlocklist(g.config.GameServer.Options.NameBlocklist, config.DEFAULT_NAME_BLOCKLIST_RELOAD_INTERVAL, g.stop, func(err error) {
+			fmt.Printf("Couldn't reload the name blocklist: %v\n", err)
+		})
+	}
 
-		for {
-			opcode, _, err := g.Receive()
+	g.startLoop()
+
+	if g.adminServer != nil {
+		go g.adminServer.Serve(g.adminListener)
+	}
+
+	if g.loginServerSocket != nil {
+		go func() {
+			err := g.Send(linkpackets.NewRegisterGameServerPacket(g.config.GameServer.Name, g.config.GameServer.Secret, g.linkCompression()))
 
 			if err != nil {
 				fmt.Println(err)
-				fmt.Println("Closing the connection...")
-				break
 			}
 
-			switch opcode {
-			case 00:
-				fmt.Println("A game server sent a request to register")
-			default:
-				fmt.Println("Can't recognize the packet sent by the gameserver")
+			for {
+				opcode, data, err := g.Receive()
+
+				if err != nil {
+					fmt.Println(err)
+					fmt.Println("Closing the connection...")
+					break
+				}
+
+				switch opcode {
+				case opcodes.LinkLoginServerRegisterResult:
+					registerResult, err := linkpackets.NewRegisterResult(data)
+
+					if err != nil {
+						fmt.Println(err)
+					} else if registerResult.Accepted {
+						fmt.Printf("Registered on the Login Server with the id %d\n", registerResult.ServerID)
+
+						// Both ends compress the packets following the result, the heartbeats included
+						if compression := protocol.NegotiateCompression(g.linkCompression(), registerResult.Compression); compression.Enabled {
+							g.linkCompressor.Store(protocol.NewCompressor(compression))
+						}
+						go g.sendHeartbeats()
+					} else {
+						fmt.Println("The Login Server refused to register the Game Server")
+					}
+				case opcodes.LinkLoginServerPlayerAuth:
+					playerAuth, err := linkpackets.NewPlayerAuth(data)
+
+					if err != nil {
+						fmt.Println(err)
+					} else {
+						g.pendingPlayers.Add(playerAuth.Account, playerAuth.LoginKey, playerAuth.PlayKey, playerAuth.AccessLevel, time.Now())
+					}
+				default:
+					fmt.Println("Can't recognize the packet sent by the login server")
+				}
 			}
-		}
-		done <- true
-	}()
+			done <- true
+		}()
+	}
 
 	go func() {
 		for {
 			var err error
 			client := models.NewClient()
+			client.MaxPacketSize = g.config.GameServer.Options.PacketSizeLimit()
+			client.IdleTimeout = g.config.GameServer.Options.ClientPreAuthTimeout()
+			client.Plain = g.config.GameServer.Options.NullCrypto
 			client.Socket, err = g.clientListener.Accept()
-			g.clients = append(g.clients, client)
+			if errors.Is(err, net.ErrClosed) {
+				done <- true
+				return
+			}
 			if err != nil {
 				fmt.Println("Couldn't accept the incoming connection.")
 				continue
 			} else {
+				if capacity := g.config.GameServer.Options.SendQueueCapacity(); capacity > 0 {
+					client.StartSendQueue(capacity, g.sendPolicy, g.config.GameServer.Options.DropBroadcasts, &g.sendCounters)
+				}
+
+				g.clientsMutex.Lock()
+				client.ObjectID = g.nextPlayerID
+				client.Adena = STARTING_ADENA
+				client.Level = 1
+				client.HP, client.MaxHP = STARTING_HP, STARTING_HP
+				client.MP, client.MaxMP = STARTING_MP, STARTING_MP
+				client.CP, client.MaxCP = STARTING_CP, STARTING_CP
+				client.Vitals = vitals.NewTracker(g.clock, g.vitals.For(client.ClassID), currentVitals(client))
+				client.Movement = movement.NewTracker(g.movementLimits())
+				if g.behavior != nil {
+					client.Behavior = g.behavior.NewTracker()
+				}
+				g.nextPlayerID += 1
+				g.clients = append(g.clients, client)
+				g.players[client.ObjectID] = client
+				g.clientsMutex.Unlock()
+
+				atomic.AddUint32(&g.status.onlinePlayers, 1)
 				go g.handleClientPackets(client)
 			}
 		}
@@ -175,17 +509,233 @@ func (g *GameServer) Start() {
 	}
 }
 
+// Stop saves the changed characters, then closes the listener and the login server link, which makes Start return
+func (g *GameServer) Stop() {
+	g.stopOnce.Do(func() {
+		close(g.stop)
+		g.saveAll()
+	})
+	g.clientListener.Close()
+	if g.loginServerSocket != nil {
+		g.loginServerSocket.Close()
+	}
+	if g.adminServer != nil {
+		g.adminServer.Close()
+	}
+	if g.diagnostics != nil {
+		g.diagnostics.Close()
+	}
+}
+
+// sendHeartbeats tells the login server the game server is alive until it stops
+func (g *GameServer) sendHeartbeats() {
+	ticker := time.NewTicker(g.config.LoginServer.HeartbeatPeriod())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+			onlinePlayers := atomic.LoadUint32(&g.status.onlinePlayers)
+
+			if err := g.Send(linkpackets.NewHeartbeatPacket(uint16(onlinePlayers))); err != nil {
+				fmt.Println(err)
+				return
+			}
+		}
+	}
+}
+
+// SpawnNpc places an NPC in the world, giving it an object id if it has none
+func (g *GameServer) SpawnNpc(npc *models.Npc) uint32 {
+	g.npcsMutex.Lock()
+	if npc.ObjectID == 0 {
+		npc.ObjectID = g.nextObjectID
+		g.nextObjectID += 1
+	}
+	g.npcs[npc.ObjectID] = npc
+	g.npcsMutex.Unlock()
+
+	g.publish(g.interest.Add(interest.Object{ObjectID: npc.ObjectID, Kind: interest.NPC, X: npc.X, Y: npc.Y, Z: npc.Z}), nil)
+	return npc.ObjectID
+}
+
+// Npc returns the NPC spawned with the given object id
+func (g *GameServer) Npc(objectID uint32) (*models.Npc, bool) {
+	g.npcsMutex.RLock()
+	defer g.npcsMutex.RUnlock()
+
+	npc, ok := g.npcs[objectID]
+	return npc, ok
+}
+
+// RegisterDialogHandler sets the handler answering the players talking to an NPC type
+func (g *GameServer) RegisterDialogHandler(npcType string, handler html.Handler) error {
+	return g.dialogs.Register(npcType, handler)
+}
+
+// loadWorld reads the character templates, the experience table, the regeneration rates, the capacities, the drop tables,
+// the teleport lists, the buy lists, the dialog scripts, the quests and the spawns from the data directory, when present
+func (g *GameServer) loadWorld() error {
+	dataPath := g.config.GameServer.Options.DataPath()
+
+	if err := g.loadTemplates(dataPath); err != nil {
+		return err
+	}
+
+	table, err := experience.Load(filepath.Join(dataPath, "experience.json"))
+	if err == nil {
+		g.experience = table
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to load experience.json: %w", err)
+	}
+
+	rates, err := vitals.Load(filepath.Join(dataPath, "regeneration.json"))
+	if err == nil {
+		g.vitals = rates
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to load regeneration.json: %w", err)
+	}
+
+	capacities, err := capacity.Load(filepath.Join(dataPath, "capacity.json"))
+	if err == nil {
+		g.capacity = capacities
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to load capacity.json: %w", err)
+	}
+
+	dropTables, err := drops.Load(filepath.Join(dataPath, "drops.json"))
+	if err == nil {
+		g.drops = dropTables
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to load drops.json: %w", err)
+	}
+
+	teleports, err := teleport.Load(filepath.Join(dataPath, "teleports.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		teleports, err = &teleport.Teleports{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = g.RegisterDialogHandler(teleport.NPC_TYPE, &teleport.Handler{Teleports: teleports, Teleport: g.travel})
+	if err != nil {
+		return err
+	}
+
+	shops, err := shop.Load(filepath.Join(dataPath, "shops.json"))
+	if err == nil {
+		g.shops = shops
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to load shops.json: %w", err)
+	}
+
+	err = g.RegisterDialogHandler(shop.NPC_TYPE, &shop.Handler{Shops: g.shops, Inventory: g.inventory})
+	if err != nil {
+		return err
+	}
+
+	err = g.RegisterDialogHandler(warehouse.NPC_TYPE, &warehouse.Handler{Inventory: g.inventory, Stored: g.Stored, PageSize: g.config.GameServer.Options.ListPacketSize})
+	if err != nil {
+		return err
+	}
+
+	scripts, err := g.dialogs.LoadScripts(filepath.Join(dataPath, "scripts"))
+	if err != nil {
+		return fmt.Errorf("failed to load the dialog scripts: %w", err)
+	}
+	if scripts > 0 {
+		fmt.Printf("Loaded %d dialog scripts\n", scripts)
+	}
+
+	worldQuests, err := quests.Load(filepath.Join(dataPath, "quests.json"))
+	if err == nil {
+		g.quests = worldQuests
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to load quests.json: %w", err)
+	}
+
+	spawns, err := loadSpawns(filepath.Join(dataPath, "spawns.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, s := range spawns {
+		if s.AI != nil {
+			g.SpawnNpcWithAI(s.npc(), *s.AI)
+		} else {
+			g.SpawnNpc(s.npc())
+		}
+	}
+	fmt.Printf("Spawned %d NPCs\n", len(spawns))
+
+	return nil
+}
+
+// loadTemplates reads the character templates of the data directory, in JSON or CSV,
+// falling back on the templates bundled for the chronicle of the configuration
+func (g *GameServer) loadTemplates(dataPath string) error {
+	for _, name := range []string{"templates.json", "templates.csv"} {
+		set, err := templates.Load(filepath.Join(dataPath, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", name, err)
+		}
+
+		g.templates = set
+		fmt.Printf("Loaded %d character templates from %s\n", len(set.Templates), name)
+		return nil
+	}
+
+	chronicle := g.config.GameServer.Options.Chronicle
+	if chronicle == "" {
+		chronicle = templates.DefaultChronicle
+	}
+
+	set, err := templates.Bundled(chronicle)
+	if err != nil {
+		return err
+	}
+	g.templates = set
+	return nil
+}
+
+// countReaped records a connection dropped for staying silent too long
+func (g *GameServer) countReaped(authenticated bool) {
+	if authenticated {
+		atomic.AddUint32(&g.status.reapedPostAuth, 1)
+	} else {
+		atomic.AddUint32(&g.status.reapedPreAuth, 1)
+	}
+	fmt.Println("The client stayed idle for too long")
+}
+
 func (g *GameServer) kickClient(client *models.Client) {
+	if err := client.CloseSend(SEND_FLUSH_TIMEOUT); err != nil {
+		fmt.Printf("Couldn't send the last packets of the client: %v\n", err)
+	}
 	client.Socket.Close()
+	g.leaveGame(client)
+	atomic.AddUint32(&g.status.onlinePlayers, ^uint32(0))
 
+	g.clientsMutex.Lock()
 	for i, item := range g.clients {
-		if bytes.Equal(item.SessionID, client.SessionID) {
+		if item == client {
 			copy(g.clients[i:], g.clients[i+1:])
 			g.clients[len(g.clients)-1] = nil
 			g.clients = g.clients[:len(g.clients)-1]
 			break
 		}
 	}
+	delete(g.players, client.ObjectID)
+	g.clientsMutex.Unlock()
 
 	fmt.Println("The client has been successfully kicked from the server.")
 }
@@ -196,7 +746,10 @@ func (g *GameServer) handleClientPackets(client *models.Client) {
 
 	// Client protocol version
 	_, data, err := client.Receive(false)
-	protocolVersion := clientpackets.NewProtocolVersion(data)
+
+	if errors.Is(err, packets.ErrIdleTimeout) {
+		g.countReaped(false)
+	}
 
 	if err != nil {
 		fmt.Println(err)
@@ -204,14 +757,23 @@ func (g *GameServer) handleClientPackets(client *models.Client) {
 		return
 	}
 
-	if protocolVersion.Version < 419 {
-		fmt.Printf("Wrong protocol version ! <Expected 419> <Got: %d>\n", protocolVersion.Version)
+	protocolVersion, err := clientpackets.NewProtocolVersion(data)
+
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if protocolVersion.Version < opcodes.GameProtocolRevision {
+		fmt.Printf("Wrong protocol version ! <Expected %d> <Got: %d>\n", opcodes.GameProtocolRevision, protocolVersion.Version)
 		return
 	}
 
 	fmt.Println("Sending the Xor Key to the client...")
 
-	buffer := serverpackets.NewCryptInitPacket()
+	// The packets following CryptInit are compressed if both ends offered it
+	compression := protocol.NegotiateCompression(g.clientCompression(client), protocol.ParseCompressionTrailer(data[4:]))
+	buffer := serverpackets.NewCryptInitPacket(client.Plain, compression)
 	err = client.Send(buffer, false)
 
 	if err != nil {
@@ -221,59 +783,531 @@ func (g *GameServer) handleClientPackets(client *models.Client) {
 		fmt.Println("CryptInit packet sent.")
 	}
 
+	if compression.Enabled {
+		client.EnableCompression(compression)
+	}
+
+	// The handlers ending the connection are timed too
+	var handling packettiming.Timer
+	defer func() { handling.Stop() }()
+
 	for {
 		opcode, data, err := client.Receive()
 
+		if errors.Is(err, packets.ErrFrameTooLarge) {
+			atomic.AddUint32(&g.status.hackAttempts, 1)
+		}
+
+		if errors.Is(err, packets.ErrIdleTimeout) {
+			g.countReaped(client.Account != "")
+		}
+
 		if err != nil {
 			fmt.Println(err)
 			fmt.Println("Closing the connection...")
 			break
 		}
 
+		// The connections yet to authenticate can only log in, anything else they send is an attempt at
+		// playing without an account, their starting adena and target having no character to be kept with
+		if opcode != opcodes.GameClientAuthLogin && client.Account == "" {
+			fmt.Printf("The client sent %s before authenticating\n", opcodes.Name(opcodes.Game, opcodes.ClientToServer, opcode))
+			atomic.AddUint32(&g.status.hackAttempts, 1)
+
+			// The character creation waits for its answer, without claiming a slot or a name of the empty account
+			if opcode == opcodes.GameClientCharacterCreate {
+				err := client.Send(serverpackets.NewCharCreateFailPacket(serverpackets.REASON_CREATION_FAILED))
+				if err != nil {
+					fmt.Println(err)
+				}
+			}
+			continue
+		}
+
+		// The move of the player, for the bot detection
+		var move *behavior.Move
+
+		handling = g.handlerTimes.Start(opcode)
 		switch opcode {
-		case 0x08:
+		case opcodes.GameClientAuthLogin:
 			fmt.Println("Client is requesting login to the Game Server")
 
-			buffer := serverpackets.NewCharListPacket()
-			err := client.Send(buffer)
+			authLogin, err := clientpackets.NewAuthLogin(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				return
+			}
+
+			// Only the tokens minted by the login server for this game server are accepted
+			accessLevel, ok := g.pendingPlayers.Consume(authLogin.Account, authLogin.LoginKey, authLogin.PlayKey, time.Now())
+			if !ok {
+				fmt.Printf("The client sent a wrong session key for the account %s\n", authLogin.Account)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				return
+			}
+
+			// The snapshot lists the accounts of the clients
+			g.clientsMutex.Lock()
+			client.SetAccount(authLogin.Account)
+			g.clientsMutex.Unlock()
+			client.AccessLevel = accessLevel
+
+			client.IdleTimeout = g.config.GameServer.Options.ClientIdleTimeout()
+			g.loadCharacter(client)
+			if character, ok := g.characterSlots.First(client.Account); ok {
+				g.setClass(client, int(character.ClassID))
+			}
+			g.startEffects(client)
+			g.enterWorld(client)
+			g.sendFriendList(client)
+			g.notifyFriends(client, true)
+			g.sendQuestList(client)
+			g.sendItemList(client)
+			g.sendGameTime(client)
+
+			buffer := g.charListPacket(client)
+			err = client.Send(buffer)
 
 			if err != nil {
 				fmt.Println(err)
 			}
 
-		case 0x0e:
+		case opcodes.GameClientRequestNewCharacter:
 			fmt.Println("Client is requesting character creation template")
 
-			buffer := serverpackets.NewCharTemplatePacket()
+			buffer := serverpackets.NewCharTemplatePacket(g.templates.Templates)
 			err := client.Send(buffer)
 
 			if err != nil {
 				fmt.Println(err)
 			}
 
-		case 0x0b:
+		case opcodes.GameClientCharacterCreate:
 			character := clientpackets.NewCharacterCreate(data)
 
-			fmt.Printf("Created a new character : %s\n", character.Name)
+			slots := g.config.GameServer.Options.CharacterSlots()
+			if g.characterSlots.Used(client.Account) >= slots {
+				fmt.Printf("Refused the character %q: %s has no slot left out of %d\n", character.Name, client.Account, slots)
+
+				err := client.Send(serverpackets.NewCharCreateFailPacket(serverpackets.REASON_TOO_MANY_CHARACTERS))
+				if err != nil {
+					fmt.Println(err)
+				}
+				break
+			}
+
+			template, err := g.templates.Find(int(character.Race), int(character.ClassID))
+			if err != nil {
+				fmt.Printf("Refused the character %q: %v\n", character.Name, err)
+
+				err = client.Send(serverpackets.NewCharCreateFailPacket(serverpackets.REASON_CREATION_FAILED))
+				if err != nil {
+					fmt.Println(err)
+				}
+				break
+			}
+
+			if err := g.names.Validate(character.Name); err != nil {
+				fmt.Printf("Refused the character name %q: %v\n", character.Name, err)
+
+				err = client.Send(serverpackets.NewCharCreateFailPacket(serverpackets.REASON_16_ENG_CHARS))
+				if err != nil {
+					fmt.Println(err)
+				}
+				break
+			}
+
+			if err := g.reservedNames.Reserve(character.Name); err != nil {
+				fmt.Println(err)
+
+				err = client.Send(serverpackets.NewCharCreateFailPacket(serverpackets.REASON_NAME_ALREADY_EXISTS))
+				if err != nil {
+					fmt.Println(err)
+				}
+				break
+			}
+
+			// Another creation of the account may have taken the last slot meanwhile
+			if !g.characterSlots.Claim(client.Account, character, slots) {
+				g.reservedNames.Release(character.Name)
+
+				err = client.Send(serverpackets.NewCharCreateFailPacket(serverpackets.REASON_TOO_MANY_CHARACTERS))
+				if err != nil {
+					fmt.Println(err)
+				}
+				break
+			}
+
+			// The player regenerates as the first character of its account
+			if first, ok := g.characterSlots.First(client.Account); ok && first.Name == character.Name {
+				g.setClass(client, int(character.ClassID))
+			}
+
+			// Characters aren't stored yet, the template only decides what they start with
+			spawn := template.Spawns[0]
+			fmt.Printf("Created a new character : %s, %s starting at %d, %d, %d with %d items\n", character.Name, template.Name, spawn.X, spawn.Y, spawn.Z, len(template.Items))
 
 			// ACK
 			buffer := serverpackets.NewCharCreateOkPacket()
-			err := client.Send(buffer)
+			err = client.Send(buffer)
 
 			if err != nil {
 				fmt.Println(err)
 			}
 
 			// Return to the character select screen
-			buffer = serverpackets.NewCharListPacket()
+			buffer = g.charListPacket(client)
 			err = client.Send(buffer)
 
 			if err != nil {
 				fmt.Println(err)
 			}
 
+		case opcodes.GameClientAction:
+			action, err := clientpackets.NewAction(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			// The clicks select what the player talks to, which only the players in the world do
+			if !g.playing(client) {
+				fmt.Println("The client clicked on an object outside of the world")
+				break
+			}
+
+			// Clicking an item on the ground picks it up
+			if _, ok := g.GroundItem(action.ObjectID); ok {
+				if err := g.PickUp(client, action.ObjectID); err != nil {
+					fmt.Println(err)
+				}
+				break
+			}
+
+			npc, ok := g.Npc(action.ObjectID)
+			if !ok {
+				fmt.Printf("The client clicked on an unknown object: %d\n", action.ObjectID)
+				break
+			}
+
+			// The first click selects the NPC, the next ones talk to it
+			if client.TargetID != npc.ObjectID {
+				client.TargetID = npc.ObjectID
+				err = client.Send(serverpackets.NewMyTargetSelectedPacket(npc.ObjectID))
+			} else {
+				err = g.dialogs.Talk(npc, client)
+			}
+
+			if err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientRequestBypassToServer:
+			request, err := clientpackets.NewRequestBypassToServer(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			bypass, err := html.ParseBypass(request.Command)
+			if err != nil {
+				fmt.Println(err)
+				break
+			}
+
+			// Only the selected NPC can be talked to
+			npc, ok := g.Npc(bypass.ObjectID)
+			if !ok || client.TargetID != bypass.ObjectID {
+				fmt.Printf("The client sent a bypass to an NPC it didn't select: %s\n", request.Command)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			// Any NPC can be asked about a quest, the quest tells whether it has something to say
+			if bypass.Command == "Quest" {
+				questID, _ := strconv.Atoi(bypass.Arg(0))
+				err = g.TalkQuest(client, npc, questID)
+			} else {
+				err = g.dialogs.Bypass(npc, client, bypass)
+			}
+			if err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientSendBypassBuildCmd:
+			request, err := clientpackets.NewSendBypassBuildCmd(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			if !g.inWorld(client) {
+				fmt.Println("The client sent an admin command outside of the world")
+				break
+			}
+
+			if err := g.AdminCommand(client, request.Command); err != nil {
+				fmt.Printf("Player %d: //%s: %v\n", client.ObjectID, request.Command, err)
+			}
+
+		case opcodes.GameClientSay2:
+			message, err := clientpackets.NewSay2(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			// The players only speak in the world, under the name of their account
+			if !g.playing(client) {
+				fmt.Println("The client chatted outside of the world")
+				break
+			}
+
+			g.say(client, message)
+
+		case opcodes.GameClientRequestActionUse:
+			request, err := clientpackets.NewRequestActionUse(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			// The players only sit down in the world
+			if !g.inWorld(client) {
+				fmt.Println("The client used an action outside of the world")
+				break
+			}
+
+			if err := g.UseAction(client, int(request.ActionID)); err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientRequestSocialAction:
+			request, err := clientpackets.NewRequestSocialAction(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			if !g.inWorld(client) {
+				fmt.Println("The client sent a social action outside of the world")
+				break
+			}
+
+			// The actions of the server, such as the level up glow, are never asked by the clients
+			if err := g.SocialAction(client, int(request.ActionID)); errors.Is(err, ErrInvalidSocialAction) {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+			} else if err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientMoveBackwardToLocation:
+			request, err := clientpackets.NewMoveBackwardToLocation(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			// The players only move in the world
+			if !g.inWorld(client) {
+				fmt.Println("The client tried to move outside of the world")
+				break
+			}
+
+			origin := movement.Position{X: request.OriginX, Y: request.OriginY, Z: request.OriginZ}
+			g.MoveTo(client, origin, movement.Position{X: request.TargetX, Y: request.TargetY, Z: request.TargetZ})
+			move = &behavior.Move{DX: request.TargetX - request.OriginX, DY: request.TargetY - request.OriginY, DZ: request.TargetZ - request.OriginZ}
+
+		case opcodes.GameClientValidatePosition:
+			position, err := clientpackets.NewValidatePosition(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			if !g.inWorld(client) {
+				fmt.Println("The client reported its position outside of the world")
+				break
+			}
+
+			g.ValidatePosition(client, movement.Position{X: position.X, Y: position.Y, Z: position.Z})
+
+		case opcodes.GameClientRequestFriendInvite:
+			invite, err := clientpackets.NewRequestFriendInvite(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			if err := g.InviteFriend(client, invite.Name); err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientRequestAnswerFriend:
+			answer, err := clientpackets.NewRequestAnswerFriendInvite(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			if err := g.AnswerFriendInvite(client, answer.Response == 1); err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientRequestFriendList:
+			g.sendFriendList(client)
+
+		case opcodes.GameClientRequestFriendDel:
+			request, err := clientpackets.NewRequestFriendDel(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			if err := g.RemoveFriend(client, request.Name); err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientRequestQuestList:
+			g.sendQuestList(client)
+
+		case opcodes.GameClientRequestQuestAbort:
+			request, err := clientpackets.NewRequestQuestAbort(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			if err := g.AbortQuest(client, int(request.QuestID)); err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientRequestBuyItem:
+			request, err := clientpackets.NewRequestBuyItem(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			if err := g.BuyItems(client, int(request.ListID), orders(request.Items)); err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientRequestSellItem:
+			request, err := clientpackets.NewRequestSellItem(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			if err := g.SellItems(client, int(request.ListID), orders(request.Items)); err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientSendWarehouseDeposit:
+			request, err := clientpackets.NewSendWarehouseDeposit(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			clan := request.Kind == serverpackets.WAREHOUSE_CLAN
+			if err := g.Deposit(client, clan, warehouseItems(request.Items)); err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientSendWarehouseWithdraw:
+			request, err := clientpackets.NewSendWarehouseWithdraw(data)
+
+			if err != nil {
+				fmt.Println(err)
+				atomic.AddUint32(&g.status.hackAttempts, 1)
+				break
+			}
+
+			clan := request.Kind == serverpackets.WAREHOUSE_CLAN
+			if err := g.Withdraw(client, clan, warehouseItems(request.Items)); err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientRequestTargetCanceld:
+			client.TargetID = 0
+
+			x, y, z := client.Position()
+			err := client.Send(serverpackets.NewTargetUnselectedPacket(client.ObjectID, x, y, z))
+			if err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientLogout:
+			fmt.Println("The client is logging out")
+
+			// The character is saved before the client learns it can leave
+			g.leaveGame(client)
+
+			err := client.Send(serverpackets.NewLogoutOkPacket())
+			if err != nil {
+				fmt.Println(err)
+			}
+			return
+
+		case opcodes.GameClientRequestRestart:
+			fmt.Println("The client is going back to the character selection")
+
+			if err := g.Restart(client); err != nil {
+				fmt.Println(err)
+			}
+
+		case opcodes.GameClientExtended:
+			if len(data) < 2 {
+				fmt.Println("Received an extended packet without sub-opcode")
+				break
+			}
+
+			subOpcode := uint16(data[0]) | uint16(data[1])<<8
+			fmt.Printf("Couldn't detect the extended packet type: %s\n", opcodes.ExtendedName(opcodes.ClientToServer, subOpcode))
+
 		default:
-			fmt.Println("Couldn't detect the packet type.")
+			fmt.Printf("Couldn't detect the packet type: %s\n", opcodes.Name(opcodes.Game, opcodes.ClientToServer, opcode))
+		}
+		handling.Stop()
+
+		if g.observe(client, opcode, move) {
+			fmt.Println("Kicking the client for looking like a bot")
+			return
 		}
 	}
 
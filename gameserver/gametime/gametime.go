@@ -0,0 +1,51 @@
+// Package gametime keeps the time of the world, which runs faster than the real
+// one: at the retail speed a day lasts four real hours, the night going from
+// midnight until the sun rises at 6 in the morning
+package gametime
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	MINUTES_PER_HOUR = 60
+	MINUTES_PER_DAY  = 24 * MINUTES_PER_HOUR
+
+	// The sun sets at midnight and rises at SUNRISE_HOUR
+	SUNRISE_HOUR = 6
+)
+
+// Clock converts the real time to the time of the world
+type Clock struct {
+	epoch time.Time // The world was at midnight of its first day
+	speed float64   // Minutes of the world per real minute
+}
+
+// New returns the clock of a world which was at midnight of its first day at the epoch, then
+// ran speed times faster than the real time
+func New(epoch time.Time, speed float64) *Clock {
+	return &Clock{epoch: epoch, speed: speed}
+}
+
+// Speed returns how many times faster than the real time the world runs
+func (c *Clock) Speed() float64 {
+	return c.speed
+}
+
+// Minutes returns the minutes of the world elapsed since the epoch
+func (c *Clock) Minutes(now time.Time) int64 {
+	return int64(math.Floor(now.Sub(c.epoch).Minutes() * c.speed))
+}
+
+// TimeOfDay returns the hour and the minute of the world
+func (c *Clock) TimeOfDay(now time.Time) (hour, minute int) {
+	minutes := int((c.Minutes(now)%MINUTES_PER_DAY + MINUTES_PER_DAY) % MINUTES_PER_DAY)
+	return minutes / MINUTES_PER_HOUR, minutes % MINUTES_PER_HOUR
+}
+
+// IsNight reports whether the sun is down in the world
+func (c *Clock) IsNight(now time.Time) bool {
+	hour, _ := c.TimeOfDay(now)
+	return hour < SUNRISE_HOUR
+}
@@ -0,0 +1,40 @@
+package gametime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := New(epoch, 6) // A day of the world lasts 4 real hours
+
+	tests := []struct {
+		name         string
+		elapsed      time.Duration
+		minutes      int64
+		hour, minute int
+		night        bool
+	}{
+		{name: "midnight", elapsed: 0, minutes: 0, hour: 0, minute: 0, night: true},
+		{name: "night", elapsed: 50 * time.Minute, minutes: 300, hour: 5, minute: 0, night: true},
+		{name: "sunrise", elapsed: time.Hour, minutes: 360, hour: 6, minute: 0},
+		{name: "afternoon", elapsed: 2*time.Hour + 25*time.Second, minutes: 722, hour: 12, minute: 2},
+		{name: "second day", elapsed: 4*time.Hour + 10*time.Minute, minutes: 1500, hour: 1, minute: 0, night: true},
+		{name: "before the epoch", elapsed: -10 * time.Minute, minutes: -60, hour: 23, minute: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := epoch.Add(tt.elapsed)
+			if minutes := clock.Minutes(now); minutes != tt.minutes {
+				t.Errorf("Minutes() = %d, want %d", minutes, tt.minutes)
+			}
+			if hour, minute := clock.TimeOfDay(now); hour != tt.hour || minute != tt.minute {
+				t.Errorf("TimeOfDay() = %02d:%02d, want %02d:%02d", hour, minute, tt.hour, tt.minute)
+			}
+			if night := clock.IsNight(now); night != tt.night {
+				t.Errorf("IsNight() = %t, want %t", night, tt.night)
+			}
+		})
+	}
+}
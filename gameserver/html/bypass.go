@@ -0,0 +1,52 @@
+package html
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidBypass is returned when a bypass command doesn't target an NPC
+var ErrInvalidBypass = errors.New("invalid bypass")
+
+// Bypass is a command sent by a dialog link, like "npc_268435457_Chat 1"
+type Bypass struct {
+	ObjectID uint32
+	Command  string
+	Args     []string
+}
+
+// ParseBypass decodes an NPC bypass command: npc_<object id>_<command> [args...]
+func ParseBypass(raw string) (Bypass, error) {
+	var bypass Bypass
+
+	fields := strings.Fields(raw)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "npc_") {
+		return bypass, fmt.Errorf("%w: %q", ErrInvalidBypass, raw)
+	}
+
+	parts := strings.SplitN(fields[0][len("npc_"):], "_", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return bypass, fmt.Errorf("%w: %q", ErrInvalidBypass, raw)
+	}
+
+	objectID, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return bypass, fmt.Errorf("%w: %q", ErrInvalidBypass, raw)
+	}
+
+	bypass.ObjectID = uint32(objectID)
+	bypass.Command = parts[1]
+	bypass.Args = fields[1:]
+
+	return bypass, nil
+}
+
+// Arg returns the argument at the given position, or an empty string
+func (b Bypass) Arg(index int) string {
+	if index < 0 || index >= len(b.Args) {
+		return ""
+	}
+	return b.Args[index]
+}
@@ -0,0 +1,82 @@
+// Package html renders the NPC dialogs: the HTML templates are read from the
+// data directory, filled with the variables of the conversation, then sent in
+// NpcHtmlMessage packets. The links of the dialogs come back as bypass
+// commands, dispatched to the handler registered for the NPC type.
+package html
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrDialogNotFound = errors.New("dialog not found")
+	ErrInvalidPath    = errors.New("invalid dialog path")
+)
+
+// Cache keeps the dialog templates read from a directory
+type Cache struct {
+	dir   string
+	files map[string]string
+	mu    sync.RWMutex
+}
+
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir, files: make(map[string]string)}
+}
+
+// Get returns the template stored at the given path, relative to the cache directory
+func (c *Cache) Get(name string) (string, error) {
+	name = filepath.ToSlash(filepath.Clean(name))
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidPath, name)
+	}
+
+	c.mu.RLock()
+	template, ok := c.files[name]
+	c.mu.RUnlock()
+
+	if ok {
+		return template, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(c.dir, filepath.FromSlash(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrDialogNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.files[name] = string(content)
+	c.mu.Unlock()
+
+	return string(content), nil
+}
+
+// Exists reports whether a template can be found at the given path
+func (c *Cache) Exists(name string) bool {
+	_, err := c.Get(name)
+	return err == nil
+}
+
+// Reload forgets the cached templates, they are read again on their next use
+func (c *Cache) Reload() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.files = make(map[string]string)
+}
+
+// Len returns the number of cached templates
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.files)
+}
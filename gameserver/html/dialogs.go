@@ -0,0 +1,136 @@
+package html
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+var (
+	ErrHandlerExists  = errors.New("dialog handler already registered")
+	ErrUnknownCommand = errors.New("unknown bypass command")
+)
+
+// Handler answers the players talking to the NPCs of a type
+type Handler interface {
+	// Talk opens the first dialog of the NPC
+	Talk(d *Dialog) error
+
+	// Bypass answers a link of a dialog
+	Bypass(d *Dialog, bypass Bypass) error
+}
+
+// Dialog is a conversation between a player and an NPC
+type Dialog struct {
+	Npc    *models.Npc
+	Client *models.Client
+	cache  *Cache
+}
+
+// Variables returns the variables available to the templates of the dialog
+func (d *Dialog) Variables() Variables {
+	return Variables{
+		"objectId":   strconv.FormatUint(uint64(d.Npc.ObjectID), 10),
+		"npcName":    d.Npc.Name,
+		"playerName": d.Client.Account, // Characters aren't stored yet, the account stands for the player
+	}
+}
+
+// Show sends the template stored at the given path, filled with the variables of the dialog
+func (d *Dialog) Show(name string) error {
+	template, err := d.cache.Get(name)
+	if err != nil {
+		return err
+	}
+
+	return d.ShowHTML(template)
+}
+
+// ShowHTML sends an HTML dialog, filled with the variables of the dialog
+func (d *Dialog) ShowHTML(template string) error {
+	return d.Client.Send(serverpackets.NewNpcHtmlMessagePacket(d.Npc.ObjectID, Render(template, d.Variables())))
+}
+
+// Dialogs dispatches the conversations to the handlers registered per NPC type
+type Dialogs struct {
+	cache    *Cache
+	handlers map[string]Handler
+	fallback Handler
+	mu       sync.RWMutex
+}
+
+func NewDialogs(cache *Cache) *Dialogs {
+	return &Dialogs{cache: cache, handlers: make(map[string]Handler), fallback: DefaultHandler{}}
+}
+
+// Cache returns the templates cache used by the dialogs
+func (d *Dialogs) Cache() *Cache {
+	return d.cache
+}
+
+// Register sets the handler of an NPC type
+func (d *Dialogs) Register(npcType string, handler Handler) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.handlers[npcType]; ok {
+		return fmt.Errorf("%w: %s", ErrHandlerExists, npcType)
+	}
+
+	d.handlers[npcType] = handler
+	return nil
+}
+
+// handler returns the handler of an NPC type, the default one if none was registered
+func (d *Dialogs) handler(npcType string) Handler {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if handler, ok := d.handlers[npcType]; ok {
+		return handler
+	}
+	return d.fallback
+}
+
+// Talk starts a conversation between a client and an NPC
+func (d *Dialogs) Talk(npc *models.Npc, client *models.Client) error {
+	return d.handler(npc.Type).Talk(&Dialog{Npc: npc, Client: client, cache: d.cache})
+}
+
+// Bypass hands a bypass command to the handler of the NPC it targets
+func (d *Dialogs) Bypass(npc *models.Npc, client *models.Client, bypass Bypass) error {
+	if bypass.ObjectID != npc.ObjectID {
+		return fmt.Errorf("%w: the bypass targets %d instead of %d", ErrInvalidBypass, bypass.ObjectID, npc.ObjectID)
+	}
+
+	return d.handler(npc.Type).Bypass(&Dialog{Npc: npc, Client: client, cache: d.cache}, bypass)
+}
+
+// DefaultHandler shows the dialogs of the NPC template: default/<template id>.htm when
+// talking, then default/<template id>-<page>.htm for the "Chat <page>" commands
+type DefaultHandler struct{}
+
+func (DefaultHandler) Talk(d *Dialog) error {
+	err := d.Show(fmt.Sprintf("default/%d.htm", d.Npc.TemplateID))
+	if errors.Is(err, ErrDialogNotFound) {
+		return d.Show("default/npcdefault.htm")
+	}
+	return err
+}
+
+func (DefaultHandler) Bypass(d *Dialog, bypass Bypass) error {
+	if bypass.Command != "Chat" {
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, bypass.Command)
+	}
+
+	page, err := strconv.Atoi(bypass.Arg(0))
+	if err != nil || page == 0 {
+		return DefaultHandler{}.Talk(d)
+	}
+
+	return d.Show(fmt.Sprintf("default/%d-%d.htm", d.Npc.TemplateID, page))
+}
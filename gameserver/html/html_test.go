@@ -0,0 +1,254 @@
+package html
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/crypt/xor"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/scripting"
+)
+
+func TestRender(t *testing.T) {
+	got := Render(`<a action="bypass -h npc_%objectId%_Chat 1">%playerName%</a> %unknown%`, Variables{"objectId": "42", "playerName": "Tester"})
+	want := `<a action="bypass -h npc_42_Chat 1">Tester</a> %unknown%`
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestParseBypass(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Bypass
+		wantErr bool
+	}{
+		{"npc_268435457_Chat 1", Bypass{ObjectID: 268435457, Command: "Chat", Args: []string{"1"}}, false},
+		{"npc_42_teleport_request", Bypass{ObjectID: 42, Command: "teleport_request", Args: []string{}}, false},
+		{"npc_%objectId%_Chat 1", Bypass{}, true},
+		{"npc_42_", Bypass{}, true},
+		{"_bbshome", Bypass{}, true},
+		{"", Bypass{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBypass(tt.raw)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidBypass) {
+				t.Errorf("ParseBypass(%q) error = %v, want %v", tt.raw, err, ErrInvalidBypass)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseBypass(%q) = %+v, %v, want %+v", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "default", "30006.htm")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewCache(dir)
+	if got, err := cache.Get("default/30006.htm"); err != nil || got != "first" {
+		t.Fatalf("Get() = %q, %v", got, err)
+	}
+
+	// Cached until reloaded
+	os.WriteFile(path, []byte("second"), 0644)
+	if got, _ := cache.Get("default/30006.htm"); got != "first" {
+		t.Errorf("Get() = %q, want the cached template", got)
+	}
+	cache.Reload()
+	if got, _ := cache.Get("default/30006.htm"); got != "second" {
+		t.Errorf("Get() = %q after Reload(), want %q", got, "second")
+	}
+
+	if _, err := cache.Get("default/missing.htm"); !errors.Is(err, ErrDialogNotFound) {
+		t.Errorf("Get() error = %v, want %v", err, ErrDialogNotFound)
+	}
+	if _, err := cache.Get("../../etc/passwd"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("Get() error = %v, want %v", err, ErrInvalidPath)
+	}
+}
+
+// recordingHandler remembers the bypass commands it receives
+type recordingHandler struct {
+	bypasses []Bypass
+}
+
+func (h *recordingHandler) Talk(d *Dialog) error {
+	return d.ShowHTML("Welcome %playerName%")
+}
+
+func (h *recordingHandler) Bypass(d *Dialog, bypass Bypass) error {
+	h.bypasses = append(h.bypasses, bypass)
+	return nil
+}
+
+func TestDialogs(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "default"), 0755)
+	os.WriteFile(filepath.Join(dir, "default", "7.htm"), []byte(`<a action="bypass -h npc_%objectId%_Chat 1">%npcName%</a>`), 0644)
+	os.WriteFile(filepath.Join(dir, "default", "7-1.htm"), []byte("Page 1"), 0644)
+
+	dialogs := NewDialogs(NewCache(dir))
+	handler := &recordingHandler{}
+	if err := dialogs.Register("gatekeeper", handler); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := dialogs.Register("gatekeeper", handler); !errors.Is(err, ErrHandlerExists) {
+		t.Fatalf("Register() error = %v, want %v", err, ErrHandlerExists)
+	}
+
+	server, conn := net.Pipe()
+	defer server.Close()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	client := models.NewClient()
+	client.Socket = server
+	client.Account = "tester"
+	key := xor.NewCipher().OutputKey
+
+	receive := func(t *testing.T) (uint32, string) {
+		t.Helper()
+
+		data, err := packets.ReadFrame(conn, packets.MaxFrameSize)
+		if err != nil {
+			t.Fatalf("ReadFrame() error = %v", err)
+		}
+		xor.Decrypt(data, key)
+		if data[0] != opcodes.GameServerNpcHtmlMessage {
+			t.Fatalf("expected NpcHtmlMessage, got %#x", data[0])
+		}
+		reader := packets.NewReader(data[1:])
+		return reader.ReadUInt32(), reader.ReadString()
+	}
+
+	talk := func(npc *models.Npc) {
+		go func() {
+			if err := dialogs.Talk(npc, client); err != nil {
+				t.Errorf("Talk() error = %v", err)
+			}
+		}()
+	}
+
+	guard := &models.Npc{ObjectID: 100, TemplateID: 7, Type: "guard", Name: "Guard"}
+	talk(guard)
+	if objectID, html := receive(t); objectID != 100 || html != `<a action="bypass -h npc_100_Chat 1">Guard</a>` {
+		t.Errorf("default dialog = %d %q", objectID, html)
+	}
+
+	go dialogs.Bypass(guard, client, Bypass{ObjectID: 100, Command: "Chat", Args: []string{"1"}})
+	if _, html := receive(t); html != "Page 1" {
+		t.Errorf("chat page = %q, want %q", html, "Page 1")
+	}
+
+	gatekeeper := &models.Npc{ObjectID: 101, Type: "gatekeeper"}
+	talk(gatekeeper)
+	if _, html := receive(t); html != "Welcome tester" {
+		t.Errorf("gatekeeper dialog = %q", html)
+	}
+
+	if err := dialogs.Bypass(gatekeeper, client, Bypass{ObjectID: 101, Command: "teleport"}); err != nil {
+		t.Fatalf("Bypass() error = %v", err)
+	}
+	if len(handler.bypasses) != 1 || handler.bypasses[0].Command != "teleport" {
+		t.Errorf("bypasses = %+v", handler.bypasses)
+	}
+
+	if err := dialogs.Bypass(gatekeeper, client, Bypass{ObjectID: 100, Command: "teleport"}); !errors.Is(err, ErrInvalidBypass) {
+		t.Errorf("Bypass() error = %v, want %v", err, ErrInvalidBypass)
+	}
+}
+
+func TestScriptHandler(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "scripts"), 0755)
+	os.MkdirAll(filepath.Join(dir, "quest"), 0755)
+	os.WriteFile(filepath.Join(dir, "quest", "start.htm"), []byte("Hello %playerName%"), 0644)
+	os.WriteFile(filepath.Join(dir, "scripts", "questgiver.lua"), []byte(`
+talks = 0
+function talk(dialog)
+	talks = talks + 1
+	dialog:show("quest/start.htm")
+end
+function bypass(dialog, command, args)
+	if command == "Quest" then
+		dialog:html(dialog.npc_name .. " asks " .. dialog.player .. " for " .. args[1] .. " pelts after " .. talks .. " talks")
+	elseif command == "Missing" then
+		dialog:show("quest/missing.htm")
+	else
+		while true do end
+	end
+end
+`), 0644)
+
+	dialogs := NewDialogs(NewCache(dir))
+	loaded, err := dialogs.LoadScripts(filepath.Join(dir, "scripts"))
+	if err != nil || loaded != 1 {
+		t.Fatalf("LoadScripts() = %d, %v", loaded, err)
+	}
+	if loaded, err := dialogs.LoadScripts(filepath.Join(dir, "none")); err != nil || loaded != 0 {
+		t.Errorf("LoadScripts() without scripts = %d, %v", loaded, err)
+	}
+
+	server, conn := net.Pipe()
+	defer server.Close()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	client := models.NewClient()
+	client.Socket = server
+	client.Account = "tester"
+	key := xor.NewCipher().OutputKey
+
+	receive := func(t *testing.T) string {
+		t.Helper()
+
+		data, err := packets.ReadFrame(conn, packets.MaxFrameSize)
+		if err != nil {
+			t.Fatalf("ReadFrame() error = %v", err)
+		}
+		xor.Decrypt(data, key)
+		reader := packets.NewReader(data[1:])
+		reader.ReadUInt32()
+		return reader.ReadString()
+	}
+
+	npc := &models.Npc{ObjectID: 100, Type: "questgiver", Name: "Elder"}
+	go dialogs.Talk(npc, client)
+	if html := receive(t); html != "Hello tester" {
+		t.Errorf("talk = %q, want %q", html, "Hello tester")
+	}
+
+	go dialogs.Bypass(npc, client, Bypass{ObjectID: 100, Command: "Quest", Args: []string{"5"}})
+	if html, want := receive(t), "Elder asks tester for 5 pelts after 1 talks"; html != want {
+		t.Errorf("bypass = %q, want %q", html, want)
+	}
+
+	if err := dialogs.Bypass(npc, client, Bypass{ObjectID: 100, Command: "Missing"}); !errors.Is(err, ErrDialogNotFound) {
+		t.Errorf("Bypass() error = %v, want %v", err, ErrDialogNotFound)
+	}
+	if err := dialogs.Bypass(npc, client, Bypass{ObjectID: 100, Command: "Loop"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Bypass() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	os.WriteFile(filepath.Join(dir, "scripts", "broken.lua"), []byte("function talk("), 0644)
+	if _, err := NewDialogs(NewCache(dir)).LoadScripts(filepath.Join(dir, "scripts")); !errors.Is(err, scripting.ErrScript) {
+		t.Errorf("LoadScripts() error = %v, want %v", err, scripting.ErrScript)
+	}
+}
@@ -0,0 +1,22 @@
+package html
+
+import (
+	"strings"
+)
+
+// Variables are substituted in the templates, %name% being replaced with the value of name
+type Variables map[string]string
+
+// Render fills the template with the variables, unknown variables are left untouched
+func Render(template string, vars Variables) string {
+	if len(vars) == 0 {
+		return template
+	}
+
+	pairs := make([]string, 0, len(vars)*2)
+	for name, value := range vars {
+		pairs = append(pairs, "%"+name+"%", value)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(template)
+}
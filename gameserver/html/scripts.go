@@ -0,0 +1,162 @@
+package html
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/scripting"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// SCRIPT_TIMEOUT bounds the time a dialog script takes to answer, so a runaway loop doesn't hold the player
+const SCRIPT_TIMEOUT = time.Second
+
+// ScriptHandler answers the dialogs of an NPC type with a Lua script, which defines talk and bypass:
+//
+//	function talk(dialog)
+//		dialog:show("merchant/start.htm")
+//	end
+//
+//	function bypass(dialog, command, args)
+//		if command == "Quest" then
+//			dialog:html("Hello " .. dialog.player .. ", bring me " .. args[1] .. " wolf pelts")
+//		end
+//	end
+//
+// The dialog holds npc_id, npc_name, template_id and player. A script without talk shows the default
+// dialogs. The globals of the script are kept between the conversations.
+type ScriptHandler struct {
+	state *lua.LState
+	mu    sync.Mutex
+}
+
+// NewScriptHandler runs the top level of a dialog script, returning the handler calling its functions
+func NewScriptHandler(program *scripting.Program) (*ScriptHandler, error) {
+	state := scripting.NewState()
+
+	ctx, cancel := context.WithTimeout(context.Background(), SCRIPT_TIMEOUT)
+	defer cancel()
+
+	if err := program.Load(ctx, state); err != nil {
+		state.Close()
+		return nil, err
+	}
+	return &ScriptHandler{state: state}, nil
+}
+
+func (h *ScriptHandler) Talk(d *Dialog) error {
+	called, err := h.call(d, "talk")
+	if !called {
+		return DefaultHandler{}.Talk(d)
+	}
+	return err
+}
+
+func (h *ScriptHandler) Bypass(d *Dialog, bypass Bypass) error {
+	args := h.state.NewTable()
+	for _, arg := range bypass.Args {
+		args.Append(lua.LString(arg))
+	}
+
+	called, err := h.call(d, "bypass", lua.LString(bypass.Command), args)
+	if !called {
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, bypass.Command)
+	}
+	return err
+}
+
+// Close releases the state of the script
+func (h *ScriptHandler) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.state.Close()
+}
+
+// call calls a function of the script with the dialog, then the given arguments
+func (h *ScriptHandler) call(d *Dialog, name string, args ...lua.LValue) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), SCRIPT_TIMEOUT)
+	defer cancel()
+
+	// The error of a dialog is kept as is, rather than as the message of the Lua error it raises
+	var failed error
+	show := func(send func(string) error) lua.LGFunction {
+		return func(L *lua.LState) int {
+			if err := send(L.CheckString(2)); err != nil {
+				failed = err
+				L.RaiseError("%v", err)
+			}
+			return 0
+		}
+	}
+
+	L := h.state
+	dialog := L.NewTable()
+	dialog.RawSetString("npc_id", lua.LNumber(d.Npc.ObjectID))
+	dialog.RawSetString("npc_name", lua.LString(d.Npc.Name))
+	dialog.RawSetString("template_id", lua.LNumber(d.Npc.TemplateID))
+	dialog.RawSetString("player", lua.LString(d.Variables()["playerName"]))
+	dialog.RawSetString("show", L.NewFunction(show(d.Show)))
+	dialog.RawSetString("html", L.NewFunction(show(d.ShowHTML)))
+
+	called, err := scripting.Call(ctx, L, name, append([]lua.LValue{dialog}, args...)...)
+	if failed != nil {
+		return called, failed
+	}
+	return called, err
+}
+
+// LoadScripts registers the dialog scripts of a directory, each <npc type>.lua answering
+// the NPCs of its type, and returns how many it registered. A missing directory has none.
+func (d *Dialogs) LoadScripts(dir string) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.lua"))
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	loaded := 0
+	for _, path := range paths {
+		npcType := strings.TrimSuffix(filepath.Base(path), ".lua")
+		if err := d.loadScript(npcType, path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		loaded++
+	}
+	return loaded, errors.Join(errs...)
+}
+
+// loadScript registers a dialog script for an NPC type
+func (d *Dialogs) loadScript(npcType, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	program, err := scripting.Compile(filepath.Base(path), file)
+	if err != nil {
+		return err
+	}
+
+	handler, err := NewScriptHandler(program)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Register(npcType, handler); err != nil {
+		handler.Close()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+// Package interest splits the world into a grid of regions and tracks the objects
+// standing in each one, so the players are only told about what happens in their
+// region and the eight around it, and learn about the objects entering or leaving
+// their sight as they or the objects move across the regions
+package interest
+
+import "sync"
+
+// REGION_SIZE is the side of the square regions the world is split into
+const REGION_SIZE = 4096
+
+// Kinds of objects
+const (
+	PLAYER = "player" // The only kind seeing the others
+	NPC    = "npc"
+	ITEM   = "item" // Lying on the ground
+)
+
+// Region is a square of the world
+type Region struct {
+	X, Y int32
+}
+
+// RegionOf returns the region holding a location
+func RegionOf(x, y int32) Region {
+	return Region{X: floorDiv(x, REGION_SIZE), Y: floorDiv(y, REGION_SIZE)}
+}
+
+// Around returns the region and the eight ones around it, the ones seen from it
+func (r Region) Around() [9]Region {
+	var around [9]Region
+	for i := range around {
+		around[i] = Region{X: r.X + int32(i%3) - 1, Y: r.Y + int32(i/3) - 1}
+	}
+	return around
+}
+
+// sees reports whether the objects of two regions see each other
+func (r Region) sees(other Region) bool {
+	return abs(r.X-other.X) <= 1 && abs(r.Y-other.Y) <= 1
+}
+
+func floorDiv(a, b int32) int32 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+func abs(a int32) int32 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Object is something of the world the players can see
+type Object struct {
+	ObjectID uint32
+	Kind     string
+	X, Y, Z  int32
+}
+
+// Update tells who has to learn what once an object appeared, moved or left
+type Update struct {
+	Object   Object   // The object, as it is after the change
+	Watching []uint32 // Players seeing the object both before and after the change
+	Entered  []uint32 // Players starting to see the object
+	Left     []uint32 // Players no longer seeing the object
+	Shown    []Object // Objects a player starts to see, when the object is one
+	Hidden   []uint32 // Objects a player no longer sees, when the object is one
+}
+
+// Grid holds the objects of the world by region
+type Grid struct {
+	objects map[uint32]Object
+	regions map[Region]map[uint32]struct{}
+	mu      sync.Mutex
+}
+
+// NewGrid creates an empty world
+func NewGrid() *Grid {
+	return &Grid{objects: make(map[uint32]Object), regions: make(map[Region]map[uint32]struct{})}
+}
+
+// Add places an object in the world, the players around starting to see it.
+// An object already in the world is moved instead.
+func (g *Grid) Add(object Object) Update {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.objects[object.ObjectID]; ok {
+		return g.move(object.ObjectID, object.X, object.Y, object.Z)
+	}
+
+	g.objects[object.ObjectID] = object
+	region := RegionOf(object.X, object.Y)
+	g.place(object.ObjectID, region)
+
+	update := Update{Object: object, Entered: g.players(region, object.ObjectID)}
+	if object.Kind == PLAYER {
+		update.Shown = g.all(region, object.ObjectID)
+	}
+	return update
+}
+
+// Move changes the location of an object, returning false if it isn't in the world
+func (g *Grid) Move(objectID uint32, x, y, z int32) (Update, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.objects[objectID]; !ok {
+		return Update{}, false
+	}
+	return g.move(objectID, x, y, z), true
+}
+
+// Remove takes an object out of the world, returning false if it wasn't in it
+func (g *Grid) Remove(objectID uint32) (Update, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	object, ok := g.objects[objectID]
+	if !ok {
+		return Update{}, false
+	}
+
+	region := RegionOf(object.X, object.Y)
+	delete(g.objects, objectID)
+	g.unplace(objectID, region)
+
+	return Update{Object: object, Left: g.players(region, objectID)}, true
+}
+
+// Object returns an object of the world
+func (g *Grid) Object(objectID uint32) (Object, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	object, ok := g.objects[objectID]
+	return object, ok
+}
+
+// Observers returns the players seeing a location
+func (g *Grid) Observers(x, y int32) []uint32 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.players(RegionOf(x, y), 0)
+}
+
+// Visible returns the objects a player sees, itself excluded
+func (g *Grid) Visible(objectID uint32) []Object {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	object, ok := g.objects[objectID]
+	if !ok {
+		return nil
+	}
+	return g.all(RegionOf(object.X, object.Y), objectID)
+}
+
+// move changes the location of an object of the world, the lock being held
+func (g *Grid) move(objectID uint32, x, y, z int32) Update {
+	object := g.objects[objectID]
+	from, to := RegionOf(object.X, object.Y), RegionOf(x, y)
+	object.X, object.Y, object.Z = x, y, z
+	g.objects[objectID] = object
+
+	update := Update{Object: object}
+	if from == to {
+		update.Watching = g.players(to, objectID)
+		return update
+	}
+
+	g.unplace(objectID, from)
+	g.place(objectID, to)
+
+	before, after := g.all(from, objectID), g.all(to, objectID)
+	for _, other := range before {
+		seen := to.sees(RegionOf(other.X, other.Y))
+		switch {
+		case other.Kind == PLAYER && seen:
+			update.Watching = append(update.Watching, other.ObjectID)
+		case other.Kind == PLAYER:
+			update.Left = append(update.Left, other.ObjectID)
+		}
+		if object.Kind == PLAYER && !seen {
+			update.Hidden = append(update.Hidden, other.ObjectID)
+		}
+	}
+	for _, other := range after {
+		if from.sees(RegionOf(other.X, other.Y)) {
+			continue
+		}
+		if other.Kind == PLAYER {
+			update.Entered = append(update.Entered, other.ObjectID)
+		}
+		if object.Kind == PLAYER {
+			update.Shown = append(update.Shown, other)
+		}
+	}
+	return update
+}
+
+// players returns the players seeing a region, but one, the lock being held
+func (g *Grid) players(region Region, except uint32) []uint32 {
+	var players []uint32
+	for _, object := range g.all(region, except) {
+		if object.Kind == PLAYER {
+			players = append(players, object.ObjectID)
+		}
+	}
+	return players
+}
+
+// all returns the objects seen from a region, but one, the lock being held
+func (g *Grid) all(region Region, except uint32) []Object {
+	var objects []Object
+	for _, around := range region.Around() {
+		for objectID := range g.regions[around] {
+			if objectID != except {
+				objects = append(objects, g.objects[objectID])
+			}
+		}
+	}
+	return objects
+}
+
+func (g *Grid) place(objectID uint32, region Region) {
+	if g.regions[region] == nil {
+		g.regions[region] = make(map[uint32]struct{})
+	}
+	g.regions[region][objectID] = struct{}{}
+}
+
+func (g *Grid) unplace(objectID uint32, region Region) {
+	delete(g.regions[region], objectID)
+	if len(g.regions[region]) == 0 {
+		delete(g.regions, region)
+	}
+}
@@ -0,0 +1,90 @@
+package interest
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRegionOf(t *testing.T) {
+	tests := []struct {
+		x, y int32
+		want Region
+	}{
+		{0, 0, Region{0, 0}},
+		{REGION_SIZE - 1, REGION_SIZE, Region{0, 1}},
+		{-1, -REGION_SIZE, Region{-1, -1}},
+		{-REGION_SIZE - 1, 83000, Region{-2, 20}},
+	}
+
+	for _, tt := range tests {
+		if got := RegionOf(tt.x, tt.y); got != tt.want {
+			t.Errorf("RegionOf(%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func ids(objects []Object) []uint32 {
+	var ids []uint32
+	for _, object := range objects {
+		ids = append(ids, object.ObjectID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func sorted(ids []uint32) []uint32 {
+	slices.Sort(ids)
+	return ids
+}
+
+func TestGrid(t *testing.T) {
+	grid := NewGrid()
+
+	// A player and an NPC next to each other, another player two regions away from the NPC
+	grid.Add(Object{ObjectID: 1, Kind: PLAYER, X: 100, Y: 100})
+	if update := grid.Add(Object{ObjectID: 10, Kind: NPC, X: REGION_SIZE + 100, Y: 100}); !slices.Equal(update.Entered, []uint32{1}) || update.Shown != nil {
+		t.Fatalf("Add(npc) = %+v", update)
+	}
+	update := grid.Add(Object{ObjectID: 2, Kind: PLAYER, X: 3*REGION_SIZE + 100, Y: 100})
+	if update.Entered != nil || update.Shown != nil {
+		t.Fatalf("Add(far player) = %+v", update)
+	}
+
+	// Moving within its region changes nothing but the location
+	update, ok := grid.Move(1, 200, 200, 0)
+	if !ok || update.Watching != nil || update.Entered != nil || update.Shown != nil || update.Hidden != nil {
+		t.Fatalf("Move() in the same region = %+v, %v", update, ok)
+	}
+
+	// The NPC walks one region further, in sight of the second player and out of the first one's
+	update, _ = grid.Move(10, 2*REGION_SIZE+100, 100, 0)
+	if !slices.Equal(update.Entered, []uint32{2}) || update.Watching != nil || !slices.Equal(update.Left, []uint32{1}) {
+		t.Errorf("Move(npc) = %+v", update)
+	}
+
+	// The first player catches up with it, starting to see the second player as well
+	update, _ = grid.Move(1, 2*REGION_SIZE+100, 100, 0)
+	if !slices.Equal(update.Entered, []uint32{2}) || !slices.Equal(ids(update.Shown), []uint32{2, 10}) || update.Hidden != nil {
+		t.Errorf("Move(player) = %+v", update)
+	}
+	if visible := ids(grid.Visible(1)); !slices.Equal(visible, []uint32{2, 10}) {
+		t.Errorf("Visible() = %v", visible)
+	}
+	if observers := sorted(grid.Observers(2*REGION_SIZE, 0)); !slices.Equal(observers, []uint32{1, 2}) {
+		t.Errorf("Observers() = %v", observers)
+	}
+
+	// And walks back, losing sight of both
+	update, _ = grid.Move(1, -100, 100, 0)
+	if !slices.Equal(update.Left, []uint32{2}) || !slices.Equal(sorted(update.Hidden), []uint32{2, 10}) {
+		t.Errorf("Move(player) back = %+v", update)
+	}
+
+	update, ok = grid.Remove(10)
+	if !ok || !slices.Equal(update.Left, []uint32{2}) {
+		t.Errorf("Remove() = %+v, %v", update, ok)
+	}
+	if _, ok := grid.Move(10, 0, 0, 0); ok {
+		t.Error("Move() = true once removed")
+	}
+}
@@ -0,0 +1,14 @@
+package linkpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+func NewHeartbeatPacket(onlinePlayers uint16) []byte {
+	buffer := new(packets.Buffer)
+	buffer.WriteByte(opcodes.LinkGameServerHeartbeat)
+	buffer.WriteUInt16(onlinePlayers)
+
+	return buffer.Bytes()
+}
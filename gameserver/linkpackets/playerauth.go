@@ -0,0 +1,33 @@
+package linkpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+const playerAuthKeysSize = 16
+
+type PlayerAuth struct {
+	Account     string
+	LoginKey    []byte
+	PlayKey     []byte
+	AccessLevel int8 // The player one when the login server doesn't send it
+}
+
+func NewPlayerAuth(request []byte) (PlayerAuth, error) {
+	var result PlayerAuth
+
+	var packet = packets.NewReader(request)
+
+	result.Account = packet.ReadString()
+	if result.Account == "" || packet.Len() < playerAuthKeysSize {
+		return result, ErrLinkPacketTooShort
+	}
+
+	result.LoginKey = packet.ReadBytes(8)
+	result.PlayKey = packet.ReadBytes(8)
+	if packet.Len() > 0 {
+		result.AccessLevel = int8(packet.ReadUInt8())
+	}
+
+	return result, nil
+}
@@ -0,0 +1,18 @@
+package linkpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
+)
+
+// NewRegisterGameServerPacket registers the game server on the login server, offering to compress the link
+func NewRegisterGameServerPacket(name, secret string, compression protocol.CompressionOptions) []byte {
+	buffer := new(packets.Buffer)
+	buffer.WriteByte(opcodes.LinkGameServerRegister)
+	buffer.WriteString(name)   // Must match the name in the login server configuration
+	buffer.WriteString(secret) // Auth key shared with the login server
+	buffer.Write(compression.Trailer())
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,35 @@
+package linkpackets
+
+import (
+	"errors"
+
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
+)
+
+const registerResultSize = 2
+
+// ErrLinkPacketTooShort is returned when a packet from the login server is truncated
+var ErrLinkPacketTooShort = errors.New("login server packet too short")
+
+type RegisterResult struct {
+	Accepted    bool
+	ServerID    uint8
+	Compression protocol.CompressionOptions // Of the packets following the result, disabled unless both servers offered it
+}
+
+func NewRegisterResult(request []byte) (RegisterResult, error) {
+	var result RegisterResult
+
+	if len(request) < registerResultSize {
+		return result, ErrLinkPacketTooShort
+	}
+
+	var packet = packets.NewReader(request)
+
+	result.Accepted = packet.ReadUInt8() == 0x01
+	result.ServerID = packet.ReadUInt8()
+	result.Compression = protocol.ParseCompressionTrailer(request[registerResultSize:])
+
+	return result, nil
+}
@@ -0,0 +1,68 @@
+package gameserver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+var ErrNotInWorld = errors.New("the player isn't in the world")
+
+// Restart takes a player out of the world back to the character selection, its connection staying open.
+// Its character and its effects are saved first.
+func (g *GameServer) Restart(client *models.Client) error {
+	if !g.inWorld(client) {
+		if err := client.Send(serverpackets.NewRestartResponsePacket(false)); err != nil {
+			fmt.Println(err)
+		}
+		return fmt.Errorf("%w: %d", ErrNotInWorld, client.ObjectID)
+	}
+
+	g.leaveGame(client)
+	client.TargetID = 0
+
+	g.progressMutex.Lock()
+	client.Sitting = false
+	g.progressMutex.Unlock()
+
+	if err := client.Send(serverpackets.NewRestartResponsePacket(true)); err != nil {
+		return err
+	}
+	return client.Send(g.charListPacket(client))
+}
+
+// inWorld reports whether a player entered the world and is still in it
+func (g *GameServer) inWorld(client *models.Client) bool {
+	g.clientsMutex.Lock()
+	defer g.clientsMutex.Unlock()
+
+	return client.Effects != nil
+}
+
+// playing reports whether a player authenticated and entered the world, the connections yet to
+// authenticate having no account their adena and items could be kept with
+func (g *GameServer) playing(client *models.Client) bool {
+	return g.inWorld(client) && client.Account != ""
+}
+
+// leaveGame saves the character and the effects of a player, then takes it out of the world,
+// its online friends seeing it go offline. Leaving twice does nothing more.
+func (g *GameServer) leaveGame(client *models.Client) {
+	wasInWorld := g.inWorld(client)
+
+	g.saveCharacter(client)
+	g.saveEffects(client)
+	g.leaveWorld(client)
+	g.forgetFriendInvite(client)
+
+	// The game loop stops running its effects and its regeneration
+	g.clientsMutex.Lock()
+	client.Effects = nil
+	g.clientsMutex.Unlock()
+
+	if wasInWorld {
+		g.notifyFriends(client, false)
+	}
+}
@@ -0,0 +1,160 @@
+// Package loop runs the game at a fixed timestep: the systems of the game server,
+// such as the movements, the AI, the regeneration and the effects, are advanced
+// on the ticks of a single loop which keeps its pace on the clock rather than
+// drifting, and measures how long each system takes so an overload shows
+package loop
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+)
+
+// System is a part of the game advanced by the loop
+type System struct {
+	Name   string
+	Every  time.Duration                              // Time between two runs, at least a tick
+	Budget time.Duration                              // Longest run expected, the tick period when 0
+	Run    func(now time.Time, elapsed time.Duration) // Given the time elapsed since its previous run
+}
+
+// Stats counts the ticks of the loop and the time its systems took
+type Stats struct {
+	Ticks    uint64
+	Skipped  uint64 // Ticks dropped to catch up after falling behind
+	Overruns uint64 // Ticks which lasted longer than the tick period
+	Systems  []SystemStats
+}
+
+// SystemStats is the time a system of the loop took
+type SystemStats struct {
+	Name       string
+	Runs       uint64
+	OverBudget uint64 // Runs which took longer than the budget of the system
+	Last       time.Duration
+	Max        time.Duration
+	Total      time.Duration
+}
+
+// Loop ticks at a fixed rate, running the systems due at every tick
+type Loop struct {
+	clock   clock.Clock
+	period  time.Duration
+	systems []*system
+	stats   Stats
+	mu      sync.Mutex
+}
+
+type system struct {
+	System
+	next, last time.Time
+	stats      SystemStats
+}
+
+// New creates a loop ticking rate times per second on the clock
+func New(c clock.Clock, rate int) *Loop {
+	return &Loop{clock: c, period: time.Second / time.Duration(max(rate, 1))}
+}
+
+// Period returns the time between two ticks
+func (l *Loop) Period() time.Duration {
+	return l.period
+}
+
+// Add registers a system, run from the next tick on
+func (l *Loop) Add(s System) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s.Every = max(s.Every, l.period)
+	if s.Budget <= 0 {
+		s.Budget = l.period
+	}
+	l.systems = append(l.systems, &system{System: s, stats: SystemStats{Name: s.Name}})
+}
+
+// Run ticks until the context is done. Every tick is scheduled from the start of the loop rather
+// than from the end of the previous one, so the time the systems take doesn't delay the next
+// ticks; once more than a tick behind, the missed ticks are dropped instead of run in a burst.
+func (l *Loop) Run(ctx context.Context) {
+	next := l.clock.Now()
+	for {
+		next = next.Add(l.period)
+		if err := clock.Sleep(ctx, l.clock, next.Sub(l.clock.Now())); err != nil {
+			return
+		}
+
+		now := l.clock.Now()
+		if behind := now.Sub(next); behind >= l.period {
+			missed := behind / l.period
+			next = next.Add(missed * l.period)
+
+			l.mu.Lock()
+			l.stats.Skipped += uint64(missed)
+			l.mu.Unlock()
+		}
+
+		l.Tick(now)
+	}
+}
+
+// Tick runs the systems due, in the order they were added. It is called from a single goroutine,
+// the one of Run or of a test driving the loop by hand.
+func (l *Loop) Tick(now time.Time) {
+	l.mu.Lock()
+	systems := append([]*system(nil), l.systems...)
+	l.mu.Unlock()
+
+	start := l.clock.Now()
+	for _, s := range systems {
+		if now.Before(s.next) {
+			continue
+		}
+
+		// The system keeps its own pace, skipping the runs it missed
+		elapsed := s.Every
+		if s.last.IsZero() {
+			s.next = now.Add(s.Every)
+		} else {
+			elapsed = now.Sub(s.last)
+			s.next = s.next.Add((now.Sub(s.next)/s.Every + 1) * s.Every)
+		}
+		s.last = now
+
+		began := l.clock.Now()
+		s.Run(now, elapsed)
+		took := l.clock.Now().Sub(began)
+
+		l.mu.Lock()
+		s.stats.Runs++
+		s.stats.Last = took
+		s.stats.Max = max(s.stats.Max, took)
+		s.stats.Total += took
+		if took > s.Budget {
+			s.stats.OverBudget++
+		}
+		l.mu.Unlock()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats.Ticks++
+	if l.clock.Now().Sub(start) > l.period {
+		l.stats.Overruns++
+	}
+}
+
+// Stats returns the counters of the loop and of its systems
+func (l *Loop) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := l.stats
+	stats.Systems = make([]SystemStats, 0, len(l.systems))
+	for _, s := range l.systems {
+		stats.Systems = append(stats.Systems, s.stats)
+	}
+	return stats
+}
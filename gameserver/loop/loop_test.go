@@ -0,0 +1,79 @@
+package loop
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+)
+
+func TestTick(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := New(fake, 10)
+
+	var fast, slow []time.Duration
+	l.Add(System{Name: "fast", Run: func(now time.Time, elapsed time.Duration) { fast = append(fast, elapsed) }})
+	l.Add(System{Name: "slow", Every: 250 * time.Millisecond, Run: func(now time.Time, elapsed time.Duration) { slow = append(slow, elapsed) }})
+
+	for i := 0; i < 6; i++ {
+		l.Tick(fake.Now())
+		fake.Advance(l.Period())
+	}
+
+	// The slow system keeps its pace: due at 250ms and 500ms, it runs on the first ticks from then on
+	if len(fast) != 6 || fast[1] != 100*time.Millisecond {
+		t.Errorf("fast system ran %v", fast)
+	}
+	if len(slow) != 3 || slow[0] != 250*time.Millisecond || slow[1] != 300*time.Millisecond || slow[2] != 200*time.Millisecond {
+		t.Errorf("slow system ran %v", slow)
+	}
+
+	stats := l.Stats()
+	if stats.Ticks != 6 || len(stats.Systems) != 2 || stats.Systems[0].Runs != 6 || stats.Systems[1].Runs != 3 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestRunCompensatesDrift(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	l := New(fake, 10)
+	start := fake.Now()
+
+	// The third tick takes 350ms, the time of three more ticks and a half
+	var ticks []time.Duration
+	l.Add(System{Name: "slow", Budget: 50 * time.Millisecond, Run: func(now time.Time, elapsed time.Duration) {
+		ticks = append(ticks, now.Sub(start))
+		if len(ticks) == 3 {
+			fake.Advance(350 * time.Millisecond)
+		}
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx)
+		close(done)
+	}()
+
+	// Waiting for the loop to sleep before moving the clock, it only ticks on time
+	for l.Stats().Ticks < 5 {
+		if err := fake.WaitForTimers(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+		fake.Advance(50 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// The ticks keep their pace: after the slow one at 300ms, the ones due at 400ms and 500ms
+	// are dropped, the one due at 600ms runs late at 650ms and the next one on time
+	stats := l.Stats()
+	if stats.Skipped != 2 || stats.Overruns != 1 || stats.Systems[0].OverBudget != 1 || stats.Systems[0].Max != 350*time.Millisecond {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	if ticks[3] != 650*time.Millisecond || ticks[4] != 700*time.Millisecond {
+		t.Errorf("ticks at %v", ticks)
+	}
+}
@@ -0,0 +1,156 @@
+package gameserver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/drops"
+	"github.com/frostwind/l2go/gameserver/interest"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/sendqueue"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/random"
+)
+
+var (
+	ErrUnknownItem  = errors.New("no such item on the ground")
+	ErrItemReserved = errors.New("item reserved to another player")
+)
+
+// SetRandSource replaces the source the drops are rolled with, to reproduce them in tests
+func (g *GameServer) SetRandSource(source random.Source) {
+	g.itemsMutex.Lock()
+	defer g.itemsMutex.Unlock()
+
+	g.random = source
+}
+
+// DropLoot rolls the drops of an NPC killed by a player, the kill counting for its quests. They
+// go straight to the killer with the auto-loot, as long as it can carry them, otherwise they are
+// left on the ground around the NPC, reserved to the killer for a while, and returned.
+func (g *GameServer) DropLoot(npc *models.Npc, killer *models.Client) []*models.GroundItem {
+	g.countKill(killer, npc)
+
+	autoLoot := g.config.GameServer.Options.AutoLoot
+	limits := g.Limits(killer)
+	g.itemsMutex.Lock()
+	dropped := g.drops.Roll(g.random, npc.TemplateID)
+
+	if autoLoot {
+		var left []drops.Drop
+		for _, drop := range dropped {
+			if err := g.canCarry(killer, limits, map[int]uint64{drop.ItemID: drop.Count}); err != nil {
+				left = append(left, drop)
+				continue
+			}
+			g.give(killer, drop.ItemID, drop.Count)
+		}
+		dropped = left
+	}
+	if len(dropped) == 0 {
+		g.itemsMutex.Unlock()
+		if autoLoot {
+			g.updateLoad(killer)
+		}
+		return nil
+	}
+
+	reservedUntil := time.Now().Add(g.config.GameServer.Options.LootProtectionTime())
+	items := make([]*models.GroundItem, 0, len(dropped))
+	for i, drop := range dropped {
+		item := &models.GroundItem{
+			ObjectID:      g.newObjectID(),
+			ItemID:        drop.ItemID,
+			Count:         drop.Count,
+			X:             npc.X + int32(i%3-1)*30, // Spread around the NPC
+			Y:             npc.Y + int32(i/3%3-1)*30,
+			Z:             npc.Z,
+			OwnerID:       killer.ObjectID,
+			ReservedUntil: reservedUntil,
+		}
+		g.groundItems[item.ObjectID] = item
+		items = append(items, item)
+	}
+	g.itemsMutex.Unlock()
+
+	// The players around see the items fall rather than lying there
+	for _, item := range items {
+		update := g.interest.Add(interest.Object{ObjectID: item.ObjectID, Kind: interest.ITEM, X: item.X, Y: item.Y, Z: item.Z})
+		g.sendTo(update.Entered, sendqueue.CRITICAL, serverpackets.NewDropItemPacket(npc.ObjectID, item.ObjectID, item.ItemID, item.X, item.Y, item.Z, item.Count))
+	}
+	if autoLoot {
+		g.updateLoad(killer)
+	}
+	return items
+}
+
+// GroundItem returns the item lying on the ground with the given object id
+func (g *GameServer) GroundItem(objectID uint32) (*models.GroundItem, bool) {
+	g.itemsMutex.Lock()
+	defer g.itemsMutex.Unlock()
+
+	item, ok := g.groundItems[objectID]
+	return item, ok
+}
+
+// PickUp moves an item from the ground to the inventory of a player, if it isn't reserved to another one
+// and the player can carry it. The player is told why it can't carry the item, which stays on the ground.
+// Only the players in the world pick items up.
+func (g *GameServer) PickUp(client *models.Client, objectID uint32) error {
+	if !g.playing(client) {
+		return ErrNotInWorld
+	}
+	limits := g.Limits(client)
+	g.itemsMutex.Lock()
+	item, ok := g.groundItems[objectID]
+	if !ok {
+		g.itemsMutex.Unlock()
+		return fmt.Errorf("%w: %d", ErrUnknownItem, objectID)
+	}
+	if !item.CanPickUp(client.ObjectID, time.Now()) {
+		g.itemsMutex.Unlock()
+		return fmt.Errorf("%w: %d", ErrItemReserved, objectID)
+	}
+	if err := g.canCarry(client, limits, map[int]uint64{item.ItemID: item.Count}); err != nil {
+		g.itemsMutex.Unlock()
+		g.refusePickUp(client, err)
+		return err
+	}
+
+	delete(g.groundItems, objectID)
+	g.give(client, item.ItemID, item.Count)
+	g.itemsMutex.Unlock()
+	g.updateLoad(client)
+
+	if update, ok := g.interest.Remove(item.ObjectID); ok {
+		g.sendTo(update.Left, sendqueue.CRITICAL, serverpackets.NewGetItemPacket(client.ObjectID, item.ObjectID, item.X, item.Y, item.Z))
+		g.publish(update, nil)
+	}
+	return nil
+}
+
+// give adds items to the inventory of a player, the items mutex being held
+func (g *GameServer) give(client *models.Client, itemID int, count uint64) {
+	g.markDirty(client)
+
+	if itemID == drops.ADENA_ID {
+		client.Adena += count
+		return
+	}
+
+	if client.Items == nil {
+		client.Items = make(map[int]uint64)
+	}
+	client.Items[itemID] += count
+}
+
+// newObjectID returns the next object id of the world, shared by the NPCs and the items
+func (g *GameServer) newObjectID() uint32 {
+	g.npcsMutex.Lock()
+	defer g.npcsMutex.Unlock()
+
+	objectID := g.nextObjectID
+	g.nextObjectID += 1
+	return objectID
+}
@@ -1,23 +1,100 @@
 package models
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"github.com/frostwind/l2go/gameserver/behavior"
 	"github.com/frostwind/l2go/gameserver/crypt/xor"
+	"github.com/frostwind/l2go/gameserver/effects"
+	"github.com/frostwind/l2go/gameserver/movement"
+	"github.com/frostwind/l2go/gameserver/sendqueue"
+	"github.com/frostwind/l2go/gameserver/vitals"
 	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
 	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Client struct {
-	SessionID []byte
-	Socket    net.Conn
-	Cipher    *xor.Cipher
+	SessionID      []byte
+	Account        string
+	Socket         net.Conn
+	Cipher         *xor.Cipher
+	MaxPacketSize  int
+	Violations     uint32        // Oversized packets sent by the client
+	IdleTimeout    time.Duration // Longest wait for the next packet, 0 for none
+	TargetID       uint32        // Object selected by the client, 0 for none
+	Sitting        bool          // Sat down, until the player stands up, written under the progress mutex the regeneration reads it under
+	ObjectID       uint32        // Object id of the player in the world
+	AccessLevel    int8          // Of the account, sent by the login server along with its session
+	X, Y, Z        int32         // Location of the player, written with SetPosition and read with Position, the game loop reading it too
+	Adena          uint64
+	Items          map[int]uint64 // Counts of the items other than the adena, by item id
+	Enchants       map[int]int    // Enchant levels of the items, by item id, the stack of an item sharing its level
+	WeightPenalty  int            // Overload level of the player, from 0 to capacity.MAX_PENALTY, along with the items
+	Clan           string         // Clan of the player, loaded with its character, sharing the clan warehouse, "" for none
+	Level          int
+	Exp            uint64
+	Karma          int
+	Paperdoll      map[int]int // Item ids worn, by paperdoll slot
+	LastAccess     time.Time   // When the character was saved last, zero for a new one
+	HP, MaxHP      int
+	MP, MaxMP      int
+	CP, MaxCP      int
+	ClassID        int             // Class of the first character of the account, deciding the regeneration
+	Vitals         *vitals.Tracker // Regenerates the HP, the MP and the CP
+	Effects        *effects.List   // Buffs and debuffs, once the player is authenticated
+	Movement       *movement.Tracker
+	Behavior       *behavior.Tracker               // Scores the player for the bot detection, nil when it is disabled
+	positionMutex  sync.RWMutex                    // Guards X, Y and Z, and the Account the character is saved under
+	sendMutex      sync.Mutex                      // Packets broadcast by other players are sent concurrently
+	sendQueue      atomic.Pointer[sendqueue.Queue] // Read without the send mutex, which a synchronous write holds
+	compressor     *protocol.Compressor            // Set under the send mutex once negotiated, nil while the packets aren't compressed
+	dropBroadcasts bool
+	slow           bool // Disconnected for filling its send queue
+	Plain          bool // The packets go in clear, in the null crypto debug mode
 }
 
 func NewClient() *Client {
 	return &Client{Cipher: xor.NewCipher()}
 }
 
+// Position returns where the player stands
+func (c *Client) Position() (x, y, z int32) {
+	c.positionMutex.RLock()
+	defer c.positionMutex.RUnlock()
+
+	return c.X, c.Y, c.Z
+}
+
+// SetPosition moves the player
+func (c *Client) SetPosition(x, y, z int32) {
+	c.positionMutex.Lock()
+	defer c.positionMutex.Unlock()
+
+	c.X, c.Y, c.Z = x, y, z
+}
+
+// SetAccount records the account the player authenticated with
+func (c *Client) SetAccount(account string) {
+	c.positionMutex.Lock()
+	defer c.positionMutex.Unlock()
+
+	c.Account = account
+}
+
+// Whereabouts returns the account of the player and where it stands at once, for its character to be saved
+func (c *Client) Whereabouts() (account string, x, y, z int32) {
+	c.positionMutex.RLock()
+	defer c.positionMutex.RUnlock()
+
+	return c.Account, c.X, c.Y, c.Z
+}
+
 func (c *Client) Receive(params ...bool) (opcode byte, data []byte, e error) {
 	doXor := true
 
@@ -26,33 +103,30 @@ func (c *Client) Receive(params ...bool) (opcode byte, data []byte, e error) {
 		doXor = false
 	}
 
-	// Read the first two bytes to define the packet size
-	header := make([]byte, 2)
-	n, err := c.Socket.Read(header)
-
-	if n != 2 || err != nil {
-		return 0x00, nil, errors.New(string(header))
+	// Read the packet, refusing oversized length headers before allocating anything
+	if c.IdleTimeout > 0 {
+		c.Socket.SetReadDeadline(time.Now().Add(c.IdleTimeout))
 	}
 
-	// Calculate the packet size
-	size := 0
-	size = size + int(header[0])
-	size = size + int(header[1])*256
+	data, err := packets.ReadFrame(c.Socket, c.MaxPacketSize)
 
-	// Allocate the appropriate size for our data (size - 2 bytes used for the length
-	data = make([]byte, size-2)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return 0x00, nil, packets.ErrIdleTimeout
+	}
 
-	// Read the encrypted part of the packet
-	n, err = c.Socket.Read(data)
+	if errors.Is(err, packets.ErrFrameTooLarge) {
+		c.Violations += 1
+		return 0x00, nil, err
+	}
 
-	if n != size-2 || err != nil {
-		return 0x00, nil, errors.New("An error occured while reading the packet data.")
+	if err != nil {
+		return 0x00, nil, errors.New("An error occured while reading the packet.")
 	}
 
 	// Print the raw packet
-	fmt.Printf("Raw packet : %X%X\n", header, data)
+	fmt.Printf("Raw packet : %X\n", data)
 
-	if doXor == true {
+	if doXor == true && !c.Plain {
 		// Decrypt the packet data using the xor key
 		xor.Decrypt(data, c.Cipher.InputKey)
 
@@ -64,6 +138,19 @@ func (c *Client) Receive(params ...bool) (opcode byte, data []byte, e error) {
 		}
 	}
 
+	// The compressor is only set by the goroutine receiving the packets
+	if doXor == true && c.compressor != nil {
+		data, err = c.compressor.Decompress(data)
+
+		if err != nil {
+			return 0x00, nil, err
+		}
+	}
+
+	if len(data) == 0 {
+		return 0x00, nil, errors.New("An error occured while reading the packet.")
+	}
+
 	// Extract the opcode, and return our values
 	opcode = data[0]
 	data = data[1:]
@@ -72,34 +159,143 @@ func (c *Client) Receive(params ...bool) (opcode byte, data []byte, e error) {
 }
 
 func (c *Client) Send(data []byte, params ...bool) error {
-	doXor := true
-
 	// Should we skip the checksum?
-	if len(params) >= 1 && params[0] == false {
-		doXor = false
+	doXor := !(len(params) >= 1 && params[0] == false)
+	return c.send(data, doXor, sendqueue.CRITICAL)
+}
+
+// EnableCompression compresses the packets following the handshake with the options negotiated with the
+// client. It must be called by the goroutine receiving the packets, between two of them.
+func (c *Client) EnableCompression(options protocol.CompressionOptions) {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	c.compressor = protocol.NewCompressor(options)
+}
+
+// CompressionStats returns the compression metrics of the connection, nothing while it isn't compressed
+func (c *Client) CompressionStats() protocol.CompressionStats {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	if c.compressor == nil {
+		return protocol.CompressionStats{}
 	}
+	return c.compressor.Stats()
+}
+
+// SendPages sends the packets of a list split across several, in order, stopping at the first which fails
+func (c *Client) SendPages(pages [][]byte) error {
+	for _, page := range pages {
+		if err := c.send(page, true, sendqueue.CRITICAL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendWithPriority sends a packet the client could do without, like the broadcasts of the players
+// around, which the send queue sheds under saturation as its policy says
+func (c *Client) SendWithPriority(data []byte, priority sendqueue.Priority) error {
+	return c.send(data, true, priority)
+}
+
+// StartSendQueue makes the packets written by a goroutine of their own, queued up to capacity.
+// A client whose queue fills up is disconnected as a slow consumer, unless dropBroadcasts lets
+// the packets which aren't critical be dropped instead. The counters are shared by the clients of a server.
+func (c *Client) StartSendQueue(capacity int, policy sendqueue.Policy, dropBroadcasts bool, counters *sendqueue.Counters) {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	c.sendQueue.Store(sendqueue.New(c.Socket, capacity, policy, counters))
+	c.dropBroadcasts = dropBroadcasts
+}
+
+// SendQueue returns the send queue of the client, nil while the packets are written synchronously
+func (c *Client) SendQueue() *sendqueue.Queue {
+	return c.sendQueue.Load()
+}
+
+// CloseSend writes the packets still queued, waiting for them up to timeout, before the socket is closed
+func (c *Client) CloseSend(timeout time.Duration) error {
+	queue := c.SendQueue()
+	if queue == nil {
+		return nil
+	}
+	return queue.Close(timeout)
+}
 
-	if doXor == true {
-		// Do the encryption
-		xor.Encrypt(data, c.Cipher.OutputKey)
+func (c *Client) send(data []byte, doXor bool, priority sendqueue.Priority) error {
+	// The xor key changes with every packet, they must be sent one at a time
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	// The queue is checked before the encryption, a dropped packet mustn't advance the key
+	queue := c.sendQueue.Load()
+	if queue != nil {
+		if ok, err := c.admit(queue, priority); !ok {
+			return err
+		}
+	}
+
+	if doXor == true && c.compressor != nil {
+		compressed, err := c.compressor.Compress(data)
+
+		if err != nil {
+			return err
+		}
+		data = compressed
 	}
 
 	// Add the packet length
-	length := uint16(len(data) + 2)
-	header := packets.NewBuffer()
-	header.WriteUInt16(length)
+	writer := packets.NewPacketWriter()
+	defer writer.Release()
 
-	_, err := c.Socket.Write(header.Bytes())
+	frame, err := writer.B(data).Finalize()
 
 	if err != nil {
-		return errors.New("The packet header couldn't be sent.")
+		return err
+	}
+
+	if doXor == true && !c.Plain {
+		// Do the encryption, the length stays in clear text
+		xor.Encrypt(writer.Payload(), c.Cipher.OutputKey)
+	}
+
+	if queue != nil {
+		// The writer is released once the packet is queued, the queue keeps a copy
+		return queue.Push(bytes.Clone(frame))
 	}
 
-	_, err = c.Socket.Write(data)
+	_, err = c.Socket.Write(frame)
 
 	if err != nil {
-		return errors.New("The packet data couldn't be sent.")
+		return errors.New("The packet couldn't be sent.")
 	}
 
 	return nil
 }
+
+// admit reports whether a packet of some priority goes in the send queue. A packet the policy sheds is
+// dropped, and a full queue disconnects the slow client, or drops the packet if it isn't critical and
+// the client was told so.
+func (c *Client) admit(queue *sendqueue.Queue, priority sendqueue.Priority) (bool, error) {
+	err := queue.Admit(priority)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, sendqueue.ErrShed), errors.Is(err, sendqueue.ErrQueueFull) && priority != sendqueue.CRITICAL && c.dropBroadcasts:
+		queue.Drop(priority)
+		return false, nil
+	case !errors.Is(err, sendqueue.ErrQueueFull):
+		return false, err
+	}
+
+	// The slow consumer is disconnected, its reader sees the socket closed and the client leaves
+	if !c.slow {
+		c.slow = true
+		queue.Overflow()
+		c.Socket.Close()
+	}
+	return false, fmt.Errorf("%w: disconnected the slow client", sendqueue.ErrQueueFull)
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// GroundItem is an item lying in the world, waiting to be picked up
+type GroundItem struct {
+	ObjectID      uint32
+	ItemID        int
+	Count         uint64
+	X, Y, Z       int32
+	OwnerID       uint32    // Player the item is reserved to, 0 for anyone
+	ReservedUntil time.Time // Anyone can pick the item up past this time
+}
+
+// CanPickUp returns whether a player can pick the item up at the given time
+func (i *GroundItem) CanPickUp(playerID uint32, now time.Time) bool {
+	return i.OwnerID == 0 || i.OwnerID == playerID || !now.Before(i.ReservedUntil)
+}
@@ -0,0 +1,10 @@
+package models
+
+// Npc is a non playable character spawned in the world
+type Npc struct {
+	ObjectID   uint32
+	TemplateID int
+	Type       string // Picks the dialog handler, like "gatekeeper" or "merchant"
+	Name       string
+	X, Y, Z    int32
+}
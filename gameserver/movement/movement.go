@@ -0,0 +1,120 @@
+// Package movement checks the positions the clients report against how far their
+// player could have gone since the previous one, so a client can't make its player
+// run faster than it can or jump across the world
+package movement
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTooFast = errors.New("moved faster than possible")
+	ErrJumped  = errors.New("jumped too far")
+)
+
+// Limits are the thresholds of the positions the clients can report
+type Limits struct {
+	MaxSpeed  int // Units per second, 0 to trust the clients
+	Tolerance int // Distance a position can be off by, for the latency
+	MaxJump   int // Distance a position can't move by at once, whatever the time elapsed
+}
+
+// Position is a location of the world
+type Position struct {
+	X, Y, Z int32
+}
+
+// Distance returns the distance between two positions, ignoring the height
+func (p Position) Distance(other Position) int64 {
+	dx, dy := float64(p.X-other.X), float64(p.Y-other.Y)
+	return int64(math.Sqrt(dx*dx + dy*dy))
+}
+
+// Violations counts the positions refused for a player, for the anti-cheat reports
+type Violations struct {
+	TooFast uint64
+	Jumped  uint64
+}
+
+// Tracker validates the positions reported for a player. The player can only cover ground
+// while moving to a destination it asked for, at most at the speed limit.
+type Tracker struct {
+	limits     Limits
+	last       time.Time // When the previous position was accepted
+	moving     bool
+	dest       Position
+	violations Violations
+	mu         sync.Mutex
+}
+
+// NewTracker creates the tracker of a player standing still
+func NewTracker(limits Limits) *Tracker {
+	return &Tracker{limits: limits}
+}
+
+// Validate checks a position reported by the client against the position known by the server,
+// returning ErrTooFast or ErrJumped if it is refused. A refused position stops the player.
+func (t *Tracker) Validate(known, reported Position, now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.limits.MaxSpeed <= 0 {
+		return nil
+	}
+
+	reach := int64(t.limits.Tolerance)
+	if t.moving && !t.last.IsZero() {
+		reach += int64(now.Sub(t.last).Seconds() * float64(t.limits.MaxSpeed))
+	}
+
+	distance := known.Distance(reported)
+	var err error
+	switch {
+	case distance > int64(t.limits.MaxJump):
+		t.violations.Jumped++
+		err = fmt.Errorf("%w: %d units", ErrJumped, distance)
+	case distance > reach:
+		t.violations.TooFast++
+		err = fmt.Errorf("%w: %d units where %d were possible", ErrTooFast, distance, reach)
+	}
+
+	t.last = now
+	if err != nil {
+		t.moving = false
+		return err
+	}
+
+	// Once at its destination, the player stands still until its next move
+	if t.moving && reported.Distance(t.dest) <= int64(t.limits.Tolerance) {
+		t.moving = false
+	}
+	return nil
+}
+
+// MoveTo starts a move of the player towards a destination, from the last position accepted
+func (t *Tracker) MoveTo(dest Position, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.moving, t.dest, t.last = true, dest, now
+}
+
+// Stop makes the player stand still, as the server moved it
+func (t *Tracker) Stop(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.moving, t.last = false, now
+}
+
+// Violations returns the positions refused so far
+func (t *Tracker) Violations() Violations {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.violations
+}
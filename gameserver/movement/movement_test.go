@@ -0,0 +1,72 @@
+package movement
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	limits := Limits{MaxSpeed: 100, Tolerance: 50, MaxJump: 2000}
+	start := time.Now()
+
+	tests := []struct {
+		name     string
+		moving   bool
+		elapsed  time.Duration
+		reported Position
+		want     error
+	}{
+		{name: "standing within the tolerance", reported: Position{X: 30, Y: 30}},
+		{name: "standing but drifting", elapsed: 10 * time.Second, reported: Position{X: 200}, want: ErrTooFast},
+		{name: "moving at the speed limit", moving: true, elapsed: 2 * time.Second, reported: Position{X: 240}},
+		{name: "moving too fast", moving: true, elapsed: 2 * time.Second, reported: Position{Y: 400}, want: ErrTooFast},
+		{name: "jumping after a long walk", moving: true, elapsed: time.Minute, reported: Position{X: 2500}, want: ErrJumped},
+		{name: "the height doesn't count", reported: Position{Z: 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(limits)
+			tracker.Stop(start)
+			if tt.moving {
+				tracker.MoveTo(Position{X: 5000}, start)
+			}
+
+			err := tracker.Validate(Position{}, tt.reported, start.Add(tt.elapsed))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.want)
+			}
+
+			violations := tracker.Violations()
+			if got := violations.TooFast + violations.Jumped; (got > 0) != (tt.want != nil) {
+				t.Errorf("Violations() = %+v", violations)
+			}
+		})
+	}
+}
+
+func TestRefusedPositionStopsThePlayer(t *testing.T) {
+	tracker := NewTracker(Limits{MaxSpeed: 100, Tolerance: 50, MaxJump: 2000})
+	start := time.Now()
+
+	tracker.MoveTo(Position{X: 1000}, start)
+	if err := tracker.Validate(Position{}, Position{X: 900}, start.Add(time.Second)); !errors.Is(err, ErrTooFast) {
+		t.Fatalf("Validate() error = %v, want %v", err, ErrTooFast)
+	}
+
+	// Put back where it was, the player has to ask for a new move to cover ground
+	if err := tracker.Validate(Position{}, Position{X: 300}, start.Add(3*time.Second)); !errors.Is(err, ErrTooFast) {
+		t.Errorf("Validate() error = %v once stopped, want %v", err, ErrTooFast)
+	}
+	if violations := tracker.Violations(); violations.TooFast != 2 {
+		t.Errorf("Violations() = %+v", violations)
+	}
+}
+
+func TestTrustedClients(t *testing.T) {
+	tracker := NewTracker(Limits{})
+	if err := tracker.Validate(Position{}, Position{X: 100000}, time.Now()); err != nil {
+		t.Errorf("Validate() error = %v without a speed limit", err)
+	}
+}
@@ -0,0 +1,61 @@
+package gameserver
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/movement"
+	"github.com/frostwind/l2go/gameserver/sendqueue"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// movementLimits returns the thresholds the positions reported by the clients are checked against
+func (g *GameServer) movementLimits() movement.Limits {
+	options := g.config.GameServer.Options
+	return movement.Limits{
+		MaxSpeed:  options.MoveSpeedLimit(),
+		Tolerance: options.MoveToleranceDistance(),
+		MaxJump:   options.MoveJumpLimit(),
+	}
+}
+
+// MoveTo makes a player walk towards a destination, the players around seeing it. The origin the client
+// reported is validated first, the player setting off from where the server knows it stands if it is refused.
+func (g *GameServer) MoveTo(client *models.Client, origin, dest movement.Position) {
+	g.ValidatePosition(client, origin)
+	client.Movement.MoveTo(dest, g.clock.Now())
+
+	x, y, z := client.Position()
+	packet := serverpackets.NewMoveToLocationPacket(client.ObjectID, dest.X, dest.Y, dest.Z, x, y, z)
+	g.broadcastAround(x, y, sendqueue.NORMAL, packet)
+}
+
+// ValidatePosition moves a player where its client reports it stands, unless it couldn't have got there
+// since its previous position. A refused position counts as a violation of the player and the client is
+// put back where the server knows it stands. It returns whether the position was accepted.
+func (g *GameServer) ValidatePosition(client *models.Client, reported movement.Position) bool {
+	var known movement.Position
+	known.X, known.Y, known.Z = client.Position()
+	if err := client.Movement.Validate(known, reported, g.clock.Now()); err != nil {
+		atomic.AddUint32(&g.status.movementViolations, 1)
+		fmt.Printf("Refused the position of player %d: %v\n", client.ObjectID, err)
+
+		if err := client.Send(serverpackets.NewValidateLocationPacket(client.ObjectID, known.X, known.Y, known.Z, 0)); err != nil {
+			fmt.Println(err)
+		}
+		return false
+	}
+
+	if reported == known {
+		return true
+	}
+
+	client.SetPosition(reported.X, reported.Y, reported.Z)
+	g.markDirty(client)
+
+	if update, ok := g.interest.Move(client.ObjectID, reported.X, reported.Y, reported.Z); ok {
+		g.publish(update, nil)
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package gameserver
+
+import (
+	"github.com/frostwind/l2go/gameserver/ai"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/sendqueue"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// AI returns the scheduler running the behaviors of the NPCs
+func (g *GameServer) AI() *ai.Scheduler {
+	return g.ai
+}
+
+// SpawnNpcWithAI places an NPC in the world and gives it a behavior
+func (g *GameServer) SpawnNpcWithAI(npc *models.Npc, profile ai.Profile) uint32 {
+	objectID := g.SpawnNpc(npc)
+	g.ai.Add(npc, profile)
+	return objectID
+}
+
+// aiWorld is the world as seen by the NPCs
+type aiWorld struct {
+	g *GameServer
+}
+
+func (w aiWorld) Players() []ai.Target {
+	w.g.clientsMutex.Lock()
+	defer w.g.clientsMutex.Unlock()
+
+	players := make([]ai.Target, 0, len(w.g.clients))
+	for _, client := range w.g.clients {
+		x, y, z := client.Position()
+		players = append(players, ai.Target{ObjectID: client.ObjectID, X: x, Y: y, Z: z})
+	}
+	return players
+}
+
+func (w aiWorld) Walk(npc *models.Npc, x, y, z int32) {
+	w.g.npcsMutex.RLock()
+	origX, origY, origZ := npc.X, npc.Y, npc.Z
+	w.g.npcsMutex.RUnlock()
+
+	w.g.broadcastAround(origX, origY, sendqueue.NORMAL, serverpackets.NewMoveToLocationPacket(npc.ObjectID, x, y, z, origX, origY, origZ))
+}
+
+// Place moves the NPC on the server side only, the players around following its walk on their own
+func (w aiWorld) Place(npc *models.Npc, x, y, z int32) {
+	w.g.npcsMutex.Lock()
+	npc.X, npc.Y, npc.Z = x, y, z
+	w.g.npcsMutex.Unlock()
+
+	if update, ok := w.g.interest.Move(npc.ObjectID, x, y, z); ok {
+		w.g.publish(update, nil)
+	}
+}
+
+// Attack only keeps the NPC next to its target, there is no combat to hit it with yet, though
+// the player stops regenerating while it is attacked
+func (w aiWorld) Attack(npc *models.Npc, target ai.Target) {
+	if client, ok := w.g.Player(target.ObjectID); ok {
+		w.g.EnterCombat(client)
+	}
+}
@@ -0,0 +1,208 @@
+// Package persistence saves the characters of the players without a database write
+// for every action: a change only marks the character dirty, and the dirty characters
+// are written in batches once they waited for the save interval, when their player
+// logs out and when the game server shuts down
+package persistence
+
+import (
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/gameserver/repository"
+)
+
+// Stats counts the saves of the characters and the time their writes took
+type Stats struct {
+	Queued      int    // Dirty characters waiting for their save
+	Flushes     uint64 // Batches written
+	Saved       uint64 // Characters written
+	Failed      uint64 // Characters whose write failed, queued again
+	LastLatency time.Duration
+	MaxLatency  time.Duration
+}
+
+// Snapshot returns the character of an online player as it is now, false if it left
+type Snapshot func(objectID uint32) (repository.Character, bool)
+
+// Scheduler keeps the dirty characters, in the order they changed, until they are written
+type Scheduler struct {
+	repository repository.CharacterRepository
+	snapshot   Snapshot
+	clock      clock.Clock
+	interval   time.Duration
+	batch      int
+	dirty      map[uint32]time.Time // Since when each character waits, by object id
+	queue      []uint32             // The dirty characters, the oldest first
+	stats      Stats
+	mu         sync.Mutex
+	writeMu    sync.Mutex // A batch is written at a time, so an old snapshot never overwrites a newer one
+}
+
+// New creates a scheduler writing the characters to a repository, at most batch characters per tick
+// once they waited for the interval. An interval of 0 only saves them on logout and shutdown.
+func New(repo repository.CharacterRepository, snapshot Snapshot, c clock.Clock, interval time.Duration, batch int) *Scheduler {
+	return &Scheduler{
+		repository: repo,
+		snapshot:   snapshot,
+		clock:      c,
+		interval:   interval,
+		batch:      max(batch, 1),
+		dirty:      make(map[uint32]time.Time),
+	}
+}
+
+// MarkDirty queues the character of a player for its next save, keeping its place if already queued
+func (s *Scheduler) MarkDirty(objectID uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.dirty[objectID]; ok {
+		return
+	}
+	s.dirty[objectID] = s.clock.Now()
+	s.queue = append(s.queue, objectID)
+}
+
+// Tick writes the characters which waited for the interval, one batch at most
+func (s *Scheduler) Tick(now time.Time) error {
+	if s.interval <= 0 {
+		return nil
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.write(s.take(s.batch, now.Add(-s.interval)))
+}
+
+// Save writes the character of a player right away if it is dirty, as its player leaves
+func (s *Scheduler) Save(objectID uint32) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.mu.Lock()
+	since, ok := s.dirty[objectID]
+	if ok {
+		s.remove(objectID)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return s.write([]entry{{objectID, since}})
+}
+
+// Exclusive runs a write of the caller between two batches, so a batch snapshotting the
+// characters before the write can't overwrite it once it is done
+func (s *Scheduler) Exclusive(write func() error) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return write()
+}
+
+// Flush writes every dirty character, in batches, as the game server shuts down
+func (s *Scheduler) Flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	for {
+		taken := s.take(s.batch, time.Time{})
+		if len(taken) == 0 {
+			return nil
+		}
+		if err := s.write(taken); err != nil {
+			return err
+		}
+	}
+}
+
+// Stats returns the counters of the saves
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Queued = len(s.queue)
+	return stats
+}
+
+// entry is a character taken from the queue
+type entry struct {
+	objectID uint32
+	since    time.Time
+}
+
+// take dequeues up to limit characters dirty since before a time, any of them when the time is zero
+func (s *Scheduler) take(limit int, before time.Time) []entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var taken []entry
+	for len(s.queue) > 0 && len(taken) < limit {
+		objectID := s.queue[0]
+		since := s.dirty[objectID]
+		if !before.IsZero() && since.After(before) {
+			break
+		}
+
+		s.queue = s.queue[1:]
+		delete(s.dirty, objectID)
+		taken = append(taken, entry{objectID, since})
+	}
+	return taken
+}
+
+// write saves the characters taken from the queue, queuing them back in front if it fails.
+// The write lock is held.
+func (s *Scheduler) write(taken []entry) error {
+	characters := make([]repository.Character, 0, len(taken))
+	for _, e := range taken {
+		// The players which left were saved on their way out
+		if character, ok := s.snapshot(e.objectID); ok {
+			characters = append(characters, character)
+		}
+	}
+	if len(characters) == 0 {
+		return nil
+	}
+
+	began := s.clock.Now()
+	err := s.repository.Save(characters)
+	took := s.clock.Now().Sub(began)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Flushes++
+	s.stats.LastLatency = took
+	s.stats.MaxLatency = max(s.stats.MaxLatency, took)
+	if err != nil {
+		s.stats.Failed += uint64(len(characters))
+		var requeued []uint32
+		for _, e := range taken {
+			if _, ok := s.dirty[e.objectID]; !ok {
+				s.dirty[e.objectID] = e.since
+				requeued = append(requeued, e.objectID)
+			}
+		}
+		s.queue = append(requeued, s.queue...)
+		return err
+	}
+
+	s.stats.Saved += uint64(len(characters))
+	return nil
+}
+
+// remove takes a character out of the queue, the lock being held
+func (s *Scheduler) remove(objectID uint32) {
+	delete(s.dirty, objectID)
+	for i, queued := range s.queue {
+		if queued == objectID {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package persistence
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/gameserver/repository"
+)
+
+// fakeRepository records the batches it is given, failing while told to
+type fakeRepository struct {
+	repository.MemoryCharacterRepository
+	batches [][]string
+	fail    bool
+}
+
+func (r *fakeRepository) Save(characters []repository.Character) error {
+	if r.fail {
+		return errors.New("database unavailable")
+	}
+
+	var accounts []string
+	for _, character := range characters {
+		accounts = append(accounts, character.Account)
+	}
+	slices.Sort(accounts)
+	r.batches = append(r.batches, accounts)
+	return nil
+}
+
+// online answers the snapshots of the players 1 to 9, named after their object id
+func online(objectID uint32) (repository.Character, bool) {
+	if objectID < 1 || objectID > 9 {
+		return repository.Character{}, false
+	}
+	return repository.Character{Account: string(rune('a' + objectID - 1))}, true
+}
+
+func TestTick(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	repo := &fakeRepository{}
+	s := New(repo, online, fake, time.Minute, 2)
+
+	s.MarkDirty(1)
+	s.MarkDirty(2)
+	s.MarkDirty(1)
+	fake.Advance(30 * time.Second)
+	s.MarkDirty(3)
+
+	// Nothing waited for the interval yet
+	if err := s.Tick(fake.Now()); err != nil || len(repo.batches) != 0 {
+		t.Fatalf("Tick() = %v, wrote %v", err, repo.batches)
+	}
+
+	fake.Advance(30 * time.Second)
+	if err := s.Tick(fake.Now()); err != nil || !slices.Equal(repo.batches[0], []string{"a", "b"}) {
+		t.Fatalf("Tick() = %v, wrote %v", err, repo.batches)
+	}
+	if stats := s.Stats(); stats.Queued != 1 || stats.Flushes != 1 || stats.Saved != 2 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+
+	// The third one is due 30 seconds later
+	if err := s.Tick(fake.Now()); err != nil || len(repo.batches) != 1 {
+		t.Fatalf("Tick() = %v, wrote %v", err, repo.batches)
+	}
+	fake.Advance(30 * time.Second)
+	if err := s.Tick(fake.Now()); err != nil || len(repo.batches) != 2 || !slices.Equal(repo.batches[1], []string{"c"}) {
+		t.Errorf("Tick() = %v, wrote %v", err, repo.batches)
+	}
+}
+
+func TestSaveAndFlush(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	repo := &fakeRepository{}
+	s := New(repo, online, fake, 0, 2)
+
+	for objectID := uint32(1); objectID <= 5; objectID++ {
+		s.MarkDirty(objectID)
+	}
+
+	// Without an interval, the characters wait for their player to leave or the server to stop
+	if err := s.Tick(fake.Now().Add(time.Hour)); err != nil || len(repo.batches) != 0 {
+		t.Fatalf("Tick() = %v, wrote %v", err, repo.batches)
+	}
+
+	if err := s.Save(3); err != nil || !slices.Equal(repo.batches[0], []string{"c"}) {
+		t.Fatalf("Save() = %v, wrote %v", err, repo.batches)
+	}
+	if err := s.Save(3); err != nil || len(repo.batches) != 1 {
+		t.Errorf("Save() of a clean character = %v, wrote %v", err, repo.batches)
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	want := [][]string{{"c"}, {"a", "b"}, {"d", "e"}}
+	if !slices.EqualFunc(repo.batches, want, slices.Equal) {
+		t.Errorf("wrote %v, want %v", repo.batches, want)
+	}
+	if stats := s.Stats(); stats.Queued != 0 || stats.Flushes != 3 || stats.Saved != 5 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestFailedWritesAreQueuedAgain(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	repo := &fakeRepository{fail: true}
+	s := New(repo, online, fake, time.Minute, 10)
+
+	s.MarkDirty(1)
+	s.MarkDirty(2)
+	fake.Advance(time.Minute)
+	s.MarkDirty(3)
+
+	if err := s.Tick(fake.Now()); err == nil {
+		t.Fatal("Tick() = nil while the database is unavailable")
+	}
+	if stats := s.Stats(); stats.Queued != 3 || stats.Failed != 2 || stats.Saved != 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+
+	// The characters keep their place in front of the queue and are written once the database is back
+	repo.fail = false
+	if err := s.Tick(fake.Now()); err != nil || len(repo.batches) != 1 || !slices.Equal(repo.batches[0], []string{"a", "b"}) {
+		t.Errorf("Tick() = %v, wrote %v", err, repo.batches)
+	}
+}
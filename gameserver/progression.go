@@ -0,0 +1,71 @@
+package gameserver
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// Player returns the player of the world with the given object id
+func (g *GameServer) Player(objectID uint32) (*models.Client, bool) {
+	g.clientsMutex.Lock()
+	defer g.clientsMutex.Unlock()
+
+	client, ok := g.players[objectID]
+	return client, ok
+}
+
+// AddExp gives experience to a player, the players around seeing it glow when it levels up
+func (g *GameServer) AddExp(client *models.Client, exp uint64) {
+	g.progressMutex.Lock()
+	previous := client.Level
+	client.Exp = g.experience.Cap(client.Exp + min(exp, math.MaxUint64-client.Exp))
+	client.Level = g.experience.LevelOf(client.Exp)
+	level, total := client.Level, client.Exp
+	g.progressMutex.Unlock()
+	g.markDirty(client)
+
+	g.sendProgress(client, level, total)
+
+	if level > previous {
+		fmt.Printf("Player %d reached the level %d\n", client.ObjectID, level)
+		x, y, _ := client.Position()
+		g.broadcastSocial(x, y, serverpackets.NewSocialActionPacket(client.ObjectID, serverpackets.SOCIAL_ACTION_LEVEL_UP))
+		g.updateLoad(client)
+	}
+}
+
+// ApplyDeathPenalty takes the experience a player loses when dying, which can make it lose
+// a level, and returns how much was taken
+func (g *GameServer) ApplyDeathPenalty(client *models.Client) uint64 {
+	g.progressMutex.Lock()
+	previous := client.Level
+	lost := min(g.experience.DeathPenalty(client.Level, g.config.GameServer.Options.DeathPenaltyPercent()), client.Exp)
+	client.Exp -= lost
+	client.Level = g.experience.LevelOf(client.Exp)
+	level, total := client.Level, client.Exp
+	g.progressMutex.Unlock()
+
+	if lost > 0 {
+		g.markDirty(client)
+		g.sendProgress(client, level, total)
+	}
+	if level != previous {
+		g.updateLoad(client)
+	}
+	return lost
+}
+
+// sendProgress tells a player its level and experience
+func (g *GameServer) sendProgress(client *models.Client, level int, exp uint64) {
+	packet := serverpackets.NewStatusUpdatePacket(client.ObjectID,
+		serverpackets.StatusAttribute{ID: serverpackets.STATUS_LEVEL, Value: uint32(level)},
+		serverpackets.StatusAttribute{ID: serverpackets.STATUS_EXP, Value: uint32(min(exp, math.MaxUint32))},
+	)
+
+	if err := client.Send(packet); err != nil {
+		fmt.Println(err)
+	}
+}
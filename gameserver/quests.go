@@ -0,0 +1,166 @@
+package gameserver
+
+import (
+	"fmt"
+
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/quests"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// Quests returns the quests of the world, so quests written in Go can be added along with their conditions
+func (g *GameServer) Quests() *quests.Quests {
+	return g.quests
+}
+
+// GiveItem adds items to the inventory of a player, as the quest rewards do, whether it can carry them or not
+func (g *GameServer) GiveItem(client *models.Client, itemID int, count uint64) {
+	g.itemsMutex.Lock()
+	g.give(client, itemID, count)
+	g.itemsMutex.Unlock()
+
+	g.updateLoad(client)
+}
+
+// TalkQuest answers a player asking an NPC about a quest: the NPC giving the quest starts it,
+// the NPC of its current step moves it on, and the reward is given once the last step is done.
+// The player is shown where it is in the quest, or why nothing happened, then its quest list.
+// Only the players in the world take part in quests.
+func (g *GameServer) TalkQuest(client *models.Client, npc *models.Npc, questID int) error {
+	if !g.playing(client) {
+		return ErrNotInWorld
+	}
+	quest, ok := g.quests.Quest(questID)
+	if !ok {
+		return fmt.Errorf("%w: %d", quests.ErrUnknownQuest, questID)
+	}
+
+	g.questsMutex.Lock()
+	defer g.questsMutex.Unlock()
+
+	state, started, err := g.questState(client, questID)
+	if err != nil {
+		return err
+	}
+
+	if !started && npc.TemplateID != quest.StartNpc {
+		err = fmt.Errorf("%w: the quest %d is given by the NPC %d", quests.ErrWrongNpc, questID, quest.StartNpc)
+	} else if !started {
+		state, err = quest.Start(client)
+	} else {
+		err = quest.Talk(&state, npc.TemplateID)
+	}
+
+	if err == nil {
+		err = g.updateQuest(client, quest, state)
+	}
+
+	var text string
+	if err != nil {
+		text = quest.Name + ": " + err.Error()
+	} else {
+		text = quest.Text(&state)
+	}
+	if err := client.Send(serverpackets.NewNpcHtmlMessagePacket(npc.ObjectID, "<html><body>"+text+"</body></html>")); err != nil {
+		fmt.Println(err)
+	}
+	g.sendQuestList(client)
+	return err
+}
+
+// AbortQuest abandons a quest of a player in the world, which can start it again, and sends it its quest list
+func (g *GameServer) AbortQuest(client *models.Client, questID int) error {
+	if !g.playing(client) {
+		return ErrNotInWorld
+	}
+	g.questsMutex.Lock()
+	err := g.questRepository.Remove(client.Account, questID)
+	g.questsMutex.Unlock()
+
+	g.sendQuestList(client)
+	return err
+}
+
+// countKill counts the kill of an NPC for the quests of its killer, which gets its quest list when one moved on
+func (g *GameServer) countKill(client *models.Client, npc *models.Npc) {
+	g.questsMutex.Lock()
+	states, err := g.questRepository.List(client.Account)
+	if err != nil {
+		g.questsMutex.Unlock()
+		fmt.Printf("Couldn't list the quests of %s: %v\n", client.Account, err)
+		return
+	}
+
+	changed := false
+	for _, state := range states {
+		quest, ok := g.quests.Quest(state.QuestID)
+		if !ok || !quest.Kill(&state, npc.TemplateID) {
+			continue
+		}
+
+		if err := g.updateQuest(client, quest, state); err != nil {
+			fmt.Printf("Couldn't save the quest %d of %s: %v\n", state.QuestID, client.Account, err)
+			continue
+		}
+		changed = true
+	}
+	g.questsMutex.Unlock()
+
+	if changed {
+		g.sendQuestList(client)
+	}
+}
+
+// updateQuest saves the state of a quest of a player, giving the reward once it is completed,
+// the quests mutex being held
+func (g *GameServer) updateQuest(client *models.Client, quest *quests.Quest, state quests.State) error {
+	if err := g.questRepository.Save(client.Account, state); err != nil {
+		return err
+	}
+
+	if state.Completed {
+		fmt.Printf("Player %d completed the quest %d\n", client.ObjectID, quest.ID)
+		quest.Reward.Give(g, client)
+	}
+	return nil
+}
+
+// questState returns where a player is in a quest, false if it didn't start it
+func (g *GameServer) questState(client *models.Client, questID int) (quests.State, bool, error) {
+	states, err := g.questRepository.List(client.Account)
+	if err != nil {
+		return quests.State{}, false, err
+	}
+
+	for _, state := range states {
+		if state.QuestID == questID {
+			return state, true, nil
+		}
+	}
+	return quests.State{}, false, nil
+}
+
+// QuestStates returns where a player is in the quests it started, the completed ones included
+func (g *GameServer) QuestStates(client *models.Client) ([]quests.State, error) {
+	return g.questRepository.List(client.Account)
+}
+
+// sendQuestList sends a player the quests it is doing, the completed ones left out
+func (g *GameServer) sendQuestList(client *models.Client) {
+	states, err := g.QuestStates(client)
+	if err != nil {
+		fmt.Printf("Couldn't list the quests of %s: %v\n", client.Account, err)
+		return
+	}
+
+	list := make([]serverpackets.Quest, 0, len(states))
+	for _, state := range states {
+		if !state.Completed {
+			list = append(list, serverpackets.Quest{ID: uint32(state.QuestID), Cond: uint32(state.Cond)})
+		}
+	}
+
+	if err := client.Send(serverpackets.NewQuestListPacket(list)); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,257 @@
+// Package quests holds the quests the NPCs give, read from a data file, and moves the
+// quests of the players through their steps: a quest starts by talking to the NPC
+// giving it, each step then asks to talk to an NPC or to kill a number of NPCs, and
+// the reward is given once the last step is done.
+package quests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/frostwind/l2go/gameserver/drops"
+	"github.com/frostwind/l2go/gameserver/models"
+)
+
+var (
+	ErrUnknownQuest   = errors.New("unknown quest")
+	ErrDuplicateQuest = errors.New("duplicate quest")
+	ErrInvalidQuest   = errors.New("invalid quest")
+	ErrWrongNpc       = errors.New("the NPC has nothing to say about the quest")
+	ErrLevelTooLow    = errors.New("the level of the player is too low for the quest")
+	ErrQuestCompleted = errors.New("the quest is already completed")
+)
+
+// Step is something a quest asks for: talking to an NPC, or killing Count NPCs of a template
+type Step struct {
+	Text  string `json:"text"`            // Shown while the step isn't done
+	Npc   int    `json:"npc,omitempty"`   // Template of the NPC to talk to
+	Kill  int    `json:"kill,omitempty"`  // Template of the NPCs to kill
+	Count int    `json:"count,omitempty"` // NPCs to kill, 1 when not set
+}
+
+// Item is an item given by a reward
+type Item struct {
+	ItemID int    `json:"itemId"`
+	Count  uint64 `json:"count"`
+}
+
+// Reward is what a player gets for completing a quest
+type Reward struct {
+	Exp   uint64 `json:"exp"`
+	Adena uint64 `json:"adena"`
+	Items []Item `json:"items"`
+}
+
+// Rewarder gives the rewards to the players, the game server
+type Rewarder interface {
+	AddExp(client *models.Client, exp uint64)
+	GiveItem(client *models.Client, itemID int, count uint64)
+}
+
+// Give gives the reward to a player
+func (r Reward) Give(rewarder Rewarder, client *models.Client) {
+	if r.Exp > 0 {
+		rewarder.AddExp(client, r.Exp)
+	}
+	if r.Adena > 0 {
+		rewarder.GiveItem(client, drops.ADENA_ID, r.Adena)
+	}
+	for _, item := range r.Items {
+		rewarder.GiveItem(client, item.ItemID, item.Count)
+	}
+}
+
+// Condition tells whether a player can start a quest, or why not
+type Condition func(client *models.Client) error
+
+// Quest is a quest given by the NPCs of a template
+type Quest struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	StartNpc int    `json:"startNpc"` // Template of the NPCs giving the quest
+	MinLevel int    `json:"minLevel"`
+	Steps    []Step `json:"steps"`
+	Reward   Reward `json:"reward"`
+
+	conditions []Condition
+}
+
+// Validate checks the quest can be started and completed
+func (q *Quest) Validate() error {
+	if q.ID <= 0 || q.StartNpc == 0 {
+		return fmt.Errorf("%w: the quest %d needs an id and an NPC giving it", ErrInvalidQuest, q.ID)
+	}
+	if len(q.Steps) == 0 {
+		return fmt.Errorf("%w: the quest %d has no step", ErrInvalidQuest, q.ID)
+	}
+	for i, step := range q.Steps {
+		if (step.Npc == 0) == (step.Kill == 0) {
+			return fmt.Errorf("%w: the step %d of the quest %d must either talk to or kill an NPC", ErrInvalidQuest, i+1, q.ID)
+		}
+	}
+	return nil
+}
+
+// State is where a player is in a quest
+type State struct {
+	QuestID   int
+	Cond      int // The step the player is at, from 1
+	Progress  int // NPCs killed for the current step
+	Completed bool
+}
+
+// step returns the step a state is at
+func (q *Quest) step(state *State) Step {
+	return q.Steps[state.Cond-1]
+}
+
+// Start checks a player can start the quest, returning the state of the quest at its first step
+func (q *Quest) Start(client *models.Client) (State, error) {
+	if client.Level < q.MinLevel {
+		return State{}, fmt.Errorf("%w: %d, the quest %d needs %d", ErrLevelTooLow, client.Level, q.ID, q.MinLevel)
+	}
+	for _, condition := range q.conditions {
+		if err := condition(client); err != nil {
+			return State{}, err
+		}
+	}
+	return State{QuestID: q.ID, Cond: 1}, nil
+}
+
+// Talk moves the quest past a step asking to talk to the NPC of the given template,
+// or returns ErrWrongNpc if the step asks for something else
+func (q *Quest) Talk(state *State, npcID int) error {
+	if state.Completed {
+		return fmt.Errorf("%w: %d", ErrQuestCompleted, q.ID)
+	}
+
+	step := q.step(state)
+	if step.Npc != npcID {
+		return fmt.Errorf("%w: %d at the step %d of the quest %d", ErrWrongNpc, npcID, state.Cond, q.ID)
+	}
+
+	q.advance(state)
+	return nil
+}
+
+// Kill counts the kill of an NPC of the given template, moving the quest past a step once
+// it killed enough of them. It tells whether the state changed.
+func (q *Quest) Kill(state *State, npcID int) bool {
+	if state.Completed {
+		return false
+	}
+
+	step := q.step(state)
+	if step.Kill == 0 || step.Kill != npcID {
+		return false
+	}
+
+	state.Progress++
+	if state.Progress >= max(step.Count, 1) {
+		q.advance(state)
+	}
+	return true
+}
+
+// advance moves a state to the next step, completing the quest after the last one
+func (q *Quest) advance(state *State) {
+	state.Progress = 0
+	if state.Cond == len(q.Steps) {
+		state.Completed = true
+		return
+	}
+	state.Cond++
+}
+
+// Text returns what a player is told about its quest
+func (q *Quest) Text(state *State) string {
+	if state.Completed {
+		return fmt.Sprintf("%s: completed", q.Name)
+	}
+
+	step := q.step(state)
+	if step.Kill != 0 {
+		return fmt.Sprintf("%s: %s (%d/%d)", q.Name, step.Text, state.Progress, max(step.Count, 1))
+	}
+	return fmt.Sprintf("%s: %s", q.Name, step.Text)
+}
+
+// Quests holds the quests of the world, by id
+type Quests struct {
+	quests map[int]*Quest
+	mu     sync.RWMutex
+}
+
+// New returns an empty set of quests
+func New() *Quests {
+	return &Quests{quests: make(map[int]*Quest)}
+}
+
+// Parse reads the quests, a JSON array of Quest
+func Parse(r io.Reader) (*Quests, error) {
+	var list []*Quest
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	quests := New()
+	for _, quest := range list {
+		if err := quests.Add(quest); err != nil {
+			return nil, err
+		}
+	}
+	return quests, nil
+}
+
+// Load reads the quests from a file
+func Load(path string) (*Quests, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Add adds a quest, for the quests written in Go
+func (q *Quests) Add(quest *Quest) error {
+	if err := quest.Validate(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.quests[quest.ID]; ok {
+		return fmt.Errorf("%w: %d", ErrDuplicateQuest, quest.ID)
+	}
+	q.quests[quest.ID] = quest
+	return nil
+}
+
+// AddCondition adds a condition the players must meet to start a quest, beyond its level
+func (q *Quests) AddCondition(questID int, condition Condition) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	quest, ok := q.quests[questID]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownQuest, questID)
+	}
+	quest.conditions = append(quest.conditions, condition)
+	return nil
+}
+
+// Quest returns the quest with the given id
+func (q *Quests) Quest(id int) (*Quest, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	quest, ok := q.quests[id]
+	return quest, ok
+}
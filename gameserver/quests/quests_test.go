@@ -0,0 +1,109 @@
+package quests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/frostwind/l2go/gameserver/models"
+)
+
+const wolves = `[{"id": 1, "name": "Wolves", "startNpc": 30001, "minLevel": 2, "steps": [
+	{"text": "Kill 2 wolves", "kill": 20120, "count": 2},
+	{"text": "Report to the guard", "npc": 30002}
+], "reward": {"exp": 100, "adena": 50, "items": [{"itemId": 1864, "count": 3}]}}]`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		quests string
+		want   error
+	}{
+		{"valid", wolves, nil},
+		{"no step", `[{"id": 1, "startNpc": 30001, "steps": []}]`, ErrInvalidQuest},
+		{"no giver", `[{"id": 1, "steps": [{"npc": 30002}]}]`, ErrInvalidQuest},
+		{"talk and kill", `[{"id": 1, "startNpc": 30001, "steps": [{"npc": 30002, "kill": 20120}]}]`, ErrInvalidQuest},
+		{"duplicate", `[{"id": 1, "startNpc": 30001, "steps": [{"npc": 30002}]}, {"id": 1, "startNpc": 30001, "steps": [{"npc": 30002}]}]`, ErrDuplicateQuest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.quests)); !errors.Is(err, tt.want) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// rewarder records the rewards given
+type rewarder struct {
+	exp   uint64
+	items map[int]uint64
+}
+
+func (r *rewarder) AddExp(client *models.Client, exp uint64) { r.exp += exp }
+func (r *rewarder) GiveItem(client *models.Client, itemID int, count uint64) {
+	r.items[itemID] += count
+}
+
+func TestQuestSteps(t *testing.T) {
+	quests, err := Parse(strings.NewReader(wolves))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	quest, _ := quests.Quest(1)
+
+	client := models.NewClient()
+	client.Level = 1
+	if _, err := quest.Start(client); !errors.Is(err, ErrLevelTooLow) {
+		t.Fatalf("Start() error = %v, want %v", err, ErrLevelTooLow)
+	}
+
+	errNoClan := errors.New("no clan")
+	quests.AddCondition(1, func(client *models.Client) error {
+		if client.Account != "clanned" {
+			return errNoClan
+		}
+		return nil
+	})
+	client.Level = 2
+	if _, err := quest.Start(client); !errors.Is(err, errNoClan) {
+		t.Fatalf("Start() error = %v, want %v", err, errNoClan)
+	}
+
+	client.Account = "clanned"
+	state, err := quest.Start(client)
+	if err != nil || state != (State{QuestID: 1, Cond: 1}) {
+		t.Fatalf("Start() = %+v, %v", state, err)
+	}
+
+	// The guard has nothing to say until the wolves are dead
+	if err := quest.Talk(&state, 30002); !errors.Is(err, ErrWrongNpc) {
+		t.Fatalf("Talk() error = %v, want %v", err, ErrWrongNpc)
+	}
+	if quest.Kill(&state, 20001) {
+		t.Fatal("Kill() counted another NPC")
+	}
+	if !quest.Kill(&state, 20120) || state.Cond != 1 || state.Progress != 1 {
+		t.Fatalf("state = %+v after a kill", state)
+	}
+	if text := quest.Text(&state); text != "Wolves: Kill 2 wolves (1/2)" {
+		t.Errorf("Text() = %q", text)
+	}
+	if !quest.Kill(&state, 20120) || state.Cond != 2 || state.Progress != 0 {
+		t.Fatalf("state = %+v after two kills", state)
+	}
+
+	if err := quest.Talk(&state, 30002); err != nil || !state.Completed {
+		t.Fatalf("Talk() error = %v, state = %+v", err, state)
+	}
+	if err := quest.Talk(&state, 30002); !errors.Is(err, ErrQuestCompleted) {
+		t.Errorf("Talk() error = %v once completed, want %v", err, ErrQuestCompleted)
+	}
+
+	r := &rewarder{items: make(map[int]uint64)}
+	quest.Reward.Give(r, client)
+	if r.exp != 100 || r.items[57] != 50 || r.items[1864] != 3 {
+		t.Errorf("reward = %d exp and %v", r.exp, r.items)
+	}
+}
@@ -0,0 +1,78 @@
+package gameserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/gameserver/vitals"
+)
+
+// statusOfVital is the attribute of the StatusUpdate packet giving the current points of each vital
+var statusOfVital = [vitals.COUNT]uint32{
+	vitals.HP: serverpackets.STATUS_CUR_HP,
+	vitals.MP: serverpackets.STATUS_CUR_MP,
+	vitals.CP: serverpackets.STATUS_CUR_CP,
+}
+
+// currentVitals returns the current points of a player, under the progress mutex
+func currentVitals(client *models.Client) vitals.Values {
+	return vitals.Values{vitals.HP: client.HP, vitals.MP: client.MP, vitals.CP: client.CP}
+}
+
+// maxVitals returns the maximum points of a player, under the progress mutex
+func maxVitals(client *models.Client) vitals.Values {
+	return vitals.Values{vitals.HP: client.MaxHP, vitals.MP: client.MaxMP, vitals.CP: client.MaxCP}
+}
+
+// setClass makes a player regenerate at the rates of a class
+func (g *GameServer) setClass(client *models.Client, classID int) {
+	g.progressMutex.Lock()
+	client.ClassID = classID
+	g.progressMutex.Unlock()
+
+	if client.Vitals != nil {
+		client.Vitals.SetRates(g.vitals.For(classID))
+	}
+}
+
+// EnterCombat stops the regeneration of a player until vitals.COMBAT_PAUSE from now
+func (g *GameServer) EnterCombat(client *models.Client) {
+	if client.Vitals != nil {
+		client.Vitals.EnterCombat()
+	}
+}
+
+// regenerate gives the players in the world the points they regained since the last tick, only telling
+// their clients once the points add up
+func (g *GameServer) regenerate(now time.Time) {
+	for _, client := range g.authenticated() {
+		if client.Vitals == nil {
+			continue
+		}
+
+		g.progressMutex.Lock()
+		current, maximum := currentVitals(client), maxVitals(client)
+		regenerated := client.Vitals.Regenerate(now, current, maximum, client.Sitting)
+		client.HP, client.MP, client.CP = regenerated[vitals.HP], regenerated[vitals.MP], regenerated[vitals.CP]
+		changed := client.Vitals.Report(regenerated, maximum)
+		g.progressMutex.Unlock()
+
+		// Only the HP are stored
+		if regenerated[vitals.HP] != current[vitals.HP] {
+			g.markDirty(client)
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		attributes := make([]serverpackets.StatusAttribute, 0, len(changed))
+		for _, vital := range changed {
+			attributes = append(attributes, serverpackets.StatusAttribute{ID: statusOfVital[vital], Value: uint32(regenerated[vital])})
+		}
+		if err := client.Send(serverpackets.NewStatusUpdatePacket(client.ObjectID, attributes...)); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
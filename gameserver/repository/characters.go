@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"database/sql"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Character is what a player keeps from a session to the next
+type Character struct {
+	Account    string
+	Level      int
+	Exp        uint64
+	HP         int
+	Adena      uint64
+	X, Y, Z    int32
+	Items      map[int]uint64 // Counts of the items other than the adena, by item id
+	Karma      int
+	Clan       string      // Id of the clan of the character, "" for none
+	Paperdoll  map[int]int // Item ids worn, by paperdoll slot, one of the serverpackets PAPERDOLL_ ones
+	LastAccess time.Time   // When the character was saved last, set by the repository
+}
+
+// CharacterRepository keeps the progress, the inventory and the location of the players.
+// Characters aren't stored yet, so they are kept by account.
+type CharacterRepository interface {
+	// Save writes a batch of characters at once
+	Save(characters []Character) error
+
+	// Load returns the character kept for an account, false if it has none
+	Load(account string) (Character, bool, error)
+
+	// Close releases the underlying resources
+	Close() error
+}
+
+// MySQLCharacterRepository stores characters in the character_states, character_items and
+// character_paperdolls tables
+type MySQLCharacterRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLCharacterRepository creates a repository backed by db
+func NewMySQLCharacterRepository(db *sql.DB) *MySQLCharacterRepository {
+	return &MySQLCharacterRepository{db: db}
+}
+
+func (r *MySQLCharacterRepository) Save(characters []Character) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, character := range characters {
+		if err := writeCharacter(tx, character); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// writeCharacter writes the state and the items of a character within a transaction
+func writeCharacter(tx *sql.Tx, character Character) error {
+	// updated_at is set even when nothing changed, being when the character was saved last
+	_, err := tx.Exec("INSERT INTO character_states (account, level, experience, hp, adena, x, y, z, karma, clan) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE level = VALUES(level), experience = VALUES(experience), hp = VALUES(hp), adena = VALUES(adena), x = VALUES(x), y = VALUES(y), z = VALUES(z), "+
+		"karma = VALUES(karma), clan = VALUES(clan), updated_at = CURRENT_TIMESTAMP",
+		character.Account, character.Level, character.Exp, character.HP, character.Adena, character.X, character.Y, character.Z, character.Karma, character.Clan)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM character_items WHERE account = ?", character.Account); err != nil {
+		return err
+	}
+	for itemID, count := range character.Items {
+		_, err := tx.Exec("INSERT INTO character_items (account, item_id, count) VALUES (?, ?, ?)", character.Account, itemID, count)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM character_paperdolls WHERE account = ?", character.Account); err != nil {
+		return err
+	}
+	for slot, itemID := range character.Paperdoll {
+		_, err := tx.Exec("INSERT INTO character_paperdolls (account, slot, item_id) VALUES (?, ?, ?)", character.Account, slot, itemID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MySQLCharacterRepository) Load(account string) (Character, bool, error) {
+	character := Character{Account: account}
+	var lastAccess int64
+	err := r.db.QueryRow("SELECT level, experience, hp, adena, x, y, z, karma, clan, UNIX_TIMESTAMP(updated_at) FROM character_states WHERE account = ?", account).
+		Scan(&character.Level, &character.Exp, &character.HP, &character.Adena, &character.X, &character.Y, &character.Z, &character.Karma, &character.Clan, &lastAccess)
+	if err == sql.ErrNoRows {
+		return Character{}, false, nil
+	}
+	if err != nil {
+		return Character{}, false, err
+	}
+	character.LastAccess = time.Unix(lastAccess, 0)
+
+	paperdoll, err := r.db.Query("SELECT slot, item_id FROM character_paperdolls WHERE account = ?", account)
+	if err != nil {
+		return Character{}, false, err
+	}
+	defer paperdoll.Close()
+
+	for paperdoll.Next() {
+		var slot, itemID int
+		if err := paperdoll.Scan(&slot, &itemID); err != nil {
+			return Character{}, false, err
+		}
+
+		if character.Paperdoll == nil {
+			character.Paperdoll = make(map[int]int)
+		}
+		character.Paperdoll[slot] = itemID
+	}
+	if err := paperdoll.Err(); err != nil {
+		return Character{}, false, err
+	}
+
+	rows, err := r.db.Query("SELECT item_id, count FROM character_items WHERE account = ?", account)
+	if err != nil {
+		return Character{}, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var itemID int
+		var count uint64
+		if err := rows.Scan(&itemID, &count); err != nil {
+			return Character{}, false, err
+		}
+
+		if character.Items == nil {
+			character.Items = make(map[int]uint64)
+		}
+		character.Items[itemID] = count
+	}
+
+	return character, true, rows.Err()
+}
+
+func (r *MySQLCharacterRepository) Close() error {
+	return r.db.Close()
+}
+
+// MemoryCharacterRepository keeps characters in memory, mostly for tests and local runs
+type MemoryCharacterRepository struct {
+	characters map[string]Character
+	mu         sync.RWMutex
+}
+
+// NewMemoryCharacterRepository creates an empty in-memory repository
+func NewMemoryCharacterRepository() *MemoryCharacterRepository {
+	return &MemoryCharacterRepository{characters: make(map[string]Character)}
+}
+
+func (r *MemoryCharacterRepository) Save(characters []Character) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, character := range characters {
+		character.Items = maps.Clone(character.Items)
+		character.Paperdoll = maps.Clone(character.Paperdoll)
+		character.LastAccess = now
+		r.characters[strings.ToLower(character.Account)] = character
+	}
+
+	return nil
+}
+
+func (r *MemoryCharacterRepository) Load(account string) (Character, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	character, ok := r.characters[strings.ToLower(account)]
+	character.Items = maps.Clone(character.Items)
+	character.Paperdoll = maps.Clone(character.Paperdoll)
+	return character, ok, nil
+}
+
+func (r *MemoryCharacterRepository) Close() error {
+	return nil
+}
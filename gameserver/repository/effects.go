@@ -0,0 +1,128 @@
+// Package repository holds the storage backends of the game server
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/effects"
+)
+
+// SavedEffect is an effect kept while its player is away, along with the time it still lasts
+type SavedEffect struct {
+	effects.Effect
+	Remaining time.Duration // Negative for an effect lasting until it is removed
+}
+
+// EffectRepository keeps the effects of the players across their sessions.
+// Characters aren't stored yet, so the effects are kept by account.
+type EffectRepository interface {
+	// Save replaces the effects kept for an account
+	Save(account string, saved []SavedEffect) error
+
+	// Load returns the effects kept for an account
+	Load(account string) ([]SavedEffect, error)
+
+	// Close releases the underlying resources
+	Close() error
+}
+
+// MySQLEffectRepository stores effects in the character_effects table
+type MySQLEffectRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLEffectRepository creates a repository backed by db
+func NewMySQLEffectRepository(db *sql.DB) *MySQLEffectRepository {
+	return &MySQLEffectRepository{db: db}
+}
+
+func (r *MySQLEffectRepository) Save(account string, saved []SavedEffect) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM character_effects WHERE account = ?", account); err != nil {
+		return err
+	}
+
+	for _, effect := range saved {
+		_, err := tx.Exec("INSERT INTO character_effects (account, skill_id, level, kind, stack_type, duration_ms, remaining_ms, tick_ms, tick_hp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			account, effect.SkillID, effect.Level, effect.Kind, effect.StackType,
+			effect.Duration.Milliseconds(), effect.Remaining.Milliseconds(), effect.Tick.Milliseconds(), effect.TickHP)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *MySQLEffectRepository) Load(account string) ([]SavedEffect, error) {
+	rows, err := r.db.Query("SELECT skill_id, level, kind, stack_type, duration_ms, remaining_ms, tick_ms, tick_hp FROM character_effects WHERE account = ? ORDER BY id", account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var saved []SavedEffect
+	for rows.Next() {
+		var effect SavedEffect
+		var duration, remaining, tick int64
+		err := rows.Scan(&effect.SkillID, &effect.Level, &effect.Kind, &effect.StackType, &duration, &remaining, &tick, &effect.TickHP)
+		if err != nil {
+			return nil, err
+		}
+
+		effect.Duration = time.Duration(duration) * time.Millisecond
+		effect.Remaining = time.Duration(remaining) * time.Millisecond
+		effect.Tick = time.Duration(tick) * time.Millisecond
+		saved = append(saved, effect)
+	}
+
+	return saved, rows.Err()
+}
+
+func (r *MySQLEffectRepository) Close() error {
+	return r.db.Close()
+}
+
+// MemoryEffectRepository keeps effects in memory, mostly for tests and local runs
+type MemoryEffectRepository struct {
+	effects map[string][]SavedEffect
+	mu      sync.RWMutex
+}
+
+// NewMemoryEffectRepository creates an empty in-memory repository
+func NewMemoryEffectRepository() *MemoryEffectRepository {
+	return &MemoryEffectRepository{effects: make(map[string][]SavedEffect)}
+}
+
+func (r *MemoryEffectRepository) Save(account string, saved []SavedEffect) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := strings.ToLower(account)
+	if len(saved) == 0 {
+		delete(r.effects, key)
+		return nil
+	}
+	r.effects[key] = append([]SavedEffect(nil), saved...)
+
+	return nil
+}
+
+func (r *MemoryEffectRepository) Load(account string) ([]SavedEffect, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]SavedEffect(nil), r.effects[strings.ToLower(account)]...), nil
+}
+
+func (r *MemoryEffectRepository) Close() error {
+	return nil
+}
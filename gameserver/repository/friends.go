@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FriendRepository keeps the friend lists of the players. A friendship goes both ways,
+// each player being on the list of the other. Characters aren't stored yet, so the
+// friends are kept by account.
+type FriendRepository interface {
+	// Add makes two accounts friends
+	Add(account, friend string) error
+
+	// Remove ends the friendship of two accounts
+	Remove(account, friend string) error
+
+	// List returns the friends of an account, sorted by name
+	List(account string) ([]string, error)
+
+	// Close releases the underlying resources
+	Close() error
+}
+
+// MySQLFriendRepository stores friendships in the character_friends table, a row for each side
+type MySQLFriendRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLFriendRepository creates a repository backed by db
+func NewMySQLFriendRepository(db *sql.DB) *MySQLFriendRepository {
+	return &MySQLFriendRepository{db: db}
+}
+
+func (r *MySQLFriendRepository) Add(account, friend string) error {
+	_, err := r.db.Exec("INSERT IGNORE INTO character_friends (account, friend) VALUES (?, ?), (?, ?)", account, friend, friend, account)
+	return err
+}
+
+func (r *MySQLFriendRepository) Remove(account, friend string) error {
+	_, err := r.db.Exec("DELETE FROM character_friends WHERE (account = ? AND friend = ?) OR (account = ? AND friend = ?)", account, friend, friend, account)
+	return err
+}
+
+func (r *MySQLFriendRepository) List(account string) ([]string, error) {
+	rows, err := r.db.Query("SELECT friend FROM character_friends WHERE account = ? ORDER BY friend", account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var friends []string
+	for rows.Next() {
+		var friend string
+		if err := rows.Scan(&friend); err != nil {
+			return nil, err
+		}
+		friends = append(friends, friend)
+	}
+
+	return friends, rows.Err()
+}
+
+func (r *MySQLFriendRepository) Close() error {
+	return r.db.Close()
+}
+
+// MemoryFriendRepository keeps friendships in memory, mostly for tests and local runs
+type MemoryFriendRepository struct {
+	friends map[string]map[string]string // Names of the friends of each account, by lowercased name
+	mu      sync.RWMutex
+}
+
+// NewMemoryFriendRepository creates an empty in-memory repository
+func NewMemoryFriendRepository() *MemoryFriendRepository {
+	return &MemoryFriendRepository{friends: make(map[string]map[string]string)}
+}
+
+func (r *MemoryFriendRepository) Add(account, friend string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.link(account, friend)
+	r.link(friend, account)
+	return nil
+}
+
+func (r *MemoryFriendRepository) link(account, friend string) {
+	key := strings.ToLower(account)
+	if r.friends[key] == nil {
+		r.friends[key] = make(map[string]string)
+	}
+	r.friends[key][strings.ToLower(friend)] = friend
+}
+
+func (r *MemoryFriendRepository) Remove(account, friend string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.friends[strings.ToLower(account)], strings.ToLower(friend))
+	delete(r.friends[strings.ToLower(friend)], strings.ToLower(account))
+	return nil
+}
+
+func (r *MemoryFriendRepository) List(account string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var friends []string
+	for _, friend := range r.friends[strings.ToLower(account)] {
+		friends = append(friends, friend)
+	}
+	slices.Sort(friends)
+	return friends, nil
+}
+
+func (r *MemoryFriendRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/frostwind/l2go/gameserver/quests"
+)
+
+// QuestRepository keeps where the players are in their quests. Characters
+// aren't stored yet, so the quests are kept by account.
+type QuestRepository interface {
+	// Save stores the state of a quest of an account
+	Save(account string, state quests.State) error
+
+	// Remove forgets a quest of an account, which can start it again
+	Remove(account string, questID int) error
+
+	// List returns the quests of an account, sorted by id
+	List(account string) ([]quests.State, error)
+
+	// Close releases the underlying resources
+	Close() error
+}
+
+// MySQLQuestRepository stores the quests in the character_quests table, a row per quest of an account
+type MySQLQuestRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLQuestRepository creates a repository backed by db
+func NewMySQLQuestRepository(db *sql.DB) *MySQLQuestRepository {
+	return &MySQLQuestRepository{db: db}
+}
+
+func (r *MySQLQuestRepository) Save(account string, state quests.State) error {
+	_, err := r.db.Exec("REPLACE INTO character_quests (account, quest_id, cond, progress, completed) VALUES (?, ?, ?, ?, ?)",
+		account, state.QuestID, state.Cond, state.Progress, state.Completed)
+	return err
+}
+
+func (r *MySQLQuestRepository) Remove(account string, questID int) error {
+	_, err := r.db.Exec("DELETE FROM character_quests WHERE account = ? AND quest_id = ?", account, questID)
+	return err
+}
+
+func (r *MySQLQuestRepository) List(account string) ([]quests.State, error) {
+	rows, err := r.db.Query("SELECT quest_id, cond, progress, completed FROM character_quests WHERE account = ? ORDER BY quest_id", account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []quests.State
+	for rows.Next() {
+		var state quests.State
+		if err := rows.Scan(&state.QuestID, &state.Cond, &state.Progress, &state.Completed); err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+
+	return states, rows.Err()
+}
+
+func (r *MySQLQuestRepository) Close() error {
+	return r.db.Close()
+}
+
+// MemoryQuestRepository keeps the quests in memory, mostly for tests and local runs
+type MemoryQuestRepository struct {
+	quests map[string]map[int]quests.State // By lowercased account, then by quest id
+	mu     sync.RWMutex
+}
+
+// NewMemoryQuestRepository creates an empty in-memory repository
+func NewMemoryQuestRepository() *MemoryQuestRepository {
+	return &MemoryQuestRepository{quests: make(map[string]map[int]quests.State)}
+}
+
+func (r *MemoryQuestRepository) Save(account string, state quests.State) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := strings.ToLower(account)
+	if r.quests[key] == nil {
+		r.quests[key] = make(map[int]quests.State)
+	}
+	r.quests[key][state.QuestID] = state
+	return nil
+}
+
+func (r *MemoryQuestRepository) Remove(account string, questID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.quests[strings.ToLower(account)], questID)
+	return nil
+}
+
+func (r *MemoryQuestRepository) List(account string) ([]quests.State, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var states []quests.State
+	for _, state := range r.quests[strings.ToLower(account)] {
+		states = append(states, state)
+	}
+	slices.SortFunc(states, func(a, b quests.State) int { return a.QuestID - b.QuestID })
+	return states, nil
+}
+
+func (r *MemoryQuestRepository) Close() error {
+	return nil
+}
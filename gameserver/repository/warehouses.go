@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"database/sql"
+	"maps"
+	"strings"
+	"sync"
+)
+
+// WarehouseOwner is who a warehouse belongs to
+type WarehouseOwner struct {
+	Clan bool   // A clan warehouse, shared by the members of the clan
+	ID   string // The account of a private warehouse, the id of the clan of a clan warehouse
+}
+
+// key returns the owner as the in-memory repository keeps it
+func (o WarehouseOwner) key() WarehouseOwner {
+	return WarehouseOwner{Clan: o.Clan, ID: strings.ToLower(o.ID)}
+}
+
+// WarehouseRepository keeps the items the players store away from their inventory
+type WarehouseRepository interface {
+	// Load returns the counts of the items of a warehouse by item id, the adena included
+	Load(owner WarehouseOwner) (map[int]uint64, error)
+
+	// Move writes a character along with the items of a warehouse in a single transaction,
+	// so an item moved from one to the other is never kept by both, nor lost
+	Move(character Character, owner WarehouseOwner, items map[int]uint64) error
+
+	// Close releases the underlying resources
+	Close() error
+}
+
+// MySQLWarehouseRepository stores the warehouses in the warehouse_items table, the characters
+// moving items in the tables of the MySQLCharacterRepository
+type MySQLWarehouseRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLWarehouseRepository creates a repository backed by db
+func NewMySQLWarehouseRepository(db *sql.DB) *MySQLWarehouseRepository {
+	return &MySQLWarehouseRepository{db: db}
+}
+
+func (r *MySQLWarehouseRepository) Load(owner WarehouseOwner) (map[int]uint64, error) {
+	rows, err := r.db.Query("SELECT item_id, count FROM warehouse_items WHERE clan = ? AND owner = ?", owner.Clan, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[int]uint64)
+	for rows.Next() {
+		var itemID int
+		var count uint64
+		if err := rows.Scan(&itemID, &count); err != nil {
+			return nil, err
+		}
+		items[itemID] = count
+	}
+
+	return items, rows.Err()
+}
+
+func (r *MySQLWarehouseRepository) Move(character Character, owner WarehouseOwner, items map[int]uint64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := writeCharacter(tx, character); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM warehouse_items WHERE clan = ? AND owner = ?", owner.Clan, owner.ID); err != nil {
+		return err
+	}
+	for itemID, count := range items {
+		_, err := tx.Exec("INSERT INTO warehouse_items (clan, owner, item_id, count) VALUES (?, ?, ?, ?)", owner.Clan, owner.ID, itemID, count)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *MySQLWarehouseRepository) Close() error {
+	return r.db.Close()
+}
+
+// MemoryWarehouseRepository keeps the warehouses in memory, the characters moving items
+// being written to a MemoryCharacterRepository. Mostly for tests and local runs.
+type MemoryWarehouseRepository struct {
+	characters *MemoryCharacterRepository
+	warehouses map[WarehouseOwner]map[int]uint64 // By owner, its id lowercased
+	mu         sync.RWMutex
+}
+
+// NewMemoryWarehouseRepository creates an empty in-memory repository writing the characters to characters
+func NewMemoryWarehouseRepository(characters *MemoryCharacterRepository) *MemoryWarehouseRepository {
+	return &MemoryWarehouseRepository{characters: characters, warehouses: make(map[WarehouseOwner]map[int]uint64)}
+}
+
+func (r *MemoryWarehouseRepository) Load(owner WarehouseOwner) (map[int]uint64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	items := maps.Clone(r.warehouses[owner.key()])
+	if items == nil {
+		items = make(map[int]uint64)
+	}
+	return items, nil
+}
+
+func (r *MemoryWarehouseRepository) Move(character Character, owner WarehouseOwner, items map[int]uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.characters.Save([]Character{character}); err != nil {
+		return err
+	}
+	r.warehouses[owner.key()] = maps.Clone(items)
+	return nil
+}
+
+func (r *MemoryWarehouseRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package sendqueue
+
+import "fmt"
+
+// Priority tells which frames a saturated queue sheds first
+type Priority int
+
+const (
+	CRITICAL Priority = iota // The status of the player itself, its teleports, the answers to its requests
+	NORMAL                   // The movement of the objects around the player
+	LOW                      // The social traffic, the chat and the animations of the players around
+
+	PRIORITIES = 3
+)
+
+// String returns the name of a priority, for the logs
+func (p Priority) String() string {
+	switch p {
+	case CRITICAL:
+		return "critical"
+	case NORMAL:
+		return "normal"
+	case LOW:
+		return "low"
+	}
+	return fmt.Sprintf("priority %d", int(p))
+}
+
+// Policy reports whether a frame of some priority is queued, given the frames already waiting.
+// The frames refused are dropped. A queue which is full refuses any frame, whatever its policy.
+type Policy func(priority Priority, depth, capacity int) bool
+
+// Watermarks sheds the low priority frames once the queue is half full, then the normal ones once
+// it is three quarters full, keeping the rest of the queue for the critical ones
+func Watermarks(priority Priority, depth, capacity int) bool {
+	switch priority {
+	case CRITICAL:
+		return true
+	case NORMAL:
+		return depth < capacity*3/4
+	}
+	return depth < capacity/2
+}
+
+// FIFO queues every frame until the queue is full
+func FIFO(priority Priority, depth, capacity int) bool {
+	return true
+}
+
+// Policies are the policies the game servers can be configured with, by name
+var Policies = map[string]Policy{
+	"watermarks": Watermarks,
+	"fifo":       FIFO,
+}
+
+// Lookup returns the policy configured under a name, Watermarks when it is empty
+func Lookup(name string) (Policy, error) {
+	if name == "" {
+		return Watermarks, nil
+	}
+	policy, ok := Policies[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPolicy, name)
+	}
+	return policy, nil
+}
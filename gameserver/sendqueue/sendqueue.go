@@ -0,0 +1,198 @@
+// Package sendqueue buffers the frames sent to a connection, written by a goroutine of
+// their own, so a client reading slowly can't hold up the players broadcasting to it.
+// A saturated queue sheds the frames of the lowest priorities first, as its policy says.
+package sendqueue
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrQueueFull     = errors.New("send queue full")
+	ErrShed          = errors.New("frame shed by the send policy")
+	ErrClosed        = errors.New("send queue closed")
+	ErrUnknownPolicy = errors.New("unknown send policy")
+)
+
+// Counters add up what happened to the queues of many connections, for the server metrics
+type Counters struct {
+	Dropped   [PRIORITIES]atomic.Uint64 // Frames dropped because their queue was saturated, by priority
+	Overflows atomic.Uint64             // Connections dropped because their queue was full
+	MaxDepth  atomic.Int64              // Deepest a queue has been, the ones of the connections dropped since included
+}
+
+// Stats is a snapshot of a queue
+type Stats struct {
+	Depth    int // Frames waiting to be written
+	MaxDepth int // Deepest the queue has been
+	Written  uint64
+	Dropped  uint64
+}
+
+// Queue holds the frames waiting to be written to a connection, up to its capacity
+type Queue struct {
+	frames    chan []byte
+	conn      io.Writer
+	policy    Policy
+	counters  *Counters
+	closing   chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error // Why the writer stopped, set before done is closed
+	maxDepth  atomic.Int64
+	written   atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// New creates a queue and starts its writer. The policy is Watermarks when nil, and the
+// counters, if any, are shared with other queues.
+func New(conn io.Writer, capacity int, policy Policy, counters *Counters) *Queue {
+	if policy == nil {
+		policy = Watermarks
+	}
+	if counters == nil {
+		counters = &Counters{}
+	}
+
+	q := &Queue{
+		frames:   make(chan []byte, capacity),
+		conn:     conn,
+		policy:   policy,
+		counters: counters,
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go q.write()
+	return q
+}
+
+// Admit checks whether a frame of some priority would be queued, before it is built. It fails
+// with ErrQueueFull when the queue is full, and with ErrShed when the policy refuses the frame.
+// As long as a single goroutine pushes at a time, an admitted frame can be pushed.
+func (q *Queue) Admit(priority Priority) error {
+	depth, capacity := len(q.frames), cap(q.frames)
+	switch {
+	case depth == capacity:
+		return ErrQueueFull
+	case !q.policy(priority, depth, capacity):
+		return ErrShed
+	}
+	return nil
+}
+
+// Push queues a frame, failing with ErrQueueFull instead of waiting when the queue is full
+func (q *Queue) Push(frame []byte) error {
+	select {
+	case <-q.closing:
+		return ErrClosed
+	case <-q.done:
+		if q.err != nil {
+			return q.err
+		}
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case q.frames <- frame:
+	default:
+		return ErrQueueFull
+	}
+
+	depth := int64(len(q.frames))
+	raise(&q.maxDepth, depth)
+	raise(&q.counters.MaxDepth, depth)
+	return nil
+}
+
+// raise sets a high-water mark to depth if it is deeper
+func raise(mark *atomic.Int64, depth int64) {
+	for {
+		max := mark.Load()
+		if depth <= max || mark.CompareAndSwap(max, depth) {
+			return
+		}
+	}
+}
+
+// Drop counts a frame of some priority given up on because the queue was saturated
+func (q *Queue) Drop(priority Priority) {
+	q.dropped.Add(1)
+	q.counters.Dropped[priority].Add(1)
+}
+
+// Overflow counts the connection dropped because its queue was full
+func (q *Queue) Overflow() {
+	q.counters.Overflows.Add(1)
+}
+
+// Close stops accepting frames and waits up to timeout for the writer to write the ones
+// queued, returning why the writer stopped if it failed
+func (q *Queue) Close(timeout time.Duration) error {
+	q.closeOnce.Do(func() {
+		close(q.closing)
+	})
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-q.done:
+		return q.err
+	case <-timer.C:
+		return ErrQueueFull
+	}
+}
+
+// Done is closed once the writer stopped, after a Close or a failed write
+func (q *Queue) Done() <-chan struct{} {
+	return q.done
+}
+
+// Stats returns the current depth of the queue and what it has done so far
+func (q *Queue) Stats() Stats {
+	return Stats{
+		Depth:    len(q.frames),
+		MaxDepth: int(q.maxDepth.Load()),
+		Written:  q.written.Load(),
+		Dropped:  q.dropped.Load(),
+	}
+}
+
+// write writes the frames in order until the queue is closed, then writes the ones left
+func (q *Queue) write() {
+	defer close(q.done)
+
+	for {
+		select {
+		case frame := <-q.frames:
+			if !q.writeFrame(frame) {
+				return
+			}
+		case <-q.closing:
+			for {
+				select {
+				case frame := <-q.frames:
+					if !q.writeFrame(frame) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *Queue) writeFrame(frame []byte) bool {
+	if _, err := q.conn.Write(frame); err != nil {
+		q.err = err
+		return false
+	}
+	q.written.Add(1)
+	return true
+}
@@ -0,0 +1,195 @@
+package sendqueue
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stalledConn holds the writes until it is released, like a client not reading its socket
+type stalledConn struct {
+	release chan struct{}
+	mu      sync.Mutex
+	written bytes.Buffer
+	err     error
+}
+
+func (c *stalledConn) Write(p []byte) (int, error) {
+	<-c.release
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.written.Write(p)
+}
+
+func (c *stalledConn) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.written.String()
+}
+
+func TestCloseWritesTheQueuedFrames(t *testing.T) {
+	conn := &stalledConn{release: make(chan struct{})}
+	close(conn.release)
+
+	q := New(conn, 8, FIFO, nil)
+	for _, frame := range []string{"a", "b", "c"} {
+		if err := q.Push([]byte(frame)); err != nil {
+			t.Fatalf("Push(%q) error = %v", frame, err)
+		}
+	}
+
+	if err := q.Close(time.Second); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := conn.String(); got != "abc" {
+		t.Errorf("written %q, want %q", got, "abc")
+	}
+	if stats := q.Stats(); stats.Written != 3 || stats.Depth != 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	if err := q.Push([]byte("d")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Push() after Close() = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestFullQueue(t *testing.T) {
+	conn := &stalledConn{release: make(chan struct{})}
+	counters := &Counters{}
+	q := New(conn, 2, FIFO, counters)
+
+	// The writer holds the first frame, the next ones fill the queue
+	tests := []struct {
+		frame string
+		want  error
+	}{
+		{"a", nil},
+		{"b", nil},
+		{"c", nil},
+		{"d", ErrQueueFull},
+	}
+	for _, tt := range tests {
+		if tt.frame == "b" {
+			waitFor(t, func() bool { return q.Stats().Depth == 0 })
+		}
+		if err := q.Push([]byte(tt.frame)); !errors.Is(err, tt.want) {
+			t.Fatalf("Push(%q) error = %v, want %v", tt.frame, err, tt.want)
+		}
+	}
+
+	if err := q.Admit(CRITICAL); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Admit() = %v with the writer stalled, want %v", err, ErrQueueFull)
+	}
+	q.Drop(LOW)
+	q.Overflow()
+	if stats := q.Stats(); stats.Depth != 2 || stats.MaxDepth != 2 || stats.Dropped != 1 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+	if counters.Dropped[LOW].Load() != 1 || counters.Overflows.Load() != 1 || counters.MaxDepth.Load() != 2 {
+		t.Errorf("counters = %d dropped, %d overflows, %d deep", counters.Dropped[LOW].Load(), counters.Overflows.Load(), counters.MaxDepth.Load())
+	}
+
+	// A stalled client can't hold up the one closing its queue
+	if err := q.Close(10 * time.Millisecond); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Close() of a stalled queue = %v, want %v", err, ErrQueueFull)
+	}
+
+	close(conn.release)
+	<-q.Done()
+	if got := conn.String(); got != "abc" {
+		t.Errorf("written %q, want %q", got, "abc")
+	}
+}
+
+func TestAdmit(t *testing.T) {
+	conn := &stalledConn{release: make(chan struct{})}
+	defer close(conn.release)
+
+	// The writer holds the first frame, the next ones are left in the queue
+	q := New(conn, 8, nil, nil)
+	q.Push([]byte("held"))
+	waitFor(t, func() bool { return q.Stats().Depth == 0 })
+
+	// Watermarks keeps the second half of the queue for the normal and critical frames, and its last quarter for the critical ones
+	tests := []struct {
+		depth    int
+		priority Priority
+		want     error
+	}{
+		{0, LOW, nil},
+		{3, LOW, nil},
+		{4, LOW, ErrShed},
+		{4, NORMAL, nil},
+		{5, NORMAL, nil},
+		{6, NORMAL, ErrShed},
+		{6, CRITICAL, nil},
+		{7, CRITICAL, nil},
+		{8, CRITICAL, ErrQueueFull},
+	}
+	for _, tt := range tests {
+		for q.Stats().Depth < tt.depth {
+			if err := q.Push([]byte("x")); err != nil {
+				t.Fatalf("Push() error = %v", err)
+			}
+		}
+		if err := q.Admit(tt.priority); !errors.Is(err, tt.want) {
+			t.Errorf("Admit(%v) at a depth of %d = %v, want %v", tt.priority, tt.depth, err, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		want error
+	}{
+		{"", nil},
+		{"watermarks", nil},
+		{"fifo", nil},
+		{"lifo", ErrUnknownPolicy},
+	}
+	for _, tt := range tests {
+		if policy, err := Lookup(tt.name); !errors.Is(err, tt.want) || (err == nil && policy == nil) {
+			t.Errorf("Lookup(%q) = %v", tt.name, err)
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	broken := errors.New("connection reset")
+	conn := &stalledConn{release: make(chan struct{}), err: broken}
+	close(conn.release)
+
+	q := New(conn, 4, FIFO, nil)
+	if err := q.Push([]byte("a")); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	<-q.Done()
+	if err := q.Push([]byte("b")); !errors.Is(err, broken) {
+		t.Errorf("Push() after a failed write = %v, want %v", err, broken)
+	}
+	if err := q.Close(time.Second); !errors.Is(err, broken) {
+		t.Errorf("Close() = %v, want %v", err, broken)
+	}
+}
+
+// waitFor waits for the writer to reach a state
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,22 @@
+package gameserver
+
+import "github.com/frostwind/l2go/gameserver/sendqueue"
+
+// SetSendPolicy replaces the policy telling the send queues of the clients which packets they shed
+// first when saturated, the one configured otherwise. It must be called before the game server starts.
+func (g *GameServer) SetSendPolicy(policy sendqueue.Policy) {
+	g.sendPolicy = policy
+}
+
+// sendQueueDepth returns the packets waiting in the send queues of the clients
+func (g *GameServer) sendQueueDepth() (depth int) {
+	g.clientsMutex.Lock()
+	defer g.clientsMutex.Unlock()
+
+	for _, client := range g.clients {
+		if queue := client.SendQueue(); queue != nil {
+			depth += queue.Stats().Depth
+		}
+	}
+	return depth
+}
@@ -0,0 +1,27 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// AbnormalEffect is an effect icon shown to a player
+type AbnormalEffect struct {
+	SkillID   uint32
+	Level     uint16
+	Remaining int32 // Seconds, -1 for an effect lasting until it is removed
+}
+
+func NewAbnormalStatusUpdatePacket(effects []AbnormalEffect) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerAbnormalStatusUpdate)
+	buffer.WriteUInt16(uint16(len(effects)))
+
+	for _, effect := range effects {
+		buffer.WriteUInt32(effect.SkillID)
+		buffer.WriteUInt16(effect.Level)
+		buffer.WriteUInt32(uint32(effect.Remaining))
+	}
+
+	return buffer.Bytes()
+}
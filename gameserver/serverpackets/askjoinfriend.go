@@ -0,0 +1,17 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// AskJoinFriend asks a player whether it wants to join the friend list of another
+type AskJoinFriend struct {
+	Requestor string `l2:"string"`
+}
+
+func NewAskJoinFriendPacket(requestor string) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerAskJoinFriend}, AskJoinFriend{requestor})
+
+	return buffer
+}
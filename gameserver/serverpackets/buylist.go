@@ -0,0 +1,30 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// Goods is an item of a buy list, with its price and the weight of a single one
+type Goods struct {
+	ItemID uint32
+	Price  uint64
+	Weight uint32
+}
+
+// NewBuyListPacket opens the goods a merchant sells, along with the adena of the player
+func NewBuyListPacket(adena uint64, listID uint32, goods []Goods) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerBuyList)
+	buffer.WriteUInt64(adena)
+	buffer.WriteUInt32(listID)
+	buffer.WriteUInt16(uint16(len(goods)))
+
+	for _, item := range goods {
+		buffer.WriteUInt32(item.ItemID)
+		buffer.WriteUInt64(item.Price)
+		buffer.WriteUInt32(item.Weight)
+	}
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,26 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// Positions of a player sent by ChangeWaitType
+const (
+	WAIT_TYPE_SITTING  = 0
+	WAIT_TYPE_STANDING = 1
+)
+
+type ChangeWaitType struct {
+	ObjectID uint32 `l2:"u32"`
+	WaitType uint32 `l2:"u32"`
+	X        int32  `l2:"u32"`
+	Y        int32  `l2:"u32"`
+	Z        int32  `l2:"u32"`
+}
+
+func NewChangeWaitTypePacket(objectID, waitType uint32, x, y, z int32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerChangeWaitType}, ChangeWaitType{objectID, waitType, x, y, z})
+
+	return buffer
+}
@@ -0,0 +1,23 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+const (
+	REASON_CREATION_FAILED     = 0x00
+	REASON_TOO_MANY_CHARACTERS = 0x01
+	REASON_NAME_ALREADY_EXISTS = 0x02
+	REASON_16_ENG_CHARS        = 0x03
+)
+
+type CharCreateFail struct {
+	Reason uint32 `l2:"u32"`
+}
+
+func NewCharCreateFailPacket(reason uint32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerCharCreateFail}, CharCreateFail{reason})
+
+	return buffer
+}
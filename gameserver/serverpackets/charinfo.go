@@ -0,0 +1,22 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// CharInfo shows another player coming into sight
+type CharInfo struct {
+	X        int32  `l2:"u32"`
+	Y        int32  `l2:"u32"`
+	Z        int32  `l2:"u32"`
+	Heading  uint32 `l2:"u32"`
+	ObjectID uint32 `l2:"u32"`
+	Name     string `l2:"string"`
+}
+
+func NewCharInfoPacket(objectID uint32, name string, x, y, z int32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerCharInfo}, CharInfo{X: x, Y: y, Z: z, ObjectID: objectID, Name: name})
+
+	return buffer
+}
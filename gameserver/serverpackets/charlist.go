@@ -1,13 +1,77 @@
 package serverpackets
 
 import (
+	"time"
+
+	"github.com/frostwind/l2go/opcodes"
 	"github.com/frostwind/l2go/packets"
 )
 
-func NewCharListPacket() []byte {
+// Paperdoll slots of the characters, in the order CharList sends the items worn in them
+const (
+	PAPERDOLL_UNDER = iota
+	PAPERDOLL_REAR
+	PAPERDOLL_LEAR
+	PAPERDOLL_NECK
+	PAPERDOLL_RFINGER
+	PAPERDOLL_LFINGER
+	PAPERDOLL_HEAD
+	PAPERDOLL_RHAND
+	PAPERDOLL_LHAND
+	PAPERDOLL_GLOVES
+	PAPERDOLL_CHEST
+	PAPERDOLL_LEGS
+	PAPERDOLL_FEET
+	PAPERDOLL_BACK
+	PAPERDOLL_LRHAND
+	PAPERDOLL_HAIR
+	PAPERDOLL_SLOTS // Count of the slots
+)
+
+// CharListEntry is a character of the account, as shown on the character selection
+type CharListEntry struct {
+	Name       string
+	ObjectID   uint32
+	Sex        uint32
+	Race       uint32
+	ClassID    uint32
+	Level      uint32
+	Karma      uint32
+	HairStyle  uint32
+	HairColor  uint32
+	Face       uint32
+	Paperdoll  [PAPERDOLL_SLOTS]uint32 // Item ids worn, 0 for the empty slots
+	LastAccess time.Time               // Zero when the character was never saved
+}
+
+// NewCharListPacket lists the characters of the account, followed by how many more it can create
+func NewCharListPacket(characters []CharListEntry, slotsLeft int) []byte {
 	buffer := packets.NewBuffer()
-	buffer.WriteByte(0x1f)                       // Packet type: CharList
-	buffer.Write([]byte{0x00, 0x00, 0x00, 0x00}) // TODO
+	buffer.WriteByte(opcodes.GameServerCharList)
+	buffer.WriteUInt32(uint32(len(characters)))
+
+	for _, character := range characters {
+		buffer.WriteString(character.Name)
+		buffer.WriteUInt32(character.ObjectID)
+		buffer.WriteUInt32(character.Sex)
+		buffer.WriteUInt32(character.Race)
+		buffer.WriteUInt32(character.ClassID)
+		buffer.WriteUInt32(character.Level)
+		buffer.WriteUInt32(character.Karma)
+		buffer.WriteUInt32(character.HairStyle)
+		buffer.WriteUInt32(character.HairColor)
+		buffer.WriteUInt32(character.Face)
+		for _, itemID := range character.Paperdoll {
+			buffer.WriteUInt32(itemID)
+		}
+
+		var lastAccess uint32
+		if !character.LastAccess.IsZero() {
+			lastAccess = uint32(character.LastAccess.Unix()) // Seconds since the epoch
+		}
+		buffer.WriteUInt32(lastAccess)
+	}
+	buffer.WriteUInt32(uint32(max(slotsLeft, 0)))
 
 	return buffer.Bytes()
 }
@@ -1,13 +1,32 @@
 package serverpackets
 
 import (
+	"github.com/frostwind/l2go/opcodes"
 	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/templates"
 )
 
-func NewCharTemplatePacket() []byte {
+// Every stat is framed by the two values the character creation screen expects around it
+const (
+	STAT_PREFIX = 0x46
+	STAT_SUFFIX = 0x0a
+)
+
+func NewCharTemplatePacket(list []templates.Template) []byte {
 	buffer := packets.NewBuffer()
-	buffer.WriteByte(0x23)   // Packet type: CharTemplate
-	buffer.WriteUInt32(0x00) // We don't actually need to send the template to the client
+	buffer.WriteByte(opcodes.GameServerCharTemplate)
+	buffer.WriteUInt32(uint32(len(list)))
+
+	for _, template := range list {
+		buffer.WriteUInt32(uint32(template.Race))
+		buffer.WriteUInt32(uint32(template.Class))
+
+		for _, stat := range []int{template.STR, template.DEX, template.CON, template.INT, template.WIT, template.MEN} {
+			buffer.WriteUInt32(STAT_PREFIX)
+			buffer.WriteUInt32(uint32(stat))
+			buffer.WriteUInt32(STAT_SUFFIX)
+		}
+	}
 
 	return buffer.Bytes()
 }
@@ -0,0 +1,18 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// ClientSetTime sets the clock of the world the client shows, which runs on by itself afterwards
+type ClientSetTime struct {
+	Minutes uint32 `l2:"u32"` // Minutes of the world since its epoch
+	Speed   uint32 `l2:"u32"` // Minutes of the world per real minute
+}
+
+func NewClientSetTimePacket(minutes, speed uint32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerClientSetTime}, ClientSetTime{minutes, speed})
+
+	return buffer
+}
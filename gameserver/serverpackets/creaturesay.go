@@ -0,0 +1,25 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// Types of the chat messages
+const (
+	CHAT_ALL  = 0 // Heard by the players around the one speaking
+	CHAT_TELL = 2 // Whispered to a single player, wherever it is
+)
+
+type CreatureSay struct {
+	ObjectID uint32 `l2:"u32"` // The one speaking
+	ChatType uint32 `l2:"u32"`
+	Name     string `l2:"string"`
+	Text     string `l2:"string"`
+}
+
+func NewCreatureSayPacket(objectID, chatType uint32, name, text string) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerCreatureSay}, CreatureSay{objectID, chatType, name, text})
+
+	return buffer
+}
@@ -1,16 +1,24 @@
 package serverpackets
 
 import (
+	"github.com/frostwind/l2go/opcodes"
 	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
 )
 
-func NewCryptInitPacket() []byte {
+// NewCryptInitPacket returns the XOR key of a client, announcing the null crypto debug mode when plain,
+// and the compression of the packets following it when it is enabled
+func NewCryptInitPacket(plain bool, compression protocol.CompressionOptions) []byte {
 	key := []byte{0x94, 0x35, 0x00, 0x00, 0xa1, 0x6c, 0x54, 0x87}
 
 	buffer := packets.NewBuffer()
-	buffer.WriteByte(0x00) // Packet type: CryptInit
+	buffer.WriteByte(opcodes.GameServerCryptInit)
 	buffer.WriteByte(0x01) // ?
 	buffer.Write(key)      // Key
+	if plain {
+		buffer.WriteUInt32(opcodes.NullCryptoMarker)
+	}
+	buffer.Write(compression.Trailer())
 
 	return buffer.Bytes()
 }
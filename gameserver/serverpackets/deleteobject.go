@@ -0,0 +1,17 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+type DeleteObject struct {
+	ObjectID uint32 `l2:"u32"`
+	Unknown  uint32 `l2:"u32"`
+}
+
+func NewDeleteObjectPacket(objectID uint32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerDeleteObject}, DeleteObject{ObjectID: objectID})
+
+	return buffer
+}
@@ -0,0 +1,39 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+type DropItem struct {
+	DropperID uint32 `l2:"u32"` // The NPC or the player dropping the item
+	ObjectID  uint32 `l2:"u32"`
+	ItemID    uint32 `l2:"u32"`
+	X         int32  `l2:"u32"`
+	Y         int32  `l2:"u32"`
+	Z         int32  `l2:"u32"`
+	Stackable uint32 `l2:"u32"`
+	Count     uint32 `l2:"u32"`
+	Unknown   uint32 `l2:"u32"`
+}
+
+func NewDropItemPacket(dropperID, objectID uint32, itemID int, x, y, z int32, count uint64) []byte {
+	var stackable uint32
+	if count > 1 {
+		stackable = 1
+	}
+
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerDropItem}, DropItem{
+		DropperID: dropperID,
+		ObjectID:  objectID,
+		ItemID:    uint32(itemID),
+		X:         x,
+		Y:         y,
+		Z:         z,
+		Stackable: stackable,
+		Count:     uint32(count),
+		Unknown:   1,
+	})
+
+	return buffer
+}
@@ -0,0 +1,31 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// Friend is a player of a friend list
+type Friend struct {
+	ObjectID uint32 // 0 while the friend is offline
+	Name     string
+	Online   bool
+}
+
+func NewFriendListPacket(friends []Friend) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerFriendList)
+	buffer.WriteUInt16(uint16(len(friends)))
+
+	for _, friend := range friends {
+		buffer.WriteUInt32(friend.ObjectID)
+		buffer.WriteString(friend.Name)
+		if friend.Online {
+			buffer.WriteUInt32(1)
+		} else {
+			buffer.WriteUInt32(0)
+		}
+	}
+
+	return buffer.Bytes()
+}
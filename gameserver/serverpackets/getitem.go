@@ -0,0 +1,20 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+type GetItem struct {
+	PlayerID uint32 `l2:"u32"` // The player picking the item up
+	ObjectID uint32 `l2:"u32"`
+	X        int32  `l2:"u32"`
+	Y        int32  `l2:"u32"`
+	Z        int32  `l2:"u32"`
+}
+
+func NewGetItemPacket(playerID, objectID uint32, x, y, z int32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerGetItem}, GetItem{playerID, objectID, x, y, z})
+
+	return buffer
+}
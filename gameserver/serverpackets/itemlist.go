@@ -0,0 +1,66 @@
+package serverpackets
+
+import (
+	"sort"
+
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// ADENA_ID is the item id of the adena, listed before the other items
+const ADENA_ID = 57
+
+// ITEM_SIZE is the size of an item of the lists, its id then its count
+const ITEM_SIZE = 12
+
+// Lists announce at most MAX_LIST_COUNT items per packet
+const MAX_LIST_COUNT = 0xffff
+
+// Item is a stack of items of an inventory
+type Item struct {
+	ItemID uint32
+	Count  uint64
+}
+
+// Items lists the counts of items by id, the adena first then the other items by id
+func Items(counts map[int]uint64) []Item {
+	items := make([]Item, 0, len(counts))
+	for itemID, count := range counts {
+		if count > 0 {
+			items = append(items, Item{ItemID: uint32(itemID), Count: count})
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if (items[i].ItemID == ADENA_ID) != (items[j].ItemID == ADENA_ID) {
+			return items[i].ItemID == ADENA_ID
+		}
+		return items[i].ItemID < items[j].ItemID
+	})
+	return items
+}
+
+// NewItemListPackets sends the whole inventory of a player, its adena being one of the items, in
+// packets of at most maxSize bytes, packets.DefaultPageSize when 0. Every packet but the last one is
+// flagged with packets.MorePages.
+func NewItemListPackets(items []Item, maxSize int) [][]byte {
+	pages := packets.Paginate(items, packets.PerPage(maxSize, 4, ITEM_SIZE, MAX_LIST_COUNT))
+
+	buffers := make([][]byte, 0, len(pages))
+	for _, page := range pages {
+		buffer := packets.NewBuffer()
+		buffer.WriteByte(opcodes.GameServerItemList)
+		buffer.WriteByte(page.Flag)
+		buffer.WriteUInt16(uint16(len(page.Items)))
+		writeItems(buffer, page.Items)
+		buffers = append(buffers, buffer.Bytes())
+	}
+	return buffers
+}
+
+func writeItems(buffer *packets.Buffer, items []Item) {
+	for _, item := range items {
+		buffer.WriteUInt32(item.ItemID)
+		buffer.WriteUInt64(item.Count)
+	}
+}
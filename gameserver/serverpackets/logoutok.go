@@ -0,0 +1,9 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+)
+
+func NewLogoutOkPacket() []byte {
+	return []byte{opcodes.GameServerLogoutOk}
+}
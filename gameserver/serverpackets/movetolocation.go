@@ -0,0 +1,22 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+type MoveToLocation struct {
+	ObjectID uint32 `l2:"u32"` // The creature walking
+	DestX    int32  `l2:"u32"`
+	DestY    int32  `l2:"u32"`
+	DestZ    int32  `l2:"u32"`
+	OrigX    int32  `l2:"u32"`
+	OrigY    int32  `l2:"u32"`
+	OrigZ    int32  `l2:"u32"`
+}
+
+func NewMoveToLocationPacket(objectID uint32, destX, destY, destZ, origX, origY, origZ int32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerMoveToLocation}, MoveToLocation{objectID, destX, destY, destZ, origX, origY, origZ})
+
+	return buffer
+}
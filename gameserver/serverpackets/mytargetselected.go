@@ -0,0 +1,17 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+type MyTargetSelected struct {
+	ObjectID uint32 `l2:"u32"`
+	Color    uint16 `l2:"u16"` // Level difference color
+}
+
+func NewMyTargetSelectedPacket(objectID uint32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerMyTargetSelected}, MyTargetSelected{ObjectID: objectID})
+
+	return buffer
+}
@@ -0,0 +1,16 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+func NewNpcHtmlMessagePacket(objectID uint32, html string) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerNpcHtmlMessage)
+	buffer.WriteUInt32(objectID) // The NPC the dialog belongs to
+	buffer.WriteString(html)
+	buffer.WriteUInt32(0x00) // Item id, for the item dialogs
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,40 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// NPC_TEMPLATE_OFFSET is added to the template ids of the NPCs to get the ids the client knows them by
+const NPC_TEMPLATE_OFFSET = 1000000
+
+// NpcInfo shows an NPC coming into sight
+type NpcInfo struct {
+	ObjectID   uint32 `l2:"u32"`
+	TemplateID uint32 `l2:"u32"`
+	Attackable uint32 `l2:"u32"`
+	X          int32  `l2:"u32"`
+	Y          int32  `l2:"u32"`
+	Z          int32  `l2:"u32"`
+	Heading    uint32 `l2:"u32"`
+	Name       string `l2:"string"`
+}
+
+func NewNpcInfoPacket(objectID uint32, templateID int, attackable bool, name string, x, y, z int32) []byte {
+	var canBeAttacked uint32
+	if attackable {
+		canBeAttacked = 1
+	}
+
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerNpcInfo}, NpcInfo{
+		ObjectID:   objectID,
+		TemplateID: uint32(templateID + NPC_TEMPLATE_OFFSET),
+		Attackable: canBeAttacked,
+		X:          x,
+		Y:          y,
+		Z:          z,
+		Name:       name,
+	})
+
+	return buffer
+}
@@ -0,0 +1,27 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// Quest is a quest of the quest list, at the step it reached
+type Quest struct {
+	ID   uint32
+	Cond uint32
+}
+
+// NewQuestListPacket lists the quests a player is doing. The quest items follow, none are shown yet.
+func NewQuestListPacket(quests []Quest) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerQuestList)
+	buffer.WriteUInt16(uint16(len(quests)))
+
+	for _, quest := range quests {
+		buffer.WriteUInt32(quest.ID)
+		buffer.WriteUInt32(quest.Cond)
+	}
+	buffer.WriteUInt16(0)
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,22 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// RestartResponse answers a player asking to go back to the character selection
+type RestartResponse struct {
+	Accepted uint32 `l2:"u32"`
+}
+
+func NewRestartResponsePacket(accepted bool) []byte {
+	var response uint32
+	if accepted {
+		response = 1
+	}
+
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerRestartResponse}, RestartResponse{response})
+
+	return buffer
+}
@@ -0,0 +1,30 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// SellItem is an item of a player a merchant buys back, with the price of a single one
+type SellItem struct {
+	ItemID uint32
+	Count  uint64
+	Price  uint64
+}
+
+// NewSellListPacket opens the items of a player a merchant buys back, along with its adena
+func NewSellListPacket(adena uint64, listID uint32, items []SellItem) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerSellList)
+	buffer.WriteUInt64(adena)
+	buffer.WriteUInt32(listID)
+	buffer.WriteUInt16(uint16(len(items)))
+
+	for _, item := range items {
+		buffer.WriteUInt32(item.ItemID)
+		buffer.WriteUInt64(item.Count)
+		buffer.WriteUInt64(item.Price)
+	}
+
+	return buffer.Bytes()
+}
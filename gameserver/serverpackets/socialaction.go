@@ -0,0 +1,36 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// Emotes of the players, which they ask for with RequestSocialAction
+const (
+	SOCIAL_ACTION_GREETING = iota + 2
+	SOCIAL_ACTION_VICTORY
+	SOCIAL_ACTION_ADVANCE
+	SOCIAL_ACTION_NO
+	SOCIAL_ACTION_YES
+	SOCIAL_ACTION_BOW
+	SOCIAL_ACTION_UNAWARE
+	SOCIAL_ACTION_WAITING
+	SOCIAL_ACTION_LAUGH
+	SOCIAL_ACTION_APPLAUD
+	SOCIAL_ACTION_DANCE
+	SOCIAL_ACTION_SORROW
+)
+
+// SOCIAL_ACTION_LEVEL_UP is the glow shown around the players gaining a level
+const SOCIAL_ACTION_LEVEL_UP = 15
+
+type SocialAction struct {
+	ObjectID uint32 `l2:"u32"`
+	ActionID uint32 `l2:"u32"`
+}
+
+func NewSocialActionPacket(objectID, actionID uint32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerSocialAction}, SocialAction{objectID, actionID})
+
+	return buffer
+}
@@ -0,0 +1,40 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// Attributes of the StatusUpdate packet
+const (
+	STATUS_LEVEL    = 0x01
+	STATUS_EXP      = 0x02
+	STATUS_CUR_HP   = 0x09
+	STATUS_MAX_HP   = 0x0a
+	STATUS_CUR_MP   = 0x0b
+	STATUS_MAX_MP   = 0x0c
+	STATUS_CUR_LOAD = 0x0e
+	STATUS_MAX_LOAD = 0x0f
+	STATUS_CUR_CP   = 0x21
+	STATUS_MAX_CP   = 0x22
+)
+
+// StatusAttribute is a value of a player shown by its client
+type StatusAttribute struct {
+	ID    uint32
+	Value uint32
+}
+
+func NewStatusUpdatePacket(objectID uint32, attributes ...StatusAttribute) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerStatusUpdate)
+	buffer.WriteUInt32(objectID)
+	buffer.WriteUInt32(uint32(len(attributes)))
+
+	for _, attribute := range attributes {
+		buffer.WriteUInt32(attribute.ID)
+		buffer.WriteUInt32(attribute.Value)
+	}
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,15 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// NewSunRisePacket tells the client the day begins
+func NewSunRisePacket() []byte {
+	return []byte{opcodes.GameServerSunRise}
+}
+
+// NewSunSetPacket tells the client the night begins
+func NewSunSetPacket() []byte {
+	return []byte{opcodes.GameServerSunSet}
+}
@@ -0,0 +1,41 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// Ids of the system messages, shown by the client in the language of the player
+const (
+	SYSTEM_MESSAGE_NOT_LOGGED_IN    = 3    // $s1 is not currently logged in
+	SYSTEM_MESSAGE_ENCHANTED        = 63   // Your +$s1 $s2 has been successfully enchanted
+	SYSTEM_MESSAGE_INVENTORY_FULL   = 129  // Your inventory is full
+	SYSTEM_MESSAGE_FRIEND_ADDED     = 132  // $s1 has been added to your friends list
+	SYSTEM_MESSAGE_FRIEND_REMOVED   = 133  // $s1 has been removed from your friends list
+	SYSTEM_MESSAGE_NOT_CLAN_MEMBER  = 212  // You are not a clan member and cannot perform this action
+	SYSTEM_MESSAGE_NOT_ENOUGH_ADENA = 279  // You do not have enough adena
+	SYSTEM_MESSAGE_INCORRECT_COUNT  = 351  // Incorrect item count
+	SYSTEM_MESSAGE_WEIGHT_LIMIT     = 422  // You have exceeded the weight limit
+	SYSTEM_MESSAGE_ALREADY_FRIEND   = 484  // $s1 is already on your friends list
+	SYSTEM_MESSAGE_FRIEND_LOGGED_IN = 503  // Your friend $s1 has logged in
+	SYSTEM_MESSAGE_WAREHOUSE_FULL   = 1036 // You have exceeded the quantity that can be inputted
+	SYSTEM_MESSAGE_S1               = 1983 // $s1
+)
+
+// SYSTEM_MESSAGE_TEXT is the type of the parameters filling the $s placeholders
+const SYSTEM_MESSAGE_TEXT = 0
+
+// NewSystemMessagePacket shows a system message, its text parameters filling its placeholders in order
+func NewSystemMessagePacket(messageID uint32, params ...string) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerSystemMessage)
+	buffer.WriteUInt32(messageID)
+	buffer.WriteUInt32(uint32(len(params)))
+
+	for _, param := range params {
+		buffer.WriteUInt32(SYSTEM_MESSAGE_TEXT)
+		buffer.WriteString(param)
+	}
+
+	return buffer.Bytes()
+}
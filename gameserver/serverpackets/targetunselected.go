@@ -0,0 +1,19 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+type TargetUnselected struct {
+	ObjectID uint32 `l2:"u32"`
+	X        int32  `l2:"u32"`
+	Y        int32  `l2:"u32"`
+	Z        int32  `l2:"u32"`
+}
+
+func NewTargetUnselectedPacket(objectID uint32, x, y, z int32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerTargetUnselected}, TargetUnselected{objectID, x, y, z})
+
+	return buffer
+}
@@ -0,0 +1,20 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+type ValidateLocation struct {
+	ObjectID uint32 `l2:"u32"` // The player put back where the server knows it stands
+	X        int32  `l2:"u32"`
+	Y        int32  `l2:"u32"`
+	Z        int32  `l2:"u32"`
+	Heading  int32  `l2:"u32"`
+}
+
+func NewValidateLocationPacket(objectID uint32, x, y, z, heading int32) []byte {
+	buffer, _ := packets.AppendMarshal([]byte{opcodes.GameServerValidateLocation}, ValidateLocation{objectID, x, y, z, heading})
+
+	return buffer
+}
@@ -0,0 +1,42 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// Kinds of warehouse, telling the client which one the lists are about
+const (
+	WAREHOUSE_PRIVATE = 1
+	WAREHOUSE_CLAN    = 2
+)
+
+// NewWarehouseDepositListPackets opens the items of a player it can store in a warehouse, along with its adena,
+// in packets of at most maxSize bytes
+func NewWarehouseDepositListPackets(kind uint16, adena uint64, items []Item, maxSize int) [][]byte {
+	return newWarehouseListPackets(opcodes.GameServerWarehouseDepositList, kind, adena, items, maxSize)
+}
+
+// NewWarehouseWithdrawListPackets opens the items stored in a warehouse, along with the adena of the player,
+// in packets of at most maxSize bytes
+func NewWarehouseWithdrawListPackets(kind uint16, adena uint64, items []Item, maxSize int) [][]byte {
+	return newWarehouseListPackets(opcodes.GameServerWarehouseWithdrawList, kind, adena, items, maxSize)
+}
+
+// newWarehouseListPackets repeats the kind of warehouse and the adena in every page of the list
+func newWarehouseListPackets(opcode byte, kind uint16, adena uint64, items []Item, maxSize int) [][]byte {
+	pages := packets.Paginate(items, packets.PerPage(maxSize, 14, ITEM_SIZE, MAX_LIST_COUNT))
+
+	buffers := make([][]byte, 0, len(pages))
+	for _, page := range pages {
+		buffer := packets.NewBuffer()
+		buffer.WriteByte(opcode)
+		buffer.WriteByte(page.Flag)
+		buffer.WriteUInt16(kind)
+		buffer.WriteUInt64(adena)
+		buffer.WriteUInt16(uint16(len(page.Items)))
+		writeItems(buffer, page.Items)
+		buffers = append(buffers, buffer.Bytes())
+	}
+	return buffers
+}
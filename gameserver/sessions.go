@@ -0,0 +1,61 @@
+package gameserver
+
+import (
+	"crypto/subtle"
+	"sync"
+	"time"
+)
+
+// playerAuthLifetime is how long a client has to show up once the login server sent its token
+const playerAuthLifetime = time.Minute
+
+type pendingPlayer struct {
+	loginKey    []byte
+	playKey     []byte
+	accessLevel int8
+	expires     time.Time
+}
+
+// pendingPlayers holds the session tokens the login server minted for this game server
+type pendingPlayers struct {
+	players map[string]pendingPlayer
+	mu      sync.Mutex
+}
+
+func newPendingPlayers() *pendingPlayers {
+	return &pendingPlayers{players: make(map[string]pendingPlayer)}
+}
+
+// Add remembers the token of an account and its access level, replacing any previous one
+func (p *pendingPlayers) Add(account string, loginKey, playKey []byte, accessLevel int8, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name, player := range p.players {
+		if now.After(player.expires) {
+			delete(p.players, name)
+		}
+	}
+
+	p.players[account] = pendingPlayer{loginKey: loginKey, playKey: playKey, accessLevel: accessLevel, expires: now.Add(playerAuthLifetime)}
+}
+
+// Consume checks the keys sent by a client, returning the access level of the account when they
+// match. A token can only be used once.
+func (p *pendingPlayers) Consume(account string, loginKey, playKey []byte, now time.Time) (int8, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	player, ok := p.players[account]
+	if !ok {
+		return 0, false
+	}
+	delete(p.players, account)
+
+	if now.After(player.expires) {
+		return 0, false
+	}
+
+	ok = subtle.ConstantTimeCompare(player.loginKey, loginKey) == 1 && subtle.ConstantTimeCompare(player.playKey, playKey) == 1
+	return player.accessLevel, ok
+}
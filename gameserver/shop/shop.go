@@ -0,0 +1,249 @@
+// Package shop implements the merchants: the goods each of them sells, with
+// their prices and weights, are read from a data file. The players open the buy
+// list or the sell list from the dialog of a merchant, then send what they trade.
+package shop
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"sort"
+
+	"github.com/frostwind/l2go/gameserver/html"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// NPC_TYPE is the NPC type answered by the merchant handler
+const NPC_TYPE = "merchant"
+
+// SELL_DIVISOR divides the price of the items the merchants buy back
+const SELL_DIVISOR = 2
+
+var (
+	ErrUnknownList   = errors.New("unknown buy list")
+	ErrDuplicateList = errors.New("duplicate buy list")
+	ErrInvalidGoods  = errors.New("invalid goods")
+	ErrNotSold       = errors.New("item not traded by the merchant")
+	ErrInvalidCount  = errors.New("invalid item count")
+)
+
+// Goods is an item a merchant sells, for a price in adena
+type Goods struct {
+	ItemID int    `json:"itemId"`
+	Price  uint64 `json:"price"`
+	Weight int    `json:"weight"` // Of a single item
+}
+
+// List holds the goods sold by the merchants of an NPC template
+type List struct {
+	ID    int     `json:"id"`
+	NpcID int     `json:"npcId"`
+	Goods []Goods `json:"goods"`
+}
+
+// Item returns the goods of the list for an item
+func (l *List) Item(itemID int) (Goods, bool) {
+	for _, goods := range l.Goods {
+		if goods.ItemID == itemID {
+			return goods, true
+		}
+	}
+	return Goods{}, false
+}
+
+// Order is an item a player buys or sells, and how many of it
+type Order struct {
+	ItemID int
+	Count  uint64
+}
+
+// Shops holds the buy lists of every merchant template, along with the items they trade
+type Shops struct {
+	lists map[int]*List
+	npcs  map[int]*List // By NPC template
+	items map[int]Goods // Every item sold, at its lowest price
+}
+
+// New returns shops without any merchant
+func New() *Shops {
+	return &Shops{lists: make(map[int]*List), npcs: make(map[int]*List), items: make(map[int]Goods)}
+}
+
+// Parse reads the buy lists, a JSON array of List
+func Parse(r io.Reader) (*Shops, error) {
+	var lists []*List
+	if err := json.NewDecoder(r).Decode(&lists); err != nil {
+		return nil, err
+	}
+
+	shops := New()
+	for _, list := range lists {
+		if _, ok := shops.lists[list.ID]; ok {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateList, list.ID)
+		}
+		if _, ok := shops.npcs[list.NpcID]; ok {
+			return nil, fmt.Errorf("%w: a second one for the NPC %d", ErrDuplicateList, list.NpcID)
+		}
+
+		for _, goods := range list.Goods {
+			if goods.ItemID <= 0 || goods.Price == 0 || goods.Weight < 0 {
+				return nil, fmt.Errorf("%w: the item %d of the list %d costs %d and weighs %d", ErrInvalidGoods, goods.ItemID, list.ID, goods.Price, goods.Weight)
+			}
+			if known, ok := shops.items[goods.ItemID]; !ok || goods.Price < known.Price {
+				shops.items[goods.ItemID] = goods
+			}
+		}
+
+		shops.lists[list.ID] = list
+		shops.npcs[list.NpcID] = list
+	}
+
+	return shops, nil
+}
+
+// Load reads the buy lists from a file
+func Load(path string) (*Shops, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// List returns the buy list with the given id
+func (s *Shops) List(id int) (*List, bool) {
+	list, ok := s.lists[id]
+	return list, ok
+}
+
+// ForNpc returns the buy list of the merchants of an NPC template
+func (s *Shops) ForNpc(npcID int) (*List, bool) {
+	list, ok := s.npcs[npcID]
+	return list, ok
+}
+
+// SellPrice returns what the merchants pay for an item, false for the items none of them sells
+func (s *Shops) SellPrice(itemID int) (uint64, bool) {
+	goods, ok := s.items[itemID]
+	return goods.Price / SELL_DIVISOR, ok
+}
+
+// Weight returns the weight of the items of an inventory, the items no merchant sells weighing nothing
+func (s *Shops) Weight(items map[int]uint64) uint64 {
+	var weight uint64
+	for itemID, count := range items {
+		weight = saturatedAdd(weight, saturatedMul(uint64(s.items[itemID].Weight), count))
+	}
+	return weight
+}
+
+// QuoteBuy returns the price and the weight of the items a player buys from a list
+func (s *Shops) QuoteBuy(list *List, orders []Order) (price, weight uint64, err error) {
+	for _, order := range orders {
+		goods, ok := list.Item(order.ItemID)
+		if !ok {
+			return 0, 0, fmt.Errorf("%w: the list %d doesn't sell the item %d", ErrNotSold, list.ID, order.ItemID)
+		}
+		if order.Count == 0 {
+			return 0, 0, fmt.Errorf("%w: 0 of the item %d", ErrInvalidCount, order.ItemID)
+		}
+
+		price = saturatedAdd(price, saturatedMul(goods.Price, order.Count))
+		weight = saturatedAdd(weight, saturatedMul(uint64(goods.Weight), order.Count))
+	}
+	return price, weight, nil
+}
+
+// QuoteSell returns what the merchants pay for the items a player sells
+func (s *Shops) QuoteSell(orders []Order) (uint64, error) {
+	var price uint64
+	for _, order := range orders {
+		itemPrice, ok := s.SellPrice(order.ItemID)
+		if !ok {
+			return 0, fmt.Errorf("%w: no merchant buys the item %d", ErrNotSold, order.ItemID)
+		}
+		if order.Count == 0 {
+			return 0, fmt.Errorf("%w: 0 of the item %d", ErrInvalidCount, order.ItemID)
+		}
+
+		price = saturatedAdd(price, saturatedMul(itemPrice, order.Count))
+	}
+	return price, nil
+}
+
+// saturatedAdd adds two amounts, a huge order costing more than anyone can pay instead of wrapping around
+func saturatedAdd(a, b uint64) uint64 {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return math.MaxUint64
+	}
+	return sum
+}
+
+// saturatedMul multiplies two amounts, the same way
+func saturatedMul(a, b uint64) uint64 {
+	high, low := bits.Mul64(a, b)
+	if high != 0 {
+		return math.MaxUint64
+	}
+	return low
+}
+
+// Handler is the dialog handler of the merchants
+type Handler struct {
+	Shops     *Shops
+	Inventory func(client *models.Client) (uint64, map[int]uint64) // Copies the adena and the items of a player
+}
+
+// Talk shows merchant/<template id>.htm, or a generated dialog offering to buy and sell
+func (h *Handler) Talk(d *html.Dialog) error {
+	err := d.Show(fmt.Sprintf("merchant/%d.htm", d.Npc.TemplateID))
+	if errors.Is(err, html.ErrDialogNotFound) {
+		return d.ShowHTML(`<html><body>Merchant %npcName%:<br>What are you looking for?<br>` +
+			`<a action="bypass -h npc_%objectId%_Buy">Buy</a><br>` +
+			`<a action="bypass -h npc_%objectId%_Sell">Sell</a></body></html>`)
+	}
+	return err
+}
+
+// Bypass answers "Buy", opening the goods of the merchant, "Sell", opening the items
+// of the player it buys back, and "Chat 0", which shows the first dialog again
+func (h *Handler) Bypass(d *html.Dialog, bypass html.Bypass) error {
+	switch bypass.Command {
+	case "Chat":
+		return h.Talk(d)
+	case "Buy", "Sell":
+	default:
+		return fmt.Errorf("%w: %s", html.ErrUnknownCommand, bypass.Command)
+	}
+
+	list, ok := h.Shops.ForNpc(d.Npc.TemplateID)
+	if !ok {
+		return fmt.Errorf("%w: none for the NPC %d", ErrUnknownList, d.Npc.TemplateID)
+	}
+	adena, items := h.Inventory(d.Client)
+
+	if bypass.Command == "Buy" {
+		goods := make([]serverpackets.Goods, len(list.Goods))
+		for i, item := range list.Goods {
+			goods[i] = serverpackets.Goods{ItemID: uint32(item.ItemID), Price: item.Price, Weight: uint32(item.Weight)}
+		}
+		return d.Client.Send(serverpackets.NewBuyListPacket(adena, uint32(list.ID), goods))
+	}
+
+	var sold []serverpackets.SellItem
+	for itemID, count := range items {
+		if price, ok := h.Shops.SellPrice(itemID); ok && count > 0 {
+			sold = append(sold, serverpackets.SellItem{ItemID: uint32(itemID), Count: count, Price: price})
+		}
+	}
+	sort.Slice(sold, func(i, j int) bool { return sold[i].ItemID < sold[j].ItemID })
+	return d.Client.Send(serverpackets.NewSellListPacket(adena, uint32(list.ID), sold))
+}
@@ -0,0 +1,182 @@
+package shop
+
+import (
+	"errors"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/crypt/xor"
+	"github.com/frostwind/l2go/gameserver/html"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+const testShops = `[
+  {"id": 1, "npcId": 30001, "goods": [
+    {"itemId": 1835, "price": 10, "weight": 5},
+    {"itemId": 1060, "price": 40, "weight": 80}
+  ]},
+  {"id": 2, "npcId": 30002, "goods": [
+    {"itemId": 1835, "price": 8, "weight": 5}
+  ]}
+]`
+
+func TestParse(t *testing.T) {
+	shops, err := Parse(strings.NewReader(testShops))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if list, ok := shops.ForNpc(30002); !ok || list.ID != 2 {
+		t.Errorf("ForNpc() = %+v, %v", list, ok)
+	}
+	if goods, ok := shops.lists[1].Item(1060); !ok || goods.Price != 40 {
+		t.Errorf("Item() = %+v, %v", goods, ok)
+	}
+	if _, ok := shops.List(3); ok {
+		t.Error("List() found an unknown list")
+	}
+
+	// The merchants buy back for half the lowest price
+	if price, ok := shops.SellPrice(1835); !ok || price != 4 {
+		t.Errorf("SellPrice() = %d, %v, want 4", price, ok)
+	}
+	if _, ok := shops.SellPrice(57); ok {
+		t.Error("SellPrice() priced an item no merchant sells")
+	}
+	if weight := shops.Weight(map[int]uint64{1835: 3, 1060: 1, 57: 1000}); weight != 95 {
+		t.Errorf("Weight() = %d, want 95", weight)
+	}
+
+	invalid := []struct {
+		name   string
+		source string
+		want   error
+	}{
+		{"duplicate id", `[{"id": 1, "npcId": 1}, {"id": 1, "npcId": 2}]`, ErrDuplicateList},
+		{"duplicate NPC", `[{"id": 1, "npcId": 1}, {"id": 2, "npcId": 1}]`, ErrDuplicateList},
+		{"free goods", `[{"id": 1, "npcId": 1, "goods": [{"itemId": 1835}]}]`, ErrInvalidGoods},
+		{"negative weight", `[{"id": 1, "npcId": 1, "goods": [{"itemId": 1835, "price": 1, "weight": -1}]}]`, ErrInvalidGoods},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.source)); !errors.Is(err, tt.want) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	shops, err := Parse(strings.NewReader(testShops))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	list, _ := shops.List(1)
+
+	tests := []struct {
+		name       string
+		orders     []Order
+		wantPrice  uint64
+		wantWeight uint64
+		wantSell   uint64
+		err        error
+	}{
+		{name: "nothing"},
+		{name: "goods", orders: []Order{{ItemID: 1835, Count: 20}, {ItemID: 1060, Count: 2}}, wantPrice: 280, wantWeight: 260, wantSell: 120},
+		{name: "not sold", orders: []Order{{ItemID: 57, Count: 1}}, err: ErrNotSold},
+		{name: "no item", orders: []Order{{ItemID: 1835}}, err: ErrInvalidCount},
+		{name: "overflow", orders: []Order{{ItemID: 1060, Count: math.MaxUint64 / 10}}, wantPrice: math.MaxUint64, wantWeight: math.MaxUint64, wantSell: math.MaxUint64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, weight, err := shops.QuoteBuy(list, tt.orders)
+			if !errors.Is(err, tt.err) || price != tt.wantPrice || weight != tt.wantWeight {
+				t.Errorf("QuoteBuy() = %d, %d, %v, want %d, %d, %v", price, weight, err, tt.wantPrice, tt.wantWeight, tt.err)
+			}
+
+			sell, err := shops.QuoteSell(tt.orders)
+			if !errors.Is(err, tt.err) || sell != tt.wantSell {
+				t.Errorf("QuoteSell() = %d, %v, want %d, %v", sell, err, tt.wantSell, tt.err)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	shops, err := Parse(strings.NewReader(testShops))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	dialogs := html.NewDialogs(html.NewCache(t.TempDir()))
+	dialogs.Register(NPC_TYPE, &Handler{Shops: shops, Inventory: func(client *models.Client) (uint64, map[int]uint64) {
+		return client.Adena, client.Items
+	}})
+
+	server, conn := net.Pipe()
+	defer server.Close()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	client := models.NewClient()
+	client.Socket = server
+	client.ObjectID = 0x10000000
+	client.Adena = 500
+	client.Items = map[int]uint64{1060: 3, 1864: 1}
+	key := xor.NewCipher().OutputKey
+
+	receive := func(t *testing.T, opcode byte) *packets.Reader {
+		t.Helper()
+
+		data, err := packets.ReadFrame(conn, packets.MaxFrameSize)
+		if err != nil {
+			t.Fatalf("ReadFrame() error = %v", err)
+		}
+		xor.Decrypt(data, key)
+		if data[0] != opcode {
+			t.Fatalf("expected %#x, got %#x", opcode, data[0])
+		}
+		return packets.NewReader(data[1:])
+	}
+
+	npc := &models.Npc{ObjectID: 100, TemplateID: 30001, Type: NPC_TYPE, Name: "Lector"}
+
+	go dialogs.Talk(npc, client)
+	reader := receive(t, opcodes.GameServerNpcHtmlMessage)
+	reader.ReadUInt32()
+	if dialog := reader.ReadString(); !strings.Contains(dialog, `<a action="bypass -h npc_100_Sell">Sell</a>`) {
+		t.Errorf("merchant dialog = %q", dialog)
+	}
+
+	go dialogs.Bypass(npc, client, html.Bypass{ObjectID: 100, Command: "Buy"})
+	reader = receive(t, opcodes.GameServerBuyList)
+	if adena, listID, count := reader.ReadUInt64(), reader.ReadUInt32(), reader.ReadUInt16(); adena != 500 || listID != 1 || count != 2 {
+		t.Errorf("BuyList of list %d with %d adena and %d goods", listID, adena, count)
+	}
+	if itemID, price, weight := reader.ReadUInt32(), reader.ReadUInt64(), reader.ReadUInt32(); itemID != 1835 || price != 10 || weight != 5 {
+		t.Errorf("first goods = %d for %d, weighing %d", itemID, price, weight)
+	}
+
+	// The merchant only buys back the items it knows the price of
+	go dialogs.Bypass(npc, client, html.Bypass{ObjectID: 100, Command: "Sell"})
+	reader = receive(t, opcodes.GameServerSellList)
+	reader.ReadUInt64()
+	reader.ReadUInt32()
+	if count := reader.ReadUInt16(); count != 1 {
+		t.Fatalf("SellList of %d items, want 1", count)
+	}
+	if itemID, count, price := reader.ReadUInt32(), reader.ReadUInt64(), reader.ReadUInt64(); itemID != 1060 || count != 3 || price != 20 {
+		t.Errorf("sold item = %d of %d for %d", count, itemID, price)
+	}
+
+	err = dialogs.Bypass(&models.Npc{ObjectID: 101, TemplateID: 30003, Type: NPC_TYPE}, client, html.Bypass{ObjectID: 101, Command: "Buy"})
+	if !errors.Is(err, ErrUnknownList) {
+		t.Errorf("Bypass() error = %v, want %v", err, ErrUnknownList)
+	}
+}
@@ -0,0 +1,184 @@
+package gameserver
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/frostwind/l2go/gameserver/clientpackets"
+	"github.com/frostwind/l2go/gameserver/drops"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/gameserver/shop"
+)
+
+var (
+	ErrNotAtMerchant  = errors.New("not talking to a merchant of the list")
+	ErrNotEnoughAdena = errors.New("not enough adena")
+	ErrNotEnoughItems = errors.New("not enough items")
+	ErrWeightLimit    = errors.New("weight limit exceeded")
+	ErrInventoryFull  = errors.New("inventory full")
+)
+
+// Shops returns the buy lists of the merchants of the world
+func (g *GameServer) Shops() *shop.Shops {
+	return g.shops
+}
+
+// BuyItems buys items of a buy list for a player talking to one of its merchants, as long as it
+// has the adena and can carry them at its level. The player is sent its inventory, or why nothing was bought.
+func (g *GameServer) BuyItems(client *models.Client, listID int, orders []shop.Order) error {
+	list, err := g.merchantList(client, listID)
+	if err != nil {
+		return err
+	}
+	price, _, err := g.shops.QuoteBuy(list, orders)
+	if err != nil {
+		return err
+	}
+
+	bought := make(map[int]uint64, len(orders))
+	for _, order := range orders {
+		bought[order.ItemID] += order.Count
+	}
+
+	limits := g.Limits(client)
+	g.itemsMutex.Lock()
+	if client.Adena < price {
+		err = fmt.Errorf("%w: %d needed, %d owned", ErrNotEnoughAdena, price, client.Adena)
+	} else {
+		err = g.canCarry(client, limits, bought)
+	}
+	if err == nil {
+		client.Adena -= price
+		for _, order := range orders {
+			g.give(client, order.ItemID, order.Count)
+		}
+	}
+	g.itemsMutex.Unlock()
+
+	if err != nil {
+		g.refuseTrade(client, err)
+		return err
+	}
+
+	fmt.Printf("Player %d bought %d items of the list %d for %d adena\n", client.ObjectID, len(orders), listID, price)
+	g.sendItemList(client)
+	return nil
+}
+
+// SellItems sells items of a player to the merchant it is talking to, for a part of their price.
+// The player is sent its inventory, or why nothing was sold.
+func (g *GameServer) SellItems(client *models.Client, listID int, orders []shop.Order) error {
+	if _, err := g.merchantList(client, listID); err != nil {
+		return err
+	}
+	price, err := g.shops.QuoteSell(orders)
+	if err != nil {
+		return err
+	}
+
+	// The same item may be listed twice
+	counts := make(map[int]uint64, len(orders))
+	for _, order := range orders {
+		if counts[order.ItemID]+order.Count < order.Count {
+			return fmt.Errorf("%w: more than %d of the item %d", shop.ErrInvalidCount, counts[order.ItemID], order.ItemID)
+		}
+		counts[order.ItemID] += order.Count
+	}
+
+	g.itemsMutex.Lock()
+	for itemID, count := range counts {
+		if client.Items[itemID] < count {
+			err = fmt.Errorf("%w: %d of the item %d, %d owned", ErrNotEnoughItems, count, itemID, client.Items[itemID])
+			break
+		}
+	}
+	if err == nil {
+		for itemID, count := range counts {
+			client.Items[itemID] -= count
+			if client.Items[itemID] == 0 {
+				delete(client.Items, itemID)
+			}
+		}
+		g.give(client, drops.ADENA_ID, price)
+	}
+	g.itemsMutex.Unlock()
+
+	if err != nil {
+		g.refuseTrade(client, err)
+		return err
+	}
+
+	fmt.Printf("Player %d sold %d items to the list %d for %d adena\n", client.ObjectID, len(orders), listID, price)
+	g.sendItemList(client)
+	return nil
+}
+
+// orders converts the items of a buy or sell request
+func orders(items []clientpackets.ItemCount) []shop.Order {
+	orders := make([]shop.Order, len(items))
+	for i, item := range items {
+		orders[i] = shop.Order{ItemID: int(item.ItemID), Count: item.Count}
+	}
+	return orders
+}
+
+// merchantList returns a buy list, as long as the player is in the world and selected one of its merchants
+func (g *GameServer) merchantList(client *models.Client, listID int) (*shop.List, error) {
+	if !g.playing(client) {
+		return nil, ErrNotInWorld
+	}
+	list, ok := g.shops.List(listID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", shop.ErrUnknownList, listID)
+	}
+
+	npc, ok := g.Npc(client.TargetID)
+	if !ok || npc.TemplateID != list.NpcID {
+		return nil, fmt.Errorf("%w: %d", ErrNotAtMerchant, listID)
+	}
+	return list, nil
+}
+
+// refuseTrade tells a player why the merchant refused its trade
+func (g *GameServer) refuseTrade(client *models.Client, err error) {
+	var messageID uint32
+	switch {
+	case errors.Is(err, ErrNotEnoughAdena):
+		messageID = serverpackets.SYSTEM_MESSAGE_NOT_ENOUGH_ADENA
+	case errors.Is(err, ErrWeightLimit):
+		messageID = serverpackets.SYSTEM_MESSAGE_WEIGHT_LIMIT
+	case errors.Is(err, ErrInventoryFull):
+		messageID = serverpackets.SYSTEM_MESSAGE_INVENTORY_FULL
+	default:
+		messageID = serverpackets.SYSTEM_MESSAGE_INCORRECT_COUNT
+	}
+
+	if err := client.Send(serverpackets.NewSystemMessagePacket(messageID)); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// inventory copies the adena and the items of a player
+func (g *GameServer) inventory(client *models.Client) (uint64, map[int]uint64) {
+	g.itemsMutex.Lock()
+	defer g.itemsMutex.Unlock()
+
+	return client.Adena, maps.Clone(client.Items)
+}
+
+// sendItemList sends its inventory to a player, then what it carries
+func (g *GameServer) sendItemList(client *models.Client) {
+	adena, items := g.inventory(client)
+	if items == nil {
+		items = make(map[int]uint64)
+	}
+	items[drops.ADENA_ID] = adena
+
+	pages := serverpackets.NewItemListPackets(serverpackets.Items(items), g.config.GameServer.Options.ListPacketSize)
+	if err := client.SendPages(pages); err != nil {
+		fmt.Println(err)
+	}
+	g.updateLoad(client)
+}
@@ -0,0 +1,67 @@
+package gameserver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// Actions of the action window the players use through RequestActionUse
+const (
+	ACTION_SIT_STAND = 0
+)
+
+var (
+	ErrUnsupportedAction   = errors.New("unsupported action")
+	ErrInvalidSocialAction = errors.New("invalid social action")
+	ErrSitting             = errors.New("the player is sitting")
+)
+
+// UseAction runs an action of the action window of a player, only sitting down and standing up
+// being supported for now
+func (g *GameServer) UseAction(client *models.Client, actionID int) error {
+	switch actionID {
+	case ACTION_SIT_STAND:
+		g.SitStand(client)
+		return nil
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupportedAction, actionID)
+	}
+}
+
+// SitStand makes a player sit down, or stand up when it already sits, the players around seeing it
+func (g *GameServer) SitStand(client *models.Client) {
+	// The regeneration of the game loop reads it under the same lock
+	g.progressMutex.Lock()
+	client.Sitting = !client.Sitting
+	sitting := client.Sitting
+	g.progressMutex.Unlock()
+
+	waitType := uint32(serverpackets.WAIT_TYPE_STANDING)
+	if sitting {
+		waitType = serverpackets.WAIT_TYPE_SITTING
+	}
+	x, y, z := client.Position()
+	g.broadcastSocial(x, y, serverpackets.NewChangeWaitTypePacket(client.ObjectID, waitType, x, y, z))
+}
+
+// SocialAction plays an emote of a player for the players around. The actions the server plays
+// by itself, such as the level up glow, are refused, and so are the emotes of a sitting player.
+func (g *GameServer) SocialAction(client *models.Client, actionID int) error {
+	if actionID < serverpackets.SOCIAL_ACTION_GREETING || actionID > serverpackets.SOCIAL_ACTION_SORROW {
+		return fmt.Errorf("%w: %d", ErrInvalidSocialAction, actionID)
+	}
+	g.progressMutex.Lock()
+	sitting := client.Sitting
+	g.progressMutex.Unlock()
+
+	if sitting {
+		return fmt.Errorf("%w: %d can't play the social action %d", ErrSitting, client.ObjectID, actionID)
+	}
+
+	x, y, _ := client.Position()
+	g.broadcastSocial(x, y, serverpackets.NewSocialActionPacket(client.ObjectID, uint32(actionID)))
+	return nil
+}
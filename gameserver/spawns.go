@@ -0,0 +1,47 @@
+package gameserver
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/frostwind/l2go/gameserver/ai"
+	"github.com/frostwind/l2go/gameserver/models"
+)
+
+// spawn is an NPC placed in the world by the spawns data file
+type spawn struct {
+	TemplateID int         `json:"templateId"`
+	Type       string      `json:"type"`
+	Name       string      `json:"name"`
+	X          int32       `json:"x"`
+	Y          int32       `json:"y"`
+	Z          int32       `json:"z"`
+	AI         *ai.Profile `json:"ai,omitempty"` // Behavior of the NPC, which stands still without one
+}
+
+// loadSpawns reads the NPCs to place in the world
+func loadSpawns(path string) ([]spawn, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var spawns []spawn
+	if err := json.Unmarshal(file, &spawns); err != nil {
+		return nil, err
+	}
+
+	return spawns, nil
+}
+
+// npc returns the NPC of a spawn, it gets its object id once spawned
+func (s spawn) npc() *models.Npc {
+	return &models.Npc{
+		TemplateID: s.TemplateID,
+		Type:       s.Type,
+		Name:       s.Name,
+		X:          s.X,
+		Y:          s.Y,
+		Z:          s.Z,
+	}
+}
@@ -0,0 +1,159 @@
+// Package teleport implements the gatekeepers: the destinations each of them
+// offers are read from a data file, and players paying the price are moved
+// there, the players around being told about it.
+package teleport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/frostwind/l2go/gameserver/html"
+	"github.com/frostwind/l2go/gameserver/models"
+)
+
+// NPC_TYPE is the NPC type answered by the gatekeeper handler
+const NPC_TYPE = "gatekeeper"
+
+var (
+	ErrUnknownDestination   = errors.New("unknown destination")
+	ErrDuplicateDestination = errors.New("duplicate destination")
+)
+
+// Destination is a place a gatekeeper sends the players to
+type Destination struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	X     int32  `json:"x"`
+	Y     int32  `json:"y"`
+	Z     int32  `json:"z"`
+	Price uint64 `json:"price"`
+}
+
+// List holds the destinations offered by the gatekeepers of an NPC template
+type List struct {
+	NpcID        int           `json:"npcId"`
+	Destinations []Destination `json:"destinations"`
+}
+
+// Teleports holds the destinations of every gatekeeper template
+type Teleports struct {
+	lists map[int][]Destination
+}
+
+// Parse reads the teleport lists, a JSON array of List
+func Parse(r io.Reader) (*Teleports, error) {
+	var lists []List
+	if err := json.NewDecoder(r).Decode(&lists); err != nil {
+		return nil, err
+	}
+
+	teleports := &Teleports{lists: make(map[int][]Destination)}
+	for _, list := range lists {
+		for _, destination := range list.Destinations {
+			if _, ok := teleports.Destination(list.NpcID, destination.ID); ok {
+				return nil, fmt.Errorf("%w: %d for the NPC %d", ErrDuplicateDestination, destination.ID, list.NpcID)
+			}
+			teleports.lists[list.NpcID] = append(teleports.lists[list.NpcID], destination)
+		}
+	}
+
+	return teleports, nil
+}
+
+// Load reads the teleport lists from a file
+func Load(path string) (*Teleports, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Destinations returns the destinations offered by the gatekeepers of an NPC template
+func (t *Teleports) Destinations(npcID int) []Destination {
+	return t.lists[npcID]
+}
+
+// Destination returns a destination offered by the gatekeepers of an NPC template
+func (t *Teleports) Destination(npcID, id int) (Destination, bool) {
+	for _, destination := range t.lists[npcID] {
+		if destination.ID == id {
+			return destination, true
+		}
+	}
+	return Destination{}, false
+}
+
+// Handler is the dialog handler of the gatekeepers
+type Handler struct {
+	Teleports *Teleports
+	Teleport  func(client *models.Client, x, y, z int32) // Moves a player, showing it to the ones around its destination
+}
+
+// Talk shows teleporter/<template id>.htm, or a generated list of the destinations
+func (h *Handler) Talk(d *html.Dialog) error {
+	err := d.Show(fmt.Sprintf("teleporter/%d.htm", d.Npc.TemplateID))
+	if errors.Is(err, html.ErrDialogNotFound) {
+		return d.ShowHTML(h.list(d.Npc.TemplateID))
+	}
+	return err
+}
+
+// Bypass answers "teleport <destination id>" and "Chat 0", which shows the destinations again
+func (h *Handler) Bypass(d *html.Dialog, bypass html.Bypass) error {
+	switch bypass.Command {
+	case "Chat":
+		return h.Talk(d)
+	case "teleport":
+		id, err := strconv.Atoi(bypass.Arg(0))
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrUnknownDestination, bypass.Arg(0))
+		}
+		return h.teleport(d, id)
+	default:
+		return fmt.Errorf("%w: %s", html.ErrUnknownCommand, bypass.Command)
+	}
+}
+
+// teleport moves the player to the destination once it is paid
+func (h *Handler) teleport(d *html.Dialog, id int) error {
+	destination, ok := h.Teleports.Destination(d.Npc.TemplateID, id)
+	if !ok {
+		return fmt.Errorf("%w: %d for the NPC %d", ErrUnknownDestination, id, d.Npc.TemplateID)
+	}
+
+	client := d.Client
+	if client.Adena < destination.Price {
+		err := d.Show("teleporter/noadena.htm")
+		if errors.Is(err, html.ErrDialogNotFound) {
+			return d.ShowHTML("<html><body>You don't have enough adena.</body></html>")
+		}
+		return err
+	}
+
+	client.Adena -= destination.Price
+	client.TargetID = 0
+
+	h.Teleport(client, destination.X, destination.Y, destination.Z)
+	return nil
+}
+
+// list builds the dialog listing the destinations of a gatekeeper template
+func (h *Handler) list(npcID int) string {
+	var builder strings.Builder
+
+	builder.WriteString("<html><body>Gatekeeper %npcName%:<br>Where do you want to go?<br>")
+	for _, destination := range h.Teleports.Destinations(npcID) {
+		fmt.Fprintf(&builder, `<a action="bypass -h npc_%%objectId%%_teleport %d">%s - %d Adena</a><br>`, destination.ID, destination.Name, destination.Price)
+	}
+	builder.WriteString("</body></html>")
+
+	return builder.String()
+}
@@ -0,0 +1,117 @@
+package teleport
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/crypt/xor"
+	"github.com/frostwind/l2go/gameserver/html"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+const testTeleports = `[
+  {"npcId": 30006, "destinations": [
+    {"id": 1, "name": "Gludio", "x": -12672, "y": 122776, "z": -3116, "price": 7300},
+    {"id": 2, "name": "Dion", "x": 15670, "y": 142983, "z": -2705, "price": 11000}
+  ]}
+]`
+
+func TestParse(t *testing.T) {
+	teleports, err := Parse(strings.NewReader(testTeleports))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if got := len(teleports.Destinations(30006)); got != 2 {
+		t.Errorf("Destinations() returned %d destinations, want 2", got)
+	}
+	if destination, ok := teleports.Destination(30006, 2); !ok || destination.Name != "Dion" {
+		t.Errorf("Destination() = %+v, %v", destination, ok)
+	}
+	if _, ok := teleports.Destination(30080, 1); ok {
+		t.Error("Destination() found a destination for an unknown NPC")
+	}
+
+	duplicate := `[{"npcId": 1, "destinations": [{"id": 1}, {"id": 1}]}]`
+	if _, err := Parse(strings.NewReader(duplicate)); !errors.Is(err, ErrDuplicateDestination) {
+		t.Errorf("Parse() error = %v, want %v", err, ErrDuplicateDestination)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	teleports, err := Parse(strings.NewReader(testTeleports))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	teleported := 0
+	handler := &Handler{Teleports: teleports, Teleport: func(client *models.Client, x, y, z int32) {
+		client.X, client.Y, client.Z = x, y, z
+		teleported++
+	}}
+
+	dialogs := html.NewDialogs(html.NewCache(t.TempDir()))
+	dialogs.Register(NPC_TYPE, handler)
+
+	server, conn := net.Pipe()
+	defer server.Close()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	client := models.NewClient()
+	client.Socket = server
+	client.ObjectID = 0x10000000
+	client.Adena = 10000
+	key := xor.NewCipher().OutputKey
+
+	receive := func(t *testing.T) string {
+		t.Helper()
+
+		data, err := packets.ReadFrame(conn, packets.MaxFrameSize)
+		if err != nil {
+			t.Fatalf("ReadFrame() error = %v", err)
+		}
+		xor.Decrypt(data, key)
+		if data[0] != opcodes.GameServerNpcHtmlMessage {
+			t.Fatalf("expected NpcHtmlMessage, got %#x", data[0])
+		}
+		reader := packets.NewReader(data[1:])
+		reader.ReadUInt32()
+		return reader.ReadString()
+	}
+
+	npc := &models.Npc{ObjectID: 100, TemplateID: 30006, Type: NPC_TYPE, Name: "Roxxy"}
+
+	go dialogs.Talk(npc, client)
+	list := receive(t)
+	if !strings.Contains(list, `<a action="bypass -h npc_100_teleport 1">Gludio - 7300 Adena</a>`) {
+		t.Errorf("destinations dialog = %q", list)
+	}
+
+	// Dion costs more than the player owns
+	go dialogs.Bypass(npc, client, html.Bypass{ObjectID: 100, Command: "teleport", Args: []string{"2"}})
+	if refused := receive(t); !strings.Contains(refused, "enough adena") {
+		t.Errorf("refusal dialog = %q", refused)
+	}
+	if teleported != 0 || client.Adena != 10000 {
+		t.Fatalf("refused teleport moved the player %d times and left %d adena", teleported, client.Adena)
+	}
+
+	err = dialogs.Bypass(npc, client, html.Bypass{ObjectID: 100, Command: "teleport", Args: []string{"1"}})
+	if err != nil {
+		t.Fatalf("Bypass() error = %v", err)
+	}
+	if teleported != 1 || client.Adena != 2700 || client.X != -12672 || client.Y != 122776 || client.Z != -3116 {
+		t.Errorf("player after %d teleports: adena %d at %d,%d,%d", teleported, client.Adena, client.X, client.Y, client.Z)
+	}
+
+	err = dialogs.Bypass(npc, client, html.Bypass{ObjectID: 100, Command: "teleport", Args: []string{"9"}})
+	if !errors.Is(err, ErrUnknownDestination) {
+		t.Errorf("Bypass() error = %v, want %v", err, ErrUnknownDestination)
+	}
+}
@@ -0,0 +1,156 @@
+package gameserver
+
+import (
+	"fmt"
+
+	"github.com/frostwind/l2go/gameserver/clientpackets"
+	"github.com/frostwind/l2go/gameserver/interest"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/sendqueue"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// MONSTER_TYPE is the type of the NPCs the players can attack
+const MONSTER_TYPE = "monster"
+
+// enterWorld shows an authenticated player to the players around, and shows it what it sees
+func (g *GameServer) enterWorld(client *models.Client) {
+	x, y, z := client.Position()
+	g.publish(g.interest.Add(interest.Object{ObjectID: client.ObjectID, Kind: interest.PLAYER, X: x, Y: y, Z: z}), nil)
+}
+
+// leaveWorld hides a leaving player from the players around
+func (g *GameServer) leaveWorld(client *models.Client) {
+	if update, ok := g.interest.Remove(client.ObjectID); ok {
+		g.publish(update, nil)
+	}
+}
+
+// Teleport moves a player, the players around the destination starting to see it
+func (g *GameServer) Teleport(client *models.Client, x, y, z int32) {
+	client.SetPosition(x, y, z)
+	client.Movement.Stop(g.clock.Now())
+	g.markDirty(client)
+
+	packet := serverpackets.NewTeleportToLocationPacket(client.ObjectID, x, y, z)
+	if err := client.Send(packet); err != nil {
+		fmt.Println(err)
+	}
+
+	if update, ok := g.interest.Move(client.ObjectID, x, y, z); ok {
+		g.publish(update, packet)
+	}
+}
+
+// say sends a chat message of a player to the players around it, or whispers it to another player
+func (g *GameServer) say(client *models.Client, message clientpackets.Say2) {
+	switch message.ChatType {
+	case serverpackets.CHAT_ALL:
+		// Characters aren't stored yet, the players speak under the name of their account
+		x, y, _ := client.Position()
+		g.broadcastSocial(x, y, serverpackets.NewCreatureSayPacket(client.ObjectID, message.ChatType, client.Account, message.Text))
+	case serverpackets.CHAT_TELL:
+		if err := g.Whisper(client, message.Target, message.Text); err != nil {
+			fmt.Println(err)
+		}
+	default:
+		fmt.Printf("Couldn't handle the chat type %d\n", message.ChatType)
+	}
+}
+
+// publish tells the players about an object which appeared, moved or left: the ones
+// starting to see it get its info and the ones no longer seeing it its removal, while
+// a moving player learns about the objects it starts or stops seeing. The packet, if
+// any, goes to the players seeing the object both before and after.
+//
+// The packet is only a move, the next one making up for it if it is shed, while a
+// client missing an object coming or going would show it wrong until it leaves sight.
+func (g *GameServer) publish(update interest.Update, packet []byte) {
+	if packet != nil {
+		g.sendTo(update.Watching, sendqueue.NORMAL, packet)
+	}
+
+	if len(update.Entered) > 0 {
+		if info := g.objectInfo(update.Object); info != nil {
+			g.sendTo(update.Entered, sendqueue.CRITICAL, info)
+		}
+	}
+	g.sendTo(update.Left, sendqueue.CRITICAL, serverpackets.NewDeleteObjectPacket(update.Object.ObjectID))
+
+	if len(update.Shown) == 0 && len(update.Hidden) == 0 {
+		return
+	}
+	player, ok := g.Player(update.Object.ObjectID)
+	if !ok {
+		return
+	}
+	for _, object := range update.Shown {
+		if info := g.objectInfo(object); info != nil {
+			if err := player.Send(info); err != nil {
+				fmt.Println(err)
+			}
+		}
+	}
+	for _, objectID := range update.Hidden {
+		if err := player.Send(serverpackets.NewDeleteObjectPacket(objectID)); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// objectInfo returns the packet showing an object coming into sight, nil if it is already gone
+func (g *GameServer) objectInfo(object interest.Object) []byte {
+	switch object.Kind {
+	case interest.PLAYER:
+		if player, ok := g.Player(object.ObjectID); ok {
+			return serverpackets.NewCharInfoPacket(player.ObjectID, player.Account, object.X, object.Y, object.Z)
+		}
+	case interest.NPC:
+		if npc, ok := g.Npc(object.ObjectID); ok {
+			return serverpackets.NewNpcInfoPacket(npc.ObjectID, npc.TemplateID, npc.Type == MONSTER_TYPE, npc.Name, object.X, object.Y, object.Z)
+		}
+	case interest.ITEM:
+		if item, ok := g.GroundItem(object.ObjectID); ok {
+			return serverpackets.NewSpawnItemPacket(item.ObjectID, item.ItemID, item.X, item.Y, item.Z, item.Count)
+		}
+	}
+	return nil
+}
+
+// broadcastAround sends a packet to the players seeing a location
+func (g *GameServer) broadcastAround(x, y int32, priority sendqueue.Priority, packet []byte) {
+	g.sendTo(g.interest.Observers(x, y), priority, packet)
+}
+
+// broadcastSocial sends the chat or an animation to the players seeing a location, the ones
+// standing in another region than it getting it at a lower priority than the ones close by
+func (g *GameServer) broadcastSocial(x, y int32, packet []byte) {
+	region := interest.RegionOf(x, y)
+	for _, objectID := range g.interest.Observers(x, y) {
+		player, ok := g.Player(objectID)
+		if !ok {
+			continue
+		}
+
+		priority := sendqueue.NORMAL
+		if x, y, _ := player.Position(); interest.RegionOf(x, y) != region {
+			priority = sendqueue.LOW
+		}
+		if err := player.SendWithPriority(packet, priority); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// sendTo sends a packet to some players, the slow ones missing it if the send policy sheds its priority
+func (g *GameServer) sendTo(objectIDs []uint32, priority sendqueue.Priority, packet []byte) {
+	for _, objectID := range objectIDs {
+		player, ok := g.Player(objectID)
+		if !ok {
+			continue
+		}
+		if err := player.SendWithPriority(packet, priority); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Package vitals regenerates the HP, the MP and the CP of the players at the
+// rates of their class, read from a data file. The players regenerate faster
+// sitting and not at all while fighting, and their clients are only told of
+// the points they regained once these add up.
+package vitals
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+)
+
+// Vital is a pool of points of a player
+type Vital int
+
+const (
+	HP Vital = iota
+	MP
+	CP
+	COUNT // Number of vitals
+)
+
+const (
+	// A sitting player regenerates SIT_BONUS times faster
+	SIT_BONUS = 1.5
+
+	// A player stops regenerating until COMBAT_PAUSE after it was last attacked
+	COMBAT_PAUSE = 15 * time.Second
+
+	// The clients are told of the points regained once they add up to BROADCAST_DELTA,
+	// or fill the vital
+	BROADCAST_DELTA = 5
+)
+
+var ErrInvalidTable = errors.New("invalid regeneration table")
+
+//go:embed regeneration.json
+var bundled []byte
+
+func (v Vital) String() string {
+	switch v {
+	case HP:
+		return "HP"
+	case MP:
+		return "MP"
+	case CP:
+		return "CP"
+	default:
+		return fmt.Sprintf("Vital(%d)", int(v))
+	}
+}
+
+// Values are the points of every vital of a player
+type Values [COUNT]int
+
+// Rates are the points regained per second standing
+type Rates struct {
+	HP float64 `json:"hp"`
+	MP float64 `json:"mp"`
+	CP float64 `json:"cp"`
+}
+
+// Of returns the rate of a vital
+func (r Rates) Of(vital Vital) float64 {
+	switch vital {
+	case HP:
+		return r.HP
+	case MP:
+		return r.MP
+	case CP:
+		return r.CP
+	default:
+		return 0
+	}
+}
+
+// Class is an entry of the regeneration table
+type Class struct {
+	ClassID int    `json:"class"`
+	Name    string `json:"name"`
+	Rates
+}
+
+// Table gives the rates of every class. The classes it doesn't list regenerate
+// at the rates of its first one.
+type Table struct {
+	rates    map[int]Rates
+	fallback Rates
+}
+
+// Parse reads a regeneration table, a JSON array of Class
+func Parse(r io.Reader) (*Table, error) {
+	var classes []Class
+	if err := json.NewDecoder(r).Decode(&classes); err != nil {
+		return nil, err
+	}
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("%w: no class", ErrInvalidTable)
+	}
+
+	table := &Table{rates: make(map[int]Rates, len(classes)), fallback: classes[0].Rates}
+	for _, class := range classes {
+		if _, ok := table.rates[class.ClassID]; ok {
+			return nil, fmt.Errorf("%w: class %d listed twice", ErrInvalidTable, class.ClassID)
+		}
+		if class.HP < 0 || class.MP < 0 || class.CP < 0 {
+			return nil, fmt.Errorf("%w: class %d has a negative rate", ErrInvalidTable, class.ClassID)
+		}
+		table.rates[class.ClassID] = class.Rates
+	}
+
+	return table, nil
+}
+
+// Load reads a regeneration table from a file
+func Load(path string) (*Table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Default returns the bundled regeneration table, listing the base classes
+func Default() *Table {
+	table, err := Parse(bytes.NewReader(bundled))
+	if err != nil {
+		panic(err)
+	}
+	return table
+}
+
+// For returns the rates of a class
+func (t *Table) For(classID int) Rates {
+	if rates, ok := t.rates[classID]; ok {
+		return rates
+	}
+	return t.fallback
+}
+
+// Tracker regenerates the vitals of a player, carrying over the fractions of points
+// between the ticks, and remembers what its client was told of them
+type Tracker struct {
+	clock       clock.Clock
+	rates       Rates
+	factor      float64   // Scales the rates
+	last        time.Time // Regenerated up to then
+	combatUntil time.Time // Doesn't regenerate before then
+	fractions   [COUNT]float64
+	told        Values
+	mu          sync.Mutex
+}
+
+// NewTracker returns the tracker of a player whose client knows its current values
+func NewTracker(c clock.Clock, rates Rates, current Values) *Tracker {
+	return &Tracker{clock: c, rates: rates, factor: 1, last: c.Now(), told: current}
+}
+
+// SetRates changes the rates of the player, from the next regeneration on
+func (t *Tracker) SetRates(rates Rates) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rates = rates
+}
+
+// SetFactor scales the rates of the player, such as for an overload, from the next regeneration on
+func (t *Tracker) SetFactor(factor float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.factor = max(factor, 0)
+}
+
+// EnterCombat pauses the regeneration until COMBAT_PAUSE from now
+func (t *Tracker) EnterCombat() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.combatUntil = t.clock.Now().Add(COMBAT_PAUSE)
+}
+
+// InCombat reports whether the regeneration is paused
+func (t *Tracker) InCombat() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.clock.Now().Before(t.combatUntil)
+}
+
+// Regenerate returns the values of the player once the points regained since the last
+// regeneration are added, never beyond the maximums. The time spent in combat doesn't count.
+func (t *Tracker) Regenerate(now time.Time, current, maximum Values, sitting bool) Values {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	from := t.last
+	if from.Before(t.combatUntil) {
+		from = t.combatUntil
+	}
+	if now.After(t.last) {
+		t.last = now
+	}
+	if !now.After(from) {
+		return current
+	}
+
+	seconds := now.Sub(from).Seconds() * t.factor
+	if sitting {
+		seconds *= SIT_BONUS
+	}
+	for vital := range COUNT {
+		if current[vital] >= maximum[vital] {
+			t.fractions[vital] = 0
+			continue
+		}
+
+		points, fraction := math.Modf(t.fractions[vital] + t.rates.Of(vital)*seconds)
+		current[vital] = min(current[vital]+int(points), maximum[vital])
+		t.fractions[vital] = fraction
+		if current[vital] == maximum[vital] {
+			t.fractions[vital] = 0
+		}
+	}
+
+	return current
+}
+
+// Report returns the vitals the client should be told of: the ones which moved by BROADCAST_DELTA
+// since it was last told, and the ones which were filled. They count as told.
+func (t *Tracker) Report(current, maximum Values) []Vital {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var changed []Vital
+	for vital := range COUNT {
+		delta := current[vital] - t.told[vital]
+		if delta == 0 {
+			continue
+		}
+		if delta >= BROADCAST_DELTA || delta <= -BROADCAST_DELTA || current[vital] == maximum[vital] {
+			changed = append(changed, vital)
+			t.told[vital] = current[vital]
+		}
+	}
+	return changed
+}
+
+// Told records a value the client was sent otherwise, such as the HP left by an attack
+func (t *Tracker) Told(vital Vital, value int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.told[vital] = value
+}
@@ -0,0 +1,131 @@
+package vitals
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+)
+
+func TestTable(t *testing.T) {
+	table := Default()
+
+	if rates := table.For(44); rates != (Rates{HP: 1.2, MP: 0.3, CP: 1.2}) {
+		t.Errorf("For(orc fighter) = %+v", rates)
+	}
+	if rates := table.For(10); rates.MP <= table.For(0).MP {
+		t.Errorf("For(human mystic) = %+v, regenerating less MP than a human fighter", rates)
+	}
+	if rates := table.For(1000); rates != table.For(0) {
+		t.Errorf("For(unknown class) = %+v, want the rates of the human fighter", rates)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "no class", data: `[]`},
+		{name: "class listed twice", data: `[{"class": 0, "hp": 1}, {"class": 0, "hp": 2}]`},
+		{name: "negative rate", data: `[{"class": 0, "hp": 1, "mp": -1}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.data)); !errors.Is(err, ErrInvalidTable) {
+				t.Errorf("Parse() = %v, want %v", err, ErrInvalidTable)
+			}
+		})
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	rates := Rates{HP: 1, MP: 0.5, CP: 2}
+	maximum := Values{100, 100, 100}
+
+	tests := []struct {
+		name    string
+		current Values
+		sitting bool
+		factor  float64       // Of the rates, 1 when 0
+		combat  time.Duration // Attacked that long before the ticks start, none when 0
+		ticks   []time.Duration
+		want    Values
+	}{
+		{name: "standing", current: Values{50, 50, 50}, ticks: []time.Duration{10 * time.Second}, want: Values{60, 55, 70}},
+		{name: "sitting", current: Values{50, 50, 50}, sitting: true, ticks: []time.Duration{10 * time.Second}, want: Values{65, 57, 80}},
+		{name: "overloaded", current: Values{50, 50, 50}, sitting: true, factor: 0.5, ticks: []time.Duration{10 * time.Second}, want: Values{57, 53, 65}},
+		{name: "fractions carried over", current: Values{50, 50, 50}, ticks: []time.Duration{time.Second, time.Second, time.Second}, want: Values{53, 51, 56}},
+		{name: "capped", current: Values{95, 100, 99}, ticks: []time.Duration{time.Minute}, want: Values{100, 100, 100}},
+		{name: "in combat", current: Values{50, 50, 50}, combat: time.Nanosecond, ticks: []time.Duration{10 * time.Second}, want: Values{50, 50, 50}},
+		{name: "combat ending between ticks", current: Values{50, 50, 50}, combat: 10 * time.Second, ticks: []time.Duration{4 * time.Second, 4 * time.Second}, want: Values{53, 51, 56}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Now())
+			tracker := NewTracker(fake, rates, tt.current)
+			if tt.factor > 0 {
+				tracker.SetFactor(tt.factor)
+			}
+			if tt.combat > 0 {
+				tracker.EnterCombat()
+				fake.Advance(tt.combat)
+			}
+
+			current := tt.current
+			for _, tick := range tt.ticks {
+				fake.Advance(tick)
+				current = tracker.Regenerate(fake.Now(), current, maximum, tt.sitting)
+			}
+			if current != tt.want {
+				t.Errorf("Regenerate() = %v, want %v", current, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombatPause(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	tracker := NewTracker(fake, Rates{HP: 1}, Values{})
+
+	tracker.EnterCombat()
+	fake.Advance(COMBAT_PAUSE - time.Second)
+	if !tracker.InCombat() {
+		t.Fatalf("InCombat() = false %v after the attack", COMBAT_PAUSE-time.Second)
+	}
+
+	fake.Advance(time.Second)
+	if tracker.InCombat() {
+		t.Fatalf("InCombat() = true %v after the attack", COMBAT_PAUSE)
+	}
+}
+
+func TestReport(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	maximum := Values{100, 100, 100}
+	tracker := NewTracker(fake, Rates{}, Values{50, 50, 50})
+
+	steps := []struct {
+		name    string
+		current Values
+		want    []Vital
+	}{
+		{name: "small gains", current: Values{54, 51, 50}},
+		{name: "gains adding up", current: Values{55, 53, 50}, want: []Vital{HP}},
+		{name: "filled", current: Values{57, 53, 100}, want: []Vital{CP}},
+		{name: "unchanged", current: Values{57, 53, 100}},
+		{name: "big loss", current: Values{57, 40, 100}, want: []Vital{MP}},
+	}
+	for _, step := range steps {
+		if got := tracker.Report(step.current, maximum); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: Report() = %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	// The HP sent by an attack count as told
+	tracker.Told(HP, 20)
+	if got := tracker.Report(Values{22, 40, 100}, maximum); got != nil {
+		t.Errorf("Report() after Told(HP, 20) = %v, want none", got)
+	}
+}
@@ -0,0 +1,131 @@
+// Package warehouse implements the warehouse keepers: the players store items away
+// from their inventory, in their private warehouse or in the one of their clan, and
+// take them back later. The items are moved all at once or not at all.
+package warehouse
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/frostwind/l2go/gameserver/html"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// NPC_TYPE is the NPC type answered by the warehouse handler
+const NPC_TYPE = "warehouse"
+
+var (
+	ErrNoClan         = errors.New("not a clan member")
+	ErrNotEnoughItems = errors.New("not enough items")
+	ErrNoSlot         = errors.New("no slot left")
+	ErrInvalidCount   = errors.New("invalid item count")
+)
+
+// Item is an item moved in or out of a warehouse, and how many of it
+type Item struct {
+	ItemID int
+	Count  uint64
+}
+
+// Move moves items from counts by item id to others holding at most slots kinds of items.
+// Nothing is moved unless every item can be, an item listed twice counting for both.
+func Move(from, to map[int]uint64, items []Item, slots int) error {
+	counts := make(map[int]uint64, len(items))
+	for _, item := range items {
+		if item.Count == 0 || counts[item.ItemID]+item.Count < item.Count {
+			return fmt.Errorf("%w: %d more of the item %d", ErrInvalidCount, item.Count, item.ItemID)
+		}
+		counts[item.ItemID] += item.Count
+	}
+
+	kinds := 0
+	for _, count := range to {
+		if count > 0 {
+			kinds++
+		}
+	}
+	for itemID, count := range counts {
+		if from[itemID] < count {
+			return fmt.Errorf("%w: %d of the item %d, %d kept", ErrNotEnoughItems, count, itemID, from[itemID])
+		}
+		if to[itemID]+count < count {
+			return fmt.Errorf("%w: %d more of the item %d", ErrInvalidCount, count, itemID)
+		}
+		if to[itemID] == 0 {
+			kinds++
+		}
+	}
+	if kinds > slots {
+		return fmt.Errorf("%w: %d kinds of items for %d slots", ErrNoSlot, kinds, slots)
+	}
+
+	for itemID, count := range counts {
+		from[itemID] -= count
+		if from[itemID] == 0 {
+			delete(from, itemID)
+		}
+		to[itemID] += count
+	}
+	return nil
+}
+
+// Handler is the dialog handler of the warehouse keepers
+type Handler struct {
+	Inventory func(client *models.Client) (uint64, map[int]uint64)           // Copies the adena and the items of a player
+	Stored    func(client *models.Client, clan bool) (map[int]uint64, error) // Loads a warehouse of a player, the adena included
+	PageSize  int                                                            // Largest packet of the lists, packets.DefaultPageSize when 0
+}
+
+// Talk shows warehouse/<template id>.htm, or a generated dialog offering to deposit and withdraw
+func (h *Handler) Talk(d *html.Dialog) error {
+	err := d.Show(fmt.Sprintf("warehouse/%d.htm", d.Npc.TemplateID))
+	if errors.Is(err, html.ErrDialogNotFound) {
+		return d.ShowHTML(`<html><body>Warehouse Keeper %npcName%:<br>What can I keep for you?<br>` +
+			`<a action="bypass -h npc_%objectId%_Deposit">Deposit an item</a><br>` +
+			`<a action="bypass -h npc_%objectId%_Withdraw">Withdraw an item</a><br>` +
+			`<a action="bypass -h npc_%objectId%_DepositClan">Deposit an item in the clan warehouse</a><br>` +
+			`<a action="bypass -h npc_%objectId%_WithdrawClan">Withdraw an item from the clan warehouse</a></body></html>`)
+	}
+	return err
+}
+
+// Bypass answers "Deposit" and "DepositClan", opening the items of the player it can store,
+// "Withdraw" and "WithdrawClan", opening the items stored, and "Chat 0", which shows the first dialog again.
+// The players without a clan are told they can't use the clan warehouse.
+func (h *Handler) Bypass(d *html.Dialog, bypass html.Bypass) error {
+	clan := bypass.Command == "DepositClan" || bypass.Command == "WithdrawClan"
+	kind := uint16(serverpackets.WAREHOUSE_PRIVATE)
+	if clan {
+		kind = serverpackets.WAREHOUSE_CLAN
+	}
+	if clan && d.Client.Clan == "" {
+		if err := d.Client.Send(serverpackets.NewSystemMessagePacket(serverpackets.SYSTEM_MESSAGE_NOT_CLAN_MEMBER)); err != nil {
+			return err
+		}
+		return ErrNoClan
+	}
+
+	switch bypass.Command {
+	case "Chat":
+		return h.Talk(d)
+	case "Deposit", "DepositClan":
+		adena, items := h.Inventory(d.Client)
+		inventory := maps.Clone(items)
+		if inventory == nil {
+			inventory = make(map[int]uint64)
+		}
+		inventory[serverpackets.ADENA_ID] = adena
+		return d.Client.SendPages(serverpackets.NewWarehouseDepositListPackets(kind, adena, serverpackets.Items(inventory), h.PageSize))
+	case "Withdraw", "WithdrawClan":
+		stored, err := h.Stored(d.Client, clan)
+		if err != nil {
+			return err
+		}
+		adena, _ := h.Inventory(d.Client)
+		return d.Client.SendPages(serverpackets.NewWarehouseWithdrawListPackets(kind, adena, serverpackets.Items(stored), h.PageSize))
+	default:
+		return fmt.Errorf("%w: %s", html.ErrUnknownCommand, bypass.Command)
+	}
+}
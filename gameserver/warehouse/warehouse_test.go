@@ -0,0 +1,209 @@
+package warehouse
+
+import (
+	"errors"
+	"maps"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/crypt/xor"
+	"github.com/frostwind/l2go/gameserver/html"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     map[int]uint64
+		to       map[int]uint64
+		items    []Item
+		slots    int
+		err      error
+		wantFrom map[int]uint64
+		wantTo   map[int]uint64
+	}{
+		{
+			name:     "some",
+			from:     map[int]uint64{57: 1000, 1835: 20},
+			to:       map[int]uint64{1835: 5},
+			items:    []Item{{ItemID: 57, Count: 400}, {ItemID: 1835, Count: 5}},
+			slots:    2,
+			wantFrom: map[int]uint64{57: 600, 1835: 15},
+			wantTo:   map[int]uint64{57: 400, 1835: 10},
+		},
+		{
+			name:     "all",
+			from:     map[int]uint64{1835: 20},
+			to:       map[int]uint64{},
+			items:    []Item{{ItemID: 1835, Count: 20}},
+			slots:    1,
+			wantFrom: map[int]uint64{},
+			wantTo:   map[int]uint64{1835: 20},
+		},
+		{
+			name:  "more than kept",
+			from:  map[int]uint64{1835: 20},
+			to:    map[int]uint64{},
+			items: []Item{{ItemID: 1835, Count: 21}},
+			slots: 10,
+			err:   ErrNotEnoughItems,
+		},
+		{
+			// Each line is within the count, not both together
+			name:  "listed twice",
+			from:  map[int]uint64{1835: 20},
+			to:    map[int]uint64{},
+			items: []Item{{ItemID: 1835, Count: 15}, {ItemID: 1835, Count: 15}},
+			slots: 10,
+			err:   ErrNotEnoughItems,
+		},
+		{
+			// The counts would wrap around to a small one
+			name:  "overflowing count",
+			from:  map[int]uint64{1835: 20},
+			to:    map[int]uint64{},
+			items: []Item{{ItemID: 1835, Count: math.MaxUint64}, {ItemID: 1835, Count: 2}},
+			slots: 10,
+			err:   ErrInvalidCount,
+		},
+		{
+			name:  "overflowing destination",
+			from:  map[int]uint64{57: 10},
+			to:    map[int]uint64{57: math.MaxUint64 - 5},
+			items: []Item{{ItemID: 57, Count: 10}},
+			slots: 10,
+			err:   ErrInvalidCount,
+		},
+		{
+			name:  "nothing",
+			from:  map[int]uint64{1835: 20},
+			to:    map[int]uint64{},
+			items: []Item{{ItemID: 1835}},
+			slots: 10,
+			err:   ErrInvalidCount,
+		},
+		{
+			name:  "no slot",
+			from:  map[int]uint64{1835: 20, 1060: 1},
+			to:    map[int]uint64{1835: 1},
+			items: []Item{{ItemID: 1835, Count: 1}, {ItemID: 1060, Count: 1}},
+			slots: 1,
+			err:   ErrNoSlot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err != nil {
+				tt.wantFrom, tt.wantTo = maps.Clone(tt.from), maps.Clone(tt.to)
+			}
+
+			err := Move(tt.from, tt.to, tt.items, tt.slots)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Move() error = %v, want %v", err, tt.err)
+			}
+			if !maps.Equal(tt.from, tt.wantFrom) || !maps.Equal(tt.to, tt.wantTo) {
+				t.Errorf("Move() left %v and %v, want %v and %v", tt.from, tt.to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	stored := map[int]uint64{57: 300, 1835: 7}
+	dialogs := html.NewDialogs(html.NewCache(t.TempDir()))
+	handler := &Handler{
+		Inventory: func(client *models.Client) (uint64, map[int]uint64) { return client.Adena, client.Items },
+		Stored:    func(client *models.Client, clan bool) (map[int]uint64, error) { return stored, nil },
+	}
+	dialogs.Register(NPC_TYPE, handler)
+
+	server, conn := net.Pipe()
+	defer server.Close()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	client := models.NewClient()
+	client.Socket = server
+	client.ObjectID = 0x10000000
+	client.Adena = 500
+	client.Items = map[int]uint64{1060: 3}
+	key := xor.NewCipher().OutputKey
+
+	receive := func(t *testing.T, opcode byte, wantFlag byte) (uint16, uint64, []serverpackets.Item) {
+		t.Helper()
+
+		data, err := packets.ReadFrame(conn, packets.MaxFrameSize)
+		if err != nil {
+			t.Fatalf("ReadFrame() error = %v", err)
+		}
+		xor.Decrypt(data, key)
+		if data[0] != opcode {
+			t.Fatalf("expected %#x, got %#x", opcode, data[0])
+		}
+
+		if data[1] != wantFlag {
+			t.Errorf("page flag %#x, want %#x", data[1], wantFlag)
+		}
+
+		reader := packets.NewReader(data[2:])
+		kind, adena := reader.ReadUInt16(), reader.ReadUInt64()
+		items := make([]serverpackets.Item, reader.ReadUInt16())
+		for i := range items {
+			items[i] = serverpackets.Item{ItemID: reader.ReadUInt32(), Count: reader.ReadUInt64()}
+		}
+		return kind, adena, items
+	}
+
+	npc := &models.Npc{ObjectID: 100, TemplateID: 30005, Type: NPC_TYPE, Name: "Hagger"}
+
+	go dialogs.Bypass(npc, client, html.Bypass{ObjectID: 100, Command: "Deposit"})
+	kind, adena, items := receive(t, opcodes.GameServerWarehouseDepositList, packets.LastPage)
+	want := []serverpackets.Item{{ItemID: 57, Count: 500}, {ItemID: 1060, Count: 3}}
+	if kind != serverpackets.WAREHOUSE_PRIVATE || adena != 500 || len(items) != 2 || items[0] != want[0] || items[1] != want[1] {
+		t.Errorf("deposit list %d with %d adena: %v", kind, adena, items)
+	}
+
+	client.Clan = "knights"
+	go dialogs.Bypass(npc, client, html.Bypass{ObjectID: 100, Command: "WithdrawClan"})
+	kind, _, items = receive(t, opcodes.GameServerWarehouseWithdrawList, packets.LastPage)
+	if kind != serverpackets.WAREHOUSE_CLAN || len(items) != 2 || items[1].ItemID != 1835 {
+		t.Errorf("withdraw list %d: %v", kind, items)
+	}
+
+	// A list too long for a packet is split across pages, repeating the kind and the adena
+	handler.PageSize = 44 // A single item per page
+	go dialogs.Bypass(npc, client, html.Bypass{ObjectID: 100, Command: "WithdrawClan"})
+	for i, wantItem := range []serverpackets.Item{{ItemID: 57, Count: 300}, {ItemID: 1835, Count: 7}} {
+		wantFlag := packets.MorePages
+		if i == 1 {
+			wantFlag = packets.LastPage
+		}
+		kind, adena, items = receive(t, opcodes.GameServerWarehouseWithdrawList, wantFlag)
+		if kind != serverpackets.WAREHOUSE_CLAN || adena != 500 || len(items) != 1 || items[0] != wantItem {
+			t.Errorf("withdraw list page %d, %d with %d adena: %v", i, kind, adena, items)
+		}
+	}
+	handler.PageSize = 0
+
+	// The players without a clan are told so
+	client.Clan = ""
+	errs := make(chan error, 1)
+	go func() { errs <- dialogs.Bypass(npc, client, html.Bypass{ObjectID: 100, Command: "DepositClan"}) }()
+	data, err := packets.ReadFrame(conn, packets.MaxFrameSize)
+	if err != nil {
+		t.Fatalf("ReadFrame() error = %v", err)
+	}
+	xor.Decrypt(data, key)
+	if data[0] != opcodes.GameServerSystemMessage {
+		t.Errorf("expected a SystemMessage, got %#x", data[0])
+	}
+	if err := <-errs; !errors.Is(err, ErrNoClan) {
+		t.Errorf("Bypass() error = %v, want %v", err, ErrNoClan)
+	}
+}
@@ -0,0 +1,180 @@
+package gameserver
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/frostwind/l2go/gameserver/clientpackets"
+	"github.com/frostwind/l2go/gameserver/drops"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/repository"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/gameserver/warehouse"
+)
+
+const (
+	// Kinds of items the warehouses keep, the adena included
+	WAREHOUSE_SLOTS      = 100
+	CLAN_WAREHOUSE_SLOTS = 200
+)
+
+var (
+	ErrNotAtWarehouse = errors.New("not talking to a warehouse keeper")
+	ErrWarehouseFull  = errors.New("warehouse full")
+)
+
+// Deposit stores items of a player talking to a warehouse keeper in its private warehouse, or in
+// the one of its clan. The inventory and the warehouse are written at once, and the player is
+// sent its inventory, or why nothing was stored.
+func (g *GameServer) Deposit(client *models.Client, clan bool, items []warehouse.Item) error {
+	err := g.moveItems(client, clan, func(inventory, stored map[int]uint64) error {
+		err := warehouse.Move(inventory, stored, items, g.warehouseSlots(clan))
+		if errors.Is(err, warehouse.ErrNoSlot) {
+			return fmt.Errorf("%w: %v", ErrWarehouseFull, err)
+		}
+		return err
+	})
+	return g.answerMove(client, "stored", items, err)
+}
+
+// Withdraw takes items of the private warehouse of a player talking to a warehouse keeper, or of
+// the one of its clan, back to its inventory, as long as it can carry them at its level. The inventory
+// and the warehouse are written at once, and the player is sent its inventory, or why nothing was taken.
+func (g *GameServer) Withdraw(client *models.Client, clan bool, items []warehouse.Item) error {
+	limits := g.Limits(client)
+	err := g.moveItems(client, clan, func(inventory, stored map[int]uint64) error {
+		err := warehouse.Move(stored, inventory, items, limits.Slots)
+		if errors.Is(err, warehouse.ErrNoSlot) {
+			return fmt.Errorf("%w: %v", ErrInventoryFull, err)
+		}
+		if weight := g.shops.Weight(inventory); err == nil && weight > limits.Weight {
+			return fmt.Errorf("%w: %d over %d", ErrWeightLimit, weight, limits.Weight)
+		}
+		return err
+	})
+	return g.answerMove(client, "withdrew", items, err)
+}
+
+// Stored returns the items of the private warehouse of a player, or of the one of its clan, the adena included
+func (g *GameServer) Stored(client *models.Client, clan bool) (map[int]uint64, error) {
+	if !g.playing(client) {
+		return nil, ErrNotInWorld
+	}
+	owner, err := g.warehouseOwner(client, clan)
+	if err != nil {
+		return nil, err
+	}
+	return g.warehouseRepository.Load(owner)
+}
+
+// moveItems moves items between the inventory of a player and one of its warehouses, the adena being
+// among the items of both. The warehouse is read, the move checked, then the character and the warehouse
+// written in a single transaction before the inventory changes, so a failed write leaves both as they were.
+// The write happens between the batches of the persistence, so an older snapshot of the character
+// can't overwrite it, and under the items mutex, so the inventory doesn't change meanwhile.
+func (g *GameServer) moveItems(client *models.Client, clan bool, move func(inventory, stored map[int]uint64) error) error {
+	if !g.playing(client) {
+		return ErrNotInWorld
+	}
+	if npc, ok := g.Npc(client.TargetID); !ok || npc.Type != warehouse.NPC_TYPE {
+		return ErrNotAtWarehouse
+	}
+	owner, err := g.warehouseOwner(client, clan)
+	if err != nil {
+		return err
+	}
+
+	return g.persistence.Exclusive(func() error {
+		account, x, y, z := client.Whereabouts()
+		character := repository.Character{Account: account, Clan: client.Clan, X: x, Y: y, Z: z}
+		g.progressMutex.Lock()
+		character.Level, character.Exp, character.HP = client.Level, client.Exp, client.HP
+		g.progressMutex.Unlock()
+
+		g.itemsMutex.Lock()
+		defer g.itemsMutex.Unlock()
+
+		stored, err := g.warehouseRepository.Load(owner)
+		if err != nil {
+			return err
+		}
+
+		inventory := maps.Clone(client.Items)
+		if inventory == nil {
+			inventory = make(map[int]uint64)
+		}
+		inventory[drops.ADENA_ID] = client.Adena
+		if err := move(inventory, stored); err != nil {
+			return err
+		}
+
+		character.Adena = inventory[drops.ADENA_ID]
+		delete(inventory, drops.ADENA_ID)
+		character.Items = inventory
+		if err := g.warehouseRepository.Move(character, owner, stored); err != nil {
+			return fmt.Errorf("couldn't write the warehouse of %s: %w", client.Account, err)
+		}
+
+		client.Adena, client.Items = character.Adena, maps.Clone(inventory)
+		return nil
+	})
+}
+
+// warehouseOwner returns the owner of the private warehouse of a player, or of the one of its clan
+func (g *GameServer) warehouseOwner(client *models.Client, clan bool) (repository.WarehouseOwner, error) {
+	if !clan {
+		return repository.WarehouseOwner{ID: client.Account}, nil
+	}
+	if client.Clan == "" {
+		return repository.WarehouseOwner{}, warehouse.ErrNoClan
+	}
+	return repository.WarehouseOwner{Clan: true, ID: client.Clan}, nil
+}
+
+// warehouseSlots returns the kinds of items a warehouse keeps
+func (g *GameServer) warehouseSlots(clan bool) int {
+	if clan {
+		return CLAN_WAREHOUSE_SLOTS
+	}
+	return WAREHOUSE_SLOTS
+}
+
+// answerMove sends its inventory to a player who moved items, or tells it why it couldn't
+func (g *GameServer) answerMove(client *models.Client, verb string, items []warehouse.Item, err error) error {
+	if err == nil {
+		fmt.Printf("Player %d %s %d items\n", client.ObjectID, verb, len(items))
+		g.sendItemList(client)
+		return nil
+	}
+
+	var messageID uint32
+	switch {
+	case errors.Is(err, warehouse.ErrNoClan):
+		messageID = serverpackets.SYSTEM_MESSAGE_NOT_CLAN_MEMBER
+	case errors.Is(err, ErrWarehouseFull):
+		messageID = serverpackets.SYSTEM_MESSAGE_WAREHOUSE_FULL
+	case errors.Is(err, ErrInventoryFull):
+		messageID = serverpackets.SYSTEM_MESSAGE_INVENTORY_FULL
+	case errors.Is(err, ErrWeightLimit):
+		messageID = serverpackets.SYSTEM_MESSAGE_WEIGHT_LIMIT
+	case errors.Is(err, warehouse.ErrNotEnoughItems), errors.Is(err, warehouse.ErrInvalidCount):
+		messageID = serverpackets.SYSTEM_MESSAGE_INCORRECT_COUNT
+	default:
+		return err
+	}
+
+	if err := client.Send(serverpackets.NewSystemMessagePacket(messageID)); err != nil {
+		fmt.Println(err)
+	}
+	return err
+}
+
+// warehouseItems converts the items of a warehouse request
+func warehouseItems(items []clientpackets.ItemCount) []warehouse.Item {
+	converted := make([]warehouse.Item, len(items))
+	for i, item := range items {
+		converted[i] = warehouse.Item{ItemID: int(item.ItemID), Count: item.Count}
+	}
+	return converted
+}
@@ -0,0 +1,99 @@
+package gameserver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/frostwind/l2go/gameserver/capacity"
+	"github.com/frostwind/l2go/gameserver/drops"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// Limits returns what a player can carry at its level, unless the configuration sets it for every level
+func (g *GameServer) Limits(client *models.Client) capacity.Limits {
+	g.progressMutex.Lock()
+	level := client.Level
+	g.progressMutex.Unlock()
+
+	limits := g.capacity.At(level)
+	options := g.config.GameServer.Options
+	if options.WeightLimit > 0 {
+		limits.Weight = uint64(options.WeightLimit)
+	}
+	if options.InventorySlots > 0 {
+		limits.Slots = options.InventorySlots
+	}
+	return limits
+}
+
+// Load returns the weight of the items a player carries
+func (g *GameServer) Load(client *models.Client) uint64 {
+	g.itemsMutex.Lock()
+	defer g.itemsMutex.Unlock()
+
+	return g.shops.Weight(client.Items)
+}
+
+// canCarry tells why a player can't take items on top of its inventory, by counts by item id,
+// the items mutex being held. The adena weigh nothing and take a single slot.
+func (g *GameServer) canCarry(client *models.Client, limits capacity.Limits, added map[int]uint64) error {
+	load, weight := g.shops.Weight(client.Items), g.shops.Weight(added)
+	if load+weight < load || load+weight > limits.Weight {
+		return fmt.Errorf("%w: %d more over %d out of %d", ErrWeightLimit, weight, load, limits.Weight)
+	}
+
+	slots := len(client.Items)
+	for itemID := range added {
+		if _, ok := client.Items[itemID]; !ok && itemID != drops.ADENA_ID {
+			slots += 1
+		}
+	}
+	if client.Adena > 0 || added[drops.ADENA_ID] > 0 {
+		slots += 1
+	}
+	if slots > limits.Slots {
+		return fmt.Errorf("%w: %d slots", ErrInventoryFull, limits.Slots)
+	}
+	return nil
+}
+
+// updateLoad tells a player what it carries after its inventory or its level changed. The player
+// is overloaded, regenerating slower, as it gets close to its weight limit.
+func (g *GameServer) updateLoad(client *models.Client) {
+	limits := g.Limits(client)
+
+	g.itemsMutex.Lock()
+	load := g.shops.Weight(client.Items)
+	penalty := capacity.Penalty(load, limits.Weight)
+	previous := client.WeightPenalty
+	client.WeightPenalty = penalty
+	g.itemsMutex.Unlock()
+
+	if penalty != previous {
+		fmt.Printf("Player %d carries %d out of %d, overloaded at level %d\n", client.ObjectID, load, limits.Weight, penalty)
+		if client.Vitals != nil {
+			client.Vitals.SetFactor(capacity.Regeneration(penalty))
+		}
+	}
+
+	err := client.Send(serverpackets.NewStatusUpdatePacket(client.ObjectID,
+		serverpackets.StatusAttribute{ID: serverpackets.STATUS_CUR_LOAD, Value: uint32(min(load, 1<<32-1))},
+		serverpackets.StatusAttribute{ID: serverpackets.STATUS_MAX_LOAD, Value: uint32(min(limits.Weight, 1<<32-1))},
+	))
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// refusePickUp tells a player why it can't pick up an item
+func (g *GameServer) refusePickUp(client *models.Client, err error) {
+	messageID := uint32(serverpackets.SYSTEM_MESSAGE_INVENTORY_FULL)
+	if errors.Is(err, ErrWeightLimit) {
+		messageID = serverpackets.SYSTEM_MESSAGE_WEIGHT_LIMIT
+	}
+
+	if err := client.Send(serverpackets.NewSystemMessagePacket(messageID)); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,77 @@
+package gameserver
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/gametime"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+)
+
+// SUN_CHECK_INTERVAL is how often the game loop looks whether the sun rose or set
+const SUN_CHECK_INTERVAL = time.Second
+
+// newGameTime returns the clock of the world, which starts with the game server unless
+// the configuration gives its epoch
+func (g *GameServer) newGameTime() *gametime.Clock {
+	options := g.config.GameServer.Options
+
+	epoch := g.clock.Now()
+	if options.GameTimeEpoch != 0 {
+		epoch = time.Unix(options.GameTimeEpoch, 0)
+	}
+	return gametime.New(epoch, options.GameTimeMultiplier())
+}
+
+// GameTime returns the clock of the world
+func (g *GameServer) GameTime() *gametime.Clock {
+	return g.gameTime
+}
+
+// gameTimePacket returns the ClientSetTime packet setting the clock of the clients to the time of the world
+func (g *GameServer) gameTimePacket(now time.Time) []byte {
+	minutes := max(g.gameTime.Minutes(now), 0)
+	return serverpackets.NewClientSetTimePacket(uint32(minutes), uint32(math.Round(g.gameTime.Speed())))
+}
+
+// sendGameTime sets the clock of a player entering the world, which goes dark when it is night
+func (g *GameServer) sendGameTime(client *models.Client) {
+	now := g.clock.Now()
+	if err := client.Send(g.gameTimePacket(now)); err != nil {
+		fmt.Println(err)
+	}
+
+	if g.gameTime.IsNight(now) {
+		if err := client.Send(serverpackets.NewSunSetPacket()); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// updateSun tells the players in the world when the sun rises or sets, setting their clocks again
+// so they don't drift
+func (g *GameServer) updateSun(now time.Time) {
+	night := g.gameTime.IsNight(now)
+	if g.night.Swap(night) == night {
+		return
+	}
+
+	packet, change := serverpackets.NewSunRisePacket(), "rises"
+	if night {
+		packet, change = serverpackets.NewSunSetPacket(), "sets"
+	}
+	hour, minute := g.gameTime.TimeOfDay(now)
+	fmt.Printf("The sun %s in the world, at %02d:%02d\n", change, hour, minute)
+
+	clock := g.gameTimePacket(now)
+	for _, client := range g.authenticated() {
+		if err := client.Send(packet); err != nil {
+			fmt.Println(err)
+		}
+		if err := client.Send(clock); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
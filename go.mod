@@ -4,5 +4,6 @@ go 1.25.3
 
 require (
 	github.com/go-sql-driver/mysql v1.7.1
+	github.com/yuin/gopher-lua v1.1.1
 	golang.org/x/crypto v0.47.0
 )
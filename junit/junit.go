@@ -0,0 +1,122 @@
+// Package junit writes test results as JUnit XML reports, the format CI
+// pipelines show as test cases
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Suites is the root element of a report
+type Suites struct {
+	XMLName  xml.Name `xml:"testsuites"`
+	Name     string   `xml:"name,attr,omitempty"`
+	Tests    int      `xml:"tests,attr"`
+	Failures int      `xml:"failures,attr"`
+	Errors   int      `xml:"errors,attr"`
+	Time     float64  `xml:"time,attr"`
+	Suites   []*Suite `xml:"testsuite"`
+}
+
+// Suite groups the test cases of a run
+type Suite struct {
+	Name       string     `xml:"name,attr"`
+	Tests      int        `xml:"tests,attr"`
+	Failures   int        `xml:"failures,attr"`
+	Errors     int        `xml:"errors,attr"`
+	Skipped    int        `xml:"skipped,attr"`
+	Time       float64    `xml:"time,attr"`
+	Timestamp  string     `xml:"timestamp,attr,omitempty"`
+	Properties []Property `xml:"properties>property,omitempty"`
+	Cases      []Case     `xml:"testcase"`
+}
+
+// Property is a name and value describing the context of a suite
+type Property struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// Case is a single test, failed when it has a failure or an error
+type Case struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Time      float64  `xml:"time,attr"`
+	Failure   *Outcome `xml:"failure,omitempty"`
+	Error     *Outcome `xml:"error,omitempty"`
+	Skipped   *Outcome `xml:"skipped,omitempty"`
+	SystemOut string   `xml:"system-out,omitempty"`
+}
+
+// Outcome details why a case did not pass
+type Outcome struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// NewSuite creates an empty suite started at the given time
+func NewSuite(name string, started time.Time) *Suite {
+	suite := &Suite{Name: name}
+	if !started.IsZero() {
+		suite.Timestamp = started.UTC().Format("2006-01-02T15:04:05")
+	}
+	return suite
+}
+
+// AddProperty describes the context of the suite
+func (s *Suite) AddProperty(name, value string) {
+	s.Properties = append(s.Properties, Property{Name: name, Value: value})
+}
+
+// Pass adds a passed case
+func (s *Suite) Pass(name string, took time.Duration) {
+	s.add(Case{Name: name, Time: took.Seconds()})
+}
+
+// Fail adds a case which ran but did not meet its expectations
+func (s *Suite) Fail(name string, took time.Duration, message, details string) {
+	s.add(Case{Name: name, Time: took.Seconds(), Failure: &Outcome{Message: message, Type: "failure", Text: details}})
+	s.Failures++
+}
+
+// Error adds a case which could not run to its end
+func (s *Suite) Error(name string, took time.Duration, err error) {
+	s.add(Case{Name: name, Time: took.Seconds(), Error: &Outcome{Message: err.Error(), Type: fmt.Sprintf("%T", err)}})
+	s.Errors++
+}
+
+// Skip adds a case which did not run
+func (s *Suite) Skip(name, reason string) {
+	s.add(Case{Name: name, Skipped: &Outcome{Message: reason}})
+	s.Skipped++
+}
+
+// add adds a case, named after the suite
+func (s *Suite) add(testCase Case) {
+	testCase.ClassName = s.Name
+	s.Cases = append(s.Cases, testCase)
+	s.Tests++
+	s.Time += testCase.Time
+}
+
+// Write writes the suites as a JUnit XML report
+func Write(w io.Writer, name string, suites ...*Suite) error {
+	report := Suites{Name: name, Suites: suites}
+	for _, suite := range suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Time += suite.Time
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
@@ -0,0 +1,70 @@
+package junit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	conformance := NewSuite("conformance.login", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	conformance.AddProperty("server", "127.0.0.1:2106")
+	conformance.Pass("Init", 10*time.Millisecond)
+	conformance.Fail("RequestAuthLogin", 20*time.Millisecond, "expected LoginOk, got LoginFail", "reason 0x03")
+	conformance.Error("RequestServerList", 0, errors.New("connection is closed"))
+	conformance.Skip("RequestServerLogin", "the server list is unknown")
+
+	load := NewSuite("loadtest.step", time.Time{})
+	load.Pass("login_p99 <= 500", 0)
+
+	var buffer bytes.Buffer
+	if err := Write(&buffer, "l2go", conformance, load); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var report Suites
+	if err := xml.Unmarshal(buffer.Bytes(), &report); err != nil {
+		t.Fatalf("the report is not valid xml: %v\n%s", err, buffer.String())
+	}
+
+	if report.Tests != 5 || report.Failures != 1 || report.Errors != 1 || len(report.Suites) != 2 {
+		t.Errorf("Tests = %d, Failures = %d, Errors = %d, %d suites", report.Tests, report.Failures, report.Errors, len(report.Suites))
+	}
+
+	suite := report.Suites[0]
+	if suite.Timestamp != "2024-05-01T12:00:00" || suite.Skipped != 1 || suite.Properties[0].Value != "127.0.0.1:2106" {
+		t.Errorf("unexpected suite %+v", suite)
+	}
+	if took := suite.Time; took < 0.029 || took > 0.031 {
+		t.Errorf("suite Time = %v, want 0.03", took)
+	}
+
+	tests := []struct {
+		name    string
+		outcome func(Case) *Outcome
+		message string
+	}{
+		{name: "Init"},
+		{name: "RequestAuthLogin", outcome: func(c Case) *Outcome { return c.Failure }, message: "expected LoginOk, got LoginFail"},
+		{name: "RequestServerList", outcome: func(c Case) *Outcome { return c.Error }, message: "connection is closed"},
+		{name: "RequestServerLogin", outcome: func(c Case) *Outcome { return c.Skipped }, message: "the server list is unknown"},
+	}
+
+	for i, tt := range tests {
+		testCase := suite.Cases[i]
+		if testCase.Name != tt.name || testCase.ClassName != "conformance.login" {
+			t.Errorf("case %d = %s (%s), want %s", i, testCase.Name, testCase.ClassName, tt.name)
+		}
+		if tt.outcome == nil {
+			if testCase.Failure != nil || testCase.Error != nil || testCase.Skipped != nil {
+				t.Errorf("%s did not pass", tt.name)
+			}
+			continue
+		}
+		if outcome := tt.outcome(testCase); outcome == nil || outcome.Message != tt.message {
+			t.Errorf("%s outcome = %+v, want message %q", tt.name, outcome, tt.message)
+		}
+	}
+}
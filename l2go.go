@@ -3,22 +3,56 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"runtime"
+
 	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/configschema"
+	"github.com/frostwind/l2go/conformance"
 	"github.com/frostwind/l2go/gameserver"
+	"github.com/frostwind/l2go/junit"
 	"github.com/frostwind/l2go/loginserver"
-	"runtime"
+	"github.com/frostwind/l2go/packetdiff"
+	"github.com/frostwind/l2go/seed"
 )
 
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "packetdiff":
+			os.Exit(packetDiff(os.Args[2:]))
+		case "conformance":
+			os.Exit(conformanceRun(os.Args[2:]))
+		case "config":
+			os.Exit(configCommand(os.Args[2:]))
+		case "seed":
+			os.Exit(seedCommand(os.Args[2:]))
+		}
+	}
+
+	// The flags default to L2GO_MODE and L2GO_SERVER, so a container picks the server to run from its environment
 	var mode, gameServerId int
-	flag.IntVar(&mode, "mode", 0, "Set to 0 to run the Login Server or 1 to run the Game Server")
-	flag.IntVar(&gameServerId, "server", 1, "Set the id of the Game Server you want to run")
+	if err := config.FromEnv(os.LookupEnv, config.ENV_PREFIX+"MODE", &mode); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	gameServerId = 1
+	if err := config.FromEnv(os.LookupEnv, config.ENV_PREFIX+"SERVER", &gameServerId); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	flag.IntVar(&mode, "mode", mode, "Set to 0 to run the Login Server or 1 to run the Game Server")
+	flag.IntVar(&gameServerId, "server", gameServerId, "Set the id of the Game Server you want to run")
 	flag.Parse()
 
-	// Load the global configuration object
-	globalConfig := config.Read()
+	// Load the global configuration object, overridden by the L2GO_ environment variables
+	globalConfig, err := config.Load(os.LookupEnv)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
 
 	if mode == 0 {
 		server := loginserver.New(globalConfig)
@@ -41,3 +75,158 @@ func main() {
 
 	fmt.Println("Server stopped.")
 }
+
+// packetDiff compares two packet captures, exiting with 0 when they are the same, 1 when they differ and 2 on error, as diff does
+func packetDiff(args []string) int {
+	flags := flag.NewFlagSet("packetdiff", flag.ExitOnError)
+	all := flags.Bool("all", false, "also list the packets the same in both captures")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: l2go packetdiff [-all] <capture> <capture>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	a, err := packetdiff.ReadCapture(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "packetdiff:", err)
+		return 2
+	}
+	b, err := packetdiff.ReadCapture(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "packetdiff:", err)
+		return 2
+	}
+
+	entries := packetdiff.Diff(a, b)
+	packetdiff.Write(os.Stdout, entries, flags.Arg(0), flags.Arg(1), *all)
+	if packetdiff.Divergent(entries) {
+		return 1
+	}
+	return 0
+}
+
+// conformanceRun replays a corpus of sessions, exiting with 0 when they all pass, 1 when some fail and 2 on error
+func conformanceRun(args []string) int {
+	flags := flag.NewFlagSet("conformance", flag.ExitOnError)
+	junitFile := flags.String("junit", "", "file the replay is also written to as a JUnit report")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: l2go conformance [-junit report.xml] <corpus>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	report, err := conformance.Run(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "conformance:", err)
+		return 2
+	}
+	report.Write(os.Stdout)
+
+	if *junitFile != "" {
+		file, err := os.Create(*junitFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "conformance:", err)
+			return 2
+		}
+		defer file.Close()
+		if err := junit.Write(file, "conformance", report.JUnitSuite()); err != nil {
+			fmt.Fprintln(os.Stderr, "conformance:", err)
+			return 2
+		}
+	}
+
+	if !report.Passed() {
+		return 1
+	}
+	return 0
+}
+
+// configCommand runs the config subcommands, only schema so far, exiting with 0 on success and 2 on error
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "schema" {
+		fmt.Fprintln(os.Stderr, "Usage: l2go config schema [-o file] <toolkit|server>")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config schema", flag.ExitOnError)
+	output := flags.String("o", "", "file the schema is written to, the standard output when empty")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: l2go config schema [-o file] <toolkit|server>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args[1:])
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	schema, err := configschema.ByName(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "config schema:", err)
+		return 2
+	}
+	if *output == "" {
+		schema.Write(os.Stdout)
+		return 0
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "config schema:", err)
+		return 2
+	}
+	defer file.Close()
+	if err := schema.Write(file); err != nil {
+		fmt.Fprintln(os.Stderr, "config schema:", err)
+		return 2
+	}
+	return 0
+}
+
+// seedCommand populates the databases of the configuration from a fixture file, exiting with 0 on success and 2 on error
+func seedCommand(args []string) int {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	gameServerId := flags.Uint("server", 1, "id of the game server the characters are seeded on")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: l2go seed [-server id] <fixture>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 || *gameServerId == 0 || *gameServerId > 255 {
+		flags.Usage()
+		return 2
+	}
+
+	fixture, err := seed.ReadFixture(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		return 2
+	}
+	globalConfig, err := config.Load(os.LookupEnv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		return 2
+	}
+	repositories, err := seed.Open(globalConfig, uint8(*gameServerId))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		return 2
+	}
+	defer repositories.Close()
+
+	result, err := seed.Seed(fixture, repositories)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "seed:", err)
+		return 2
+	}
+	fmt.Println("Seeded", flags.Arg(0)+":", result)
+	return 0
+}
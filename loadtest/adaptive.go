@@ -0,0 +1,111 @@
+package loadtest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+// AdaptiveReport is what the adaptive shape found out about the capacity of the servers
+type AdaptiveReport struct {
+	Capacity  int              `json:"capacity"`            // Most clients of a window where the servers kept up
+	Capped    bool             `json:"capped,omitempty"`    // The servers kept up with the client count of the test, their capacity lying beyond
+	Converged bool             `json:"converged,omitempty"` // The step narrowed down to a single client around the capacity
+	Settled   int              `json:"settled"`             // Clients of the last window where the servers kept up, below the capacity when they no longer do
+	Backoffs  int              `json:"backoffs"`
+	Windows   []AdaptiveWindow `json:"windows,omitempty"`
+}
+
+// AdaptiveWindow is how the servers fared over a window of the adaptive shape
+type AdaptiveWindow struct {
+	At        time.Duration `json:"at"`        // End of the window, into the run
+	Clients   int           `json:"clients"`   // Live clients at the end of the window
+	LoginP95  time.Duration `json:"loginP95"`  // Of the logins during the window
+	ErrorRate float64       `json:"errorRate"` // Percentage of the connections of the window that failed
+	Healthy   bool          `json:"healthy"`
+}
+
+// rampController steers the population of the adaptive shape: a step of clients is added after every
+// window where the servers kept up. After a window where they didn't, the population goes back to the
+// last one they kept up with, or drops by the backoff percentage when they no longer keep up with it,
+// and the step is halved, so that the population settles on the capacity of the servers.
+type rampController struct {
+	shape   client.LoadShape
+	ceiling int // Client count of the test, never exceeded
+	target  int
+	step    int
+	healthy int // Clients of the last window where the servers kept up, -1 before any
+
+	// Start of the current window, and the figures of the run at that point
+	started  time.Duration
+	logins   int
+	connects int
+	failures int
+
+	report AdaptiveReport
+}
+
+func newRampController(shape client.LoadShape, ceiling int) *rampController {
+	return &rampController{
+		shape:   shape,
+		ceiling: ceiling,
+		target:  min(shape.BaseClients, ceiling),
+		step:    shape.StepClients,
+		healthy: -1,
+	}
+}
+
+// adjust returns the population to hold once elapsed has passed, judging the servers on the logins
+// and the connections of the run whenever a window ends
+func (c *rampController) adjust(elapsed time.Duration, live int, logins []time.Duration, connects, failures int) int {
+	if elapsed-c.started < c.shape.StepInterval {
+		return c.target
+	}
+
+	window := append([]time.Duration(nil), logins[c.logins:]...)
+	sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+	judged := AdaptiveWindow{At: elapsed, Clients: live, LoginP95: percentile(window, 95)}
+	if attempts := connects - c.connects + failures - c.failures; attempts > 0 {
+		judged.ErrorRate = float64(failures-c.failures) * 100 / float64(attempts)
+	}
+	judged.Healthy = judged.LoginP95 <= c.shape.MaxLoginP95 && judged.ErrorRate <= c.shape.MaxErrorPercent
+	c.report.Windows = append(c.report.Windows, judged)
+
+	c.started, c.logins, c.connects, c.failures = elapsed, len(logins), connects, failures
+
+	if judged.Healthy {
+		c.healthy = live
+		c.report.Capacity = max(c.report.Capacity, live)
+		c.report.Settled = live
+		c.report.Capped = live >= c.ceiling
+		c.target = min(live+c.step, c.ceiling)
+		return c.target
+	}
+
+	c.report.Backoffs++
+	c.report.Capped = false
+	c.report.Converged = c.report.Converged || c.step == 1
+	if c.healthy >= 0 && c.healthy < live {
+		c.target = c.healthy
+	} else {
+		backoff := c.shape.BackoffPercent
+		if backoff == 0 {
+			backoff = client.DefaultBackoffPercent
+		}
+		c.target = max(live-max(int(float64(live)*backoff/100), 1), 0)
+		c.healthy = -1
+	}
+	c.step = max(c.step/2, 1)
+
+	fmt.Printf("Adaptive ramp backing off to %d clients: login p95 %v, error rate %.2f%% with %d clients\n",
+		c.target, judged.LoginP95, judged.ErrorRate, live)
+	return c.target
+}
+
+// finish returns the report of the run
+func (c *rampController) finish() *AdaptiveReport {
+	report := c.report
+	return &report
+}
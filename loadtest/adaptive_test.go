@@ -0,0 +1,212 @@
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/manager"
+)
+
+func TestRampController(t *testing.T) {
+	shape := client.LoadShape{Type: client.ShapeAdaptive, BaseClients: 10, StepClients: 8, StepInterval: time.Second, MaxLoginP95: 100 * time.Millisecond}
+	type window struct {
+		live     int
+		login    time.Duration // Of every login of the window
+		failures int
+		want     int
+	}
+	tests := []struct {
+		name          string
+		shape         client.LoadShape
+		windows       []window
+		wantCapacity  int
+		wantSettled   int // The capacity when 0
+		wantBackoffs  int
+		wantConverged bool
+		wantCapped    bool
+	}{
+		{
+			name:         "ramping",
+			shape:        shape,
+			windows:      []window{{live: 10, login: time.Millisecond, want: 18}, {live: 18, login: time.Millisecond, want: 26}},
+			wantCapacity: 18,
+		},
+		{
+			name:         "capped by the client count",
+			shape:        shape,
+			windows:      []window{{live: 10, login: time.Millisecond, want: 18}, {live: 18, login: time.Millisecond, want: 26}, {live: 26, login: time.Millisecond, want: 30}, {live: 30, login: time.Millisecond, want: 30}},
+			wantCapacity: 30,
+			wantCapped:   true,
+		},
+		{
+			name:  "narrowing down on slow logins",
+			shape: shape,
+			windows: []window{
+				{live: 10, login: time.Millisecond, want: 18},
+				{live: 18, login: time.Second, want: 10},
+				{live: 10, login: time.Millisecond, want: 14},
+				{live: 14, login: time.Millisecond, want: 18},
+				{live: 18, login: time.Second, want: 14},
+				{live: 14, login: time.Millisecond, want: 16},
+				{live: 16, login: time.Second, want: 14},
+				{live: 14, login: time.Millisecond, want: 15},
+				{live: 15, login: time.Second, want: 14},
+			},
+			wantCapacity:  14,
+			wantBackoffs:  4,
+			wantConverged: true,
+		},
+		{
+			name:  "settling below the capacity",
+			shape: shape,
+			windows: []window{
+				{live: 10, login: time.Millisecond, want: 18},
+				{live: 18, login: time.Millisecond, want: 26},
+				{live: 26, login: time.Second, want: 18},
+				{live: 18, login: time.Second, want: 15},
+				{live: 15, login: time.Millisecond, want: 17},
+			},
+			wantCapacity: 18,
+			wantSettled:  15,
+			wantBackoffs: 2,
+		},
+		{
+			name:  "backing off on errors",
+			shape: client.LoadShape{Type: client.ShapeAdaptive, BaseClients: 20, StepClients: 4, StepInterval: time.Second, MaxLoginP95: time.Second, MaxErrorPercent: 10, BackoffPercent: 50},
+			windows: []window{
+				{live: 20, login: time.Millisecond, want: 24},
+				{live: 22, login: time.Millisecond, failures: 2, want: 20},
+				{live: 20, login: time.Millisecond, failures: 2, want: 10},
+			},
+			wantCapacity: 20,
+			wantBackoffs: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := newRampController(tt.shape, 30)
+			if got := controller.adjust(0, 0, nil, 0, 0); got != tt.shape.BaseClients {
+				t.Fatalf("adjust() = %d at the start, want %d", got, tt.shape.BaseClients)
+			}
+
+			var logins []time.Duration
+			var connects, failures int
+			target := tt.shape.BaseClients
+			for i, w := range tt.windows {
+				for range 10 {
+					logins = append(logins, w.login)
+				}
+				connects += 10
+				failures += w.failures
+
+				elapsed := time.Duration(i+1) * tt.shape.StepInterval
+				if got := controller.adjust(elapsed-time.Millisecond, w.live, logins, connects, failures); got != target {
+					t.Fatalf("adjust() = %d before the end of window %d, want %d", got, i, target)
+				}
+				if target = controller.adjust(elapsed, w.live, logins, connects, failures); target != w.want {
+					t.Fatalf("adjust() = %d after window %d, want %d", target, i, w.want)
+				}
+			}
+
+			report := controller.finish()
+			if report.Capacity != tt.wantCapacity || report.Backoffs != tt.wantBackoffs {
+				t.Errorf("Capacity = %d, Backoffs = %d, want %d and %d", report.Capacity, report.Backoffs, tt.wantCapacity, tt.wantBackoffs)
+			}
+			wantSettled := tt.wantSettled
+			if wantSettled == 0 {
+				wantSettled = tt.wantCapacity
+			}
+			if report.Settled != wantSettled {
+				t.Errorf("Settled = %d, want %d", report.Settled, wantSettled)
+			}
+			if report.Converged != tt.wantConverged || report.Capped != tt.wantCapped {
+				t.Errorf("Converged = %v, Capped = %v", report.Converged, report.Capped)
+			}
+			if len(report.Windows) != len(tt.windows) {
+				t.Errorf("%d windows reported, want %d", len(report.Windows), len(tt.windows))
+			}
+		})
+	}
+}
+
+// cappedClient is a client of servers turning away the logins past their capacity
+type cappedClient struct {
+	client.GameClient
+	servers *cappedServers
+	in      bool
+}
+
+// cappedServers counts the clients logged in to them
+type cappedServers struct {
+	capacity int
+	players  int
+	mu       sync.Mutex
+}
+
+func (c *cappedClient) Connect() error {
+	c.servers.mu.Lock()
+	defer c.servers.mu.Unlock()
+	if c.servers.players >= c.servers.capacity {
+		return fmt.Errorf("%w: %d players", client.ErrServerFull, c.servers.players)
+	}
+	c.servers.players++
+	c.in = true
+	return c.GameClient.Connect()
+}
+
+func (c *cappedClient) Disconnect() error {
+	c.servers.mu.Lock()
+	defer c.servers.mu.Unlock()
+	if c.in {
+		c.servers.players--
+		c.in = false
+	}
+	return c.GameClient.Disconnect()
+}
+
+func (c *cappedClient) Logout() error {
+	return c.Disconnect()
+}
+
+func TestRunnerAdaptive(t *testing.T) {
+	m := manager.NewManager(&client.ManagerConfig{MaxClients: 20, HealthCheck: time.Second})
+	defer m.Shutdown()
+
+	servers := &cappedServers{capacity: 7}
+	runner := &Runner{
+		Manager: m,
+		Test: client.LoadTestConfig{
+			DefaultClientCount: 20,
+			DefaultDuration:    time.Minute,
+			MaxConcurrentTests: 1,
+			ReportFormat:       "json",
+			Shape: client.LoadShape{Type: client.ShapeAdaptive, BaseClients: 2, StepClients: 4, StepInterval: time.Second,
+				MaxLoginP95: time.Second},
+			Speed: 100,
+		},
+		NewClient: func(id string, config client.ClientConfig) client.GameClient {
+			return &cappedClient{GameClient: manager.NewGameClient(id, config), servers: servers}
+		},
+	}
+
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	adaptive := result.Adaptive
+	if adaptive == nil {
+		t.Fatal("no adaptive report")
+	}
+	if adaptive.Capacity != servers.capacity || !adaptive.Converged || adaptive.Capped {
+		t.Errorf("Capacity = %d, Converged = %v, Capped = %v, want %d", adaptive.Capacity, adaptive.Converged, adaptive.Capped, servers.capacity)
+	}
+	if result.Peak != servers.capacity || result.Failures == 0 {
+		t.Errorf("Peak = %d, Failures = %d", result.Peak, result.Failures)
+	}
+}
@@ -0,0 +1,312 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+// Actions the actor of a broadcast check performs
+const (
+	BROADCAST_CHAT = "chat"
+	BROADCAST_MOVE = "move"
+)
+
+const (
+	// DEFAULT_BROADCAST_LATENCY is how long the observers have to see an action by default
+	DEFAULT_BROADCAST_LATENCY = time.Second
+
+	// broadcastPoll is how often the observers are checked for an action
+	broadcastPoll = 10 * time.Millisecond
+
+	// broadcastStep is how far the actor walks at every move, along the X axis
+	broadcastStep = 20
+)
+
+// Observer is a client watching the world, such as a client.Client configured as a spectator
+type Observer interface {
+	client.GameClient
+	Spectate(ctx context.Context) error
+	WorldEvents() []client.WorldEvent
+}
+
+// Actor is a client performing the default actions of a broadcast check, such as a client.Client
+type Actor interface {
+	client.GameClient
+	Sessions() *client.SessionManager
+	Say(text string) error
+	MoveTo(x, y, z int) error
+}
+
+// ActFunc performs an action of the actor and returns what the observers should see of it
+type ActFunc func(actor client.GameClient, action string, round int) (func(event client.WorldEvent) bool, error)
+
+// BroadcastCheck has an actor perform actions in the world while observers watch it, checking that
+// every observer sees every action within a latency bound
+type BroadcastCheck struct {
+	// Client is the configuration of the clients. The actor logs in as its username followed by 0,
+	// the observers followed by their index from 1, as spectators.
+	Client    client.ClientConfig
+	Observers int                  // Clients watching the actor
+	Actions   []string             // Performed in order at every round, BROADCAST_CHAT and BROADCAST_MOVE by default
+	Rounds    int                  // Times the actions are performed, once by default
+	Latency   time.Duration        // How long the observers have to see an action, defaults to DEFAULT_BROADCAST_LATENCY
+	NewClient client.ClientFactory // Defaults to client.NewClient
+	Act       ActFunc              // Performs the actions, through the chat and the moves of an Actor by default
+}
+
+// BroadcastAction is how the observers saw an action of the actor
+type BroadcastAction struct {
+	Action     string        `json:"action"`
+	Round      int           `json:"round"` // Starting at 1
+	Sent       time.Time     `json:"sent"`
+	Seen       int           `json:"seen"`             // Observers which saw it within the bound
+	Late       []string      `json:"late,omitempty"`   // Observers which saw it after the bound
+	Missed     []string      `json:"missed,omitempty"` // Observers which never saw it
+	MaxLatency time.Duration `json:"maxLatency"`
+	Error      string        `json:"error,omitempty"` // Why the actor couldn't perform it
+}
+
+// BroadcastResult is the consistency report of a broadcast check
+type BroadcastResult struct {
+	Started    time.Time         `json:"started"`
+	Duration   time.Duration     `json:"duration"`
+	Observers  int               `json:"observers"`
+	Latency    time.Duration     `json:"latency"` // The bound
+	Actions    []BroadcastAction `json:"actions"`
+	Deliveries int               `json:"deliveries"` // Actions seen by an observer, within the bound or not
+	Late       int               `json:"late"`
+	Missed     int               `json:"missed"`
+	LatencyP50 time.Duration     `json:"latencyP50"`
+	LatencyP95 time.Duration     `json:"latencyP95"`
+	LatencyP99 time.Duration     `json:"latencyP99"`
+	Consistent bool              `json:"consistent"` // Every action was performed and seen by every observer within the bound
+}
+
+// Run connects the actor and the observers, has the actor perform the actions and disconnects them all.
+// It fails when a client couldn't connect, or when the context is done before the actions are over.
+func (b *BroadcastCheck) Run(ctx context.Context) (*BroadcastResult, error) {
+	if b.Observers <= 0 {
+		return nil, fmt.Errorf("invalid broadcast check: there must be observers")
+	}
+
+	actions := b.Actions
+	if len(actions) == 0 {
+		actions = []string{BROADCAST_CHAT, BROADCAST_MOVE}
+	}
+	rounds := max(b.Rounds, 1)
+	latency := b.Latency
+	if latency <= 0 {
+		latency = DEFAULT_BROADCAST_LATENCY
+	}
+	act := b.Act
+	if act == nil {
+		act = defaultAct
+	}
+
+	result := &BroadcastResult{Started: time.Now(), Observers: b.Observers, Latency: latency}
+
+	// The observers enter the world before the actor, so they see it coming
+	spectating, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	observers := make([]Observer, 0, b.Observers)
+	for i := 1; i <= b.Observers; i++ {
+		config := b.Client
+		config.Username = fmt.Sprintf("%s%d", b.Client.Username, i)
+		config.Spectator = true
+
+		gameClient := b.newClient(fmt.Sprintf("observer-%d", i), config)
+		observer, ok := gameClient.(Observer)
+		if !ok {
+			return nil, fmt.Errorf("invalid broadcast check: the client %s can't observe the world", gameClient.GetID())
+		}
+		defer observer.Disconnect()
+		if err := observer.Connect(); err != nil {
+			return nil, fmt.Errorf("observer %s couldn't connect: %w", observer.GetID(), err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			observer.Spectate(spectating)
+		}()
+		observers = append(observers, observer)
+	}
+
+	config := b.Client
+	config.Username = b.Client.Username + "0"
+	actor := b.newClient("actor", config)
+	defer actor.Disconnect()
+	if err := actor.Connect(); err != nil {
+		return nil, fmt.Errorf("actor couldn't connect: %w", err)
+	}
+
+	var latencies []time.Duration
+	for round := 1; round <= rounds; round++ {
+		for _, action := range actions {
+			if err := ctx.Err(); err != nil {
+				result.finish(latencies)
+				return result, err
+			}
+
+			performed := BroadcastAction{Action: action, Round: round, Sent: time.Now()}
+			match, err := act(actor, action, round)
+			if err != nil {
+				performed.Error = err.Error()
+			} else {
+				latencies = append(latencies, watch(ctx, observers, &performed, match, latency)...)
+			}
+			result.Actions = append(result.Actions, performed)
+		}
+	}
+
+	result.finish(latencies)
+	return result, nil
+}
+
+// watch waits for the observers to see an action, for no longer than the bound, and records how
+// they saw it. It returns the latencies of the observers which saw it.
+func watch(ctx context.Context, observers []Observer, performed *BroadcastAction, match func(client.WorldEvent) bool, latency time.Duration) []time.Duration {
+	deadline := performed.Sent.Add(latency)
+	seen := make(map[string]time.Duration, len(observers))
+
+	for {
+		for _, observer := range observers {
+			if _, ok := seen[observer.GetID()]; ok {
+				continue
+			}
+			for _, event := range observer.WorldEvents() {
+				if !event.At.Before(performed.Sent) && match(event) {
+					seen[observer.GetID()] = event.At.Sub(performed.Sent)
+					break
+				}
+			}
+		}
+
+		if len(seen) == len(observers) || !time.Now().Before(deadline) || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-time.After(broadcastPoll):
+		case <-ctx.Done():
+		}
+	}
+
+	latencies := make([]time.Duration, 0, len(seen))
+	for _, observer := range observers {
+		took, ok := seen[observer.GetID()]
+		switch {
+		case !ok:
+			performed.Missed = append(performed.Missed, observer.GetID())
+			continue
+		case took > latency:
+			performed.Late = append(performed.Late, observer.GetID())
+		default:
+			performed.Seen++
+		}
+		performed.MaxLatency = max(performed.MaxLatency, took)
+		latencies = append(latencies, took)
+	}
+	return latencies
+}
+
+// finish computes the figures of the check
+func (r *BroadcastResult) finish(latencies []time.Duration) {
+	r.Duration = time.Since(r.Started)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	r.Deliveries = len(latencies)
+	r.LatencyP50 = percentile(latencies, 50)
+	r.LatencyP95 = percentile(latencies, 95)
+	r.LatencyP99 = percentile(latencies, 99)
+
+	r.Consistent = len(r.Actions) > 0
+	for _, action := range r.Actions {
+		r.Late += len(action.Late)
+		r.Missed += len(action.Missed)
+		if action.Error != "" || len(action.Late) > 0 || len(action.Missed) > 0 {
+			r.Consistent = false
+		}
+	}
+}
+
+// defaultAct performs the actions through the chat and the moves of an Actor
+func defaultAct(gameClient client.GameClient, action string, round int) (func(client.WorldEvent) bool, error) {
+	actor, ok := gameClient.(Actor)
+	if !ok {
+		return nil, fmt.Errorf("the client %s can't act in the world", gameClient.GetID())
+	}
+
+	switch action {
+	case BROADCAST_CHAT:
+		text := fmt.Sprintf("broadcast check %d", round)
+		if err := actor.Say(text); err != nil {
+			return nil, err
+		}
+		return func(event client.WorldEvent) bool {
+			return event.Kind == client.WorldChat && event.Text == text
+		}, nil
+
+	case BROADCAST_MOVE:
+		session := actor.Sessions().GameSession()
+		if session == nil || session.SelectedChar == nil || session.SelectedChar.Location == nil {
+			return nil, fmt.Errorf("%w: the actor isn't in the world", client.ErrInvalidState)
+		}
+		location := session.SelectedChar.Location
+		destination := client.CharacterLocation{X: location.X + broadcastStep, Y: location.Y, Z: location.Z}
+		if err := actor.MoveTo(destination.X, destination.Y, destination.Z); err != nil {
+			return nil, err
+		}
+		return func(event client.WorldEvent) bool {
+			return event.Kind == client.WorldMove && event.Location != nil && *event.Location == destination
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown broadcast action: %s", action)
+}
+
+// newClient creates a client of the check
+func (b *BroadcastCheck) newClient(id string, config client.ClientConfig) client.GameClient {
+	if b.NewClient != nil {
+		return b.NewClient(id, config)
+	}
+	return client.NewClient(id, config)
+}
+
+// WriteBroadcastReport writes the result of a broadcast check in one of the report formats: json or text
+func WriteBroadcastReport(w io.Writer, result *BroadcastResult, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case "text":
+		verdict := "consistent"
+		if !result.Consistent {
+			verdict = "inconsistent"
+		}
+		fmt.Fprintf(w, "Broadcast check (%d observers, %d actions, within %v): %s\n", result.Observers, len(result.Actions), result.Latency, verdict)
+		fmt.Fprintf(w, "Deliveries: %d, %d late, %d missed\n", result.Deliveries, result.Late, result.Missed)
+		fmt.Fprintf(w, "Latency: p50 %v, p95 %v, p99 %v\n", result.LatencyP50, result.LatencyP95, result.LatencyP99)
+		for _, action := range result.Actions {
+			switch {
+			case action.Error != "":
+				fmt.Fprintf(w, "  %s #%d failed: %s\n", action.Action, action.Round, action.Error)
+			case len(action.Late) > 0 || len(action.Missed) > 0:
+				fmt.Fprintf(w, "  %s #%d: seen by %d, late for %v, missed by %v\n", action.Action, action.Round, action.Seen, action.Late, action.Missed)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid report format: %s, must be one of: json, text", format)
+}
@@ -0,0 +1,100 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/testserver"
+)
+
+func TestBroadcastCheck(t *testing.T) {
+	gameServer := testserver.NewGameServer(testserver.Character{ObjectID: 0x10000001, Name: "Tester", Level: 1, X: 10, Y: 20, Z: -30})
+	if err := gameServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer gameServer.Close()
+
+	loginServer := testserver.NewLoginServer()
+	loginServer.AddGameServer(1, gameServer.Addr())
+	if err := loginServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer loginServer.Close()
+
+	config := client.ClientConfig{
+		LoginServerHost: "127.0.0.1",
+		LoginServerPort: loginServer.Addr().Port,
+		GameServerHost:  "127.0.0.1",
+		GameServerPort:  gameServer.Addr().Port,
+		Username:        "broadcast",
+		Password:        "testpass",
+		Timeout:         time.Second,
+	}
+
+	tests := []struct {
+		name           string
+		act            ActFunc
+		wantConsistent bool
+		wantMissed     int
+		wantError      bool
+	}{
+		{name: "chat and moves", wantConsistent: true},
+		{
+			name: "never seen",
+			act: func(actor client.GameClient, action string, round int) (func(client.WorldEvent) bool, error) {
+				return func(event client.WorldEvent) bool { return event.Text == "never said" }, nil
+			},
+			wantMissed: 3 * 4,
+		},
+		{
+			name: "unknown action",
+			act: func(actor client.GameClient, action string, round int) (func(client.WorldEvent) bool, error) {
+				return defaultAct(actor, "dance", round)
+			},
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := &BroadcastCheck{Client: config, Observers: 3, Rounds: 2, Latency: 200 * time.Millisecond, Act: tt.act}
+			result, err := check.Run(context.Background())
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if len(result.Actions) != 4 {
+				t.Fatalf("Actions = %+v, want 2 rounds of 2 actions", result.Actions)
+			}
+			if result.Consistent != tt.wantConsistent || result.Missed != tt.wantMissed || result.Late != 0 {
+				t.Errorf("Consistent = %t, Missed = %d, Late = %d, want %t, %d and 0", result.Consistent, result.Missed, result.Late, tt.wantConsistent, tt.wantMissed)
+			}
+			for _, action := range result.Actions {
+				if (action.Error != "") != tt.wantError {
+					t.Errorf("%s #%d error = %q", action.Action, action.Round, action.Error)
+				}
+				if tt.wantConsistent && (action.Seen != 3 || action.MaxLatency > check.Latency) {
+					t.Errorf("%s #%d seen by %d within %v", action.Action, action.Round, action.Seen, action.MaxLatency)
+				}
+			}
+			if tt.wantConsistent && result.Deliveries != 3*4 {
+				t.Errorf("Deliveries = %d, want %d", result.Deliveries, 3*4)
+			}
+
+			var report bytes.Buffer
+			if err := WriteBroadcastReport(&report, result, "text"); err != nil {
+				t.Fatalf("WriteBroadcastReport() error = %v", err)
+			}
+			if verdict := strings.Contains(report.String(), ": consistent"); verdict != tt.wantConsistent {
+				t.Errorf("report = %q", report.String())
+			}
+		})
+	}
+
+	if _, err := (&BroadcastCheck{Client: config}).Run(context.Background()); err == nil {
+		t.Error("Run() error = nil without observers")
+	}
+}
@@ -0,0 +1,167 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+)
+
+// Delta is how a figure moved between a baseline run and a candidate run
+type Delta struct {
+	Name      string  `json:"name"`
+	Baseline  float64 `json:"baseline"`
+	Candidate float64 `json:"candidate"`
+	Change    float64 `json:"change"`  // Candidate minus baseline
+	Percent   float64 `json:"percent"` // Change in percent of the baseline, 0 when the baseline is 0
+	Worse     bool    `json:"worse"`   // The candidate regressed on this figure
+}
+
+// Comparison is the regression summary of a candidate run against a baseline run
+type Comparison struct {
+	Baseline  string   `json:"baseline"`
+	Candidate string   `json:"candidate"`
+	Deltas    []Delta  `json:"deltas"`
+	NewErrors []string `json:"newErrors,omitempty"` // Types of error the baseline never ran into
+	Regressed bool     `json:"regressed"`
+}
+
+// LoadResult reads the json report of a previous run
+func LoadResult(path string) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report %s: %w", path, err)
+	}
+
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+
+	return &result, nil
+}
+
+// Compare summarizes how the candidate run moved from the baseline run. A figure
+// only regresses when it worsened by more than tolerance, in percent of the baseline.
+func Compare(baseline, candidate *Result, tolerance float64) *Comparison {
+	comparison := &Comparison{
+		Baseline:  baseline.Started.Format("2006-01-02 15:04:05"),
+		Candidate: candidate.Started.Format("2006-01-02 15:04:05"),
+	}
+
+	// Higher is worse for every figure but the throughput and the capacity
+	type figure struct {
+		name                string
+		baseline, candidate float64
+		higherIsBetter      bool
+	}
+	figures := []figure{
+		{name: "login p50 (ms)", baseline: milliseconds(baseline.LoginP50), candidate: milliseconds(candidate.LoginP50)},
+		{name: "login p95 (ms)", baseline: milliseconds(baseline.LoginP95), candidate: milliseconds(candidate.LoginP95)},
+		{name: "login p99 (ms)", baseline: milliseconds(baseline.LoginP99), candidate: milliseconds(candidate.LoginP99)},
+		{name: "throughput (connects/s)", baseline: baseline.Throughput(), candidate: candidate.Throughput(), higherIsBetter: true},
+		{name: "error rate (%)", baseline: baseline.ErrorRate, candidate: candidate.ErrorRate},
+		{name: "drops", baseline: float64(baseline.Drops), candidate: float64(candidate.Drops)},
+	}
+	if baseline.Memory != nil && candidate.Memory != nil {
+		figures = append(figures,
+			figure{name: "peak heap (MiB)", baseline: float64(baseline.Memory.PeakHeap) / MEBIBYTE, candidate: float64(candidate.Memory.PeakHeap) / MEBIBYTE},
+			figure{name: "client footprint (KiB)", baseline: float64(baseline.Memory.PerClient) / 1024, candidate: float64(candidate.Memory.PerClient) / 1024},
+		)
+	}
+	if baseline.Adaptive != nil && candidate.Adaptive != nil {
+		figures = append(figures, figure{name: "capacity (clients)", baseline: float64(baseline.Adaptive.Capacity),
+			candidate: float64(candidate.Adaptive.Capacity), higherIsBetter: true})
+	}
+
+	for _, figure := range figures {
+		delta := Delta{
+			Name:      figure.name,
+			Baseline:  figure.baseline,
+			Candidate: figure.candidate,
+			Change:    figure.candidate - figure.baseline,
+		}
+		worsened := (delta.Change > 0 && !figure.higherIsBetter) || (delta.Change < 0 && figure.higherIsBetter)
+		if figure.baseline != 0 {
+			delta.Percent = delta.Change * 100 / figure.baseline
+			delta.Worse = worsened && math.Abs(delta.Percent) > tolerance
+		} else {
+			delta.Worse = worsened
+		}
+
+		comparison.Deltas = append(comparison.Deltas, delta)
+		comparison.Regressed = comparison.Regressed || delta.Worse
+	}
+
+	known := make(map[string]bool)
+	for _, count := range baseline.Errors {
+		known[count.Type] = true
+	}
+	for _, count := range candidate.Errors {
+		if !known[count.Type] {
+			comparison.NewErrors = append(comparison.NewErrors, count.Type)
+		}
+	}
+	comparison.Regressed = comparison.Regressed || len(comparison.NewErrors) > 0
+
+	return comparison
+}
+
+var comparisonTemplate = template.Must(template.New("comparison").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Load test comparison</title></head>
+<body>
+<h1>{{if .Regressed}}Regressed{{else}}No regression{{end}}</h1>
+<p>Baseline run of {{.Baseline}}, candidate run of {{.Candidate}}</p>
+<table>
+<tr><th>Figure</th><th>Baseline</th><th>Candidate</th><th>Change</th><th>%</th></tr>
+{{range .Deltas}}<tr{{if .Worse}} style="color: red"{{end}}><td>{{.Name}}</td><td>{{printf "%.2f" .Baseline}}</td><td>{{printf "%.2f" .Candidate}}</td><td>{{printf "%+.2f" .Change}}</td><td>{{printf "%+.1f" .Percent}}</td></tr>
+{{end}}</table>
+{{if .NewErrors}}<h2>New errors</h2>
+<ul>
+{{range .NewErrors}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+// WriteComparison writes the comparison in one of the comparison formats: text, json or html
+func WriteComparison(w io.Writer, comparison *Comparison, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(comparison)
+	case "html":
+		return comparisonTemplate.Execute(w, comparison)
+	case "text":
+		return writeComparisonText(w, comparison)
+	}
+	return fmt.Errorf("invalid comparison format: %s, must be one of: text, json, html", format)
+}
+
+// writeComparisonText writes one line per figure, flagging the regressions
+func writeComparisonText(w io.Writer, comparison *Comparison) error {
+	verdict := "no regression"
+	if comparison.Regressed {
+		verdict = "REGRESSED"
+	}
+	fmt.Fprintf(w, "Baseline %s, candidate %s: %s\n", comparison.Baseline, comparison.Candidate, verdict)
+
+	for _, delta := range comparison.Deltas {
+		flag := " "
+		if delta.Worse {
+			flag = "!"
+		}
+		fmt.Fprintf(w, "%s %-24s %10.2f -> %10.2f (%+.2f, %+.1f%%)\n", flag, delta.Name, delta.Baseline, delta.Candidate, delta.Change, delta.Percent)
+	}
+
+	for _, errorType := range comparison.NewErrors {
+		if _, err := fmt.Fprintf(w, "! new error: %s\n", errorType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
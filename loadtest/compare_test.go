@@ -0,0 +1,125 @@
+package loadtest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+func TestCompare(t *testing.T) {
+	baseline := &Result{
+		Duration: 10 * time.Second,
+		Connects: 100,
+		LoginP50: 100 * time.Millisecond,
+		LoginP95: 200 * time.Millisecond,
+		LoginP99: 400 * time.Millisecond,
+		Errors:   []ErrorCount{{Type: "connection timeout", Count: 1}},
+	}
+
+	tests := []struct {
+		name          string
+		candidate     Result
+		wantWorse     []string
+		wantNewErrors []string
+	}{
+		{
+			name:      "same",
+			candidate: *baseline,
+		},
+		{
+			name:      "within tolerance",
+			candidate: Result{Duration: 10 * time.Second, Connects: 98, LoginP50: 102 * time.Millisecond, LoginP95: 190 * time.Millisecond, LoginP99: 400 * time.Millisecond},
+		},
+		{
+			name:          "slower with new errors",
+			candidate:     Result{Duration: 10 * time.Second, Connects: 50, LoginP50: 100 * time.Millisecond, LoginP95: 200 * time.Millisecond, LoginP99: 800 * time.Millisecond, Drops: 1, Errors: []ErrorCount{{Type: "server is full", Count: 3}}},
+			wantWorse:     []string{"login p99 (ms)", "throughput (connects/s)", "drops"},
+			wantNewErrors: []string{"server is full"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comparison := Compare(baseline, &tt.candidate, 5)
+
+			var worse []string
+			for _, delta := range comparison.Deltas {
+				if delta.Worse {
+					worse = append(worse, delta.Name)
+				}
+			}
+			if strings.Join(worse, ",") != strings.Join(tt.wantWorse, ",") {
+				t.Errorf("worse figures = %v, want %v", worse, tt.wantWorse)
+			}
+			if strings.Join(comparison.NewErrors, ",") != strings.Join(tt.wantNewErrors, ",") {
+				t.Errorf("NewErrors = %v, want %v", comparison.NewErrors, tt.wantNewErrors)
+			}
+			if comparison.Regressed != (len(tt.wantWorse)+len(tt.wantNewErrors) > 0) {
+				t.Errorf("Regressed = %v", comparison.Regressed)
+			}
+		})
+	}
+}
+
+func TestWriteComparison(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	baseline := &Result{Duration: time.Second, Connects: 10, LoginP99: 100 * time.Millisecond}
+
+	var report bytes.Buffer
+	if err := WriteReport(&report, baseline, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, report.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadResult(path)
+	if err != nil {
+		t.Fatalf("LoadResult() error = %v", err)
+	}
+
+	candidate := &Result{Duration: time.Second, Connects: 10, LoginP99: 300 * time.Millisecond, Errors: []ErrorCount{{Type: "<server is full>", Count: 1}}}
+	comparison := Compare(loaded, candidate, 5)
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{format: "text", want: []string{"REGRESSED", "! login p99 (ms)", "+200.00, +200.0%", "! new error: <server is full>"}},
+		{format: "json", want: []string{`"regressed": true`, `"newErrors": [`}},
+		{format: "html", want: []string{"<h1>Regressed</h1>", `<tr style="color: red"><td>login p99 (ms)</td>`, "<li>&lt;server is full&gt;</li>"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := WriteComparison(&buffer, comparison, tt.format); err != nil {
+				t.Fatalf("WriteComparison() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buffer.String(), want) {
+					t.Errorf("comparison does not contain %q:\n%s", want, buffer.String())
+				}
+			}
+		})
+	}
+}
+
+func TestResultRecordError(t *testing.T) {
+	var result Result
+	result.recordError(fmt.Errorf("%w: %v", client.ErrConnectionFailed, "dial tcp: connection refused"))
+	result.recordError(fmt.Errorf("login: %w", client.ErrConnectionFailed))
+	result.recordError(client.ErrServerFull)
+
+	want := []ErrorCount{{Type: client.ErrConnectionFailed.Error(), Count: 2}, {Type: client.ErrServerFull.Error(), Count: 1}}
+	if result.Failures != 3 || !reflect.DeepEqual(result.Errors, want) {
+		t.Errorf("Failures = %d, Errors = %+v, want 3 and %+v", result.Failures, result.Errors, want)
+	}
+}
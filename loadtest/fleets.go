@@ -0,0 +1,168 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/junit"
+	"github.com/frostwind/l2go/manager"
+)
+
+// FleetResult is the result of the load test of a fleet, or why it could not complete
+type FleetResult struct {
+	Name   string  `json:"name"`
+	Result *Result `json:"result,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// FleetsResult gathers the results of the fleets run side by side
+type FleetsResult struct {
+	Fleets []FleetResult `json:"fleets"`
+	Passed bool          `json:"passed"`
+}
+
+// ExitCode returns the exit code of the process, the worst of the fleets
+func (r *FleetsResult) ExitCode() int {
+	code := EXIT_PASSED
+	for _, fleet := range r.Fleets {
+		switch {
+		case fleet.Result == nil || fleet.Error != "":
+			return EXIT_ERROR
+		case !fleet.Result.Passed:
+			code = EXIT_FAILED
+		}
+	}
+	return code
+}
+
+// Fleets runs the fleets of a toolkit configuration at once, every fleet with its own manager
+// following its own load test, and its metrics published under the name of the fleet
+type Fleets struct {
+	Config    *client.ToolkitConfig
+	NewClient client.ClientFactory // Defaults to client.NewClient
+	Tick      time.Duration        // Defaults to DEFAULT_TICK
+
+	managers map[string]*manager.Manager
+	mu       sync.Mutex
+}
+
+// Run runs every fleet until its load test ends, or until the context is done. The managers
+// are shut down once they all stopped.
+func (f *Fleets) Run(ctx context.Context) (*FleetsResult, error) {
+	if len(f.Config.Fleets) == 0 {
+		return nil, fmt.Errorf("the configuration has no fleet")
+	}
+
+	f.mu.Lock()
+	f.managers = make(map[string]*manager.Manager, len(f.Config.Fleets))
+	runners := make([]*Runner, len(f.Config.Fleets))
+	for i, fleet := range f.Config.Fleets {
+		cfg, err := f.Config.FleetClient(fleet)
+		if err != nil {
+			f.mu.Unlock()
+			f.shutdown()
+			return nil, fmt.Errorf("fleet %s: %w", fleet.Name, err)
+		}
+
+		managerConfig := fleet.Manager
+		if managerConfig.Namespace == "" {
+			managerConfig.Namespace = "fleet." + fleet.Name
+		}
+		m := manager.NewManager(&managerConfig)
+		f.managers[fleet.Name] = m
+		if err := m.StartDiagnostics(); err != nil {
+			f.mu.Unlock()
+			f.shutdown()
+			return nil, fmt.Errorf("fleet %s: %w", fleet.Name, err)
+		}
+
+		runners[i] = &Runner{Manager: m, Client: cfg, Test: fleet.LoadTest, NewClient: f.NewClient, Tick: f.Tick, Accounts: fleet.Accounts}
+	}
+	f.mu.Unlock()
+	defer f.shutdown()
+
+	result := &FleetsResult{Fleets: make([]FleetResult, len(runners)), Passed: true}
+	var wg sync.WaitGroup
+	for i, runner := range runners {
+		wg.Add(1)
+		go func(i int, runner *Runner) {
+			defer wg.Done()
+			fleetResult, err := runner.Run(ctx)
+			result.Fleets[i] = FleetResult{Name: f.Config.Fleets[i].Name, Result: fleetResult}
+			if err != nil {
+				result.Fleets[i].Error = err.Error()
+			}
+		}(i, runner)
+	}
+	wg.Wait()
+
+	for _, fleet := range result.Fleets {
+		if fleet.Result == nil || !fleet.Result.Passed || fleet.Error != "" {
+			result.Passed = false
+		}
+	}
+	return result, ctx.Err()
+}
+
+// Snapshot returns the state of the clients of every fleet, by name of fleet
+func (f *Fleets) Snapshot() map[string]manager.FleetSnapshot {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	snapshots := make(map[string]manager.FleetSnapshot, len(f.managers))
+	for name, m := range f.managers {
+		snapshots[name] = m.Snapshot()
+	}
+	return snapshots
+}
+
+// shutdown shuts the managers of the fleets down
+func (f *Fleets) shutdown() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, m := range f.managers {
+		m.Shutdown()
+	}
+}
+
+// WriteFleetsReport writes the results of the fleets in one of the report formats: json, text or junit
+func WriteFleetsReport(w io.Writer, result *FleetsResult, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case "text":
+		fmt.Fprintf(w, "%d fleets: %s\n", len(result.Fleets), verdictText(result.Passed))
+		for _, fleet := range result.Fleets {
+			fmt.Fprintf(w, "\n[%s]\n", fleet.Name)
+			if fleet.Error != "" {
+				fmt.Fprintf(w, "Error: %s\n", fleet.Error)
+			}
+			if fleet.Result != nil {
+				if err := writeText(w, fleet.Result); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case "junit":
+		var suites []*junit.Suite
+		for _, fleet := range result.Fleets {
+			if fleet.Result == nil {
+				continue
+			}
+			suite := JUnitSuite(fleet.Result)
+			suite.Name = "loadtest." + fleet.Name + "." + fleet.Result.Shape
+			suites = append(suites, suite)
+		}
+		return junit.Write(w, "loadtest", suites...)
+	}
+	return fmt.Errorf("invalid report format: %s, must be one of: json, text, junit", format)
+}
@@ -0,0 +1,106 @@
+package loadtest
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/manager"
+)
+
+func TestFleets(t *testing.T) {
+	cfg := client.DefaultToolkitConfig()
+	test := client.LoadTestConfig{
+		DefaultClientCount: 3,
+		DefaultDuration:    100 * time.Millisecond,
+		MaxConcurrentTests: 1,
+		ReportFormat:       "json",
+	}
+	cfg.Fleets = []client.FleetConfig{
+		{Name: "logins", Username: "login", Accounts: 2, Manager: cfg.Manager, LoadTest: test},
+		{Name: "players", Profile: "development", Manager: cfg.Manager, LoadTest: test},
+	}
+	cfg.Fleets[1].LoadTest.DefaultClientCount = 1
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	var usernames []string
+	fleets := &Fleets{
+		Config: cfg,
+		Tick:   10 * time.Millisecond,
+		NewClient: func(id string, config client.ClientConfig) client.GameClient {
+			mu.Lock()
+			usernames = append(usernames, config.Username)
+			mu.Unlock()
+			return manager.NewGameClient(id, config)
+		},
+	}
+
+	result, err := fleets.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !result.Passed || result.ExitCode() != EXIT_PASSED || len(result.Fleets) != 2 {
+		t.Fatalf("Run() = %+v", result)
+	}
+	for i, want := range []int{3, 1} {
+		if fleet := result.Fleets[i]; fleet.Result.Peak != want {
+			t.Errorf("fleet %s peaked at %d clients, want %d", fleet.Name, fleet.Result.Peak, want)
+		}
+	}
+
+	// Every fleet has its own accounts, the players the ones of their profile
+	sort.Strings(usernames)
+	if want := []string{"devuser", "login0", "login0", "login1"}; !slices.Equal(usernames, want) {
+		t.Errorf("usernames = %v, want %v", usernames, want)
+	}
+
+	// Each fleet had its own manager
+	if snapshots := fleets.Snapshot(); len(snapshots) != 2 {
+		t.Errorf("Snapshot() = %+v, want the managers of the 2 fleets", snapshots)
+	}
+
+	var report strings.Builder
+	if err := WriteFleetsReport(&report, result, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report.String(), "[logins]") || !strings.Contains(report.String(), "[players]") {
+		t.Errorf("text report misses a fleet:\n%s", report.String())
+	}
+}
+
+func TestFleetsValidation(t *testing.T) {
+	defaults := client.DefaultToolkitConfig()
+	fleet := func(name, profile string) client.FleetConfig {
+		return client.FleetConfig{Name: name, Profile: profile, Manager: defaults.Manager, LoadTest: defaults.LoadTest}
+	}
+	idle := fleet("a", "")
+	idle.LoadTest.DefaultClientCount = 0
+
+	tests := []struct {
+		name   string
+		fleets []client.FleetConfig
+	}{
+		{name: "unnamed", fleets: []client.FleetConfig{fleet("", "")}},
+		{name: "duplicate", fleets: []client.FleetConfig{fleet("a", ""), fleet("a", "testing")}},
+		{name: "unknown profile", fleets: []client.FleetConfig{fleet("a", "staging")}},
+		{name: "no clients", fleets: []client.FleetConfig{idle}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := client.DefaultToolkitConfig()
+			cfg.Fleets = tt.fleets
+			if err := cfg.Validate(); err == nil {
+				t.Error("Validate() accepted the fleets")
+			}
+		})
+	}
+}
@@ -0,0 +1,414 @@
+// L2load runs a load test against a login server and its game servers, as
+// described by a client toolkit configuration file, then writes its report.
+// It exits with 0 when the run meets its SLOs, 1 when it doesn't and 2 when
+// it could not complete, so it can gate a CI pipeline:
+//
+//	go run github.com/frostwind/l2go/loadtest/l2load -config client-toolkit.json -report report.json
+//
+// With -speed, the load shape and the scenario waits run that many times
+// faster, to check a long run in seconds against the stub servers:
+//
+//	go run github.com/frostwind/l2go/loadtest/l2load -config client-toolkit.json -speed 60
+//
+// With -snapshot, an interrupted run first writes the state of every client,
+// before logging them out, and with -debug the same snapshot is served live
+// over HTTP, to find out where a stuck run is stuck:
+//
+//	go run github.com/frostwind/l2go/loadtest/l2load -config client-toolkit.json -snapshot fleet.json -debug 127.0.0.1:6070
+//	curl http://127.0.0.1:6070/snapshot
+//
+// Once the run is over, the summary of the clients is logged to the standard
+// error: the phases of the handshake they failed in, their most frequent
+// errors and how long they took to connect. The summaryFile of the manager
+// configuration writes it as json as well.
+//
+// With the diagnostics enabled in the manager configuration, the pprof profiles
+// and the expvar variables are served during the run, and SIGUSR1 dumps the
+// goroutines, to investigate a soak test without rebuilding.
+//
+// When the configuration lists fleets, they run side by side instead, every
+// fleet with its own manager, profile, accounts and load test, its metrics
+// published under its name, and the report gathers their results.
+//
+// The login-storm command benchmarks the login server alone instead: clients
+// log in and disconnect as fast as they can, never entering the game, cycling
+// through keyed accounts. Given the admin API of the login server, the report
+// tells whether the logins wait for the database or for the CPU:
+//
+//	go run github.com/frostwind/l2go/loadtest/l2load login-storm -config client-toolkit.json -concurrency 64 -duration 30s -accounts 1000 -admin http://127.0.0.1:8080
+//
+// The broadcast-check command verifies the broadcasts of the game server
+// instead: an actor chats and moves while spectating observers check they see
+// it within a latency bound, and it exits with 1 when one of them didn't:
+//
+//	go run github.com/frostwind/l2go/loadtest/l2load broadcast-check -config client-toolkit.json -observers 8 -rounds 5 -latency 500ms
+//
+// With -compare, it compares the json reports of two previous runs instead,
+// and exits with 1 when the candidate regressed:
+//
+//	go run github.com/frostwind/l2go/loadtest/l2load -compare baseline.json -format html candidate.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/loadtest"
+	"github.com/frostwind/l2go/manager"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "login-storm" {
+		os.Exit(loginStorm(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "broadcast-check" {
+		os.Exit(broadcastCheck(os.Args[2:]))
+	}
+
+	configFile := flag.String("config", "", "client toolkit configuration file, searched in the default locations when empty")
+	reportFile := flag.String("report", "", "report file, the standard output when empty")
+	format := flag.String("format", "", "report format, the one of the configuration when empty, or text when comparing")
+	baseline := flag.String("compare", "", "json report of the baseline run to compare the report given as argument with")
+	tolerance := flag.Float64("tolerance", 5, "percentage a figure can worsen by before the comparison flags it")
+	speed := flag.Float64("speed", 0, "how many times faster than real time the run goes, the speed of the configuration when 0")
+	snapshotFile := flag.String("snapshot", "", "file the state of the clients is written to when the run is interrupted")
+	debugAddress := flag.String("debug", "", "address serving the state of the clients at /snapshot during the run")
+	flag.Parse()
+
+	if *baseline != "" {
+		if flag.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "l2load: -compare needs the json report of the candidate run as argument")
+			os.Exit(loadtest.EXIT_ERROR)
+		}
+		os.Exit(compare(*baseline, flag.Arg(0), *reportFile, *format, *tolerance))
+	}
+
+	os.Exit(run(*configFile, *reportFile, *format, *speed, *snapshotFile, *debugAddress))
+}
+
+func run(configFile, reportFile, format string, speed float64, snapshotFile, debugAddress string) int {
+	config, err := client.LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+	if format == "" {
+		format = config.LoadTest.ReportFormat
+	}
+	if speed != 0 {
+		config.LoadTest.Speed = speed
+		for i := range config.Fleets {
+			config.Fleets[i].LoadTest.Speed = speed
+		}
+	}
+
+	if len(config.Fleets) > 0 {
+		return runFleets(config, reportFile, format, snapshotFile, debugAddress)
+	}
+
+	m := manager.NewManager(&config.Manager)
+	defer m.Shutdown()
+
+	if err := m.StartDiagnostics(); err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+	if addr := m.DiagnosticsAddr(); addr != nil {
+		fmt.Fprintf(os.Stderr, "l2load: serving the diagnostics on %s\n", addr)
+	}
+
+	if debugAddress != "" {
+		listener, err := net.Listen("tcp", debugAddress)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "l2load:", err)
+			return loadtest.EXIT_ERROR
+		}
+		server := &http.Server{Handler: snapshotHandler(func() any { return m.Snapshot() })}
+		defer server.Close()
+		go server.Serve(listener)
+	}
+
+	ctx, stop := interruptible(snapshotFile, func() any { return m.Snapshot() })
+	defer stop()
+
+	runner := &loadtest.Runner{Manager: m, Client: config.Client, Test: config.LoadTest}
+	result, runErr := runner.Run(ctx)
+	if result == nil {
+		fmt.Fprintln(os.Stderr, "l2load:", runErr)
+		return loadtest.EXIT_ERROR
+	}
+
+	w, closeReport, err := createReport(reportFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+	defer closeReport()
+
+	if err := loadtest.WriteReport(w, result, format); err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+
+	// An interrupted run didn't last long enough to be judged
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", runErr)
+		return loadtest.EXIT_ERROR
+	}
+	return result.ExitCode()
+}
+
+func runFleets(config *client.ToolkitConfig, reportFile, format, snapshotFile, debugAddress string) int {
+	fleets := &loadtest.Fleets{Config: config}
+
+	if debugAddress != "" {
+		listener, err := net.Listen("tcp", debugAddress)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "l2load:", err)
+			return loadtest.EXIT_ERROR
+		}
+		server := &http.Server{Handler: snapshotHandler(func() any { return fleets.Snapshot() })}
+		defer server.Close()
+		go server.Serve(listener)
+	}
+
+	ctx, stop := interruptible(snapshotFile, func() any { return fleets.Snapshot() })
+	defer stop()
+
+	result, runErr := fleets.Run(ctx)
+	if result == nil {
+		fmt.Fprintln(os.Stderr, "l2load:", runErr)
+		return loadtest.EXIT_ERROR
+	}
+
+	w, closeReport, err := createReport(reportFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+	defer closeReport()
+
+	if err := loadtest.WriteFleetsReport(w, result, format); err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", runErr)
+		return loadtest.EXIT_ERROR
+	}
+	return result.ExitCode()
+}
+
+// interruptible returns a context done on an interrupt, which first writes the snapshot
+// of the clients to snapshotFile, before they are logged out, showing where they were stuck
+func interruptible(snapshotFile string, snapshot func() any) (context.Context, context.CancelFunc) {
+	ctx, stop := context.WithCancel(context.Background())
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		defer signal.Stop(interrupts)
+		select {
+		case <-interrupts:
+			if snapshotFile != "" {
+				if err := writeSnapshot(snapshotFile, snapshot()); err != nil {
+					fmt.Fprintln(os.Stderr, "l2load:", err)
+				}
+			}
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, stop
+}
+
+func loginStorm(args []string) int {
+	flags := flag.NewFlagSet("login-storm", flag.ExitOnError)
+	configFile := flags.String("config", "", "client toolkit configuration file, searched in the default locations when empty")
+	reportFile := flags.String("report", "", "report file, the standard output when empty")
+	format := flags.String("format", "text", "report format, json or text")
+	concurrency := flags.Int("concurrency", loadtest.DEFAULT_STORM_CONCURRENCY, "clients logging in at once")
+	duration := flags.Duration("duration", 10*time.Second, "how long the clients keep logging in")
+	accounts := flags.Int("accounts", 0, "accounts cycled through, the username of the configuration followed by an index, 0 for the username alone")
+	adminURL := flags.String("admin", "", "admin API of the login server, read to break the time of the logins down between the database and the CPU")
+	flags.Parse(args)
+
+	config, err := client.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	storm := &loadtest.LoginStorm{
+		Client:      config.Client,
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Accounts:    *accounts,
+		AdminURL:    *adminURL,
+	}
+	result, stormErr := storm.Run(ctx)
+	if result == nil {
+		fmt.Fprintln(os.Stderr, "l2load:", stormErr)
+		return loadtest.EXIT_ERROR
+	}
+
+	w, closeReport, err := createReport(*reportFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+	defer closeReport()
+
+	if err := loadtest.WriteStormReport(w, result, *format); err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+
+	if stormErr != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", stormErr)
+		return loadtest.EXIT_ERROR
+	}
+	if result.Logins == 0 {
+		return loadtest.EXIT_FAILED
+	}
+	return loadtest.EXIT_PASSED
+}
+
+func broadcastCheck(args []string) int {
+	flags := flag.NewFlagSet("broadcast-check", flag.ExitOnError)
+	configFile := flags.String("config", "", "client toolkit configuration file, searched in the default locations when empty")
+	reportFile := flags.String("report", "", "report file, the standard output when empty")
+	format := flags.String("format", "text", "report format, json or text")
+	observers := flags.Int("observers", 4, "clients watching the actor, logging in as the username of the configuration followed by their index")
+	rounds := flags.Int("rounds", 1, "times the actor chats and moves")
+	latency := flags.Duration("latency", loadtest.DEFAULT_BROADCAST_LATENCY, "how long the observers have to see an action")
+	flags.Parse(args)
+
+	config, err := client.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	check := &loadtest.BroadcastCheck{
+		Client:    config.Client,
+		Observers: *observers,
+		Rounds:    *rounds,
+		Latency:   *latency,
+	}
+	result, checkErr := check.Run(ctx)
+	if result == nil {
+		fmt.Fprintln(os.Stderr, "l2load:", checkErr)
+		return loadtest.EXIT_ERROR
+	}
+
+	w, closeReport, err := createReport(*reportFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+	defer closeReport()
+
+	if err := loadtest.WriteBroadcastReport(w, result, *format); err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+
+	if checkErr != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", checkErr)
+		return loadtest.EXIT_ERROR
+	}
+	if !result.Consistent {
+		return loadtest.EXIT_FAILED
+	}
+	return loadtest.EXIT_PASSED
+}
+
+func compare(baselineFile, candidateFile, reportFile, format string, tolerance float64) int {
+	if format == "" {
+		format = "text"
+	}
+
+	baseline, err := loadtest.LoadResult(baselineFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+	candidate, err := loadtest.LoadResult(candidateFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+
+	w, closeReport, err := createReport(reportFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+	defer closeReport()
+
+	comparison := loadtest.Compare(baseline, candidate, tolerance)
+	if err := loadtest.WriteComparison(w, comparison, format); err != nil {
+		fmt.Fprintln(os.Stderr, "l2load:", err)
+		return loadtest.EXIT_ERROR
+	}
+
+	if comparison.Regressed {
+		return loadtest.EXIT_FAILED
+	}
+	return loadtest.EXIT_PASSED
+}
+
+// createReport opens the report file, or the standard output when it is empty
+func createReport(reportFile string) (io.Writer, func() error, error) {
+	if reportFile == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+
+	file, err := os.Create(reportFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, file.Close, nil
+}
+
+// snapshotHandler serves the state of the clients
+func snapshotHandler(snapshot func() any) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshot())
+	})
+	return mux
+}
+
+// writeSnapshot writes the state of the clients as indented json
+func writeSnapshot(snapshotFile string, snapshot any) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(snapshotFile, data, 0644)
+}
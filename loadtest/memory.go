@@ -0,0 +1,85 @@
+package loadtest
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// MEMORY_WARNING is the share of the memory budget past which the ramp should stop
+const MEMORY_WARNING = 0.9
+
+// MEBIBYTE is the unit of the memory budget and of the heap figures of the reports
+const MEBIBYTE = 1 << 20
+
+// MemoryReport is what a run weighed on the heap of the process, for capacity planning. The heap is
+// the one of the whole process, shared by the fleets run side by side.
+type MemoryReport struct {
+	Budget      uint64        `json:"budget,omitempty"`      // Heap the run should stay under, in bytes, none when 0
+	Baseline    uint64        `json:"baseline"`              // Heap in use before the first client, in bytes
+	PeakHeap    uint64        `json:"peakHeap"`              // Highest heap in use, in bytes
+	Clients     int           `json:"clients"`               // Most clients in the world at once, as the footprint was measured
+	PerClient   uint64        `json:"perClient"`             // Approximate footprint of a client, the heap over the baseline divided by the clients
+	Capacity    int           `json:"capacity,omitempty"`    // Clients the budget holds at that footprint
+	WarnedAt    int           `json:"warnedAt,omitempty"`    // Clients in the world as the heap neared the budget, the ramp having to stop
+	WarnedAfter time.Duration `json:"warnedAfter,omitempty"` // How long into the run it did
+	Exceeded    bool          `json:"exceeded,omitempty"`    // The heap went over the budget
+}
+
+// memoryMeter samples the heap of the process as the population of a run changes
+type memoryMeter struct {
+	report MemoryReport
+	heap   func() uint64 // Heap in use, in bytes
+}
+
+// newMemoryMeter measures the baseline of a run with a budget in bytes, none when 0
+func newMemoryMeter(budget uint64, heap func() uint64) *memoryMeter {
+	if heap == nil {
+		heap = heapInUse
+	}
+
+	baseline := heap()
+	return &memoryMeter{
+		report: MemoryReport{Budget: budget, Baseline: baseline, PeakHeap: baseline},
+		heap:   heap,
+	}
+}
+
+// sample records the heap with clients in the world, warning once when it nears the budget
+func (m *memoryMeter) sample(clients int, elapsed time.Duration) {
+	heap := m.heap()
+	m.report.PeakHeap = max(m.report.PeakHeap, heap)
+
+	// The footprint is measured with the most clients, where it is the least noisy
+	if clients > 0 && clients >= m.report.Clients {
+		m.report.Clients = clients
+		m.report.PerClient = (max(heap, m.report.Baseline) - m.report.Baseline) / uint64(clients)
+	}
+
+	if m.report.Budget == 0 {
+		return
+	}
+	m.report.Exceeded = m.report.Exceeded || heap > m.report.Budget
+	if m.report.WarnedAt == 0 && clients > 0 && float64(heap) >= MEMORY_WARNING*float64(m.report.Budget) {
+		m.report.WarnedAt = clients
+		m.report.WarnedAfter = elapsed
+		fmt.Printf("Load test heap at %d MiB with %d clients, past %.0f%% of the budget of %d MiB: the ramp should stop\n",
+			heap/MEBIBYTE, clients, MEMORY_WARNING*100, m.report.Budget/MEBIBYTE)
+	}
+}
+
+// finish returns the report of the run, with the clients the budget holds
+func (m *memoryMeter) finish() *MemoryReport {
+	report := m.report
+	if report.Budget > report.Baseline && report.PerClient > 0 {
+		report.Capacity = int((report.Budget - report.Baseline) / report.PerClient)
+	}
+	return &report
+}
+
+// heapInUse returns the bytes of the heap spans in use
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
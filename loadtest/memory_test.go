@@ -0,0 +1,59 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryMeter(t *testing.T) {
+	tests := []struct {
+		name          string
+		budget        uint64
+		heaps         []uint64 // Heap sampled with one more client each time, after the baseline
+		wantPerClient uint64
+		wantCapacity  int
+		wantWarnedAt  int
+		wantExceeded  bool
+	}{
+		{name: "no budget", heaps: []uint64{11, 12, 13}, wantPerClient: 1},
+		{name: "within the budget", budget: 40, heaps: []uint64{12, 14, 16}, wantPerClient: 2, wantCapacity: 15},
+		{name: "neared", budget: 20, heaps: []uint64{12, 14, 16, 18}, wantPerClient: 2, wantCapacity: 5, wantWarnedAt: 4},
+		{name: "exceeded", budget: 14, heaps: []uint64{13, 16, 19}, wantPerClient: 3, wantCapacity: 1, wantWarnedAt: 1, wantExceeded: true},
+		{name: "heap under the baseline", budget: 40, heaps: []uint64{8, 9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heaps := append([]uint64{10}, tt.heaps...)
+			for i := range heaps {
+				heaps[i] *= MEBIBYTE
+			}
+			next := 0
+			meter := newMemoryMeter(tt.budget*MEBIBYTE, func() uint64 {
+				heap := heaps[next]
+				next++
+				return heap
+			})
+			for clients := 1; clients < len(heaps); clients++ {
+				meter.sample(clients, time.Duration(clients)*time.Second)
+			}
+
+			report := meter.finish()
+			if report.Baseline != 10*MEBIBYTE || report.PeakHeap != max(10*MEBIBYTE, heaps[len(heaps)-1]) {
+				t.Errorf("Baseline = %d, PeakHeap = %d", report.Baseline, report.PeakHeap)
+			}
+			if report.PerClient != tt.wantPerClient*MEBIBYTE {
+				t.Errorf("PerClient = %d, want %d MiB", report.PerClient, tt.wantPerClient)
+			}
+			if report.Capacity != tt.wantCapacity {
+				t.Errorf("Capacity = %d, want %d", report.Capacity, tt.wantCapacity)
+			}
+			if report.WarnedAt != tt.wantWarnedAt {
+				t.Errorf("WarnedAt = %d, want %d", report.WarnedAt, tt.wantWarnedAt)
+			}
+			if report.Exceeded != tt.wantExceeded {
+				t.Errorf("Exceeded = %v, want %v", report.Exceeded, tt.wantExceeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package loadtest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/frostwind/l2go/junit"
+)
+
+// xmlReport names the root element of the xml reports
+type xmlReport struct {
+	XMLName xml.Name `xml:"loadTest"`
+	*Result
+}
+
+// WriteReport writes the result of a run in one of the report formats: json, xml, csv, text or junit
+func WriteReport(w io.Writer, result *Result, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case "xml":
+		data, err := xml.MarshalIndent(xmlReport{Result: result}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+		return err
+	case "csv":
+		return writeCSV(w, result)
+	case "text":
+		return writeText(w, result)
+	case "junit":
+		return junit.Write(w, "loadtest", JUnitSuite(result))
+	}
+	return fmt.Errorf("invalid report format: %s, must be one of: json, xml, csv, text, junit", format)
+}
+
+// JUnitSuite turns the verdicts of a run into a suite with a test case per SLO.
+// A run without SLOs is a single passed case.
+func JUnitSuite(result *Result) *junit.Suite {
+	suite := junit.NewSuite("loadtest."+result.Shape, result.Started)
+	suite.AddProperty("peak", strconv.Itoa(result.Peak))
+	suite.AddProperty("connects", strconv.Itoa(result.Connects))
+	suite.AddProperty("failures", strconv.Itoa(result.Failures))
+	suite.AddProperty("drops", strconv.Itoa(result.Drops))
+	if result.Memory != nil {
+		suite.AddProperty("peakHeap", strconv.FormatUint(result.Memory.PeakHeap, 10))
+		suite.AddProperty("perClient", strconv.FormatUint(result.Memory.PerClient, 10))
+	}
+	if result.Adaptive != nil {
+		suite.AddProperty("capacity", strconv.Itoa(result.Adaptive.Capacity))
+		suite.AddProperty("settled", strconv.Itoa(result.Adaptive.Settled))
+	}
+
+	for _, verdict := range result.Verdicts {
+		if verdict.Passed {
+			suite.Pass(verdict.SLO.String(), 0)
+			continue
+		}
+		message := fmt.Sprintf("%s is %g, over %g", verdict.SLO.Metric, verdict.Value, verdict.SLO.Max)
+		suite.Fail(verdict.SLO.String(), 0, message, message)
+	}
+	if len(result.Verdicts) == 0 {
+		suite.Pass("run", 0)
+	}
+
+	suite.Time = result.Duration.Seconds()
+	return suite
+}
+
+// writeCSV writes one line per SLO, after the verdict of the whole run
+func writeCSV(w io.Writer, result *Result) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"metric", "value", "max", "passed"})
+	writer.Write([]string{"run", "", "", strconv.FormatBool(result.Passed)})
+	for _, verdict := range result.Verdicts {
+		writer.Write([]string{
+			verdict.SLO.Metric,
+			strconv.FormatFloat(verdict.Value, 'f', -1, 64),
+			strconv.FormatFloat(verdict.SLO.Max, 'f', -1, 64),
+			strconv.FormatBool(verdict.Passed),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeText writes a summary meant to be read in a terminal or a CI log
+func writeText(w io.Writer, result *Result) error {
+	duration := result.Duration.Round(time.Millisecond).String()
+	if result.Speed > 1 {
+		duration += fmt.Sprintf(" simulated at %gx", result.Speed)
+	}
+	fmt.Fprintf(w, "Load test (%s shape, %s): %s\n", result.Shape, duration, verdictText(result.Passed))
+	fmt.Fprintf(w, "Peak: %d clients, %d connects, %d reconnects, %d disconnects\n", result.Peak, result.Connects, result.Reconnects, result.Disconnects)
+	fmt.Fprintf(w, "Errors: %d failures (%.3f%%), %d drops (%d while steady)\n", result.Failures, result.ErrorRate, result.Drops, result.SteadyDrops)
+	fmt.Fprintf(w, "Login: p50 %v, p95 %v, p99 %v\n", result.LoginP50, result.LoginP95, result.LoginP99)
+	if memory := result.Memory; memory != nil {
+		writeMemory(w, memory)
+	}
+	if adaptive := result.Adaptive; adaptive != nil {
+		writeAdaptive(w, adaptive)
+	}
+
+	for _, verdict := range result.Verdicts {
+		if _, err := fmt.Fprintf(w, "  %s %s (got %g)\n", verdictText(verdict.Passed), verdict.SLO, verdict.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMemory writes the heap figures of a run, and how many clients its budget holds
+func writeMemory(w io.Writer, memory *MemoryReport) {
+	fmt.Fprintf(w, "Memory: peak heap %.1f MiB over a baseline of %.1f MiB, ~%.1f KiB per client at %d clients\n",
+		float64(memory.PeakHeap)/MEBIBYTE, float64(memory.Baseline)/MEBIBYTE, float64(memory.PerClient)/1024, memory.Clients)
+	if memory.Budget == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "Budget: %d MiB, holding ~%d clients", memory.Budget/MEBIBYTE, memory.Capacity)
+	if memory.WarnedAt > 0 {
+		fmt.Fprintf(w, ", neared at %d clients after %v", memory.WarnedAt, memory.WarnedAfter.Round(time.Millisecond))
+	}
+	if memory.Exceeded {
+		fmt.Fprint(w, ", exceeded")
+	}
+	fmt.Fprintln(w)
+}
+
+// writeAdaptive writes the capacity the adaptive shape found
+func writeAdaptive(w io.Writer, adaptive *AdaptiveReport) {
+	fmt.Fprintf(w, "Capacity: %d clients after %d windows and %d backoffs", adaptive.Capacity, len(adaptive.Windows), adaptive.Backoffs)
+	switch {
+	case adaptive.Capped:
+		fmt.Fprint(w, ", the servers keeping up with the client count of the test")
+	case adaptive.Converged:
+		fmt.Fprint(w, ", converged")
+	default:
+		fmt.Fprint(w, ", not converged yet")
+	}
+	if adaptive.Settled != adaptive.Capacity {
+		fmt.Fprintf(w, ", settled on %d clients", adaptive.Settled)
+	}
+	fmt.Fprintln(w)
+}
+
+func verdictText(passed bool) string {
+	if passed {
+		return "PASS"
+	}
+	return "FAIL"
+}
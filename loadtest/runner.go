@@ -0,0 +1,342 @@
+// Package loadtest drives a population of clients against the servers,
+// following the load shape of a LoadTestConfig
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/manager"
+)
+
+// DEFAULT_TICK is how often the runner moves the population towards the shape
+const DEFAULT_TICK = time.Second
+
+// Result sums up what happened during a load test run
+type Result struct {
+	Started     time.Time     `json:"started"`
+	Duration    time.Duration `json:"duration"` // Simulated when the run was sped up
+	Shape       string        `json:"shape"`
+	Speed       float64       `json:"speed,omitempty"` // How many times faster than real time the run went, when sped up
+	Peak        int           `json:"peak"`            // Highest number of clients in the world at once
+	Connects    int           `json:"connects"`        // Successful connections, reconnections included
+	Failures    int           `json:"failures"`        // Failed connections
+	Disconnects int           `json:"disconnects"`     // Clients logged out, as the shape went down or the run ended
+	Reconnects  int           `json:"reconnects"`      // Clients churned while soaking
+
+	LoginP50    time.Duration   `json:"loginP50"`
+	LoginP95    time.Duration   `json:"loginP95"`
+	LoginP99    time.Duration   `json:"loginP99"`
+	ErrorRate   float64         `json:"errorRate"`          // Failed connections, in percent of the attempts
+	Drops       int             `json:"drops"`              // Clients dropped by the servers
+	SteadyDrops int             `json:"steadyDrops"`        // Clients dropped by the servers while the population was steady
+	Errors      []ErrorCount    `json:"errors,omitempty"`   // Failed connections by type of error
+	Memory      *MemoryReport   `json:"memory,omitempty"`   // Heap of the process and footprint of a client
+	Adaptive    *AdaptiveReport `json:"adaptive,omitempty"` // Capacity of the servers the adaptive shape found
+	Verdicts    []Verdict       `json:"verdicts,omitempty"`
+	Passed      bool            `json:"passed"`
+}
+
+// ErrorCount counts the failed connections of a type of error
+type ErrorCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// Throughput returns the successful connections per second of the run
+func (r *Result) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Connects) / r.Duration.Seconds()
+}
+
+// recordError counts a failed connection
+func (r *Result) recordError(err error) {
+	r.Failures++
+	r.Errors = countError(r.Errors, err)
+}
+
+// countError counts an error under the innermost error of its chain, which is
+// the client sentinel error for the errors the client wraps
+func countError(counts []ErrorCount, err error) []ErrorCount {
+	for inner := errors.Unwrap(err); inner != nil; inner = errors.Unwrap(inner) {
+		err = inner
+	}
+
+	for i := range counts {
+		if counts[i].Type == err.Error() {
+			counts[i].Count++
+			return counts
+		}
+	}
+	return append(counts, ErrorCount{Type: err.Error(), Count: 1})
+}
+
+// Runner grows and shrinks a population of managed clients to follow a load shape.
+// When the load test has a speed, the shape and the scenario waits of the manager
+// run on a clock that many times faster than the real one, so that long runs can
+// be checked in seconds against stub servers. The logins still take real time.
+type Runner struct {
+	Manager   *manager.Manager
+	Client    client.ClientConfig
+	Test      client.LoadTestConfig
+	NewClient client.ClientFactory // Defaults to client.NewClient
+	Tick      time.Duration        // Defaults to DEFAULT_TICK
+	Accounts  int                  // Accounts the clients cycle through, the username followed by an index, 0 for the username alone
+	Heap      func() uint64        // Returns the heap in use in bytes, the one of the process by default
+
+	tick    time.Duration
+	clock   clock.Clock
+	live    []client.GameClient // In the world, oldest first
+	idle    []client.GameClient // Created but stopped, ready to be reused
+	created int
+	churn   float64 // Reconnections owed but not done yet
+	cursor  int     // Next live client to churn
+	logins  []time.Duration
+	memory  *memoryMeter
+	ramp    *rampController // Steers the adaptive shape
+	result  Result
+}
+
+// Run follows the shape for the duration of the load test, then logs every client out.
+// It returns early, still logging the clients out, when the context is done.
+func (r *Runner) Run(ctx context.Context) (*Result, error) {
+	if err := r.Test.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid load test configuration: %w", err)
+	}
+
+	r.tick = r.Tick
+	if r.tick <= 0 {
+		r.tick = DEFAULT_TICK
+	}
+
+	r.clock = clock.Real{}
+	if r.Test.Speed > 1 {
+		r.clock = clock.NewScaled(r.Test.Speed)
+		r.Manager.SetClock(r.clock)
+	}
+
+	shape := r.Test.Shape
+	r.result = Result{Started: r.clock.Now(), Shape: shape.Type}
+	if r.result.Shape == "" {
+		r.result.Shape = client.ShapeLinear
+	}
+	if r.Test.Speed > 1 {
+		r.result.Speed = r.Test.Speed
+	}
+	r.memory = newMemoryMeter(uint64(r.Test.MemoryBudgetMB)*MEBIBYTE, r.Heap)
+	r.ramp = nil
+	if shape.Type == client.ShapeAdaptive {
+		r.ramp = newRampController(shape, r.Test.DefaultClientCount)
+	}
+
+	previous := -1
+	for {
+		elapsed := r.clock.Now().Sub(r.result.Started)
+		if elapsed >= r.Test.DefaultDuration {
+			break
+		}
+
+		target := shape.Target(elapsed, r.Test.DefaultClientCount, r.Test.DefaultRampUpTime)
+		if r.ramp != nil {
+			target = r.ramp.adjust(elapsed, len(r.live), r.logins, r.result.Connects, r.result.Failures)
+		}
+		r.reap(target == previous)
+		previous = target
+
+		if err := r.scale(target); err != nil {
+			r.stop(len(r.live))
+			return nil, err
+		}
+
+		r.churn += shape.Churn(len(r.live), r.tick)
+		r.reconnect()
+		r.memory.sample(len(r.live), elapsed)
+
+		if err := clock.Sleep(ctx, r.clock, r.tick); err != nil {
+			r.stop(len(r.live))
+			r.finish()
+			return &r.result, err
+		}
+	}
+
+	r.stop(len(r.live))
+	r.finish()
+	return &r.result, nil
+}
+
+// finish computes the figures of the run and checks them against the SLOs
+func (r *Runner) finish() {
+	r.result.Duration = r.clock.Now().Sub(r.result.Started)
+
+	sort.Slice(r.logins, func(i, j int) bool { return r.logins[i] < r.logins[j] })
+	r.result.LoginP50 = percentile(r.logins, 50)
+	r.result.LoginP95 = percentile(r.logins, 95)
+	r.result.LoginP99 = percentile(r.logins, 99)
+
+	if attempts := r.result.Connects + r.result.Failures; attempts > 0 {
+		r.result.ErrorRate = float64(r.result.Failures) * 100 / float64(attempts)
+	}
+	r.result.Memory = r.memory.finish()
+	if r.ramp != nil {
+		r.result.Adaptive = r.ramp.finish()
+	}
+
+	r.result.Evaluate(r.Test.SLOs)
+}
+
+// reap takes the clients the servers dropped out of the live ones
+func (r *Runner) reap(steady bool) {
+	live := r.live[:0]
+	for _, gameClient := range r.live {
+		if gameClient.GetState() == client.StateInGame {
+			live = append(live, gameClient)
+			continue
+		}
+
+		gameClient.Disconnect()
+		r.idle = append(r.idle, gameClient)
+		r.result.Drops++
+		if steady {
+			r.result.SteadyDrops++
+		}
+	}
+	r.live = live
+}
+
+// connect connects a client, recording how long it took when it succeeds, and
+// records the connection in the summary of the manager
+func (r *Runner) connect(gameClient client.GameClient) (time.Duration, error) {
+	start := time.Now()
+	err := gameClient.Connect()
+	took := time.Since(start)
+	r.Manager.RecordConnect(gameClient, took, err)
+	if err != nil {
+		return 0, err
+	}
+	return took, nil
+}
+
+// scale starts or stops clients until target of them are live
+func (r *Runner) scale(target int) error {
+	if target < len(r.live) {
+		r.stop(len(r.live) - target)
+		return nil
+	}
+
+	var starting []client.GameClient
+	for len(r.live)+len(starting) < target {
+		if len(r.idle) > 0 {
+			starting = append(starting, r.idle[len(r.idle)-1])
+			r.idle = r.idle[:len(r.idle)-1]
+			continue
+		}
+
+		gameClient := r.newClient()
+		if err := r.Manager.AddClient(gameClient); err != nil {
+			r.idle = append(r.idle, starting...)
+			return fmt.Errorf("failed to add client %s: %w", gameClient.GetID(), err)
+		}
+		starting = append(starting, gameClient)
+	}
+
+	errs := make([]error, len(starting))
+	took := make([]time.Duration, len(starting))
+	var wg sync.WaitGroup
+	for i, gameClient := range starting {
+		wg.Add(1)
+		go func(i int, gc client.GameClient) {
+			defer wg.Done()
+			took[i], errs[i] = r.connect(gc)
+		}(i, gameClient)
+	}
+	wg.Wait()
+
+	for i, gameClient := range starting {
+		if errs[i] != nil {
+			gameClient.Disconnect()
+			r.idle = append(r.idle, gameClient)
+			r.result.recordError(errs[i])
+			continue
+		}
+		r.live = append(r.live, gameClient)
+		r.logins = append(r.logins, took[i])
+		r.result.Connects++
+	}
+
+	r.result.Peak = max(r.result.Peak, len(r.live))
+	return nil
+}
+
+// stop drains the count newest live clients, giving them a tick to log out
+func (r *Runner) stop(count int) {
+	if count <= 0 {
+		return
+	}
+
+	stopping := make(map[string]bool, count)
+	for _, gameClient := range r.live[len(r.live)-count:] {
+		stopping[gameClient.GetID()] = true
+	}
+
+	r.Manager.DrainClients(func(id string, gc client.GameClient) bool {
+		return stopping[id]
+	}, time.Now().Add(r.wallTime(r.tick)))
+
+	r.idle = append(r.idle, r.live[len(r.live)-count:]...)
+	r.live = r.live[:len(r.live)-count]
+	r.result.Disconnects += count
+}
+
+// reconnect disconnects and reconnects as many live clients as the churn allows, oldest first
+func (r *Runner) reconnect() {
+	for ; r.churn >= 1 && len(r.live) > 0; r.churn-- {
+		if r.cursor >= len(r.live) {
+			r.cursor = 0
+		}
+		gameClient := r.live[r.cursor]
+
+		gameClient.Disconnect()
+		took, err := r.connect(gameClient)
+		if err != nil {
+			gameClient.Disconnect()
+			r.live = append(r.live[:r.cursor], r.live[r.cursor+1:]...)
+			r.idle = append(r.idle, gameClient)
+			r.result.recordError(err)
+			continue
+		}
+
+		r.logins = append(r.logins, took)
+		r.result.Connects++
+		r.result.Reconnects++
+		r.cursor++
+	}
+}
+
+// wallTime returns how long a duration of the run lasts in real time
+func (r *Runner) wallTime(d time.Duration) time.Duration {
+	if scaled, ok := r.clock.(*clock.Scaled); ok {
+		return scaled.Real(d)
+	}
+	return d
+}
+
+// newClient creates the next client of the population
+func (r *Runner) newClient() client.GameClient {
+	r.created++
+	id := fmt.Sprintf("load-%d", r.created)
+
+	config := r.Client
+	config.Username = poolUsername(r.Client.Username, r.Accounts, int64(r.created-1))
+	if r.NewClient != nil {
+		return r.NewClient(id, config)
+	}
+	return client.NewClient(id, config)
+}
@@ -0,0 +1,220 @@
+package loadtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/manager"
+)
+
+func TestRunnerShapes(t *testing.T) {
+	tests := []struct {
+		name           string
+		shape          client.LoadShape
+		wantPeak       int
+		wantReconnects bool
+	}{
+		{name: "linear", shape: client.LoadShape{}, wantPeak: 6},
+		{name: "step", shape: client.LoadShape{Type: client.ShapeStep, StepClients: 2, StepInterval: 40 * time.Millisecond}, wantPeak: 6},
+		{name: "spike", shape: client.LoadShape{Type: client.ShapeSpike, BaseClients: 1, SpikeAt: 50 * time.Millisecond, SpikeHold: 50 * time.Millisecond}, wantPeak: 6},
+		{name: "soak", shape: client.LoadShape{Type: client.ShapeSoak, ChurnPercent: 60000}, wantPeak: 6, wantReconnects: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := manager.NewManager(&client.ManagerConfig{MaxClients: 10, HealthCheck: time.Second})
+			defer m.Shutdown()
+
+			runner := &Runner{
+				Manager: m,
+				Test: client.LoadTestConfig{
+					DefaultClientCount: 6,
+					DefaultDuration:    200 * time.Millisecond,
+					DefaultRampUpTime:  50 * time.Millisecond,
+					MaxConcurrentTests: 1,
+					ReportFormat:       "json",
+					Shape:              tt.shape,
+				},
+				NewClient: manager.NewGameClient,
+				Tick:      10 * time.Millisecond,
+			}
+
+			result, err := runner.Run(context.Background())
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if result.Peak != tt.wantPeak {
+				t.Errorf("Peak = %d, want %d", result.Peak, tt.wantPeak)
+			}
+			if (result.Reconnects > 0) != tt.wantReconnects {
+				t.Errorf("Reconnects = %d", result.Reconnects)
+			}
+			if result.Failures != 0 {
+				t.Errorf("Failures = %d", result.Failures)
+			}
+			if len(m.GetAllClients()) > 6 {
+				t.Errorf("%d clients were created for a peak of 6", len(m.GetAllClients()))
+			}
+			for id, gc := range m.GetAllClients() {
+				if state := gc.GetState(); state != client.StateDisconnected {
+					t.Errorf("%s state = %v after the run", id, state)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerCancel(t *testing.T) {
+	m := manager.NewManager(&client.ManagerConfig{MaxClients: 10, HealthCheck: time.Second})
+	defer m.Shutdown()
+
+	runner := &Runner{
+		Manager: m,
+		Test: client.LoadTestConfig{
+			DefaultClientCount: 3,
+			DefaultDuration:    time.Hour,
+			MaxConcurrentTests: 1,
+			ReportFormat:       "json",
+		},
+		NewClient: manager.NewGameClient,
+		Tick:      10 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	result, err := runner.Run(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Run() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if result.Peak != 3 || result.Disconnects != 3 {
+		t.Errorf("Run() = %+v", result)
+	}
+}
+
+func TestRunnerSLOs(t *testing.T) {
+	m := manager.NewManager(&client.ManagerConfig{MaxClients: 10, HealthCheck: time.Second})
+	defer m.Shutdown()
+
+	runner := &Runner{
+		Manager: m,
+		Test: client.LoadTestConfig{
+			DefaultClientCount: 4,
+			DefaultDuration:    150 * time.Millisecond,
+			MaxConcurrentTests: 1,
+			ReportFormat:       "json",
+			SLOs: []client.SLO{
+				{Metric: client.SLOLoginP99, Max: 500},
+				{Metric: client.SLOErrorRate, Max: 0.1},
+				{Metric: client.SLOSteadyDisconnects, Max: 0},
+			},
+		},
+		NewClient: manager.NewGameClient,
+		Tick:      10 * time.Millisecond,
+	}
+
+	// The server drops a client once the population is steady
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		if gc, err := m.GetClient("load-1"); err == nil {
+			gc.Disconnect()
+		}
+	}()
+
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Drops != 1 || result.SteadyDrops != 1 {
+		t.Errorf("Drops = %d, SteadyDrops = %d, want 1 and 1", result.Drops, result.SteadyDrops)
+	}
+	if result.Passed || result.ExitCode() != EXIT_FAILED {
+		t.Errorf("Passed = %v, ExitCode() = %d", result.Passed, result.ExitCode())
+	}
+
+	wantPassed := []bool{true, true, false}
+	for i, verdict := range result.Verdicts {
+		if verdict.Passed != wantPassed[i] {
+			t.Errorf("%s: Passed = %v with %v", verdict.SLO, verdict.Passed, verdict.Value)
+		}
+	}
+}
+
+func TestRunnerSpeed(t *testing.T) {
+	m := manager.NewManager(&client.ManagerConfig{MaxClients: 10, HealthCheck: time.Second})
+	defer m.Shutdown()
+
+	runner := &Runner{
+		Manager: m,
+		Test: client.LoadTestConfig{
+			DefaultClientCount: 6,
+			DefaultDuration:    time.Minute,
+			MaxConcurrentTests: 1,
+			ReportFormat:       "json",
+			Shape:              client.LoadShape{Type: client.ShapeStep, StepClients: 2, StepInterval: 10 * time.Second},
+			Speed:              200,
+		},
+		NewClient: manager.NewGameClient,
+	}
+
+	start := time.Now()
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("a minute at 200x took %v", took)
+	}
+	if result.Duration < time.Minute || result.Speed != 200 {
+		t.Errorf("Duration = %v, Speed = %g", result.Duration, result.Speed)
+	}
+	if result.Peak != 6 || result.Failures != 0 {
+		t.Errorf("Peak = %d, Failures = %d", result.Peak, result.Failures)
+	}
+}
+
+func TestRunnerMemory(t *testing.T) {
+	m := manager.NewManager(&client.ManagerConfig{MaxClients: 10, HealthCheck: time.Second})
+	defer m.Shutdown()
+
+	// Every client created weighs a MiB over a baseline of 10
+	runner := &Runner{
+		Manager: m,
+		Test: client.LoadTestConfig{
+			DefaultClientCount: 6,
+			DefaultDuration:    150 * time.Millisecond,
+			DefaultRampUpTime:  50 * time.Millisecond,
+			MaxConcurrentTests: 1,
+			ReportFormat:       "json",
+			MemoryBudgetMB:     15,
+			SLOs:               []client.SLO{{Metric: client.SLOPeakHeap, Max: 15}},
+		},
+		NewClient: manager.NewGameClient,
+		Tick:      10 * time.Millisecond,
+		Heap:      func() uint64 { return uint64(10+len(m.GetAllClients())) * MEBIBYTE },
+	}
+
+	result, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	memory := result.Memory
+	if memory == nil {
+		t.Fatal("no memory report")
+	}
+	if memory.PeakHeap != 16*MEBIBYTE || memory.Clients != 6 || memory.PerClient != MEBIBYTE {
+		t.Errorf("PeakHeap = %d, Clients = %d, PerClient = %d", memory.PeakHeap, memory.Clients, memory.PerClient)
+	}
+	if memory.Capacity != 5 || memory.WarnedAt == 0 || !memory.Exceeded {
+		t.Errorf("Capacity = %d, WarnedAt = %d, Exceeded = %v", memory.Capacity, memory.WarnedAt, memory.Exceeded)
+	}
+	if result.Passed || len(result.Verdicts) != 1 || result.Verdicts[0].Value != 16 {
+		t.Errorf("Passed = %v with %+v", result.Passed, result.Verdicts)
+	}
+}
@@ -0,0 +1,82 @@
+package loadtest
+
+import (
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+// Exit codes of a load test process
+const (
+	EXIT_PASSED = 0
+	EXIT_FAILED = 1 // An SLO was not met
+	EXIT_ERROR  = 2 // The run could not complete
+)
+
+// Verdict is the outcome of checking a run against an SLO
+type Verdict struct {
+	SLO    client.SLO `json:"slo"`
+	Value  float64    `json:"value"`
+	Passed bool       `json:"passed"`
+}
+
+// Evaluate checks the run against the SLOs, passing it when it meets every one of them
+func (r *Result) Evaluate(slos []client.SLO) {
+	r.Verdicts = nil
+	r.Passed = true
+
+	for _, slo := range slos {
+		value := r.Value(slo.Metric)
+		passed := value <= slo.Max
+		r.Verdicts = append(r.Verdicts, Verdict{SLO: slo, Value: value, Passed: passed})
+		r.Passed = r.Passed && passed
+	}
+}
+
+// Value returns the figure of the run an SLO metric bounds, in the unit of the metric
+func (r *Result) Value(metric string) float64 {
+	switch metric {
+	case client.SLOLoginP50:
+		return milliseconds(r.LoginP50)
+	case client.SLOLoginP95:
+		return milliseconds(r.LoginP95)
+	case client.SLOLoginP99:
+		return milliseconds(r.LoginP99)
+	case client.SLOErrorRate:
+		return r.ErrorRate
+	case client.SLODisconnects:
+		return float64(r.Drops)
+	case client.SLOSteadyDisconnects:
+		return float64(r.SteadyDrops)
+	case client.SLOPeakHeap:
+		if r.Memory != nil {
+			return float64(r.Memory.PeakHeap) / MEBIBYTE
+		}
+	case client.SLOClientFootprint:
+		if r.Memory != nil {
+			return float64(r.Memory.PerClient) / 1024
+		}
+	}
+	return 0
+}
+
+// ExitCode returns the exit code a process running the load test ends with
+func (r *Result) ExitCode() int {
+	if !r.Passed {
+		return EXIT_FAILED
+	}
+	return EXIT_PASSED
+}
+
+// percentile returns the nearest-rank percentile p of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
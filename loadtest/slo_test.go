@@ -0,0 +1,97 @@
+package loadtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 200)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	tests := []struct {
+		sorted []time.Duration
+		p      int
+		want   time.Duration
+	}{
+		{sorted: nil, p: 99, want: 0},
+		{sorted: sorted[:1], p: 50, want: time.Millisecond},
+		{sorted: sorted, p: 50, want: 100 * time.Millisecond},
+		{sorted: sorted, p: 99, want: 198 * time.Millisecond},
+		{sorted: sorted[:10], p: 99, want: 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(tt.sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%d durations, %d) = %v, want %v", len(tt.sorted), tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	result := &Result{Shape: client.ShapeLinear, Peak: 10, Connects: 10, LoginP99: 600 * time.Millisecond}
+	result.Evaluate([]client.SLO{{Metric: client.SLOLoginP99, Max: 500}, {Metric: client.SLOErrorRate, Max: 0.1}})
+
+	tests := []struct {
+		format string
+		check  func(t *testing.T, report []byte)
+	}{
+		{format: "json", check: func(t *testing.T, report []byte) {
+			var decoded Result
+			if err := json.Unmarshal(report, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Passed || len(decoded.Verdicts) != 2 || decoded.Verdicts[0].Value != 600 {
+				t.Errorf("unexpected report %+v", decoded)
+			}
+		}},
+		{format: "xml", check: func(t *testing.T, report []byte) {
+			var decoded xmlReport
+			if err := xml.Unmarshal(report, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Result == nil || decoded.Passed || len(decoded.Verdicts) != 2 {
+				t.Errorf("unexpected report %s", report)
+			}
+		}},
+		{format: "csv", check: func(t *testing.T, report []byte) {
+			if want := "run,,,false\nlogin_p99,600,500,false\nerror_rate,0,0.1,true\n"; !strings.HasSuffix(string(report), want) {
+				t.Errorf("report = %q, want it to end with %q", report, want)
+			}
+		}},
+		{format: "junit", check: func(t *testing.T, report []byte) {
+			for _, want := range []string{`<testsuites name="loadtest" tests="2" failures="1"`, `<testcase name="error_rate &lt;= 0.1" classname="loadtest.linear"`, `<failure message="login_p99 is 600, over 500" type="failure">`} {
+				if !strings.Contains(string(report), want) {
+					t.Errorf("report does not contain %q:\n%s", want, report)
+				}
+			}
+		}},
+		{format: "text", check: func(t *testing.T, report []byte) {
+			if !strings.Contains(string(report), ": FAIL\n") || !strings.Contains(string(report), "FAIL login_p99 <= 500 (got 600)") {
+				t.Errorf("report = %q", report)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := WriteReport(&buffer, result, tt.format); err != nil {
+				t.Fatalf("WriteReport() error = %v", err)
+			}
+			tt.check(t, buffer.Bytes())
+		})
+	}
+
+	if err := WriteReport(&bytes.Buffer{}, result, "yaml"); err == nil {
+		t.Error("WriteReport() error = nil for an unknown format")
+	}
+}
@@ -0,0 +1,288 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/protocol"
+)
+
+// DEFAULT_STORM_CONCURRENCY is how many clients log in at once during a login storm
+const DEFAULT_STORM_CONCURRENCY = 16
+
+// Bounds of a login storm, telling where the login server spends the time of the logins
+const (
+	BOUND_DATABASE = "database"
+	BOUND_CPU      = "cpu"
+	BOUND_NETWORK  = "network" // Neither the database nor the passwords take most of the time of the logins
+)
+
+// LoginStorm cycles clients through connect, authentication and disconnection as fast as the
+// login server lets them, never entering the game, to benchmark the login server itself
+type LoginStorm struct {
+	Client      client.ClientConfig
+	Concurrency int                  // Clients logging in at once, defaults to DEFAULT_STORM_CONCURRENCY
+	Duration    time.Duration        // How long the clients keep logging in
+	Accounts    int                  // Accounts cycled through, the username followed by an index, 0 for the username alone
+	NewClient   client.ClientFactory // Defaults to client.NewClient
+
+	// AdminURL is the admin API of the login server, its counters being read before and after
+	// the storm to break the time of the logins down. The breakdown is skipped when empty.
+	AdminURL string
+
+	next   atomic.Int64 // Index of the next account to log in
+	mu     sync.Mutex
+	logins []time.Duration
+	result StormResult
+}
+
+// StormResult sums up what happened during a login storm
+type StormResult struct {
+	Started         time.Time      `json:"started"`
+	Duration        time.Duration  `json:"duration"`
+	Concurrency     int            `json:"concurrency"`
+	Accounts        int            `json:"accounts"`
+	Logins          int            `json:"logins"`   // Successful logins
+	Failures        int            `json:"failures"` // Failed logins
+	LoginsPerSecond float64        `json:"loginsPerSecond"`
+	LoginP50        time.Duration  `json:"loginP50"`
+	LoginP95        time.Duration  `json:"loginP95"`
+	LoginP99        time.Duration  `json:"loginP99"`
+	Errors          []ErrorCount   `json:"errors,omitempty"` // Failed logins by type of error
+	Server          *Breakdown     `json:"server,omitempty"` // Where the login server spent the time, when its admin API was read
+	Crypto          *CryptoSummary `json:"crypto,omitempty"` // Encryption of the clients, when they expose it
+}
+
+// CryptoSummary sums the encryption metrics of the clients of a storm, and counts the login attempts by the
+// kind of the last Blowfish key they were given: a handshake failing before the key is installed counts as None
+type CryptoSummary struct {
+	Totals       protocol.CryptoStats `json:"totals"`
+	BlowfishKeys map[string]int       `json:"blowfishKeys"`
+}
+
+// ServerTimes are the counters of the login server the storm reads through its admin API
+type ServerTimes struct {
+	SuccessfulLogins          uint32        `json:"successfulLogins"`
+	SuccessfulAccountCreation uint32        `json:"successfulAccountCreation"`
+	FailedLogins              uint32        `json:"failedLogins"`
+	DatabaseTime              time.Duration `json:"databaseTime"`
+	CryptoTime                time.Duration `json:"cryptoTime"`
+}
+
+// Breakdown is the time the login server spent on each login, split between its database and
+// its CPU, and the share of the time the clients waited for their logins it stands for
+type Breakdown struct {
+	Database      time.Duration `json:"database"`      // Average per login
+	Crypto        time.Duration `json:"crypto"`        // Average per login
+	DatabaseShare float64       `json:"databaseShare"` // In percent of the time of the logins
+	CryptoShare   float64       `json:"cryptoShare"`   // In percent of the time of the logins
+	Bound         string        `json:"bound"`
+}
+
+// Run logs the clients in and out for the duration of the storm. It returns early, with
+// what was done so far, when the context is done.
+func (s *LoginStorm) Run(ctx context.Context) (*StormResult, error) {
+	if s.Duration <= 0 {
+		return nil, fmt.Errorf("invalid login storm: the duration must be positive")
+	}
+
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_STORM_CONCURRENCY
+	}
+
+	var before ServerTimes
+	if s.AdminURL != "" {
+		var err error
+		if before, err = FetchServerTimes(ctx, s.AdminURL); err != nil {
+			return nil, err
+		}
+	}
+
+	s.result = StormResult{Started: time.Now(), Concurrency: concurrency, Accounts: s.Accounts}
+	ctx, cancel := context.WithTimeout(ctx, s.Duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(gameClient client.GameClient) {
+			defer wg.Done()
+			s.cycle(ctx, gameClient)
+		}(s.newClient(i + 1))
+	}
+	wg.Wait()
+
+	s.result.Duration = time.Since(s.result.Started)
+	s.finish()
+
+	if s.AdminURL != "" {
+		after, err := FetchServerTimes(context.Background(), s.AdminURL)
+		if err != nil {
+			return &s.result, err
+		}
+		s.result.Server = s.breakdown(before, after)
+	}
+
+	if ctx.Err() == context.Canceled {
+		return &s.result, ctx.Err()
+	}
+	return &s.result, nil
+}
+
+// cycle logs a client in and out until the context is done
+func (s *LoginStorm) cycle(ctx context.Context, gameClient client.GameClient) {
+	for ctx.Err() == nil {
+		username := s.username(s.next.Add(1) - 1)
+
+		start := time.Now()
+		err := gameClient.Login(username, s.Client.Password)
+		took := time.Since(start)
+		encrypted, exposed := gameClient.(interface{ CryptoStats() protocol.CryptoStats })
+		var stats protocol.CryptoStats
+		if exposed {
+			stats = encrypted.CryptoStats()
+		}
+		gameClient.Disconnect()
+
+		s.mu.Lock()
+		if exposed {
+			if s.result.Crypto == nil {
+				s.result.Crypto = &CryptoSummary{BlowfishKeys: make(map[string]int)}
+			}
+			s.result.Crypto.Totals = s.result.Crypto.Totals.Add(stats)
+			s.result.Crypto.BlowfishKeys[stats.BlowfishKey.String()]++
+		}
+		if err != nil {
+			s.result.Failures++
+			s.result.Errors = countError(s.result.Errors, err)
+		} else {
+			s.logins = append(s.logins, took)
+			s.result.Logins++
+		}
+		s.mu.Unlock()
+	}
+}
+
+// username returns the account of the index-th login
+func (s *LoginStorm) username(index int64) string {
+	return poolUsername(s.Client.Username, s.Accounts, index)
+}
+
+// poolUsername returns the index-th account of a pool of accounts named after username,
+// or username alone when the pool is empty
+func poolUsername(username string, accounts int, index int64) string {
+	if accounts <= 0 {
+		return username
+	}
+	return fmt.Sprintf("%s%d", username, index%int64(accounts))
+}
+
+// finish computes the figures of the storm
+func (s *LoginStorm) finish() {
+	sort.Slice(s.logins, func(i, j int) bool { return s.logins[i] < s.logins[j] })
+	s.result.LoginP50 = percentile(s.logins, 50)
+	s.result.LoginP95 = percentile(s.logins, 95)
+	s.result.LoginP99 = percentile(s.logins, 99)
+
+	if s.result.Duration > 0 {
+		s.result.LoginsPerSecond = float64(s.result.Logins) / s.result.Duration.Seconds()
+	}
+}
+
+// breakdown splits the time the login server spent between two readings of its counters
+func (s *LoginStorm) breakdown(before, after ServerTimes) *Breakdown {
+	database := after.DatabaseTime - before.DatabaseTime
+	crypto := after.CryptoTime - before.CryptoTime
+
+	breakdown := &Breakdown{Bound: BOUND_NETWORK}
+	logins := int64(after.SuccessfulLogins-before.SuccessfulLogins) + int64(after.SuccessfulAccountCreation-before.SuccessfulAccountCreation) + int64(after.FailedLogins-before.FailedLogins)
+	if logins > 0 {
+		breakdown.Database = database / time.Duration(logins)
+		breakdown.Crypto = crypto / time.Duration(logins)
+	}
+
+	var waited time.Duration
+	for _, took := range s.logins {
+		waited += took
+	}
+	if waited > 0 {
+		breakdown.DatabaseShare = float64(database) * 100 / float64(waited)
+		breakdown.CryptoShare = float64(crypto) * 100 / float64(waited)
+	}
+
+	if breakdown.DatabaseShare+breakdown.CryptoShare >= 50 {
+		breakdown.Bound = BOUND_CPU
+		if database > crypto {
+			breakdown.Bound = BOUND_DATABASE
+		}
+	}
+	return breakdown
+}
+
+// newClient creates the index-th client of the storm
+func (s *LoginStorm) newClient(index int) client.GameClient {
+	id := fmt.Sprintf("storm-%d", index)
+	if s.NewClient != nil {
+		return s.NewClient(id, s.Client)
+	}
+	return client.NewClient(id, s.Client)
+}
+
+// FetchServerTimes reads the counters of a login server from the stats of its admin API.
+// Credentials in the url are sent as basic authentication.
+func FetchServerTimes(ctx context.Context, adminURL string) (ServerTimes, error) {
+	var times ServerTimes
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, adminURL+"/stats", nil)
+	if err != nil {
+		return times, err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return times, fmt.Errorf("couldn't read the login server stats: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return times, fmt.Errorf("couldn't read the login server stats: %s", response.Status)
+	}
+	if err := json.NewDecoder(response.Body).Decode(&times); err != nil {
+		return times, fmt.Errorf("couldn't read the login server stats: %w", err)
+	}
+	return times, nil
+}
+
+// WriteStormReport writes the result of a login storm in one of the report formats: json or text
+func WriteStormReport(w io.Writer, result *StormResult, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case "text":
+		fmt.Fprintf(w, "Login storm (%d clients, %s, %d accounts): %.1f logins/s\n", result.Concurrency, result.Duration.Round(time.Millisecond), result.Accounts, result.LoginsPerSecond)
+		fmt.Fprintf(w, "Logins: %d, %d failures\n", result.Logins, result.Failures)
+		fmt.Fprintf(w, "Login: p50 %v, p95 %v, p99 %v\n", result.LoginP50, result.LoginP95, result.LoginP99)
+		for _, count := range result.Errors {
+			fmt.Fprintf(w, "  %d x %s\n", count.Count, count.Type)
+		}
+		if crypto := result.Crypto; crypto != nil {
+			fmt.Fprintf(w, "Crypto: %d encryptions, %d decryptions, %d failures, Blowfish keys %v\n", crypto.Totals.Encryptions, crypto.Totals.Decryptions, crypto.Totals.Failures, crypto.BlowfishKeys)
+		}
+		if server := result.Server; server != nil {
+			_, err := fmt.Fprintf(w, "Server: %s bound, database %v (%.1f%%), crypto %v (%.1f%%) per login\n", server.Bound, server.Database, server.DatabaseShare, server.Crypto, server.CryptoShare)
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid report format: %s, must be one of: json, text", format)
+}
@@ -0,0 +1,139 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/testserver"
+)
+
+func TestLoginStorm(t *testing.T) {
+	tests := []struct {
+		name         string
+		accounts     int
+		reject       bool
+		times        [2]ServerTimes // Read before and after the storm
+		wantAccounts int
+		wantBound    string
+	}{
+		{name: "single account", accounts: 0, wantAccounts: 1},
+		{name: "keyed accounts", accounts: 3, wantAccounts: 3},
+		{name: "rejected", reject: true, wantAccounts: 1},
+		{
+			name:         "database bound",
+			accounts:     2,
+			times:        [2]ServerTimes{{}, {SuccessfulLogins: 1, DatabaseTime: time.Hour}},
+			wantAccounts: 2,
+			wantBound:    BOUND_DATABASE,
+		},
+		{
+			name:         "cpu bound",
+			accounts:     2,
+			times:        [2]ServerTimes{{CryptoTime: time.Hour}, {SuccessfulLogins: 1, CryptoTime: 3 * time.Hour}},
+			wantAccounts: 2,
+			wantBound:    BOUND_CPU,
+		},
+		{
+			name:         "network bound",
+			accounts:     2,
+			times:        [2]ServerTimes{{}, {SuccessfulLogins: 1}},
+			wantAccounts: 2,
+			wantBound:    BOUND_NETWORK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			usernames := make(map[string]bool)
+
+			loginServer := testserver.NewLoginServer()
+			loginServer.AddGameServer(1, &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7777})
+			accept := loginServer.AcceptLogin()
+			loginServer.On(int(opcodes.LoginClientRequestAuthLogin), func(data []byte) testserver.Response {
+				mu.Lock()
+				usernames[string(bytes.TrimRight(data[:14], "\x00"))] = true
+				mu.Unlock()
+
+				if tt.reject {
+					return testserver.RejectLogin(0x02)(data)
+				}
+				return accept(data)
+			})
+			if err := loginServer.Start(); err != nil {
+				t.Fatal(err)
+			}
+			defer loginServer.Close()
+
+			storm := &LoginStorm{
+				Client: client.ClientConfig{
+					LoginServerHost: "127.0.0.1",
+					LoginServerPort: loginServer.Addr().Port,
+					Username:        "storm",
+					Password:        "storm",
+					Timeout:         time.Second,
+				},
+				Concurrency: 2,
+				Duration:    100 * time.Millisecond,
+				Accounts:    tt.accounts,
+			}
+
+			if tt.wantBound != "" {
+				reads := 0
+				admin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/stats" {
+						http.NotFound(w, r)
+						return
+					}
+					json.NewEncoder(w).Encode(tt.times[min(reads, 1)])
+					reads++
+				}))
+				defer admin.Close()
+				storm.AdminURL = admin.URL
+			}
+
+			result, err := storm.Run(context.Background())
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if tt.reject {
+				if result.Logins != 0 || result.Failures == 0 || len(result.Errors) != 1 {
+					t.Errorf("Logins = %d, Failures = %d, Errors = %v", result.Logins, result.Failures, result.Errors)
+				}
+			} else if result.Logins == 0 || result.Failures != 0 || result.LoginsPerSecond <= 0 {
+				t.Errorf("Logins = %d, Failures = %d, LoginsPerSecond = %g, Errors = %v", result.Logins, result.Failures, result.LoginsPerSecond, result.Errors)
+			}
+
+			// Every attempt got past the Init packet, installing the static key
+			if crypto := result.Crypto; crypto == nil || crypto.BlowfishKeys["Static"] != result.Logins+result.Failures || crypto.Totals.Encryptions == 0 {
+				t.Errorf("Crypto = %+v after %d attempts", crypto, result.Logins+result.Failures)
+			}
+
+			mu.Lock()
+			if len(usernames) != tt.wantAccounts {
+				t.Errorf("logged in with %v, want %d accounts", usernames, tt.wantAccounts)
+			}
+			mu.Unlock()
+
+			if tt.wantBound == "" {
+				if result.Server != nil {
+					t.Errorf("Server = %+v without an admin API", result.Server)
+				}
+				return
+			}
+			if result.Server == nil || result.Server.Bound != tt.wantBound {
+				t.Fatalf("Server = %+v, want %s bound", result.Server, tt.wantBound)
+			}
+		})
+	}
+}
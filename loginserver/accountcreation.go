@@ -0,0 +1,174 @@
+package loginserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/config"
+)
+
+var (
+	ErrCreationThrottled  = errors.New("too many accounts created from this address")
+	ErrCreationCapReached = errors.New("daily account creation cap reached")
+	ErrChallengeFailed    = errors.New("account creation challenge failed")
+)
+
+// AccountChallenge is asked before AutoCreate creates an account, which is refused
+// when it returns an error. It can call out to a CAPTCHA service, check invitation
+// tokens or anything else gating the creations.
+type AccountChallenge func(ctx context.Context, username, address string) error
+
+// SetAccountChallenge replaces the challenge of the account creations, nil to create accounts unchallenged
+func (l *LoginServer) SetAccountChallenge(challenge AccountChallenge) {
+	l.creations.mu.Lock()
+	defer l.creations.mu.Unlock()
+	l.creations.challenge = challenge
+}
+
+// HTTPChallenge posts the username and the address of the client to the URL as JSON,
+// accepting the creation when the URL answers with a 2xx status
+func HTTPChallenge(url, token string, timeout time.Duration) AccountChallenge {
+	httpClient := &http.Client{Timeout: timeout}
+
+	return func(ctx context.Context, username, address string) error {
+		body, err := json.Marshal(map[string]string{"username": username, "address": address})
+		if err != nil {
+			return err
+		}
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		response, err := httpClient.Do(request)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrChallengeFailed, err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			return fmt.Errorf("%w: the challenge answered %s", ErrChallengeFailed, response.Status)
+		}
+		return nil
+	}
+}
+
+// accountCreations throttles the accounts created on the fly
+type accountCreations struct {
+	config    config.AccountCreationType
+	challenge AccountChallenge
+	byAddress map[string][]time.Time // Creations of the current window, per IP address
+	day       string
+	today     int
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+func newAccountCreations(cfg config.AccountCreationType) *accountCreations {
+	creations := &accountCreations{
+		config:    cfg,
+		byAddress: make(map[string][]time.Time),
+		now:       time.Now,
+	}
+	if cfg.ChallengeURL != "" {
+		creations.challenge = HTTPChallenge(cfg.ChallengeURL, cfg.ChallengeToken, cfg.ChallengeDeadline())
+	}
+	return creations
+}
+
+// reserve counts a creation from the address against the limits, the release
+// function giving it back when the account couldn't be created after all
+func (a *accountCreations) reserve(address string) (release func(), err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	since := now.Add(-a.config.CreationWindow())
+	if day := now.Format("2006-01-02"); day != a.day {
+		a.day = day
+		a.today = 0
+
+		// Once a day, forget the addresses which didn't create anything lately
+		for address, recent := range a.byAddress {
+			if !recent[len(recent)-1].After(since) {
+				delete(a.byAddress, address)
+			}
+		}
+	}
+	if a.config.PerDay > 0 && a.today >= a.config.PerDay {
+		return nil, ErrCreationCapReached
+	}
+
+	// Forget the creations which left the window
+	recent := a.byAddress[address]
+	for len(recent) > 0 && !recent[0].After(since) {
+		recent = recent[1:]
+	}
+	if a.config.PerAddress > 0 && len(recent) >= a.config.PerAddress {
+		a.byAddress[address] = recent
+		return nil, ErrCreationThrottled
+	}
+
+	a.byAddress[address] = append(recent, now)
+	a.today++
+	day := a.day
+
+	return func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+
+		if a.day == day {
+			a.today--
+		}
+		recent := a.byAddress[address]
+		for i := len(recent) - 1; i >= 0; i-- {
+			if recent[i].Equal(now) {
+				a.byAddress[address] = append(recent[:i], recent[i+1:]...)
+				break
+			}
+		}
+		if len(a.byAddress[address]) == 0 {
+			delete(a.byAddress, address)
+		}
+	}, nil
+}
+
+// admit checks the limits then the challenge, if any, before an account is created
+func (a *accountCreations) admit(username, address string) (release func(), err error) {
+	address = host(address)
+
+	release, err = a.reserve(address)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	challenge := a.challenge
+	a.mu.Unlock()
+
+	if challenge != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), a.config.ChallengeDeadline())
+		defer cancel()
+
+		if err := challenge(ctx, username, address); err != nil {
+			release()
+			if !errors.Is(err, ErrChallengeFailed) {
+				err = fmt.Errorf("%w: %v", ErrChallengeFailed, err)
+			}
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
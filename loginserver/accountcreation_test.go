@@ -0,0 +1,91 @@
+package loginserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/config"
+)
+
+func TestAccountCreationLimits(t *testing.T) {
+	creations := newAccountCreations(config.AccountCreationType{PerAddress: 2, Window: time.Hour, PerDay: 3})
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	creations.now = func() time.Time { return now }
+
+	steps := []struct {
+		address string
+		later   time.Duration // Elapsed since the previous step
+		release bool          // The account couldn't be created, its slot is given back
+		want    error
+	}{
+		{address: "10.0.0.1"},
+		{address: "10.0.0.1", release: true},
+		{address: "10.0.0.1"},
+		{address: "10.0.0.1", want: ErrCreationThrottled},
+		{address: "10.0.0.2"},
+		{address: "10.0.0.3", want: ErrCreationCapReached},
+		{address: "10.0.0.1", later: time.Hour, want: ErrCreationCapReached},
+		{address: "10.0.0.1", later: 14 * time.Hour},
+		{address: "10.0.0.1"},
+		{address: "10.0.0.1", want: ErrCreationThrottled},
+	}
+
+	for i, step := range steps {
+		now = now.Add(step.later)
+		release, err := creations.reserve(step.address)
+		if !errors.Is(err, step.want) {
+			t.Fatalf("step %d: reserve(%s) error = %v, want %v", i+1, step.address, err, step.want)
+		}
+		if err == nil && step.release {
+			release()
+		}
+	}
+}
+
+func TestAccountCreationChallenge(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		buffer := make([]byte, 128)
+		n, _ := r.Body.Read(buffer)
+		body = string(buffer[:n])
+		if r.URL.Query().Get("deny") != "" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	creations := newAccountCreations(config.AccountCreationType{PerDay: 1, ChallengeURL: server.URL, ChallengeToken: "secret"})
+
+	if _, err := creations.admit("newuser", "10.0.0.1:51234"); err != nil {
+		t.Fatalf("admit() error = %v", err)
+	}
+	if body != `{"address":"10.0.0.1","username":"newuser"}` {
+		t.Errorf("the challenge got %s", body)
+	}
+
+	// A refused challenge gives the slot back
+	creations = newAccountCreations(config.AccountCreationType{PerDay: 1, ChallengeURL: server.URL + "?deny=1", ChallengeToken: "secret"})
+	if _, err := creations.admit("newuser", "10.0.0.1:51234"); !errors.Is(err, ErrChallengeFailed) {
+		t.Fatalf("admit() error = %v, want ErrChallengeFailed", err)
+	}
+
+	creations.challenge = func(ctx context.Context, username, address string) error {
+		return errors.New("wrong invitation token")
+	}
+	if _, err := creations.admit("newuser", "10.0.0.1:51234"); !errors.Is(err, ErrChallengeFailed) {
+		t.Fatalf("admit() error = %v, want ErrChallengeFailed", err)
+	}
+
+	creations.challenge = nil
+	if _, err := creations.admit("newuser", "10.0.0.1:51234"); err != nil {
+		t.Fatalf("admit() error = %v once the challenge is gone", err)
+	}
+}
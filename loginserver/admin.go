@@ -0,0 +1,245 @@
+package loginserver
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/access"
+	"github.com/frostwind/l2go/packettiming"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// GameServerInfo is the state of a configured game server, as listed by the admin API
+type GameServerInfo struct {
+	Id            uint8     `json:"id"`
+	Name          string    `json:"name"`
+	Address       string    `json:"address"`
+	MaxPlayers    uint16    `json:"maxPlayers"`
+	OnlinePlayers uint16    `json:"onlinePlayers"`
+	Up            bool      `json:"up"`
+	Testing       bool      `json:"testing"`
+	LastSeen      time.Time `json:"lastSeen"`
+}
+
+// GameServers returns every configured game server along with its live state
+func (l *LoginServer) GameServers() []GameServerInfo {
+	l.gameserversMutex.Lock()
+	defer l.gameserversMutex.Unlock()
+
+	list := make([]GameServerInfo, 0, len(l.config.GameServers))
+	for index, item := range l.config.GameServers {
+		info := GameServerInfo{
+			Id:         item.ServerID(index),
+			Name:       item.Name,
+			Address:    net.JoinHostPort(item.ExternalIP, strconv.Itoa(item.Port)),
+			MaxPlayers: item.Options.MaxPlayers,
+			Testing:    item.Options.Testing,
+		}
+
+		if gameserver, ok := l.gameservers[info.Id]; ok {
+			info.Up = gameserver.IsUp()
+			info.OnlinePlayers = gameserver.OnlinePlayers()
+			info.LastSeen = gameserver.LastSeen()
+		}
+
+		list = append(list, info)
+	}
+
+	return list
+}
+
+// Stats is a snapshot of the login server counters, as listed by the admin API
+type Stats struct {
+	SuccessfulAccountCreation uint32            `json:"successfulAccountCreation"`
+	FailedAccountCreation     uint32            `json:"failedAccountCreation"`
+	RefusedAccountCreation    uint32            `json:"refusedAccountCreation"` // Creations throttled or failing the challenge
+	SuccessfulLogins          uint32            `json:"successfulLogins"`
+	FailedLogins              uint32            `json:"failedLogins"`
+	HackAttempts              uint32            `json:"hackAttempts"`
+	HackAttemptsByType        map[string]uint32 `json:"hackAttemptsByType,omitempty"` // By SECURITY_ type
+	ReapedPreAuth             uint32            `json:"reapedPreAuth"`                // Clients dropped for not logging in in time
+	ReapedPostAuth            uint32            `json:"reapedPostAuth"`               // Logged in clients dropped for staying idle
+	ExpiredSessions           uint32            `json:"expiredSessions"`              // Session ids used or swept past their lifetime
+
+	// Time the logins spent waiting for the accounts database, and hashing or checking the passwords,
+	// telling whether the login server is bound by its database or by its CPU
+	DatabaseTime time.Duration `json:"databaseTime"`
+	CryptoTime   time.Duration `json:"cryptoTime"`
+}
+
+// Stats returns the login server counters
+func (l *LoginServer) Stats() Stats {
+	return Stats{
+		SuccessfulAccountCreation: atomic.LoadUint32(&l.status.successfulAccountCreation),
+		FailedAccountCreation:     atomic.LoadUint32(&l.status.failedAccountCreation),
+		RefusedAccountCreation:    atomic.LoadUint32(&l.status.refusedAccountCreation),
+		SuccessfulLogins:          atomic.LoadUint32(&l.status.successfulLogins),
+		FailedLogins:              atomic.LoadUint32(&l.status.failedLogins),
+		HackAttempts:              atomic.LoadUint32(&l.status.hackAttempts),
+		HackAttemptsByType:        l.security.byType(),
+		ReapedPreAuth:             atomic.LoadUint32(&l.status.reapedPreAuth),
+		ReapedPostAuth:            atomic.LoadUint32(&l.status.reapedPostAuth),
+		ExpiredSessions:           atomic.LoadUint32(&l.status.expiredSessions),
+		DatabaseTime:              time.Duration(atomic.LoadInt64(&l.status.databaseTime)),
+		CryptoTime:                time.Duration(atomic.LoadInt64(&l.status.cryptoTime)),
+	}
+}
+
+// ModeInfo is the mode of the login server, as listed and changed by the admin API
+type ModeInfo struct {
+	Mode string `json:"mode"`
+}
+
+// HandlerStats returns how long the packets of the clients took to be handled, by opcode
+func (l *LoginServer) HandlerStats() packettiming.Stats {
+	return l.handlerTimes.Stats()
+}
+
+// AdminHandler serves the admin API
+func (l *LoginServer) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gameservers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(l.GameServers())
+	})
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(l.Stats())
+	})
+	mux.HandleFunc("/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(l.Snapshot())
+	})
+	mux.HandleFunc("/handlers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(l.HandlerStats())
+	})
+	mux.HandleFunc("/security", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := 0
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+				http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(l.SecurityEvents(r.URL.Query().Get("type"), limit))
+	})
+	mux.HandleFunc("/mode", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var request ModeInfo
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := l.SetMode(request.Mode); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodPut)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ModeInfo{Mode: l.Mode()})
+	})
+
+	var handler http.Handler = mux
+	if l.config.LoginServer.AdminAuth {
+		handler = l.authorize(handler)
+	} else {
+		handler = readOnly(handler)
+	}
+	if l.audit != nil {
+		handler = l.auditCalls(handler)
+	}
+	return handler
+}
+
+// readOnly refuses the other methods than GET, anyone reaching the admin API being able to use it
+// without the authentication
+func readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "the admin API is read only unless AdminAuth is enabled", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorize only lets through the accounts allowed to read the admin API, and to write it
+// for the other methods than GET
+func (l *LoginServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="l2go"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		account, err := l.accounts.FindByUsername(username)
+		if err == nil {
+			err = bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(password))
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="l2go"`)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		account.Tier = l.access.TierOf(account.AccessLevel)
+
+		capability := access.ADMIN_READ
+		if r.Method != http.MethodGet {
+			capability = access.ADMIN_WRITE
+		}
+		if !account.Can(capability) {
+			http.Error(w, "the account isn't allowed to "+capability, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,101 @@
+package loginserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/loginserver/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAdminAuth(t *testing.T) {
+	l := New(config.ConfigObject{LoginServer: config.LoginServerType{AdminAuth: true}})
+	accounts := repository.NewMemoryAccountRepository()
+	l.accounts = accounts
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	for username, level := range map[string]int8{"player": ACCESS_LEVEL_PLAYER, "gm": ACCESS_LEVEL_GM, "admin": ACCESS_LEVEL_ADMIN} {
+		if err := accounts.Create(&models.Account{Username: username, Password: string(hash), AccessLevel: level}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(l.AdminHandler())
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		username string
+		password string
+		want     int
+	}{
+		{name: "anonymous", method: http.MethodGet, path: "/stats", want: http.StatusUnauthorized},
+		{name: "wrong password", method: http.MethodGet, path: "/stats", username: "admin", password: "wrong", want: http.StatusUnauthorized},
+		{name: "unknown account", method: http.MethodGet, path: "/stats", username: "nobody", password: "secret", want: http.StatusUnauthorized},
+		{name: "player", method: http.MethodGet, path: "/stats", username: "player", password: "secret", want: http.StatusForbidden},
+		{name: "gm reads", method: http.MethodGet, path: "/mode", username: "gm", password: "secret", want: http.StatusOK},
+		{name: "gm writes", method: http.MethodPut, path: "/mode", username: "gm", password: "secret", want: http.StatusForbidden},
+		{name: "admin writes", method: http.MethodPut, path: "/mode", username: "admin", password: "secret", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, _ := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(`{"mode":"maintenance"}`))
+			if tt.username != "" {
+				request.SetBasicAuth(tt.username, tt.password)
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			if response.StatusCode != tt.want {
+				t.Errorf("%s %s answered %d, want %d", tt.method, tt.path, response.StatusCode, tt.want)
+			}
+		})
+	}
+
+	if mode := l.Mode(); mode != MODE_MAINTENANCE {
+		t.Errorf("Mode() = %s, want the mode set by the admin", mode)
+	}
+}
+
+func TestAdminReadOnly(t *testing.T) {
+	l := New(config.ConfigObject{})
+	server := httptest.NewServer(l.AdminHandler())
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		want   int
+	}{
+		{name: "read", method: http.MethodGet, want: http.StatusOK},
+		{name: "write", method: http.MethodPut, want: http.StatusForbidden},
+		{name: "post", method: http.MethodPost, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, _ := http.NewRequest(tt.method, server.URL+"/mode", strings.NewReader(`{"mode":"maintenance"}`))
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			if response.StatusCode != tt.want {
+				t.Errorf("%s /mode answered %d without AdminAuth, want %d", tt.method, response.StatusCode, tt.want)
+			}
+		})
+	}
+
+	if mode := l.Mode(); mode != MODE_NORMAL {
+		t.Errorf("Mode() = %s, want it unchanged without AdminAuth", mode)
+	}
+}
@@ -0,0 +1,83 @@
+package loginserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/frostwind/l2go/audit"
+)
+
+// startAudit opens the audit log, if enabled, and records the events of the login server in it
+func (l *LoginServer) startAudit() error {
+	if l.config.LoginServer.Audit.Path == "" {
+		return nil
+	}
+
+	logger, err := audit.Open(l.config.LoginServer.Audit)
+	if err != nil {
+		return err
+	}
+	l.audit = logger
+
+	l.OnAccountCreated(func(event AccountCreated) {
+		l.record(audit.Entry{Time: event.At, Action: audit.ACCOUNT_CREATED, Actor: event.Username, Target: event.Username, Address: host(event.Address)})
+	})
+	l.OnHackAttempt(func(event HackAttempt) {
+		l.record(audit.Entry{Time: event.At, Action: audit.HACK_ATTEMPT, Actor: event.Username, Address: host(event.Address), Details: event.Reason})
+	})
+	l.events.Subscribe(TOPIC_MODE_CHANGED, func(event interface{}) error {
+		changed := event.(ModeChanged)
+		l.record(audit.Entry{Time: changed.At, Action: audit.MODE_CHANGED, Target: changed.To, Details: "from " + changed.From})
+		return nil
+	})
+
+	return nil
+}
+
+// record appends an entry to the audit log, if enabled
+func (l *LoginServer) record(entry audit.Entry) {
+	if l.audit == nil {
+		return
+	}
+	if err := l.audit.Record(entry); err != nil {
+		fmt.Printf("Couldn't record %s in the audit log: %v\n", entry.Action, err)
+	}
+}
+
+// auditCalls records every admin API call along with its outcome
+func (l *LoginServer) auditCalls(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		username, _, _ := r.BasicAuth()
+		l.record(audit.Entry{
+			Action:  audit.ADMIN_CALL,
+			Actor:   username,
+			Target:  r.URL.Path,
+			Address: host(r.RemoteAddr),
+			Details: r.Method + " " + strconv.Itoa(recorder.status),
+		})
+	})
+}
+
+// statusRecorder remembers the status code a handler answered with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// host strips the port of an address
+func host(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
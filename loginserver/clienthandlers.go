@@ -0,0 +1,240 @@
+package loginserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/access"
+	"github.com/frostwind/l2go/loginserver/clientpackets"
+	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/loginserver/repository"
+	"github.com/frostwind/l2go/loginserver/serverpackets"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// handleRequestAuthLogin checks the credentials of a client, creating its account when the configuration
+// allows it
+func (l *LoginServer) handleRequestAuthLogin(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+	var buffer []byte
+
+	requestAuthLogin, err := clientpackets.NewRequestAuthLogin(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedPacket, err)
+	}
+
+	fmt.Printf("User %s is trying to login\n", requestAuthLogin.Username)
+
+	// The clients refused here never sent their GameGuard authentication, whatever their credentials
+	if l.gameGuardMode() == GAMEGUARD_STRICT && !client.GameGuard {
+		fmt.Printf("User %s didn't authenticate with GameGuard\n", requestAuthLogin.Username)
+		l.loginFailed(client, requestAuthLogin.Username, FAILURE_NO_GAMEGUARD)
+
+		return serverpackets.NewLoginFailPacket(serverpackets.REASON_ACCESS_FAILED), nil
+	}
+
+	// Query for existing account
+	queried := time.Now()
+	account, err := l.accounts.FindByUsername(requestAuthLogin.Username)
+	l.spent(&l.status.databaseTime, queried)
+
+	if err == repository.ErrAccountNotFound {
+		if l.config.LoginServer.AutoCreate == true {
+			policy := l.config.LoginServer.AccountPolicy
+			account = models.Account{Username: requestAuthLogin.Username, AccessLevel: policy.AccessLevel}
+			account.Tier = l.access.TierOf(account.AccessLevel)
+
+			if reason, closed := l.closedTo(account); closed {
+				fmt.Printf("No account is created for the user %s in the %s mode\n", requestAuthLogin.Username, l.Mode())
+				l.loginFailed(client, requestAuthLogin.Username, FAILURE_SERVER_CLOSED)
+
+				buffer = serverpackets.NewLoginFailPacket(reason)
+			} else if err := policy.Check(requestAuthLogin.Username, requestAuthLogin.Password); err != nil {
+				fmt.Printf("Refused to create an account for the user %s: %v\n", requestAuthLogin.Username, err)
+				atomic.AddUint32(&l.status.refusedAccountCreation, 1)
+				l.loginFailed(client, requestAuthLogin.Username, FAILURE_CREATION_REFUSED)
+
+				buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_USER_OR_PASS_WRONG)
+			} else if release, err := l.creations.admit(requestAuthLogin.Username, clientAddress(client)); err != nil {
+				fmt.Printf("Refused to create an account for the user %s: %v\n", requestAuthLogin.Username, err)
+				atomic.AddUint32(&l.status.refusedAccountCreation, 1)
+				l.loginFailed(client, requestAuthLogin.Username, FAILURE_CREATION_REFUSED)
+
+				buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_ACCESS_FAILED)
+			} else if hashedPassword, err := l.hashPassword(requestAuthLogin.Password); err != nil {
+				fmt.Println("An error occured while trying to generate the password")
+				release()
+				atomic.AddUint32(&l.status.failedAccountCreation, 1)
+				l.loginFailed(client, requestAuthLogin.Username, FAILURE_SYSTEM_ERROR)
+
+				buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_SYSTEM_ERROR)
+			} else {
+				// Insert new account
+				account.Password = string(hashedPassword)
+
+				created := time.Now()
+				err = l.accounts.Create(&account)
+				l.spent(&l.status.databaseTime, created)
+
+				if err != nil {
+					fmt.Printf("Couldn't create an account for the user %s: %v\n", requestAuthLogin.Username, err)
+					release()
+					atomic.AddUint32(&l.status.failedAccountCreation, 1)
+					l.loginFailed(client, requestAuthLogin.Username, FAILURE_SYSTEM_ERROR)
+
+					buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_SYSTEM_ERROR)
+				} else {
+					client.Account = account
+
+					fmt.Printf("Account successfully created for the user %s\n", requestAuthLogin.Username)
+					atomic.AddUint32(&l.status.successfulAccountCreation, 1)
+					l.events.Emit(AccountCreated{Username: account.Username, Address: clientAddress(client), At: time.Now()})
+
+					// The policy can create the accounts at an access level which isn't let in
+					if !account.Can(access.LOGIN) {
+						client.Banned = true
+						l.loginFailed(client, requestAuthLogin.Username, FAILURE_ACCESS_DENIED)
+
+						buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_ACCESS_FAILED)
+					} else {
+						l.events.Emit(LoginSucceeded{Username: account.Username, Address: clientAddress(client), At: time.Now()})
+						l.loggedIn(client, account.Username)
+
+						buffer = serverpackets.NewLoginOkPacket(client.SessionID)
+					}
+				}
+			}
+		} else {
+			fmt.Println("Account not found !")
+			l.loginFailed(client, requestAuthLogin.Username, FAILURE_UNKNOWN_ACCOUNT)
+
+			buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_USER_OR_PASS_WRONG)
+		}
+	} else if err != nil {
+		fmt.Printf("Database error: %v\n", err)
+		l.loginFailed(client, requestAuthLogin.Username, FAILURE_SYSTEM_ERROR)
+		buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_SYSTEM_ERROR)
+	} else {
+		// Account exists; Is the password ok?
+		account.Tier = l.access.TierOf(account.AccessLevel)
+		client.Account = account
+		checked := time.Now()
+		err = bcrypt.CompareHashAndPassword([]byte(client.Account.Password), []byte(requestAuthLogin.Password))
+		l.spent(&l.status.cryptoTime, checked)
+
+		if err != nil {
+			fmt.Printf("Wrong password for the account %s\n", requestAuthLogin.Username)
+			l.loginFailed(client, requestAuthLogin.Username, FAILURE_WRONG_PASSWORD)
+
+			buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_USER_OR_PASS_WRONG)
+		} else {
+
+			if !client.Account.Can(access.LOGIN) {
+				client.Banned = true
+				l.loginFailed(client, requestAuthLogin.Username, FAILURE_ACCESS_DENIED)
+
+				buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_ACCESS_FAILED)
+			} else if reason, closed := l.closedTo(client.Account); closed {
+				fmt.Printf("The account %s can't log in in the %s mode\n", requestAuthLogin.Username, l.Mode())
+				l.loginFailed(client, requestAuthLogin.Username, FAILURE_SERVER_CLOSED)
+
+				buffer = serverpackets.NewLoginFailPacket(reason)
+			} else {
+				l.events.Emit(LoginSucceeded{Username: client.Account.Username, Address: clientAddress(client), At: time.Now()})
+				l.loggedIn(client, client.Account.Username)
+
+				buffer = serverpackets.NewLoginOkPacket(client.SessionID)
+			}
+
+		}
+	}
+
+	return buffer, nil
+}
+
+// handleRequestPlay hands a client the key to play on the game server it chose
+func (l *LoginServer) handleRequestPlay(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+	requestPlay, err := clientpackets.NewRequestPlay(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedPacket, err)
+	}
+
+	fmt.Printf("The client wants to connect to the server : %d\n", requestPlay.ServerID)
+
+	if l.sessionExpired(client) {
+		l.refuseExpired(client)
+		return nil, fmt.Errorf("%w: expired session", ErrCloseConnection)
+	}
+
+	var buffer []byte
+	gameserver, _, ok := l.gameServerConfig(requestPlay.ServerID)
+	if ok && (gameserver.Options.Testing == false || client.Account.Can(access.TESTING_LOGIN)) {
+		if !bytes.Equal(client.SessionID[:8], requestPlay.SessionID) {
+			l.hackAttempt(client, SECURITY_SESSION_MISMATCH, "wrong session id in RequestPlay")
+
+			buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_ACCESS_FAILED)
+		} else {
+			playKey, err := l.mintPlayKey(client, requestPlay.ServerID, gameserver.Options.MaxPlayers)
+
+			if errors.Is(err, ErrGameServerFull) {
+				buffer = serverpackets.NewPlayFailPacket(serverpackets.REASON_TOO_MANY_PLAYERS)
+			} else if err != nil {
+				fmt.Printf("Couldn't send the client to the game server %s: %v\n", gameserver.Name, err)
+				buffer = serverpackets.NewPlayFailPacket(serverpackets.REASON_SYSTEM_ERROR)
+			} else {
+				buffer = serverpackets.NewPlayOkPacket(playKey)
+			}
+		}
+	} else if !ok {
+		l.hackAttempt(client, SECURITY_INVALID_SERVER, fmt.Sprintf("unknown game server %d", requestPlay.ServerID))
+
+		buffer = serverpackets.NewPlayFailPacket(serverpackets.REASON_ACCESS_FAILED)
+	} else {
+		l.hackAttempt(client, SECURITY_INVALID_SERVER, fmt.Sprintf("access to the testing game server %d refused", requestPlay.ServerID))
+
+		buffer = serverpackets.NewPlayFailPacket(serverpackets.REASON_ACCESS_FAILED)
+	}
+	return buffer, nil
+}
+
+// handleRequestServerList sends the game servers to a client, down in the maintenance mode. The pages
+// of a list too long for a packet are sent right away but the last one, answering the request.
+func (l *LoginServer) handleRequestServerList(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+	requestServerList, err := clientpackets.NewRequestServerList(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedPacket, err)
+	}
+
+	if l.sessionExpired(client) {
+		l.refuseExpired(client)
+		return nil, fmt.Errorf("%w: expired session", ErrCloseConnection)
+	}
+
+	var buffer []byte
+	if !bytes.Equal(client.SessionID[:8], requestServerList.SessionID) {
+		l.hackAttempt(client, SECURITY_SESSION_MISMATCH, "wrong session id in RequestServerList")
+
+		buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_ACCESS_FAILED)
+	} else {
+		statuses := l.gameServerStatuses()
+		if l.Mode() == MODE_MAINTENANCE {
+			for index := range statuses {
+				statuses[index].Up = false
+			}
+		}
+		pages := serverpackets.NewServerListPackets(l.config.GameServers, statuses, client.Socket.RemoteAddr().String(), l.config.LoginServer.ListPacketSize)
+		for _, page := range pages[:len(pages)-1] {
+			if err := client.Send(page); err != nil {
+				return nil, err
+			}
+		}
+		buffer = pages[len(pages)-1]
+	}
+	return buffer, nil
+}
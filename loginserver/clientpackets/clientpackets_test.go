@@ -0,0 +1,113 @@
+package clientpackets
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewRequestAuthLogin(t *testing.T) {
+	request := make([]byte, 28)
+	copy(request, "username")
+	copy(request[14:], "password")
+
+	result, err := NewRequestAuthLogin(request)
+	if err != nil {
+		t.Fatalf("NewRequestAuthLogin() unexpected error: %v", err)
+	}
+	if result.Username != "username" {
+		t.Errorf("Username = %q, want %q", result.Username, "username")
+	}
+	if result.Password != "password" {
+		t.Errorf("Password = %q, want %q", result.Password, "password")
+	}
+}
+
+func TestTruncatedPackets(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func([]byte) error
+		size  int
+	}{
+		{
+			name:  "RequestAuthLogin",
+			parse: func(b []byte) error { _, err := NewRequestAuthLogin(b); return err },
+			size:  requestAuthLoginSize,
+		},
+		{
+			name:  "RequestPlay",
+			parse: func(b []byte) error { _, err := NewRequestPlay(b); return err },
+			size:  requestPlaySize,
+		},
+		{
+			name:  "RequestServerList",
+			parse: func(b []byte) error { _, err := NewRequestServerList(b); return err },
+			size:  requestServerListSize,
+		},
+		{
+			name:  "RequestGGAuth",
+			parse: func(b []byte) error { _, err := NewRequestGGAuth(b); return err },
+			size:  requestGGAuthSize,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for n := 0; n < tt.size; n++ {
+				err := tt.parse(make([]byte, n))
+				if !errors.Is(err, ErrPacketTooShort) {
+					t.Errorf("length %d: error = %v, want ErrPacketTooShort", n, err)
+				}
+			}
+
+			if err := tt.parse(make([]byte, tt.size)); err != nil {
+				t.Errorf("length %d: unexpected error: %v", tt.size, err)
+			}
+		})
+	}
+}
+
+func FuzzNewRequestAuthLogin(f *testing.F) {
+	f.Add([]byte{})
+	f.Add(make([]byte, requestAuthLoginSize))
+	f.Add([]byte("username\x00\x00\x00\x00\x00\x00password\x00\x00\x00\x00\x00\x00"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		result, err := NewRequestAuthLogin(data)
+		if err != nil {
+			return
+		}
+		if len(result.Username) > requestAuthLoginFieldSize || len(result.Password) > requestAuthLoginFieldSize {
+			t.Errorf("fields over-read the request: %q / %q", result.Username, result.Password)
+		}
+	})
+}
+
+func FuzzNewRequestPlay(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{1, 2, 3, 4, 5, 6, 7, 8, 1})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		result, err := NewRequestPlay(data)
+		if err != nil {
+			return
+		}
+		if len(result.SessionID) != 8 {
+			t.Errorf("SessionID length = %d, want 8", len(result.SessionID))
+		}
+	})
+}
+
+func FuzzNewRequestServerList(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		result, err := NewRequestServerList(data)
+		if err != nil {
+			return
+		}
+		if len(result.SessionID) != 8 {
+			t.Errorf("SessionID length = %d, want 8", len(result.SessionID))
+		}
+	})
+}
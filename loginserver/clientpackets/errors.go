@@ -0,0 +1,32 @@
+package clientpackets
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPacketTooShort is wrapped by every ParseError caused by a truncated packet
+var ErrPacketTooShort = errors.New("packet too short")
+
+// ParseError describes a client packet that couldn't be decoded
+type ParseError struct {
+	Packet string
+	Want   int
+	Got    int
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("malformed %s packet: expected at least %d bytes, got %d", e.Packet, e.Want, e.Got)
+}
+
+func (e *ParseError) Unwrap() error {
+	return ErrPacketTooShort
+}
+
+// checkLength makes sure the request holds at least size bytes
+func checkLength(packet string, request []byte, size int) error {
+	if len(request) < size {
+		return &ParseError{Packet: packet, Want: size, Got: len(request)}
+	}
+	return nil
+}
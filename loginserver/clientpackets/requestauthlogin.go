@@ -1,15 +1,36 @@
 package clientpackets
 
+import (
+	"bytes"
+)
+
+const (
+	requestAuthLoginFieldSize = 14
+	requestAuthLoginSize      = requestAuthLoginFieldSize * 2
+)
+
 type RequestAuthLogin struct {
 	Username string
 	Password string
 }
 
-func NewRequestAuthLogin(request []byte) RequestAuthLogin {
+func NewRequestAuthLogin(request []byte) (RequestAuthLogin, error) {
 	var result RequestAuthLogin
 
-	result.Username = string(request[:14])
-	result.Password = string(request[14:28])
+	if err := checkLength("RequestAuthLogin", request, requestAuthLoginSize); err != nil {
+		return result, err
+	}
+
+	result.Username = readFixedString(request[:requestAuthLoginFieldSize])
+	result.Password = readFixedString(request[requestAuthLoginFieldSize:requestAuthLoginSize])
+
+	return result, nil
+}
 
-	return result
+// readFixedString reads a null padded field
+func readFixedString(field []byte) string {
+	if i := bytes.IndexByte(field, 0x00); i >= 0 {
+		field = field[:i]
+	}
+	return string(field)
 }
@@ -0,0 +1,31 @@
+package clientpackets
+
+import (
+	"github.com/frostwind/l2go/packets"
+)
+
+const requestGGAuthSize = 20
+
+// RequestGGAuth is sent by the retail clients between Init and RequestAuthLogin, once GameGuard
+// vouched for them
+type RequestGGAuth struct {
+	SessionID uint32    // Sent in Init
+	Data      [4]uint32 // GameGuard data, opaque to the server
+}
+
+func NewRequestGGAuth(request []byte) (RequestGGAuth, error) {
+	var result RequestGGAuth
+
+	if err := checkLength("RequestGGAuth", request, requestGGAuthSize); err != nil {
+		return result, err
+	}
+
+	var packet = packets.NewReader(request)
+
+	result.SessionID = packet.ReadUInt32()
+	for i := range result.Data {
+		result.Data[i] = packet.ReadUInt32()
+	}
+
+	return result, nil
+}
@@ -4,17 +4,24 @@ import (
 	"github.com/frostwind/l2go/packets"
 )
 
+const requestPlaySize = 9
+
 type RequestPlay struct {
 	ServerID  uint8
 	SessionID []byte
 }
 
-func NewRequestPlay(request []byte) RequestPlay {
-	var packet = packets.NewReader(request)
+func NewRequestPlay(request []byte) (RequestPlay, error) {
 	var result RequestPlay
 
+	if err := checkLength("RequestPlay", request, requestPlaySize); err != nil {
+		return result, err
+	}
+
+	var packet = packets.NewReader(request)
+
 	result.SessionID = packet.ReadBytes(8)
 	result.ServerID = packet.ReadUInt8()
 
-	return result
+	return result, nil
 }
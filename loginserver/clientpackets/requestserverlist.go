@@ -4,15 +4,22 @@ import (
 	"github.com/frostwind/l2go/packets"
 )
 
+const requestServerListSize = 8
+
 type RequestServerList struct {
 	SessionID []byte
 }
 
-func NewRequestServerList(request []byte) RequestServerList {
-	var packet = packets.NewReader(request)
+func NewRequestServerList(request []byte) (RequestServerList, error) {
 	var result RequestServerList
 
+	if err := checkLength("RequestServerList", request, requestServerListSize); err != nil {
+		return result, err
+	}
+
+	var packet = packets.NewReader(request)
+
 	result.SessionID = packet.ReadBytes(8)
 
-	return result
+	return result, nil
 }
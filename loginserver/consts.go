@@ -1,7 +1,10 @@
 package loginserver
 
+// Access levels of the default tiers
 const (
 	ACCESS_LEVEL_BANNED = -1
 	ACCESS_LEVEL_PLAYER = 0
-	ACCESS_LEVEL_ADMIN  = 1
+	ACCESS_LEVEL_TESTER = 1
+	ACCESS_LEVEL_GM     = 2
+	ACCESS_LEVEL_ADMIN  = 3
 )
@@ -84,6 +84,16 @@ func (c *Cipher) Decrypt(dst, src []byte) {
 	dst[7], dst[6], dst[5], dst[4] = byte(r>>24), byte(r>>16), byte(r>>8), byte(r)
 }
 
+// Reset zeroes the key schedule so the key can't be recovered from memory.
+// The cipher must not be used afterwards.
+func (c *Cipher) Reset() {
+	clear(c.p[:])
+	clear(c.s0[:])
+	clear(c.s1[:])
+	clear(c.s2[:])
+	clear(c.s3[:])
+}
+
 func initCipher(c *Cipher) {
 	copy(c.p[0:], p[0:])
 	copy(c.s0[0:], s0[0:])
@@ -1,10 +1,35 @@
 package crypt
 
 import (
-  "errors"
+	"crypto/rand"
+	"errors"
+	"io"
+
 	"github.com/frostwind/l2go/loginserver/crypt/blowfish"
 )
 
+// StaticBlowfishKey is the key used to encrypt the login server traffic
+var StaticBlowfishKey = []byte("[;'.]94-31==-%&@!^+]\000")
+
+// NewSessionID returns size bytes read from the system CSPRNG
+func NewSessionID(size int) ([]byte, error) {
+	return GenerateSessionID(rand.Reader, size)
+}
+
+// GenerateSessionID returns size bytes read from random, failing on short reads
+func GenerateSessionID(random io.Reader, size int) ([]byte, error) {
+	id := make([]byte, size)
+	if _, err := io.ReadFull(random, id); err != nil {
+		return nil, errors.New("Couldn't generate the session id: " + err.Error())
+	}
+	return id, nil
+}
+
+// Wipe zeroes sensitive key material in place
+func Wipe(data []byte) {
+	clear(data)
+}
+
 func Checksum(raw []byte) bool {
 	var chksum int = 0
 	count := len(raw) - 8
@@ -0,0 +1,49 @@
+package crypt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenerateSessionID(t *testing.T) {
+	source := bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+
+	id, err := GenerateSessionID(source, 8)
+	if err != nil {
+		t.Fatalf("GenerateSessionID() error = %v", err)
+	}
+	if !bytes.Equal(id, []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("GenerateSessionID() = %X, want the bytes of the source", id)
+	}
+
+	if _, err := GenerateSessionID(bytes.NewReader([]byte{1, 2}), 8); err == nil {
+		t.Error("GenerateSessionID() should fail on a short source")
+	}
+}
+
+func TestNewSessionID(t *testing.T) {
+	first, err := NewSessionID(16)
+	if err != nil {
+		t.Fatalf("NewSessionID() error = %v", err)
+	}
+	second, err := NewSessionID(16)
+	if err != nil {
+		t.Fatalf("NewSessionID() error = %v", err)
+	}
+
+	if len(first) != 16 {
+		t.Errorf("NewSessionID() returned %d bytes, want 16", len(first))
+	}
+	if bytes.Equal(first, second) {
+		t.Error("NewSessionID() returned the same id twice")
+	}
+}
+
+func TestWipe(t *testing.T) {
+	key := []byte("secret key")
+	Wipe(key)
+
+	if !bytes.Equal(key, make([]byte, len(key))) {
+		t.Errorf("Wipe() left %X", key)
+	}
+}
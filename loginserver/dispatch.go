@@ -0,0 +1,157 @@
+package loginserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packettiming"
+)
+
+var (
+	ErrUnknownOpcode   = errors.New("unknown opcode")
+	ErrHandlerExists   = errors.New("handler already registered")
+	ErrMalformedPacket = errors.New("malformed packet")
+	ErrCloseConnection = errors.New("connection closed")
+)
+
+// Handler handles a packet of a client, returning the packet to answer it with, nil for none.
+// Any error closes the connection once the answer is sent, but ErrUnknownOpcode which is only logged.
+// ctx is done once the connection ends or the login server stops.
+type Handler func(ctx context.Context, client *models.Client, data []byte) ([]byte, error)
+
+// Middleware wraps the handler of an opcode, to run code around the handling of its packets.
+// The packets of the opcodes without a handler go through the middlewares too.
+type Middleware func(opcode byte, next Handler) Handler
+
+// Dispatcher routes the packets of the clients to the handlers registered for their opcode,
+// through the middlewares, the first one used running first
+type Dispatcher struct {
+	handlers    map[byte]Handler
+	middlewares []Middleware
+	mu          sync.RWMutex
+}
+
+// NewDispatcher creates a dispatcher without any handler nor middleware
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[byte]Handler)}
+}
+
+// Handle registers the handler of an opcode, which can only have one
+func (d *Dispatcher) Handle(opcode byte, handler Handler) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.handlers[opcode]; ok {
+		return fmt.Errorf("%w: %s", ErrHandlerExists, opcodes.Name(opcodes.Login, opcodes.ClientToServer, opcode))
+	}
+	d.handlers[opcode] = handler
+	return nil
+}
+
+// Use adds middlewares, run after the ones already used
+func (d *Dispatcher) Use(middlewares ...Middleware) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.middlewares = append(d.middlewares, middlewares...)
+}
+
+// Dispatch hands a packet to the handler of its opcode, through the middlewares
+func (d *Dispatcher) Dispatch(ctx context.Context, opcode byte, client *models.Client, data []byte) ([]byte, error) {
+	d.mu.RLock()
+	handler, ok := d.handlers[opcode]
+	middlewares := d.middlewares
+	d.mu.RUnlock()
+
+	if !ok {
+		handler = unknownOpcode(opcode)
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](opcode, handler)
+	}
+	return handler(ctx, client, data)
+}
+
+func unknownOpcode(opcode byte) Handler {
+	return func(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownOpcode, opcodes.Name(opcodes.Login, opcodes.ClientToServer, opcode))
+	}
+}
+
+// newDispatcher registers the handlers of the packets of the clients, behind the middlewares
+// refusing the banned accounts and the floods, timing the handlers and reporting the malformed packets
+func (l *LoginServer) newDispatcher() *Dispatcher {
+	d := NewDispatcher()
+	d.Use(l.refuseBanned, l.limitRate, timed(l.handlerTimes), l.reportMalformed)
+	d.Handle(opcodes.LoginClientRequestAuthLogin, l.handleRequestAuthLogin)
+	d.Handle(opcodes.LoginClientRequestPlay, l.handleRequestPlay)
+	d.Handle(opcodes.LoginClientRequestServerList, l.handleRequestServerList)
+	d.Handle(opcodes.LoginClientRequestGGAuth, l.handleRequestGGAuth)
+	return d
+}
+
+// Dispatcher returns the dispatcher of the packets of the clients, so that handlers and middlewares
+// can be added before the login server starts
+func (l *LoginServer) Dispatcher() *Dispatcher {
+	return l.dispatcher
+}
+
+// refuseBanned closes the connection of an account refused for good, whatever it sends next
+// coming from a tampered client
+func (l *LoginServer) refuseBanned(opcode byte, next Handler) Handler {
+	return func(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+		if client.Banned {
+			l.hackAttempt(client, SECURITY_PACKET_AFTER_BAN, fmt.Sprintf("packet 0x%02X sent by a banned account", opcode))
+			return nil, fmt.Errorf("%w: banned account", ErrCloseConnection)
+		}
+		return next(ctx, client, data)
+	}
+}
+
+// limitRate closes the connection of a client sending more packets a second than the configuration allows
+func (l *LoginServer) limitRate(opcode byte, next Handler) Handler {
+	return func(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+		limit := l.config.LoginServer.MaxPacketRate
+		if limit <= 0 {
+			return next(ctx, client, data)
+		}
+
+		now := l.clock.Now()
+		if now.Sub(client.RateWindow) >= time.Second {
+			client.RateWindow, client.RatePackets = now, 0
+		}
+		client.RatePackets++
+		if client.RatePackets > limit {
+			l.hackAttempt(client, SECURITY_PACKET_FLOOD, fmt.Sprintf("more than %d packets in a second", limit))
+			return nil, fmt.Errorf("%w: packet flood", ErrCloseConnection)
+		}
+		return next(ctx, client, data)
+	}
+}
+
+// timed records how long the handlers take
+func timed(recorder *packettiming.Recorder) Middleware {
+	return func(opcode byte, next Handler) Handler {
+		return func(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+			timer := recorder.Start(opcode)
+			defer timer.Stop()
+			return next(ctx, client, data)
+		}
+	}
+}
+
+// reportMalformed reports the packets their handler couldn't decode as hack attempts
+func (l *LoginServer) reportMalformed(opcode byte, next Handler) Handler {
+	return func(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+		answer, err := next(ctx, client, data)
+		if errors.Is(err, ErrMalformedPacket) {
+			l.hackAttempt(client, SECURITY_MALFORMED_PACKET, "malformed "+opcodes.Name(opcodes.Login, opcodes.ClientToServer, opcode))
+		}
+		return answer, err
+	}
+}
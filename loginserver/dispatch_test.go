@@ -0,0 +1,103 @@
+package loginserver
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/opcodes"
+)
+
+func TestDispatcher(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(opcode byte, next Handler) Handler {
+			return func(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+				calls = append(calls, name)
+				return next(ctx, client, data)
+			}
+		}
+	}
+
+	d := NewDispatcher()
+	d.Use(trace("outer"), trace("inner"))
+	err := d.Handle(0x07, func(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+		calls = append(calls, "handler")
+		return append([]byte{0x0b}, data...), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Handle(0x07, nil); !errors.Is(err, ErrHandlerExists) {
+		t.Errorf("Handle(0x07) again = %v, want ErrHandlerExists", err)
+	}
+
+	tests := []struct {
+		name   string
+		opcode byte
+		answer []byte
+		err    error
+		calls  []string
+	}{
+		{name: "registered", opcode: 0x07, answer: []byte{0x0b, 0x01}, calls: []string{"outer", "inner", "handler"}},
+		{name: "unknown", opcode: 0x42, err: ErrUnknownOpcode, calls: []string{"outer", "inner"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			answer, err := d.Dispatch(context.Background(), tt.opcode, &models.Client{}, []byte{0x01})
+			if !slices.Equal(answer, tt.answer) || !errors.Is(err, tt.err) {
+				t.Errorf("Dispatch(0x%02X) = %X, %v, want %X, %v", tt.opcode, answer, err, tt.answer, tt.err)
+			}
+			if !slices.Equal(calls, tt.calls) {
+				t.Errorf("calls = %v, want %v", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestLoginServerMiddlewares(t *testing.T) {
+	l := New(config.ConfigObject{LoginServer: config.LoginServerType{MaxPacketRate: 2}})
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	l.SetClock(fake)
+
+	tests := []struct {
+		name      string
+		client    *models.Client
+		advance   time.Duration
+		opcode    byte
+		data      []byte
+		err       error
+		eventType string // Of the hack attempt reported, none when empty
+	}{
+		{name: "banned", client: &models.Client{Banned: true}, opcode: opcodes.LoginClientRequestServerList, err: ErrCloseConnection, eventType: SECURITY_PACKET_AFTER_BAN},
+		{name: "malformed", client: &models.Client{}, opcode: opcodes.LoginClientRequestServerList, err: ErrMalformedPacket, eventType: SECURITY_MALFORMED_PACKET},
+		{name: "under the rate", client: &models.Client{RateWindow: fake.Now(), RatePackets: 1}, opcode: 0x42, err: ErrUnknownOpcode},
+		{name: "flood", client: &models.Client{RateWindow: fake.Now(), RatePackets: 2}, opcode: 0x42, err: ErrCloseConnection, eventType: SECURITY_PACKET_FLOOD},
+		{name: "next second", client: &models.Client{RateWindow: fake.Now(), RatePackets: 2}, advance: time.Second, opcode: 0x42, err: ErrUnknownOpcode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.Advance(tt.advance)
+			before := len(l.SecurityEvents("", 0).Events)
+
+			_, err := l.Dispatcher().Dispatch(context.Background(), tt.opcode, tt.client, tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Dispatch(0x%02X) = %v, want %v", tt.opcode, err, tt.err)
+			}
+
+			events := l.SecurityEvents("", 0).Events
+			switch {
+			case tt.eventType == "" && len(events) != before:
+				t.Errorf("a hack attempt was reported: %+v", events[0])
+			case tt.eventType != "" && (len(events) != before+1 || events[0].Type != tt.eventType):
+				t.Errorf("events = %+v, want a %s one", events, tt.eventType)
+			}
+		})
+	}
+}
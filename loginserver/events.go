@@ -0,0 +1,147 @@
+package loginserver
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/eventbus"
+	"github.com/frostwind/l2go/loginserver/models"
+)
+
+// Topics of the events published by the login server
+const (
+	TOPIC_ACCOUNT_CREATED = "login.account.created"
+	TOPIC_LOGIN_SUCCESS   = "login.success"
+	TOPIC_LOGIN_FAILURE   = "login.failure"
+	TOPIC_HACK_ATTEMPT    = "login.hack"
+	TOPIC_CLIENT_KICKED   = "login.kicked"
+)
+
+// Reasons of the failed logins
+const (
+	FAILURE_UNKNOWN_ACCOUNT  = "unknown account"
+	FAILURE_WRONG_PASSWORD   = "wrong password"
+	FAILURE_ACCESS_DENIED    = "access denied"
+	FAILURE_SYSTEM_ERROR     = "system error"
+	FAILURE_CREATION_REFUSED = "account creation refused"
+	FAILURE_SERVER_CLOSED    = "server closed to the players"
+	FAILURE_NO_GAMEGUARD     = "no GameGuard authentication"
+)
+
+// AccountCreated is published when a login creates the account of an unknown user
+type AccountCreated struct {
+	Username string    `json:"username"`
+	Address  string    `json:"address"`
+	At       time.Time `json:"at"`
+}
+
+// LoginSucceeded is published when a client is logged in
+type LoginSucceeded struct {
+	Username string    `json:"username"`
+	Address  string    `json:"address"`
+	At       time.Time `json:"at"`
+}
+
+// LoginFailed is published when a client is refused, Reason being one of the FAILURE_ reasons
+type LoginFailed struct {
+	Username string    `json:"username"`
+	Address  string    `json:"address"`
+	Reason   string    `json:"reason"`
+	At       time.Time `json:"at"`
+}
+
+// HackAttempt is published every time a client sends something only a tampered client would,
+// Type being one of the SECURITY_ types and Reason telling the details
+type HackAttempt struct {
+	Type     string    `json:"type"`
+	Username string    `json:"username,omitempty"` // Empty until the client sent its credentials
+	Address  string    `json:"address"`
+	Reason   string    `json:"reason"`
+	At       time.Time `json:"at"`
+}
+
+// ClientKicked is published when the connection of a client is closed
+type ClientKicked struct {
+	Username      string    `json:"username,omitempty"`
+	Address       string    `json:"address"`
+	Authenticated bool      `json:"authenticated"`
+	At            time.Time `json:"at"`
+}
+
+func (AccountCreated) Topic() string { return TOPIC_ACCOUNT_CREATED }
+func (LoginSucceeded) Topic() string { return TOPIC_LOGIN_SUCCESS }
+func (LoginFailed) Topic() string    { return TOPIC_LOGIN_FAILURE }
+func (HackAttempt) Topic() string    { return TOPIC_HACK_ATTEMPT }
+func (ClientKicked) Topic() string   { return TOPIC_CLIENT_KICKED }
+
+// Events returns the bus the login server publishes its events on
+func (l *LoginServer) Events() *eventbus.Bus {
+	return l.events
+}
+
+// OnAccountCreated registers a callback run for every created account
+func (l *LoginServer) OnAccountCreated(callback func(event AccountCreated)) {
+	l.events.Subscribe(TOPIC_ACCOUNT_CREATED, func(event interface{}) error {
+		callback(event.(AccountCreated))
+		return nil
+	})
+}
+
+// OnLoginSuccess registers a callback run for every logged in client
+func (l *LoginServer) OnLoginSuccess(callback func(event LoginSucceeded)) {
+	l.events.Subscribe(TOPIC_LOGIN_SUCCESS, func(event interface{}) error {
+		callback(event.(LoginSucceeded))
+		return nil
+	})
+}
+
+// OnLoginFailure registers a callback run for every refused login
+func (l *LoginServer) OnLoginFailure(callback func(event LoginFailed)) {
+	l.events.Subscribe(TOPIC_LOGIN_FAILURE, func(event interface{}) error {
+		callback(event.(LoginFailed))
+		return nil
+	})
+}
+
+// OnHackAttempt registers a callback run for every hack attempt
+func (l *LoginServer) OnHackAttempt(callback func(event HackAttempt)) {
+	l.events.Subscribe(TOPIC_HACK_ATTEMPT, func(event interface{}) error {
+		callback(event.(HackAttempt))
+		return nil
+	})
+}
+
+// OnClientKicked registers a callback run for every closed client connection
+func (l *LoginServer) OnClientKicked(callback func(event ClientKicked)) {
+	l.events.Subscribe(TOPIC_CLIENT_KICKED, func(event interface{}) error {
+		callback(event.(ClientKicked))
+		return nil
+	})
+}
+
+// hackAttempt counts a hack attempt of the client, keeps it for the admin API and reports it
+func (l *LoginServer) hackAttempt(client *models.Client, eventType, reason string) {
+	atomic.AddUint32(&l.status.hackAttempts, 1)
+	fmt.Printf("Hack attempt (%s): %s\n", eventType, reason)
+
+	event := HackAttempt{Type: eventType, Username: client.Account.Username, Address: clientAddress(client), Reason: reason, At: time.Now()}
+	l.security.record(event)
+	l.events.Emit(event)
+}
+
+// loginFailed reports a refused login, counting the ones refused for their credentials
+func (l *LoginServer) loginFailed(client *models.Client, username, reason string) {
+	if reason == FAILURE_UNKNOWN_ACCOUNT || reason == FAILURE_WRONG_PASSWORD || reason == FAILURE_ACCESS_DENIED {
+		atomic.AddUint32(&l.status.failedLogins, 1)
+	}
+
+	l.events.Emit(LoginFailed{Username: username, Address: clientAddress(client), Reason: reason, At: time.Now()})
+}
+
+func clientAddress(client *models.Client) string {
+	if client.Socket == nil {
+		return ""
+	}
+	return client.Socket.RemoteAddr().String()
+}
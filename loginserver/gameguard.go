@@ -0,0 +1,62 @@
+package loginserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/frostwind/l2go/loginserver/clientpackets"
+	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/loginserver/serverpackets"
+)
+
+// GameGuard modes of the login server, deciding how the RequestGGAuth of the retail clients is answered.
+// Those clients wait for GGAuth before sending their credentials.
+const (
+	GAMEGUARD_STATIC = "static" // GGAuth sent back with the session id of the request, whatever it is
+	GAMEGUARD_IGNORE = "ignore" // RequestGGAuth dropped without an answer
+	GAMEGUARD_STRICT = "strict" // Only the clients which sent the session id of Init in RequestGGAuth can log in
+)
+
+var ErrInvalidGameGuardMode = errors.New("invalid GameGuard mode")
+
+// gameGuardMode returns the GameGuard mode of the configuration
+func (l *LoginServer) gameGuardMode() string {
+	if l.config.LoginServer.GameGuard == "" {
+		return GAMEGUARD_STATIC
+	}
+	return l.config.LoginServer.GameGuard
+}
+
+// checkGameGuardMode makes sure the GameGuard mode of the configuration is one of the known ones
+func (l *LoginServer) checkGameGuardMode() error {
+	switch mode := l.gameGuardMode(); mode {
+	case GAMEGUARD_STATIC, GAMEGUARD_IGNORE, GAMEGUARD_STRICT:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s, must be one of: %s, %s, %s", ErrInvalidGameGuardMode, mode, GAMEGUARD_STATIC, GAMEGUARD_IGNORE, GAMEGUARD_STRICT)
+	}
+}
+
+// handleRequestGGAuth answers the GameGuard authentication of a client according to the GameGuard mode
+func (l *LoginServer) handleRequestGGAuth(ctx context.Context, client *models.Client, data []byte) ([]byte, error) {
+	requestGGAuth, err := clientpackets.NewRequestGGAuth(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedPacket, err)
+	}
+
+	switch l.gameGuardMode() {
+	case GAMEGUARD_IGNORE:
+		return nil, nil
+	case GAMEGUARD_STRICT:
+		if requestGGAuth.SessionID != serverpackets.INIT_SESSION_ID {
+			l.hackAttempt(client, SECURITY_SESSION_MISMATCH, "wrong session id in RequestGGAuth")
+
+			return serverpackets.NewLoginFailPacket(serverpackets.REASON_ACCESS_FAILED), fmt.Errorf("%w: wrong GameGuard session", ErrCloseConnection)
+		}
+	}
+
+	client.GameGuard = true
+	return serverpackets.NewGGAuthPacket(requestGGAuth.SessionID), nil
+}
@@ -0,0 +1,57 @@
+package loginserver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/loginserver/serverpackets"
+	"github.com/frostwind/l2go/opcodes"
+)
+
+func TestRequestGGAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		sessionID uint32
+		answer    byte // Opcode of the answer, 0 for none
+		err       error
+		gameGuard bool
+	}{
+		{name: "default", sessionID: 0x01020304, answer: opcodes.LoginServerGGAuth, gameGuard: true},
+		{name: "ignored", mode: GAMEGUARD_IGNORE, sessionID: serverpackets.INIT_SESSION_ID},
+		{name: "strict", mode: GAMEGUARD_STRICT, sessionID: serverpackets.INIT_SESSION_ID, answer: opcodes.LoginServerGGAuth, gameGuard: true},
+		{name: "strict other session", mode: GAMEGUARD_STRICT, sessionID: 0x01020304, answer: opcodes.LoginServerLoginFail, err: ErrCloseConnection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(config.ConfigObject{LoginServer: config.LoginServerType{GameGuard: tt.mode}})
+			client := &models.Client{}
+
+			data := append(binary.LittleEndian.AppendUint32(nil, tt.sessionID), make([]byte, 16)...)
+			answer, err := l.Dispatcher().Dispatch(context.Background(), opcodes.LoginClientRequestGGAuth, client, data)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Dispatch() error = %v, want %v", err, tt.err)
+			}
+			switch {
+			case tt.answer == 0 && answer != nil:
+				t.Errorf("answered %X, want no answer", answer)
+			case tt.answer != 0 && (len(answer) == 0 || answer[0] != tt.answer):
+				t.Errorf("answered %X, want a %s", answer, opcodes.Name(opcodes.Login, opcodes.ServerToClient, tt.answer))
+			case tt.answer == opcodes.LoginServerGGAuth && binary.LittleEndian.Uint32(answer[1:]) != tt.sessionID:
+				t.Errorf("GGAuth answered for the session %X, want %X", answer[1:5], tt.sessionID)
+			}
+			if client.GameGuard != tt.gameGuard {
+				t.Errorf("GameGuard = %v, want %v", client.GameGuard, tt.gameGuard)
+			}
+		})
+	}
+
+	l := New(config.ConfigObject{LoginServer: config.LoginServerType{GameGuard: "lenient"}})
+	if err := l.checkGameGuardMode(); !errors.Is(err, ErrInvalidGameGuardMode) {
+		t.Errorf("checkGameGuardMode() = %v, want ErrInvalidGameGuardMode", err)
+	}
+}
@@ -0,0 +1,30 @@
+package gameserverpackets
+
+import (
+	"errors"
+
+	"github.com/frostwind/l2go/packets"
+)
+
+const heartbeatSize = 2
+
+// ErrHeartbeatTooShort is returned when a heartbeat doesn't hold the online players count
+var ErrHeartbeatTooShort = errors.New("heartbeat packet too short")
+
+type Heartbeat struct {
+	OnlinePlayers uint16
+}
+
+func NewHeartbeat(request []byte) (Heartbeat, error) {
+	var result Heartbeat
+
+	if len(request) < heartbeatSize {
+		return result, ErrHeartbeatTooShort
+	}
+
+	var packet = packets.NewReader(request)
+
+	result.OnlinePlayers = packet.ReadUInt16()
+
+	return result, nil
+}
@@ -0,0 +1,19 @@
+package gameserverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// NewPlayerAuthPacket hands the session token minted for an account to the only
+// game server it is valid for, along with the access level of the account
+func NewPlayerAuthPacket(account string, accessLevel int8, loginKey, playKey []byte) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.LinkLoginServerPlayerAuth)
+	buffer.WriteString(account)
+	buffer.Write(loginKey[:8])
+	buffer.Write(playKey[:8])
+	buffer.WriteByte(byte(accessLevel))
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,32 @@
+package gameserverpackets
+
+import (
+	"errors"
+
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
+)
+
+// ErrMissingName is returned when a game server registers without a name
+var ErrMissingName = errors.New("the game server didn't send its name")
+
+type RegisterGameServer struct {
+	Name        string
+	Secret      string
+	Compression protocol.CompressionOptions // Offered by the game server for the link, disabled when it offered nothing
+}
+
+func NewRegisterGameServer(request []byte) (RegisterGameServer, error) {
+	var result RegisterGameServer
+
+	var packet = packets.NewReader(request)
+
+	result.Name = packet.ReadString()
+	result.Secret = packet.ReadString()
+	result.Compression = protocol.ParseCompressionTrailer(packet.ReadBytes(packet.Len()))
+	if result.Name == "" {
+		return result, ErrMissingName
+	}
+
+	return result, nil
+}
@@ -0,0 +1,25 @@
+package gameserverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
+)
+
+const (
+	REGISTER_RESULT_ACCEPTED     = 0x01
+	REGISTER_RESULT_WRONG_SECRET = 0x02
+	REGISTER_RESULT_UNKNOWN      = 0x03
+)
+
+// NewRegisterResultPacket answers the registration of a game server, with the compression of the link
+// when it is accepted and both servers offered it
+func NewRegisterResultPacket(result uint8, id uint8, compression protocol.CompressionOptions) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.LinkLoginServerRegisterResult)
+	buffer.WriteUInt8(result)
+	buffer.WriteUInt8(id) // The id the game server is known as
+	buffer.Write(compression.Trailer())
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,188 @@
+package loginserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/loginserver/crypt"
+	"github.com/frostwind/l2go/loginserver/gameserverpackets"
+	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/loginserver/serverpackets"
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
+)
+
+var (
+	ErrGameServerDown = errors.New("the game server is down")
+	ErrGameServerFull = errors.New("the game server is full")
+)
+
+// checkGameServers makes sure every game server can be told apart
+func (l *LoginServer) checkGameServers() error {
+	ids := make(map[uint8]string)
+	names := make(map[string]bool)
+
+	for index, gameserver := range l.config.GameServers {
+		id := gameserver.ServerID(index)
+		if other, ok := ids[id]; ok {
+			return fmt.Errorf("The game servers %s and %s share the id %d", other, gameserver.Name, id)
+		}
+		if names[gameserver.Name] {
+			return fmt.Errorf("The game server name %s is used twice", gameserver.Name)
+		}
+		ids[id] = gameserver.Name
+		names[gameserver.Name] = true
+	}
+
+	return nil
+}
+
+// gameServerConfig returns the configuration of the game server with the given id
+func (l *LoginServer) gameServerConfig(id uint8) (config.GameServerType, int, bool) {
+	for index, gameserver := range l.config.GameServers {
+		if gameserver.ServerID(index) == id {
+			return gameserver, index, true
+		}
+	}
+	return config.GameServerType{}, 0, false
+}
+
+// GameServerUp reports whether the game server with the given id is registered and alive
+func (l *LoginServer) GameServerUp(id uint8) bool {
+	l.gameserversMutex.Lock()
+	defer l.gameserversMutex.Unlock()
+
+	gameserver, ok := l.gameservers[id]
+	return ok && gameserver.IsUp()
+}
+
+// gameServerStatuses returns the live state of every configured game server, in the configuration order
+func (l *LoginServer) gameServerStatuses() []serverpackets.ServerStatus {
+	l.gameserversMutex.Lock()
+	defer l.gameserversMutex.Unlock()
+
+	statuses := make([]serverpackets.ServerStatus, len(l.config.GameServers))
+	for index, item := range l.config.GameServers {
+		if gameserver, ok := l.gameservers[item.ServerID(index)]; ok {
+			statuses[index] = serverpackets.ServerStatus{Up: gameserver.IsUp(), OnlinePlayers: gameserver.OnlinePlayers()}
+		}
+	}
+
+	return statuses
+}
+
+// registerGameServer binds a link to the configured game server of the same name
+// once its secret is checked. A game server coming back replaces its previous link.
+func (l *LoginServer) registerGameServer(gameserver *models.GameServer, name, secret string, compression protocol.CompressionOptions) bool {
+	var id uint8
+	var expected string
+	for index, item := range l.config.GameServers {
+		if item.Name == name {
+			id = item.ServerID(index)
+			expected = item.Secret
+			break
+		}
+	}
+
+	if id == 0 {
+		fmt.Printf("The game server %s isn't in the configuration\n", name)
+		gameserver.Send(gameserverpackets.NewRegisterResultPacket(gameserverpackets.REGISTER_RESULT_UNKNOWN, 0, protocol.CompressionOptions{}))
+		return false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+		fmt.Printf("The game server %s sent a wrong secret\n", name)
+		atomic.AddUint32(&l.status.hackAttempts, 1)
+		gameserver.Send(gameserverpackets.NewRegisterResultPacket(gameserverpackets.REGISTER_RESULT_WRONG_SECRET, 0, protocol.CompressionOptions{}))
+		return false
+	}
+
+	gameserver.Register(id, name)
+
+	l.gameserversMutex.Lock()
+	previous, ok := l.gameservers[id]
+	l.gameservers[id] = gameserver
+	l.gameserversMutex.Unlock()
+
+	if ok && previous != gameserver {
+		fmt.Printf("The game server %s registered again, dropping its previous link\n", name)
+		previous.Socket.Close()
+	}
+
+	// The packets following the result are compressed if both servers offered it
+	compression = protocol.NegotiateCompression(protocol.CompressionOptions{
+		Enabled:   l.config.LoginServer.Compression.Enabled,
+		Threshold: l.config.LoginServer.Compression.Threshold,
+		MaxSize:   packets.MaxFrameSize,
+	}, compression)
+
+	fmt.Printf("The game server %s is registered with the id %d\n", name, id)
+	gameserver.SendCompressing(gameserverpackets.NewRegisterResultPacket(gameserverpackets.REGISTER_RESULT_ACCEPTED, id, compression), compression)
+	return true
+}
+
+// unregisterGameServer closes a link and marks its game server as down, unless it has been replaced
+func (l *LoginServer) unregisterGameServer(gameserver *models.GameServer) {
+	gameserver.Socket.Close()
+
+	l.gameserversMutex.Lock()
+	defer l.gameserversMutex.Unlock()
+
+	if current, ok := l.gameservers[gameserver.Id]; ok && current == gameserver {
+		gameserver.MarkDown()
+		fmt.Printf("Lost the link with the game server %s\n", gameserver.Name)
+	}
+}
+
+// monitorGameServers marks as down the game servers that missed too many heartbeats
+func (l *LoginServer) monitorGameServers() {
+	ticker := time.NewTicker(l.config.LoginServer.HeartbeatPeriod())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case now := <-ticker.C:
+			l.gameserversMutex.Lock()
+			for _, gameserver := range l.gameservers {
+				if gameserver.Demote(now, l.config.LoginServer.HeartbeatTimeout()) {
+					fmt.Printf("The game server %s missed its heartbeats, marking it as down\n", gameserver.Name)
+				}
+			}
+			l.gameserversMutex.Unlock()
+		}
+	}
+}
+
+// mintPlayKey creates the session token of an account for one game server and
+// hands it to that game server only, so it can't be used to enter another one
+func (l *LoginServer) mintPlayKey(client *models.Client, id uint8, maxPlayers uint16) ([]byte, error) {
+	l.gameserversMutex.Lock()
+	gameserver, ok := l.gameservers[id]
+	l.gameserversMutex.Unlock()
+
+	if !ok || !gameserver.IsUp() {
+		return nil, ErrGameServerDown
+	}
+
+	if gameserver.OnlinePlayers() >= maxPlayers {
+		return nil, ErrGameServerFull
+	}
+
+	playKey, err := crypt.GenerateSessionID(l.random, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	err = gameserver.Send(gameserverpackets.NewPlayerAuthPacket(client.Account.Username, client.Account.AccessLevel, client.SessionID[:8], playKey))
+	if err != nil {
+		return nil, ErrGameServerDown
+	}
+
+	return playKey, nil
+}
@@ -2,85 +2,232 @@ package loginserver
 
 import (
 	"bytes"
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
 	"net"
-
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/access"
+	"github.com/frostwind/l2go/audit"
+	"github.com/frostwind/l2go/clock"
 	"github.com/frostwind/l2go/config"
-	"github.com/frostwind/l2go/loginserver/clientpackets"
+	"github.com/frostwind/l2go/database"
+	"github.com/frostwind/l2go/diagnostics"
+	"github.com/frostwind/l2go/eventbus"
+	"github.com/frostwind/l2go/loginserver/gameserverpackets"
 	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/loginserver/repository"
 	"github.com/frostwind/l2go/loginserver/serverpackets"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/packettiming"
+	"github.com/frostwind/l2go/random"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type LoginServer struct {
 	clients             []*models.Client
-	gameservers         []*models.GameServer
-	database            *sql.DB
+	sessions            map[*models.Client]*SessionInfo // The same clients, as dumped by the snapshot
+	clientsMutex        sync.Mutex
+	gameservers         map[uint8]*models.GameServer
+	gameserversMutex    sync.Mutex
+	accounts            repository.AccountRepository
 	config              config.ConfigObject
 	internalServersList []byte
 	externalServersList []byte
 	status              loginServerStatus
 	clientsListener     net.Listener
 	gameServersListener net.Listener
+	adminListener       net.Listener
+	adminServer         *http.Server
+	diagnostics         *diagnostics.Server
+	stop                chan struct{}
+	stopOnce            sync.Once
+	events              *eventbus.Bus
+	creations           *accountCreations
+	security            *securityLog
+	mode                atomic.Value
+	access              *access.Model
+	handlerTimes        *packettiming.Recorder
+	dispatcher          *Dispatcher
+	audit               *audit.Logger
+	random              random.Source
+	clock               clock.Clock
 }
 
 type loginServerStatus struct {
 	successfulAccountCreation uint32
 	failedAccountCreation     uint32
+	refusedAccountCreation    uint32
 	successfulLogins          uint32
 	failedLogins              uint32
 	hackAttempts              uint32
+	reapedPreAuth             uint32
+	reapedPostAuth            uint32
+	expiredSessions           uint32
+	databaseTime              int64 // Nanoseconds spent in the account repository by the logins
+	cryptoTime                int64 // Nanoseconds spent hashing and checking the passwords
 }
 
 func New(cfg config.ConfigObject) *LoginServer {
-	return &LoginServer{config: cfg}
+	l := &LoginServer{
+		config:      cfg,
+		gameservers: make(map[uint8]*models.GameServer),
+		sessions:    make(map[*models.Client]*SessionInfo),
+		stop:        make(chan struct{}),
+		events:      eventbus.New(),
+		creations:   newAccountCreations(cfg.LoginServer.AccountCreation),
+		security:    newSecurityLog(SECURITY_EVENTS_KEPT),
+		access:      access.DefaultModel(),
+		random:      random.Crypto(),
+		clock:       clock.Real{},
+	}
+	l.handlerTimes = packettiming.New("login server", cfg.LoginServer.SlowHandlerThreshold(), func(opcode byte) string {
+		return opcodes.Name(opcodes.Login, opcodes.ClientToServer, opcode)
+	})
+	l.dispatcher = l.newDispatcher()
+	return l
 }
 
 func (l *LoginServer) Init() {
 	var err error
 
-	// Connect to MySQL database
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-		l.config.LoginServer.Database.User,
-		l.config.LoginServer.Database.Password,
-		l.config.LoginServer.Database.Host,
-		l.config.LoginServer.Database.Port,
-		l.config.LoginServer.Database.Name)
+	err = l.checkGameServers()
+	if err != nil {
+		panic("Couldn't load the game servers: " + err.Error())
+	}
+
+	l.access, err = access.NewModel(l.config.LoginServer.AccessTiers)
+	if err != nil {
+		panic("Couldn't load the access tiers: " + err.Error())
+	}
+
+	err = l.config.LoginServer.AccountPolicy.Validate()
+	if err != nil {
+		panic("Couldn't load the account policy: " + err.Error())
+	}
+
+	err = l.startAudit()
+	if err != nil {
+		panic("Couldn't open the audit log: " + err.Error())
+	}
+
+	err = l.SetMode(l.config.LoginServer.Mode)
+	if err != nil {
+		panic("Couldn't set the server mode: " + err.Error())
+	}
 
-	l.database, err = sql.Open("mysql", dsn)
+	err = l.checkGameGuardMode()
 	if err != nil {
-		panic("Couldn't connect to the database server: " + err.Error())
+		panic("Couldn't set the GameGuard mode: " + err.Error())
 	}
 
-	// Test the connection
-	err = l.database.Ping()
+	err = l.config.LoginServer.CheckNullCrypto()
 	if err != nil {
-		panic("Couldn't ping the database server: " + err.Error())
+		panic("Couldn't disable the encryption: " + err.Error())
 	}
+	if l.config.LoginServer.NullCrypto {
+		fmt.Println("WARNING: the Login Server sends its packets in clear, for local testing only")
+	}
+
+	if l.config.LoginServer.Database.IsMemory() {
+		l.accounts = repository.NewMemoryAccountRepository()
+		fmt.Println("Using the in-memory account storage")
+	} else {
+		// Connect to MySQL database, which may still be starting
+		db, err := database.Open(l.config.LoginServer.Database)
+		if err != nil {
+			panic("Couldn't connect to the database server: " + err.Error())
+		}
 
-	fmt.Println("Successfully connected to the MySQL database server")
+		l.accounts = repository.NewMySQLAccountRepository(db)
+		fmt.Println("Successfully connected to the MySQL database server")
+	}
 
 	// Listen for client connections
-	l.clientsListener, err = net.Listen("tcp", ":2106")
+	l.clientsListener, err = net.Listen("tcp", l.config.LoginServer.ClientsAddress())
 	if err != nil {
 		fmt.Println("Couldn't initialize the Login Server (Clients listener)")
 	} else {
-		fmt.Println("Login Server listening for clients connections on port 2106")
+		fmt.Printf("Login Server listening for clients connections on %s\n", l.clientsListener.Addr())
 	}
 
 	// Listen for game servers connections
-	l.gameServersListener, err = net.Listen("tcp", ":9413")
+	l.gameServersListener, err = net.Listen("tcp", l.config.LoginServer.GameServersListenAddress())
 	if err != nil {
 		fmt.Println("Couldn't initialize the Login Server (Gameservers listener)")
 	} else {
-		fmt.Println("Login Server listening for gameservers connections on port 9413")
+		fmt.Printf("Login Server listening for gameservers connections on %s\n", l.gameServersListener.Addr())
+	}
+
+	// Listen for the admin API, if enabled
+	if l.config.LoginServer.AdminAddress != "" {
+		l.adminListener, err = net.Listen("tcp", l.config.LoginServer.AdminAddress)
+		if err != nil {
+			fmt.Println("Couldn't initialize the Login Server (Admin listener)")
+		} else {
+			l.adminServer = &http.Server{Handler: l.AdminHandler()}
+			fmt.Printf("Login Server listening for admin requests on %s\n", l.adminListener.Addr())
+		}
+	}
+
+	// Serve the diagnostics, if enabled
+	l.diagnostics, err = diagnostics.Start("loginserver", l.config.LoginServer.Diagnostics)
+	if err != nil {
+		fmt.Printf("Couldn't initialize the Login Server (Diagnostics listener): %v\n", err)
+	} else if l.diagnostics != nil {
+		diagnostics.Publish("loginserver", func() any { return l.Stats() })
+		diagnostics.Publish("loginserver.handlers", func() any { return l.HandlerStats() })
+		fmt.Printf("Login Server serving its diagnostics on %s\n", l.diagnostics.Addr())
+	}
+}
+
+// Accounts returns the storage of the accounts, for the tools seeding them, once the server is initialized
+func (l *LoginServer) Accounts() repository.AccountRepository {
+	return l.accounts
+}
+
+// ClientsAddr returns the address of the clients listener, or nil if it isn't listening
+func (l *LoginServer) ClientsAddr() net.Addr {
+	if l.clientsListener == nil {
+		return nil
+	}
+	return l.clientsListener.Addr()
+}
+
+// GameServersAddr returns the address of the game servers listener, or nil if it isn't listening
+func (l *LoginServer) GameServersAddr() net.Addr {
+	if l.gameServersListener == nil {
+		return nil
+	}
+	return l.gameServersListener.Addr()
+}
+
+// DiagnosticsAddr returns the address the diagnostics are served on, or nil if they are disabled
+func (l *LoginServer) DiagnosticsAddr() net.Addr {
+	if l.diagnostics == nil {
+		return nil
 	}
+	return l.diagnostics.Addr()
+}
+
+// AdminAddr returns the address of the admin API listener, or nil if it isn't listening
+func (l *LoginServer) AdminAddr() net.Addr {
+	if l.adminListener == nil {
+		return nil
+	}
+	return l.adminListener.Addr()
 }
 
 func (l *LoginServer) Start() {
-	defer l.database.Close()
+	defer l.accounts.Close()
+	if l.audit != nil {
+		defer l.audit.Close()
+	}
 	defer l.clientsListener.Close()
 	defer l.gameServersListener.Close()
 
@@ -89,13 +236,24 @@ func (l *LoginServer) Start() {
 	go func() {
 		for {
 			var err error
-			client := models.NewClient()
+			client := models.NewClientFrom(l.random)
+			client.MaxPacketSize = l.config.LoginServer.PacketSizeLimit()
+			client.IdleTimeout = l.config.LoginServer.ClientPreAuthTimeout()
+			client.Plain = l.config.LoginServer.NullCrypto
 			client.Socket, err = l.clientsListener.Accept()
-			l.clients = append(l.clients, client)
+			if errors.Is(err, net.ErrClosed) {
+				done <- true
+				return
+			}
 			if err != nil {
 				fmt.Println("Couldn't accept the incoming connection.")
 				continue
 			} else {
+				l.clientsMutex.Lock()
+				l.clients = append(l.clients, client)
+				l.sessions[client] = &SessionInfo{Address: clientAddress(client), ConnectedAt: time.Now()}
+				l.clientsMutex.Unlock()
+
 				go l.handleClientPackets(client)
 			}
 		}
@@ -106,7 +264,10 @@ func (l *LoginServer) Start() {
 			var err error
 			gameserver := models.NewGameServer()
 			gameserver.Socket, err = l.gameServersListener.Accept()
-			l.gameservers = append(l.gameservers, gameserver)
+			if errors.Is(err, net.ErrClosed) {
+				done <- true
+				return
+			}
 			if err != nil {
 				fmt.Println("Couldn't accept the incoming connection.")
 				continue
@@ -116,15 +277,84 @@ func (l *LoginServer) Start() {
 		}
 	}()
 
+	go l.monitorGameServers()
+	go l.sweepSessions()
+
+	if l.adminServer != nil {
+		go l.adminServer.Serve(l.adminListener)
+	}
+
 	for i := 0; i < 2; i++ {
 		<-done
 	}
 
 }
 
+// SetRandSource replaces the source the session ids and the play keys are read from, before Start.
+// Only deterministic tests should use another source than random.Crypto.
+func (l *LoginServer) SetRandSource(source random.Source) {
+	l.random = source
+}
+
+// Stop closes the listeners, which makes Start return
+func (l *LoginServer) Stop() {
+	l.stopOnce.Do(func() { close(l.stop) })
+	l.clientsListener.Close()
+	l.gameServersListener.Close()
+	if l.adminServer != nil {
+		l.adminServer.Close()
+	}
+	if l.diagnostics != nil {
+		l.diagnostics.Close()
+	}
+}
+
+// connectionContext returns the context of the handlers of a connection, done once the connection
+// ends or the login server stops
+func (l *LoginServer) connectionContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-l.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// authenticate gives the client the longer idle timeout of the logged in players
+func (l *LoginServer) authenticate(client *models.Client) {
+	client.Authenticated = true
+	client.IdleTimeout = l.config.LoginServer.ClientIdleTimeout()
+}
+
+// hashPassword hashes the password of a new account, counting the time it took
+func (l *LoginServer) hashPassword(password string) ([]byte, error) {
+	defer l.spent(&l.status.cryptoTime, time.Now())
+	return bcrypt.GenerateFromPassword([]byte(password), 10)
+}
+
+// spent adds the time elapsed since start to a counter of the status
+func (l *LoginServer) spent(counter *int64, start time.Time) {
+	atomic.AddInt64(counter, int64(time.Since(start)))
+}
+
+// countReaped records a connection dropped for staying silent too long
+func (l *LoginServer) countReaped(authenticated bool) {
+	if authenticated {
+		atomic.AddUint32(&l.status.reapedPostAuth, 1)
+	} else {
+		atomic.AddUint32(&l.status.reapedPreAuth, 1)
+	}
+	fmt.Println("The client stayed idle for too long")
+}
+
 func (l *LoginServer) kickClient(client *models.Client) {
 	client.Socket.Close()
+	l.events.Emit(ClientKicked{Username: client.Account.Username, Address: clientAddress(client), Authenticated: client.Authenticated, At: time.Now()})
 
+	l.clientsMutex.Lock()
 	for i, item := range l.clients {
 		if bytes.Equal(item.SessionID, client.SessionID) {
 			copy(l.clients[i:], l.clients[i+1:])
@@ -133,15 +363,19 @@ func (l *LoginServer) kickClient(client *models.Client) {
 			break
 		}
 	}
+	delete(l.sessions, client)
+	l.clientsMutex.Unlock()
+
+	client.Wipe()
 
 	fmt.Println("The client has been successfully kicked from the server.")
 }
 
 func (l *LoginServer) handleGameServerPackets(gameserver *models.GameServer) {
-	defer gameserver.Socket.Close()
+	defer l.unregisterGameServer(gameserver)
 
 	for {
-		opcode, _, err := gameserver.Receive()
+		opcode, data, err := gameserver.Receive()
 
 		if err != nil {
 			fmt.Println(err)
@@ -150,8 +384,35 @@ func (l *LoginServer) handleGameServerPackets(gameserver *models.GameServer) {
 		}
 
 		switch opcode {
-		case 00:
+		case opcodes.LinkGameServerRegister:
 			fmt.Println("A game server sent a request to register")
+
+			registerGameServer, err := gameserverpackets.NewRegisterGameServer(data)
+
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			if !l.registerGameServer(gameserver, registerGameServer.Name, registerGameServer.Secret, registerGameServer.Compression) {
+				return
+			}
+		case opcodes.LinkGameServerHeartbeat:
+			heartbeat, err := gameserverpackets.NewHeartbeat(data)
+
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+
+			if gameserver.Id == 0 {
+				fmt.Println("A game server sent a heartbeat before registering")
+				return
+			}
+
+			if gameserver.Heartbeat(heartbeat.OnlinePlayers) {
+				fmt.Printf("The game server %s is back up\n", gameserver.Name)
+			}
 		default:
 			fmt.Println("Can't recognize the packet sent by the gameserver")
 		}
@@ -162,7 +423,7 @@ func (l *LoginServer) handleClientPackets(client *models.Client) {
 	fmt.Println("A client is trying to connect...")
 	defer l.kickClient(client)
 
-	buffer := serverpackets.NewInitPacket()
+	buffer := serverpackets.NewInitPacket(client.Plain)
 	err := client.Send(buffer, false, false)
 
 	if err != nil {
@@ -172,145 +433,43 @@ func (l *LoginServer) handleClientPackets(client *models.Client) {
 		fmt.Println("Init packet sent.")
 	}
 
+	ctx, cancel := l.connectionContext()
+	defer cancel()
+
 	for {
 		opcode, data, err := client.Receive()
 
+		if errors.Is(err, packets.ErrFrameTooLarge) {
+			l.hackAttempt(client, SECURITY_OVERSIZED_PACKET, "oversized packet")
+		}
+
+		if errors.Is(err, models.ErrChecksumMismatch) {
+			l.hackAttempt(client, SECURITY_CHECKSUM_FAILURE, "wrong checksum")
+		}
+
+		if errors.Is(err, packets.ErrIdleTimeout) {
+			l.countReaped(client.Authenticated)
+		}
+
 		if err != nil {
 			fmt.Println(err)
 			fmt.Println("Closing the connection...")
 			break
 		}
 
-		switch opcode {
-		case 00:
-			// response buffer
-			var buffer []byte
-
-			requestAuthLogin := clientpackets.NewRequestAuthLogin(data)
-
-			fmt.Printf("User %s is trying to login\n", requestAuthLogin.Username)
-
-			// Query for existing account
-			var account models.Account
-			err := l.database.QueryRow("SELECT id, username, password, access_level FROM accounts WHERE username = ?", requestAuthLogin.Username).Scan(
-				&account.Id, &account.Username, &account.Password, &account.AccessLevel)
-
-			if err == sql.ErrNoRows {
-				if l.config.LoginServer.AutoCreate == true {
-					hashedPassword, err := bcrypt.GenerateFromPassword([]byte(requestAuthLogin.Password), 10)
-					if err != nil {
-						fmt.Println("An error occured while trying to generate the password")
-						l.status.failedAccountCreation += 1
-
-						buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_SYSTEM_ERROR)
-					} else {
-						// Insert new account
-						result, err := l.database.Exec("INSERT INTO accounts (username, password, access_level) VALUES (?, ?, ?)",
-							requestAuthLogin.Username, string(hashedPassword), ACCESS_LEVEL_PLAYER)
-
-						if err != nil {
-							fmt.Printf("Couldn't create an account for the user %s: %v\n", requestAuthLogin.Username, err)
-							l.status.failedAccountCreation += 1
-
-							buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_SYSTEM_ERROR)
-						} else {
-							accountId, _ := result.LastInsertId()
-							client.Account = models.Account{
-								Id:          accountId,
-								Username:    requestAuthLogin.Username,
-								Password:    string(hashedPassword),
-								AccessLevel: ACCESS_LEVEL_PLAYER}
-
-							fmt.Printf("Account successfully created for the user %s\n", requestAuthLogin.Username)
-							l.status.successfulAccountCreation += 1
-
-							buffer = serverpackets.NewLoginOkPacket(client.SessionID)
-						}
-					}
-				} else {
-					fmt.Println("Account not found !")
-					l.status.failedLogins += 1
-
-					buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_USER_OR_PASS_WRONG)
-				}
-			} else if err != nil {
-				fmt.Printf("Database error: %v\n", err)
-				buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_SYSTEM_ERROR)
-			} else {
-				// Account exists; Is the password ok?
-				client.Account = account
-				err = bcrypt.CompareHashAndPassword([]byte(client.Account.Password), []byte(requestAuthLogin.Password))
-
-				if err != nil {
-					fmt.Printf("Wrong password for the account %s\n", requestAuthLogin.Username)
-					l.status.failedLogins += 1
-
-					buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_USER_OR_PASS_WRONG)
-				} else {
-
-					if client.Account.AccessLevel >= ACCESS_LEVEL_PLAYER {
-						l.status.successfulLogins += 1
-
-						buffer = serverpackets.NewLoginOkPacket(client.SessionID)
-					} else {
-						l.status.failedLogins += 1
-
-						buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_ACCESS_FAILED)
-					}
-
-				}
-			}
-
-			err = client.Send(buffer)
-
-			if err != nil {
+		answer, err := l.dispatcher.Dispatch(ctx, opcode, client, data)
+		if answer != nil {
+			if err := client.Send(answer); err != nil {
 				fmt.Println(err)
 			}
-
-		case 02:
-			requestPlay := clientpackets.NewRequestPlay(data)
-
-			fmt.Printf("The client wants to connect to the server : %d\n", requestPlay.ServerID)
-
-			var buffer []byte
-			if len(l.config.GameServers) >= int(requestPlay.ServerID) && (l.config.GameServers[requestPlay.ServerID-1].Options.Testing == false || client.Account.AccessLevel > ACCESS_LEVEL_PLAYER) {
-				if !bytes.Equal(client.SessionID[:8], requestPlay.SessionID) {
-					l.status.hackAttempts += 1
-
-					buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_ACCESS_FAILED)
-				} else {
-					buffer = serverpackets.NewPlayOkPacket()
-				}
-			} else {
-				l.status.hackAttempts += 1
-
-				buffer = serverpackets.NewPlayFailPacket(serverpackets.REASON_ACCESS_FAILED)
-			}
-			err := client.Send(buffer)
-
-			if err != nil {
-				fmt.Println(err)
-			}
-
-		case 05:
-			requestServerList := clientpackets.NewRequestServerList(data)
-
-			var buffer []byte
-			if !bytes.Equal(client.SessionID[:8], requestServerList.SessionID) {
-				l.status.hackAttempts += 1
-
-				buffer = serverpackets.NewLoginFailPacket(serverpackets.REASON_ACCESS_FAILED)
-			} else {
-				buffer = serverpackets.NewServerListPacket(l.config.GameServers, client.Socket.RemoteAddr().String())
-			}
-			err := client.Send(buffer)
-
-			if err != nil {
+		}
+		if errors.Is(err, ErrUnknownOpcode) {
+			fmt.Printf("Couldn't detect the packet type: %s\n", opcodes.Name(opcodes.Login, opcodes.ClientToServer, opcode))
+		} else if err != nil {
+			if !errors.Is(err, ErrCloseConnection) {
 				fmt.Println(err)
 			}
-
-		default:
-			fmt.Println("Couldn't detect the packet type.")
+			return
 		}
 	}
 }
@@ -0,0 +1,75 @@
+package loginserver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/frostwind/l2go/access"
+	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/loginserver/serverpackets"
+)
+
+// Modes of the login server, deciding who can log in
+const (
+	MODE_NORMAL      = "normal"      // Open to everyone
+	MODE_GM_ONLY     = "gm-only"     // Only the accounts allowed to log in a closed server can log in
+	MODE_MAINTENANCE = "maintenance" // Same as gm-only, the game servers being listed as down
+)
+
+const TOPIC_MODE_CHANGED = "login.mode"
+
+var ErrInvalidMode = errors.New("invalid server mode")
+
+// ModeChanged is published when the mode of the login server changes
+type ModeChanged struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+func (ModeChanged) Topic() string { return TOPIC_MODE_CHANGED }
+
+// Mode returns the current mode of the login server
+func (l *LoginServer) Mode() string {
+	if mode, ok := l.mode.Load().(string); ok {
+		return mode
+	}
+	return MODE_NORMAL
+}
+
+// SetMode changes who can log in, the clients already logged in are left alone
+func (l *LoginServer) SetMode(mode string) error {
+	if mode == "" {
+		mode = MODE_NORMAL
+	}
+	if mode != MODE_NORMAL && mode != MODE_GM_ONLY && mode != MODE_MAINTENANCE {
+		return fmt.Errorf("%w: %s, must be one of: %s, %s, %s", ErrInvalidMode, mode, MODE_NORMAL, MODE_GM_ONLY, MODE_MAINTENANCE)
+	}
+
+	previous, ok := l.mode.Swap(mode).(string)
+	if !ok {
+		previous = MODE_NORMAL
+	}
+
+	if previous != mode {
+		fmt.Printf("The login server switched from the %s mode to the %s mode\n", previous, mode)
+		l.events.Emit(ModeChanged{From: previous, To: mode, At: time.Now()})
+	}
+	return nil
+}
+
+// closedTo returns whether the current mode refuses the account, along with the reason sent to its client
+func (l *LoginServer) closedTo(account models.Account) (reason uint32, closed bool) {
+	if account.Can(access.CLOSED_LOGIN) {
+		return 0, false
+	}
+
+	switch l.Mode() {
+	case MODE_GM_ONLY:
+		return serverpackets.REASON_ACCESS_FAILED, true
+	case MODE_MAINTENANCE:
+		return serverpackets.REASON_MAINTENANCE, true
+	}
+	return 0, false
+}
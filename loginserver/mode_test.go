@@ -0,0 +1,47 @@
+package loginserver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/loginserver/serverpackets"
+)
+
+func TestLoginServerModes(t *testing.T) {
+	l := New(config.ConfigObject{})
+
+	tests := []struct {
+		mode        string
+		accessLevel int8
+		wantClosed  bool
+		wantReason  uint32
+	}{
+		{mode: MODE_NORMAL, accessLevel: ACCESS_LEVEL_PLAYER},
+		{mode: MODE_GM_ONLY, accessLevel: ACCESS_LEVEL_PLAYER, wantClosed: true, wantReason: serverpackets.REASON_ACCESS_FAILED},
+		{mode: MODE_GM_ONLY, accessLevel: ACCESS_LEVEL_TESTER, wantClosed: true, wantReason: serverpackets.REASON_ACCESS_FAILED},
+		{mode: MODE_GM_ONLY, accessLevel: ACCESS_LEVEL_GM},
+		{mode: MODE_MAINTENANCE, accessLevel: ACCESS_LEVEL_PLAYER, wantClosed: true, wantReason: serverpackets.REASON_MAINTENANCE},
+		{mode: MODE_MAINTENANCE, accessLevel: ACCESS_LEVEL_ADMIN},
+		{mode: "", accessLevel: ACCESS_LEVEL_PLAYER},
+	}
+
+	for _, tt := range tests {
+		if err := l.SetMode(tt.mode); err != nil {
+			t.Fatalf("SetMode(%q) error = %v", tt.mode, err)
+		}
+		account := models.Account{AccessLevel: tt.accessLevel, Tier: l.access.TierOf(tt.accessLevel)}
+		reason, closed := l.closedTo(account)
+		if closed != tt.wantClosed || reason != tt.wantReason {
+			t.Errorf("%s mode: closedTo(%d) = %#x, %v, want %#x, %v", l.Mode(), tt.accessLevel, reason, closed, tt.wantReason, tt.wantClosed)
+		}
+	}
+
+	if err := l.SetMode("closed"); !errors.Is(err, ErrInvalidMode) {
+		t.Errorf("SetMode() error = %v, want ErrInvalidMode", err)
+	}
+	if mode := l.Mode(); mode != MODE_NORMAL {
+		t.Errorf("Mode() = %s after an invalid mode", mode)
+	}
+}
@@ -1,8 +1,16 @@
 package models
 
+import "github.com/frostwind/l2go/access"
+
 type Account struct {
-	Id          int64  `json:"id"`
-	Username    string `json:"username"`
-	Password    string `json:"password"`
-	AccessLevel int8   `json:"access_level"`
+	Id          int64        `json:"id"`
+	Username    string       `json:"username"`
+	Password    string       `json:"password"`
+	AccessLevel int8         `json:"access_level"`
+	Tier        *access.Tier `json:"-"` // Resolved from the access level once the account is loaded
+}
+
+// Can returns whether the tier of the account grants the capability
+func (a Account) Can(capability string) bool {
+	return a.Tier.Can(capability)
 }
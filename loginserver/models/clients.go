@@ -1,23 +1,35 @@
 package models
 
 import (
-	"crypto/rand"
 	"errors"
 	"fmt"
 	"github.com/frostwind/l2go/loginserver/crypt"
 	"github.com/frostwind/l2go/packets"
+	"io"
 	"net"
+	"os"
+	"time"
 )
 
 type Client struct {
-	Account   Account
-	SessionID []byte
-	Socket    net.Conn
+	Account       Account
+	SessionID     []byte
+	Socket        net.Conn
+	MaxPacketSize int
+	Violations    uint32        // Oversized packets sent by the client
+	IdleTimeout   time.Duration // Longest wait for the next packet, 0 for none
+	Authenticated bool          // Set once the credentials are accepted
+	Banned        bool          // Set once the account is refused the login, for good
+	Plain         bool          // The packets go without checksum nor encryption, in the null crypto debug mode
+	GameGuard     bool          // Set once RequestGGAuth carried the session id of Init
+	RateWindow    time.Time     // Start of the second the packets of the client are counted in
+	RatePackets   int           // Packets sent by the client since RateWindow
 }
 
+var ErrChecksumMismatch = errors.New("The packet checksum doesn't look right...")
+
 func NewClient() *Client {
-	id := make([]byte, 16)
-	_, err := rand.Read(id)
+	id, err := crypt.NewSessionID(16)
 
 	if err != nil {
 		return nil
@@ -25,35 +37,56 @@ func NewClient() *Client {
 	return &Client{SessionID: id}
 }
 
-func (c *Client) Receive() (opcode byte, data []byte, e error) {
-	// Read the first two bytes to define the packet size
-	header := make([]byte, 2)
-	n, err := c.Socket.Read(header)
+// NewClientFrom creates a client whose session id is read from the random source
+func NewClientFrom(random io.Reader) *Client {
+	id, err := crypt.GenerateSessionID(random, 16)
 
-	if n != 2 || err != nil {
-		return 0x00, nil, errors.New("An error occured while reading the packet header.")
+	if err != nil {
+		return nil
 	}
+	return &Client{SessionID: id}
+}
 
-	// Calculate the packet size
-	size := 0
-	size = size + int(header[0])
-	size = size + int(header[1])*256
+// Wipe zeroes the session id and forgets the account password hash
+func (c *Client) Wipe() {
+	crypt.Wipe(c.SessionID)
+	c.Account.Password = ""
+}
 
-	// Allocate the appropriate size for our data (size - 2 bytes used for the length
-	data = make([]byte, size-2)
+func (c *Client) Receive() (opcode byte, data []byte, e error) {
+	// Read the packet, refusing oversized length headers before allocating anything
+	if c.IdleTimeout > 0 {
+		c.Socket.SetReadDeadline(time.Now().Add(c.IdleTimeout))
+	}
+
+	data, err := packets.ReadFrame(c.Socket, c.MaxPacketSize)
 
-	// Read the encrypted part of the packet
-	n, err = c.Socket.Read(data)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return 0x00, nil, packets.ErrIdleTimeout
+	}
+
+	if errors.Is(err, packets.ErrFrameTooLarge) {
+		c.Violations += 1
+		return 0x00, nil, err
+	}
 
-	if n != size-2 || err != nil {
-		return 0x00, nil, errors.New("An error occured while reading the packet data.")
+	if err != nil {
+		return 0x00, nil, errors.New("An error occured while reading the packet.")
 	}
 
 	// Print the raw packet
-	fmt.Printf("Raw packet : %X%X\n", header, data)
+	fmt.Printf("Raw packet : %X\n", data)
+
+	// The packet comes in clear in the null crypto debug mode
+	if c.Plain {
+		if len(data) == 0 {
+			return 0x00, nil, errors.New("An error occured while reading the packet.")
+		}
+		return data[0], data[1:], nil
+	}
 
 	// Decrypt the packet data using the blowfish key
-	data, err = crypt.BlowfishDecrypt(data, []byte("[;'.]94-31==-%&@!^+]\000"))
+	data, err = crypt.BlowfishDecrypt(data, crypt.StaticBlowfishKey)
 
 	if err != nil {
 		return 0x00, nil, errors.New("An error occured while decrypting the packet data.")
@@ -64,7 +97,7 @@ func (c *Client) Receive() (opcode byte, data []byte, e error) {
 		fmt.Printf("Decrypted packet content : %X\n", data)
 		fmt.Println("Packet checksum ok")
 	} else {
-		return 0x00, nil, errors.New("The packet checksum doesn't look right...")
+		return 0x00, nil, ErrChecksumMismatch
 	}
 
 	// Extract the op code
@@ -87,10 +120,12 @@ func (c *Client) Send(data []byte, params ...bool) error {
 		doBlowfish = false
 	}
 
-	if doChecksum == true {
-		// Add 4 empty bytes for the checksum new( new(
-		data = append(data, []byte{0x00, 0x00, 0x00, 0x00}...)
+	// Nothing is added in the null crypto debug mode
+	if c.Plain {
+		doChecksum, doBlowfish = false, false
+	}
 
+	if doChecksum == true {
 		// Add blowfish padding
 		missing := len(data) % 8
 
@@ -100,28 +135,33 @@ func (c *Client) Send(data []byte, params ...bool) error {
 			}
 		}
 
+		// The checksum goes 8 bytes before the end, past the data
+		data = append(data, []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}...)
+
 		// Finally do the checksum
 		crypt.Checksum(data)
 	}
 
 	if doBlowfish == true {
 		var err error
-		data, err = crypt.BlowfishEncrypt(data, []byte("[;'.]94-31==-%&@!^+]\000"))
+		data, err = crypt.BlowfishEncrypt(data, crypt.StaticBlowfishKey)
 
 		if err != nil {
 			return err
 		}
 	}
 
-	// Calculate the packet length
-	length := uint16(len(data) + 2)
-
 	// Put everything together
-	buffer := packets.NewBuffer()
-	buffer.WriteUInt16(length)
-	buffer.Write(data)
+	writer := packets.NewPacketWriter()
+	defer writer.Release()
+
+	frame, err := writer.B(data).Finalize()
+
+	if err != nil {
+		return err
+	}
 
-	_, err := c.Socket.Write(buffer.Bytes())
+	_, err = c.Socket.Write(frame)
 
 	if err != nil {
 		return errors.New("The packet couldn't be sent.")
@@ -2,46 +2,118 @@ package models
 
 import (
 	"errors"
-	"fmt"
-	"github.com/frostwind/l2go/packets"
 	"net"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/protocol"
 )
 
 type GameServer struct {
 	Id     uint8
+	Name   string
 	Socket net.Conn
+
+	lastSeen      time.Time
+	onlinePlayers uint16
+	up            bool
+	mu            sync.Mutex
+
+	compressor *protocol.Compressor // Set under the send mutex once negotiated, nil while the link isn't compressed
+	sendMutex  sync.Mutex           // Orders the packets of the link around the start of the compression
 }
 
 func NewGameServer() *GameServer {
-  return &GameServer{}
+	return &GameServer{}
 }
 
-func (g *GameServer) Receive() (opcode byte, data []byte, e error) {
-	// Read the first two bytes to define the packet size
-	header := make([]byte, 2)
-	n, err := g.Socket.Read(header)
+// Register binds the link to a configured game server and marks it as up
+func (g *GameServer) Register(id uint8, name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.Id = id
+	g.Name = name
+	g.lastSeen = time.Now()
+	g.up = true
+}
+
+// Heartbeat records a sign of life, bringing the game server back up if it was down.
+// It returns true when the game server came back.
+func (g *GameServer) Heartbeat(onlinePlayers uint16) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	cameBack := !g.up
+	g.lastSeen = time.Now()
+	g.onlinePlayers = onlinePlayers
+	g.up = true
+
+	return cameBack
+}
+
+// Demote marks the game server as down if it has been silent longer than timeout.
+// It returns true when the status changed.
+func (g *GameServer) Demote(now time.Time, timeout time.Duration) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-	if n != 2 || err != nil {
-		return 0x00, nil, errors.New("An error occured while reading the packet header.")
+	if !g.up || now.Sub(g.lastSeen) <= timeout {
+		return false
 	}
 
-	// Calculate the packet size
-	size := 0
-	size = size + int(header[0])
-	size = size + int(header[1])*256
+	g.up = false
+	return true
+}
+
+// MarkDown marks the game server as down right away, when its link is lost
+func (g *GameServer) MarkDown() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.up = false
+}
+
+// IsUp reports whether the game server is registered and sending heartbeats
+func (g *GameServer) IsUp() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.up
+}
+
+// LastSeen returns when the game server last gave a sign of life
+func (g *GameServer) LastSeen() time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lastSeen
+}
+
+// OnlinePlayers returns the players count reported by the last heartbeat
+func (g *GameServer) OnlinePlayers() uint16 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.onlinePlayers
+}
+
+func (g *GameServer) Receive() (opcode byte, data []byte, e error) {
+	data, err := packets.ReadFrame(g.Socket, packets.MaxFrameSize)
 
-	// Allocate the appropriate size for our data (size - 2 bytes used for the length
-	data = make([]byte, size-2)
+	if err != nil {
+		return 0x00, nil, errors.New("An error occured while reading the packet.")
+	}
 
-	// Read the encrypted part of the packet
-	n, err = g.Socket.Read(data)
+	// The compressor is only set by the goroutine receiving the packets
+	if g.compressor != nil {
+		data, err = g.compressor.Decompress(data)
 
-	if n != size-2 || err != nil {
-		return 0x00, nil, errors.New("An error occured while reading the packet data.")
+		if err != nil {
+			return 0x00, nil, err
+		}
 	}
 
-	// Print the raw packet
-	fmt.Printf("Raw packet : %X%X\n", header, data)
+	if len(data) == 0 {
+		return 0x00, nil, errors.New("An error occured while reading the packet.")
+	}
 
 	// Extract the op code
 	opcode = data[0]
@@ -51,15 +123,57 @@ func (g *GameServer) Receive() (opcode byte, data []byte, e error) {
 }
 
 func (g *GameServer) Send(data []byte) error {
-	// Calculate the packet length
-	length := uint16(len(data) + 2)
+	g.sendMutex.Lock()
+	defer g.sendMutex.Unlock()
+
+	return g.send(data)
+}
+
+// SendCompressing sends the last packet of the link in clear, the ones following it in both directions being
+// compressed with the negotiated options. It must be called by the goroutine receiving the packets.
+func (g *GameServer) SendCompressing(data []byte, options protocol.CompressionOptions) error {
+	g.sendMutex.Lock()
+	defer g.sendMutex.Unlock()
+
+	err := g.send(data)
+	if options.Enabled {
+		g.compressor = protocol.NewCompressor(options)
+	}
+	return err
+}
+
+// CompressionStats returns the compression metrics of the link, nothing while it isn't compressed
+func (g *GameServer) CompressionStats() protocol.CompressionStats {
+	g.sendMutex.Lock()
+	defer g.sendMutex.Unlock()
+
+	if g.compressor == nil {
+		return protocol.CompressionStats{}
+	}
+	return g.compressor.Stats()
+}
+
+func (g *GameServer) send(data []byte) error {
+	if g.compressor != nil {
+		compressed, err := g.compressor.Compress(data)
+
+		if err != nil {
+			return err
+		}
+		data = compressed
+	}
 
 	// Put everything together
-	buffer := packets.NewBuffer()
-	buffer.WriteUInt16(length)
-	buffer.Write(data)
+	writer := packets.NewPacketWriter()
+	defer writer.Release()
+
+	frame, err := writer.B(data).Finalize()
+
+	if err != nil {
+		return err
+	}
 
-	_, err := g.Socket.Write(buffer.Bytes())
+	_, err = g.Socket.Write(frame)
 
 	if err != nil {
 		return errors.New("The packet couldn't be sent.")
@@ -0,0 +1,108 @@
+// Package repository holds the storage backends of the login server
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/frostwind/l2go/loginserver/models"
+)
+
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrAccountExists   = errors.New("account already exists")
+)
+
+// AccountRepository stores the login server accounts
+type AccountRepository interface {
+	// FindByUsername returns the account matching username or ErrAccountNotFound
+	FindByUsername(username string) (models.Account, error)
+
+	// Create inserts a new account and sets its Id
+	Create(account *models.Account) error
+
+	// Close releases the underlying resources
+	Close() error
+}
+
+// MySQLAccountRepository stores accounts in the accounts table
+type MySQLAccountRepository struct {
+	db *sql.DB
+}
+
+// NewMySQLAccountRepository creates a repository backed by db
+func NewMySQLAccountRepository(db *sql.DB) *MySQLAccountRepository {
+	return &MySQLAccountRepository{db: db}
+}
+
+func (r *MySQLAccountRepository) FindByUsername(username string) (models.Account, error) {
+	var account models.Account
+	err := r.db.QueryRow("SELECT id, username, password, access_level FROM accounts WHERE username = ?", username).Scan(
+		&account.Id, &account.Username, &account.Password, &account.AccessLevel)
+
+	if err == sql.ErrNoRows {
+		return account, ErrAccountNotFound
+	}
+	return account, err
+}
+
+func (r *MySQLAccountRepository) Create(account *models.Account) error {
+	result, err := r.db.Exec("INSERT INTO accounts (username, password, access_level) VALUES (?, ?, ?)",
+		account.Username, account.Password, account.AccessLevel)
+
+	if err != nil {
+		return err
+	}
+
+	account.Id, err = result.LastInsertId()
+	return err
+}
+
+func (r *MySQLAccountRepository) Close() error {
+	return r.db.Close()
+}
+
+// MemoryAccountRepository keeps accounts in memory, mostly for tests and local runs
+type MemoryAccountRepository struct {
+	accounts map[string]models.Account
+	nextId   int64
+	mu       sync.RWMutex
+}
+
+// NewMemoryAccountRepository creates an empty in-memory repository
+func NewMemoryAccountRepository() *MemoryAccountRepository {
+	return &MemoryAccountRepository{accounts: make(map[string]models.Account)}
+}
+
+func (r *MemoryAccountRepository) FindByUsername(username string) (models.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, ok := r.accounts[strings.ToLower(username)]
+	if !ok {
+		return models.Account{}, ErrAccountNotFound
+	}
+	return account, nil
+}
+
+func (r *MemoryAccountRepository) Create(account *models.Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := strings.ToLower(account.Username)
+	if _, ok := r.accounts[key]; ok {
+		return ErrAccountExists
+	}
+
+	r.nextId++
+	account.Id = r.nextId
+	r.accounts[key] = *account
+
+	return nil
+}
+
+func (r *MemoryAccountRepository) Close() error {
+	return nil
+}
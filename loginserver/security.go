@@ -0,0 +1,87 @@
+package loginserver
+
+import (
+	"maps"
+	"sync"
+)
+
+// Types of the security events, classifying the hack attempts by what the client did
+const (
+	SECURITY_OVERSIZED_PACKET = "oversized packet"
+	SECURITY_MALFORMED_PACKET = "malformed packet"
+	SECURITY_CHECKSUM_FAILURE = "checksum failure"
+	SECURITY_SESSION_MISMATCH = "session mismatch"
+	SECURITY_INVALID_SERVER   = "invalid server id"
+	SECURITY_PACKET_AFTER_BAN = "packet after ban"
+	SECURITY_PACKET_FLOOD     = "packet flood"
+)
+
+// SECURITY_EVENTS_KEPT is how many of the latest security events are kept for the admin API
+const SECURITY_EVENTS_KEPT = 256
+
+// SecurityReport lists the latest security events, newest first, along with the count of
+// every type of event since the login server started
+type SecurityReport struct {
+	Counts map[string]uint32 `json:"counts"`
+	Events []HackAttempt     `json:"events"`
+}
+
+// securityLog keeps the latest security events and counts them by type
+type securityLog struct {
+	mu     sync.Mutex
+	events []HackAttempt // Ring buffer, whose oldest event is at next once full
+	next   int
+	counts map[string]uint32
+}
+
+func newSecurityLog(capacity int) *securityLog {
+	return &securityLog{events: make([]HackAttempt, 0, capacity), counts: make(map[string]uint32)}
+}
+
+// record keeps an event, replacing the oldest one once the log is full
+func (s *securityLog) record(event HackAttempt) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[event.Type]++
+	if len(s.events) < cap(s.events) {
+		s.events = append(s.events, event)
+		return
+	}
+	s.events[s.next] = event
+	s.next = (s.next + 1) % len(s.events)
+}
+
+// recent returns at most limit of the latest events of a type, newest first. An empty type
+// matches every event and a limit of 0 or less returns them all.
+func (s *securityLog) recent(eventType string, limit int) []HackAttempt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]HackAttempt, 0)
+	for i := range s.events {
+		event := s.events[(s.next+len(s.events)-1-i)%len(s.events)]
+		if eventType != "" && event.Type != eventType {
+			continue
+		}
+		if limit > 0 && len(events) == limit {
+			break
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// byType returns the count of every type of event recorded
+func (s *securityLog) byType() map[string]uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return maps.Clone(s.counts)
+}
+
+// SecurityEvents returns the latest security events of a type, or of every type when it is
+// empty, newest first, with the count of every type of event
+func (l *LoginServer) SecurityEvents(eventType string, limit int) SecurityReport {
+	return SecurityReport{Counts: l.security.byType(), Events: l.security.recent(eventType, limit)}
+}
@@ -0,0 +1,49 @@
+package loginserver
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSecurityLog(t *testing.T) {
+	log := newSecurityLog(3)
+	for _, event := range []HackAttempt{
+		{Type: SECURITY_CHECKSUM_FAILURE, Reason: "1"},
+		{Type: SECURITY_SESSION_MISMATCH, Reason: "2"},
+		{Type: SECURITY_CHECKSUM_FAILURE, Reason: "3"},
+		{Type: SECURITY_INVALID_SERVER, Reason: "4"},
+		{Type: SECURITY_CHECKSUM_FAILURE, Reason: "5"},
+	} {
+		log.record(event)
+	}
+
+	tests := []struct {
+		name      string
+		eventType string
+		limit     int
+		want      []string // Reasons of the events, newest first
+	}{
+		{name: "all", want: []string{"5", "4", "3"}},
+		{name: "limited", limit: 2, want: []string{"5", "4"}},
+		{name: "by type", eventType: SECURITY_CHECKSUM_FAILURE, want: []string{"5", "3"}},
+		{name: "by type limited", eventType: SECURITY_CHECKSUM_FAILURE, limit: 1, want: []string{"5"}},
+		{name: "type dropped from the log", eventType: SECURITY_SESSION_MISMATCH, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons := []string{}
+			for _, event := range log.recent(tt.eventType, tt.limit) {
+				reasons = append(reasons, event.Reason)
+			}
+			if !slices.Equal(reasons, tt.want) {
+				t.Errorf("recent(%q, %d) = %v, want %v", tt.eventType, tt.limit, reasons, tt.want)
+			}
+		})
+	}
+
+	counts := log.byType()
+	if counts[SECURITY_CHECKSUM_FAILURE] != 3 || counts[SECURITY_SESSION_MISMATCH] != 1 || counts[SECURITY_INVALID_SERVER] != 1 {
+		t.Errorf("byType() = %v, the events dropped from the log still counting", counts)
+	}
+}
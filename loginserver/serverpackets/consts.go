@@ -12,3 +12,7 @@ const (
 	REASON_EXPIRED            = 0x12
 	REASON_NO_TIME_LEFT       = 0x13
 )
+
+const (
+	REASON_TOO_MANY_PLAYERS = 0x0f
+)
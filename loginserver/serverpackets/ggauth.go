@@ -0,0 +1,19 @@
+package serverpackets
+
+import (
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// NewGGAuthPacket answers RequestGGAuth, response being the session id the client sent
+func NewGGAuthPacket(response uint32) []byte {
+	buffer := new(packets.Buffer)
+	buffer.WriteByte(opcodes.LoginServerGGAuth)
+	buffer.WriteUInt32(response)
+	buffer.WriteUInt32(0x00)
+	buffer.WriteUInt32(0x00)
+	buffer.WriteUInt32(0x00)
+	buffer.WriteUInt32(0x00)
+
+	return buffer.Bytes()
+}
@@ -1,12 +1,13 @@
 package serverpackets
 
 import (
+	"github.com/frostwind/l2go/opcodes"
 	"github.com/frostwind/l2go/packets"
 )
 
 func NewPlayFailPacket(reason uint32) []byte {
 	buffer := new(packets.Buffer)
-	buffer.WriteByte(0x06) // Packet type: PlayFail
+	buffer.WriteByte(opcodes.LoginServerPlayFail)
 	buffer.WriteUInt32(reason)
 
 	return buffer.Bytes()
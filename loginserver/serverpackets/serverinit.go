@@ -1,14 +1,22 @@
 package serverpackets
 
 import (
+	"github.com/frostwind/l2go/opcodes"
 	"github.com/frostwind/l2go/packets"
 )
 
-func NewInitPacket() []byte {
+// INIT_SESSION_ID is the session id sent in Init, which the clients send back in RequestGGAuth
+const INIT_SESSION_ID uint32 = 0x03ed779c
+
+// NewInitPacket returns the first packet sent to a client, announcing the null crypto debug mode when plain
+func NewInitPacket(plain bool) []byte {
 	buffer := new(packets.Buffer)
-	buffer.WriteByte(0x00)                       // Packet type: Init
-	buffer.Write([]byte{0x9c, 0x77, 0xed, 0x03}) // Session id?
+	buffer.WriteByte(opcodes.LoginServerInit)
+	buffer.WriteUInt32(INIT_SESSION_ID)
 	buffer.Write([]byte{0x5a, 0x78, 0x00, 0x00}) // Protocol version : 785a
+	if plain {
+		buffer.WriteUInt32(opcodes.NullCryptoMarker)
+	}
 
 	return buffer.Bytes()
 }
@@ -2,20 +2,54 @@ package serverpackets
 
 import (
 	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/opcodes"
 	"github.com/frostwind/l2go/packets"
 	"net"
 )
 
-func NewServerListPacket(gameServers []config.GameServerType, remoteAddr string) []byte {
+// ServerStatus is the live state of a game server, as reported by its heartbeats
+type ServerStatus struct {
+	Up            bool
+	OnlinePlayers uint16
+}
+
+// SERVER_ENTRY_SIZE is the size of a game server in the list
+const SERVER_ENTRY_SIZE = 20
+
+// NewServerListPackets lists the game servers in packets of at most maxSize bytes, packets.DefaultPageSize
+// when 0. A single packet is enough for any realistic cluster, the byte after the count flagging the
+// pages followed by another with packets.MorePages otherwise.
+func NewServerListPackets(gameServers []config.GameServerType, statuses []ServerStatus, remoteAddr string, maxSize int) [][]byte {
+	indexes := make([]int, len(gameServers))
+	for index := range indexes {
+		indexes[index] = index
+	}
+	pages := packets.Paginate(indexes, packets.PerPage(maxSize, 3, SERVER_ENTRY_SIZE, 0xff))
+
+	buffers := make([][]byte, 0, len(pages))
+	for _, page := range pages {
+		buffers = append(buffers, newServerListPacket(gameServers, statuses, remoteAddr, page))
+	}
+	return buffers
+}
+
+// newServerListPacket lists the game servers of a page, by index
+func newServerListPacket(gameServers []config.GameServerType, statuses []ServerStatus, remoteAddr string, page packets.Page[int]) []byte {
 	buffer := new(packets.Buffer)
-	buffer.WriteByte(0x04)
-	buffer.WriteUInt8(uint8(len(gameServers))) // Servers count
-	buffer.WriteByte(0x00)                     // Unused
+	buffer.WriteByte(opcodes.LoginServerServerList)
+	buffer.WriteUInt8(uint8(len(page.Items))) // Servers count
+	buffer.WriteByte(page.Flag)               // Another page follows
 
 	network, _, _ := net.SplitHostPort(remoteAddr)
 
 	// Server Data (Repeat for each server)
-	for index, gameserver := range gameServers {
+	for _, index := range page.Items {
+		gameserver := gameServers[index]
+		var status ServerStatus
+		if index < len(statuses) {
+			status = statuses[index]
+		}
+
 		var ip net.IP
 		if network == "127.0.0.1" {
 			ip = net.ParseIP(gameserver.InternalIP).To4()
@@ -31,10 +65,12 @@ func NewServerListPacket(gameServers []config.GameServerType, remoteAddr string)
 		buffer.WriteUInt32(uint32(gameserver.Port))       // Server port number
 		buffer.WriteByte(0x0f)                            // Age limit
 		buffer.WriteByte(0x01)                            // Is pvp allowed?
-		buffer.WriteUInt16(0)                             // How many players are online
+		buffer.WriteUInt16(status.OnlinePlayers)          // How many players are online
 		buffer.WriteUInt16(gameserver.Options.MaxPlayers) // Maximum allowed players
 		if gameserver.Options.Testing == true {           // Is this a testing server?
 			buffer.WriteByte(0x00)
+		} else if status.Up == false { // Is the server down?
+			buffer.WriteByte(0x00)
 		} else {
 			buffer.WriteByte(0x01)
 		}
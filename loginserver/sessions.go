@@ -0,0 +1,83 @@
+package loginserver
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/loginserver/models"
+	"github.com/frostwind/l2go/loginserver/serverpackets"
+)
+
+// SESSION_SWEEP_INTERVAL is how often the clients whose session expired are disconnected
+const SESSION_SWEEP_INTERVAL = 10 * time.Second
+
+// SetClock replaces the clock the sessions expire on, before Start
+func (l *LoginServer) SetClock(c clock.Clock) {
+	l.clock = c
+}
+
+// sessionExpired reports whether the session id of a client can't be used anymore, counting the session
+// as expired the first time
+func (l *LoginServer) sessionExpired(client *models.Client) bool {
+	l.clientsMutex.Lock()
+	defer l.clientsMutex.Unlock()
+
+	session, ok := l.sessions[client]
+	if !ok {
+		return false
+	}
+	return l.expire(session, l.clock.Now())
+}
+
+// expire marks a session as expired once its lifetime is over, reporting whether it is expired.
+// The clients mutex must be held.
+func (l *LoginServer) expire(session *SessionInfo, now time.Time) bool {
+	if session.Expired {
+		return true
+	}
+	if session.ExpiresAt.IsZero() || now.Before(session.ExpiresAt) {
+		return false
+	}
+
+	session.Expired = true
+	atomic.AddUint32(&l.status.expiredSessions, 1)
+	return true
+}
+
+// refuseExpired tells a client its session expired, before it is disconnected
+func (l *LoginServer) refuseExpired(client *models.Client) {
+	fmt.Printf("The session of %s expired\n", clientAddress(client))
+	if err := client.Send(serverpackets.NewLoginFailPacket(serverpackets.REASON_EXPIRED)); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// sweepSessions disconnects the clients whose session expired before they went to a game server,
+// until the login server stops
+func (l *LoginServer) sweepSessions() {
+	for {
+		timer := l.clock.NewTimer(SESSION_SWEEP_INTERVAL)
+		select {
+		case <-l.stop:
+			timer.Stop()
+			return
+		case <-timer.C():
+			now := l.clock.Now()
+			l.clientsMutex.Lock()
+			var expired []*models.Client
+			for client, session := range l.sessions {
+				if !session.Expired && l.expire(session, now) {
+					expired = append(expired, client)
+				}
+			}
+			l.clientsMutex.Unlock()
+
+			for _, client := range expired {
+				fmt.Printf("The session of %s expired\n", clientAddress(client))
+				client.Socket.Close()
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package loginserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/loginserver/models"
+)
+
+func TestSweepSessions(t *testing.T) {
+	l := New(config.ConfigObject{LoginServer: config.LoginServerType{SessionLifetime: time.Minute}})
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	l.SetClock(fake)
+
+	stale, fresh := models.NewClient(), models.NewClient()
+	var remote net.Conn
+	stale.Socket, remote = net.Pipe()
+	defer remote.Close()
+	fresh.Socket, _ = net.Pipe()
+
+	l.sessions[stale] = &SessionInfo{}
+	l.sessions[fresh] = &SessionInfo{}
+	l.loggedIn(stale, "stale")
+
+	go l.sweepSessions()
+	defer close(l.stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := fake.WaitForTimers(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(30 * time.Second)
+	l.loggedIn(fresh, "fresh")
+	if err := fake.WaitForTimers(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(40 * time.Second)
+
+	// The sweeper closing the socket of the stale client ends the reads on the other side
+	remote.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := remote.Read(make([]byte, 1)); err == nil || isTimeout(err) {
+		t.Fatalf("the stale client wasn't disconnected: %v", err)
+	}
+	if l.sessionExpired(fresh) {
+		t.Error("the session logged in 40s ago expired")
+	}
+	if expired := l.Stats().ExpiredSessions; expired != 1 {
+		t.Errorf("ExpiredSessions = %d, want 1", expired)
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
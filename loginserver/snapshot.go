@@ -0,0 +1,70 @@
+package loginserver
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/loginserver/models"
+)
+
+// SessionInfo is a client connected to the login server, as dumped by the snapshot
+type SessionInfo struct {
+	Address     string    `json:"address"`
+	Username    string    `json:"username,omitempty"` // Empty until the client logs in
+	LoggedIn    bool      `json:"loggedIn"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	ExpiresAt   time.Time `json:"expiresAt,omitzero"` // When the session id stops being accepted, zero for never
+	Expired     bool      `json:"expired,omitempty"`
+}
+
+// Snapshot is the state of the running login server, for the post-mortem analysis of a stuck load test
+type Snapshot struct {
+	TakenAt     time.Time        `json:"takenAt"`
+	Mode        string           `json:"mode"`
+	Stats       Stats            `json:"stats"`
+	Sessions    []SessionInfo    `json:"sessions"` // Oldest first
+	GameServers []GameServerInfo `json:"gameServers"`
+}
+
+// Snapshot returns the clients connected to the login server and the game servers registered with it
+func (l *LoginServer) Snapshot() Snapshot {
+	l.clientsMutex.Lock()
+	sessions := make([]SessionInfo, 0, len(l.sessions))
+	for _, session := range l.sessions {
+		sessions = append(sessions, *session)
+	}
+	l.clientsMutex.Unlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].ConnectedAt.Equal(sessions[j].ConnectedAt) {
+			return sessions[i].ConnectedAt.Before(sessions[j].ConnectedAt)
+		}
+		return sessions[i].Address < sessions[j].Address
+	})
+
+	return Snapshot{
+		TakenAt:     time.Now(),
+		Mode:        l.Mode(),
+		Stats:       l.Stats(),
+		Sessions:    sessions,
+		GameServers: l.GameServers(),
+	}
+}
+
+// loggedIn records the account a client logged in with, issues its session id for the session lifetime,
+// and gives the client the idle timeout of the authenticated ones
+func (l *LoginServer) loggedIn(client *models.Client, username string) {
+	l.authenticate(client)
+	atomic.AddUint32(&l.status.successfulLogins, 1)
+
+	l.clientsMutex.Lock()
+	defer l.clientsMutex.Unlock()
+
+	if session, ok := l.sessions[client]; ok {
+		session.Username, session.LoggedIn = username, true
+		if ttl := l.config.LoginServer.SessionTTL(); ttl > 0 {
+			session.ExpiresAt = l.clock.Now().Add(ttl)
+		}
+	}
+}
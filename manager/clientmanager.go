@@ -1,23 +1,54 @@
 package manager
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/diagnostics"
+	"github.com/frostwind/l2go/random"
 )
 
 // Manager implements the ClientManager interface
 type Manager struct {
-	clients      map[string]client.GameClient
+	clients      *registry
 	config       *client.ManagerConfig
 	metrics      *client.ConnectionMetrics
+	published    atomic.Pointer[client.ConnectionMetrics] // Snapshot of the metrics, read without locking
+	publishMu    sync.Mutex
 	eventBus     *client.EventBus
+	runs         map[string]*scenarioRun
+	runsMu       sync.Mutex
+	clock        clock.Clock   // Times the scenarios
+	random       random.Source // Randomizes the scenarios
 	shutdownChan chan struct{}
 	wg           sync.WaitGroup
-	mu           sync.RWMutex
+	mu           sync.RWMutex // Guards isShutdown, the clients being added and started under the read lock
 	isShutdown   bool
+	diagnostics  *diagnostics.Server
+	factory      client.ClientFactory            // Creates the clients of CreateClients
+	groups       map[string]client.ClientFactory // Create the clients of CreateGroup, by group
+	factoriesMu  sync.RWMutex
+	created      atomic.Int64 // Clients created in a group, numbering their ids
+
+	summary       *summaryRecorder
+	summaryOutput io.Writer // Where Shutdown logs the summary, if anywhere
+	summaryMu     sync.Mutex
+
+	accounts *client.AccountPool  // Lends the accounts of the clients, if the configuration lists any
+	sources  *client.SourceBinder // Binds the connections of the clients to source addresses, if the configuration lists any
+	reactor  *client.Reactor      // Reads the connections of the spectators, with the reactor backend
+
+	errorTracker    *client.ErrorTracker // Checks the failures of the connections and the scenarios against their budgets
+	budgetExhausted []string             // Categories over their budget as of the last check
+	budgetMu        sync.Mutex
 }
 
 // NewManager creates a new client manager
@@ -33,12 +64,37 @@ func NewManager(config *client.ManagerConfig) *Manager {
 	}
 
 	manager := &Manager{
-		clients:      make(map[string]client.GameClient),
+		clients:      newRegistry(),
 		config:       config,
 		metrics:      &client.ConnectionMetrics{},
 		eventBus:     client.NewEventBus(),
+		runs:         make(map[string]*scenarioRun),
+		clock:        clock.Real{},
+		random:       random.Crypto(),
 		shutdownChan: make(chan struct{}),
+		factory:      NewGameClient,
+		groups:       make(map[string]client.ClientFactory),
+
+		summary:       newSummaryRecorder(),
+		summaryOutput: os.Stderr,
+
+		errorTracker: client.NewErrorTracker(config.ErrorBudgets),
+	}
+	if config.AccountPool.Enabled() {
+		manager.accounts = client.NewAccountPool(config.AccountPool)
+	}
+	if len(config.SourceAddresses) > 0 {
+		manager.sources = client.NewSourceBinder(config.SourceAddresses, config.ReusePort)
+	}
+	if config.ConnectionBackend == client.BackendReactor {
+		reactor, err := client.NewReactor(config.ReactorWorkers)
+		if err != nil {
+			fmt.Printf("Falling back to a goroutine per connection: %v\n", err)
+		}
+		manager.reactor = reactor
 	}
+	manager.metrics.SetErrorBudget(manager.errorTracker.Status())
+	manager.publishMetrics()
 
 	// Start health check routine
 	manager.startHealthCheck()
@@ -46,6 +102,79 @@ func NewManager(config *client.ManagerConfig) *Manager {
 	return manager
 }
 
+// StartDiagnostics publishes the metrics of the manager under its namespace, and serves the pprof profiles
+// and the expvar variables of the toolkit if the configuration enables them. They stop with the manager.
+func (m *Manager) StartDiagnostics() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isShutdown {
+		return client.ErrClientManagerClosed
+	}
+	if m.diagnostics != nil {
+		return nil
+	}
+
+	namespace := m.config.Namespace
+	if namespace == "" {
+		namespace = "manager"
+	}
+
+	// The metrics are published even when another manager of the process serves the diagnostics
+	diagnostics.Publish(namespace, func() any { return m.GetMetrics() })
+
+	server, err := diagnostics.Start(namespace, m.config.Diagnostics)
+	if err != nil || server == nil {
+		return err
+	}
+	m.diagnostics = server
+	return nil
+}
+
+// DiagnosticsAddr returns the address the diagnostics are served on, or nil if they aren't
+func (m *Manager) DiagnosticsAddr() net.Addr {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.diagnostics == nil {
+		return nil
+	}
+	return m.diagnostics.Addr()
+}
+
+// SetClock replaces the clock the scenarios wait on, for deterministic tests
+func (m *Manager) SetClock(c clock.Clock) {
+	m.runsMu.Lock()
+	defer m.runsMu.Unlock()
+	m.clock = c
+}
+
+// SetRandSource replaces the source of the random think-times of the scenarios, for deterministic tests
+func (m *Manager) SetRandSource(source random.Source) {
+	m.runsMu.Lock()
+	defer m.runsMu.Unlock()
+	m.random = source
+}
+
+// SetClientFactory replaces the factory creating the clients of CreateClients, the mock NewGameClient by default
+func (m *Manager) SetClientFactory(factory client.ClientFactory) {
+	m.factoriesMu.Lock()
+	defer m.factoriesMu.Unlock()
+	m.factory = factory
+}
+
+// RegisterClientFactory names the factory creating the clients of a group, for CreateGroup
+func (m *Manager) RegisterClientFactory(group string, factory client.ClientFactory) error {
+	m.factoriesMu.Lock()
+	defer m.factoriesMu.Unlock()
+
+	if _, ok := m.groups[group]; ok {
+		return fmt.Errorf("%w: %s", client.ErrClientGroupExists, group)
+	}
+	m.groups[group] = factory
+	return nil
+}
+
 // Start starts the manager and its background routines
 func (m *Manager) Start() error {
 	m.mu.Lock()
@@ -63,16 +192,43 @@ func (m *Manager) Start() error {
 
 // CreateClients creates the specified number of clients with the given configuration
 func (m *Manager) CreateClients(count int, config client.ClientConfig) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.factoriesMu.RLock()
+	factory := m.factory
+	m.factoriesMu.RUnlock()
+
+	now := time.Now().Unix()
+	return m.createClients(count, config, factory, func(i int) string {
+		return fmt.Sprintf("client-%d-%d", now, i)
+	})
+}
 
-	if m.isShutdown {
-		return client.ErrClientManagerClosed
+// CreateGroup creates the specified number of clients of a group, with the factory registered for it.
+// Their ids are the name of the group followed by a number.
+func (m *Manager) CreateGroup(group string, count int, config client.ClientConfig) ([]string, error) {
+	m.factoriesMu.RLock()
+	factory, ok := m.groups[group]
+	m.factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", client.ErrUnknownClientGroup, group)
 	}
 
-	// Check if we would exceed the maximum number of clients
-	if len(m.clients)+count > m.config.MaxClients {
-		return client.ErrMaxClientsReached
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s-%d", group, m.created.Add(1))
+	}
+	if err := m.createClients(count, config, factory, func(i int) string { return ids[i] }); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// createClients creates count clients with a factory, the i-th one with the id returned by id
+func (m *Manager) createClients(count int, config client.ClientConfig, factory client.ClientFactory, id func(i int) string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.isShutdown {
+		return client.ErrClientManagerClosed
 	}
 
 	// Validate the client configuration
@@ -80,28 +236,31 @@ func (m *Manager) CreateClients(count int, config client.ClientConfig) error {
 		return fmt.Errorf("invalid client configuration: %w", err)
 	}
 
+	// Check if we would exceed the maximum number of clients
+	if err := m.clients.reserve(count, m.config.MaxClients); err != nil {
+		return err
+	}
+
 	// Create clients
 	for i := 0; i < count; i++ {
-		clientID := fmt.Sprintf("client-%d-%d", time.Now().Unix(), i)
+		gameClient := factory(id(i), config)
 
 		// Check if client already exists (shouldn't happen with timestamp-based IDs)
-		if _, exists := m.clients[clientID]; exists {
-			return client.ErrClientAlreadyExists
+		if err := m.clients.put(gameClient); err != nil {
+			m.clients.release(count - i)
+			return err
 		}
 
-		// Create new client (this would be implemented in the actual GameClient)
-		gameClient := NewGameClient(clientID, config)
-		m.clients[clientID] = gameClient
+		m.attach(gameClient)
+		m.countAdded(gameClient)
 	}
-
-	// Update metrics
-	m.updateMetrics()
+	m.summary.added(count)
 
 	return nil
 }
 
-// StartClients starts the specified clients
-func (m *Manager) StartClients(clientIDs []string) error {
+// AddClient manages an already created client, under its own id
+func (m *Manager) AddClient(gameClient client.GameClient) error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -109,36 +268,86 @@ func (m *Manager) StartClients(clientIDs []string) error {
 		return client.ErrClientManagerClosed
 	}
 
+	if err := m.clients.reserve(1, m.config.MaxClients); err != nil {
+		return err
+	}
+	if err := m.clients.put(gameClient); err != nil {
+		m.clients.release(1)
+		return err
+	}
+
+	m.attach(gameClient)
+	m.countAdded(gameClient)
+	m.summary.added(1)
+
+	return nil
+}
+
+// attach has a new client publish its state transitions on the bus of the manager, borrow its
+// accounts from the pool of the manager and bind its connections to its source addresses, if it can
+func (m *Manager) attach(gameClient client.GameClient) {
+	if tracked, ok := gameClient.(interface{ StateMachine() *client.StateMachine }); ok {
+		tracked.StateMachine().SetEventBus(m.eventBus)
+	}
+	if pooled, ok := gameClient.(interface {
+		SetAccountPool(pool *client.AccountPool)
+	}); ok && m.accounts != nil {
+		pooled.SetAccountPool(m.accounts)
+	}
+	if bound, ok := gameClient.(interface {
+		SetSourceBinder(binder *client.SourceBinder)
+	}); ok && m.sources != nil {
+		bound.SetSourceBinder(m.sources)
+	}
+}
+
+// AccountPool returns the pool the clients borrow their accounts from, or nil if the configuration lists none
+func (m *Manager) AccountPool() *client.AccountPool {
+	return m.accounts
+}
+
+// StartClients starts the specified clients. No lock is held while waiting between
+// the connections, and the manager shutting down stops the clients left to start.
+func (m *Manager) StartClients(clientIDs []string) error {
 	var errors []error
 
-	for _, clientID := range clientIDs {
-		gameClient, exists := m.clients[clientID]
+	for i, clientID := range clientIDs {
+		gameClient, exists := m.clients.get(clientID)
 		if !exists {
 			errors = append(errors, fmt.Errorf("client %s: %w", clientID, client.ErrClientNotFound))
 			continue
 		}
 
-		// Start client in a goroutine
+		// Start client in a goroutine, unless the manager shut down since the previous one
+		m.mu.RLock()
+		if m.isShutdown {
+			m.mu.RUnlock()
+			return client.ErrClientManagerClosed
+		}
 		m.wg.Add(1)
+		m.mu.RUnlock()
+
 		go func(id string, gc client.GameClient) {
 			defer m.wg.Done()
 
-			if err := gc.Connect(); err != nil {
-				m.eventBus.Publish("client.error", map[string]interface{}{
-					"clientID": id,
-					"error":    err,
-					"action":   "connect",
-				})
+			start := time.Now()
+			err := gc.Connect()
+			m.RecordConnect(gc, time.Since(start), err)
+			if err != nil {
+				m.eventBus.Emit(client.NewClientError(id, "connect", err))
 			} else {
-				m.eventBus.Publish("client.connected", map[string]interface{}{
-					"clientID": id,
-				})
+				m.eventBus.Emit(client.ClientConnected{ClientID: id, At: time.Now()})
+				m.spectate(id, gc)
 			}
 		}(clientID, gameClient)
 
 		// Add delay between connections if configured
-		if m.config.ConnectInterval > 0 {
-			time.Sleep(m.config.ConnectInterval)
+		if m.config.ConnectInterval > 0 && i < len(clientIDs)-1 {
+			select {
+			case <-time.After(m.config.ConnectInterval):
+			case <-m.shutdownChan:
+				return client.ErrClientManagerClosed
+			}
 		}
 	}
 
@@ -149,6 +358,36 @@ func (m *Manager) StartClients(clientIDs []string) error {
 	return nil
 }
 
+// spectate keeps a spectator watching the world until it is stopped, its world events
+// being published on the bus of the manager. With the reactor backend, the reactor reads
+// what the spectator sees instead of the goroutine of the connection.
+func (m *Manager) spectate(id string, gc client.GameClient) {
+	spectator, ok := gc.(interface {
+		IsSpectator() bool
+		Spectate(ctx context.Context) error
+	})
+	if !ok || !spectator.IsSpectator() {
+		return
+	}
+
+	var err error
+	if multiplexed, ok := gc.(interface {
+		SpectateOn(reactor *client.Reactor) error
+	}); ok && m.reactor != nil {
+		err = multiplexed.SpectateOn(m.reactor)
+	} else {
+		err = spectator.Spectate(context.Background())
+	}
+	if err != nil {
+		m.eventBus.Emit(client.NewClientError(id, "spectate", err))
+	}
+}
+
+// Reactor returns the reactor reading the connections of the spectators, or nil without the reactor backend
+func (m *Manager) Reactor() *client.Reactor {
+	return m.reactor
+}
+
 // StopClients stops the specified clients
 func (m *Manager) StopClients(clientIDs []string) error {
 	m.mu.RLock()
@@ -161,7 +400,7 @@ func (m *Manager) StopClients(clientIDs []string) error {
 	var errors []error
 
 	for _, clientID := range clientIDs {
-		gameClient, exists := m.clients[clientID]
+		gameClient, exists := m.clients.get(clientID)
 		if !exists {
 			errors = append(errors, fmt.Errorf("client %s: %w", clientID, client.ErrClientNotFound))
 			continue
@@ -171,9 +410,7 @@ func (m *Manager) StopClients(clientIDs []string) error {
 		if err := gameClient.Disconnect(); err != nil {
 			errors = append(errors, fmt.Errorf("failed to stop client %s: %w", clientID, err))
 		} else {
-			m.eventBus.Publish("client.disconnected", map[string]interface{}{
-				"clientID": clientID,
-			})
+			m.eventBus.Emit(client.ClientDisconnected{ClientID: clientID, At: time.Now()})
 		}
 	}
 
@@ -189,10 +426,7 @@ func (m *Manager) StopClients(clientIDs []string) error {
 
 // GetClient retrieves a client by ID
 func (m *Manager) GetClient(clientID string) (client.GameClient, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	gameClient, exists := m.clients[clientID]
+	gameClient, exists := m.clients.get(clientID)
 	if !exists {
 		return nil, client.ErrClientNotFound
 	}
@@ -202,38 +436,25 @@ func (m *Manager) GetClient(clientID string) (client.GameClient, error) {
 
 // GetAllClients returns all managed clients
 func (m *Manager) GetAllClients() map[string]client.GameClient {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
 	// Return a copy to prevent external modification
-	clients := make(map[string]client.GameClient)
-	for id, gameClient := range m.clients {
-		clients[id] = gameClient
-	}
-
-	return clients
+	return m.clients.all()
 }
 
-// GetMetrics returns connection metrics
+// GetMetrics returns connection metrics, as of their last update. It doesn't wait for
+// the updates, the published snapshots never being written to.
 func (m *Manager) GetMetrics() *client.ConnectionMetrics {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	snapshot := m.published.Load().GetSnapshot()
+	return &snapshot
+}
 
-	return &client.ConnectionMetrics{
-		TotalConnections:   m.metrics.TotalConnections,
-		ActiveConnections:  m.metrics.ActiveConnections,
-		FailedConnections:  m.metrics.FailedConnections,
-		AverageConnectTime: m.metrics.AverageConnectTime,
-		LastUpdateTime:     m.metrics.LastUpdateTime,
-	}
+// EventBus returns the bus the manager publishes the client events on
+func (m *Manager) EventBus() *client.EventBus {
+	return m.eventBus
 }
 
 // GetClientStatus returns the status of a specific client
 func (m *Manager) GetClientStatus(clientID string) (*client.ClientStatus, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	gameClient, exists := m.clients[clientID]
+	gameClient, exists := m.clients.get(clientID)
 	if !exists {
 		return nil, client.ErrClientNotFound
 	}
@@ -251,7 +472,7 @@ func (m *Manager) GetClientStatus(clientID string) (*client.ClientStatus, error)
 	return status, nil
 }
 
-// Shutdown gracefully shuts down all clients and the manager
+// Shutdown gracefully shuts down all clients and the manager, then logs the summary of the run
 func (m *Manager) Shutdown() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -263,9 +484,20 @@ func (m *Manager) Shutdown() error {
 	m.isShutdown = true
 	close(m.shutdownChan)
 
-	// Stop all clients
+	if m.diagnostics != nil {
+		m.diagnostics.Close()
+	}
+
+	// Stop all scenarios
+	m.runsMu.Lock()
+	for _, run := range m.runs {
+		run.cancel()
+	}
+	m.runsMu.Unlock()
+
+	// Stop all clients, clearing the registry
 	var errors []error
-	for clientID, gameClient := range m.clients {
+	for clientID, gameClient := range m.clients.clear() {
 		if err := gameClient.Disconnect(); err != nil {
 			errors = append(errors, fmt.Errorf("failed to disconnect client %s: %w", clientID, err))
 		}
@@ -273,13 +505,18 @@ func (m *Manager) Shutdown() error {
 
 	// Wait for all goroutines to finish
 	m.wg.Wait()
-
-	// Clear clients map
-	m.clients = make(map[string]client.GameClient)
+	if m.reactor != nil {
+		m.reactor.Close()
+	}
 
 	// Update metrics
 	m.updateMetrics()
 
+	// Sum the run up, now that no client is left to record anything
+	if err := m.reportSummary(); err != nil {
+		errors = append(errors, err)
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("errors during shutdown: %v", errors)
 	}
@@ -287,22 +524,67 @@ func (m *Manager) Shutdown() error {
 	return nil
 }
 
-// updateMetrics updates the connection metrics
+// updateMetrics updates the connection metrics, going over the states of all the clients
 func (m *Manager) updateMetrics() {
-	var active, failed int64
-	total := int64(len(m.clients))
+	var total, active, failed int64
 
-	for _, gameClient := range m.clients {
-		state := gameClient.GetState()
-		switch state {
-		case client.StateInGame, client.StateConnectingLogin, client.StateAuthenticating, client.StateSelectingServer, client.StateConnectingGame:
+	for _, gameClient := range m.clients.all() {
+		total++
+		switch classify(gameClient.GetState()) {
+		case stateActive:
 			active++
-		case client.StateError:
+		case stateFailed:
 			failed++
 		}
 	}
 
 	m.metrics.Update(total, active, failed, 0) // AverageConnectTime would be calculated from actual connection times
+	if m.sources != nil {
+		m.metrics.SetSourceAddresses(m.sources.Stats())
+	}
+	m.publishMetrics()
+}
+
+// countAdded counts a newly added client in the metrics, without going over the others
+func (m *Manager) countAdded(gameClient client.GameClient) {
+	var active, failed int64
+	switch classify(gameClient.GetState()) {
+	case stateActive:
+		active = 1
+	case stateFailed:
+		failed = 1
+	}
+
+	m.metrics.AddConnections(1, active, failed)
+	m.publishMetrics()
+}
+
+// publishMetrics makes the current metrics the ones GetMetrics returns. The snapshots are
+// published one at a time, so a newer one is never replaced by an older one.
+func (m *Manager) publishMetrics() {
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	snapshot := m.metrics.GetSnapshot()
+	m.published.Store(&snapshot)
+}
+
+// Kinds of client states, as counted by the metrics
+const (
+	stateIdle = iota
+	stateActive
+	stateFailed
+)
+
+// classify tells whether a client state counts as an active or a failed connection
+func classify(state client.ClientState) int {
+	switch state {
+	case client.StateInGame, client.StateConnectingLogin, client.StateAuthenticating, client.StateSelectingServer, client.StateConnectingGame:
+		return stateActive
+	case client.StateError:
+		return stateFailed
+	}
+	return stateIdle
 }
 
 // startHealthCheck starts the health check routine
@@ -327,31 +609,35 @@ func (m *Manager) startHealthCheck() {
 
 // performHealthCheck performs health checks on all clients
 func (m *Manager) performHealthCheck() {
-	m.mu.RLock()
-	clients := make(map[string]client.GameClient)
-	for id, gameClient := range m.clients {
-		clients[id] = gameClient
-	}
-	m.mu.RUnlock()
+	clients := m.clients.all()
 
+	stuck := make(map[string]int64)
 	for clientID, gameClient := range clients {
 		state := gameClient.GetState()
 		if state == client.StateError {
-			m.eventBus.Publish("client.health.error", map[string]interface{}{
-				"clientID": clientID,
-				"state":    state,
-			})
+			m.eventBus.Emit(client.HealthDegraded{ClientID: clientID, State: state, At: time.Now()})
+		}
+
+		// Only the clients tracking their transitions know since when they are in their state
+		tracked, ok := gameClient.(interface{ StateMachine() *client.StateMachine })
+		if !ok {
+			continue
+		}
+		limit := m.config.StuckAfter[state.String()]
+		if dwell := time.Since(tracked.StateMachine().EnteredAt(state)); limit > 0 && dwell > limit {
+			stuck[state.String()]++
+			m.eventBus.Emit(client.HealthDegraded{ClientID: clientID, State: state, Stuck: true, Dwell: dwell, At: time.Now()})
 		}
 	}
 
 	// Update metrics after health check
-	m.mu.Lock()
+	m.metrics.SetStuck(stuck)
 	m.updateMetrics()
-	m.mu.Unlock()
+	m.checkErrorBudgets()
 }
 
-// NewGameClient creates a new game client (placeholder implementation)
-// This would be replaced with the actual GameClient implementation
+// NewGameClient creates a mock game client, the default factory of the managers.
+// SetClientFactory replaces it with client.NewClient or any other implementation.
 func NewGameClient(id string, config client.ClientConfig) client.GameClient {
 	return &MockGameClient{
 		id:     id,
@@ -399,6 +685,10 @@ func (m *MockGameClient) GetCharacterList() ([]client.CharacterInfo, error) {
 	return nil, nil
 }
 
+func (m *MockGameClient) CharacterSlotsLeft() (int, error) {
+	return -1, nil
+}
+
 func (m *MockGameClient) Disconnect() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -406,6 +696,18 @@ func (m *MockGameClient) Disconnect() error {
 	return nil
 }
 
+func (m *MockGameClient) Logout() error {
+	return m.Disconnect()
+}
+
+func (m *MockGameClient) Restart() error {
+	return nil
+}
+
+func (m *MockGameClient) SwitchCharacter(characterID int) error {
+	return nil
+}
+
 func (m *MockGameClient) GetState() client.ClientState {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -415,3 +717,13 @@ func (m *MockGameClient) GetState() client.ClientState {
 func (m *MockGameClient) GetID() string {
 	return m.id
 }
+
+func (m *MockGameClient) SendRawLogin(opcode byte, payload []byte) error {
+	return nil
+}
+
+func (m *MockGameClient) SendRawGame(opcode byte, payload []byte) error {
+	return nil
+}
+
+func (m *MockGameClient) OnRawPacket(callback func(packet client.RawPacket)) {}
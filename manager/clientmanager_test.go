@@ -0,0 +1,320 @@
+package manager
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/config"
+)
+
+func TestManagerHealthCheckStuckClients(t *testing.T) {
+	m := NewManager(&client.ManagerConfig{
+		MaxClients:  10,
+		HealthCheck: time.Hour,
+		StuckAfter:  map[string]time.Duration{"Authenticating": 50 * time.Millisecond},
+	})
+	defer m.Shutdown()
+
+	stuckEvents := make(chan client.HealthDegraded, 4)
+	m.eventBus.Subscribe(client.TopicHealthStuck, func(event interface{}) error {
+		stuckEvents <- event.(client.HealthDegraded)
+		return nil
+	})
+
+	// Clients moved by hand through their states, as if their servers never answered
+	states := map[string][]client.ClientState{
+		"stuck":      {client.StateConnectingLogin, client.StateAuthenticating},
+		"logging-in": {client.StateConnectingLogin},
+		"idle":       nil,
+	}
+	for id, path := range states {
+		c := client.NewClient(id, client.ClientConfig{})
+		for _, state := range path {
+			if err := c.StateMachine().Transition(state); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := m.AddClient(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.AddClient(NewGameClient("mock", client.ClientConfig{}))
+
+	m.performHealthCheck()
+	if stuck := m.GetMetrics().StuckClients; len(stuck) != 0 {
+		t.Errorf("StuckClients = %v before the limit", stuck)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	m.performHealthCheck()
+	if stuck := m.GetMetrics().StuckClients; len(stuck) != 1 || stuck["Authenticating"] != 1 {
+		t.Errorf("StuckClients = %v, want one Authenticating", stuck)
+	}
+
+	select {
+	case event := <-stuckEvents:
+		if event.ClientID != "stuck" || event.State != client.StateAuthenticating || !event.Stuck || event.Dwell < 50*time.Millisecond {
+			t.Errorf("unexpected event %v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the stuck client wasn't reported")
+	}
+}
+
+func TestManagerDiagnostics(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{"disabled", false},
+		{"enabled", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(&client.ManagerConfig{
+				MaxClients:  10,
+				HealthCheck: time.Hour,
+				Diagnostics: config.DiagnosticsType{Enabled: tt.enabled, Address: "127.0.0.1:0"},
+			})
+			defer m.Shutdown()
+
+			if err := m.StartDiagnostics(); err != nil {
+				t.Fatalf("StartDiagnostics() error = %v", err)
+			}
+			address := m.DiagnosticsAddr()
+			if (address != nil) != tt.enabled {
+				t.Fatalf("DiagnosticsAddr() = %v", address)
+			}
+			if address == nil {
+				return
+			}
+
+			response, err := http.Get("http://" + address.String() + "/debug/vars")
+			if err != nil {
+				t.Fatalf("GET /debug/vars error = %v", err)
+			}
+			body, _ := io.ReadAll(response.Body)
+			response.Body.Close()
+			if !strings.Contains(string(body), `"manager":`) {
+				t.Errorf("the variables don't show the metrics of the manager: %.300s", body)
+			}
+
+			// The diagnostics stop with the manager
+			m.Shutdown()
+			if _, err := http.Get("http://" + address.String() + "/debug/vars"); err == nil {
+				t.Error("the diagnostics are still served after the shutdown")
+			}
+		})
+	}
+}
+
+// botClient stands for a custom client implementation
+type botClient struct {
+	*MockGameClient
+	group string
+}
+
+func TestManagerClientFactories(t *testing.T) {
+	m := NewManager(&client.ManagerConfig{MaxClients: 10, HealthCheck: time.Hour})
+	defer m.Shutdown()
+
+	cfg := client.ClientConfig{LoginServerHost: "127.0.0.1", LoginServerPort: 2106, GameServerHost: "127.0.0.1", GameServerPort: 7777, Username: "bot", Password: "bot"}
+	factory := func(group string) client.ClientFactory {
+		return func(id string, config client.ClientConfig) client.GameClient {
+			return &botClient{MockGameClient: NewGameClient(id, config).(*MockGameClient), group: group}
+		}
+	}
+
+	m.SetClientFactory(factory("default"))
+	if err := m.RegisterClientFactory("chaos", factory("chaos")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RegisterClientFactory("chaos", factory("chaos")); !errors.Is(err, client.ErrClientGroupExists) {
+		t.Errorf("RegisterClientFactory() error = %v, want %v", err, client.ErrClientGroupExists)
+	}
+
+	if err := m.CreateClients(2, cfg); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := m.CreateGroup("chaos", 3, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"chaos-1", "chaos-2", "chaos-3"}; !slices.Equal(ids, want) {
+		t.Errorf("CreateGroup() = %v, want %v", ids, want)
+	}
+	if _, err := m.CreateGroup("replay", 1, cfg); !errors.Is(err, client.ErrUnknownClientGroup) {
+		t.Errorf("CreateGroup() error = %v, want %v", err, client.ErrUnknownClientGroup)
+	}
+
+	groups := make(map[string]int)
+	for id, gameClient := range m.GetAllClients() {
+		bot, ok := gameClient.(*botClient)
+		if !ok {
+			t.Fatalf("client %s wasn't created by the factories", id)
+		}
+		groups[bot.group]++
+	}
+	if groups["default"] != 2 || groups["chaos"] != 3 {
+		t.Errorf("clients by group = %v", groups)
+	}
+}
+
+func TestManagerAccountPool(t *testing.T) {
+	config, _ := startStubServers(t)
+
+	m := NewManager(&client.ManagerConfig{
+		MaxClients:  3,
+		HealthCheck: time.Hour,
+		AccountPool: client.AccountPoolConfig{Username: "pooled", Password: "pass", Size: 2},
+	})
+	defer m.Shutdown()
+	m.SetClientFactory(func(id string, config client.ClientConfig) client.GameClient {
+		return client.NewClient(id, config)
+	})
+
+	if err := m.CreateClients(3, config); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for id := range m.GetAllClients() {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	// Two clients borrow the two accounts, the third one finds none left
+	for _, id := range ids[:2] {
+		gameClient, _ := m.GetClient(id)
+		if err := gameClient.Connect(); err != nil {
+			t.Fatalf("Connect() error = %v", err)
+		}
+	}
+	third, _ := m.GetClient(ids[2])
+	if err := third.Connect(); !errors.Is(err, client.ErrAccountPoolExhausted) {
+		t.Fatalf("Connect() error = %v, want %v", err, client.ErrAccountPoolExhausted)
+	}
+
+	holders := m.AccountPool().Holders()
+	if len(holders) != 2 || holders["pooled0"] == "" || holders["pooled1"] == "" || holders["pooled0"] == holders["pooled1"] {
+		t.Fatalf("Holders() = %v, want the two accounts held by two clients", holders)
+	}
+
+	// A client stopping gives its account to the next one
+	if err := m.StopClients(ids[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := third.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v after a client stopped", err)
+	}
+	if holders := m.AccountPool().Holders(); len(holders) != 2 || slices.Contains(slices.Collect(maps.Values(holders)), ids[0]) {
+		t.Errorf("Holders() = %v, want the account of %s handed over", holders, ids[0])
+	}
+}
+
+func TestManagerSourceAddresses(t *testing.T) {
+	config, _ := startStubServers(t)
+
+	m := NewManager(&client.ManagerConfig{
+		MaxClients:      4,
+		HealthCheck:     time.Hour,
+		SourceAddresses: []client.SourceAddress{{IP: "127.0.0.2"}, {IP: "127.0.0.3"}},
+	})
+	defer m.Shutdown()
+	m.SetClientFactory(func(id string, config client.ClientConfig) client.GameClient {
+		return client.NewClient(id, config)
+	})
+
+	if err := m.CreateClients(4, config); err != nil {
+		t.Fatal(err)
+	}
+	for id, gameClient := range m.GetAllClients() {
+		if err := gameClient.Connect(); err != nil {
+			t.Fatalf("Connect() error = %v for %s", err, id)
+		}
+	}
+	m.updateMetrics()
+
+	// Every client opened a login and a game connection, shared out between the two addresses,
+	// the login ones being closed as the clients entered the game
+	stats := m.GetMetrics().SourceAddresses
+	if stats["127.0.0.2"].Total != 4 || stats["127.0.0.3"].Total != 4 {
+		t.Errorf("SourceAddresses = %+v, want 4 connections from each address", stats)
+	}
+	if active := stats["127.0.0.2"].Active + stats["127.0.0.3"].Active; active != 4 {
+		t.Errorf("SourceAddresses = %+v, want the 4 game connections active", stats)
+	}
+}
+
+func TestManagerReactorBackend(t *testing.T) {
+	config, _ := startStubServers(t)
+
+	m := NewManager(&client.ManagerConfig{MaxClients: 2, HealthCheck: time.Hour, ConnectionBackend: client.BackendReactor, ReactorWorkers: 1})
+	defer m.Shutdown()
+	if m.Reactor() == nil {
+		t.Skip("no reactor on this system")
+	}
+	m.SetClientFactory(func(id string, config client.ClientConfig) client.GameClient {
+		return client.NewClient(id, config)
+	})
+
+	spectating := config
+	spectating.Spectator = true
+	if err := m.CreateClients(2, spectating); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for id := range m.GetAllClients() {
+		ids = append(ids, id)
+	}
+	if err := m.StartClients(ids); err != nil {
+		t.Fatal(err)
+	}
+
+	// The spectators are read by the reactor once connected, no goroutine waiting on them
+	for deadline := time.Now().Add(2 * time.Second); m.Reactor().Watched() < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Watched() = %d, want the 2 spectators", m.Reactor().Watched())
+		}
+	}
+
+	actor := client.NewClient("actor", config)
+	defer actor.Disconnect()
+	if err := actor.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := actor.Say("hello spectators"); err != nil {
+		t.Fatalf("Say() error = %v", err)
+	}
+
+	var spectators []*client.Client
+	for _, id := range ids {
+		gameClient, _ := m.GetClient(id)
+		spectators = append(spectators, gameClient.(*client.Client))
+	}
+	said := func(event client.WorldEvent) bool {
+		return event.Kind == client.WorldChat && event.Text == "hello spectators"
+	}
+	missed := client.Unwitnessed(spectators, said)
+	for deadline := time.Now().Add(2 * time.Second); len(missed) > 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		missed = client.Unwitnessed(spectators, said)
+	}
+	if len(missed) > 0 {
+		t.Errorf("%v didn't witness the chat", missed)
+	}
+
+	// Stopping a spectator stops its watch
+	if err := m.StopClients(ids[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Reactor().Watched(); got != 1 {
+		t.Errorf("Watched() = %d after a stop, want 1", got)
+	}
+}
@@ -0,0 +1,198 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/random"
+	"github.com/frostwind/l2go/scenario"
+)
+
+// scenarioRun is a scenario being played by one client
+type scenarioRun struct {
+	cancel   context.CancelFunc
+	done     chan struct{}
+	scenario string
+	step     atomic.Int64 // Last step completed, for the snapshot
+}
+
+// DrainResult lists the drained clients by the way they were stopped
+type DrainResult struct {
+	Graceful []string // Logged out before the deadline
+	Forced   []string // Closed once the deadline passed or the logout failed
+}
+
+// RunScenario makes the specified clients play the scenario, in the background
+func (m *Manager) RunScenario(clientIDs []string, s *scenario.Scenario) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.isShutdown {
+		return client.ErrClientManagerClosed
+	}
+
+	m.runsMu.Lock()
+	defer m.runsMu.Unlock()
+
+	var errors []error
+
+	for _, clientID := range clientIDs {
+		gameClient, exists := m.clients.get(clientID)
+		if !exists {
+			errors = append(errors, fmt.Errorf("client %s: %w", clientID, client.ErrClientNotFound))
+			continue
+		}
+
+		player, ok := gameClient.(scenario.Player)
+		if !ok {
+			errors = append(errors, fmt.Errorf("client %s cannot play scenarios", clientID))
+			continue
+		}
+
+		if _, running := m.runs[clientID]; running {
+			errors = append(errors, fmt.Errorf("client %s is already playing a scenario", clientID))
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(clock.WithContext(random.WithContext(context.Background(), m.random), m.clock))
+		run := &scenarioRun{cancel: cancel, done: make(chan struct{}), scenario: s.Name}
+		m.runs[clientID] = run
+
+		m.wg.Add(1)
+		go func(id string) {
+			defer m.wg.Done()
+			defer close(run.done)
+			defer cancel()
+
+			err := s.RunSteps(ctx, player, func(number int, step scenario.Step, took time.Duration) {
+				run.step.Store(int64(number))
+				m.eventBus.Emit(client.ScenarioStepCompleted{ClientID: id, Scenario: s.Name, Step: number, Verb: step.Verb, Took: took, At: time.Now()})
+			})
+
+			m.runsMu.Lock()
+			if m.runs[id] == run {
+				delete(m.runs, id)
+			}
+			m.runsMu.Unlock()
+
+			if err != nil && ctx.Err() == nil {
+				m.summary.scenarioFailed(err)
+				m.recordOutcome(err)
+				m.eventBus.Emit(client.NewClientError(id, "scenario", err))
+				return
+			}
+
+			m.recordOutcome(nil)
+			m.eventBus.Emit(client.ScenarioDone{ClientID: id, Scenario: s.Name, At: time.Now()})
+		}(clientID)
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to run scenario on some clients: %v", errors)
+	}
+
+	return nil
+}
+
+// DrainClients stops the clients picked by the selector: each one finishes its current
+// scenario step and logs out, and the ones still running at the deadline are closed
+func (m *Manager) DrainClients(selector func(id string, gc client.GameClient) bool, deadline time.Time) (*DrainResult, error) {
+	m.mu.RLock()
+	if m.isShutdown {
+		m.mu.RUnlock()
+		return nil, client.ErrClientManagerClosed
+	}
+
+	selected := make(map[string]client.GameClient)
+	runs := make(map[string]*scenarioRun)
+	m.runsMu.Lock()
+	for id, gameClient := range m.clients.all() {
+		if selector != nil && !selector(id, gameClient) {
+			continue
+		}
+		selected[id] = gameClient
+		if run, running := m.runs[id]; running {
+			runs[id] = run
+		}
+	}
+	m.runsMu.Unlock()
+	m.mu.RUnlock()
+
+	// Closed once the deadline passes, or when the manager shuts down in the meantime
+	expired := make(chan struct{})
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-m.shutdownChan:
+		case <-finished:
+			return
+		}
+		close(expired)
+	}()
+
+	result := &DrainResult{}
+	var resultMu sync.Mutex
+	var wg sync.WaitGroup
+
+	for id, gameClient := range selected {
+		wg.Add(1)
+		go func(id string, gc client.GameClient, run *scenarioRun) {
+			defer wg.Done()
+
+			graceful := m.drainClient(gc, run, expired)
+			if !graceful {
+				gc.Disconnect()
+			}
+
+			resultMu.Lock()
+			if graceful {
+				result.Graceful = append(result.Graceful, id)
+			} else {
+				result.Forced = append(result.Forced, id)
+			}
+			resultMu.Unlock()
+
+			m.eventBus.Emit(client.ClientDrained{ClientID: id, Graceful: graceful, At: time.Now()})
+		}(id, gameClient, runs[id])
+	}
+
+	wg.Wait()
+
+	m.metrics.RecordStops(int64(len(result.Graceful)), int64(len(result.Forced)))
+	m.updateMetrics()
+
+	return result, nil
+}
+
+// drainClient stops the scenario of a client then logs it out, reporting whether it all happened before expired is closed
+func (m *Manager) drainClient(gc client.GameClient, run *scenarioRun, expired <-chan struct{}) bool {
+	if run != nil {
+		run.cancel()
+		select {
+		case <-run.done:
+		case <-expired:
+			return false
+		}
+	}
+
+	loggedOut := make(chan error, 1)
+	go func() {
+		loggedOut <- gc.Logout()
+	}()
+
+	select {
+	case err := <-loggedOut:
+		return err == nil
+	case <-expired:
+		return false
+	}
+}
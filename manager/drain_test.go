@@ -0,0 +1,137 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/scenario"
+	"github.com/frostwind/l2go/testserver"
+)
+
+// startGameClients connects count clients to stub servers and hands them to a new manager
+func startGameClients(t *testing.T, count int) (*Manager, *testserver.GameServer) {
+	t.Helper()
+
+	config, gameServer := startStubServers(t)
+	m := NewManager(&client.ManagerConfig{MaxClients: count, HealthCheck: time.Second})
+	t.Cleanup(func() { m.Shutdown() })
+
+	for i := 0; i < count; i++ {
+		c := client.NewClient("client-"+string(rune('a'+i)), config)
+		if err := c.Connect(); err != nil {
+			t.Fatalf("Connect() error = %v", err)
+		}
+		if err := m.AddClient(c); err != nil {
+			t.Fatalf("AddClient() error = %v", err)
+		}
+	}
+
+	return m, gameServer
+}
+
+// startStubServers starts stub login and game servers, returning the configuration of their clients
+func startStubServers(t *testing.T) (client.ClientConfig, *testserver.GameServer) {
+	t.Helper()
+
+	gameServer := testserver.NewGameServer(testserver.Character{ObjectID: 0x10000001, Name: "Tester", Level: 1})
+	if err := gameServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { gameServer.Close() })
+
+	loginServer := testserver.NewLoginServer()
+	loginServer.AddGameServer(1, gameServer.Addr())
+	if err := loginServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { loginServer.Close() })
+
+	config := client.ClientConfig{
+		LoginServerHost: "127.0.0.1",
+		LoginServerPort: loginServer.Addr().Port,
+		GameServerHost:  "127.0.0.1",
+		GameServerPort:  gameServer.Addr().Port,
+		Username:        "testuser",
+		Password:        "testpass",
+		Timeout:         time.Second,
+	}
+	return config, gameServer
+}
+
+func TestManagerDrainClients(t *testing.T) {
+	tests := []struct {
+		name         string
+		delayLogout  time.Duration
+		drain        time.Duration
+		wantGraceful int
+		wantForced   int
+	}{
+		{name: "graceful", drain: 2 * time.Second, wantGraceful: 2},
+		{name: "forced", delayLogout: time.Second, drain: 100 * time.Millisecond, wantForced: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, gameServer := startGameClients(t, 3)
+			gameServer.DelayLogout = tt.delayLogout
+
+			s, err := scenario.Parse("idle", strings.NewReader("sit\nwait 10s\nstand\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.RunScenario([]string{"client-a", "client-b"}, s); err != nil {
+				t.Fatalf("RunScenario() error = %v", err)
+			}
+
+			start := time.Now()
+			result, err := m.DrainClients(func(id string, gc client.GameClient) bool {
+				return id != "client-c"
+			}, time.Now().Add(tt.drain))
+			if err != nil {
+				t.Fatalf("DrainClients() error = %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > tt.drain+500*time.Millisecond {
+				t.Errorf("DrainClients() took %v past a %v deadline", elapsed, tt.drain)
+			}
+
+			if len(result.Graceful) != tt.wantGraceful || len(result.Forced) != tt.wantForced {
+				t.Errorf("DrainClients() = %+v, want %d graceful and %d forced", result, tt.wantGraceful, tt.wantForced)
+			}
+
+			for _, id := range []string{"client-a", "client-b"} {
+				gc, _ := m.GetClient(id)
+				if state := gc.GetState(); state != client.StateDisconnected {
+					t.Errorf("%s state = %v after the drain", id, state)
+				}
+			}
+			gc, _ := m.GetClient("client-c")
+			if state := gc.GetState(); state != client.StateInGame {
+				t.Errorf("client-c state = %v, it was not selected", state)
+			}
+
+			metrics := m.GetMetrics()
+			if metrics.GracefulStops != int64(tt.wantGraceful) || metrics.ForcedStops != int64(tt.wantForced) {
+				t.Errorf("GracefulStops = %d, ForcedStops = %d", metrics.GracefulStops, metrics.ForcedStops)
+			}
+		})
+	}
+}
+
+func TestManagerAddClient(t *testing.T) {
+	m := NewManager(&client.ManagerConfig{MaxClients: 1, HealthCheck: time.Second})
+	defer m.Shutdown()
+
+	if err := m.AddClient(NewGameClient("client-1", client.ClientConfig{})); err != nil {
+		t.Fatalf("AddClient() error = %v", err)
+	}
+	if err := m.AddClient(NewGameClient("client-1", client.ClientConfig{})); err != client.ErrMaxClientsReached {
+		t.Errorf("AddClient() error = %v, want %v", err, client.ErrMaxClientsReached)
+	}
+
+	m.Shutdown()
+	if _, err := m.DrainClients(nil, time.Now()); err != client.ErrClientManagerClosed {
+		t.Errorf("DrainClients() error = %v, want %v", err, client.ErrClientManagerClosed)
+	}
+}
@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"slices"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+// Health returns how the actions of the clients fare against the error budgets of the configuration
+func (m *Manager) Health() client.ErrorBudgetStatus {
+	return m.errorTracker.Status()
+}
+
+// recordOutcome counts an action of a client against the error budgets, successful when err is nil
+func (m *Manager) recordOutcome(err error) {
+	m.errorTracker.Record(err)
+	m.checkErrorBudgets()
+}
+
+// checkErrorBudgets publishes the status of the error budgets, and an event when a category went over
+// its budget or back under it. The health check calls it too, the failures leaving the window over time.
+func (m *Manager) checkErrorBudgets() {
+	m.budgetMu.Lock()
+	defer m.budgetMu.Unlock()
+
+	status := m.errorTracker.Status()
+	m.metrics.SetErrorBudget(status)
+	m.publishMetrics()
+
+	if slices.Equal(status.Exhausted, m.budgetExhausted) {
+		return
+	}
+	m.budgetExhausted = status.Exhausted
+	m.eventBus.Emit(client.ErrorBudgetChanged{Healthy: status.Healthy, Exhausted: status.Exhausted, Rates: status.Rates, At: time.Now()})
+}
@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+func TestManagerErrorBudgets(t *testing.T) {
+	m := NewManager(&client.ManagerConfig{
+		MaxClients:   10,
+		HealthCheck:  time.Hour,
+		ErrorBudgets: client.ErrorBudgetConfig{Budgets: map[string]float64{client.ErrorCategoryServer: 25}},
+	})
+	m.SetSummaryOutput(nil)
+	defer m.Shutdown()
+
+	changes := make(chan client.ErrorBudgetChanged, 4)
+	m.EventBus().Subscribe(client.TopicHealthBudget, func(event interface{}) error {
+		changes <- event.(client.ErrorBudgetChanged)
+		return nil
+	})
+
+	gameClient := NewGameClient("client-1", client.ClientConfig{})
+	serverFull := fmt.Errorf("server 1 refused: %w", client.LoginFailTooManyPlayers.Err())
+	m.RecordConnect(gameClient, time.Millisecond, nil)
+	m.RecordConnect(gameClient, 0, serverFull)
+	m.RecordConnect(gameClient, 0, client.ErrConnectionClosed)
+
+	select {
+	case change := <-changes:
+		if change.Healthy || !slices.Equal(change.Exhausted, []string{client.ErrorCategoryServer}) {
+			t.Errorf("ErrorBudgetChanged = %+v, want the server budget exhausted", change)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no ErrorBudgetChanged event")
+	}
+	if health := m.Health(); health.Healthy || health.Failures[client.ErrorCategoryNetwork] != 1 {
+		t.Errorf("Health() = %+v", health)
+	}
+	if metrics := m.GetMetrics(); metrics.ErrorBudget.Healthy || metrics.ErrorBudget.Actions != 3 {
+		t.Errorf("GetMetrics().ErrorBudget = %+v", metrics.ErrorBudget)
+	}
+
+	// Back under the budget, at 20% of failures
+	m.RecordConnect(gameClient, time.Millisecond, nil)
+	m.RecordConnect(gameClient, time.Millisecond, nil)
+	select {
+	case change := <-changes:
+		if !change.Healthy || len(change.Exhausted) != 0 {
+			t.Errorf("ErrorBudgetChanged = %+v, want healthy", change)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no ErrorBudgetChanged event")
+	}
+	if categories := m.Summary().Categories; categories[client.ErrorCategoryServer] != 1 || categories[client.ErrorCategoryNetwork] != 1 {
+		t.Errorf("Summary().Categories = %v", categories)
+	}
+}
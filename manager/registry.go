@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/frostwind/l2go/client"
+)
+
+// REGISTRY_SHARDS is the number of shards the clients are spread over
+const REGISTRY_SHARDS = 64
+
+// registry holds the managed clients by id. They are spread over shards each having
+// its own lock, so the lookups and the additions of different clients rarely contend,
+// and the count is kept apart so reading it doesn't lock at all.
+type registry struct {
+	shards [REGISTRY_SHARDS]registryShard
+	count  atomic.Int64 // Clients held or being added
+}
+
+type registryShard struct {
+	clients map[string]client.GameClient
+	mu      sync.RWMutex
+}
+
+func newRegistry() *registry {
+	r := &registry{}
+	for i := range r.shards {
+		r.shards[i].clients = make(map[string]client.GameClient)
+	}
+	return r
+}
+
+// shard returns the shard holding a client id, picked by its FNV-1a hash
+func (r *registry) shard(id string) *registryShard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(id); i++ {
+		hash ^= uint32(id[i])
+		hash *= 16777619
+	}
+	return &r.shards[hash%REGISTRY_SHARDS]
+}
+
+// reserve makes room for n more clients, unless it would hold more than max
+func (r *registry) reserve(n, max int) error {
+	for {
+		count := r.count.Load()
+		if count+int64(n) > int64(max) {
+			return client.ErrMaxClientsReached
+		}
+		if r.count.CompareAndSwap(count, count+int64(n)) {
+			return nil
+		}
+	}
+}
+
+// release gives back the room of n clients which weren't added
+func (r *registry) release(n int) {
+	r.count.Add(-int64(n))
+}
+
+// put adds a client in the room reserved for it, failing if its id is already taken
+func (r *registry) put(gameClient client.GameClient) error {
+	shard := r.shard(gameClient.GetID())
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if _, exists := shard.clients[gameClient.GetID()]; exists {
+		return client.ErrClientAlreadyExists
+	}
+	shard.clients[gameClient.GetID()] = gameClient
+	return nil
+}
+
+// get returns the client having an id
+func (r *registry) get(id string) (client.GameClient, bool) {
+	shard := r.shard(id)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	gameClient, ok := shard.clients[id]
+	return gameClient, ok
+}
+
+// len returns the number of clients, without locking
+func (r *registry) len() int {
+	return int(r.count.Load())
+}
+
+// all returns a copy of the clients by id, locking one shard at a time
+func (r *registry) all() map[string]client.GameClient {
+	clients := make(map[string]client.GameClient, r.len())
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.RLock()
+		for id, gameClient := range shard.clients {
+			clients[id] = gameClient
+		}
+		shard.mu.RUnlock()
+	}
+	return clients
+}
+
+// clear removes every client, returning them by id
+func (r *registry) clear() map[string]client.GameClient {
+	clients := make(map[string]client.GameClient, r.len())
+	for i := range r.shards {
+		shard := &r.shards[i]
+		shard.mu.Lock()
+		for id, gameClient := range shard.clients {
+			clients[id] = gameClient
+		}
+		shard.clients = make(map[string]client.GameClient)
+		shard.mu.Unlock()
+	}
+	r.count.Add(-int64(len(clients)))
+	return clients
+}
@@ -0,0 +1,218 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+func TestRegistry(t *testing.T) {
+	r := newRegistry()
+
+	if err := r.reserve(3, 3); err != nil {
+		t.Fatalf("reserve() error = %v", err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := r.put(NewGameClient(id, client.ClientConfig{})); err != nil {
+			t.Fatalf("put(%s) error = %v", id, err)
+		}
+	}
+	if err := r.reserve(1, 3); !errors.Is(err, client.ErrMaxClientsReached) {
+		t.Errorf("reserve() error = %v when full, want %v", err, client.ErrMaxClientsReached)
+	}
+
+	if err := r.reserve(1, 4); err != nil {
+		t.Fatalf("reserve() error = %v", err)
+	}
+	if err := r.put(NewGameClient("b", client.ClientConfig{})); !errors.Is(err, client.ErrClientAlreadyExists) {
+		t.Errorf("put() error = %v for a taken id, want %v", err, client.ErrClientAlreadyExists)
+	}
+	r.release(1)
+
+	if gameClient, ok := r.get("b"); !ok || gameClient.GetID() != "b" {
+		t.Errorf("get(b) = %v, %v", gameClient, ok)
+	}
+	if _, ok := r.get("d"); ok {
+		t.Error("get(d) found a client never added")
+	}
+	if all := r.all(); len(all) != 3 || r.len() != 3 {
+		t.Errorf("all() = %d clients, len() = %d, want 3", len(all), r.len())
+	}
+
+	if cleared := r.clear(); len(cleared) != 3 || r.len() != 0 || len(r.all()) != 0 {
+		t.Errorf("clear() = %d clients, %d left", len(cleared), r.len())
+	}
+}
+
+func TestManagerConcurrentAdds(t *testing.T) {
+	m := NewManager(&client.ManagerConfig{MaxClients: 1000, HealthCheck: time.Hour})
+	defer m.Shutdown()
+
+	// Twice as many clients as allowed, added from many goroutines
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	refused := 0
+	for i := 0; i < 2000; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := m.AddClient(NewGameClient(fmt.Sprintf("client-%d", i), client.ClientConfig{})); err != nil {
+				if !errors.Is(err, client.ErrMaxClientsReached) {
+					t.Errorf("AddClient() error = %v", err)
+				}
+				mu.Lock()
+				refused++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if refused != 1000 || len(m.GetAllClients()) != 1000 {
+		t.Errorf("%d clients refused, %d managed, want 1000 each", refused, len(m.GetAllClients()))
+	}
+	if metrics := m.GetMetrics(); metrics.TotalConnections != 1000 {
+		t.Errorf("TotalConnections = %d, want 1000", metrics.TotalConnections)
+	}
+}
+
+func TestManagerStartClientsDoesntBlock(t *testing.T) {
+	m := NewManager(&client.ManagerConfig{MaxClients: 10, ConnectInterval: time.Hour, HealthCheck: time.Hour})
+
+	ids := []string{"first", "second"}
+	for _, id := range ids {
+		if err := m.AddClient(NewGameClient(id, client.ClientConfig{})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	connected := make(chan string, len(ids))
+	m.eventBus.Subscribe(client.TopicClientConnected, func(event interface{}) error {
+		connected <- event.(client.ClientConnected).ClientID
+		return nil
+	})
+
+	started := make(chan error, 1)
+	go func() {
+		started <- m.StartClients(ids)
+	}()
+
+	// The first client connects, the second one waiting for the interval
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("the first client wasn't started")
+	}
+
+	// The manager stays usable while StartClients waits between the connections
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := m.AddClient(NewGameClient("third", client.ClientConfig{})); err != nil {
+			t.Errorf("AddClient() error = %v", err)
+		}
+		if _, err := m.GetClient("first"); err != nil {
+			t.Errorf("GetClient() error = %v", err)
+		}
+		m.GetMetrics()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the manager is blocked while starting the clients")
+	}
+
+	// Shutting down stops the clients left to start
+	m.Shutdown()
+	select {
+	case err := <-started:
+		if !errors.Is(err, client.ErrClientManagerClosed) {
+			t.Errorf("StartClients() error = %v, want %v", err, client.ErrClientManagerClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("StartClients() kept waiting after the shutdown")
+	}
+}
+
+// newBenchmarkManager returns a manager of count mock clients
+func newBenchmarkManager(b *testing.B, count int) (*Manager, []string) {
+	b.Helper()
+
+	m := NewManager(&client.ManagerConfig{MaxClients: count * 2, HealthCheck: time.Hour})
+	b.Cleanup(func() { m.Shutdown() })
+
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("client-%d", i)
+		if err := m.AddClient(NewGameClient(ids[i], client.ClientConfig{})); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return m, ids
+}
+
+func BenchmarkManagerGetClient(b *testing.B) {
+	for _, count := range []int{100, 10000} {
+		b.Run(fmt.Sprintf("%d clients", count), func(b *testing.B) {
+			m, ids := newBenchmarkManager(b, count)
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if _, err := m.GetClient(ids[i%len(ids)]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkManagerGetMetrics(b *testing.B) {
+	for _, count := range []int{100, 10000} {
+		b.Run(fmt.Sprintf("%d clients", count), func(b *testing.B) {
+			m, _ := newBenchmarkManager(b, count)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					m.GetMetrics()
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkManagerMixed looks clients up while others are added, as a load test ramping up does
+func BenchmarkManagerMixed(b *testing.B) {
+	for _, count := range []int{100, 10000} {
+		b.Run(fmt.Sprintf("%d clients", count), func(b *testing.B) {
+			m, ids := newBenchmarkManager(b, count)
+			m.config.MaxClients = count + b.N
+			b.ResetTimer()
+
+			var added sync.WaitGroup
+			added.Add(1)
+			go func() {
+				defer added.Done()
+				for i := 0; i < b.N; i++ {
+					m.AddClient(NewGameClient(fmt.Sprintf("added-%d", i), client.ClientConfig{}))
+				}
+			}()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if _, err := m.GetClient(ids[i%len(ids)]); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			added.Wait()
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"sort"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/protocol"
+)
+
+// ClientSnapshot is the state of a client of the fleet, as dumped by the snapshot
+type ClientSnapshot struct {
+	ID       string                `json:"id"`
+	State    string                `json:"state"`
+	Since    time.Time             `json:"since,omitzero"`     // When the client entered its state, if its transitions are tracked
+	Scenario string                `json:"scenario,omitempty"` // Scenario the client is playing, if any
+	Step     int                   `json:"step,omitempty"`     // Last step of the scenario completed
+	History  []client.Transition   `json:"history,omitempty"`  // Last transitions, oldest first
+	Crypto   *protocol.CryptoStats `json:"crypto,omitempty"`   // Encryption of the connection, if the client exposes it
+}
+
+// FleetSnapshot is the state of the clients of the manager, for the post-mortem analysis of a stuck load test
+type FleetSnapshot struct {
+	TakenAt time.Time                 `json:"takenAt"`
+	Metrics *client.ConnectionMetrics `json:"metrics"`
+	States  map[string]int            `json:"states"`  // Clients by state
+	Clients []ClientSnapshot          `json:"clients"` // By id
+}
+
+// Snapshot returns the state of every client, along with the scenario step it is at
+func (m *Manager) Snapshot() FleetSnapshot {
+	snapshot := FleetSnapshot{
+		TakenAt: time.Now(),
+		Metrics: m.GetMetrics(),
+		States:  make(map[string]int),
+	}
+
+	m.runsMu.Lock()
+	runs := make(map[string]*scenarioRun, len(m.runs))
+	for id, run := range m.runs {
+		runs[id] = run
+	}
+	m.runsMu.Unlock()
+
+	for id, gameClient := range m.clients.all() {
+		state := gameClient.GetState()
+		item := ClientSnapshot{ID: id, State: state.String()}
+		if tracked, ok := gameClient.(interface{ StateMachine() *client.StateMachine }); ok {
+			item.Since = tracked.StateMachine().EnteredAt(state)
+			item.History = tracked.StateMachine().History()
+		}
+		if encrypted, ok := gameClient.(interface{ CryptoStats() protocol.CryptoStats }); ok {
+			stats := encrypted.CryptoStats()
+			item.Crypto = &stats
+		}
+		if run, ok := runs[id]; ok {
+			item.Scenario, item.Step = run.scenario, int(run.step.Load())
+		}
+
+		snapshot.States[item.State]++
+		snapshot.Clients = append(snapshot.Clients, item)
+	}
+	sort.Slice(snapshot.Clients, func(i, j int) bool { return snapshot.Clients[i].ID < snapshot.Clients[j].ID })
+
+	return snapshot
+}
@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/protocol"
+	"github.com/frostwind/l2go/scenario"
+)
+
+func TestManagerSnapshot(t *testing.T) {
+	m, _ := startGameClients(t, 3)
+
+	s, err := scenario.Parse("idle", strings.NewReader("sit\nwait 10s\nstand\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RunScenario([]string{"client-a"}, s); err != nil {
+		t.Fatalf("RunScenario() error = %v", err)
+	}
+	gc, _ := m.GetClient("client-c")
+	gc.Disconnect()
+
+	// The client waits on the second step
+	deadline := time.Now().Add(5 * time.Second)
+	snapshot := m.Snapshot()
+	for snapshot.Clients[0].Step != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Snapshot() = %+v, the client never sat", snapshot.Clients[0])
+		}
+		time.Sleep(10 * time.Millisecond)
+		snapshot = m.Snapshot()
+	}
+
+	if want := map[string]int{"InGame": 2, "Disconnected": 1}; !maps.Equal(snapshot.States, want) {
+		t.Errorf("States = %v, want %v", snapshot.States, want)
+	}
+
+	tests := []struct {
+		id       string
+		state    string
+		scenario string
+	}{
+		{"client-a", "InGame", "idle"},
+		{"client-b", "InGame", ""},
+		{"client-c", "Disconnected", ""},
+	}
+	if len(snapshot.Clients) != len(tests) {
+		t.Fatalf("Clients = %+v", snapshot.Clients)
+	}
+	for i, tt := range tests {
+		got := snapshot.Clients[i]
+		if got.ID != tt.id || got.State != tt.state || got.Scenario != tt.scenario || got.Since.IsZero() || len(got.History) == 0 {
+			t.Errorf("Clients[%d] = %+v, want %s %s playing %q", i, got, tt.id, tt.state, tt.scenario)
+		}
+		if got.Crypto == nil || got.Crypto.BlowfishKey != protocol.KeyStatic || got.Crypto.Encryptions == 0 {
+			t.Errorf("Clients[%d].Crypto = %+v, want the static Blowfish key", i, got.Crypto)
+		}
+	}
+}
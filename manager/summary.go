@@ -0,0 +1,283 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+// SUMMARY_TOP_ERRORS is how many error messages the summary lists, the most frequent first
+const SUMMARY_TOP_ERRORS = 5
+
+// handshakePhases are the states a client goes through to reach the world, in order
+var handshakePhases = []client.ClientState{
+	client.StateConnectingLogin,
+	client.StateAuthenticating,
+	client.StateSelectingServer,
+	client.StateConnectingGame,
+}
+
+// PhaseCount counts the connections that went through a phase of the handshake, and the ones that failed in it
+type PhaseCount struct {
+	Phase  string `json:"phase"`
+	Passed int    `json:"passed"`
+	Failed int    `json:"failed"`
+}
+
+// ErrorCount counts the occurrences of an error message, by action of the clients
+type ErrorCount struct {
+	Action  string `json:"action"` // What the clients were doing: connect, scenario...
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// Summary consolidates what the clients of a manager went through, from its creation to its shutdown.
+// Shutdown logs it, so that the errors of the clients don't get lost among the other logs of a run.
+type Summary struct {
+	Namespace        string         `json:"namespace,omitempty"`
+	Started          time.Time      `json:"started"`
+	Duration         time.Duration  `json:"duration"`
+	Clients          int            `json:"clients"`              // Clients managed over the run
+	Connects         int            `json:"connects"`             // Successful connections
+	Failures         int            `json:"failures"`             // Failed connections
+	ScenarioFailures int            `json:"scenarioFailures"`     // Scenarios that failed before their last step
+	Phases           []PhaseCount   `json:"phases"`               // In the order of the handshake
+	Errors           []ErrorCount   `json:"errors,omitempty"`     // The SUMMARY_TOP_ERRORS most frequent
+	DistinctErrors   int            `json:"distinctErrors"`       // Error messages, listed or not
+	Categories       map[string]int `json:"categories,omitempty"` // Failed connections and scenarios, by category of error
+
+	ConnectP50 time.Duration `json:"connectP50"`
+	ConnectP95 time.Duration `json:"connectP95"`
+	ConnectP99 time.Duration `json:"connectP99"`
+	ConnectMax time.Duration `json:"connectMax"`
+}
+
+// WriteText writes the summary in a form meant to be read in a terminal or a CI log
+func (s Summary) WriteText(w io.Writer) error {
+	name := s.Namespace
+	if name == "" {
+		name = "manager"
+	}
+	fmt.Fprintf(w, "Summary of %s (%s): %d clients, %d connects, %d failures, %d failed scenarios\n",
+		name, s.Duration.Round(time.Millisecond), s.Clients, s.Connects, s.Failures, s.ScenarioFailures)
+	for _, phase := range s.Phases {
+		fmt.Fprintf(w, "  %s: %d passed, %d failed\n", phase.Phase, phase.Passed, phase.Failed)
+	}
+	fmt.Fprintf(w, "Connect: p50 %v, p95 %v, p99 %v, max %v\n", s.ConnectP50, s.ConnectP95, s.ConnectP99, s.ConnectMax)
+
+	if len(s.Errors) == 0 {
+		_, err := fmt.Fprintln(w, "No errors")
+		return err
+	}
+	fmt.Fprint(w, "Errors by category:")
+	for _, category := range client.ErrorCategories() {
+		if count := s.Categories[category]; count > 0 {
+			fmt.Fprintf(w, " %s %d", category, count)
+		}
+	}
+	fmt.Fprintf(w, "\nTop errors, out of %d:\n", s.DistinctErrors)
+	for _, e := range s.Errors {
+		if _, err := fmt.Fprintf(w, "  %dx %s: %s\n", e.Count, e.Action, e.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFile writes the summary to a file, as indented json
+func (s Summary) WriteFile(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// summaryRecorder gathers the figures of the summary as the clients are added, connected and run
+type summaryRecorder struct {
+	started          time.Time
+	clients          int
+	connects         int
+	failures         int
+	scenarioFailures int
+	failed           map[client.ClientState]int // Failed connections, by phase
+	errors           map[ErrorCount]int         // By action and message, the counts left out
+	categories       map[string]int
+	connectTimes     []time.Duration
+	mu               sync.Mutex
+}
+
+func newSummaryRecorder() *summaryRecorder {
+	return &summaryRecorder{
+		started:    time.Now(),
+		failed:     make(map[client.ClientState]int),
+		errors:     make(map[ErrorCount]int),
+		categories: make(map[string]int),
+	}
+}
+
+func (r *summaryRecorder) added(count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients += count
+}
+
+// connected records a connection, which took took when it succeeded, or failed in phase with err
+func (r *summaryRecorder) connected(took time.Duration, phase client.ClientState, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err == nil {
+		r.connects++
+		r.connectTimes = append(r.connectTimes, took)
+		return
+	}
+	r.failures++
+	r.failed[phase]++
+	r.errors[ErrorCount{Action: "connect", Message: err.Error()}]++
+	r.categories[client.ClassifyError(err)]++
+}
+
+func (r *summaryRecorder) scenarioFailed(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.scenarioFailures++
+	r.errors[ErrorCount{Action: "scenario", Message: err.Error()}]++
+	r.categories[client.ClassifyError(err)]++
+}
+
+// summary computes the summary of what was recorded so far
+func (r *summaryRecorder) summary(namespace string) Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	summary := Summary{
+		Namespace:        namespace,
+		Started:          r.started,
+		Duration:         time.Since(r.started),
+		Clients:          r.clients,
+		Connects:         r.connects,
+		Failures:         r.failures,
+		ScenarioFailures: r.scenarioFailures,
+		DistinctErrors:   len(r.errors),
+	}
+	if len(r.categories) > 0 {
+		summary.Categories = maps.Clone(r.categories)
+	}
+
+	// A connection failing in a phase went through the ones before it, and a successful one through all of them
+	passed := r.connects
+	for i := len(handshakePhases) - 1; i >= 0; i-- {
+		phase := handshakePhases[i]
+		summary.Phases = append(summary.Phases, PhaseCount{Phase: phase.String(), Passed: passed, Failed: r.failed[phase]})
+		passed += r.failed[phase]
+	}
+	slices.Reverse(summary.Phases)
+
+	for e, count := range r.errors {
+		e.Count = count
+		summary.Errors = append(summary.Errors, e)
+	}
+	sort.Slice(summary.Errors, func(i, j int) bool {
+		a, b := summary.Errors[i], summary.Errors[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Action != b.Action {
+			return a.Action < b.Action
+		}
+		return a.Message < b.Message
+	})
+	if len(summary.Errors) > SUMMARY_TOP_ERRORS {
+		summary.Errors = summary.Errors[:SUMMARY_TOP_ERRORS]
+	}
+
+	sorted := slices.Clone(r.connectTimes)
+	slices.Sort(sorted)
+	summary.ConnectP50 = percentile(sorted, 50)
+	summary.ConnectP95 = percentile(sorted, 95)
+	summary.ConnectP99 = percentile(sorted, 99)
+	if len(sorted) > 0 {
+		summary.ConnectMax = sorted[len(sorted)-1]
+	}
+	return summary
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// failedPhase returns the phase of the handshake a client failed to connect in: the state it left for
+// StateError, or the state it stopped in for the clients that don't track their transitions
+func failedPhase(gameClient client.GameClient) client.ClientState {
+	state := gameClient.GetState()
+	if tracked, ok := gameClient.(interface{ StateMachine() *client.StateMachine }); ok {
+		history := tracked.StateMachine().History()
+		if n := len(history); n > 0 && history[n-1].To == client.StateError {
+			state = history[n-1].From
+		}
+	}
+
+	if !slices.Contains(handshakePhases, state) {
+		return client.StateConnectingLogin // Failed before setting off
+	}
+	return state
+}
+
+// RecordConnect records a connection of a client in the summary and against the error budgets, for the
+// callers connecting the clients themselves rather than through StartClients. took is how long a
+// successful connection took.
+func (m *Manager) RecordConnect(gameClient client.GameClient, took time.Duration, err error) {
+	var phase client.ClientState
+	if err != nil {
+		phase = failedPhase(gameClient)
+	}
+	m.summary.connected(took, phase, err)
+	m.recordOutcome(err)
+}
+
+// Summary returns the summary of the clients of the manager so far
+func (m *Manager) Summary() Summary {
+	return m.summary.summary(m.config.Namespace)
+}
+
+// SetSummaryOutput replaces where Shutdown logs the summary, the standard error by default, nil not to log it
+func (m *Manager) SetSummaryOutput(w io.Writer) {
+	m.summaryMu.Lock()
+	defer m.summaryMu.Unlock()
+	m.summaryOutput = w
+}
+
+// reportSummary logs the summary, and writes it to the summary file of the configuration if any
+func (m *Manager) reportSummary() error {
+	summary := m.Summary()
+
+	m.summaryMu.Lock()
+	output := m.summaryOutput
+	m.summaryMu.Unlock()
+	if output != nil {
+		summary.WriteText(output)
+	}
+
+	if m.config.SummaryFile == "" {
+		return nil
+	}
+	if err := summary.WriteFile(m.config.SummaryFile); err != nil {
+		return fmt.Errorf("failed to write the summary: %w", err)
+	}
+	return nil
+}
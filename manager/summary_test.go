@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/testserver"
+)
+
+func TestManagerSummary(t *testing.T) {
+	gameServer := testserver.NewGameServer(testserver.Character{ObjectID: 0x10000001, Name: "Tester", Level: 1})
+	if err := gameServer.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { gameServer.Close() })
+
+	// A login server accepting the clients, one refusing their login and one refusing them the game server
+	scripts := []struct {
+		opcode int
+		script testserver.Script
+	}{
+		{opcode: int(opcodes.LoginClientRequestAuthLogin)},
+		{opcode: int(opcodes.LoginClientRequestAuthLogin), script: testserver.RejectLogin(0x03)},
+		{opcode: int(opcodes.LoginClientRequestPlay), script: testserver.RejectPlay(0x0f)},
+	}
+	configs := make([]client.ClientConfig, len(scripts))
+	for i, s := range scripts {
+		loginServer := testserver.NewLoginServer()
+		loginServer.AddGameServer(1, gameServer.Addr())
+		if s.script != nil {
+			loginServer.On(s.opcode, s.script)
+		}
+		if err := loginServer.Start(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { loginServer.Close() })
+
+		configs[i] = client.ClientConfig{
+			LoginServerHost: "127.0.0.1",
+			LoginServerPort: loginServer.Addr().Port,
+			GameServerHost:  "127.0.0.1",
+			GameServerPort:  gameServer.Addr().Port,
+			Username:        "testuser",
+			Password:        "testpass",
+			Timeout:         time.Second,
+		}
+	}
+
+	summaryFile := filepath.Join(t.TempDir(), "summary.json")
+	m := NewManager(&client.ManagerConfig{MaxClients: 10, HealthCheck: time.Hour, Namespace: "summary", SummaryFile: summaryFile})
+	var output bytes.Buffer
+	m.SetSummaryOutput(&output)
+
+	// 3 clients get in, 2 are refused their login and 1 the game server
+	var ids []string
+	for i, server := range []int{0, 0, 0, 1, 1, 2} {
+		id := fmt.Sprintf("client-%d", i)
+		if err := m.AddClient(client.NewClient(id, configs[server])); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := m.StartClients(ids); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for summary := m.Summary(); summary.Connects+summary.Failures < len(ids); summary = m.Summary() {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d connections of %d recorded", summary.Connects+summary.Failures, len(ids))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := m.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	summary := m.Summary()
+	if summary.Clients != 6 || summary.Connects != 3 || summary.Failures != 3 {
+		t.Errorf("Summary() = %d clients, %d connects, %d failures, want 6, 3 and 3", summary.Clients, summary.Connects, summary.Failures)
+	}
+	wantPhases := []PhaseCount{
+		{Phase: "ConnectingLogin", Passed: 6},
+		{Phase: "Authenticating", Passed: 4, Failed: 2},
+		{Phase: "SelectingServer", Passed: 3, Failed: 1},
+		{Phase: "ConnectingGame", Passed: 3},
+	}
+	if fmt.Sprint(summary.Phases) != fmt.Sprint(wantPhases) {
+		t.Errorf("Summary().Phases = %v, want %v", summary.Phases, wantPhases)
+	}
+	if len(summary.Errors) != 2 || summary.Errors[0].Count != 2 || summary.Errors[0].Action != "connect" {
+		t.Errorf("Summary().Errors = %+v, want the refused logins first", summary.Errors)
+	}
+	if summary.ConnectP50 <= 0 || summary.ConnectMax < summary.ConnectP99 {
+		t.Errorf("Summary() connect times = p50 %v, p99 %v, max %v", summary.ConnectP50, summary.ConnectP99, summary.ConnectMax)
+	}
+
+	text := output.String()
+	for _, want := range []string{"Summary of summary", "6 clients, 3 connects, 3 failures", "Authenticating: 4 passed, 2 failed", "Errors by category: auth 2 server 1\n", "2x connect: "} {
+		if !strings.Contains(text, want) {
+			t.Errorf("summary logged %q, want it to contain %q", text, want)
+		}
+	}
+
+	data, err := os.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written Summary
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.Connects != 3 || written.Failures != 3 || len(written.Phases) != len(wantPhases) {
+		t.Errorf("summary file = %s", data)
+	}
+}
+
+func TestSummaryTopErrors(t *testing.T) {
+	recorder := newSummaryRecorder()
+	for i := range 8 {
+		for range i + 1 {
+			recorder.connected(0, client.StateAuthenticating, fmt.Errorf("error %d", i))
+		}
+	}
+	recorder.scenarioFailed(errors.New("error 7"))
+
+	summary := recorder.summary("")
+	if summary.DistinctErrors != 9 || len(summary.Errors) != SUMMARY_TOP_ERRORS {
+		t.Fatalf("Summary() = %d errors listed out of %d, want %d out of 9", len(summary.Errors), summary.DistinctErrors, SUMMARY_TOP_ERRORS)
+	}
+	for i, e := range summary.Errors {
+		if want := fmt.Sprintf("error %d", 7-i); e.Message != want || e.Count != 8-i {
+			t.Errorf("Errors[%d] = %+v, want %q %d times", i, e, want, 8-i)
+		}
+	}
+	if summary.ScenarioFailures != 1 || summary.Failures != 36 {
+		t.Errorf("Summary() = %d scenario failures and %d failures, want 1 and 36", summary.ScenarioFailures, summary.Failures)
+	}
+}
@@ -0,0 +1,147 @@
+package names
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Built-in entries, always part of a blocklist
+var (
+	builtinProfanity = []string{"fuck", "shit", "cunt", "bitch", "whore"}
+	builtinReserved  = []string{"admin", "administrator", "gm", "gamemaster", "moderator", "system", "server", "support"}
+)
+
+// Blocklist holds the words a name can't contain and the names nobody can take.
+// A blocklist is never modified once built.
+type Blocklist struct {
+	profanity []string
+	reserved  map[string]bool
+}
+
+// BuiltinBlocklist returns a blocklist holding the built-in entries only
+func BuiltinBlocklist() *Blocklist {
+	b := &Blocklist{reserved: make(map[string]bool)}
+	b.add(builtinProfanity, builtinReserved)
+	return b
+}
+
+// ParseBlocklist reads a blocklist file on top of the built-in entries. Every line
+// holds a forbidden word, or a reserved name when prefixed with '='. Empty lines
+// and lines starting with '#' are ignored.
+func ParseBlocklist(r io.Reader) (*Blocklist, error) {
+	var profanity, reserved []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "=") {
+			reserved = append(reserved, strings.TrimSpace(line[1:]))
+		} else {
+			profanity = append(profanity, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	b := BuiltinBlocklist()
+	b.add(profanity, reserved)
+	return b, nil
+}
+
+// LoadBlocklist reads a blocklist file
+func LoadBlocklist(path string) (*Blocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseBlocklist(file)
+}
+
+func (b *Blocklist) add(profanity, reserved []string) {
+	for _, word := range profanity {
+		if word != "" {
+			b.profanity = append(b.profanity, strings.ToLower(word))
+		}
+	}
+	for _, name := range reserved {
+		if name != "" {
+			b.reserved[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// Check returns an error if the name is reserved or contains a forbidden word
+func (b *Blocklist) Check(name string) error {
+	lower := strings.ToLower(name)
+
+	if b.reserved[lower] {
+		return fmt.Errorf("%w: %s", ErrNameReserved, name)
+	}
+
+	for _, word := range b.profanity {
+		if strings.Contains(lower, word) {
+			return fmt.Errorf("%w: %s", ErrNameProfane, name)
+		}
+	}
+
+	return nil
+}
+
+// LoadBlocklist replaces the blocklist of the validator with the content of a file
+func (v *Validator) LoadBlocklist(path string) error {
+	blocklist, err := LoadBlocklist(path)
+	if err != nil {
+		return err
+	}
+
+	v.SetBlocklist(blocklist)
+	return nil
+}
+
+// WatchBlocklist reloads the blocklist file whenever it changes, until stop is closed.
+// A file that can't be read leaves the current blocklist in place and is reported
+// to onError, which may be nil.
+func (v *Validator) WatchBlocklist(path string, interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	// The first tick always loads the file, it may have changed since the caller read it
+	var modTime time.Time
+	var size int64 = -1
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				continue
+			}
+
+			if info.ModTime().Equal(modTime) && info.Size() == size {
+				continue
+			}
+			modTime, size = info.ModTime(), info.Size()
+
+			if err := v.LoadBlocklist(path); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
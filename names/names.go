@@ -0,0 +1,91 @@
+// Package names validates the character names chosen by the players. It is
+// shared by the game server and the toolkit, so a name refused by one is
+// refused by the other.
+package names
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMinLength is the shortest name accepted
+	DefaultMinLength = 3
+
+	// DefaultMaxLength is the longest name the client can display
+	DefaultMaxLength = 16
+)
+
+var (
+	ErrNameTooShort = errors.New("name too short")
+	ErrNameTooLong  = errors.New("name too long")
+	ErrNameCharset  = errors.New("name contains forbidden characters")
+	ErrNameProfane  = errors.New("name contains a forbidden word")
+	ErrNameReserved = errors.New("name is reserved")
+	ErrNameTaken    = errors.New("name is already taken")
+)
+
+// Validator checks names against the length and charset rules, then the blocklist
+type Validator struct {
+	MinLength int
+	MaxLength int
+	blocklist atomic.Pointer[Blocklist]
+}
+
+var defaultValidator = NewValidator()
+
+// NewValidator creates a validator using the default rules and the built-in blocklist
+func NewValidator() *Validator {
+	v := &Validator{MinLength: DefaultMinLength, MaxLength: DefaultMaxLength}
+	v.blocklist.Store(BuiltinBlocklist())
+	return v
+}
+
+// Default returns the validator shared by the callers that don't load their own blocklist
+func Default() *Validator {
+	return defaultValidator
+}
+
+// Validate checks a name with the default validator
+func Validate(name string) error {
+	return defaultValidator.Validate(name)
+}
+
+// Validate returns nil if the name can be given to a character
+func (v *Validator) Validate(name string) error {
+	length := utf8.RuneCountInString(name)
+	if length < v.MinLength {
+		return fmt.Errorf("%w: %d characters, at least %d expected", ErrNameTooShort, length, v.MinLength)
+	}
+	if length > v.MaxLength {
+		return fmt.Errorf("%w: %d characters, at most %d expected", ErrNameTooLong, length, v.MaxLength)
+	}
+
+	for _, r := range name {
+		if !isAllowed(r) {
+			return fmt.Errorf("%w: %q", ErrNameCharset, r)
+		}
+	}
+
+	return v.Blocklist().Check(name)
+}
+
+// Blocklist returns the blocklist currently in use
+func (v *Validator) Blocklist() *Blocklist {
+	return v.blocklist.Load()
+}
+
+// SetBlocklist replaces the blocklist, the validations running concurrently keep the previous one
+func (v *Validator) SetBlocklist(blocklist *Blocklist) {
+	if blocklist == nil {
+		blocklist = BuiltinBlocklist()
+	}
+	v.blocklist.Store(blocklist)
+}
+
+// isAllowed reports whether the rune can be typed by every client: ASCII letters and digits
+func isAllowed(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
@@ -0,0 +1,112 @@
+package names
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"valid", "Tester", nil},
+		{"digits", "Tester42", nil},
+		{"too short", "Ab", ErrNameTooShort},
+		{"too long", strings.Repeat("a", DefaultMaxLength+1), ErrNameTooLong},
+		{"space", "Te ster", ErrNameCharset},
+		{"accent", "Testér", ErrNameCharset},
+		{"reserved", "Admin", ErrNameReserved},
+		{"reserved any case", "GAMEMASTER", ErrNameReserved},
+		{"profane", "xXShitXx", ErrNameProfane},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.input)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("Validate(%q) = %v, want %v", tt.input, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBlocklist(t *testing.T) {
+	blocklist, err := ParseBlocklist(strings.NewReader("# Comment\n\nbadword\n= Bartz\n"))
+	if err != nil {
+		t.Fatalf("ParseBlocklist() error = %v", err)
+	}
+
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"Mybadword", ErrNameProfane},
+		{"bartz", ErrNameReserved},
+		{"Admin", ErrNameReserved},
+		{"Tester", nil},
+	}
+
+	for _, tt := range tests {
+		if err := blocklist.Check(tt.input); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("Check(%q) = %v, want %v", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestWatchBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte("=Tester\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	v := NewValidator()
+	if err := v.LoadBlocklist(path); err != nil {
+		t.Fatalf("LoadBlocklist() error = %v", err)
+	}
+	if err := v.Validate("Tester"); !errors.Is(err, ErrNameReserved) {
+		t.Fatalf("Validate() = %v, want %v", err, ErrNameReserved)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go v.WatchBlocklist(path, 10*time.Millisecond, stop, nil)
+
+	// Rewrite the file with another size, so the change is noticed even with a coarse mtime
+	if err := os.WriteFile(path, []byte("=Somebody\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for v.Validate("Tester") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the blocklist was never reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := v.Validate("Somebody"); !errors.Is(err, ErrNameReserved) {
+		t.Errorf("Validate() = %v, want %v", err, ErrNameReserved)
+	}
+}
+
+func TestReservations(t *testing.T) {
+	r := NewReservations()
+
+	if err := r.Reserve("Tester"); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if err := r.Reserve("TESTER"); !errors.Is(err, ErrNameTaken) {
+		t.Fatalf("Reserve() = %v, want %v", err, ErrNameTaken)
+	}
+
+	r.Release("tester")
+	if r.Taken("Tester") {
+		t.Fatal("the name is still taken after being released")
+	}
+}
@@ -0,0 +1,49 @@
+package names
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Reservations keeps the names in use, so two characters can't end up with the same one.
+// Names are compared regardless of their case.
+type Reservations struct {
+	names map[string]bool
+	mu    sync.Mutex
+}
+
+func NewReservations() *Reservations {
+	return &Reservations{names: make(map[string]bool)}
+}
+
+// Reserve takes a name, or returns ErrNameTaken if somebody already has it
+func (r *Reservations) Reserve(name string) error {
+	key := strings.ToLower(name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[key] {
+		return fmt.Errorf("%w: %s", ErrNameTaken, name)
+	}
+
+	r.names[key] = true
+	return nil
+}
+
+// Release frees a name, for instance when the character creation failed
+func (r *Reservations) Release(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.names, strings.ToLower(name))
+}
+
+// Taken reports whether the name is in use
+func (r *Reservations) Taken(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.names[strings.ToLower(name)]
+}
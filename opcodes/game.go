@@ -0,0 +1,175 @@
+package opcodes
+
+// Packets sent by the client to the game server
+const (
+	GameClientProtocolVersion        byte = 0x00
+	GameClientMoveBackwardToLocation byte = 0x01
+	GameClientEnterWorld             byte = 0x03
+	GameClientAction                 byte = 0x04
+	GameClientAuthLogin              byte = 0x08
+	GameClientLogout                 byte = 0x09
+	GameClientCharacterCreate        byte = 0x0b
+	GameClientCharacterSelected      byte = 0x0d
+	GameClientRequestNewCharacter    byte = 0x0e
+	GameClientRequestSocialAction    byte = 0x1b
+	GameClientRequestSellItem        byte = 0x1e
+	GameClientRequestBuyItem         byte = 0x1f
+	GameClientRequestBypassToServer  byte = 0x21
+	GameClientSendWarehouseDeposit   byte = 0x31
+	GameClientSendWarehouseWithdraw  byte = 0x32
+	GameClientRequestShortCutReg     byte = 0x33
+	GameClientRequestShortCutDel     byte = 0x35
+	GameClientRequestTargetCanceld   byte = 0x37
+	GameClientSay2                   byte = 0x38
+	GameClientRequestActionUse       byte = 0x45
+	GameClientRequestRestart         byte = 0x46
+	GameClientValidatePosition       byte = 0x48
+	GameClientSendBypassBuildCmd     byte = 0x5b // A // command typed by a GM, without the slashes
+	GameClientRequestFriendInvite    byte = 0x5e
+	GameClientRequestAnswerFriend    byte = 0x5f
+	GameClientRequestFriendList      byte = 0x60
+	GameClientRequestFriendDel       byte = 0x61
+	GameClientRequestQuestList       byte = 0x63
+	GameClientRequestQuestAbort      byte = 0x64
+	GameClientExtended               byte = 0xd0 // Followed by a 2 bytes sub-opcode
+)
+
+// Packets sent by the game server to the client
+const (
+	GameServerCryptInit             byte = 0x00
+	GameServerMoveToLocation        byte = 0x01
+	GameServerCharInfo              byte = 0x03
+	GameServerUserInfo              byte = 0x04
+	GameServerSpawnItem             byte = 0x0b
+	GameServerDropItem              byte = 0x0c
+	GameServerGetItem               byte = 0x0d
+	GameServerStatusUpdate          byte = 0x0e
+	GameServerNpcHtmlMessage        byte = 0x0f
+	GameServerSellList              byte = 0x10
+	GameServerBuyList               byte = 0x11
+	GameServerDeleteObject          byte = 0x12
+	GameServerCharSelected          byte = 0x15
+	GameServerNpcInfo               byte = 0x16
+	GameServerItemList              byte = 0x1b
+	GameServerSunRise               byte = 0x1c
+	GameServerSunSet                byte = 0x1d
+	GameServerCharList              byte = 0x1f
+	GameServerCharTemplate          byte = 0x23
+	GameServerCharCreateOk          byte = 0x25
+	GameServerCharCreateFail        byte = 0x26
+	GameServerTeleportToLocation    byte = 0x28
+	GameServerTargetUnselected      byte = 0x2a
+	GameServerSocialAction          byte = 0x2d
+	GameServerChangeMoveType        byte = 0x2e
+	GameServerChangeWaitType        byte = 0x2f
+	GameServerWarehouseDepositList  byte = 0x41
+	GameServerWarehouseWithdrawList byte = 0x42
+	GameServerShortCutRegister      byte = 0x44
+	GameServerCreatureSay           byte = 0x4a
+	GameServerRestartResponse       byte = 0x5f
+	GameServerValidateLocation      byte = 0x61
+	GameServerSystemMessage         byte = 0x64
+	GameServerAskJoinFriend         byte = 0x7d
+	GameServerLogoutOk              byte = 0x7e
+	GameServerAbnormalStatusUpdate  byte = 0x7f
+	GameServerQuestList             byte = 0x80
+	GameServerMyTargetSelected      byte = 0xa6
+	GameServerClientSetTime         byte = 0xec
+	GameServerFriendList            byte = 0xfa
+	GameServerExtended              byte = 0xfe // Followed by a 2 bytes sub-opcode
+)
+
+var gameClientNames = map[byte]string{
+	GameClientProtocolVersion:        "ProtocolVersion",
+	GameClientMoveBackwardToLocation: "MoveBackwardToLocation",
+	GameClientEnterWorld:             "EnterWorld",
+	GameClientAction:                 "Action",
+	GameClientAuthLogin:              "AuthLogin",
+	GameClientLogout:                 "Logout",
+	GameClientCharacterCreate:        "CharacterCreate",
+	GameClientCharacterSelected:      "CharacterSelected",
+	GameClientRequestNewCharacter:    "RequestNewCharacter",
+	GameClientRequestSocialAction:    "RequestSocialAction",
+	GameClientRequestSellItem:        "RequestSellItem",
+	GameClientRequestBuyItem:         "RequestBuyItem",
+	GameClientRequestBypassToServer:  "RequestBypassToServer",
+	GameClientSendWarehouseDeposit:   "SendWareHouseDepositList",
+	GameClientSendWarehouseWithdraw:  "SendWareHouseWithDrawList",
+	GameClientRequestShortCutReg:     "RequestShortCutReg",
+	GameClientRequestShortCutDel:     "RequestShortCutDel",
+	GameClientRequestTargetCanceld:   "RequestTargetCanceld",
+	GameClientSay2:                   "Say2",
+	GameClientRequestActionUse:       "RequestActionUse",
+	GameClientRequestRestart:         "RequestRestart",
+	GameClientValidatePosition:       "ValidatePosition",
+	GameClientSendBypassBuildCmd:     "SendBypassBuildCmd",
+	GameClientRequestFriendInvite:    "RequestFriendInvite",
+	GameClientRequestAnswerFriend:    "RequestAnswerFriendInvite",
+	GameClientRequestFriendList:      "RequestFriendList",
+	GameClientRequestFriendDel:       "RequestFriendDel",
+	GameClientRequestQuestList:       "RequestQuestList",
+	GameClientRequestQuestAbort:      "RequestQuestAbort",
+	GameClientExtended:               "Extended",
+}
+
+var gameServerNames = map[byte]string{
+	GameServerCryptInit:             "CryptInit",
+	GameServerMoveToLocation:        "MoveToLocation",
+	GameServerCharInfo:              "CharInfo",
+	GameServerUserInfo:              "UserInfo",
+	GameServerSpawnItem:             "SpawnItem",
+	GameServerDropItem:              "DropItem",
+	GameServerGetItem:               "GetItem",
+	GameServerStatusUpdate:          "StatusUpdate",
+	GameServerNpcHtmlMessage:        "NpcHtmlMessage",
+	GameServerSellList:              "SellList",
+	GameServerBuyList:               "BuyList",
+	GameServerDeleteObject:          "DeleteObject",
+	GameServerCharSelected:          "CharSelected",
+	GameServerNpcInfo:               "NpcInfo",
+	GameServerItemList:              "ItemList",
+	GameServerSunRise:               "SunRise",
+	GameServerSunSet:                "SunSet",
+	GameServerCharList:              "CharList",
+	GameServerCharTemplate:          "CharTemplate",
+	GameServerCharCreateOk:          "CharCreateOk",
+	GameServerCharCreateFail:        "CharCreateFail",
+	GameServerTeleportToLocation:    "TeleportToLocation",
+	GameServerTargetUnselected:      "TargetUnselected",
+	GameServerSocialAction:          "SocialAction",
+	GameServerChangeMoveType:        "ChangeMoveType",
+	GameServerChangeWaitType:        "ChangeWaitType",
+	GameServerWarehouseDepositList:  "WareHouseDepositList",
+	GameServerWarehouseWithdrawList: "WareHouseWithdrawalList",
+	GameServerShortCutRegister:      "ShortCutRegister",
+	GameServerCreatureSay:           "CreatureSay",
+	GameServerRestartResponse:       "RestartResponse",
+	GameServerValidateLocation:      "ValidateLocation",
+	GameServerSystemMessage:         "SystemMessage",
+	GameServerAskJoinFriend:         "AskJoinFriend",
+	GameServerLogoutOk:              "LogoutOk",
+	GameServerAbnormalStatusUpdate:  "AbnormalStatusUpdate",
+	GameServerQuestList:             "QuestList",
+	GameServerMyTargetSelected:      "MyTargetSelected",
+	GameServerClientSetTime:         "ClientSetTime",
+	GameServerFriendList:            "FriendList",
+	GameServerExtended:              "Extended",
+}
+
+// Extended packets sent by the client to the game server (after GameClientExtended)
+const (
+	GameClientExRequestManorList uint16 = 0x08
+)
+
+// Extended packets sent by the game server to the client (after GameServerExtended)
+const (
+	GameServerExSendManorList uint16 = 0x1b
+)
+
+var gameClientExtendedNames = map[uint16]string{
+	GameClientExRequestManorList: "RequestManorList",
+}
+
+var gameServerExtendedNames = map[uint16]string{
+	GameServerExSendManorList: "ExSendManorList",
+}
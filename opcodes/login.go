@@ -0,0 +1,59 @@
+package opcodes
+
+// Packets sent by the client to the login server
+const (
+	LoginClientRequestAuthLogin  byte = 0x00
+	LoginClientRequestPlay       byte = 0x02
+	LoginClientRequestServerList byte = 0x05
+	LoginClientRequestGGAuth     byte = 0x07
+)
+
+// Packets sent by the login server to the client
+const (
+	LoginServerInit       byte = 0x00
+	LoginServerLoginFail  byte = 0x01
+	LoginServerLoginOk    byte = 0x03
+	LoginServerServerList byte = 0x04
+	LoginServerPlayFail   byte = 0x06
+	LoginServerPlayOk     byte = 0x07
+	LoginServerGGAuth     byte = 0x0b
+)
+
+var loginClientNames = map[byte]string{
+	LoginClientRequestAuthLogin:  "RequestAuthLogin",
+	LoginClientRequestPlay:       "RequestPlay",
+	LoginClientRequestServerList: "RequestServerList",
+	LoginClientRequestGGAuth:     "RequestGGAuth",
+}
+
+var loginServerNames = map[byte]string{
+	LoginServerInit:       "Init",
+	LoginServerLoginFail:  "LoginFail",
+	LoginServerLoginOk:    "LoginOk",
+	LoginServerServerList: "ServerList",
+	LoginServerPlayFail:   "PlayFail",
+	LoginServerPlayOk:     "PlayOk",
+	LoginServerGGAuth:     "GGAuth",
+}
+
+// Packets sent by a game server to the login server
+const (
+	LinkGameServerRegister  byte = 0x00
+	LinkGameServerHeartbeat byte = 0x01
+)
+
+var linkGameServerNames = map[byte]string{
+	LinkGameServerRegister:  "RegisterGameServer",
+	LinkGameServerHeartbeat: "Heartbeat",
+}
+
+// Packets sent by the login server to a game server
+const (
+	LinkLoginServerRegisterResult byte = 0x00
+	LinkLoginServerPlayerAuth     byte = 0x01
+)
+
+var linkLoginServerNames = map[byte]string{
+	LinkLoginServerRegisterResult: "RegisterResult",
+	LinkLoginServerPlayerAuth:     "PlayerAuth",
+}
@@ -0,0 +1,127 @@
+// Package opcodes names the packet opcodes shared by the servers and the client toolkit
+package opcodes
+
+import "fmt"
+
+// Protocol identifies one of the L2Go protocols
+type Protocol int
+
+const (
+	Login Protocol = iota
+	Game
+	Link // Game server <-> login server
+)
+
+func (p Protocol) String() string {
+	switch p {
+	case Login:
+		return "Login"
+	case Game:
+		return "Game"
+	case Link:
+		return "Link"
+	default:
+		return "Unknown"
+	}
+}
+
+// Direction tells who sends a packet
+type Direction int
+
+const (
+	ClientToServer Direction = iota
+	ServerToClient
+)
+
+func (d Direction) String() string {
+	if d == ClientToServer {
+		return "C->S"
+	}
+	return "S->C"
+}
+
+// Protocol revisions spoken by the servers
+const (
+	LoginProtocolRevision = 0x785a
+	GameProtocolRevision  = 419
+)
+
+// NullCryptoMarker ends the Init and CryptInit packets of the servers running in the null crypto debug
+// mode, their packets going in clear for the loopback tests and the packet captures
+const NullCryptoMarker uint32 = 0x4c4c554e // NULL
+
+// CompressionMarker precedes the compression offer trailing the ProtocolVersion and CryptInit packets, and
+// the registration of a game server on the login server along with its result, see protocol.CompressionOptions
+const CompressionMarker uint32 = 0x42494c5a // ZLIB
+
+var names = map[Protocol]map[Direction]map[byte]string{
+	Login: {
+		ClientToServer: loginClientNames,
+		ServerToClient: loginServerNames,
+	},
+	Game: {
+		ClientToServer: gameClientNames,
+		ServerToClient: gameServerNames,
+	},
+	Link: {
+		ClientToServer: linkGameServerNames,
+		ServerToClient: linkLoginServerNames,
+	},
+}
+
+// IsExtended reports whether opcode is followed by a 2 bytes sub-opcode
+func IsExtended(protocol Protocol, direction Direction, opcode byte) bool {
+	if protocol != Game {
+		return false
+	}
+	if direction == ClientToServer {
+		return opcode == GameClientExtended
+	}
+	return opcode == GameServerExtended
+}
+
+// ExtendedName returns the packet name of an extended game packet, for logging purposes
+func ExtendedName(direction Direction, subOpcode uint16) string {
+	table := gameServerExtendedNames
+	if direction == ClientToServer {
+		table = gameClientExtendedNames
+	}
+
+	if name, ok := table[subOpcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("UnknownEx(0x%04X)", subOpcode)
+}
+
+// ExtendedNames returns a copy of the extended packet names of a direction
+func ExtendedNames(direction Direction) map[uint16]string {
+	table := gameServerExtendedNames
+	if direction == ClientToServer {
+		table = gameClientExtendedNames
+	}
+
+	result := make(map[uint16]string, len(table))
+	for subOpcode, name := range table {
+		result[subOpcode] = name
+	}
+	return result
+}
+
+// Names returns a copy of the packet names of a protocol direction
+func Names(protocol Protocol, direction Direction) map[byte]string {
+	table := names[protocol][direction]
+
+	result := make(map[byte]string, len(table))
+	for opcode, name := range table {
+		result[opcode] = name
+	}
+	return result
+}
+
+// Name returns the packet name of an opcode, for logging purposes
+func Name(protocol Protocol, direction Direction, opcode byte) string {
+	if name, ok := names[protocol][direction][opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(0x%02X)", opcode)
+}
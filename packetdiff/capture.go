@@ -0,0 +1,136 @@
+// Package packetdiff aligns two captures of decrypted packets, such as the toolkit client and a retail
+// client performing the same action, and shows where they diverge: the packets only one of them sent or
+// got, then the fields and the bytes which differ between the packets both exchanged.
+package packetdiff
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/protocol"
+)
+
+var ErrInvalidCapture = errors.New("invalid capture")
+
+// Packet is a decrypted packet of a capture.
+//
+// A capture is a text file holding a packet per line: its protocol (Login or Game), its direction
+// (C->S or S->C) and its bytes in hexadecimal, the opcode first, spaces allowed between them:
+//
+//	# The client entering the world
+//	Game C->S 03
+//	Game S->C 04 0a000000 14000000
+//
+// The blank lines and the ones starting with # are skipped. String gives the line of a packet, so the
+// OnRawPacket callback of a client records a capture by printing the packets it sees.
+type Packet struct {
+	Protocol  opcodes.Protocol
+	Direction opcodes.Direction
+	Opcode    byte
+	Payload   []byte // Without the opcode
+	Line      int    // Line of the capture, 0 when the packet wasn't read from one
+}
+
+// Key identifies the kind of a packet, the sub-opcode of the extended packets included
+func (p Packet) Key() protocol.PacketKey {
+	if opcodes.IsExtended(p.Protocol, p.Direction, p.Opcode) && len(p.Payload) >= 2 {
+		return protocol.ExtendedKey(p.Opcode, uint16(p.Payload[0])|uint16(p.Payload[1])<<8)
+	}
+	return protocol.Key(p.Opcode)
+}
+
+// Name returns the name of the packet, for the reports
+func (p Packet) Name() string {
+	if key := p.Key(); key.SubOpcode != 0 {
+		return opcodes.ExtendedName(p.Direction, key.SubOpcode)
+	}
+	return opcodes.Name(p.Protocol, p.Direction, p.Opcode)
+}
+
+// Bytes returns the packet as sent, the opcode followed by the payload
+func (p Packet) Bytes() []byte {
+	return append([]byte{p.Opcode}, p.Payload...)
+}
+
+// String returns the line of the packet in a capture
+func (p Packet) String() string {
+	return fmt.Sprintf("%s %s %s", p.Protocol, p.Direction, hex.EncodeToString(p.Bytes()))
+}
+
+// ReadCapture reads the packets of a capture file
+func ReadCapture(path string) ([]Packet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capture %s: %w", path, err)
+	}
+	defer file.Close()
+
+	captured, err := ParseCapture(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return captured, nil
+}
+
+// ParseCapture reads the packets of a capture
+func ParseCapture(r io.Reader) ([]Packet, error) {
+	var captured []Packet
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		packet, err := parsePacket(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidCapture, line, err)
+		}
+		packet.Line = line
+		captured = append(captured, packet)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return captured, nil
+}
+
+// parsePacket reads the line of a packet
+func parsePacket(text string) (Packet, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 3 {
+		return Packet{}, errors.New("want a protocol, a direction and the bytes of the packet")
+	}
+
+	var packet Packet
+	switch strings.ToLower(fields[0]) {
+	case "login":
+		packet.Protocol = opcodes.Login
+	case "game":
+		packet.Protocol = opcodes.Game
+	default:
+		return Packet{}, fmt.Errorf("unknown protocol %q, must be Login or Game", fields[0])
+	}
+	switch strings.ToUpper(fields[1]) {
+	case opcodes.ClientToServer.String():
+		packet.Direction = opcodes.ClientToServer
+	case opcodes.ServerToClient.String():
+		packet.Direction = opcodes.ServerToClient
+	default:
+		return Packet{}, fmt.Errorf("unknown direction %q, must be C->S or S->C", fields[1])
+	}
+
+	data, err := hex.DecodeString(strings.Join(fields[2:], ""))
+	if err != nil {
+		return Packet{}, err
+	}
+	packet.Opcode, packet.Payload = data[0], data[1:]
+	return packet, nil
+}
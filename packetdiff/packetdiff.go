@@ -0,0 +1,248 @@
+package packetdiff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/frostwind/l2go/gameserver/clientpackets"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// ROW_SIZE is the number of bytes per row of the hexdumps
+const ROW_SIZE = 16
+
+// layouts are the tagged structs the fields of the game packets are decoded with
+var layouts = map[opcodes.Direction]map[byte]reflect.Type{
+	opcodes.ClientToServer: {
+		opcodes.GameClientProtocolVersion:        reflect.TypeFor[clientpackets.ProtocolVersion](),
+		opcodes.GameClientMoveBackwardToLocation: reflect.TypeFor[clientpackets.MoveBackwardToLocation](),
+		opcodes.GameClientAction:                 reflect.TypeFor[clientpackets.Action](),
+		opcodes.GameClientSay2:                   reflect.TypeFor[clientpackets.Say2](),
+		opcodes.GameClientRequestActionUse:       reflect.TypeFor[clientpackets.RequestActionUse](),
+		opcodes.GameClientRequestSocialAction:    reflect.TypeFor[clientpackets.RequestSocialAction](),
+		opcodes.GameClientValidatePosition:       reflect.TypeFor[clientpackets.ValidatePosition](),
+		opcodes.GameClientRequestBypassToServer:  reflect.TypeFor[clientpackets.RequestBypassToServer](),
+		opcodes.GameClientSendBypassBuildCmd:     reflect.TypeFor[clientpackets.SendBypassBuildCmd](),
+		opcodes.GameClientRequestFriendInvite:    reflect.TypeFor[clientpackets.RequestFriendInvite](),
+		opcodes.GameClientRequestAnswerFriend:    reflect.TypeFor[clientpackets.RequestAnswerFriendInvite](),
+		opcodes.GameClientRequestFriendDel:       reflect.TypeFor[clientpackets.RequestFriendDel](),
+		opcodes.GameClientRequestQuestAbort:      reflect.TypeFor[clientpackets.RequestQuestAbort](),
+	},
+	opcodes.ServerToClient: {
+		opcodes.GameServerAskJoinFriend:      reflect.TypeFor[serverpackets.AskJoinFriend](),
+		opcodes.GameServerChangeWaitType:     reflect.TypeFor[serverpackets.ChangeWaitType](),
+		opcodes.GameServerCharCreateFail:     reflect.TypeFor[serverpackets.CharCreateFail](),
+		opcodes.GameServerCharInfo:           reflect.TypeFor[serverpackets.CharInfo](),
+		opcodes.GameServerClientSetTime:      reflect.TypeFor[serverpackets.ClientSetTime](),
+		opcodes.GameServerCreatureSay:        reflect.TypeFor[serverpackets.CreatureSay](),
+		opcodes.GameServerDeleteObject:       reflect.TypeFor[serverpackets.DeleteObject](),
+		opcodes.GameServerDropItem:           reflect.TypeFor[serverpackets.DropItem](),
+		opcodes.GameServerGetItem:            reflect.TypeFor[serverpackets.GetItem](),
+		opcodes.GameServerMoveToLocation:     reflect.TypeFor[serverpackets.MoveToLocation](),
+		opcodes.GameServerMyTargetSelected:   reflect.TypeFor[serverpackets.MyTargetSelected](),
+		opcodes.GameServerNpcInfo:            reflect.TypeFor[serverpackets.NpcInfo](),
+		opcodes.GameServerRestartResponse:    reflect.TypeFor[serverpackets.RestartResponse](),
+		opcodes.GameServerSocialAction:       reflect.TypeFor[serverpackets.SocialAction](),
+		opcodes.GameServerSpawnItem:          reflect.TypeFor[serverpackets.SpawnItem](),
+		opcodes.GameServerTargetUnselected:   reflect.TypeFor[serverpackets.TargetUnselected](),
+		opcodes.GameServerTeleportToLocation: reflect.TypeFor[serverpackets.TeleportToLocation](),
+		opcodes.GameServerValidateLocation:   reflect.TypeFor[serverpackets.ValidateLocation](),
+	},
+}
+
+// Layout returns the tagged struct the payload of a packet decodes into, when its layout is known
+func Layout(packet Packet) (reflect.Type, bool) {
+	if packet.Protocol != opcodes.Game {
+		return nil, false
+	}
+	layout, ok := layouts[packet.Direction][packet.Opcode]
+	return layout, ok
+}
+
+// Field is a field of a packet holding different values in the two captures
+type Field struct {
+	Name string
+	A, B string
+}
+
+// Entry is a step of the alignment: a packet of both captures, or of only one of them when A or B is nil
+type Entry struct {
+	A, B      *Packet
+	Fields    []Field // Differing fields, when the layout of the packet is known
+	Divergent []int   // Offsets of the differing bytes, the opcode at 0, up to the end of the longer packet
+	Undecoded string  // Why the fields couldn't be compared, despite a known layout
+}
+
+// Same reports whether the packet is the same in both captures
+func (e Entry) Same() bool {
+	return e.A != nil && e.B != nil && len(e.Divergent) == 0
+}
+
+// Diff aligns the packets of two captures by their kind, keeping the longest common sequence, and
+// compares the packets both captures hold. It is quadratic in the length of the captures, which are
+// meant to hold a single action rather than whole sessions.
+func Diff(a, b []Packet) []Entry {
+	// lengths[i][j] is the length of the common sequence of a[i:] and b[j:]
+	lengths := make([][]int32, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if sameKind(a[i], b[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var entries []Entry
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && sameKind(a[i], b[j]):
+			entries = append(entries, compare(&a[i], &b[j]))
+			i, j = i+1, j+1
+		case j == len(b) || (i < len(a) && lengths[i+1][j] >= lengths[i][j+1]):
+			entries = append(entries, Entry{A: &a[i]})
+			i++
+		default:
+			entries = append(entries, Entry{B: &b[j]})
+			j++
+		}
+	}
+	return entries
+}
+
+// sameKind reports whether two packets are of the same kind, so the alignment pairs them
+func sameKind(a, b Packet) bool {
+	return a.Protocol == b.Protocol && a.Direction == b.Direction && a.Key() == b.Key()
+}
+
+// compare lists the bytes and the fields differing between two packets of the same kind
+func compare(a, b *Packet) Entry {
+	entry := Entry{A: a, B: b}
+	dataA, dataB := a.Bytes(), b.Bytes()
+	for offset := range max(len(dataA), len(dataB)) {
+		if offset >= len(dataA) || offset >= len(dataB) || dataA[offset] != dataB[offset] {
+			entry.Divergent = append(entry.Divergent, offset)
+		}
+	}
+	if len(entry.Divergent) == 0 {
+		return entry
+	}
+
+	layout, ok := Layout(*a)
+	if !ok {
+		return entry
+	}
+	valueA, valueB := reflect.New(layout), reflect.New(layout)
+	if err := packets.Unmarshal(a.Payload, valueA.Interface()); err != nil {
+		entry.Undecoded = fmt.Sprintf("the first packet doesn't decode as %s: %v", layout.Name(), err)
+		return entry
+	}
+	if err := packets.Unmarshal(b.Payload, valueB.Interface()); err != nil {
+		entry.Undecoded = fmt.Sprintf("the second packet doesn't decode as %s: %v", layout.Name(), err)
+		return entry
+	}
+	for index := range layout.NumField() {
+		if tag := layout.Field(index).Tag.Get("l2"); tag == "" || tag == "-" {
+			continue
+		}
+		fieldA, fieldB := valueA.Elem().Field(index).Interface(), valueB.Elem().Field(index).Interface()
+		if !reflect.DeepEqual(fieldA, fieldB) {
+			entry.Fields = append(entry.Fields, Field{Name: layout.Field(index).Name, A: fmt.Sprintf("%v", fieldA), B: fmt.Sprintf("%v", fieldB)})
+		}
+	}
+	return entry
+}
+
+// Divergent reports whether the captures differ, by a packet or by a byte
+func Divergent(entries []Entry) bool {
+	for _, entry := range entries {
+		if !entry.Same() {
+			return true
+		}
+	}
+	return false
+}
+
+// Write reports the alignment of two captures named nameA and nameB. The packets the same in both are
+// only listed when all is set, the others are marked - when only in the first capture, + when only in
+// the second and ~ when they differ, followed by their fields and the rows of their hexdumps which diverge.
+func Write(w io.Writer, entries []Entry, nameA, nameB string, all bool) {
+	var same, differ, onlyA, onlyB int
+	for _, entry := range entries {
+		switch {
+		case entry.B == nil:
+			onlyA++
+			fmt.Fprintf(w, "- %s %s %s (line %d), only in %s\n", entry.A.Protocol, entry.A.Direction, entry.A.Name(), entry.A.Line, nameA)
+		case entry.A == nil:
+			onlyB++
+			fmt.Fprintf(w, "+ %s %s %s (line %d), only in %s\n", entry.B.Protocol, entry.B.Direction, entry.B.Name(), entry.B.Line, nameB)
+		case entry.Same():
+			same++
+			if all {
+				fmt.Fprintf(w, "= %s %s %s\n", entry.A.Protocol, entry.A.Direction, entry.A.Name())
+			}
+		default:
+			differ++
+			fmt.Fprintf(w, "~ %s %s %s (lines %d and %d), %d of %d and %d bytes\n", entry.A.Protocol, entry.A.Direction, entry.A.Name(),
+				entry.A.Line, entry.B.Line, len(entry.Divergent), len(entry.A.Payload)+1, len(entry.B.Payload)+1)
+			for _, field := range entry.Fields {
+				fmt.Fprintf(w, "    %s: %s -> %s\n", field.Name, field.A, field.B)
+			}
+			if entry.Undecoded != "" {
+				fmt.Fprintf(w, "    %s\n", entry.Undecoded)
+			}
+			writeHexdump(w, entry)
+		}
+	}
+	fmt.Fprintf(w, "%d packets the same, %d differing, %d only in %s, %d only in %s\n", same, differ, onlyA, nameA, onlyB, nameB)
+}
+
+// writeHexdump writes the rows of two packets holding divergent bytes, marking them
+func writeHexdump(w io.Writer, entry Entry) {
+	dataA, dataB := entry.A.Bytes(), entry.B.Bytes()
+	divergent := make(map[int]bool, len(entry.Divergent))
+	for _, offset := range entry.Divergent {
+		divergent[offset] = true
+	}
+
+	length := max(len(dataA), len(dataB))
+	for row := 0; row < length; row += ROW_SIZE {
+		end := min(row+ROW_SIZE, length)
+		var marks bytes.Buffer
+		for offset := row; offset < end; offset++ {
+			if divergent[offset] {
+				marks.WriteString(" ^^")
+			} else {
+				marks.WriteString("   ")
+			}
+		}
+		if !bytes.Contains(marks.Bytes(), []byte("^")) {
+			continue
+		}
+		fmt.Fprintf(w, "    %04x a:%s\n", row, hexRow(dataA, row, end))
+		fmt.Fprintf(w, "         b:%s\n", hexRow(dataB, row, end))
+		fmt.Fprintf(w, "           %s\n", bytes.TrimRight(marks.Bytes(), " "))
+	}
+}
+
+// hexRow returns the bytes of a row of a hexdump, with dashes past the end of the packet
+func hexRow(data []byte, row, end int) string {
+	var buffer bytes.Buffer
+	for offset := row; offset < end; offset++ {
+		if offset < len(data) {
+			fmt.Fprintf(&buffer, " %02x", data[offset])
+		} else {
+			buffer.WriteString(" --")
+		}
+	}
+	return buffer.String()
+}
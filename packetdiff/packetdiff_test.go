@@ -0,0 +1,105 @@
+package packetdiff
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/opcodes"
+)
+
+func TestParseCapture(t *testing.T) {
+	tests := []struct {
+		name    string
+		capture string
+		want    []Packet
+		wantErr error
+	}{
+		{
+			name:    "packets",
+			capture: "# The client entering the world\n\nGame C->S 03\ngame s->c 04 0a00 0000\nLogin C->S 07 01020304\n",
+			want: []Packet{
+				{Protocol: opcodes.Game, Direction: opcodes.ClientToServer, Opcode: 0x03, Payload: []byte{}, Line: 3},
+				{Protocol: opcodes.Game, Direction: opcodes.ServerToClient, Opcode: 0x04, Payload: []byte{0x0a, 0, 0, 0}, Line: 4},
+				{Protocol: opcodes.Login, Direction: opcodes.ClientToServer, Opcode: 0x07, Payload: []byte{1, 2, 3, 4}, Line: 5},
+			},
+		},
+		{name: "unknown protocol", capture: "Link C->S 00", wantErr: ErrInvalidCapture},
+		{name: "unknown direction", capture: "Game C 00", wantErr: ErrInvalidCapture},
+		{name: "no bytes", capture: "Game C->S", wantErr: ErrInvalidCapture},
+		{name: "odd hexadecimal", capture: "Game C->S 0a0", wantErr: ErrInvalidCapture},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCapture(strings.NewReader(tt.capture))
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("ParseCapture() error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.EqualFunc(got, tt.want, func(a, b Packet) bool {
+				return a.Protocol == b.Protocol && a.Direction == b.Direction && a.Opcode == b.Opcode && bytes.Equal(a.Payload, b.Payload) && a.Line == b.Line
+			}) {
+				t.Errorf("ParseCapture() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// A printed packet reads back the same
+	packet := Packet{Protocol: opcodes.Game, Direction: opcodes.ServerToClient, Opcode: 0x4a, Payload: []byte{1, 2}}
+	if got, err := ParseCapture(strings.NewReader(packet.String())); err != nil || len(got) != 1 || !bytes.Equal(got[0].Bytes(), packet.Bytes()) {
+		t.Errorf("ParseCapture(%q) = %+v, %v", packet.String(), got, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	say := func(name string) Packet {
+		data := serverpackets.NewCreatureSayPacket(0x10000001, serverpackets.CHAT_ALL, name, "hello")
+		return Packet{Protocol: opcodes.Game, Direction: opcodes.ServerToClient, Opcode: data[0], Payload: data[1:]}
+	}
+	packet := func(opcode byte, payload ...byte) Packet {
+		return Packet{Protocol: opcodes.Game, Direction: opcodes.ClientToServer, Opcode: opcode, Payload: payload}
+	}
+	toolkit := []Packet{packet(opcodes.GameClientEnterWorld), packet(opcodes.GameClientRequestFriendList), say("Tester"), packet(opcodes.GameClientLogout)}
+	retail := []Packet{packet(opcodes.GameClientEnterWorld), packet(opcodes.GameClientRequestQuestList), say("Retail"), packet(opcodes.GameClientLogout)}
+
+	entries := Diff(toolkit, retail)
+	var marks []string
+	for _, entry := range entries {
+		switch {
+		case entry.Same():
+			marks = append(marks, "=")
+		case entry.B == nil:
+			marks = append(marks, "-")
+		case entry.A == nil:
+			marks = append(marks, "+")
+		default:
+			marks = append(marks, "~")
+		}
+	}
+	if want := []string{"=", "-", "+", "~", "="}; !slices.Equal(marks, want) {
+		t.Fatalf("Diff() aligned the packets as %v, want %v", marks, want)
+	}
+
+	// The name is the only field differing, from the 10th byte of the packet
+	differing := entries[3]
+	if len(differing.Fields) != 1 || differing.Fields[0] != (Field{Name: "Name", A: "Tester", B: "Retail"}) {
+		t.Errorf("Diff() found the fields %+v to differ, want the name", differing.Fields)
+	}
+	if len(differing.Divergent) == 0 || differing.Divergent[0] != 9 {
+		t.Errorf("Diff() found the bytes %v to differ, want the name from 9", differing.Divergent)
+	}
+	if !Divergent(entries) || Divergent(Diff(toolkit, toolkit)) {
+		t.Error("Divergent() doesn't tell the captures differing from the same")
+	}
+
+	var report bytes.Buffer
+	Write(&report, entries, "toolkit", "retail", false)
+	for _, want := range []string{"- Game C->S RequestFriendList", "+ Game C->S RequestQuestList", "~ Game S->C CreatureSay", "Name: Tester -> Retail", "^^", "2 packets the same, 1 differing"} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("Write() missed %q in:\n%s", want, report.String())
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package packets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unicode/utf16"
+)
+
+// Decoder reads the fields of a packet, unlike Reader it remembers the first
+// read past the end of the data instead of returning zeroes silently
+type Decoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+// NewDecoder returns a decoder reading data from its start
+func NewDecoder(data []byte) *Decoder {
+	return &Decoder{data: data}
+}
+
+// Err returns the error of the first failed read, if any
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Remaining returns how many bytes are left to read
+func (d *Decoder) Remaining() int {
+	return len(d.data) - d.offset
+}
+
+// next returns the next n bytes, or nil once a read failed
+func (d *Decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if d.Remaining() < n {
+		d.err = fmt.Errorf("%w: %d bytes needed at offset %d, %d left", ErrInsufficientData, n, d.offset, d.Remaining())
+		return nil
+	}
+
+	data := d.data[d.offset : d.offset+n]
+	d.offset += n
+	return data
+}
+
+// U8 reads a byte
+func (d *Decoder) U8() uint8 {
+	if data := d.next(1); data != nil {
+		return data[0]
+	}
+	return 0
+}
+
+// U16 reads a little endian uint16
+func (d *Decoder) U16() uint16 {
+	if data := d.next(2); data != nil {
+		return binary.LittleEndian.Uint16(data)
+	}
+	return 0
+}
+
+// U32 reads a little endian uint32
+func (d *Decoder) U32() uint32 {
+	if data := d.next(4); data != nil {
+		return binary.LittleEndian.Uint32(data)
+	}
+	return 0
+}
+
+// U64 reads a little endian uint64
+func (d *Decoder) U64() uint64 {
+	if data := d.next(8); data != nil {
+		return binary.LittleEndian.Uint64(data)
+	}
+	return 0
+}
+
+// F64 reads a little endian float64
+func (d *Decoder) F64() float64 {
+	return math.Float64frombits(d.U64())
+}
+
+// Bool reads a byte, anything but 0 being true
+func (d *Decoder) Bool() bool {
+	return d.U8() != 0
+}
+
+// S reads a null terminated UTF-16LE string
+func (d *Decoder) S() string {
+	if d.err != nil {
+		return ""
+	}
+
+	var result []uint16
+
+	for {
+		data := d.next(2)
+		if data == nil {
+			d.err = fmt.Errorf("%w: unterminated string", ErrInvalidString)
+			return ""
+		}
+
+		char := binary.LittleEndian.Uint16(data)
+		if char == 0 {
+			break
+		}
+		result = append(result, char)
+	}
+
+	return string(utf16.Decode(result))
+}
+
+// B reads n bytes, the result being a copy
+func (d *Decoder) B(n int) []byte {
+	if data := d.next(n); data != nil {
+		return append([]byte(nil), data...)
+	}
+	return nil
+}
+
+// Rest reads everything left, the result being a copy
+func (d *Decoder) Rest() []byte {
+	return d.B(d.Remaining())
+}
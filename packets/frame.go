@@ -0,0 +1,47 @@
+package packets
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxFrameSize is the largest frame the 2 bytes length header can describe
+const MaxFrameSize = 0xffff
+
+var (
+	ErrFrameTooLarge = errors.New("frame exceeds the maximum packet size")
+	ErrFrameTooSmall = errors.New("frame is smaller than its header")
+	ErrIdleTimeout   = errors.New("no packet received before the idle timeout")
+)
+
+// ReadFrame reads a length prefixed packet and strips its header. The announced
+// size (header included) is checked against maxSize before anything is allocated,
+// a zero or negative maxSize meaning MaxFrameSize. The stream can't be trusted
+// anymore after an error, the connection should be closed.
+func ReadFrame(r io.Reader, maxSize int) ([]byte, error) {
+	if maxSize <= 0 || maxSize > MaxFrameSize {
+		maxSize = MaxFrameSize
+	}
+
+	var header [2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := int(binary.LittleEndian.Uint16(header[:]))
+	if size <= 2 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooSmall, size)
+	}
+	if size > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes announced, %d allowed", ErrFrameTooLarge, size, maxSize)
+	}
+
+	data := make([]byte, size-2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,41 @@
+package packets
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  []byte
+		maxSize int
+		want    []byte
+		wantErr error
+	}{
+		{"valid", []byte{0x05, 0x00, 0x01, 0x02, 0x03}, 16, []byte{0x01, 0x02, 0x03}, nil},
+		{"at the limit", []byte{0x04, 0x00, 0x01, 0x02}, 4, []byte{0x01, 0x02}, nil},
+		{"empty", []byte{0x02, 0x00}, 16, nil, ErrFrameTooSmall},
+		// Only the header is available, the body must not even be waited for
+		{"oversized", []byte{0xff, 0xff}, 1024, nil, ErrFrameTooLarge},
+		// Without a limit the body is expected, and missing
+		{"default limit", []byte{0xff, 0xff}, 0, nil, io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFrame(bytes.NewReader(tt.stream), tt.maxSize)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ReadFrame() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.want != nil && (err != nil || !bytes.Equal(got, tt.want)) {
+				t.Errorf("ReadFrame() = % x, %v, want % x", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package packets
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Packet structs describe their fields with the l2 tag:
+//
+//	type TeleportToLocation struct {
+//		ObjectID uint32 `l2:"u32"`
+//		X        int32  `l2:"u32"`
+//		Name     string `l2:"string"`
+//		Key      []byte `l2:"bytes:8"`
+//		Data     []byte `l2:"bytes"`
+//	}
+//
+// The numeric kinds are u8, u16, u32, u64 and f64, the signed fields being
+// written as their two's complement. bool is a byte, string a null terminated
+// UTF-16LE string, bytes:N exactly N bytes and bytes everything up to the end
+// of the packet, it can only be the last field. Fields without the tag, or
+// tagged "-", are ignored.
+//
+// The structs implementing PacketAppender and PacketUnmarshaler, usually
+// generated by packetgen for the hot paths, skip the reflection.
+
+var (
+	ErrUnsupportedType = errors.New("unsupported packet field")
+	ErrFieldSize       = errors.New("packet field has the wrong size")
+)
+
+// PacketAppender is implemented by the packets encoding themselves
+type PacketAppender interface {
+	AppendPacket(dst []byte) ([]byte, error)
+}
+
+// PacketUnmarshaler is implemented by the packets decoding themselves
+type PacketUnmarshaler interface {
+	UnmarshalPacket(data []byte) error
+}
+
+// fieldKind is the way a field is laid out in the packet
+type fieldKind int
+
+const (
+	kindU8 fieldKind = iota
+	kindU16
+	kindU32
+	kindU64
+	kindF64
+	kindBool
+	kindString
+	kindBytes
+	kindRest
+)
+
+type field struct {
+	index int
+	name  string
+	kind  fieldKind
+	size  int // Size of the bytes:N fields
+}
+
+var fieldsCache sync.Map // reflect.Type -> []field
+
+// Marshal encodes a tagged struct, or a pointer to one
+func Marshal(v interface{}) ([]byte, error) {
+	return AppendMarshal(nil, v)
+}
+
+// AppendMarshal encodes a tagged struct behind dst, which usually holds the opcode
+func AppendMarshal(dst []byte, v interface{}) ([]byte, error) {
+	if appender, ok := v.(PacketAppender); ok {
+		return appender.AppendPacket(dst)
+	}
+
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+
+	fields, err := fieldsOf(value.Type())
+	if err != nil {
+		return dst, err
+	}
+
+	w := &PacketWriter{data: dst}
+	for _, f := range fields {
+		fieldValue := value.Field(f.index)
+
+		switch f.kind {
+		case kindU8:
+			w.U8(uint8(integer(fieldValue)))
+		case kindU16:
+			w.U16(uint16(integer(fieldValue)))
+		case kindU32:
+			w.U32(uint32(integer(fieldValue)))
+		case kindU64:
+			w.U64(integer(fieldValue))
+		case kindF64:
+			w.F64(fieldValue.Float())
+		case kindBool:
+			w.Bool(fieldValue.Bool())
+		case kindString:
+			w.S(fieldValue.String())
+		case kindBytes:
+			if fieldValue.Len() != f.size {
+				return w.data, fmt.Errorf("%w: %s holds %d bytes, %d expected", ErrFieldSize, f.name, fieldValue.Len(), f.size)
+			}
+			w.B(bytesOf(fieldValue))
+		case kindRest:
+			w.B(fieldValue.Bytes())
+		}
+	}
+
+	return w.data, nil
+}
+
+// Unmarshal decodes a packet into a tagged struct pointer
+func Unmarshal(data []byte, v interface{}) error {
+	if unmarshaler, ok := v.(PacketUnmarshaler); ok {
+		return unmarshaler.UnmarshalPacket(data)
+	}
+
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T isn't a struct pointer", ErrUnsupportedType, v)
+	}
+	value = value.Elem()
+
+	fields, err := fieldsOf(value.Type())
+	if err != nil {
+		return err
+	}
+
+	d := NewDecoder(data)
+	for _, f := range fields {
+		fieldValue := value.Field(f.index)
+
+		switch f.kind {
+		case kindU8:
+			setInteger(fieldValue, uint64(d.U8()))
+		case kindU16:
+			setInteger(fieldValue, uint64(d.U16()))
+		case kindU32:
+			setInteger(fieldValue, uint64(d.U32()))
+		case kindU64:
+			setInteger(fieldValue, d.U64())
+		case kindF64:
+			fieldValue.SetFloat(d.F64())
+		case kindBool:
+			fieldValue.SetBool(d.Bool())
+		case kindString:
+			fieldValue.SetString(d.S())
+		case kindBytes:
+			setBytes(fieldValue, d.B(f.size))
+		case kindRest:
+			setBytes(fieldValue, d.Rest())
+		}
+
+		if d.Err() != nil {
+			return fmt.Errorf("%s: %w", f.name, d.Err())
+		}
+	}
+
+	return nil
+}
+
+// fieldsOf returns the tagged fields of a struct, in their packet order
+func fieldsOf(t reflect.Type) ([]field, error) {
+	if cached, ok := fieldsCache.Load(t); ok {
+		return cached.([]field), nil
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %s isn't a struct", ErrUnsupportedType, t)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		tag, ok := structField.Tag.Lookup("l2")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		f, err := parseField(structField, tag)
+		if err != nil {
+			return nil, err
+		}
+		f.index = i
+
+		if len(fields) > 0 && fields[len(fields)-1].kind == kindRest {
+			return nil, fmt.Errorf("%w: %s.%s follows a field reading the rest of the packet", ErrUnsupportedType, t, f.name)
+		}
+		fields = append(fields, f)
+	}
+
+	fieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+// parseTag returns the kind named by an l2 tag and the size of the bytes:N kinds
+func parseTag(tag string) (fieldKind, int, bool) {
+	switch tag {
+	case "u8":
+		return kindU8, 1, true
+	case "u16":
+		return kindU16, 2, true
+	case "u32":
+		return kindU32, 4, true
+	case "u64":
+		return kindU64, 8, true
+	case "f64":
+		return kindF64, 8, true
+	case "bool":
+		return kindBool, 1, true
+	case "string":
+		return kindString, 0, true
+	case "bytes":
+		return kindRest, 0, true
+	}
+
+	if size, ok := strings.CutPrefix(tag, "bytes:"); ok {
+		n, err := strconv.Atoi(size)
+		if err == nil && n > 0 {
+			return kindBytes, n, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// parseField checks the tag of a field against its Go type
+func parseField(structField reflect.StructField, tag string) (field, error) {
+	f := field{name: structField.Name}
+
+	kind, size, ok := parseTag(tag)
+	if !ok {
+		return f, fmt.Errorf("%w: %s has an unknown tag %q", ErrUnsupportedType, f.name, tag)
+	}
+	f.kind, f.size = kind, size
+
+	if !structField.IsExported() {
+		return f, fmt.Errorf("%w: %s isn't exported", ErrUnsupportedType, f.name)
+	}
+
+	t := structField.Type
+	switch kind {
+	case kindU8, kindU16, kindU32, kindU64:
+		ok = isInteger(t.Kind())
+	case kindF64:
+		ok = t.Kind() == reflect.Float64 || t.Kind() == reflect.Float32
+	case kindBool:
+		ok = t.Kind() == reflect.Bool
+	case kindString:
+		ok = t.Kind() == reflect.String
+	case kindBytes:
+		ok = (t.Kind() == reflect.Slice || t.Kind() == reflect.Array && t.Len() == size) && t.Elem().Kind() == reflect.Uint8
+	case kindRest:
+		ok = t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+	}
+
+	if !ok {
+		return f, fmt.Errorf("%w: %s of type %s can't be tagged %q", ErrUnsupportedType, f.name, t, tag)
+	}
+	return f, nil
+}
+
+func isInteger(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// integer returns the bits of an integer field, the signed ones as their two's complement
+func integer(value reflect.Value) uint64 {
+	if value.CanInt() {
+		return uint64(value.Int())
+	}
+	return value.Uint()
+}
+
+// setInteger stores the bits read from the packet, sign extending them for the signed fields
+func setInteger(value reflect.Value, bits uint64) {
+	if !value.CanInt() {
+		value.SetUint(bits)
+		return
+	}
+
+	// The field size tells where the sign bit is, a u32 read into an int32 is negative if its bit 31 is set
+	switch value.Kind() {
+	case reflect.Int8:
+		value.SetInt(int64(int8(bits)))
+	case reflect.Int16:
+		value.SetInt(int64(int16(bits)))
+	case reflect.Int32:
+		value.SetInt(int64(int32(bits)))
+	default:
+		value.SetInt(int64(bits))
+	}
+}
+
+// bytesOf returns the content of a byte slice or array, the arrays of a struct passed by value not being addressable
+func bytesOf(value reflect.Value) []byte {
+	if value.Kind() == reflect.Array {
+		data := make([]byte, value.Len())
+		reflect.Copy(reflect.ValueOf(data), value)
+		return data
+	}
+	return value.Bytes()
+}
+
+func setBytes(value reflect.Value, data []byte) {
+	if value.Kind() == reflect.Array {
+		reflect.Copy(value, reflect.ValueOf(data))
+		return
+	}
+	value.SetBytes(data)
+}
@@ -0,0 +1,141 @@
+package packets
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type taggedPacket struct {
+	Opcode   uint8   `l2:"u8"`
+	ObjectID uint32  `l2:"u32"`
+	X        int32   `l2:"u32"`
+	Color    uint16  `l2:"u16"`
+	Exp      uint64  `l2:"u64"`
+	Speed    float64 `l2:"f64"`
+	Running  bool    `l2:"bool"`
+	Name     string  `l2:"string"`
+	Key      [4]byte `l2:"bytes:4"`
+	Session  []byte  `l2:"bytes:2"`
+	Ignored  int     `l2:"-"`
+	Untagged int
+	Rest     []byte `l2:"bytes"`
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	packet := taggedPacket{
+		Opcode:   0x28,
+		ObjectID: 0x10000001,
+		X:        -12672,
+		Color:    0xbeef,
+		Exp:      1 << 40,
+		Speed:    1.5,
+		Running:  true,
+		Name:     "Héllo",
+		Key:      [4]byte{1, 2, 3, 4},
+		Session:  []byte{5, 6},
+		Rest:     []byte{7, 8, 9},
+	}
+
+	data, err := Marshal(packet)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	// Same bytes as the Buffer
+	buffer := NewBuffer()
+	buffer.WriteUInt8(0x28)
+	buffer.WriteUInt32(0x10000001)
+	buffer.WriteUInt32(uint32(0xffffffff + int64(-12672) + 1))
+	buffer.WriteUInt16(0xbeef)
+	buffer.WriteUInt64(1 << 40)
+	buffer.WriteFloat64(1.5)
+	buffer.WriteBool(true)
+	buffer.WriteString("Héllo")
+	buffer.WriteBytes([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	if !bytes.Equal(data, buffer.Bytes()) {
+		t.Errorf("Marshal() = %X, want %X", data, buffer.Bytes())
+	}
+
+	var decoded taggedPacket
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, packet) {
+		t.Errorf("Unmarshal() = %+v, want %+v", decoded, packet)
+	}
+
+	withOpcode, err := AppendMarshal([]byte{0xff}, &packet)
+	if err != nil || !bytes.Equal(withOpcode, append([]byte{0xff}, data...)) {
+		t.Errorf("AppendMarshal() = %X, %v", withOpcode, err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	type short struct {
+		ObjectID uint32 `l2:"u32"`
+		Name     string `l2:"string"`
+	}
+	type badTag struct {
+		ObjectID uint32 `l2:"u24"`
+	}
+	type badType struct {
+		Name int `l2:"string"`
+	}
+	type restFirst struct {
+		Rest []byte `l2:"bytes"`
+		Next uint8  `l2:"u8"`
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		target interface{}
+		want   error
+	}{
+		{"short integer", []byte{1, 2}, &short{}, ErrInsufficientData},
+		{"unterminated string", []byte{1, 2, 3, 4, 'a', 0}, &short{}, ErrInvalidString},
+		{"unknown tag", []byte{1, 2, 3, 4}, &badTag{}, ErrUnsupportedType},
+		{"mismatched type", []byte{0, 0}, &badType{}, ErrUnsupportedType},
+		{"field after the rest", []byte{0}, &restFirst{}, ErrUnsupportedType},
+		{"not a pointer", []byte{0}, short{}, ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Unmarshal(tt.data, tt.target); !errors.Is(err, tt.want) {
+				t.Fatalf("Unmarshal() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Marshal(taggedPacket{Session: []byte{1}}); !errors.Is(err, ErrFieldSize) {
+		t.Errorf("Marshal() error = %v, want %v", err, ErrFieldSize)
+	}
+}
+
+// generatedPacket stands for a packet with packetgen methods
+type generatedPacket struct {
+	calls int
+}
+
+func (p generatedPacket) AppendPacket(dst []byte) ([]byte, error) {
+	return append(dst, 0x42), nil
+}
+
+func (p *generatedPacket) UnmarshalPacket(data []byte) error {
+	p.calls += 1
+	return nil
+}
+
+func TestMarshalGenerated(t *testing.T) {
+	var packet generatedPacket
+
+	if data, err := Marshal(packet); err != nil || !bytes.Equal(data, []byte{0x42}) {
+		t.Errorf("Marshal() = %X, %v", data, err)
+	}
+	if err := Unmarshal(nil, &packet); err != nil || packet.calls != 1 {
+		t.Errorf("Unmarshal() error = %v, %d calls", err, packet.calls)
+	}
+}
@@ -0,0 +1,250 @@
+// Packetgen writes the AppendPacket and UnmarshalPacket methods of packet
+// structs described with l2 tags, sparing the reflection of packets.Marshal
+// and packets.Unmarshal on the hot paths. It is meant to be run by go generate:
+//
+//	//go:generate go run github.com/frostwind/l2go/packets/packetgen -type Action
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma separated names of the packet structs")
+	output := flag.String("output", "", "output file, <source file>_l2.go by default")
+	flag.Parse()
+
+	if *typeNames == "" {
+		fmt.Fprintln(os.Stderr, "packetgen: -type is required")
+		os.Exit(2)
+	}
+
+	if *output == "" {
+		source := os.Getenv("GOFILE")
+		if source == "" {
+			source = "packets.go"
+		}
+		*output = strings.TrimSuffix(source, ".go") + "_l2.go"
+	}
+
+	code, err := generate(".", strings.Split(*typeNames, ","))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "packetgen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(*output, code, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "packetgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// packetField is a tagged field of a packet struct
+type packetField struct {
+	name   string
+	goType string
+	tag    string
+	size   int // Size of the bytes:N fields
+	array  bool
+}
+
+// generate parses the package in dir and returns the methods of the given structs
+func generate(dir string, typeNames []string) ([]byte, error) {
+	fileSet := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fileSet, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && !strings.HasSuffix(info.Name(), "_l2.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected a single package in %s, found %d", dir, len(pkgs))
+	}
+
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+
+	structs := make(map[string]*ast.StructType)
+	var fileNames []string
+	for fileName := range pkg.Files {
+		fileNames = append(fileNames, fileName)
+	}
+	sort.Strings(fileNames)
+
+	for _, fileName := range fileNames {
+		ast.Inspect(pkg.Files[fileName], func(node ast.Node) bool {
+			spec, ok := node.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			if structType, ok := spec.Type.(*ast.StructType); ok {
+				structs[spec.Name.Name] = structType
+			}
+			return false
+		})
+	}
+
+	var code bytes.Buffer
+	for _, typeName := range typeNames {
+		typeName = strings.TrimSpace(typeName)
+
+		structType, ok := structs[typeName]
+		if !ok {
+			return nil, fmt.Errorf("struct %s not found in %s", typeName, filepath.Clean(dir))
+		}
+
+		fields, err := packetFields(typeName, structType)
+		if err != nil {
+			return nil, err
+		}
+
+		writeAppend(&code, typeName, fields)
+		writeUnmarshal(&code, typeName, fields)
+	}
+
+	// The size checks of the bytes:N slices are the only errors worth formatting
+	imports := "import \"github.com/frostwind/l2go/packets\"\n"
+	if bytes.Contains(code.Bytes(), []byte("fmt.Errorf")) {
+		imports = "import (\n\t\"fmt\"\n\n\t\"github.com/frostwind/l2go/packets\"\n)\n"
+	}
+	header := fmt.Sprintf("// Code generated by packetgen. DO NOT EDIT.\n\npackage %s\n\n%s", pkg.Name, imports)
+
+	return format.Source(append([]byte(header), code.Bytes()...))
+}
+
+// packetFields returns the tagged fields of a struct, in their packet order
+func packetFields(typeName string, structType *ast.StructType) ([]packetField, error) {
+	var fields []packetField
+
+	for _, astField := range structType.Fields.List {
+		if astField.Tag == nil {
+			continue
+		}
+
+		tags, err := strconv.Unquote(astField.Tag.Value)
+		if err != nil {
+			return nil, err
+		}
+		tag, ok := reflect.StructTag(tags).Lookup("l2")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		goType := types(astField.Type)
+		for _, name := range astField.Names {
+			f := packetField{name: name.Name, goType: goType, tag: tag}
+
+			if size, ok := strings.CutPrefix(tag, "bytes:"); ok {
+				f.size, err = strconv.Atoi(size)
+				if err != nil || f.size <= 0 {
+					return nil, fmt.Errorf("%s.%s has an invalid size %q", typeName, f.name, size)
+				}
+				f.tag = "bytes:N"
+				f.array = strings.HasPrefix(goType, "[") && !strings.HasPrefix(goType, "[]")
+			}
+
+			switch f.tag {
+			case "u8", "u16", "u32", "u64", "f64", "bool", "string", "bytes", "bytes:N":
+			default:
+				return nil, fmt.Errorf("%s.%s has an unknown tag %q", typeName, f.name, tag)
+			}
+
+			if len(fields) > 0 && fields[len(fields)-1].tag == "bytes" {
+				return nil, fmt.Errorf("%s.%s follows a field reading the rest of the packet", typeName, f.name)
+			}
+			fields = append(fields, f)
+		}
+	}
+
+	return fields, nil
+}
+
+// types prints a field type as written in the source
+func types(expr ast.Expr) string {
+	var buffer bytes.Buffer
+	format.Node(&buffer, token.NewFileSet(), expr)
+	return buffer.String()
+}
+
+var writers = map[string]string{"u8": "U8(uint8", "u16": "U16(uint16", "u32": "U32(uint32", "u64": "U64(uint64", "f64": "F64(float64"}
+var readers = map[string]string{"u8": "U8", "u16": "U16", "u32": "U32", "u64": "U64", "f64": "F64"}
+
+func writeAppend(code *bytes.Buffer, typeName string, fields []packetField) {
+	fmt.Fprintf(code, "\n// AppendPacket encodes the %s packet behind dst\n", typeName)
+	fmt.Fprintf(code, "func (p %s) AppendPacket(dst []byte) ([]byte, error) {\n", typeName)
+
+	var chain []string
+	flush := func() {
+		if len(chain) > 0 {
+			fmt.Fprintf(code, "\tw.%s\n", strings.Join(chain, "."))
+			chain = nil
+		}
+	}
+
+	fmt.Fprintf(code, "\tw := packets.NewPacketWriter()\n\tdefer w.Release()\n\n")
+	for _, f := range fields {
+		switch f.tag {
+		case "bool":
+			chain = append(chain, fmt.Sprintf("Bool(p.%s)", f.name))
+		case "string":
+			chain = append(chain, fmt.Sprintf("S(p.%s)", f.name))
+		case "bytes":
+			chain = append(chain, fmt.Sprintf("B(p.%s)", f.name))
+		case "bytes:N":
+			if f.array {
+				chain = append(chain, fmt.Sprintf("B(p.%s[:])", f.name))
+				break
+			}
+			flush()
+			fmt.Fprintf(code, "\tif len(p.%s) != %d {\n", f.name, f.size)
+			fmt.Fprintf(code, "\t\treturn dst, fmt.Errorf(\"%%w: %s holds %%d bytes, %d expected\", packets.ErrFieldSize, len(p.%s))\n\t}\n", f.name, f.size, f.name)
+			chain = append(chain, fmt.Sprintf("B(p.%s)", f.name))
+		default:
+			chain = append(chain, fmt.Sprintf("%s(p.%s))", writers[f.tag], f.name))
+		}
+	}
+	flush()
+
+	fmt.Fprintf(code, "\n\treturn append(dst, w.Payload()...), nil\n}\n")
+}
+
+func writeUnmarshal(code *bytes.Buffer, typeName string, fields []packetField) {
+	fmt.Fprintf(code, "\n// UnmarshalPacket decodes the %s packet\n", typeName)
+	fmt.Fprintf(code, "func (p *%s) UnmarshalPacket(data []byte) error {\n", typeName)
+	fmt.Fprintf(code, "\td := packets.NewDecoder(data)\n\n")
+
+	for _, f := range fields {
+		switch f.tag {
+		case "bool":
+			fmt.Fprintf(code, "\tp.%s = d.Bool()\n", f.name)
+		case "string":
+			fmt.Fprintf(code, "\tp.%s = d.S()\n", f.name)
+		case "bytes":
+			fmt.Fprintf(code, "\tp.%s = d.Rest()\n", f.name)
+		case "bytes:N":
+			if f.array {
+				fmt.Fprintf(code, "\tcopy(p.%s[:], d.B(%d))\n", f.name, f.size)
+			} else {
+				fmt.Fprintf(code, "\tp.%s = d.B(%d)\n", f.name, f.size)
+			}
+		default:
+			fmt.Fprintf(code, "\tp.%s = %s(d.%s())\n", f.name, f.goType, readers[f.tag])
+		}
+	}
+
+	fmt.Fprintf(code, "\n\treturn d.Err()\n}\n")
+}
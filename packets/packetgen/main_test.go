@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const source = `package sample
+
+type Sample struct {
+	ObjectID uint32 ` + "`l2:\"u32\"`" + `
+	X, Y     int32  ` + "`l2:\"u32\"`" + `
+	Name     string ` + "`l2:\"string\"`" + `
+	Key      []byte ` + "`l2:\"bytes:8\"`" + `
+	Hidden   bool
+}
+`
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sample.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := generate(dir, []string{"Sample"})
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"package sample",
+		`"fmt"`,
+		"func (p Sample) AppendPacket(dst []byte) ([]byte, error) {",
+		"if len(p.Key) != 8 {",
+		"w.U32(uint32(p.ObjectID)).U32(uint32(p.X)).U32(uint32(p.Y)).S(p.Name)",
+		"func (p *Sample) UnmarshalPacket(data []byte) error {",
+		"p.Y = int32(d.U32())",
+		"p.Key = d.B(8)",
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("generated code misses %q:\n%s", want, code)
+		}
+	}
+	if strings.Contains(string(code), "Hidden") {
+		t.Errorf("generated code handles the untagged field:\n%s", code)
+	}
+
+	if _, err := generate(dir, []string{"Missing"}); err == nil {
+		t.Error("generate() succeeded for an unknown struct")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"math"
+	"unicode"
+	"unicode/utf16"
 )
 
 var (
@@ -28,35 +31,46 @@ func NewBufferFromBytes(data []byte) *Buffer {
 
 // Enhanced write methods with error handling
 func (b *Buffer) WriteUInt64(value uint64) error {
-	return binary.Write(b, binary.LittleEndian, value)
+	_, err := b.Write(binary.LittleEndian.AppendUint64(b.AvailableBuffer(), value))
+	return err
 }
 
 func (b *Buffer) WriteUInt32(value uint32) error {
-	return binary.Write(b, binary.LittleEndian, value)
+	_, err := b.Write(binary.LittleEndian.AppendUint32(b.AvailableBuffer(), value))
+	return err
 }
 
 func (b *Buffer) WriteUInt16(value uint16) error {
-	return binary.Write(b, binary.LittleEndian, value)
+	_, err := b.Write(binary.LittleEndian.AppendUint16(b.AvailableBuffer(), value))
+	return err
 }
 
 func (b *Buffer) WriteUInt8(value uint8) error {
-	return binary.Write(b, binary.LittleEndian, value)
+	return b.WriteByte(value)
 }
 
 func (b *Buffer) WriteFloat64(value float64) error {
-	return binary.Write(b, binary.LittleEndian, value)
+	return b.WriteUInt64(math.Float64bits(value))
 }
 
 func (b *Buffer) WriteFloat32(value float32) error {
-	return binary.Write(b, binary.LittleEndian, value)
+	return b.WriteUInt32(math.Float32bits(value))
 }
 
 // Additional write methods for client use
 func (b *Buffer) WriteString(value string) error {
 	// Write string as UTF-16LE with null terminator
+	b.Grow(2*len(value) + 2)
 	for _, r := range value {
-		if err := b.WriteUInt16(uint16(r)); err != nil {
-			return err
+		switch utf16.RuneLen(r) {
+		case 1:
+			b.WriteUInt16(uint16(r))
+		case 2:
+			r1, r2 := utf16.EncodeRune(r)
+			b.WriteUInt16(uint16(r1))
+			b.WriteUInt16(uint16(r2))
+		default:
+			b.WriteUInt16(unicode.ReplacementChar)
 		}
 	}
 	// Null terminator
@@ -83,6 +97,9 @@ func (b *Buffer) WritePacketHeader(opcode byte, length uint16) error {
 	return b.WriteUInt8(opcode)
 }
 
+// PrependLength copies the buffer behind its length.
+//
+// Deprecated: build the packet with a PacketWriter, which reserves the length slot up front.
 func (b *Buffer) PrependLength() error {
 	data := b.Bytes()
 	length := uint16(len(data))
@@ -140,82 +157,63 @@ func (r *Reader) ReadBytes(number int) []byte {
 }
 
 func (r *Reader) ReadUInt64() uint64 {
-	var result uint64
-
-	buffer := make([]byte, 8)
-	n, _ := r.Read(buffer)
+	var buffer [8]byte
+	n, _ := r.Read(buffer[:])
 	if n < 8 {
 		return 0
 	}
 
-	buf := bytes.NewBuffer(buffer)
-
-	binary.Read(buf, binary.LittleEndian, &result)
-
-	return result
+	return binary.LittleEndian.Uint64(buffer[:])
 }
 
 func (r *Reader) ReadUInt32() uint32 {
-	var result uint32
-
-	buffer := make([]byte, 4)
-	n, _ := r.Read(buffer)
+	var buffer [4]byte
+	n, _ := r.Read(buffer[:])
 	if n < 4 {
 		return 0
 	}
 
-	buf := bytes.NewBuffer(buffer)
-
-	binary.Read(buf, binary.LittleEndian, &result)
-
-	return result
+	return binary.LittleEndian.Uint32(buffer[:])
 }
 
 func (r *Reader) ReadUInt16() uint16 {
-	var result uint16
-
-	buffer := make([]byte, 2)
-	n, _ := r.Read(buffer)
+	var buffer [2]byte
+	n, _ := r.Read(buffer[:])
 	if n < 2 {
 		return 0
 	}
 
-	buf := bytes.NewBuffer(buffer)
-
-	binary.Read(buf, binary.LittleEndian, &result)
-
-	return result
+	return binary.LittleEndian.Uint16(buffer[:])
 }
 
 func (r *Reader) ReadUInt8() uint8 {
-	var result uint8
-
-	buffer := make([]byte, 1)
-	n, _ := r.Read(buffer)
-	if n < 1 {
+	result, err := r.ReadByte()
+	if err != nil {
 		return 0
 	}
 
-	buf := bytes.NewBuffer(buffer)
-
-	binary.Read(buf, binary.LittleEndian, &result)
-
 	return result
 }
 
 func (r *Reader) ReadString() string {
-	var result []byte
-	var first_byte, second_byte byte
+	var result []uint16
 
 	for {
-		first_byte, _ = r.ReadByte()
-		second_byte, _ = r.ReadByte()
+		first_byte, err := r.ReadByte()
+		if err != nil {
+			break
+		}
+		second_byte, err := r.ReadByte()
+		if err != nil {
+			break
+		}
+
 		if first_byte == 0x00 && second_byte == 0x00 {
 			break
 		} else {
-			result = append(result, first_byte, second_byte)
+			result = append(result, uint16(first_byte)|uint16(second_byte)<<8)
 		}
 	}
 
-	return string(result)
+	return string(utf16.Decode(result))
 }
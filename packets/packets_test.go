@@ -0,0 +1,77 @@
+package packets
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBufferReaderRoundTrip(t *testing.T) {
+	buffer := NewBuffer()
+	buffer.WriteUInt8(0x2a)
+	buffer.WriteUInt16(0xbeef)
+	buffer.WriteUInt32(0xdeadbeef)
+	buffer.WriteUInt64(math.MaxUint64 - 1)
+	buffer.WriteFloat64(1.5)
+	buffer.WriteString("Héllo 𝄞")
+	buffer.WriteString("")
+
+	reader := NewReader(buffer.Bytes())
+	if got := reader.ReadUInt8(); got != 0x2a {
+		t.Errorf("ReadUInt8() = %#x", got)
+	}
+	if got := reader.ReadUInt16(); got != 0xbeef {
+		t.Errorf("ReadUInt16() = %#x", got)
+	}
+	if got := reader.ReadUInt32(); got != 0xdeadbeef {
+		t.Errorf("ReadUInt32() = %#x", got)
+	}
+	if got := reader.ReadUInt64(); got != math.MaxUint64-1 {
+		t.Errorf("ReadUInt64() = %#x", got)
+	}
+	if got := math.Float64frombits(reader.ReadUInt64()); got != 1.5 {
+		t.Errorf("float64 = %v", got)
+	}
+	if got := reader.ReadString(); got != "Héllo 𝄞" {
+		t.Errorf("ReadString() = %q", got)
+	}
+	if got := reader.ReadString(); got != "" {
+		t.Errorf("ReadString() = %q, want empty", got)
+	}
+	if got := reader.ReadUInt32(); got != 0 {
+		t.Errorf("ReadUInt32() past the end = %#x, want 0", got)
+	}
+}
+
+func BenchmarkBufferWrite(b *testing.B) {
+	buffer := NewBuffer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		buffer.Reset()
+		buffer.WriteUInt8(0x04)
+		buffer.WriteUInt32(uint32(i))
+		buffer.WriteUInt32(0x1234)
+		buffer.WriteUInt16(0x10)
+		buffer.WriteUInt64(uint64(i))
+		buffer.WriteString("Character")
+	}
+}
+
+func BenchmarkReaderRead(b *testing.B) {
+	buffer := NewBuffer()
+	buffer.WriteUInt8(0x04)
+	buffer.WriteUInt32(0x1234)
+	buffer.WriteUInt16(0x10)
+	buffer.WriteUInt64(0x42)
+	data := buffer.Bytes()
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		reader := NewReader(data)
+		reader.ReadUInt8()
+		reader.ReadUInt32()
+		reader.ReadUInt16()
+		reader.ReadUInt64()
+	}
+}
@@ -0,0 +1,51 @@
+package packets
+
+// Flags telling whether another page of a list split across several packets follows
+const (
+	LastPage  byte = 0x00
+	MorePages byte = 0x01
+)
+
+// DefaultPageSize is the largest packet of a split list when the server doesn't set one, comfortably
+// below MaxFrameSize
+const DefaultPageSize = 8192
+
+// pageSlack is kept free in every page for the checksum and the padding the ciphers add
+const pageSlack = 16
+
+// Page is the part of a list sent in a single packet
+type Page[T any] struct {
+	Items []T
+	Flag  byte // MorePages, LastPage for the last one
+}
+
+// PerPage returns how many items of itemSize bytes fit in a packet of maxSize bytes, its length
+// header included, after the headerSize bytes of its opcode, its page flag and the fields preceding
+// the items. A page holds at most maxCount items, the largest count its packet can announce, and at
+// least one whatever their size.
+func PerPage(maxSize, headerSize, itemSize, maxCount int) int {
+	if maxSize <= 0 {
+		maxSize = DefaultPageSize
+	}
+	maxSize = min(maxSize, MaxFrameSize)
+
+	perPage := (maxSize - 2 - pageSlack - headerSize) / max(itemSize, 1)
+	return min(max(perPage, 1), max(maxCount, 1))
+}
+
+// Paginate splits a list into pages of perPage items. An empty list still makes an empty page, so the
+// receiver is always sent a last page.
+func Paginate[T any](items []T, perPage int) []Page[T] {
+	perPage = max(perPage, 1)
+
+	pages := make([]Page[T], 0, len(items)/perPage+1)
+	for start := 0; start == 0 || start < len(items); start += perPage {
+		end := min(start+perPage, len(items))
+		page := Page[T]{Items: items[start:end], Flag: MorePages}
+		if end == len(items) {
+			page.Flag = LastPage
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
@@ -0,0 +1,59 @@
+package packets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPerPage(t *testing.T) {
+	tests := []struct {
+		name                                 string
+		maxSize, headerSize, itemSize, count int
+		want                                 int
+	}{
+		{name: "items of an inventory", maxSize: 8192, headerSize: 4, itemSize: 12, count: 0xffff, want: 680},
+		{name: "capped by the count", maxSize: 8192, headerSize: 3, itemSize: 21, count: 0xff, want: 0xff},
+		{name: "default size", maxSize: 0, headerSize: 4, itemSize: 12, count: 0xffff, want: 680},
+		{name: "larger than a frame", maxSize: 1 << 20, headerSize: 4, itemSize: 12, count: 0xffff, want: 5459},
+		{name: "items larger than the packet", maxSize: 64, headerSize: 4, itemSize: 100, count: 0xffff, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PerPage(tt.maxSize, tt.headerSize, tt.itemSize, tt.count); got != tt.want {
+				t.Errorf("PerPage() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []int
+		perPage int
+		want    []Page[int]
+	}{
+		{name: "empty", items: nil, perPage: 3, want: []Page[int]{{Items: nil, Flag: LastPage}}},
+		{name: "single page", items: []int{1, 2}, perPage: 3, want: []Page[int]{{Items: []int{1, 2}, Flag: LastPage}}},
+		{name: "full pages", items: []int{1, 2, 3, 4}, perPage: 2, want: []Page[int]{
+			{Items: []int{1, 2}, Flag: MorePages},
+			{Items: []int{3, 4}, Flag: LastPage},
+		}},
+		{name: "partial last page", items: []int{1, 2, 3, 4, 5}, perPage: 2, want: []Page[int]{
+			{Items: []int{1, 2}, Flag: MorePages},
+			{Items: []int{3, 4}, Flag: MorePages},
+			{Items: []int{5}, Flag: LastPage},
+		}},
+		{name: "no page size", items: []int{1, 2}, perPage: 0, want: []Page[int]{
+			{Items: []int{1}, Flag: MorePages},
+			{Items: []int{2}, Flag: LastPage},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Paginate(tt.items, tt.perPage); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Paginate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
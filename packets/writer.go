@@ -0,0 +1,128 @@
+package packets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+)
+
+// headerSize is the size of the length slot reserved at the start of every packet
+const headerSize = 2
+
+var writers = sync.Pool{
+	New: func() interface{} {
+		return &PacketWriter{data: make([]byte, 0, 256)}
+	},
+}
+
+// PacketWriter builds a length prefixed packet with chained writes, the length
+// slot being reserved up front and filled by Finalize:
+//
+//	w := packets.NewPacketWriter()
+//	defer w.Release()
+//	frame, err := w.U8(opcode).U32(objectID).S(name).Finalize()
+//
+// The writers come from a pool, the bytes returned by Finalize can't be used
+// once the writer is released.
+type PacketWriter struct {
+	data []byte
+}
+
+// NewPacketWriter returns an empty writer from the pool
+func NewPacketWriter() *PacketWriter {
+	w := writers.Get().(*PacketWriter)
+	w.data = append(w.data[:0], 0x00, 0x00)
+	return w
+}
+
+// U8 writes a byte
+func (w *PacketWriter) U8(value uint8) *PacketWriter {
+	w.data = append(w.data, value)
+	return w
+}
+
+// U16 writes a little endian uint16
+func (w *PacketWriter) U16(value uint16) *PacketWriter {
+	w.data = binary.LittleEndian.AppendUint16(w.data, value)
+	return w
+}
+
+// U32 writes a little endian uint32
+func (w *PacketWriter) U32(value uint32) *PacketWriter {
+	w.data = binary.LittleEndian.AppendUint32(w.data, value)
+	return w
+}
+
+// U64 writes a little endian uint64
+func (w *PacketWriter) U64(value uint64) *PacketWriter {
+	w.data = binary.LittleEndian.AppendUint64(w.data, value)
+	return w
+}
+
+// F64 writes a little endian float64
+func (w *PacketWriter) F64(value float64) *PacketWriter {
+	return w.U64(math.Float64bits(value))
+}
+
+// Bool writes a byte, 1 for true and 0 for false
+func (w *PacketWriter) Bool(value bool) *PacketWriter {
+	if value {
+		return w.U8(1)
+	}
+	return w.U8(0)
+}
+
+// S writes a null terminated UTF-16LE string, like Buffer.WriteString
+func (w *PacketWriter) S(value string) *PacketWriter {
+	for _, r := range value {
+		switch utf16.RuneLen(r) {
+		case 1:
+			w.U16(uint16(r))
+		case 2:
+			r1, r2 := utf16.EncodeRune(r)
+			w.U16(uint16(r1)).U16(uint16(r2))
+		default:
+			w.U16(unicode.ReplacementChar)
+		}
+	}
+	return w.U16(0)
+}
+
+// B writes raw bytes
+func (w *PacketWriter) B(data []byte) *PacketWriter {
+	w.data = append(w.data, data...)
+	return w
+}
+
+// Len returns the size of the packet, header included
+func (w *PacketWriter) Len() int {
+	return len(w.data)
+}
+
+// Payload returns the bytes written so far, without the header. They can be
+// modified in place, to encrypt them before sending for instance.
+func (w *PacketWriter) Payload() []byte {
+	return w.data[headerSize:]
+}
+
+// Finalize writes the packet length in the reserved slot and returns the whole packet
+func (w *PacketWriter) Finalize() ([]byte, error) {
+	if len(w.data) > MaxFrameSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, len(w.data))
+	}
+
+	binary.LittleEndian.PutUint16(w.data, uint16(len(w.data)))
+	return w.data, nil
+}
+
+// Release gives the writer back to the pool
+func (w *PacketWriter) Release() {
+	// Don't keep the buffers of the huge packets around
+	if cap(w.data) > MaxFrameSize {
+		return
+	}
+	writers.Put(w)
+}
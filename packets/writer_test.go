@@ -0,0 +1,57 @@
+package packets
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPacketWriter(t *testing.T) {
+	w := NewPacketWriter()
+	frame, err := w.U8(0x2a).U16(0xbeef).U32(0xdeadbeef).U64(7).Bool(true).S("Héllo").B([]byte{1, 2}).Finalize()
+	if err != nil {
+		t.Fatalf("Finalize() error = %v", err)
+	}
+
+	// Same bytes as the Buffer, behind the length
+	buffer := NewBuffer()
+	buffer.WriteUInt8(0x2a)
+	buffer.WriteUInt16(0xbeef)
+	buffer.WriteUInt32(0xdeadbeef)
+	buffer.WriteUInt64(7)
+	buffer.WriteBool(true)
+	buffer.WriteString("Héllo")
+	buffer.WriteBytes([]byte{1, 2})
+	buffer.PrependLength()
+
+	want := buffer.Bytes()
+	want[0] += 2 // PrependLength doesn't count the header
+	if !bytes.Equal(frame, want) {
+		t.Errorf("Finalize() = %X, want %X", frame, want)
+	}
+	if w.Len() != len(frame) || !bytes.Equal(w.Payload(), frame[2:]) {
+		t.Errorf("Len() = %d, Payload() = %X", w.Len(), w.Payload())
+	}
+
+	data, err := ReadFrame(bytes.NewReader(frame), MaxFrameSize)
+	if err != nil || !bytes.Equal(data, frame[2:]) {
+		t.Errorf("ReadFrame() = %X, %v", data, err)
+	}
+	w.Release()
+
+	// Pooled writers start empty
+	w = NewPacketWriter()
+	defer w.Release()
+	if frame, _ := w.U8(1).Finalize(); !bytes.Equal(frame, []byte{3, 0, 1}) {
+		t.Errorf("Finalize() = %X after reuse", frame)
+	}
+}
+
+func TestPacketWriterTooLarge(t *testing.T) {
+	w := NewPacketWriter()
+	defer w.Release()
+
+	if _, err := w.B(make([]byte, MaxFrameSize)).Finalize(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("Finalize() error = %v, want %v", err, ErrFrameTooLarge)
+	}
+}
@@ -0,0 +1,180 @@
+// Package packettiming measures how long the servers take to handle the packets of their clients,
+// by opcode, so that the handlers which slow down first under load show. The handlers taking longer
+// than a threshold are logged as they happen, and every opcode keeps a histogram of its handling times.
+package packettiming
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Buckets are the upper bounds of the buckets of the histograms, a last bucket counting the
+// handling times above them all
+var Buckets = []time.Duration{
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// Bucket counts the handling times up to a bound, and above the bound of the previous bucket.
+// The last bucket has no bound.
+type Bucket struct {
+	UpTo  time.Duration `json:"upTo,omitempty"`
+	Count uint64        `json:"count"`
+}
+
+// OpcodeStats sums up the handling times of an opcode. The percentiles are the bounds of the
+// buckets they fall in, the longest time for the last one.
+type OpcodeStats struct {
+	Opcode  byte          `json:"opcode"`
+	Name    string        `json:"name"`
+	Count   uint64        `json:"count"`
+	Slow    uint64        `json:"slow"` // Handled in longer than the threshold
+	Total   time.Duration `json:"total"`
+	Max     time.Duration `json:"max"`
+	P50     time.Duration `json:"p50"`
+	P95     time.Duration `json:"p95"`
+	P99     time.Duration `json:"p99"`
+	Buckets []Bucket      `json:"buckets"`
+}
+
+// Stats are the handling times of the opcodes handled so far, the longest in total first
+type Stats struct {
+	Threshold time.Duration `json:"threshold"` // 0 when the slow handlers aren't logged
+	Opcodes   []OpcodeStats `json:"opcodes"`
+}
+
+// Recorder records the handling times of the packets of a server
+type Recorder struct {
+	server    string
+	threshold time.Duration
+	name      func(opcode byte) string
+	opcodes   map[byte]*histogram
+	mu        sync.Mutex
+}
+
+type histogram struct {
+	count, slow uint64
+	total, max  time.Duration
+	buckets     []uint64 // One more than Buckets
+}
+
+// New creates a recorder for the packets of a server, logging the handlers taking longer than
+// threshold, none when it is 0. name returns the name of an opcode, for the logs and the stats.
+func New(server string, threshold time.Duration, name func(opcode byte) string) *Recorder {
+	return &Recorder{server: server, threshold: threshold, name: name, opcodes: make(map[byte]*histogram)}
+}
+
+// Observe records the time a packet took to be handled, and logs it if it was slow
+func (r *Recorder) Observe(opcode byte, took time.Duration) {
+	slow := r.threshold > 0 && took > r.threshold
+
+	r.mu.Lock()
+	h, ok := r.opcodes[opcode]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(Buckets)+1)}
+		r.opcodes[opcode] = h
+	}
+	h.count++
+	h.total += took
+	h.max = max(h.max, took)
+	if slow {
+		h.slow++
+	}
+	bucket, _ := slices.BinarySearch(Buckets, took)
+	h.buckets[bucket]++
+	r.mu.Unlock()
+
+	if slow {
+		fmt.Printf("Slow %s handler: %s took %v, over %v\n", r.server, r.name(opcode), took, r.threshold)
+	}
+}
+
+// Start starts timing the handling of a packet, recorded once the timer is stopped
+func (r *Recorder) Start(opcode byte) Timer {
+	return Timer{recorder: r, opcode: opcode, started: time.Now()}
+}
+
+// Stats returns the handling times recorded so far
+func (r *Recorder) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := Stats{Threshold: r.threshold, Opcodes: make([]OpcodeStats, 0, len(r.opcodes))}
+	for opcode, h := range r.opcodes {
+		opcodeStats := OpcodeStats{
+			Opcode: opcode,
+			Name:   r.name(opcode),
+			Count:  h.count,
+			Slow:   h.slow,
+			Total:  h.total,
+			Max:    h.max,
+			P50:    h.percentile(50),
+			P95:    h.percentile(95),
+			P99:    h.percentile(99),
+		}
+		for i, count := range h.buckets {
+			bucket := Bucket{Count: count}
+			if i < len(Buckets) {
+				bucket.UpTo = Buckets[i]
+			}
+			opcodeStats.Buckets = append(opcodeStats.Buckets, bucket)
+		}
+		stats.Opcodes = append(stats.Opcodes, opcodeStats)
+	}
+
+	slices.SortFunc(stats.Opcodes, func(a, b OpcodeStats) int {
+		if a.Total != b.Total {
+			return cmp.Compare(b.Total, a.Total)
+		}
+		return cmp.Compare(a.Opcode, b.Opcode)
+	})
+	return stats
+}
+
+// percentile returns the bound of the bucket of the nearest-rank percentile, the longest time
+// when it falls in the last bucket
+func (h *histogram) percentile(p uint64) time.Duration {
+	rank := max((p*h.count+99)/100, 1)
+	var seen uint64
+	for i, count := range h.buckets {
+		seen += count
+		if seen >= rank {
+			if i < len(Buckets) {
+				return min(Buckets[i], h.max)
+			}
+			break
+		}
+	}
+	return h.max
+}
+
+// Timer times the handling of a packet
+type Timer struct {
+	recorder *Recorder
+	opcode   byte
+	started  time.Time
+}
+
+// Stop records the time since the timer started. Stopping a timer again, or the zero timer, does nothing.
+func (t *Timer) Stop() {
+	if t.recorder == nil {
+		return
+	}
+	t.recorder.Observe(t.opcode, time.Since(t.started))
+	t.recorder = nil
+}
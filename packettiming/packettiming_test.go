@@ -0,0 +1,58 @@
+package packettiming
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	r := New("test server", 10*time.Millisecond, func(opcode byte) string { return map[byte]string{1: "Fast", 2: "Slow"}[opcode] })
+	for range 98 {
+		r.Observe(1, 80*time.Microsecond)
+	}
+	r.Observe(1, 3*time.Millisecond)
+	r.Observe(1, 2*time.Second)
+	r.Observe(2, 40*time.Millisecond)
+
+	stats := r.Stats()
+	if stats.Threshold != 10*time.Millisecond || len(stats.Opcodes) != 2 {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+
+	// Listed by total time, the longest first
+	fast, slow := stats.Opcodes[0], stats.Opcodes[1]
+	if fast.Name != "Fast" || fast.Count != 100 || fast.Slow != 1 || fast.Max != 2*time.Second {
+		t.Errorf("Opcodes[0] = %+v", fast)
+	}
+	if fast.P50 != 100*time.Microsecond || fast.P95 != 100*time.Microsecond || fast.P99 != 5*time.Millisecond {
+		t.Errorf("percentiles = %v, %v and %v", fast.P50, fast.P95, fast.P99)
+	}
+	if len(fast.Buckets) != len(Buckets)+1 || fast.Buckets[1].Count != 98 || fast.Buckets[6].Count != 1 || fast.Buckets[len(Buckets)] != (Bucket{Count: 1}) {
+		t.Errorf("Buckets = %+v", fast.Buckets)
+	}
+	if slow.Name != "Slow" || slow.Count != 1 || slow.Slow != 1 || slow.P99 != 40*time.Millisecond {
+		t.Errorf("Opcodes[1] = %+v, want its only time as its percentiles", slow)
+	}
+}
+
+func TestRecorderWithoutThreshold(t *testing.T) {
+	r := New("test server", 0, func(byte) string { return "" })
+	r.Observe(1, time.Minute)
+	if stats := r.Stats(); stats.Opcodes[0].Slow != 0 {
+		t.Errorf("Stats() = %+v, want nothing slow without a threshold", stats)
+	}
+}
+
+func TestTimer(t *testing.T) {
+	r := New("test server", 0, func(byte) string { return "" })
+
+	var zero Timer
+	zero.Stop()
+
+	timer := r.Start(3)
+	timer.Stop()
+	timer.Stop()
+	if stats := r.Stats(); len(stats.Opcodes) != 1 || stats.Opcodes[0].Count != 1 {
+		t.Errorf("Stats() = %+v, want a single packet timed", stats)
+	}
+}
@@ -0,0 +1,173 @@
+package protocol
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/frostwind/l2go/loginserver/crypt"
+)
+
+var benchmarkXORKey = []byte{0x94, 0x35, 0x00, 0x00, 0xa1, 0x6c, 0x54, 0x87}
+
+func newBenchmarkEngine(tb testing.TB) *CryptoEngine {
+	tb.Helper()
+
+	engine := NewCryptoEngine()
+	if err := engine.InitializeBlowfish(crypt.StaticBlowfishKey); err != nil {
+		tb.Fatalf("InitializeBlowfish: %v", err)
+	}
+	if err := engine.InitializeXOR(benchmarkXORKey); err != nil {
+		tb.Fatalf("InitializeXOR: %v", err)
+	}
+	return engine
+}
+
+func TestAppendEncodeDoesNotAllocate(t *testing.T) {
+	engine := newBenchmarkEngine(t)
+	login, game := NewLoginProtocol(), NewGameProtocol()
+	payload := bytes.Repeat([]byte{0x01}, 61)
+	dst := make([]byte, 0, 256)
+
+	tests := []struct {
+		name   string
+		encode func() error
+	}{
+		{"login", func() error {
+			_, err := login.AppendEncodePacket(dst[:0], 0x05, payload, engine)
+			return err
+		}},
+		{"game", func() error {
+			_, err := game.AppendEncodePacket(dst[:0], 0x38, payload, engine)
+			return err
+		}},
+		{"game extended", func() error {
+			_, err := game.AppendEncodeKeyedPacket(dst[:0], ExtendedKey(0xd0, 0x08), payload, engine)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		allocs := testing.AllocsPerRun(100, func() {
+			if err := tt.encode(); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("%s: %.0f allocations per encode, want 0", tt.name, allocs)
+		}
+	}
+}
+
+func TestAppendEncodeMatchesEncode(t *testing.T) {
+	payload := []byte{0x01, 0x02, 0x03, 0x04, 0x05}
+	prefix := []byte{0xff, 0xff}
+
+	loginA, loginB := newBenchmarkEngine(t), newBenchmarkEngine(t)
+	want, _ := NewLoginProtocol().EncodePacket(0x05, payload, loginA)
+	got, _ := NewLoginProtocol().AppendEncodePacket(bytes.Clone(prefix), 0x05, payload, loginB)
+	if !bytes.Equal(got[:2], prefix) || !bytes.Equal(got[2:], want) {
+		t.Errorf("login: got % x, want prefix + % x", got, want)
+	}
+
+	gameA, gameB := newBenchmarkEngine(t), newBenchmarkEngine(t)
+	want, _ = NewGameProtocol().EncodePacket(0x38, payload, gameA)
+	got, _ = NewGameProtocol().AppendEncodePacket(bytes.Clone(prefix), 0x38, payload, gameB)
+	if !bytes.Equal(got[:2], prefix) || !bytes.Equal(got[2:], want) {
+		t.Errorf("game: got % x, want prefix + % x", got, want)
+	}
+}
+
+func BenchmarkBlowfishEncode(b *testing.B) {
+	engine := newBenchmarkEngine(b)
+	login := NewLoginProtocol()
+	payload := make([]byte, 128)
+	dst := make([]byte, 0, 256)
+
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		login.AppendEncodePacket(dst[:0], 0x04, payload, engine)
+	}
+}
+
+func BenchmarkBlowfishDecode(b *testing.B) {
+	engine := newBenchmarkEngine(b)
+	login := NewLoginProtocol()
+	raw, _ := login.EncodePacket(0x04, make([]byte, 127), engine)
+	buffer := make([]byte, len(raw))
+
+	b.SetBytes(int64(len(raw)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		copy(buffer, raw)
+		login.DecodePacketInPlace(buffer, engine)
+	}
+}
+
+func BenchmarkXORPipeline(b *testing.B) {
+	client, server := newBenchmarkEngine(b), newBenchmarkEngine(b)
+	game := NewGameProtocol()
+	payload := make([]byte, 64)
+	dst := make([]byte, 0, 128)
+
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		raw, err := game.AppendEncodePacket(dst[:0], 0x38, payload, client)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err := game.DecodeKeyedPacketInPlace(raw, server); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkXOREncodeCopying(b *testing.B) {
+	engine := newBenchmarkEngine(b)
+	game := NewGameProtocol()
+	payload := make([]byte, 64)
+
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		game.EncodePacket(0x38, payload, engine)
+	}
+}
+
+// benchmarkBlowfishPayload encrypts and decrypts a payload of size bytes, as a burst of the login server would be
+func benchmarkBlowfishPayload(b *testing.B, size int, options ParallelOptions) {
+	engine := newBenchmarkEngine(b)
+	engine.SetParallelBlowfish(options)
+	payload := make([]byte, size)
+	dst := make([]byte, 0, size)
+
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		encrypted, err := engine.AppendEncryptBlowfish(dst[:0], payload)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := engine.DecryptBlowfishInPlace(encrypted); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBlowfishParallel(b *testing.B) {
+	for _, size := range []int{512, 4096, 16384, 65536} {
+		b.Run("sequential/"+strconv.Itoa(size), func(b *testing.B) {
+			benchmarkBlowfishPayload(b, size, ParallelOptions{})
+		})
+		b.Run("parallel/"+strconv.Itoa(size), func(b *testing.B) {
+			benchmarkBlowfishPayload(b, size, ParallelOptions{Enabled: true})
+		})
+	}
+}
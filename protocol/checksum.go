@@ -0,0 +1,59 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrChecksumMismatch is returned when a strictly verified login packet carries a wrong checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+const loginChecksumSize = 4
+
+// ChecksumMode selects how the checksum of inbound login packets is enforced
+type ChecksumMode int
+
+const (
+	// ChecksumStrict rejects packets with a wrong checksum
+	ChecksumStrict ChecksumMode = iota
+	// ChecksumLenient accepts packets with a wrong checksum, only counting them
+	ChecksumLenient
+	// ChecksumDisabled skips the verification
+	ChecksumDisabled
+)
+
+func (m ChecksumMode) String() string {
+	switch m {
+	case ChecksumStrict:
+		return "Strict"
+	case ChecksumLenient:
+		return "Lenient"
+	case ChecksumDisabled:
+		return "Disabled"
+	default:
+		return "Unknown"
+	}
+}
+
+// ChecksumStats counts the checksum verifications of a login link
+type ChecksumStats struct {
+	Verified   int64 `json:"verified"`
+	Mismatches int64 `json:"mismatches"`
+}
+
+// verifyLoginChecksum checks a decrypted login packet without modifying it. The
+// checksum is the XOR of every 32 bits word preceding it, and sits right before
+// the last 4 bytes of the packet (see crypt.Checksum).
+func verifyLoginChecksum(packet []byte) bool {
+	if len(packet)%loginChecksumSize != 0 {
+		return false
+	}
+
+	end := len(packet) - 2*loginChecksumSize
+	var checksum uint32
+	for i := 0; i < end; i += loginChecksumSize {
+		checksum ^= binary.LittleEndian.Uint32(packet[i:])
+	}
+
+	return checksum == binary.LittleEndian.Uint32(packet[end:])
+}
@@ -0,0 +1,85 @@
+package protocol
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/frostwind/l2go/loginserver/crypt"
+)
+
+func TestLoginChecksum(t *testing.T) {
+	// Every payload size modulo the block size, the checksum must never overlap the data
+	for size := 0; size < 16; size++ {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(0xa0 + i)
+		}
+
+		sender, receiver := newBenchmarkEngine(t), newBenchmarkEngine(t)
+		raw, err := NewLoginProtocol().EncodePacket(0x05, data, sender)
+		if err != nil {
+			t.Fatalf("EncodePacket: %v", err)
+		}
+
+		decrypted, err := receiver.DecryptBlowfish(raw)
+		if err != nil {
+			t.Fatalf("DecryptBlowfish: %v", err)
+		}
+		if !verifyLoginChecksum(decrypted) {
+			t.Errorf("size %d: checksum doesn't verify", size)
+		}
+		if !crypt.Checksum(append([]byte(nil), decrypted...)) {
+			t.Errorf("size %d: the login server would reject the packet", size)
+		}
+
+		opcode, got, err := NewLoginProtocol().DecodePacket(raw, receiver)
+		if err != nil {
+			t.Fatalf("size %d: DecodePacket: %v", size, err)
+		}
+		if opcode != 0x05 || len(got) < size || string(got[:size]) != string(data) {
+			t.Errorf("size %d: payload was altered: % x", size, got)
+		}
+	}
+}
+
+func TestLoginChecksumModes(t *testing.T) {
+	tests := []struct {
+		mode           ChecksumMode
+		wantErr        bool
+		wantMismatches int64
+	}{
+		{ChecksumStrict, true, 1},
+		{ChecksumLenient, false, 1},
+		{ChecksumDisabled, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			sender, receiver := newBenchmarkEngine(t), newBenchmarkEngine(t)
+
+			raw, err := NewLoginProtocol().EncodePacket(0x04, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9}, sender)
+			if err != nil {
+				t.Fatalf("EncodePacket: %v", err)
+			}
+
+			// Flip a payload bit without touching the checksum
+			decrypted, _ := receiver.DecryptBlowfish(raw)
+			decrypted[2] ^= 0x01
+			tampered, _ := receiver.EncryptBlowfish(decrypted)
+
+			login := NewLoginProtocol()
+			login.SetChecksumMode(tt.mode)
+
+			_, _, err = login.DecodePacket(tampered, receiver)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodePacket error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrChecksumMismatch) {
+				t.Errorf("error %v is not ErrChecksumMismatch", err)
+			}
+			if stats := login.ChecksumStats(); stats.Mismatches != tt.wantMismatches {
+				t.Errorf("Mismatches = %d, want %d", stats.Mismatches, tt.wantMismatches)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package protocol
+
+import (
+	"crypto/cipher"
+	"fmt"
+
+	"github.com/frostwind/l2go/gameserver/crypt/xor"
+	"github.com/frostwind/l2go/loginserver/crypt/blowfish"
+)
+
+// Phase is a stage of a session, each with the cipher of its server
+type Phase int
+
+const (
+	PhaseLogin Phase = iota // Talking to the login server, Blowfish by default
+	PhaseGame               // Talking to the game server, XOR by default
+	phaseCount
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseLogin:
+		return "Login"
+	case PhaseGame:
+		return "Game"
+	default:
+		return "Unknown"
+	}
+}
+
+// Cipher is the encryption of a phase. The engine serializes the calls on a cipher, which therefore needs
+// no locking of its own, and ciphers in place: Encode and Decode never change the size of data.
+type Cipher interface {
+	Init(key []byte) error
+	Encode(data []byte) error
+	Decode(data []byte) error
+	KeyState() KeyKind // KeyNone until Init, and again once wiped
+	Wipe()             // Zeroes the key material
+}
+
+// BlockCipher is a cipher of independent blocks, which the engine pads data to and may cipher across the
+// workers of the parallel Blowfish
+type BlockCipher interface {
+	Cipher
+	Block() cipher.Block // nil until Init
+}
+
+// newDefaultCipher returns the cipher a phase uses until another one is set
+func newDefaultCipher(phase Phase) Cipher {
+	if phase == PhaseLogin {
+		return &BlowfishCipher{}
+	}
+	return &XORCipher{}
+}
+
+// BlowfishCipher is the cipher of the login server
+type BlowfishCipher struct {
+	block cipher.Block
+	kind  KeyKind
+}
+
+func (c *BlowfishCipher) Init(key []byte) error {
+	block, err := blowfish.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("failed to create Blowfish cipher: %w", err)
+	}
+	c.block = block
+	c.kind = blowfishKeyKind(key)
+	return nil
+}
+
+func (c *BlowfishCipher) Encode(data []byte) error {
+	if err := c.check(data); err != nil {
+		return err
+	}
+	cryptBlocks(c.block, data, false)
+	return nil
+}
+
+func (c *BlowfishCipher) Decode(data []byte) error {
+	if err := c.check(data); err != nil {
+		return err
+	}
+	cryptBlocks(c.block, data, true)
+	return nil
+}
+
+// check tells whether data can be ciphered
+func (c *BlowfishCipher) check(data []byte) error {
+	if c.block == nil {
+		return fmt.Errorf("Blowfish cipher not initialized")
+	}
+	if len(data)%c.block.BlockSize() != 0 {
+		return fmt.Errorf("data length must be multiple of block size")
+	}
+	return nil
+}
+
+func (c *BlowfishCipher) KeyState() KeyKind {
+	if c.block == nil {
+		return KeyNone
+	}
+	return c.kind
+}
+
+func (c *BlowfishCipher) Block() cipher.Block {
+	return c.block
+}
+
+// Wipe zeroes the key schedule
+func (c *BlowfishCipher) Wipe() {
+	if resetter, ok := c.block.(interface{ Reset() }); ok {
+		resetter.Reset()
+	}
+	c.block = nil
+}
+
+// XORCipher is the cipher of the game server, its input and output keys rolling with every packet. A
+// key shorter than 8 bytes leaves the default key of the game server in place.
+type XORCipher struct {
+	keys *xor.Cipher
+	kind KeyKind
+}
+
+func (c *XORCipher) Init(key []byte) error {
+	keys := xor.NewCipher()
+	if len(key) >= 8 {
+		copy(keys.InputKey, key[:8])
+		copy(keys.OutputKey, key[:8])
+	}
+	c.keys = keys
+	c.kind = xorKeyKind(key)
+	return nil
+}
+
+// Encode encrypts data and advances the output key
+func (c *XORCipher) Encode(data []byte) error {
+	if c.keys == nil {
+		return fmt.Errorf("XOR cipher not initialized")
+	}
+	xor.Encrypt(data, c.keys.OutputKey)
+	return nil
+}
+
+// Decode decrypts data and advances the input key
+func (c *XORCipher) Decode(data []byte) error {
+	if c.keys == nil {
+		return fmt.Errorf("XOR cipher not initialized")
+	}
+	xor.Decrypt(data, c.keys.InputKey)
+	return nil
+}
+
+func (c *XORCipher) KeyState() KeyKind {
+	if c.keys == nil {
+		return KeyNone
+	}
+	return c.kind
+}
+
+// Wipe zeroes both keys
+func (c *XORCipher) Wipe() {
+	if c.keys != nil {
+		clear(c.keys.InputKey)
+		clear(c.keys.OutputKey)
+		c.keys = nil
+	}
+}
+
+// NullCipher leaves a phase in clear, for the servers running without encryption. It never reports a
+// key, so the protocols skip the padding and the checksum that come with one.
+type NullCipher struct{}
+
+func (NullCipher) Init(key []byte) error    { return nil }
+func (NullCipher) Encode(data []byte) error { return nil }
+func (NullCipher) Decode(data []byte) error { return nil }
+func (NullCipher) KeyState() KeyKind        { return KeyNone }
+func (NullCipher) Wipe()                    {}
@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/frostwind/l2go/loginserver/crypt"
+)
+
+// negateCipher is a cipher of a made up chronicle, flipping every bit
+type negateCipher struct {
+	key []byte
+}
+
+func (c *negateCipher) Init(key []byte) error {
+	c.key = bytes.Clone(key)
+	return nil
+}
+
+func (c *negateCipher) Encode(data []byte) error {
+	for i := range data {
+		data[i] = ^data[i]
+	}
+	return nil
+}
+
+func (c *negateCipher) Decode(data []byte) error {
+	return c.Encode(data)
+}
+
+func (c *negateCipher) KeyState() KeyKind {
+	if c.key == nil {
+		return KeyNone
+	}
+	return KeyDynamic
+}
+
+func (c *negateCipher) Wipe() {
+	c.key = nil
+}
+
+func TestCiphers(t *testing.T) {
+	payload := []byte{0x01, 0x02, 0x03}
+
+	tests := []struct {
+		name      string
+		phase     Phase
+		newCipher func() Cipher
+		key       []byte
+		kind      KeyKind
+	}{
+		{"blowfish", PhaseLogin, func() Cipher { return &BlowfishCipher{} }, crypt.StaticBlowfishKey, KeyStatic},
+		{"xor", PhaseGame, func() Cipher { return &XORCipher{} }, benchmarkXORKey, KeyStatic},
+		{"null", PhaseGame, func() Cipher { return NullCipher{} }, benchmarkXORKey, KeyNone},
+		{"custom", PhaseGame, func() Cipher { return &negateCipher{} }, []byte{1}, KeyDynamic},
+	}
+
+	for _, tt := range tests {
+		client, server := NewCryptoEngine(), NewCryptoEngine()
+		for _, engine := range []*CryptoEngine{client, server} {
+			engine.SetCipher(tt.phase, tt.newCipher())
+			if engine.Active(tt.phase) {
+				t.Errorf("%s: Active() before Initialize()", tt.name)
+			}
+			if err := engine.Initialize(tt.phase, tt.key); err != nil {
+				t.Fatalf("%s: Initialize() = %v", tt.name, err)
+			}
+		}
+		if active := client.Active(tt.phase); active != (tt.kind != KeyNone) {
+			t.Errorf("%s: Active() = %v", tt.name, active)
+		}
+		if stats := client.Stats(); stats.BlowfishKey+stats.XORKey != tt.kind {
+			t.Errorf("%s: Stats() = %+v, want the key %s", tt.name, stats, tt.kind)
+		}
+
+		data := make([]byte, 8)
+		copy(data, payload)
+		if err := client.Encode(tt.phase, data); err != nil {
+			t.Fatalf("%s: Encode() = %v", tt.name, err)
+		}
+		if encrypted := !bytes.HasPrefix(data, payload); encrypted != (tt.kind != KeyNone) {
+			t.Errorf("%s: Encode() = % x", tt.name, data)
+		}
+		if err := server.Decode(tt.phase, data); err != nil || !bytes.HasPrefix(data, payload) {
+			t.Errorf("%s: Decode() = % x, %v, want % x", tt.name, data, err, payload)
+		}
+
+		client.Wipe()
+		if client.Active(tt.phase) {
+			t.Errorf("%s: Active() after Wipe()", tt.name)
+		}
+	}
+}
+
+func TestNullCipherLeavesLoginInClear(t *testing.T) {
+	handler := NewHandler()
+	handler.SetCipher(PhaseLogin, NullCipher{})
+	if err := handler.InitializeBlowfish(crypt.StaticBlowfishKey); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := handler.EncodeLoginPacket(0x05, []byte{0x01, 0x02})
+	if err != nil || !bytes.Equal(raw, []byte{0x05, 0x01, 0x02}) {
+		t.Errorf("EncodeLoginPacket() = % x, %v, want the packet unpadded and in clear", raw, err)
+	}
+}
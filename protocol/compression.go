@@ -0,0 +1,244 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// Compression flags prefixed to every packet body once compression is negotiated on a link
+const (
+	compressionFlagRaw  byte = 0x00
+	compressionFlagZlib byte = 0x01
+)
+
+const (
+	// DefaultCompressionThreshold is the smallest body worth compressing
+	DefaultCompressionThreshold = 256
+
+	// DefaultMaxDecompressedSize caps the size of an inflated body
+	DefaultMaxDecompressedSize = 1 << 20
+
+	compressionOfferSize = 5
+)
+
+// CompressionOptions describes what one side of a link supports
+type CompressionOptions struct {
+	Enabled   bool `json:"enabled"`
+	Threshold int  `json:"threshold"` // Bodies smaller than this are sent as is
+	Level     int  `json:"level"`     // zlib level, zlib.DefaultCompression when zero
+	MaxSize   int  `json:"maxSize"`   // Largest accepted decompressed body
+}
+
+// withDefaults fills the zero values of the options
+func (o CompressionOptions) withDefaults() CompressionOptions {
+	if o.Threshold <= 0 {
+		o.Threshold = DefaultCompressionThreshold
+	}
+	if o.Level == 0 {
+		o.Level = zlib.DefaultCompression
+	}
+	if o.MaxSize <= 0 {
+		o.MaxSize = DefaultMaxDecompressedSize
+	}
+	return o
+}
+
+// Offer encodes the options so they can be sent to the other side of a link
+func (o CompressionOptions) Offer() []byte {
+	offer := make([]byte, compressionOfferSize)
+	if o.Enabled {
+		offer[0] = 1
+	}
+	binary.LittleEndian.PutUint32(offer[1:], uint32(o.withDefaults().Threshold))
+	return offer
+}
+
+// ParseCompressionOffer decodes the options sent by the other side of a link
+func ParseCompressionOffer(offer []byte) (CompressionOptions, error) {
+	if len(offer) < compressionOfferSize {
+		return CompressionOptions{}, fmt.Errorf("compression offer too short: %d bytes", len(offer))
+	}
+	return CompressionOptions{
+		Enabled:   offer[0] == 1,
+		Threshold: int(binary.LittleEndian.Uint32(offer[1:])),
+	}, nil
+}
+
+// Trailer returns the offer of the options preceded by opcodes.CompressionMarker, appended to the handshake
+// packets of a link. It is empty when compression is disabled, the other side taking no offer as a refusal.
+func (o CompressionOptions) Trailer() []byte {
+	if !o.Enabled {
+		return nil
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, opcodes.CompressionMarker), o.Offer()...)
+}
+
+// ParseCompressionTrailer returns the options offered by the bytes following the fields of a handshake packet,
+// after the null crypto marker if any. They are disabled when the other side offered nothing.
+func ParseCompressionTrailer(trailer []byte) CompressionOptions {
+	if len(trailer) >= 4 && binary.LittleEndian.Uint32(trailer) == opcodes.NullCryptoMarker {
+		trailer = trailer[4:]
+	}
+	if len(trailer) < 4 || binary.LittleEndian.Uint32(trailer) != opcodes.CompressionMarker {
+		return CompressionOptions{}
+	}
+
+	options, err := ParseCompressionOffer(trailer[4:])
+	if err != nil {
+		return CompressionOptions{}
+	}
+	return options
+}
+
+// NegotiateCompression returns the options used on a link: compression is only
+// enabled when both sides support it, using the largest of the two thresholds
+func NegotiateCompression(local, remote CompressionOptions) CompressionOptions {
+	result := local.withDefaults()
+	result.Enabled = local.Enabled && remote.Enabled
+	if remote.Threshold > result.Threshold {
+		result.Threshold = remote.Threshold
+	}
+	return result
+}
+
+// CompressionStats holds the compression metrics of a link
+type CompressionStats struct {
+	PacketsSent         int64 `json:"packetsSent"`
+	PacketsCompressed   int64 `json:"packetsCompressed"`
+	BytesBefore         int64 `json:"bytesBefore"`
+	BytesAfter          int64 `json:"bytesAfter"`
+	PacketsReceived     int64 `json:"packetsReceived"`
+	PacketsDecompressed int64 `json:"packetsDecompressed"`
+}
+
+// Ratio returns the compressed to uncompressed size ratio of the sent packets
+func (s CompressionStats) Ratio() float64 {
+	if s.BytesBefore == 0 {
+		return 1
+	}
+	return float64(s.BytesAfter) / float64(s.BytesBefore)
+}
+
+// Compressor compresses and inflates the packet bodies of a link
+type Compressor struct {
+	options CompressionOptions
+	stats   CompressionStats
+	mu      sync.Mutex
+}
+
+// NewCompressor creates a compressor using the negotiated options
+func NewCompressor(options CompressionOptions) *Compressor {
+	return &Compressor{options: options.withDefaults()}
+}
+
+// Options returns the options the compressor was created with
+func (c *Compressor) Options() CompressionOptions {
+	return c.options
+}
+
+// Compress prefixes the body with its compression flag, compressing it when it
+// is larger than the threshold and compression actually saves space
+func (c *Compressor) Compress(body []byte) ([]byte, error) {
+	out := c.raw(body)
+
+	if len(body) >= c.options.Threshold {
+		var buffer bytes.Buffer
+		buffer.WriteByte(compressionFlagZlib)
+		binary.Write(&buffer, binary.LittleEndian, uint32(len(body)))
+
+		writer, err := zlib.NewWriterLevel(&buffer, c.options.Level)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zlib writer: %w", err)
+		}
+		if _, err := writer.Write(body); err != nil {
+			return nil, fmt.Errorf("failed to compress packet: %w", err)
+		}
+		if err := writer.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress packet: %w", err)
+		}
+
+		if buffer.Len() < len(out) {
+			out = buffer.Bytes()
+		}
+	}
+
+	c.mu.Lock()
+	c.stats.PacketsSent++
+	c.stats.BytesBefore += int64(len(body))
+	c.stats.BytesAfter += int64(len(out))
+	if out[0] == compressionFlagZlib {
+		c.stats.PacketsCompressed++
+	}
+	c.mu.Unlock()
+
+	return out, nil
+}
+
+// Decompress strips the compression flag and inflates the body if needed
+func (c *Compressor) Decompress(packet []byte) ([]byte, error) {
+	if len(packet) == 0 {
+		return nil, fmt.Errorf("missing compression flag")
+	}
+
+	var body []byte
+	switch packet[0] {
+	case compressionFlagRaw:
+		body = packet[1:]
+
+	case compressionFlagZlib:
+		if len(packet) < 5 {
+			return nil, fmt.Errorf("compressed packet too short: %d bytes", len(packet))
+		}
+
+		size := int(binary.LittleEndian.Uint32(packet[1:]))
+		if size > c.options.MaxSize {
+			return nil, fmt.Errorf("decompressed size %d exceeds the limit of %d bytes", size, c.options.MaxSize)
+		}
+
+		reader, err := zlib.NewReader(bytes.NewReader(packet[5:]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress packet: %w", err)
+		}
+		defer reader.Close()
+
+		body = make([]byte, size)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return nil, fmt.Errorf("failed to decompress packet: %w", err)
+		}
+		if n, _ := reader.Read(make([]byte, 1)); n != 0 {
+			return nil, fmt.Errorf("decompressed packet is larger than announced")
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown compression flag 0x%02X", packet[0])
+	}
+
+	c.mu.Lock()
+	c.stats.PacketsReceived++
+	if packet[0] == compressionFlagZlib {
+		c.stats.PacketsDecompressed++
+	}
+	c.mu.Unlock()
+
+	return body, nil
+}
+
+// Stats returns a snapshot of the compression metrics
+func (c *Compressor) Stats() CompressionStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *Compressor) raw(body []byte) []byte {
+	out := make([]byte, 1+len(body))
+	out[0] = compressionFlagRaw
+	copy(out[1:], body)
+	return out
+}
@@ -0,0 +1,139 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+func TestNegotiateCompression(t *testing.T) {
+	tests := []struct {
+		name          string
+		local, remote CompressionOptions
+		wantEnabled   bool
+		wantThreshold int
+	}{
+		{"both enabled", CompressionOptions{Enabled: true, Threshold: 128}, CompressionOptions{Enabled: true, Threshold: 512}, true, 512},
+		{"remote disabled", CompressionOptions{Enabled: true}, CompressionOptions{}, false, DefaultCompressionThreshold},
+		{"local disabled", CompressionOptions{}, CompressionOptions{Enabled: true}, false, DefaultCompressionThreshold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote, err := ParseCompressionOffer(tt.remote.Offer())
+			if err != nil {
+				t.Fatalf("ParseCompressionOffer: %v", err)
+			}
+
+			got := NegotiateCompression(tt.local, remote)
+			if got.Enabled != tt.wantEnabled || got.Threshold != tt.wantThreshold {
+				t.Errorf("got enabled=%v threshold=%d, want %v %d", got.Enabled, got.Threshold, tt.wantEnabled, tt.wantThreshold)
+			}
+		})
+	}
+}
+
+func TestParseCompressionTrailer(t *testing.T) {
+	offered := CompressionOptions{Enabled: true, Threshold: 512}
+	nullCrypto := binary.LittleEndian.AppendUint32(nil, opcodes.NullCryptoMarker)
+
+	tests := []struct {
+		name    string
+		trailer []byte
+		want    CompressionOptions
+	}{
+		{"offer", offered.Trailer(), CompressionOptions{Enabled: true, Threshold: 512}},
+		{"after the null crypto marker", append(nullCrypto, offered.Trailer()...), CompressionOptions{Enabled: true, Threshold: 512}},
+		{"no trailer", nil, CompressionOptions{}},
+		{"null crypto alone", nullCrypto, CompressionOptions{}},
+		{"disabled", CompressionOptions{Threshold: 512}.Trailer(), CompressionOptions{}},
+		{"truncated offer", offered.Trailer()[:6], CompressionOptions{}},
+		{"unknown marker", []byte{1, 2, 3, 4, 1, 0, 2, 0, 0}, CompressionOptions{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseCompressionTrailer(tt.trailer); got != tt.want {
+				t.Errorf("ParseCompressionTrailer() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressorRoundTrip(t *testing.T) {
+	compressor := NewCompressor(CompressionOptions{Enabled: true, Threshold: 64})
+
+	small := []byte{0x1f, 0x01, 0x02}
+	large := bytes.Repeat([]byte("server list entry "), 64)
+
+	for _, body := range [][]byte{small, large} {
+		packet, err := compressor.Compress(body)
+		if err != nil {
+			t.Fatalf("Compress: %v", err)
+		}
+
+		got, err := compressor.Decompress(packet)
+		if err != nil {
+			t.Fatalf("Decompress: %v", err)
+		}
+		if !bytes.Equal(got, body) {
+			t.Errorf("round trip mismatch for a %d bytes body", len(body))
+		}
+	}
+
+	stats := compressor.Stats()
+	if stats.PacketsSent != 2 || stats.PacketsCompressed != 1 || stats.PacketsDecompressed != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if ratio := stats.Ratio(); ratio >= 0.5 {
+		t.Errorf("Ratio() = %.2f, expected the repeated payload to compress well", ratio)
+	}
+}
+
+func TestCompressorRejectsOversizedBodies(t *testing.T) {
+	sender := NewCompressor(CompressionOptions{Enabled: true, Threshold: 1})
+	receiver := NewCompressor(CompressionOptions{Enabled: true, MaxSize: 1024})
+
+	packet, err := sender.Compress(make([]byte, 4096))
+	if err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	if _, err := receiver.Decompress(packet); err == nil {
+		t.Error("Decompress accepted a body above MaxSize")
+	}
+	if _, err := receiver.Decompress([]byte{0x07}); err == nil {
+		t.Error("Decompress accepted an unknown flag")
+	}
+}
+
+func TestGameProtocolCompression(t *testing.T) {
+	key := []byte{0x94, 0x35, 0x00, 0x00, 0xa1, 0x6c, 0x54, 0x87}
+	options := CompressionOptions{Enabled: true, Threshold: 32}
+
+	client, server := NewGameProtocol(), NewGameProtocol()
+	client.SetCompression(options)
+	server.SetCompression(options)
+
+	clientCrypto, serverCrypto := NewCryptoEngine(), NewCryptoEngine()
+	clientCrypto.InitializeXOR(key)
+	serverCrypto.InitializeXOR(key)
+
+	body := bytes.Repeat([]byte{0x41, 0x00}, 200)
+	raw, err := client.EncodePacket(0x38, body, clientCrypto)
+	if err != nil {
+		t.Fatalf("EncodePacket: %v", err)
+	}
+	if len(raw) >= len(body) {
+		t.Errorf("encoded %d bytes for a %d bytes body", len(raw), len(body))
+	}
+
+	opcode, data, err := server.DecodePacket(raw, serverCrypto)
+	if err != nil {
+		t.Fatalf("DecodePacket: %v", err)
+	}
+	if opcode != 0x38 || !bytes.Equal(data, body) {
+		t.Errorf("got opcode 0x%02X and %d bytes", opcode, len(data))
+	}
+}
@@ -0,0 +1,109 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/crypt/xor"
+	"github.com/frostwind/l2go/loginserver/crypt"
+)
+
+// KeyKind tells where the key of a cipher comes from
+type KeyKind int
+
+const (
+	KeyNone    KeyKind = iota // The cipher isn't initialized
+	KeyStatic                 // The key every connection shares, such as the static Blowfish key of the login server
+	KeyDynamic                // A key of the connection, exchanged during its handshake
+)
+
+func (k KeyKind) String() string {
+	switch k {
+	case KeyNone:
+		return "None"
+	case KeyStatic:
+		return "Static"
+	case KeyDynamic:
+		return "Dynamic"
+	default:
+		return "Unknown"
+	}
+}
+
+func (k KeyKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
+
+// CryptoStats holds the metrics of a crypto engine, and the kind of the last keys it was given
+type CryptoStats struct {
+	Encryptions     int64         `json:"encryptions"`
+	Decryptions     int64         `json:"decryptions"`
+	BytesEncrypted  int64         `json:"bytesEncrypted"`
+	BytesDecrypted  int64         `json:"bytesDecrypted"`
+	EncryptTime     time.Duration `json:"encryptTime"` // Spent encrypting, in nanoseconds
+	DecryptTime     time.Duration `json:"decryptTime"`
+	Initializations int64         `json:"initializations"` // Keys installed, Blowfish and XOR
+	Failures        int64         `json:"failures"`        // Keys refused, and packets the ciphers couldn't process
+	Wipes           int64         `json:"wipes"`
+	Parallel        int64         `json:"parallel"` // Blowfish operations split across the workers
+	BlowfishKey     KeyKind       `json:"blowfishKey"`
+	XORKey          KeyKind       `json:"xorKey"`
+}
+
+// Add sums the counters of two engines, the kinds of the keys being left out
+func (s CryptoStats) Add(other CryptoStats) CryptoStats {
+	return CryptoStats{
+		Encryptions:     s.Encryptions + other.Encryptions,
+		Decryptions:     s.Decryptions + other.Decryptions,
+		BytesEncrypted:  s.BytesEncrypted + other.BytesEncrypted,
+		BytesDecrypted:  s.BytesDecrypted + other.BytesDecrypted,
+		EncryptTime:     s.EncryptTime + other.EncryptTime,
+		DecryptTime:     s.DecryptTime + other.DecryptTime,
+		Initializations: s.Initializations + other.Initializations,
+		Failures:        s.Failures + other.Failures,
+		Wipes:           s.Wipes + other.Wipes,
+		Parallel:        s.Parallel + other.Parallel,
+	}
+}
+
+// cryptoMetrics counts the operations of a crypto engine without locking, the ciphers being used concurrently
+type cryptoMetrics struct {
+	encryptions, decryptions       atomic.Int64
+	bytesEncrypted, bytesDecrypted atomic.Int64
+	encryptTime, decryptTime       atomic.Int64
+	initializations, failures      atomic.Int64
+	wipes, parallel                atomic.Int64
+}
+
+// encrypted counts an encryption of size bytes started at start
+func (m *cryptoMetrics) encrypted(size int, start time.Time) {
+	m.encryptions.Add(1)
+	m.bytesEncrypted.Add(int64(size))
+	m.encryptTime.Add(int64(time.Since(start)))
+}
+
+// decrypted counts a decryption of size bytes started at start
+func (m *cryptoMetrics) decrypted(size int, start time.Time) {
+	m.decryptions.Add(1)
+	m.bytesDecrypted.Add(int64(size))
+	m.decryptTime.Add(int64(time.Since(start)))
+}
+
+// blowfishKeyKind tells the static Blowfish key of the login server from the keys of the connections
+func blowfishKeyKind(key []byte) KeyKind {
+	if bytes.Equal(key, crypt.StaticBlowfishKey) {
+		return KeyStatic
+	}
+	return KeyDynamic
+}
+
+// xorKeyKind tells the default XOR key of the game server, which a key too short leaves in place, from the
+// keys it sends in CryptInit
+func xorKeyKind(key []byte) KeyKind {
+	if len(key) < 8 || bytes.Equal(key[:8], xor.NewCipher().InputKey) {
+		return KeyStatic
+	}
+	return KeyDynamic
+}
@@ -0,0 +1,53 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/frostwind/l2go/loginserver/crypt"
+)
+
+func TestCryptoStats(t *testing.T) {
+	engine := NewCryptoEngine()
+	if _, err := engine.EncryptBlowfish([]byte{1}); err == nil {
+		t.Fatal("EncryptBlowfish() succeeded without a key")
+	}
+
+	if err := engine.InitializeBlowfish(crypt.StaticBlowfishKey); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := engine.EncryptBlowfish(make([]byte, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.DecryptBlowfish(encrypted); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.DecryptBlowfish(encrypted[:5]); err == nil {
+		t.Fatal("DecryptBlowfish() of a partial block succeeded")
+	}
+
+	if err := engine.InitializeXOR([]byte{1, 2, 3, 4, 5, 6, 7, 8}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.EncryptXOR(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := engine.Stats()
+	want := CryptoStats{Encryptions: 2, Decryptions: 1, BytesEncrypted: 16 + 10, BytesDecrypted: 16, Initializations: 2, Failures: 2, BlowfishKey: KeyStatic, XORKey: KeyDynamic}
+	stats.EncryptTime, stats.DecryptTime = 0, 0
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+
+	engine.Wipe()
+	if stats := engine.Stats(); stats.BlowfishKey != KeyStatic || stats.Wipes != 1 || stats.Encryptions != 2 || engine.HasBlowfish() {
+		t.Errorf("Stats() after Wipe() = %+v, want the kinds of the keys and the counters kept", stats)
+	}
+
+	if data, err := json.Marshal(want); err != nil || !strings.Contains(string(data), `"blowfishKey":"Static"`) {
+		t.Errorf("json.Marshal() = %s, %v", data, err)
+	}
+}
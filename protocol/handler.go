@@ -1,12 +1,15 @@
 package protocol
 
 import (
-	"crypto/cipher"
+	"encoding/binary"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/frostwind/l2go/gameserver/crypt/xor"
-	"golang.org/x/crypto/blowfish"
+	"github.com/frostwind/l2go/loginserver/crypt"
+	"github.com/frostwind/l2go/opcodes"
 )
 
 // Handler implements the ProtocolHandler interface
@@ -58,6 +61,89 @@ func (h *Handler) DecodeGamePacket(raw []byte) (opcode byte, data []byte, err er
 	return h.gameProtocol.DecodePacket(raw, h.cryptoEngine)
 }
 
+// EncodeGameKeyedPacket encodes a packet for the game server, including extended packets
+func (h *Handler) EncodeGameKeyedPacket(key PacketKey, data []byte) ([]byte, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.gameProtocol.EncodeKeyedPacket(key, data, h.cryptoEngine)
+}
+
+// DecodeGameKeyedPacket decodes a packet from the game server, including extended packets
+func (h *Handler) DecodeGameKeyedPacket(raw []byte) (key PacketKey, data []byte, err error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.gameProtocol.DecodeKeyedPacket(raw, h.cryptoEngine)
+}
+
+// GamePacketName returns the name of a packet received from the game server
+func (h *Handler) GamePacketName(key PacketKey) string {
+	return h.gameProtocol.PacketName(key)
+}
+
+// AppendEncodeLoginPacket appends a packet for the login server to dst
+func (h *Handler) AppendEncodeLoginPacket(dst []byte, opcode byte, data []byte) ([]byte, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.loginProtocol.AppendEncodePacket(dst, opcode, data, h.cryptoEngine)
+}
+
+// AppendEncodeGamePacket appends a packet for the game server to dst
+func (h *Handler) AppendEncodeGamePacket(dst []byte, key PacketKey, data []byte) ([]byte, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.gameProtocol.AppendEncodeKeyedPacket(dst, key, data, h.cryptoEngine)
+}
+
+// CryptoStats returns the metrics of the crypto engine, and whether its last keys were static or dynamic
+func (h *Handler) CryptoStats() CryptoStats {
+	return h.cryptoEngine.Stats()
+}
+
+// SetLoginChecksumMode selects how the checksum of login server packets is enforced
+func (h *Handler) SetLoginChecksumMode(mode ChecksumMode) {
+	h.loginProtocol.SetChecksumMode(mode)
+}
+
+// LoginChecksumStats returns the checksum verification counters of the login link
+func (h *Handler) LoginChecksumStats() ChecksumStats {
+	return h.loginProtocol.ChecksumStats()
+}
+
+// NegotiateGameCompression enables compression on the game link when both sides support it
+func (h *Handler) NegotiateGameCompression(local, remote CompressionOptions) CompressionOptions {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	options := NegotiateCompression(local, remote)
+	h.gameProtocol.SetCompression(options)
+	return options
+}
+
+// GameCompressionStats returns the compression metrics of the game link
+func (h *Handler) GameCompressionStats() CompressionStats {
+	if compressor := h.gameProtocol.Compressor(); compressor != nil {
+		return compressor.Stats()
+	}
+	return CompressionStats{}
+}
+
+// SetParallelBlowfish selects whether the Blowfish blocks of the large login packets are ciphered in parallel
+func (h *Handler) SetParallelBlowfish(options ParallelOptions) {
+	h.cryptoEngine.SetParallelBlowfish(options)
+}
+
+// SetCipher replaces the cipher of a phase, for the chronicles encrypting it their own way
+func (h *Handler) SetCipher(phase Phase, cipher Cipher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cryptoEngine.SetCipher(phase, cipher)
+}
+
 // InitializeBlowfish initializes Blowfish encryption for login server
 func (h *Handler) InitializeBlowfish(key []byte) error {
 	h.mu.Lock()
@@ -74,33 +160,80 @@ func (h *Handler) InitializeXOR(key []byte) error {
 	return h.cryptoEngine.InitializeXOR(key)
 }
 
+// Wipe zeroes all the key material held by the handler
+func (h *Handler) Wipe() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cryptoEngine.Wipe()
+}
+
 // LoginProtocol handles login server protocol operations
 type LoginProtocol struct {
-	mu sync.RWMutex
+	checksumMode ChecksumMode
+	verified     atomic.Int64
+	mismatches   atomic.Int64
+	mu           sync.RWMutex
 }
 
-// NewLoginProtocol creates a new login protocol handler
+// NewLoginProtocol creates a new login protocol handler, verifying checksums strictly
 func NewLoginProtocol() *LoginProtocol {
-	return &LoginProtocol{}
+	return &LoginProtocol{checksumMode: ChecksumStrict}
+}
+
+// SetChecksumMode selects how the checksum of inbound packets is enforced
+func (lp *LoginProtocol) SetChecksumMode(mode ChecksumMode) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	lp.checksumMode = mode
+}
+
+// ChecksumMode returns how the checksum of inbound packets is enforced
+func (lp *LoginProtocol) ChecksumMode() ChecksumMode {
+	lp.mu.RLock()
+	defer lp.mu.RUnlock()
+	return lp.checksumMode
+}
+
+// ChecksumStats returns the checksum verification counters
+func (lp *LoginProtocol) ChecksumStats() ChecksumStats {
+	return ChecksumStats{
+		Verified:   lp.verified.Load(),
+		Mismatches: lp.mismatches.Load(),
+	}
 }
 
 // EncodePacket encodes a login server packet
 func (lp *LoginProtocol) EncodePacket(opcode byte, data []byte, crypto *CryptoEngine) ([]byte, error) {
-	// Create packet with opcode and data
-	packet := make([]byte, 1+len(data))
-	packet[0] = opcode
-	copy(packet[1:], data)
+	return lp.AppendEncodePacket(nil, opcode, data, crypto)
+}
+
+// AppendEncodePacket appends an encoded login server packet to dst. Once Blowfish
+// is initialized the packet carries a checksum and is padded to the block size.
+func (lp *LoginProtocol) AppendEncodePacket(dst []byte, opcode byte, data []byte, crypto *CryptoEngine) ([]byte, error) {
+	start := len(dst)
+
+	// Reserve room for the checksum and the Blowfish padding so encryption happens in place.
+	// The checksum goes in the block following the padded payload, so it never overlaps it.
+	size := blowfishPaddedSize(1+len(data)) + 2*loginChecksumSize
+	dst = slices.Grow(dst, size)
+	dst = append(dst, opcode)
+	dst = append(dst, data...)
 
 	// Encrypt if Blowfish is initialized
 	if crypto.HasBlowfish() {
-		encrypted, err := crypto.EncryptBlowfish(packet)
+		dst = dst[:start+size]
+		clear(dst[start+1+len(data):])
+		crypt.Checksum(dst[start:])
+
+		encrypted, err := crypto.AppendEncryptBlowfish(dst[:start], dst[start:])
 		if err != nil {
 			return nil, fmt.Errorf("failed to encrypt login packet: %w", err)
 		}
 		return encrypted, nil
 	}
 
-	return packet, nil
+	return dst, nil
 }
 
 // DecodePacket decodes a login server packet
@@ -108,225 +241,489 @@ func (lp *LoginProtocol) DecodePacket(raw []byte, crypto *CryptoEngine) (opcode
 	if len(raw) == 0 {
 		return 0, nil, fmt.Errorf("empty packet")
 	}
+	if crypto.HasBlowfish() {
+		raw = slices.Clone(raw)
+	}
 
-	packet := raw
+	return lp.DecodePacketInPlace(raw, crypto)
+}
 
-	// Decrypt if Blowfish is initialized
+// DecodePacketInPlace decodes a login server packet, decrypting raw in place.
+// The returned data aliases raw.
+func (lp *LoginProtocol) DecodePacketInPlace(raw []byte, crypto *CryptoEngine) (opcode byte, data []byte, err error) {
+	if len(raw) == 0 {
+		return 0, nil, fmt.Errorf("empty packet")
+	}
+
+	// Decrypt and verify the checksum if Blowfish is initialized
 	if crypto.HasBlowfish() {
-		decrypted, err := crypto.DecryptBlowfish(raw)
-		if err != nil {
+		if err := crypto.DecryptBlowfishInPlace(raw); err != nil {
 			return 0, nil, fmt.Errorf("failed to decrypt login packet: %w", err)
 		}
-		packet = decrypted
+		if err := lp.verifyChecksum(raw); err != nil {
+			return 0, nil, err
+		}
 	}
 
-	if len(packet) == 0 {
-		return 0, nil, fmt.Errorf("empty decrypted packet")
+	opcode = raw[0]
+	if len(raw) > 1 {
+		data = raw[1:]
 	}
 
-	opcode = packet[0]
-	if len(packet) > 1 {
-		data = packet[1:]
+	return opcode, data, nil
+}
+
+// verifyChecksum checks a decrypted packet, failing only in strict mode
+func (lp *LoginProtocol) verifyChecksum(packet []byte) error {
+	mode := lp.ChecksumMode()
+	if mode == ChecksumDisabled {
+		return nil
 	}
 
-	return opcode, data, nil
+	if len(packet) >= 2*loginChecksumSize && verifyLoginChecksum(packet) {
+		lp.verified.Add(1)
+		return nil
+	}
+
+	lp.mismatches.Add(1)
+	if mode == ChecksumStrict {
+		return fmt.Errorf("%w: login packet 0x%02X", ErrChecksumMismatch, packet[0])
+	}
+	return nil
+}
+
+// blowfishPaddedSize returns size rounded up to the Blowfish block size
+func blowfishPaddedSize(size int) int {
+	return (size + 7) &^ 7
 }
 
 // GameProtocol handles game server protocol operations
 type GameProtocol struct {
-	mu sync.RWMutex
+	outgoing   *Registry
+	incoming   *Registry
+	compressor *Compressor
+	mu         sync.RWMutex
 }
 
 // NewGameProtocol creates a new game protocol handler
 func NewGameProtocol() *GameProtocol {
-	return &GameProtocol{}
+	return &GameProtocol{
+		outgoing: NewGameRegistry(opcodes.ClientToServer),
+		incoming: NewGameRegistry(opcodes.ServerToClient),
+	}
 }
 
 // EncodePacket encodes a game server packet
 func (gp *GameProtocol) EncodePacket(opcode byte, data []byte, crypto *CryptoEngine) ([]byte, error) {
-	// Create packet with opcode and data
-	packet := make([]byte, 1+len(data))
-	packet[0] = opcode
-	copy(packet[1:], data)
+	return gp.EncodeKeyedPacket(Key(opcode), data, crypto)
+}
+
+// EncodeKeyedPacket encodes a game server packet, writing the sub-opcode of extended packets
+func (gp *GameProtocol) EncodeKeyedPacket(key PacketKey, data []byte, crypto *CryptoEngine) ([]byte, error) {
+	return gp.AppendEncodeKeyedPacket(nil, key, data, crypto)
+}
+
+// AppendEncodePacket appends an encoded game server packet to dst
+func (gp *GameProtocol) AppendEncodePacket(dst []byte, opcode byte, data []byte, crypto *CryptoEngine) ([]byte, error) {
+	return gp.AppendEncodeKeyedPacket(dst, Key(opcode), data, crypto)
+}
+
+// AppendEncodeKeyedPacket appends an encoded game server packet to dst. It does not
+// allocate when dst has enough capacity and compression is disabled.
+func (gp *GameProtocol) AppendEncodeKeyedPacket(dst []byte, key PacketKey, data []byte, crypto *CryptoEngine) ([]byte, error) {
+	extended := gp.outgoing.IsExtended(key.Opcode)
+	if !extended && key.SubOpcode != 0 {
+		return nil, fmt.Errorf("opcode 0x%02X does not take a sub-opcode", key.Opcode)
+	}
+
+	// Append opcode, sub-opcode and data
+	start := len(dst)
+	dst = slices.Grow(dst, 3+len(data))
+	dst = append(dst, key.Opcode)
+	if extended {
+		dst = binary.LittleEndian.AppendUint16(dst, key.SubOpcode)
+	}
+	dst = append(dst, data...)
+
+	// Compress if negotiated on this link
+	if compressor := gp.Compressor(); compressor != nil {
+		compressed, err := compressor.Compress(dst[start:])
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst[:start], compressed...)
+	}
 
 	// Encrypt if XOR is initialized
 	if crypto.HasXOR() {
-		encrypted, err := crypto.EncryptXOR(packet)
-		if err != nil {
+		if err := crypto.EncryptXORInPlace(dst[start:]); err != nil {
 			return nil, fmt.Errorf("failed to encrypt game packet: %w", err)
 		}
-		return encrypted, nil
 	}
 
-	return packet, nil
+	return dst, nil
 }
 
-// DecodePacket decodes a game server packet
+// DecodePacket decodes a game server packet. The sub-opcode of extended packets
+// is left at the start of data, use DecodeKeyedPacket to have it parsed.
 func (gp *GameProtocol) DecodePacket(raw []byte, crypto *CryptoEngine) (opcode byte, data []byte, err error) {
+	packet, err := gp.decrypt(gp.cloneEncrypted(raw, crypto), crypto)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	opcode = packet[0]
+	if len(packet) > 1 {
+		data = packet[1:]
+	}
+
+	return opcode, data, nil
+}
+
+// DecodeKeyedPacket decodes a game server packet, reading the sub-opcode of extended packets
+func (gp *GameProtocol) DecodeKeyedPacket(raw []byte, crypto *CryptoEngine) (key PacketKey, data []byte, err error) {
+	return gp.DecodeKeyedPacketInPlace(gp.cloneEncrypted(raw, crypto), crypto)
+}
+
+// DecodeKeyedPacketInPlace decodes a game server packet, decrypting raw in place.
+// The returned data aliases raw unless the packet was compressed.
+func (gp *GameProtocol) DecodeKeyedPacketInPlace(raw []byte, crypto *CryptoEngine) (key PacketKey, data []byte, err error) {
+	packet, err := gp.decrypt(raw, crypto)
+	if err != nil {
+		return PacketKey{}, nil, err
+	}
+
+	key.Opcode = packet[0]
+	header := 1
+	if gp.incoming.IsExtended(key.Opcode) {
+		if len(packet) < 3 {
+			return PacketKey{}, nil, fmt.Errorf("extended packet 0x%02X is missing its sub-opcode", key.Opcode)
+		}
+		key.SubOpcode = binary.LittleEndian.Uint16(packet[1:])
+		header = 3
+	}
+
+	if len(packet) > header {
+		data = packet[header:]
+	}
+
+	return key, data, nil
+}
+
+// PacketName returns the name of a packet received from the game server
+func (gp *GameProtocol) PacketName(key PacketKey) string {
+	return gp.incoming.Name(key)
+}
+
+func (gp *GameProtocol) decrypt(raw []byte, crypto *CryptoEngine) ([]byte, error) {
 	if len(raw) == 0 {
-		return 0, nil, fmt.Errorf("empty packet")
+		return nil, fmt.Errorf("empty packet")
 	}
 
 	packet := raw
 
 	// Decrypt if XOR is initialized
 	if crypto.HasXOR() {
-		decrypted, err := crypto.DecryptXOR(raw)
+		if err := crypto.DecryptXORInPlace(packet); err != nil {
+			return nil, fmt.Errorf("failed to decrypt game packet: %w", err)
+		}
+	}
+
+	// Decompress if negotiated on this link
+	if compressor := gp.Compressor(); compressor != nil {
+		decompressed, err := compressor.Decompress(packet)
 		if err != nil {
-			return 0, nil, fmt.Errorf("failed to decrypt game packet: %w", err)
+			return nil, err
 		}
-		packet = decrypted
+		packet = decompressed
 	}
 
 	if len(packet) == 0 {
-		return 0, nil, fmt.Errorf("empty decrypted packet")
+		return nil, fmt.Errorf("empty decrypted packet")
 	}
 
-	opcode = packet[0]
-	if len(packet) > 1 {
-		data = packet[1:]
+	return packet, nil
+}
+
+// cloneEncrypted copies raw when decoding would decrypt it, so the caller's buffer is left untouched
+func (gp *GameProtocol) cloneEncrypted(raw []byte, crypto *CryptoEngine) []byte {
+	if crypto.HasXOR() {
+		return slices.Clone(raw)
 	}
+	return raw
+}
 
-	return opcode, data, nil
+// SetCompression enables compression with the negotiated options, or disables it
+func (gp *GameProtocol) SetCompression(options CompressionOptions) {
+	gp.mu.Lock()
+	defer gp.mu.Unlock()
+
+	if !options.Enabled {
+		gp.compressor = nil
+		return
+	}
+	gp.compressor = NewCompressor(options)
+}
+
+// Compressor returns the compressor of the link, nil when compression is disabled
+func (gp *GameProtocol) Compressor() *Compressor {
+	gp.mu.RLock()
+	defer gp.mu.RUnlock()
+	return gp.compressor
 }
 
-// CryptoEngine manages encryption operations
+// CryptoEngine manages encryption operations, holding the cipher of every phase. The Blowfish and XOR
+// methods are those of the login and game phases, whichever cipher they were given.
 type CryptoEngine struct {
-	blowfishCipher cipher.Block
-	xorCipher      *xor.Cipher
-	mu             sync.RWMutex
+	phases   [phaseCount]*phaseCipher
+	parallel ParallelOptions
+	metrics  cryptoMetrics
+	mu       sync.RWMutex
+}
+
+// phaseCipher is the cipher of a phase, and the kind of the last key it was given
+type phaseCipher struct {
+	cipher Cipher
+	kind   KeyKind
+	mu     sync.Mutex
 }
 
 // NewCryptoEngine creates a new crypto engine
 func NewCryptoEngine() *CryptoEngine {
-	return &CryptoEngine{}
+	ce := &CryptoEngine{}
+	for phase := range ce.phases {
+		ce.phases[phase] = &phaseCipher{cipher: newDefaultCipher(Phase(phase))}
+	}
+	return ce
 }
 
-// InitializeBlowfish initializes Blowfish encryption
-func (ce *CryptoEngine) InitializeBlowfish(key []byte) error {
-	ce.mu.Lock()
-	defer ce.mu.Unlock()
+// SetCipher replaces the cipher of a phase, which starts uninitialized
+func (ce *CryptoEngine) SetCipher(phase Phase, cipher Cipher) {
+	p := ce.phases[phase]
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	cipher, err := blowfish.NewCipher(key)
-	if err != nil {
-		return fmt.Errorf("failed to create Blowfish cipher: %w", err)
+	p.cipher.Wipe()
+	p.cipher = cipher
+	p.kind = KeyNone
+}
+
+// Cipher returns the cipher of a phase
+func (ce *CryptoEngine) Cipher(phase Phase) Cipher {
+	p := ce.phases[phase]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cipher
+}
+
+// Initialize gives its key to the cipher of a phase
+func (ce *CryptoEngine) Initialize(phase Phase, key []byte) error {
+	p := ce.phases[phase]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.cipher.Init(key); err != nil {
+		ce.metrics.failures.Add(1)
+		return err
 	}
+	p.kind = p.cipher.KeyState()
+	ce.metrics.initializations.Add(1)
+	return nil
+}
 
-	ce.blowfishCipher = cipher
+// Active reports whether the cipher of a phase holds a key
+func (ce *CryptoEngine) Active(phase Phase) bool {
+	p := ce.phases[phase]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cipher.KeyState() != KeyNone
+}
+
+// Encode encrypts data in place with the cipher of a phase
+func (ce *CryptoEngine) Encode(phase Phase, data []byte) error {
+	return ce.crypt(phase, data, false)
+}
+
+// Decode decrypts data in place with the cipher of a phase
+func (ce *CryptoEngine) Decode(phase Phase, data []byte) error {
+	return ce.crypt(phase, data, true)
+}
+
+func (ce *CryptoEngine) crypt(phase Phase, data []byte, decrypt bool) error {
+	parallel := ce.ParallelBlowfish()
+	p := ce.phases[phase]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	start := time.Now()
+	if err := ce.cryptCipher(p.cipher, data, decrypt, parallel); err != nil {
+		ce.metrics.failures.Add(1)
+		return err
+	}
+	if decrypt {
+		ce.metrics.decrypted(len(data), start)
+	} else {
+		ce.metrics.encrypted(len(data), start)
+	}
 	return nil
 }
 
-// InitializeXOR initializes XOR encryption
+// cryptCipher ciphers data, across the workers when the cipher is made of blocks and data is large enough
+func (ce *CryptoEngine) cryptCipher(c Cipher, data []byte, decrypt bool, parallel ParallelOptions) error {
+	if blocks, ok := c.(BlockCipher); ok && blocks.Block() != nil && len(data)%blocks.Block().BlockSize() == 0 {
+		if chunks := parallel.chunks(len(data)); chunks > 1 {
+			ce.metrics.parallel.Add(1)
+			cryptBlocksParallel(blocks.Block(), data, decrypt, chunks)
+			return nil
+		}
+	}
+	if decrypt {
+		return c.Decode(data)
+	}
+	return c.Encode(data)
+}
+
+// InitializeBlowfish initializes the cipher of the login phase, Blowfish unless replaced
+func (ce *CryptoEngine) InitializeBlowfish(key []byte) error {
+	return ce.Initialize(PhaseLogin, key)
+}
+
+// InitializeXOR initializes the cipher of the game phase, XOR unless replaced
 func (ce *CryptoEngine) InitializeXOR(key []byte) error {
+	return ce.Initialize(PhaseGame, key)
+}
+
+// Wipe zeroes the key material of every phase, leaving the engine uninitialized
+func (ce *CryptoEngine) Wipe() {
+	for _, p := range ce.phases {
+		p.mu.Lock()
+		p.cipher.Wipe()
+		p.mu.Unlock()
+	}
+	ce.metrics.wipes.Add(1)
+}
+
+// SetParallelBlowfish selects whether the Blowfish blocks of the large payloads are ciphered across the
+// shared pool of workers, the smaller ones always being ciphered by the caller
+func (ce *CryptoEngine) SetParallelBlowfish(options ParallelOptions) {
 	ce.mu.Lock()
 	defer ce.mu.Unlock()
+	ce.parallel = options.withDefaults()
+}
 
-	cipher := xor.NewCipher()
-	// Copy the provided key to the cipher's keys
-	if len(key) >= 8 {
-		copy(cipher.InputKey, key[:8])
-		copy(cipher.OutputKey, key[:8])
+// ParallelBlowfish returns how the Blowfish blocks of the large payloads are ciphered
+func (ce *CryptoEngine) ParallelBlowfish() ParallelOptions {
+	ce.mu.RLock()
+	defer ce.mu.RUnlock()
+	return ce.parallel
+}
+
+// Stats returns a snapshot of the metrics of the engine, and the kind of the last keys it was given. The
+// kinds outlive Wipe, so that a connection failing its handshake still tells which keys it was using.
+func (ce *CryptoEngine) Stats() CryptoStats {
+	var kinds [phaseCount]KeyKind
+	for phase, p := range ce.phases {
+		p.mu.Lock()
+		kinds[phase] = p.kind
+		p.mu.Unlock()
+	}
+
+	return CryptoStats{
+		Encryptions:     ce.metrics.encryptions.Load(),
+		Decryptions:     ce.metrics.decryptions.Load(),
+		BytesEncrypted:  ce.metrics.bytesEncrypted.Load(),
+		BytesDecrypted:  ce.metrics.bytesDecrypted.Load(),
+		EncryptTime:     time.Duration(ce.metrics.encryptTime.Load()),
+		DecryptTime:     time.Duration(ce.metrics.decryptTime.Load()),
+		Initializations: ce.metrics.initializations.Load(),
+		Failures:        ce.metrics.failures.Load(),
+		Wipes:           ce.metrics.wipes.Load(),
+		Parallel:        ce.metrics.parallel.Load(),
+		BlowfishKey:     kinds[PhaseLogin],
+		XORKey:          kinds[PhaseGame],
 	}
-	ce.xorCipher = cipher
-	return nil
 }
 
-// HasBlowfish returns true if Blowfish encryption is initialized
+// HasBlowfish returns true if the cipher of the login phase is initialized
 func (ce *CryptoEngine) HasBlowfish() bool {
-	ce.mu.RLock()
-	defer ce.mu.RUnlock()
-	return ce.blowfishCipher != nil
+	return ce.Active(PhaseLogin)
 }
 
-// HasXOR returns true if XOR encryption is initialized
+// HasXOR returns true if the cipher of the game phase is initialized
 func (ce *CryptoEngine) HasXOR() bool {
-	ce.mu.RLock()
-	defer ce.mu.RUnlock()
-	return ce.xorCipher != nil
+	return ce.Active(PhaseGame)
 }
 
 // EncryptBlowfish encrypts data using Blowfish
 func (ce *CryptoEngine) EncryptBlowfish(data []byte) ([]byte, error) {
-	ce.mu.RLock()
-	defer ce.mu.RUnlock()
+	return ce.AppendEncryptBlowfish(nil, data)
+}
 
-	if ce.blowfishCipher == nil {
-		return nil, fmt.Errorf("Blowfish cipher not initialized")
+// AppendEncryptBlowfish appends data, zero padded to the block size of the login cipher and encrypted, to dst
+func (ce *CryptoEngine) AppendEncryptBlowfish(dst, data []byte) ([]byte, error) {
+	blockSize := 1
+	if blocks, ok := ce.Cipher(PhaseLogin).(BlockCipher); ok && blocks.Block() != nil {
+		blockSize = blocks.Block().BlockSize()
 	}
 
 	// Pad data to block size
-	blockSize := ce.blowfishCipher.BlockSize()
-	padded := make([]byte, ((len(data)+blockSize-1)/blockSize)*blockSize)
-	copy(padded, data)
-
-	// Encrypt in blocks
-	encrypted := make([]byte, len(padded))
-	for i := 0; i < len(padded); i += blockSize {
-		ce.blowfishCipher.Encrypt(encrypted[i:i+blockSize], padded[i:i+blockSize])
+	offset := len(dst)
+	size := ((len(data) + blockSize - 1) / blockSize) * blockSize
+	dst = slices.Grow(dst, size)[:offset+size]
+	copy(dst[offset:], data)
+	clear(dst[offset+len(data):])
+
+	// Encrypt in blocks, in place
+	if err := ce.Encode(PhaseLogin, dst[offset:]); err != nil {
+		return nil, err
 	}
-
-	return encrypted, nil
+	return dst, nil
 }
 
 // DecryptBlowfish decrypts data using Blowfish
 func (ce *CryptoEngine) DecryptBlowfish(data []byte) ([]byte, error) {
-	ce.mu.RLock()
-	defer ce.mu.RUnlock()
-
-	if ce.blowfishCipher == nil {
-		return nil, fmt.Errorf("Blowfish cipher not initialized")
-	}
-
-	blockSize := ce.blowfishCipher.BlockSize()
-	if len(data)%blockSize != 0 {
-		return nil, fmt.Errorf("data length must be multiple of block size")
-	}
-
-	// Decrypt in blocks
 	decrypted := make([]byte, len(data))
-	for i := 0; i < len(data); i += blockSize {
-		ce.blowfishCipher.Decrypt(decrypted[i:i+blockSize], data[i:i+blockSize])
-	}
+	copy(decrypted, data)
 
+	if err := ce.DecryptBlowfishInPlace(decrypted); err != nil {
+		return nil, err
+	}
 	return decrypted, nil
 }
 
-// EncryptXOR encrypts data using XOR
-func (ce *CryptoEngine) EncryptXOR(data []byte) ([]byte, error) {
-	ce.mu.RLock()
-	defer ce.mu.RUnlock()
-
-	if ce.xorCipher == nil {
-		return nil, fmt.Errorf("XOR cipher not initialized")
-	}
+// DecryptBlowfishInPlace decrypts data using Blowfish, overwriting it
+func (ce *CryptoEngine) DecryptBlowfishInPlace(data []byte) error {
+	return ce.Decode(PhaseLogin, data)
+}
 
+// EncryptXOR encrypts data using XOR and advances the output key
+func (ce *CryptoEngine) EncryptXOR(data []byte) ([]byte, error) {
 	encrypted := make([]byte, len(data))
 	copy(encrypted, data)
-	
-	// Make a copy of the output key for encryption
-	key := make([]byte, len(ce.xorCipher.OutputKey))
-	copy(key, ce.xorCipher.OutputKey)
-	
-	xor.Encrypt(encrypted, key)
+
+	if err := ce.EncryptXORInPlace(encrypted); err != nil {
+		return nil, err
+	}
 	return encrypted, nil
 }
 
-// DecryptXOR decrypts data using XOR
-func (ce *CryptoEngine) DecryptXOR(data []byte) ([]byte, error) {
-	ce.mu.RLock()
-	defer ce.mu.RUnlock()
-
-	if ce.xorCipher == nil {
-		return nil, fmt.Errorf("XOR cipher not initialized")
-	}
+// EncryptXORInPlace encrypts data using XOR, overwriting it, and advances the output key
+func (ce *CryptoEngine) EncryptXORInPlace(data []byte) error {
+	return ce.Encode(PhaseGame, data)
+}
 
+// DecryptXOR decrypts data using XOR and advances the input key
+func (ce *CryptoEngine) DecryptXOR(data []byte) ([]byte, error) {
 	decrypted := make([]byte, len(data))
 	copy(decrypted, data)
-	
-	// Make a copy of the input key for decryption
-	key := make([]byte, len(ce.xorCipher.InputKey))
-	copy(key, ce.xorCipher.InputKey)
-	
-	xor.Decrypt(decrypted, key)
+
+	if err := ce.DecryptXORInPlace(decrypted); err != nil {
+		return nil, err
+	}
 	return decrypted, nil
 }
+
+// DecryptXORInPlace decrypts data using XOR, overwriting it, and advances the input key
+func (ce *CryptoEngine) DecryptXORInPlace(data []byte) error {
+	return ce.Decode(PhaseGame, data)
+}
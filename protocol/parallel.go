@@ -0,0 +1,113 @@
+package protocol
+
+import (
+	"crypto/cipher"
+	"runtime"
+	"sync"
+)
+
+const (
+	// DefaultParallelThreshold is the smallest payload split across the workers, the server list bursts and
+	// the character infos going over it while the usual packets stay well under
+	DefaultParallelThreshold = 4096
+
+	// parallelMinChunk is the least a worker is given, below which handing the blocks over costs more than
+	// ciphering them
+	parallelMinChunk = 1024
+)
+
+// ParallelOptions describes how the Blowfish blocks of the large payloads are shared between workers.
+// The blocks are ciphered independently, so the payload is cut in chunks of whole blocks, the caller
+// ciphering the last one while the shared pool of workers takes the others.
+type ParallelOptions struct {
+	Enabled   bool `json:"enabled"`
+	Threshold int  `json:"threshold"` // Payloads smaller than this are ciphered by the caller alone
+	Workers   int  `json:"workers"`   // Most chunks a payload is cut in, runtime.GOMAXPROCS when zero
+}
+
+// withDefaults fills the zero values of the options
+func (o ParallelOptions) withDefaults() ParallelOptions {
+	if o.Threshold <= 0 {
+		o.Threshold = DefaultParallelThreshold
+	}
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	return o
+}
+
+// chunks returns in how many chunks a payload of size bytes is cut, 1 when it is ciphered by the caller alone
+func (o ParallelOptions) chunks(size int) int {
+	if !o.Enabled || size < o.Threshold {
+		return 1
+	}
+	return max(1, min(o.Workers, size/parallelMinChunk))
+}
+
+// blowfishJob is a chunk of a payload handed to the pool
+type blowfishJob struct {
+	block   cipher.Block
+	data    []byte
+	decrypt bool
+	done    *sync.WaitGroup
+}
+
+func (j blowfishJob) run() {
+	cryptBlocks(j.block, j.data, j.decrypt)
+	j.done.Done()
+}
+
+var (
+	blowfishPool     chan blowfishJob
+	blowfishPoolOnce sync.Once
+)
+
+// startBlowfishPool starts the workers shared by every engine the first time a payload is split, a
+// worker per processor
+func startBlowfishPool() chan blowfishJob {
+	blowfishPoolOnce.Do(func() {
+		workers := runtime.GOMAXPROCS(0)
+		blowfishPool = make(chan blowfishJob, workers)
+		for range workers {
+			go func() {
+				for job := range blowfishPool {
+					job.run()
+				}
+			}()
+		}
+	})
+	return blowfishPool
+}
+
+// cryptBlocks ciphers data in place, a block at a time
+func cryptBlocks(block cipher.Block, data []byte, decrypt bool) {
+	blockSize := block.BlockSize()
+	for i := 0; i < len(data); i += blockSize {
+		if decrypt {
+			block.Decrypt(data[i:i+blockSize], data[i:i+blockSize])
+		} else {
+			block.Encrypt(data[i:i+blockSize], data[i:i+blockSize])
+		}
+	}
+}
+
+// cryptBlocksParallel ciphers data in place across chunks of whole blocks. A chunk the pool has no room
+// for is ciphered by the caller, so that a burst of large payloads never waits on the workers.
+func cryptBlocksParallel(block cipher.Block, data []byte, decrypt bool, chunks int) {
+	chunkSize := blowfishPaddedSize((len(data) + chunks - 1) / chunks)
+	pool := startBlowfishPool()
+
+	var done sync.WaitGroup
+	for len(data) > chunkSize {
+		job := blowfishJob{block: block, data: data[:chunkSize], decrypt: decrypt, done: &done}
+		done.Add(1)
+		select {
+		case pool <- job:
+		default:
+			job.run()
+		}
+		data = data[chunkSize:]
+	}
+	cryptBlocks(block, data, decrypt)
+	done.Wait()
+}
@@ -0,0 +1,80 @@
+package protocol
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestParallelBlowfish(t *testing.T) {
+	source := rand.New(rand.NewSource(1))
+	options := ParallelOptions{Enabled: true, Threshold: 2048, Workers: 4}
+
+	tests := []struct {
+		name     string
+		size     int
+		parallel bool
+	}{
+		{"small", 120, false},
+		{"under the threshold", 2040, false},
+		{"threshold", 2048, true},
+		{"uneven chunks", 9000, true},
+		{"server list burst", 65536, true},
+	}
+
+	for _, tt := range tests {
+		sequential, parallel := newBenchmarkEngine(t), newBenchmarkEngine(t)
+		parallel.SetParallelBlowfish(options)
+		payload := make([]byte, tt.size)
+		source.Read(payload)
+
+		want, err := sequential.EncryptBlowfish(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := parallel.EncryptBlowfish(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: EncryptBlowfish() across the workers differs from the sequential one", tt.name)
+		}
+
+		if err := parallel.DecryptBlowfishInPlace(got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got[:tt.size], payload) {
+			t.Errorf("%s: DecryptBlowfishInPlace() across the workers doesn't give the payload back", tt.name)
+		}
+
+		wantParallel := int64(0)
+		if tt.parallel {
+			wantParallel = 2
+		}
+		if stats := parallel.Stats(); stats.Parallel != wantParallel || stats.Encryptions != 1 || stats.Decryptions != 1 {
+			t.Errorf("%s: Stats() = %+v, want %d parallel operations", tt.name, stats, wantParallel)
+		}
+	}
+}
+
+func TestParallelOptions(t *testing.T) {
+	engine := NewCryptoEngine()
+	if options := engine.ParallelBlowfish(); options.Enabled {
+		t.Errorf("ParallelBlowfish() = %+v, want it disabled by default", options)
+	}
+
+	engine.SetParallelBlowfish(ParallelOptions{Enabled: true})
+	options := engine.ParallelBlowfish()
+	if options.Threshold != DefaultParallelThreshold || options.Workers < 1 {
+		t.Errorf("ParallelBlowfish() = %+v, want the defaults filled", options)
+	}
+
+	options.Workers = 8
+	if chunks := options.chunks(DefaultParallelThreshold); chunks != DefaultParallelThreshold/parallelMinChunk {
+		t.Errorf("chunks() = %d, want no chunk under %d bytes", chunks, parallelMinChunk)
+	}
+	options.Enabled = false
+	if chunks := options.chunks(1 << 20); chunks != 1 {
+		t.Errorf("chunks() = %d when disabled, want 1", chunks)
+	}
+}
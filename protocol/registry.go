@@ -0,0 +1,111 @@
+package protocol
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+// PacketKey identifies a game packet by its opcode and, for extended packets, its sub-opcode
+type PacketKey struct {
+	Opcode    byte
+	SubOpcode uint16
+}
+
+// Key returns the key of a packet that has no sub-opcode
+func Key(opcode byte) PacketKey {
+	return PacketKey{Opcode: opcode}
+}
+
+// ExtendedKey returns the key of an extended packet
+func ExtendedKey(opcode byte, subOpcode uint16) PacketKey {
+	return PacketKey{Opcode: opcode, SubOpcode: subOpcode}
+}
+
+// String returns the key in hexadecimal form
+func (k PacketKey) String() string {
+	if k.SubOpcode != 0 {
+		return fmt.Sprintf("0x%02X:0x%04X", k.Opcode, k.SubOpcode)
+	}
+	return fmt.Sprintf("0x%02X", k.Opcode)
+}
+
+// Registry maps the packets of one protocol direction to their names
+type Registry struct {
+	protocol  opcodes.Protocol
+	direction opcodes.Direction
+	names     map[PacketKey]string
+	mu        sync.RWMutex
+}
+
+// NewRegistry creates an empty packet registry
+func NewRegistry(protocol opcodes.Protocol, direction opcodes.Direction) *Registry {
+	return &Registry{
+		protocol:  protocol,
+		direction: direction,
+		names:     make(map[PacketKey]string),
+	}
+}
+
+// NewGameRegistry creates a registry holding every known game packet of a direction
+func NewGameRegistry(direction opcodes.Direction) *Registry {
+	r := NewRegistry(opcodes.Game, direction)
+
+	for opcode, name := range opcodes.Names(opcodes.Game, direction) {
+		if !opcodes.IsExtended(opcodes.Game, direction, opcode) {
+			r.names[Key(opcode)] = name
+		}
+	}
+
+	extended := opcodes.GameServerExtended
+	if direction == opcodes.ClientToServer {
+		extended = opcodes.GameClientExtended
+	}
+	for subOpcode, name := range opcodes.ExtendedNames(direction) {
+		r.names[ExtendedKey(extended, subOpcode)] = name
+	}
+
+	return r
+}
+
+// Register adds a packet to the registry
+func (r *Registry) Register(key PacketKey, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key.SubOpcode != 0 && !r.IsExtended(key.Opcode) {
+		return fmt.Errorf("opcode 0x%02X does not take a sub-opcode", key.Opcode)
+	}
+	if existing, ok := r.names[key]; ok {
+		return fmt.Errorf("packet %s is already registered as %s", key, existing)
+	}
+
+	r.names[key] = name
+	return nil
+}
+
+// Lookup returns the name of a registered packet
+func (r *Registry) Lookup(key PacketKey) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.names[key]
+	return name, ok
+}
+
+// Name returns the name of a packet, for logging purposes
+func (r *Registry) Name(key PacketKey) string {
+	if name, ok := r.Lookup(key); ok {
+		return name
+	}
+	if r.IsExtended(key.Opcode) {
+		return fmt.Sprintf("UnknownEx(%s)", key)
+	}
+	return fmt.Sprintf("Unknown(%s)", key)
+}
+
+// IsExtended reports whether the opcode is followed by a sub-opcode in this direction
+func (r *Registry) IsExtended(opcode byte) bool {
+	return opcodes.IsExtended(r.protocol, r.direction, opcode)
+}
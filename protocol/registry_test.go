@@ -0,0 +1,112 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/frostwind/l2go/opcodes"
+)
+
+func TestGameRegistryKeys(t *testing.T) {
+	server := NewGameRegistry(opcodes.ServerToClient)
+
+	tests := []struct {
+		key  PacketKey
+		want string
+	}{
+		{Key(opcodes.GameServerCharList), "CharList"},
+		{ExtendedKey(opcodes.GameServerExtended, opcodes.GameServerExSendManorList), "ExSendManorList"},
+		{ExtendedKey(opcodes.GameServerExtended, 0x7fff), "UnknownEx(0xFE:0x7FFF)"},
+		{Key(0xfd), "Unknown(0xFD)"},
+	}
+
+	for _, tt := range tests {
+		if got := server.Name(tt.key); got != tt.want {
+			t.Errorf("Name(%s) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	if err := server.Register(ExtendedKey(opcodes.GameServerCharList, 1), "Bogus"); err == nil {
+		t.Error("Register accepted a sub-opcode on a regular opcode")
+	}
+	if err := server.Register(ExtendedKey(opcodes.GameServerExtended, opcodes.GameServerExSendManorList), "Dup"); err == nil {
+		t.Error("Register accepted a duplicate key")
+	}
+	if err := server.Register(ExtendedKey(opcodes.GameServerExtended, 0x42), "ExCustom"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if got := server.Name(ExtendedKey(opcodes.GameServerExtended, 0x42)); got != "ExCustom" {
+		t.Errorf("Name after Register = %q", got)
+	}
+}
+
+func TestGameProtocolExtendedFraming(t *testing.T) {
+	gp := NewGameProtocol()
+	crypto := NewCryptoEngine()
+
+	key := ExtendedKey(opcodes.GameClientExtended, opcodes.GameClientExRequestManorList)
+	raw, err := gp.EncodeKeyedPacket(key, []byte{0xaa}, crypto)
+	if err != nil {
+		t.Fatalf("EncodeKeyedPacket: %v", err)
+	}
+	if want := []byte{0xd0, 0x08, 0x00, 0xaa}; !bytes.Equal(raw, want) {
+		t.Fatalf("encoded = % x, want % x", raw, want)
+	}
+
+	if _, err := gp.EncodeKeyedPacket(ExtendedKey(opcodes.GameClientSay2, 1), nil, crypto); err == nil {
+		t.Error("EncodeKeyedPacket accepted a sub-opcode on a regular opcode")
+	}
+
+	tests := []struct {
+		name     string
+		raw      []byte
+		wantKey  PacketKey
+		wantData []byte
+		wantErr  bool
+	}{
+		{"regular", []byte{0x1f, 0x01}, Key(0x1f), []byte{0x01}, false},
+		{"extended", []byte{0xfe, 0x1b, 0x00, 0x05}, ExtendedKey(0xfe, 0x1b), []byte{0x05}, false},
+		{"extended without body", []byte{0xfe, 0x34, 0x12}, ExtendedKey(0xfe, 0x1234), nil, false},
+		{"client extended opcode is regular from the server", []byte{0xd0, 0x01}, Key(0xd0), []byte{0x01}, false},
+		{"truncated sub-opcode", []byte{0xfe, 0x1b}, PacketKey{}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, data, err := gp.DecodeKeyedPacket(tt.raw, crypto)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if key != tt.wantKey || !bytes.Equal(data, tt.wantData) {
+				t.Errorf("got %s % x, want %s % x", key, data, tt.wantKey, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestGameProtocolExtendedXOR(t *testing.T) {
+	key := []byte{0x94, 0x35, 0x00, 0x00, 0xa1, 0x6c, 0x54, 0x87}
+
+	sender := NewCryptoEngine()
+	receiver := NewCryptoEngine()
+	sender.InitializeXOR(key)
+	receiver.InitializeXOR(key)
+
+	gp := NewGameProtocol()
+	packet := ExtendedKey(opcodes.GameClientExtended, opcodes.GameClientExRequestManorList)
+
+	for i := 0; i < 3; i++ {
+		raw, err := gp.EncodeKeyedPacket(packet, []byte{byte(i)}, sender)
+		if err != nil {
+			t.Fatalf("EncodeKeyedPacket: %v", err)
+		}
+
+		decrypted, err := receiver.DecryptXOR(raw)
+		if err != nil {
+			t.Fatalf("DecryptXOR: %v", err)
+		}
+		if want := []byte{0xd0, 0x08, 0x00, byte(i)}; !bytes.Equal(decrypted, want) {
+			t.Errorf("packet %d = % x, want % x", i, decrypted, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package random abstracts the sources of randomness, so the session keys and
+// the random delays can be reproduced from a seed in deterministic tests
+package random
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+)
+
+// Source provides random bytes and numbers
+type Source interface {
+	// Read fills p with random bytes, it never fails short
+	Read(p []byte) (n int, err error)
+
+	// Int64N returns a number in [0, n), n being positive
+	Int64N(n int64) int64
+}
+
+// Crypto is the cryptographically secure source of the system, the one keys must come from
+func Crypto() Source {
+	return cryptoSource{}
+}
+
+type cryptoSource struct{}
+
+func (cryptoSource) Read(p []byte) (int, error) { return crand.Read(p) }
+
+func (cryptoSource) Int64N(n int64) int64 { return rand.Int64N(n) }
+
+// Seeded is a deterministic source, returning the same sequence for the same seed.
+// It is safe for concurrent use, the sequence then depending on the order of the calls.
+func Seeded(seed uint64) Source {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], seed)
+	chacha := rand.NewChaCha8(key)
+
+	return &seededSource{chacha: chacha, rand: rand.New(chacha)}
+}
+
+type seededSource struct {
+	chacha *rand.ChaCha8
+	rand   *rand.Rand
+	mu     sync.Mutex
+}
+
+func (s *seededSource) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chacha.Read(p)
+}
+
+func (s *seededSource) Int64N(n int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.Int64N(n)
+}
+
+type contextKey struct{}
+
+// WithContext returns a context carrying the source
+func WithContext(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, contextKey{}, source)
+}
+
+// FromContext returns the source of the context, Crypto if it carries none
+func FromContext(ctx context.Context) Source {
+	if source, ok := ctx.Value(contextKey{}).(Source); ok {
+		return source
+	}
+	return Crypto()
+}
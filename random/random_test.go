@@ -0,0 +1,39 @@
+package random
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSeeded(t *testing.T) {
+	sequence := func(source Source) ([]byte, []int64) {
+		key := make([]byte, 16)
+		source.Read(key)
+		var numbers []int64
+		for i := 0; i < 8; i++ {
+			numbers = append(numbers, source.Int64N(1000))
+		}
+		return key, numbers
+	}
+
+	key, numbers := sequence(Seeded(42))
+	sameKey, sameNumbers := sequence(Seeded(42))
+	otherKey, _ := sequence(Seeded(43))
+
+	if !bytes.Equal(key, sameKey) || len(numbers) != len(sameNumbers) {
+		t.Fatalf("the same seed gave %x and %x", key, sameKey)
+	}
+	for i := range numbers {
+		if numbers[i] != sameNumbers[i] || numbers[i] < 0 || numbers[i] >= 1000 {
+			t.Errorf("numbers = %v and %v", numbers, sameNumbers)
+		}
+	}
+	if bytes.Equal(key, otherKey) {
+		t.Error("different seeds gave the same key")
+	}
+
+	crypto := make([]byte, 16)
+	if n, err := Crypto().Read(crypto); n != 16 || err != nil || bytes.Equal(crypto, make([]byte, 16)) {
+		t.Errorf("Crypto().Read() = %d, %v", n, err)
+	}
+}
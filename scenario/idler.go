@@ -0,0 +1,111 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/random"
+)
+
+// Think-times of the idler when its scenario line doesn't give them
+const (
+	IdlerMinThink = 5 * time.Second
+	IdlerMaxThink = 15 * time.Second
+)
+
+// Idler is a player standing around, the cheapest realistic background traffic for the soak tests.
+// Every round it either sits down for a while before standing up, or plays an emote, then thinks
+// before the next one. Scenarios use it as the idler verb:
+//
+//	idler [rounds] [min think] [max think]
+//
+// It idles until the scenario is stopped when rounds is 0 or not given.
+type Idler struct {
+	Rounds   int
+	MinThink time.Duration
+	MaxThink time.Duration
+	Emotes   []int // Played at random, every emote when empty
+}
+
+func (i Idler) Name() string { return "idler" }
+
+// WithArgs returns the idler of a scenario line, the think-times defaulting to the ones of i
+func (i Idler) WithArgs(args []string) (CustomStep, error) {
+	if len(args) > 3 {
+		return nil, fmt.Errorf("expects [rounds] [min think] [max think], got %d arguments", len(args))
+	}
+
+	var err error
+	if len(args) > 0 {
+		if i.Rounds, err = intArg(args[0]); err != nil {
+			return nil, err
+		}
+		if i.Rounds < 0 {
+			return nil, fmt.Errorf("can't idle %d rounds", i.Rounds)
+		}
+	}
+	if len(args) > 1 {
+		if i.MinThink, err = time.ParseDuration(args[1]); err != nil {
+			return nil, err
+		}
+		i.MaxThink = i.MinThink
+	}
+	if len(args) > 2 {
+		if i.MaxThink, err = time.ParseDuration(args[2]); err != nil {
+			return nil, err
+		}
+	}
+	if i.MinThink < 0 || i.MaxThink < i.MinThink {
+		return nil, fmt.Errorf("can't think between %v and %v", i.MinThink, i.MaxThink)
+	}
+
+	return i, nil
+}
+
+// Execute idles the rounds, leaving the player standing
+func (i Idler) Execute(ctx context.Context, player Player) error {
+	emotes := i.Emotes
+	if len(emotes) == 0 {
+		for actionID := client.EmoteGreeting; actionID <= client.EmoteSorrow; actionID++ {
+			emotes = append(emotes, actionID)
+		}
+	}
+	rng, clk := random.FromContext(ctx), clock.FromContext(ctx)
+
+	for round := 0; i.Rounds == 0 || round < i.Rounds; round++ {
+		// One round out of three is spent sitting
+		if rng.Int64N(3) == 0 {
+			if err := player.Sit(); err != nil {
+				return err
+			}
+			if err := clock.Sleep(ctx, clk, i.think(rng)); err != nil {
+				return err
+			}
+			if err := player.Stand(); err != nil {
+				return err
+			}
+		} else if err := player.Emote(emotes[rng.Int64N(int64(len(emotes)))]); err != nil {
+			return err
+		}
+
+		if err := clock.Sleep(ctx, clk, i.think(rng)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// think returns a random think-time between the two of the idler
+func (i Idler) think(rng random.Source) time.Duration {
+	return i.MinThink + time.Duration(rng.Int64N(int64(i.MaxThink-i.MinThink)+1))
+}
+
+func init() {
+	if err := RegisterStep(Idler{MinThink: IdlerMinThink, MaxThink: IdlerMaxThink}); err != nil {
+		panic(err)
+	}
+}
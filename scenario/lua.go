@@ -0,0 +1,105 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/frostwind/l2go/scripting"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Script is a scenario written in Lua, for players deciding what to do as they go. Every verb is
+// a function of the player table, taking the arguments it takes in the scenario files, and
+// player.dialog() returns the dialog opened last, if any, with its npc and its links:
+//
+//	for i = 1, 3 do
+//		player.sit()
+//		player.wait("1s", "3s")
+//		player.stand()
+//	end
+//	local dialog = player.interact(268435457)
+//	if #dialog.links > 0 then player.choose(dialog.links[1].text) end
+//
+// A script is a custom step named after it, which scenario files use once registered.
+type Script struct {
+	program *scripting.Program
+}
+
+// ParseScript compiles a Lua scenario
+func ParseScript(name string, r io.Reader) (*Script, error) {
+	program, err := scripting.Compile(name, r)
+	if err != nil {
+		return nil, err
+	}
+	return &Script{program: program}, nil
+}
+
+func (s *Script) Name() string { return s.program.Name }
+
+func (s *Script) Execute(ctx context.Context, player Player) error { return s.Run(ctx, player) }
+
+// Run runs the script on the player, in a state of its own, until it ends, fails, or the context is done
+func (s *Script) Run(ctx context.Context, player Player) error {
+	L := scripting.NewState()
+	defer L.Close()
+
+	// The error of a verb is kept as is, rather than as the message of the Lua error it raises
+	var failed error
+	bindings := L.NewTable()
+	for _, name := range Verbs() {
+		step := name
+		bindings.RawSetString(step, L.NewFunction(func(L *lua.LState) int {
+			if err := runStep(ctx, player, Step{Verb: step, Args: scripting.Strings(L)}); err != nil {
+				failed = fmt.Errorf("%s: %w", s.Name(), err)
+				L.RaiseError("%v", err)
+			}
+			if step == "interact" || step == "choose" {
+				L.Push(dialogTable(L, player))
+				return 1
+			}
+			return 0
+		}))
+	}
+	bindings.RawSetString("dialog", L.NewFunction(func(L *lua.LState) int {
+		L.Push(dialogTable(L, player))
+		return 1
+	}))
+	L.SetGlobal("player", bindings)
+
+	err := s.program.Load(ctx, L)
+	if failed != nil && ctx.Err() == nil {
+		return failed
+	}
+	return err
+}
+
+// runStep runs a step of a script, checked as the steps of the scenario files are
+func runStep(ctx context.Context, player Player, step Step) error {
+	if err := step.Validate(); err != nil {
+		return err
+	}
+	verb, _ := lookup(step.Verb)
+	return verb.Run(ctx, player, step.Args)
+}
+
+// dialogTable returns the dialog opened last by the player as a Lua table, or nil
+func dialogTable(L *lua.LState, player Player) lua.LValue {
+	session := player.Sessions().GameSession()
+	if session == nil || session.Dialog == nil {
+		return lua.LNil
+	}
+
+	links := L.NewTable()
+	for _, link := range session.Dialog.Links {
+		entry := L.NewTable()
+		entry.RawSetString("text", lua.LString(link.Text))
+		entry.RawSetString("command", lua.LString(link.Command))
+		links.Append(entry)
+	}
+
+	dialog := L.NewTable()
+	dialog.RawSetString("npc", lua.LNumber(session.Dialog.NpcObjectID))
+	dialog.RawSetString("links", links)
+	return dialog
+}
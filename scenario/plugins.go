@@ -0,0 +1,65 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+)
+
+// CustomStep is a step written in Go, for what the verbs can't describe, such as buying from a shop
+// until the character is overweight. The player is usually a *client.Client, which the step may
+// assert to reach the rest of the client.
+type CustomStep interface {
+	Name() string
+	Execute(ctx context.Context, player Player) error
+}
+
+// ConfigurableStep is a custom step taking arguments in the scenario files. WithArgs returns the step
+// to execute for the arguments of a line, or why they are invalid, and is called when parsing too.
+type ConfigurableStep interface {
+	CustomStep
+	WithArgs(args []string) (CustomStep, error)
+}
+
+// StepFunc makes a custom step out of a function
+type StepFunc struct {
+	StepName string
+	Func     func(ctx context.Context, player Player) error
+}
+
+func (s StepFunc) Name() string { return s.StepName }
+
+func (s StepFunc) Execute(ctx context.Context, player Player) error { return s.Func(ctx, player) }
+
+// RegisterStep registers a custom step as a verb named after it, so scenarios can use it. A step
+// takes no argument, unless it is a ConfigurableStep.
+func RegisterStep(step CustomStep) error {
+	if step.Name() == "" {
+		return fmt.Errorf("%w: a custom step needs a name", ErrInvalidArgs)
+	}
+
+	configurable, ok := step.(ConfigurableStep)
+	if !ok {
+		return Register(step.Name(), Verb{
+			Usage: "no argument",
+			Run: func(ctx context.Context, player Player, args []string) error {
+				return step.Execute(ctx, player)
+			},
+		})
+	}
+
+	return Register(step.Name(), Verb{
+		Usage:   "arguments",
+		MaxArgs: -1,
+		Check: func(args []string) error {
+			_, err := configurable.WithArgs(args)
+			return err
+		},
+		Run: func(ctx context.Context, player Player, args []string) error {
+			step, err := configurable.WithArgs(args)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArgs, err)
+			}
+			return step.Execute(ctx, player)
+		},
+	})
+}
@@ -0,0 +1,171 @@
+// Package scenario describes what simulated players do once in the world, as
+// a list of verbs run one after the other, one verb per line:
+//
+//	sit
+//	wait 2s
+//	wait 1s 3s
+//	stand
+//	emote bow
+//	shortcut 0 1 action 0
+//	action 0
+//	interact 268435457
+//	choose Teleport
+//
+// Steps written in Go register with RegisterStep and are then used by their name like any verb.
+package scenario
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+)
+
+var (
+	ErrUnknownVerb = errors.New("unknown verb")
+	ErrInvalidArgs = errors.New("invalid arguments")
+	ErrVerbExists  = errors.New("verb already registered")
+)
+
+// Player is what a scenario drives, usually a *client.Client in the world
+type Player interface {
+	UseAction(actionID int, ctrl, shift bool) error
+	Sit() error
+	Stand() error
+	Run() error
+	Walk() error
+	Emote(actionID int) error
+	RegisterShortcut(shortcut client.Shortcut) error
+	DeleteShortcut(slot, page int) error
+	Target(objectID int) error
+	CancelTarget() error
+	Interact(objectID int) (*client.Dialog, error)
+	SendBypass(command string) error
+	ChooseDialogOption(index int) (*client.Dialog, error)
+	TeleportVia(npcObjectID int, destination string) error
+	InviteFriend(name string) error
+	WaitFriendInvite() (string, error)
+	AnswerFriendInvite(accept bool) error
+	RemoveFriend(name string) error
+	Whisper(name, text string) error
+	TalkQuest(npcObjectID, questID int) (*client.Dialog, error)
+	AcceptQuest(npcObjectID, questID int) error
+	AbortQuest(questID int) error
+	BuyItem(npcObjectID, itemID int, count uint64) error
+	SellItem(npcObjectID, itemID int, count uint64) error
+	Deposit(npcObjectID int, clan bool, itemID int, count uint64) error
+	Withdraw(npcObjectID int, clan bool, itemID int, count uint64) error
+	GiveItem(itemID int, count uint64) error
+	Enchant(itemID, level int) error
+	Sessions() *client.SessionManager
+}
+
+var _ Player = (*client.Client)(nil)
+
+// Step is a verb of a scenario along with its arguments
+type Step struct {
+	Verb string   `json:"verb"`
+	Args []string `json:"args"`
+}
+
+func (s Step) String() string {
+	return strings.Join(append([]string{s.Verb}, s.Args...), " ")
+}
+
+// Scenario is a named list of steps
+type Scenario struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+// Parse reads a scenario, one step per line. Empty lines and lines starting with '#' are ignored.
+func Parse(name string, r io.Reader) (*Scenario, error) {
+	scenario := &Scenario{Name: name}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		step := Step{Verb: strings.ToLower(fields[0]), Args: fields[1:]}
+		if err := step.Validate(); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+
+		scenario.Steps = append(scenario.Steps, step)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return scenario, nil
+}
+
+// Validate checks the verb exists and gets acceptable arguments
+func (s Step) Validate() error {
+	verb, ok := lookup(s.Verb)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownVerb, s.Verb)
+	}
+
+	if len(s.Args) < verb.MinArgs || (verb.MaxArgs >= 0 && len(s.Args) > verb.MaxArgs) {
+		return fmt.Errorf("%w: %s expects %s", ErrInvalidArgs, s.Verb, verb.Usage)
+	}
+	if verb.Check != nil {
+		if err := verb.Check(s.Args); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidArgs, s.Verb, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks every step of the scenario
+func (s *Scenario) Validate() error {
+	for i, step := range s.Steps {
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// StepHook is called after every step which ran successfully, numbered from 1
+type StepHook func(number int, step Step, took time.Duration)
+
+// Run runs the steps in order on the player, stopping at the first error or when the context is done
+func (s *Scenario) Run(ctx context.Context, player Player) error {
+	return s.RunSteps(ctx, player, nil)
+}
+
+// RunSteps runs the scenario like Run, calling the hook, if any, after every step
+func (s *Scenario) RunSteps(ctx context.Context, player Player, onStep StepHook) error {
+	for i, step := range s.Steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		verb, ok := lookup(step.Verb)
+		if !ok {
+			return fmt.Errorf("step %d: %w: %s", i+1, ErrUnknownVerb, step.Verb)
+		}
+
+		started := time.Now()
+		if err := verb.Run(ctx, player, step.Args); err != nil {
+			return fmt.Errorf("step %d (%s): %w", i+1, step, err)
+		}
+		if onStep != nil {
+			onStep(i+1, step, time.Since(started))
+		}
+	}
+
+	return nil
+}
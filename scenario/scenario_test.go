@@ -0,0 +1,546 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/gameserver/behavior"
+	"github.com/frostwind/l2go/random"
+	"github.com/frostwind/l2go/scripting"
+)
+
+// recorder is a player writing down what it is asked to do
+type recorder struct {
+	calls    []string
+	sessions *client.SessionManager
+}
+
+func newRecorder() *recorder {
+	sessions := client.NewSessionManager()
+	sessions.SetGameSession(&client.GameSession{GameState: &client.GameState{}})
+	return &recorder{sessions: sessions}
+}
+
+func (r *recorder) record(format string, args ...interface{}) error {
+	r.calls = append(r.calls, fmt.Sprintf(format, args...))
+	return nil
+}
+
+func (r *recorder) UseAction(actionID int, ctrl, shift bool) error {
+	return r.record("action %d %v %v", actionID, ctrl, shift)
+}
+func (r *recorder) Sit() error   { return r.record("sit") }
+func (r *recorder) Stand() error { return r.record("stand") }
+func (r *recorder) Run() error   { return r.record("run") }
+func (r *recorder) Walk() error  { return r.record("walk") }
+func (r *recorder) Emote(actionID int) error {
+	return r.record("emote %d", actionID)
+}
+func (r *recorder) RegisterShortcut(shortcut client.Shortcut) error {
+	return r.record("shortcut %+v", shortcut)
+}
+func (r *recorder) DeleteShortcut(slot, page int) error {
+	return r.record("unshortcut %d %d", slot, page)
+}
+func (r *recorder) Target(objectID int) error { return r.record("target %d", objectID) }
+func (r *recorder) CancelTarget() error       { return r.record("untarget") }
+func (r *recorder) Interact(objectID int) (*client.Dialog, error) {
+	dialog := &client.Dialog{NpcObjectID: objectID, Links: []client.DialogLink{
+		{Text: "Teleport", Command: "npc_1_Chat 1"},
+		{Text: "Shop", Command: "npc_1_Buy"},
+	}}
+	r.sessions.GameSession().Dialog = dialog
+	return dialog, r.record("interact %d", objectID)
+}
+func (r *recorder) SendBypass(command string) error { return r.record("bypass %s", command) }
+func (r *recorder) ChooseDialogOption(index int) (*client.Dialog, error) {
+	return nil, r.record("choose %d", index)
+}
+func (r *recorder) TeleportVia(npcObjectID int, destination string) error {
+	return r.record("teleport %d %s", npcObjectID, destination)
+}
+func (r *recorder) InviteFriend(name string) error { return r.record("befriend %s", name) }
+func (r *recorder) WaitFriendInvite() (string, error) {
+	return "Inviter", r.record("wait invite")
+}
+func (r *recorder) AnswerFriendInvite(accept bool) error { return r.record("answer %v", accept) }
+func (r *recorder) RemoveFriend(name string) error       { return r.record("unfriend %s", name) }
+func (r *recorder) Whisper(name, text string) error {
+	return r.record("whisper %s %s", name, text)
+}
+func (r *recorder) TalkQuest(npcObjectID, questID int) (*client.Dialog, error) {
+	return &client.Dialog{NpcObjectID: npcObjectID}, r.record("quest %d %d", npcObjectID, questID)
+}
+func (r *recorder) AcceptQuest(npcObjectID, questID int) error {
+	return r.record("accept quest %d %d", npcObjectID, questID)
+}
+func (r *recorder) AbortQuest(questID int) error { return r.record("abandon %d", questID) }
+func (r *recorder) BuyItem(npcObjectID, itemID int, count uint64) error {
+	return r.record("buy %d %d %d", npcObjectID, itemID, count)
+}
+func (r *recorder) SellItem(npcObjectID, itemID int, count uint64) error {
+	return r.record("sell %d %d %d", npcObjectID, itemID, count)
+}
+func (r *recorder) Deposit(npcObjectID int, clan bool, itemID int, count uint64) error {
+	return r.record("deposit %d %v %d %d", npcObjectID, clan, itemID, count)
+}
+func (r *recorder) Withdraw(npcObjectID int, clan bool, itemID int, count uint64) error {
+	return r.record("withdraw %d %v %d %d", npcObjectID, clan, itemID, count)
+}
+func (r *recorder) GiveItem(itemID int, count uint64) error {
+	return r.record("give_item %d %d", itemID, count)
+}
+func (r *recorder) Enchant(itemID, level int) error {
+	return r.record("enchant %d %d", itemID, level)
+}
+func (r *recorder) Sessions() *client.SessionManager { return r.sessions }
+
+func TestParseAndRun(t *testing.T) {
+	source := `# Idle player
+sit
+wait 1ms
+Stand
+
+walk
+run
+emote bow
+emote 13
+action 1000 ctrl shift
+shortcut 1 2 skill 56 3
+unshortcut 1 2
+target 42
+untarget
+interact 7
+choose 1
+choose teleport
+bypass npc_7_Quest 255
+teleport 7 Gludio Castle Town
+befriend Alter
+acceptfriend
+whisper Alter see you in Gludio
+unfriend Alter
+quest 7 255
+acceptquest 7 256
+abandon 255
+buy 8 1835 20
+sell 8 1835 5
+deposit 9 57 1000
+withdraw 9 1835 5 clan
+give_item 2369
+give_item 1835 500
+enchant 2369 16
+`
+
+	scenario, err := Parse("idle", strings.NewReader(source))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	player := newRecorder()
+	var completed []int
+	onStep := func(number int, step Step, took time.Duration) {
+		if step.String() != scenario.Steps[number-1].String() || took < 0 {
+			t.Errorf("step %d reported as %q", number, step)
+		}
+		completed = append(completed, number)
+	}
+	if err := scenario.RunSteps(context.Background(), player, onStep); err != nil {
+		t.Fatalf("RunSteps() error = %v", err)
+	}
+	if len(completed) != len(scenario.Steps) || completed[len(completed)-1] != len(scenario.Steps) {
+		t.Errorf("completed steps = %v, want all %d", completed, len(scenario.Steps))
+	}
+
+	want := []string{
+		"sit",
+		"stand",
+		"walk",
+		"run",
+		"emote 7",
+		"emote 13",
+		"action 1000 true true",
+		fmt.Sprintf("shortcut %+v", client.Shortcut{Type: client.ShortcutSkill, Page: 1, Slot: 2, ID: 56, Level: 3}),
+		"unshortcut 2 1",
+		"target 42",
+		"untarget",
+		"interact 7",
+		"choose 1",
+		"choose 0",
+		"bypass npc_7_Quest 255",
+		"teleport 7 Gludio Castle Town",
+		"befriend Alter",
+		"wait invite",
+		"answer true",
+		"whisper Alter see you in Gludio",
+		"unfriend Alter",
+		"quest 7 255",
+		"accept quest 7 256",
+		"abandon 255",
+		"buy 8 1835 20",
+		"sell 8 1835 5",
+		"deposit 9 false 57 1000",
+		"withdraw 9 true 1835 5",
+		"give_item 2369 1",
+		"give_item 1835 500",
+		"enchant 2369 16",
+	}
+	if !reflect.DeepEqual(player.calls, want) {
+		t.Errorf("calls = %q, want %q", player.calls, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   error
+	}{
+		{"unknown verb", "dance", ErrUnknownVerb},
+		{"missing argument", "wait", ErrInvalidArgs},
+		{"too many arguments", "sit now", ErrInvalidArgs},
+		{"missing destination", "teleport 7", ErrInvalidArgs},
+		{"missing count", "buy 8 1835", ErrInvalidArgs},
+		{"unknown emote", "emote wave", ErrInvalidArgs},
+		{"level up emote", "emote 15", ErrInvalidArgs},
+		{"negative idler rounds", "idler -1", ErrInvalidArgs},
+		{"idler think-times reversed", "idler 3 2s 1s", ErrInvalidArgs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse("bad", strings.NewReader(tt.source)); !errors.Is(err, tt.want) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		step Step
+		want error
+	}{
+		{"bad duration", Step{Verb: "wait", Args: []string{"soon"}}, ErrInvalidArgs},
+		{"maximum too short", Step{Verb: "wait", Args: []string{"2s", "1s"}}, ErrInvalidArgs},
+		{"bad modifier", Step{Verb: "action", Args: []string{"0", "alt"}}, ErrInvalidArgs},
+		{"bad shortcut type", Step{Verb: "shortcut", Args: []string{"0", "0", "spell", "1"}}, ErrInvalidArgs},
+		{"unknown verb", Step{Verb: "dance"}, ErrUnknownVerb},
+		{"no dialog", Step{Verb: "choose", Args: []string{"Teleport"}}, client.ErrNoDialog},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario := &Scenario{Name: tt.name, Steps: []Step{tt.step}}
+			if err := scenario.Run(context.Background(), newRecorder()); !errors.Is(err, tt.want) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	scenario := &Scenario{Steps: []Step{{Verb: "wait", Args: []string{"1h"}}}}
+	if err := scenario.Run(ctx, newRecorder()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRunThinkTime(t *testing.T) {
+	start := time.Now()
+	fake := clock.NewFake(start)
+	ctx := clock.WithContext(random.WithContext(context.Background(), random.Seeded(7)), fake)
+
+	want := time.Second + time.Duration(random.Seeded(7).Int64N(int64(2*time.Second)+1))
+
+	done := make(chan error)
+	scenario := &Scenario{Steps: []Step{{Verb: "wait", Args: []string{"1s", "3s"}}}}
+	go func() {
+		done <- scenario.Run(ctx, newRecorder())
+	}()
+
+	if err := fake.WaitForTimers(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	fake.Advance(want - time.Nanosecond)
+	select {
+	case err := <-done:
+		t.Fatalf("Run() returned %v before the think-time", err)
+	default:
+	}
+
+	fake.Advance(time.Nanosecond)
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+}
+
+// pacer is a player writing down when it sits
+type pacer struct {
+	*recorder
+	clock   clock.Clock
+	actions []time.Time
+}
+
+func (p *pacer) Sit() error {
+	p.actions = append(p.actions, p.clock.Now())
+	return nil
+}
+
+func TestThinkTimeLooksHuman(t *testing.T) {
+	tests := []struct {
+		name  string
+		wait  []string
+		human bool
+	}{
+		{"fixed think-time", []string{"2s"}, false},
+		{"random think-time", []string{"1s", "3s"}, true},
+	}
+
+	for _, tt := range tests {
+		fake := clock.NewFake(time.Now())
+		ctx := clock.WithContext(random.WithContext(context.Background(), random.Seeded(7)), fake)
+
+		scenario := &Scenario{}
+		for range 32 {
+			scenario.Steps = append(scenario.Steps, Step{Verb: "sit"}, Step{Verb: "wait", Args: tt.wait})
+		}
+		player := &pacer{recorder: newRecorder(), clock: fake}
+		done := make(chan error)
+		go func() {
+			done <- scenario.Run(ctx, player)
+		}()
+
+		// The clock moves by the think-times the scenario draws
+		source := random.Seeded(7)
+		for range 32 {
+			if err := fake.WaitForTimers(context.Background(), 1); err != nil {
+				t.Fatal(err)
+			}
+			think, _ := time.ParseDuration(tt.wait[0])
+			if len(tt.wait) == 2 {
+				max, _ := time.ParseDuration(tt.wait[1])
+				think += time.Duration(source.Int64N(int64(max-think) + 1))
+			}
+			fake.Advance(think)
+		}
+		if err := <-done; err != nil {
+			t.Fatalf("%s: Run() error = %v", tt.name, err)
+		}
+
+		// The bot detection of the game server tells the think-times apart
+		score := behavior.TimingRegularity{}.Score(behavior.History{Actions: player.actions})
+		if human := score == 0; human != tt.human {
+			t.Errorf("%s: the timing of the actions scored %.2f", tt.name, score)
+		}
+	}
+}
+
+func TestIdler(t *testing.T) {
+	ctx := random.WithContext(context.Background(), random.Seeded(7))
+	scenario, err := Parse("idler", strings.NewReader("idler 30 0s\nemote dance"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	player := newRecorder()
+	if err := scenario.Run(ctx, player); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Every round sits down then stands up, or plays an emote
+	var rounds, sat int
+	for i := 0; i < len(player.calls); i++ {
+		call := player.calls[i]
+		emote, isEmote := strings.CutPrefix(call, "emote ")
+		actionID, _ := strconv.Atoi(emote)
+		switch {
+		case call == "sit" && i+1 < len(player.calls) && player.calls[i+1] == "stand":
+			sat++
+			i++
+		case isEmote && actionID >= client.EmoteGreeting && actionID <= client.EmoteSorrow:
+		default:
+			t.Fatalf("round %d: unexpected call %q in %q", rounds+1, call, player.calls)
+		}
+		rounds++
+	}
+	if rounds != 31 || sat == 0 || sat == 30 {
+		t.Errorf("%d rounds, %d sitting, want 30 rounds mixing both then the dance", rounds-1, sat)
+	}
+	if last := player.calls[len(player.calls)-1]; last != fmt.Sprintf("emote %d", client.EmoteDance) {
+		t.Errorf("the step after the idler played %q", last)
+	}
+
+	// Without rounds, the idler runs until the scenario is stopped
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	forever := &Scenario{Steps: []Step{{Verb: "idler", Args: []string{"0", "1ms"}}}}
+	if err := forever.Run(ctx, newRecorder()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register("SIT", Verb{}); !errors.Is(err, ErrVerbExists) {
+		t.Fatalf("Register() error = %v, want %v", err, ErrVerbExists)
+	}
+}
+
+// restockStep opens the buy list count times, standing for a custom step of a user
+type restockStep struct {
+	count int
+}
+
+func (s restockStep) Name() string { return "restock" }
+
+func (s restockStep) Execute(ctx context.Context, player Player) error {
+	for i := 0; i < s.count; i++ {
+		if err := player.SendBypass("npc_1_Buy"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s restockStep) WithArgs(args []string) (CustomStep, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expects a count")
+	}
+	var count int
+	if _, err := fmt.Sscan(args[0], &count); err != nil || count <= 0 {
+		return nil, fmt.Errorf("invalid count: %s", args[0])
+	}
+	return restockStep{count: count}, nil
+}
+
+func TestRegisterStep(t *testing.T) {
+	if err := RegisterStep(restockStep{}); err != nil {
+		t.Fatalf("RegisterStep() error = %v", err)
+	}
+	if err := RegisterStep(StepFunc{StepName: "hail", Func: func(ctx context.Context, player Player) error {
+		return player.Whisper("Friend", "hail")
+	}}); err != nil {
+		t.Fatalf("RegisterStep() error = %v", err)
+	}
+
+	if err := RegisterStep(restockStep{}); !errors.Is(err, ErrVerbExists) {
+		t.Errorf("RegisterStep() twice error = %v, want %v", err, ErrVerbExists)
+	}
+	if err := RegisterStep(StepFunc{}); !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("RegisterStep() without a name error = %v, want %v", err, ErrInvalidArgs)
+	}
+
+	tests := []struct {
+		source string
+		want   []string
+		err    error
+	}{
+		{source: "restock 2\nhail", want: []string{"bypass npc_1_Buy", "bypass npc_1_Buy", "whisper Friend hail"}},
+		{source: "RESTOCK 1", want: []string{"bypass npc_1_Buy"}},
+		{source: "restock", err: ErrInvalidArgs},
+		{source: "restock none", err: ErrInvalidArgs},
+		{source: "hail Friend", err: ErrInvalidArgs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			scenario, err := Parse("custom", strings.NewReader(tt.source))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			player := newRecorder()
+			if err := scenario.Run(context.Background(), player); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !reflect.DeepEqual(player.calls, tt.want) {
+				t.Errorf("calls = %v, want %v", player.calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+		err    error
+	}{
+		{
+			name:   "verbs",
+			source: "for i = 1, 2 do player.sit() player.stand() end\nplayer.target(42)",
+			want:   []string{"sit", "stand", "sit", "stand", "target 42"},
+		},
+		{
+			name:   "dialog",
+			source: "local dialog = player.interact(7)\nplayer.bypass(dialog.links[2].command)\nplayer.whisper('Friend', #player.dialog().links)",
+			want:   []string{"interact 7", "bypass npc_1_Buy", "whisper Friend 2"},
+		},
+		{
+			name:   "invalid arguments",
+			source: "player.sit()\nplayer.target()\nplayer.stand()",
+			want:   []string{"sit"},
+			err:    ErrInvalidArgs,
+		},
+		{
+			name:   "lua error",
+			source: "player.sit()\nerror('no more potions')",
+			want:   []string{"sit"},
+			err:    scripting.ErrScript,
+		},
+		{
+			name:   "sandbox",
+			source: "dofile('/etc/passwd')",
+			err:    scripting.ErrScript,
+		},
+		{
+			name:   "no os library",
+			source: "os.exit(1)",
+			err:    scripting.ErrScript,
+		},
+		{
+			name:   "cancelled",
+			source: "while true do end",
+			err:    context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, err := ParseScript(tt.name+".lua", strings.NewReader(tt.source))
+			if err != nil {
+				t.Fatalf("ParseScript() error = %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			player := newRecorder()
+			if err := script.Run(ctx, player); !errors.Is(err, tt.err) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(player.calls, tt.want) {
+				t.Errorf("calls = %v, want %v", player.calls, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseScript("broken.lua", strings.NewReader("player.sit(")); !errors.Is(err, scripting.ErrScript) {
+		t.Errorf("ParseScript() error = %v, want %v", err, scripting.ErrScript)
+	}
+}
@@ -0,0 +1,411 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/clock"
+	"github.com/frostwind/l2go/random"
+)
+
+// Verb is something a scenario step can do
+type Verb struct {
+	Usage   string // Arguments, as shown in the errors
+	MinArgs int
+	MaxArgs int                       // -1 for no limit
+	Check   func(args []string) error // Checks the arguments further when the scenario is parsed, optional
+	Run     func(ctx context.Context, player Player, args []string) error
+}
+
+var (
+	verbs   = make(map[string]Verb)
+	verbsMu sync.RWMutex
+)
+
+// Register adds a verb, so scenarios can use it
+func Register(name string, verb Verb) error {
+	verbsMu.Lock()
+	defer verbsMu.Unlock()
+
+	name = strings.ToLower(name)
+	if _, ok := verbs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrVerbExists, name)
+	}
+
+	verbs[name] = verb
+	return nil
+}
+
+// Verbs returns the sorted names of the registered verbs
+func Verbs() []string {
+	verbsMu.RLock()
+	defer verbsMu.RUnlock()
+
+	names := make([]string, 0, len(verbs))
+	for name := range verbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookup(name string) (Verb, bool) {
+	verbsMu.RLock()
+	defer verbsMu.RUnlock()
+
+	verb, ok := verbs[strings.ToLower(name)]
+	return verb, ok
+}
+
+func mustRegister(name string, verb Verb) {
+	if err := Register(name, verb); err != nil {
+		panic(err)
+	}
+}
+
+// simple builds a verb without arguments
+func simple(run func(Player) error) Verb {
+	return Verb{Run: func(ctx context.Context, player Player, args []string) error {
+		return run(player)
+	}}
+}
+
+var shortcutTypes = map[string]client.ShortcutType{
+	"item":   client.ShortcutItem,
+	"skill":  client.ShortcutSkill,
+	"action": client.ShortcutAction,
+	"macro":  client.ShortcutMacro,
+	"recipe": client.ShortcutRecipe,
+}
+
+var emotes = map[string]int{
+	"greeting": client.EmoteGreeting,
+	"victory":  client.EmoteVictory,
+	"advance":  client.EmoteAdvance,
+	"no":       client.EmoteNo,
+	"yes":      client.EmoteYes,
+	"bow":      client.EmoteBow,
+	"unaware":  client.EmoteUnaware,
+	"waiting":  client.EmoteWaiting,
+	"laugh":    client.EmoteLaugh,
+	"applaud":  client.EmoteApplaud,
+	"dance":    client.EmoteDance,
+	"sorrow":   client.EmoteSorrow,
+}
+
+func init() {
+	mustRegister("sit", simple(Player.Sit))
+	mustRegister("stand", simple(Player.Stand))
+	mustRegister("run", simple(Player.Run))
+	mustRegister("walk", simple(Player.Walk))
+
+	mustRegister("emote", Verb{Usage: "<emote id|emote name>", MinArgs: 1, MaxArgs: 1, Check: func(args []string) error {
+		_, err := emoteArg(args[0])
+		return err
+	}, Run: func(ctx context.Context, player Player, args []string) error {
+		actionID, err := emoteArg(args[0])
+		if err != nil {
+			return err
+		}
+		return player.Emote(actionID)
+	}})
+
+	// The think-time is random between the two durations when a maximum is given
+	mustRegister("wait", Verb{Usage: "<duration> [max]", MinArgs: 1, MaxArgs: 2, Run: func(ctx context.Context, player Player, args []string) error {
+		duration, err := time.ParseDuration(args[0])
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArgs, err)
+		}
+
+		if len(args) == 2 {
+			max, err := time.ParseDuration(args[1])
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArgs, err)
+			}
+			if max < duration {
+				return fmt.Errorf("%w: the maximum %v is shorter than %v", ErrInvalidArgs, max, duration)
+			}
+			duration += time.Duration(random.FromContext(ctx).Int64N(int64(max-duration) + 1))
+		}
+
+		return clock.Sleep(ctx, clock.FromContext(ctx), duration)
+	}})
+
+	mustRegister("action", Verb{Usage: "<id> [ctrl] [shift]", MinArgs: 1, MaxArgs: 3, Run: func(ctx context.Context, player Player, args []string) error {
+		id, err := intArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		var ctrl, shift bool
+		for _, modifier := range args[1:] {
+			switch strings.ToLower(modifier) {
+			case "ctrl":
+				ctrl = true
+			case "shift":
+				shift = true
+			default:
+				return fmt.Errorf("%w: unknown modifier %s", ErrInvalidArgs, modifier)
+			}
+		}
+
+		return player.UseAction(id, ctrl, shift)
+	}})
+
+	mustRegister("shortcut", Verb{Usage: "<page> <slot> <item|skill|action|macro|recipe> <id> [level]", MinArgs: 4, MaxArgs: 5, Run: func(ctx context.Context, player Player, args []string) error {
+		shortcutType, ok := shortcutTypes[strings.ToLower(args[2])]
+		if !ok {
+			return fmt.Errorf("%w: unknown shortcut type %s", ErrInvalidArgs, args[2])
+		}
+
+		values, err := intArgs(append([]string{args[0], args[1], args[3]}, args[4:]...))
+		if err != nil {
+			return err
+		}
+
+		shortcut := client.Shortcut{Type: shortcutType, Page: values[0], Slot: values[1], ID: values[2]}
+		if len(values) > 3 {
+			shortcut.Level = values[3]
+		}
+
+		return player.RegisterShortcut(shortcut)
+	}})
+
+	mustRegister("unshortcut", Verb{Usage: "<page> <slot>", MinArgs: 2, MaxArgs: 2, Run: func(ctx context.Context, player Player, args []string) error {
+		values, err := intArgs(args)
+		if err != nil {
+			return err
+		}
+
+		return player.DeleteShortcut(values[1], values[0])
+	}})
+}
+
+func init() {
+	mustRegister("target", Verb{Usage: "<object id>", MinArgs: 1, MaxArgs: 1, Run: func(ctx context.Context, player Player, args []string) error {
+		objectID, err := intArg(args[0])
+		if err != nil {
+			return err
+		}
+		return player.Target(objectID)
+	}})
+
+	mustRegister("untarget", simple(Player.CancelTarget))
+
+	mustRegister("interact", Verb{Usage: "<object id>", MinArgs: 1, MaxArgs: 1, Run: func(ctx context.Context, player Player, args []string) error {
+		objectID, err := intArg(args[0])
+		if err != nil {
+			return err
+		}
+		_, err = player.Interact(objectID)
+		return err
+	}})
+
+	mustRegister("bypass", Verb{Usage: "<command>", MinArgs: 1, MaxArgs: -1, Run: func(ctx context.Context, player Player, args []string) error {
+		return player.SendBypass(strings.Join(args, " "))
+	}})
+
+	// The option is given by its position in the dialog, or by its text
+	mustRegister("choose", Verb{Usage: "<option number|option text>", MinArgs: 1, MaxArgs: -1, Run: func(ctx context.Context, player Player, args []string) error {
+		index, err := strconv.Atoi(args[0])
+		if err != nil || len(args) > 1 {
+			dialog := player.Sessions().GameSession().Dialog
+			if dialog == nil {
+				return client.ErrNoDialog
+			}
+
+			index, err = dialog.Option(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = player.ChooseDialogOption(index)
+		return err
+	}})
+
+	mustRegister("teleport", Verb{Usage: "<gatekeeper object id> <destination>", MinArgs: 2, MaxArgs: -1, Run: func(ctx context.Context, player Player, args []string) error {
+		objectID, err := intArg(args[0])
+		if err != nil {
+			return err
+		}
+		return player.TeleportVia(objectID, strings.Join(args[1:], " "))
+	}})
+}
+
+func init() {
+	mustRegister("befriend", Verb{Usage: "<name>", MinArgs: 1, MaxArgs: 1, Run: func(ctx context.Context, player Player, args []string) error {
+		return player.InviteFriend(args[0])
+	}})
+
+	// The player waits for the next friend invite and accepts it
+	mustRegister("acceptfriend", simple(func(player Player) error {
+		if _, err := player.WaitFriendInvite(); err != nil {
+			return err
+		}
+		return player.AnswerFriendInvite(true)
+	}))
+
+	mustRegister("unfriend", Verb{Usage: "<name>", MinArgs: 1, MaxArgs: 1, Run: func(ctx context.Context, player Player, args []string) error {
+		return player.RemoveFriend(args[0])
+	}})
+
+	mustRegister("whisper", Verb{Usage: "<name> <text>", MinArgs: 2, MaxArgs: -1, Run: func(ctx context.Context, player Player, args []string) error {
+		return player.Whisper(args[0], strings.Join(args[1:], " "))
+	}})
+}
+
+func init() {
+	// The player asks an NPC about a quest, starting it or moving it on
+	mustRegister("quest", Verb{Usage: "<npc object id> <quest id>", MinArgs: 2, MaxArgs: 2, Run: func(ctx context.Context, player Player, args []string) error {
+		values, err := intArgs(args)
+		if err != nil {
+			return err
+		}
+		_, err = player.TalkQuest(values[0], values[1])
+		return err
+	}})
+
+	// Like quest, failing when the NPC refuses to give the quest
+	mustRegister("acceptquest", Verb{Usage: "<npc object id> <quest id>", MinArgs: 2, MaxArgs: 2, Run: func(ctx context.Context, player Player, args []string) error {
+		values, err := intArgs(args)
+		if err != nil {
+			return err
+		}
+		return player.AcceptQuest(values[0], values[1])
+	}})
+
+	mustRegister("abandon", Verb{Usage: "<quest id>", MinArgs: 1, MaxArgs: 1, Run: func(ctx context.Context, player Player, args []string) error {
+		questID, err := intArg(args[0])
+		if err != nil {
+			return err
+		}
+		return player.AbortQuest(questID)
+	}})
+}
+
+func init() {
+	mustRegister("buy", Verb{Usage: "<merchant object id> <item id> <count>", MinArgs: 3, MaxArgs: 3, Run: func(ctx context.Context, player Player, args []string) error {
+		values, err := tradeArgs(args)
+		if err != nil {
+			return err
+		}
+		return player.BuyItem(values[0], values[1], uint64(values[2]))
+	}})
+
+	mustRegister("sell", Verb{Usage: "<merchant object id> <item id> <count>", MinArgs: 3, MaxArgs: 3, Run: func(ctx context.Context, player Player, args []string) error {
+		values, err := tradeArgs(args)
+		if err != nil {
+			return err
+		}
+		return player.SellItem(values[0], values[1], uint64(values[2]))
+	}})
+}
+
+func init() {
+	mustRegister("deposit", Verb{Usage: "<keeper object id> <item id> <count> [clan]", MinArgs: 3, MaxArgs: 4, Run: func(ctx context.Context, player Player, args []string) error {
+		values, clan, err := warehouseArgs(args)
+		if err != nil {
+			return err
+		}
+		return player.Deposit(values[0], clan, values[1], uint64(values[2]))
+	}})
+
+	mustRegister("withdraw", Verb{Usage: "<keeper object id> <item id> <count> [clan]", MinArgs: 3, MaxArgs: 4, Run: func(ctx context.Context, player Player, args []string) error {
+		values, clan, err := warehouseArgs(args)
+		if err != nil {
+			return err
+		}
+		return player.Withdraw(values[0], clan, values[1], uint64(values[2]))
+	}})
+}
+
+func init() {
+	mustRegister("give_item", Verb{Usage: "<item id> [count]", MinArgs: 1, MaxArgs: 2, Run: func(ctx context.Context, player Player, args []string) error {
+		values, err := intArgs(args)
+		if err != nil {
+			return err
+		}
+		count := 1
+		if len(values) == 2 {
+			count = values[1]
+		}
+		if count <= 0 {
+			return fmt.Errorf("%w: can't give %d items", ErrInvalidArgs, count)
+		}
+		return player.GiveItem(values[0], uint64(count))
+	}})
+
+	mustRegister("enchant", Verb{Usage: "<item id> <level>", MinArgs: 2, MaxArgs: 2, Run: func(ctx context.Context, player Player, args []string) error {
+		values, err := intArgs(args)
+		if err != nil {
+			return err
+		}
+		return player.Enchant(values[0], values[1])
+	}})
+}
+
+// warehouseArgs parses the arguments of a trade, followed by "clan" for the clan warehouse
+func warehouseArgs(args []string) ([]int, bool, error) {
+	clan := len(args) == 4
+	if clan && !strings.EqualFold(args[3], "clan") {
+		return nil, false, fmt.Errorf("%w: %s isn't a warehouse", ErrInvalidArgs, args[3])
+	}
+
+	values, err := tradeArgs(args[:3])
+	return values, clan, err
+}
+
+// tradeArgs parses the merchant, the item and the count of a trade, at least one item being traded
+func tradeArgs(args []string) ([]int, error) {
+	values, err := intArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if values[2] <= 0 {
+		return nil, fmt.Errorf("%w: can't trade %d items", ErrInvalidArgs, values[2])
+	}
+	return values, nil
+}
+
+// emoteArg parses an emote, given by its id or by its name such as bow
+func emoteArg(arg string) (int, error) {
+	if actionID, ok := emotes[strings.ToLower(arg)]; ok {
+		return actionID, nil
+	}
+
+	actionID, err := intArg(arg)
+	if err != nil {
+		return 0, err
+	}
+	if actionID < client.EmoteGreeting || actionID > client.EmoteSorrow {
+		return 0, fmt.Errorf("%w: %d isn't an emote", ErrInvalidArgs, actionID)
+	}
+	return actionID, nil
+}
+
+func intArg(arg string) (int, error) {
+	value, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s isn't a number", ErrInvalidArgs, arg)
+	}
+	return value, nil
+}
+
+func intArgs(args []string) ([]int, error) {
+	values := make([]int, len(args))
+	for i, arg := range args {
+		value, err := intArg(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
@@ -28,7 +28,79 @@ CREATE TABLE IF NOT EXISTS characters (
     FOREIGN KEY (account_id) REFERENCES l2go.accounts(id)
 );
 
+-- Create character effects table, the buffs and debuffs kept while their player is away
+CREATE TABLE IF NOT EXISTS character_effects (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    account VARCHAR(50) NOT NULL,
+    skill_id INT NOT NULL,
+    level INT NOT NULL,
+    kind VARCHAR(10) NOT NULL,
+    stack_type VARCHAR(50) NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL,
+    remaining_ms BIGINT NOT NULL,
+    tick_ms BIGINT NOT NULL DEFAULT 0,
+    tick_hp INT NOT NULL DEFAULT 0
+);
+
+-- Create character states table, the progress and the location of the players saved while they play
+CREATE TABLE IF NOT EXISTS character_states (
+    account VARCHAR(50) PRIMARY KEY,
+    level INT NOT NULL DEFAULT 1,
+    experience BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    hp INT NOT NULL,
+    adena BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    x INT NOT NULL DEFAULT 0,
+    y INT NOT NULL DEFAULT 0,
+    z INT NOT NULL DEFAULT 0,
+    karma INT NOT NULL DEFAULT 0,
+    clan VARCHAR(50) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- Create character items table, the inventories of the players other than their adena
+CREATE TABLE IF NOT EXISTS character_items (
+    account VARCHAR(50) NOT NULL,
+    item_id INT NOT NULL,
+    count BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (account, item_id)
+);
+
+-- Create character paperdolls table, the items worn by the players, by paperdoll slot
+CREATE TABLE IF NOT EXISTS character_paperdolls (
+    account VARCHAR(50) NOT NULL,
+    slot INT NOT NULL,
+    item_id INT NOT NULL,
+    PRIMARY KEY (account, slot)
+);
+
+-- Create character friends table, a row for each side of a friendship
+CREATE TABLE IF NOT EXISTS character_friends (
+    account VARCHAR(50) NOT NULL,
+    friend VARCHAR(50) NOT NULL,
+    PRIMARY KEY (account, friend)
+);
+
+-- Create character quests table, where the players are in their quests
+CREATE TABLE IF NOT EXISTS character_quests (
+    account VARCHAR(50) NOT NULL,
+    quest_id INT NOT NULL,
+    cond INT NOT NULL DEFAULT 1,
+    progress INT NOT NULL DEFAULT 0,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (account, quest_id)
+);
+
+-- Create warehouse items table, the items kept by the private and the clan warehouses, the adena included
+CREATE TABLE IF NOT EXISTS warehouse_items (
+    clan BOOLEAN NOT NULL,
+    owner VARCHAR(50) NOT NULL,
+    item_id INT NOT NULL,
+    count BIGINT UNSIGNED NOT NULL,
+    PRIMARY KEY (clan, owner, item_id)
+);
+
 -- Add indexes for better performance
 CREATE INDEX idx_accounts_username ON l2go.accounts(username);
 CREATE INDEX idx_characters_account_id ON l2go.characters(account_id);
-CREATE INDEX idx_characters_name ON l2go.characters(name);
\ No newline at end of file
+CREATE INDEX idx_characters_name ON l2go.characters(name);
+CREATE INDEX idx_character_effects_account ON l2go.character_effects(account);
@@ -0,0 +1,111 @@
+// Package scripting runs the Lua scripts authoring scenarios and NPC dialogs, so content can be
+// written without recompiling. The scripts run in a sandbox: the base, string, table and math
+// libraries without the functions loading code, and no os or io library, so a script only
+// reaches what its bindings expose.
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+var ErrScript = errors.New("script error")
+
+// unsafeGlobals are the base functions reaching the file system or loading code
+var unsafeGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module"}
+
+// Program is a compiled script, run in as many states as needed
+type Program struct {
+	Name  string
+	proto *lua.FunctionProto
+}
+
+// Compile parses a script, so its syntax errors come up before it runs
+func Compile(name string, r io.Reader) (*Program, error) {
+	chunk, err := parse.Parse(r, name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScript, err)
+	}
+
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrScript, err)
+	}
+
+	return &Program{Name: name, proto: proto}, nil
+}
+
+// NewState returns a sandboxed state. The caller closes it.
+func NewState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	for _, name := range unsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	return L
+}
+
+// Load runs the top level of the program in the state, defining its globals
+func (p *Program) Load(ctx context.Context, L *lua.LState) error {
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	L.Push(L.NewFunctionFromProto(p.proto))
+	return Error(ctx, L.PCall(0, lua.MultRet, nil))
+}
+
+// Call calls a global function of the state, when the script defines it. The bool tells whether it does.
+func Call(ctx context.Context, L *lua.LState, name string, args ...lua.LValue) (bool, error) {
+	fn, ok := L.GetGlobal(name).(*lua.LFunction)
+	if !ok {
+		return false, nil
+	}
+
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	return true, Error(ctx, L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, args...))
+}
+
+// Error wraps the error of a script, unless it was stopped by its context
+func Error(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) {
+		return fmt.Errorf("%w: %s", ErrScript, apiErr.Object.String())
+	}
+	return fmt.Errorf("%w: %v", ErrScript, err)
+}
+
+// Strings returns the arguments of a Go function called by a script, as strings
+func Strings(L *lua.LState) []string {
+	args := make([]string, L.GetTop())
+	for i := range args {
+		args[i] = L.Get(i + 1).String()
+	}
+	return args
+}
@@ -0,0 +1,300 @@
+// Package seed populates the databases of the servers from a fixture file, so that a test world with
+// existing accounts and characters can be stood up before a load scenario needing them. The fixture
+// is written through the repositories of the servers, the same way they write their own data.
+package seed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/database"
+	gamerepository "github.com/frostwind/l2go/gameserver/repository"
+	"github.com/frostwind/l2go/loginserver/models"
+	loginrepository "github.com/frostwind/l2go/loginserver/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidFixture = errors.New("invalid fixture")
+
+// Fixture is the content of a fixture file
+type Fixture struct {
+	Accounts   []Account   `json:"accounts,omitempty"`
+	Characters []Character `json:"characters,omitempty"`
+	Clans      []Clan      `json:"clans,omitempty"`
+}
+
+// Account is an account of the login server, its password being hashed as the login server hashes it
+type Account struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	AccessLevel int8   `json:"accessLevel,omitempty"` // The player one when 0
+}
+
+// Character is the character of an account on the game server, which keeps one per account
+type Character struct {
+	Account   string         `json:"account"`
+	Level     int            `json:"level,omitempty"` // 1 when 0
+	Exp       uint64         `json:"exp,omitempty"`
+	HP        int            `json:"hp,omitempty"` // Capped to the maximum of the character when it logs in, full when 0
+	Adena     uint64         `json:"adena,omitempty"`
+	X         int32          `json:"x,omitempty"`
+	Y         int32          `json:"y,omitempty"`
+	Z         int32          `json:"z,omitempty"`
+	Items     map[int]uint64 `json:"items,omitempty"`     // Counts of the items of the inventory other than the adena, by item id
+	Warehouse map[int]uint64 `json:"warehouse,omitempty"` // Counts of the items of the private warehouse, by item id
+	Friends   []string       `json:"friends,omitempty"`   // Accounts the character is friend with, both ways
+	Karma     int            `json:"karma,omitempty"`
+	Paperdoll map[int]int    `json:"paperdoll,omitempty"` // Item ids worn, by paperdoll slot of the CharList packet
+}
+
+// Clan is a clan of characters of the fixture and its warehouse. The game server keeps the clan of
+// a character along with it.
+type Clan struct {
+	ID        string         `json:"id"`
+	Leader    string         `json:"leader"`            // Account of a character of the fixture, written along with the warehouse
+	Members   []string       `json:"members,omitempty"` // Accounts of the other characters of the clan
+	Warehouse map[int]uint64 `json:"warehouse,omitempty"`
+}
+
+// ReadFixture reads and validates a fixture file
+func ReadFixture(filename string) (Fixture, error) {
+	var fixture Fixture
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fixture, err
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return fixture, fmt.Errorf("%w: %s: %v", ErrInvalidFixture, filename, err)
+	}
+	return fixture, fixture.Validate()
+}
+
+// Validate checks that the accounts and the characters are named once, and that the characters and
+// the clans refer to characters of the fixture
+func (f Fixture) Validate() error {
+	accounts := make(map[string]bool)
+	for i, account := range f.Accounts {
+		key := strings.ToLower(account.Username)
+		switch {
+		case account.Username == "" || account.Password == "":
+			return fmt.Errorf("%w: account %d has no username or no password", ErrInvalidFixture, i+1)
+		case accounts[key]:
+			return fmt.Errorf("%w: account %s is listed twice", ErrInvalidFixture, account.Username)
+		}
+		accounts[key] = true
+	}
+
+	characters := make(map[string]bool)
+	for i, character := range f.Characters {
+		key := strings.ToLower(character.Account)
+		switch {
+		case character.Account == "":
+			return fmt.Errorf("%w: character %d has no account", ErrInvalidFixture, i+1)
+		case characters[key]:
+			return fmt.Errorf("%w: account %s has two characters", ErrInvalidFixture, character.Account)
+		case character.Level < 0 || character.HP < 0 || character.Karma < 0:
+			return fmt.Errorf("%w: character of %s has a negative level, hp or karma", ErrInvalidFixture, character.Account)
+		}
+		characters[key] = true
+	}
+	for _, character := range f.Characters {
+		for _, friend := range character.Friends {
+			if !characters[strings.ToLower(friend)] {
+				return fmt.Errorf("%w: friend %s of %s has no character", ErrInvalidFixture, friend, character.Account)
+			}
+		}
+	}
+
+	clans := make(map[string]bool)
+	members := make(map[string]string) // Clan of every character in one, by account
+	for i, clan := range f.Clans {
+		key := strings.ToLower(clan.ID)
+		switch {
+		case clan.ID == "":
+			return fmt.Errorf("%w: clan %d has no id", ErrInvalidFixture, i+1)
+		case clans[key]:
+			return fmt.Errorf("%w: clan %s is listed twice", ErrInvalidFixture, clan.ID)
+		case !characters[strings.ToLower(clan.Leader)]:
+			return fmt.Errorf("%w: leader %q of clan %s has no character", ErrInvalidFixture, clan.Leader, clan.ID)
+		}
+		clans[key] = true
+
+		for _, member := range append([]string{clan.Leader}, clan.Members...) {
+			account := strings.ToLower(member)
+			switch {
+			case !characters[account]:
+				return fmt.Errorf("%w: member %s of clan %s has no character", ErrInvalidFixture, member, clan.ID)
+			case members[account] != "":
+				return fmt.Errorf("%w: %s is a member of clans %s and %s", ErrInvalidFixture, member, members[account], clan.ID)
+			}
+			members[account] = clan.ID
+		}
+	}
+	return nil
+}
+
+// Repositories are where a fixture is written. Accounts is needed for the accounts of the fixture, the
+// others for its characters and its clans.
+type Repositories struct {
+	Accounts   loginrepository.AccountRepository
+	Characters gamerepository.CharacterRepository
+	Warehouses gamerepository.WarehouseRepository
+	Friends    gamerepository.FriendRepository
+}
+
+// Open connects to the databases of the login server and of a game server of a configuration, by id.
+// The in-memory storage is refused, since nothing seeded in it would outlive the process.
+func Open(cfg config.ConfigObject, gameServerID uint8) (Repositories, error) {
+	var repositories Repositories
+	index := -1
+	for i, gameServer := range cfg.GameServers {
+		if gameServer.ServerID(i) == gameServerID {
+			index = i
+		}
+	}
+	switch {
+	case index < 0:
+		return repositories, fmt.Errorf("no game server %d in the configuration", gameServerID)
+	case cfg.LoginServer.Database.IsMemory() || cfg.GameServers[index].Database.IsMemory():
+		return repositories, fmt.Errorf("the in-memory storage can't be seeded from another process")
+	}
+
+	loginDatabase, err := database.Open(cfg.LoginServer.Database)
+	if err != nil {
+		return repositories, err
+	}
+	gameDatabase, err := database.Open(cfg.GameServers[index].Database)
+	if err != nil {
+		loginDatabase.Close()
+		return repositories, err
+	}
+
+	repositories.Accounts = loginrepository.NewMySQLAccountRepository(loginDatabase)
+	repositories.Characters = gamerepository.NewMySQLCharacterRepository(gameDatabase)
+	repositories.Warehouses = gamerepository.NewMySQLWarehouseRepository(gameDatabase)
+	repositories.Friends = gamerepository.NewMySQLFriendRepository(gameDatabase)
+	return repositories, nil
+}
+
+// Close closes the databases of the repositories Open returned, the repositories of the game server
+// sharing theirs
+func (r Repositories) Close() error {
+	return errors.Join(r.Accounts.Close(), r.Characters.Close())
+}
+
+// Result counts what a seeding wrote
+type Result struct {
+	Accounts         int // Created
+	ExistingAccounts int // Left as they were, their password and access level included
+	Characters       int // Written, overwriting the ones the accounts had
+	Friendships      int
+	Clans            int
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%d accounts created, %d already existing, %d characters, %d friendships, %d clans",
+		r.Accounts, r.ExistingAccounts, r.Characters, r.Friendships, r.Clans)
+}
+
+// Seed writes a fixture through the repositories. The existing accounts are kept as they are, so that
+// a fixture can be seeded again, while the characters and the warehouses are overwritten.
+func Seed(fixture Fixture, repositories Repositories) (Result, error) {
+	var result Result
+	if err := fixture.Validate(); err != nil {
+		return result, err
+	}
+	if len(fixture.Accounts) > 0 && repositories.Accounts == nil {
+		return result, fmt.Errorf("%w: no account storage to seed the accounts in", ErrInvalidFixture)
+	}
+	if len(fixture.Characters) > 0 && (repositories.Characters == nil || repositories.Warehouses == nil || repositories.Friends == nil) {
+		return result, fmt.Errorf("%w: no game server storage to seed the characters in", ErrInvalidFixture)
+	}
+
+	for _, account := range fixture.Accounts {
+		created, err := seedAccount(repositories.Accounts, account)
+		if err != nil {
+			return result, fmt.Errorf("couldn't seed the account %s: %w", account.Username, err)
+		}
+		if created {
+			result.Accounts++
+		} else {
+			result.ExistingAccounts++
+		}
+	}
+
+	clans := make(map[string]string) // Clan of the characters in one, by account
+	for _, clan := range fixture.Clans {
+		for _, member := range append([]string{clan.Leader}, clan.Members...) {
+			clans[strings.ToLower(member)] = clan.ID
+		}
+	}
+
+	characters := make(map[string]gamerepository.Character)
+	for _, c := range fixture.Characters {
+		character := gamerepository.Character{
+			Account:   c.Account,
+			Level:     max(c.Level, 1),
+			Exp:       c.Exp,
+			HP:        c.HP,
+			Adena:     c.Adena,
+			X:         c.X,
+			Y:         c.Y,
+			Z:         c.Z,
+			Items:     c.Items,
+			Karma:     c.Karma,
+			Clan:      clans[strings.ToLower(c.Account)],
+			Paperdoll: c.Paperdoll,
+		}
+		if character.HP == 0 {
+			character.HP = math.MaxInt32 // Capped to the maximum of the character, which only the game server knows
+		}
+		characters[strings.ToLower(c.Account)] = character
+
+		// The private warehouse is written along with its character, which the warehouses can't do without
+		owner := gamerepository.WarehouseOwner{ID: c.Account}
+		if err := repositories.Warehouses.Move(character, owner, c.Warehouse); err != nil {
+			return result, fmt.Errorf("couldn't seed the character of %s: %w", c.Account, err)
+		}
+		result.Characters++
+	}
+
+	for _, character := range fixture.Characters {
+		for _, friend := range character.Friends {
+			if err := repositories.Friends.Add(character.Account, friend); err != nil {
+				return result, fmt.Errorf("couldn't make %s and %s friends: %w", character.Account, friend, err)
+			}
+			result.Friendships++
+		}
+	}
+
+	for _, clan := range fixture.Clans {
+		owner := gamerepository.WarehouseOwner{Clan: true, ID: clan.ID}
+		if err := repositories.Warehouses.Move(characters[strings.ToLower(clan.Leader)], owner, clan.Warehouse); err != nil {
+			return result, fmt.Errorf("couldn't seed the clan %s: %w", clan.ID, err)
+		}
+		result.Clans++
+	}
+	return result, nil
+}
+
+// seedAccount creates an account unless it exists, returning whether it did
+func seedAccount(accounts loginrepository.AccountRepository, account Account) (bool, error) {
+	_, err := accounts.FindByUsername(account.Username)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, loginrepository.ErrAccountNotFound) {
+		return false, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(account.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return false, err
+	}
+	return true, accounts.Create(&models.Account{Username: account.Username, Password: string(hashedPassword), AccessLevel: account.AccessLevel})
+}
@@ -0,0 +1,132 @@
+package seed
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gamerepository "github.com/frostwind/l2go/gameserver/repository"
+	loginrepository "github.com/frostwind/l2go/loginserver/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func memoryRepositories() Repositories {
+	characters := gamerepository.NewMemoryCharacterRepository()
+	return Repositories{
+		Accounts:   loginrepository.NewMemoryAccountRepository(),
+		Characters: characters,
+		Warehouses: gamerepository.NewMemoryWarehouseRepository(characters),
+		Friends:    gamerepository.NewMemoryFriendRepository(),
+	}
+}
+
+func TestSeed(t *testing.T) {
+	fixture := Fixture{
+		Accounts: []Account{{Username: "veteran", Password: "veteranpass", AccessLevel: 2}, {Username: "rookie", Password: "rookiepass"}},
+		Characters: []Character{
+			{Account: "veteran", Level: 20, Exp: 83000, HP: 300, Adena: 5000, X: -84318, Y: 244579, Z: -3730,
+				Items: map[int]uint64{1864: 3}, Warehouse: map[int]uint64{57: 100}, Friends: []string{"rookie"}},
+			{Account: "rookie"},
+		},
+		Clans: []Clan{{ID: "knights", Leader: "veteran", Members: []string{"rookie"}, Warehouse: map[int]uint64{1835: 50}}},
+	}
+	repositories := memoryRepositories()
+
+	result, err := Seed(fixture, repositories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Result{Accounts: 2, Characters: 2, Friendships: 1, Clans: 1}); result != want {
+		t.Errorf("Seed() = %+v, want %+v", result, want)
+	}
+
+	account, err := repositories.Accounts.FindByUsername("veteran")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.AccessLevel != 2 || bcrypt.CompareHashAndPassword([]byte(account.Password), []byte("veteranpass")) != nil {
+		t.Errorf("account = %+v, want access level 2 and the password hashed", account)
+	}
+
+	veteran, ok, err := repositories.Characters.Load("veteran")
+	if err != nil || !ok {
+		t.Fatalf("Load() = %v, %v", ok, err)
+	}
+	if veteran.Level != 20 || veteran.Exp != 83000 || veteran.HP != 300 || veteran.Adena != 5000 || veteran.Items[1864] != 3 || veteran.X != -84318 || veteran.Clan != "knights" {
+		t.Errorf("veteran = %+v", veteran)
+	}
+	if rookie, _, _ := repositories.Characters.Load("rookie"); rookie.Level != 1 || rookie.HP <= 0 || rookie.Clan != "knights" {
+		t.Errorf("rookie = %+v, want level 1 with full hp in the clan", rookie)
+	}
+
+	if friends, _ := repositories.Friends.List("rookie"); len(friends) != 1 || friends[0] != "veteran" {
+		t.Errorf("List(rookie) = %v, want the friendship both ways", friends)
+	}
+	warehouse, _ := repositories.Warehouses.Load(gamerepository.WarehouseOwner{ID: "veteran"})
+	clan, _ := repositories.Warehouses.Load(gamerepository.WarehouseOwner{Clan: true, ID: "knights"})
+	if warehouse[57] != 100 || clan[1835] != 50 {
+		t.Errorf("warehouses = %v and %v for the clan", warehouse, clan)
+	}
+
+	// Seeding again keeps the accounts, the passwords they may have changed included
+	fixture.Accounts[0].Password = "otherpass"
+	result, err = Seed(fixture, repositories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Accounts != 0 || result.ExistingAccounts != 2 || result.Characters != 2 {
+		t.Errorf("Seed() = %+v seeding again", result)
+	}
+	if account, _ := repositories.Accounts.FindByUsername("veteran"); bcrypt.CompareHashAndPassword([]byte(account.Password), []byte("veteranpass")) != nil {
+		t.Error("seeding again changed the password of an existing account")
+	}
+}
+
+func TestFixtureValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture Fixture
+		valid   bool
+	}{
+		{name: "empty", valid: true},
+		{name: "account without password", fixture: Fixture{Accounts: []Account{{Username: "a"}}}},
+		{name: "account listed twice", fixture: Fixture{Accounts: []Account{{Username: "a", Password: "p"}, {Username: "A", Password: "p"}}}},
+		{name: "two characters", fixture: Fixture{Characters: []Character{{Account: "a"}, {Account: "a"}}}},
+		{name: "negative level", fixture: Fixture{Characters: []Character{{Account: "a", Level: -1}}}},
+		{name: "unknown friend", fixture: Fixture{Characters: []Character{{Account: "a", Friends: []string{"b"}}}}},
+		{name: "clan without leader", fixture: Fixture{Characters: []Character{{Account: "a"}}, Clans: []Clan{{ID: "c"}}}},
+		{name: "clan", fixture: Fixture{Characters: []Character{{Account: "a"}, {Account: "b"}}, Clans: []Clan{{ID: "c", Leader: "A", Members: []string{"b"}}}}, valid: true},
+		{name: "unknown member", fixture: Fixture{Characters: []Character{{Account: "a"}}, Clans: []Clan{{ID: "c", Leader: "a", Members: []string{"b"}}}}},
+		{name: "member of two clans", fixture: Fixture{Characters: []Character{{Account: "a"}, {Account: "b"}}, Clans: []Clan{{ID: "c", Leader: "a"}, {ID: "d", Leader: "b", Members: []string{"A"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fixture.Validate()
+			if tt.valid != (err == nil) {
+				t.Fatalf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidFixture) {
+				t.Errorf("Validate() = %v, want it to wrap %v", err, ErrInvalidFixture)
+			}
+		})
+	}
+}
+
+func TestReadFixture(t *testing.T) {
+	fixture, err := ReadFixture("../examples/seed-fixture.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixture.Accounts) == 0 || len(fixture.Characters) == 0 || len(fixture.Clans) == 0 {
+		t.Errorf("ReadFixture() = %+v, want accounts, characters and clans", fixture)
+	}
+
+	filename := filepath.Join(t.TempDir(), "fixture.json")
+	if err := os.WriteFile(filename, []byte(`{"characters": [{"account": "a", "level": "max"}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFixture(filename); !errors.Is(err, ErrInvalidFixture) {
+		t.Errorf("ReadFixture() = %v, want %v", err, ErrInvalidFixture)
+	}
+}
@@ -0,0 +1,242 @@
+// Package templates holds the character creation templates: the starting
+// stats, items and spawn points of every race and class the players can pick.
+// It is shared by the game server and the toolkit, so both agree on the
+// characters a chronicle can create.
+package templates
+
+import (
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultChronicle is the chronicle spoken by the servers
+const DefaultChronicle = "c1"
+
+var (
+	ErrUnknownChronicle = errors.New("unknown chronicle")
+	ErrUnknownTemplate  = errors.New("unknown character template")
+	ErrInvalidTemplate  = errors.New("invalid character template")
+)
+
+//go:embed chronicles
+var bundled embed.FS
+
+// Item is given to the characters created from a template
+type Item struct {
+	ID    int `json:"id"`
+	Count int `json:"count"`
+}
+
+// Point is a location of the world
+type Point struct {
+	X int32 `json:"x"`
+	Y int32 `json:"y"`
+	Z int32 `json:"z"`
+}
+
+// Template describes the characters of a race and a base class
+type Template struct {
+	Race   int     `json:"race"`
+	Class  int     `json:"class"`
+	Name   string  `json:"name"`
+	STR    int     `json:"str"`
+	DEX    int     `json:"dex"`
+	CON    int     `json:"con"`
+	INT    int     `json:"int"`
+	WIT    int     `json:"wit"`
+	MEN    int     `json:"men"`
+	Items  []Item  `json:"items,omitempty"`
+	Spawns []Point `json:"spawns"` // The new characters appear at one of them
+}
+
+// Set holds the templates of a chronicle, in the order they are offered to the players
+type Set struct {
+	Chronicle string     `json:"chronicle"`
+	Templates []Template `json:"templates"`
+}
+
+// Find returns the template of a race and a class
+func (s *Set) Find(race, class int) (*Template, error) {
+	for i := range s.Templates {
+		if s.Templates[i].Race == race && s.Templates[i].Class == class {
+			return &s.Templates[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: race %d, class %d in %s", ErrUnknownTemplate, race, class, s.Chronicle)
+}
+
+// Validate checks every template has a spawn point and no race and class is listed twice
+func (s *Set) Validate() error {
+	seen := make(map[[2]int]bool)
+	for _, template := range s.Templates {
+		key := [2]int{template.Race, template.Class}
+		if seen[key] {
+			return fmt.Errorf("%w: race %d, class %d is listed twice", ErrInvalidTemplate, template.Race, template.Class)
+		}
+		seen[key] = true
+
+		if len(template.Spawns) == 0 {
+			return fmt.Errorf("%w: %s has no spawn point", ErrInvalidTemplate, template.Name)
+		}
+		for _, item := range template.Items {
+			if item.Count <= 0 {
+				return fmt.Errorf("%w: %s starts with %d of the item %d", ErrInvalidTemplate, template.Name, item.Count, item.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// Bundled returns the templates shipped for a chronicle
+func Bundled(chronicle string) (*Set, error) {
+	file, err := bundled.Open("chronicles/" + chronicle + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownChronicle, chronicle)
+	}
+	defer file.Close()
+
+	return ParseJSON(file)
+}
+
+// Default returns the templates shipped for the default chronicle
+func Default() *Set {
+	set, err := Bundled(DefaultChronicle)
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// Load reads the templates from a JSON or a CSV file, depending on its extension
+func Load(path string) (*Set, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		set, err := ParseCSV(file)
+		if err != nil {
+			return nil, err
+		}
+		set.Chronicle = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		return set, nil
+	}
+	return ParseJSON(file)
+}
+
+// ParseJSON reads a Set
+func ParseJSON(r io.Reader) (*Set, error) {
+	var set Set
+	if err := json.NewDecoder(r).Decode(&set); err != nil {
+		return nil, err
+	}
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// csvColumns are the columns of the CSV files, in order
+var csvColumns = []string{"race", "class", "name", "str", "dex", "con", "int", "wit", "men", "items", "spawns"}
+
+// ParseCSV reads templates from a CSV file with a header line. The items are
+// listed as id:count and the spawn points as x:y:z, separated by semicolons:
+//
+//	race,class,name,str,dex,con,int,wit,men,items,spawns
+//	0,0,Human Fighter,40,30,43,21,11,25,1146:1;1147:1,-71338:258271:-3104
+func ParseCSV(r io.Reader) (*Set, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvColumns)
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(csvColumns, ",") {
+		return nil, fmt.Errorf("%w: the header must be %s", ErrInvalidTemplate, strings.Join(csvColumns, ","))
+	}
+
+	set := &Set{}
+	for i, record := range records[1:] {
+		template, err := parseRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidTemplate, i+2, err)
+		}
+		set.Templates = append(set.Templates, template)
+	}
+
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func parseRecord(record []string) (Template, error) {
+	template := Template{Name: record[2]}
+
+	numbers := []*int{&template.Race, &template.Class, nil, &template.STR, &template.DEX, &template.CON, &template.INT, &template.WIT, &template.MEN}
+	for i, number := range numbers {
+		if number == nil {
+			continue
+		}
+		value, err := strconv.Atoi(record[i])
+		if err != nil {
+			return template, fmt.Errorf("%s: %v", csvColumns[i], err)
+		}
+		*number = value
+	}
+
+	for _, entry := range split(record[9]) {
+		values, err := parseInts(entry, 2)
+		if err != nil {
+			return template, fmt.Errorf("items: %v", err)
+		}
+		template.Items = append(template.Items, Item{ID: values[0], Count: values[1]})
+	}
+
+	for _, entry := range split(record[10]) {
+		values, err := parseInts(entry, 3)
+		if err != nil {
+			return template, fmt.Errorf("spawns: %v", err)
+		}
+		template.Spawns = append(template.Spawns, Point{X: int32(values[0]), Y: int32(values[1]), Z: int32(values[2])})
+	}
+
+	return template, nil
+}
+
+// split returns the semicolon separated entries of a field
+func split(field string) []string {
+	if strings.TrimSpace(field) == "" {
+		return nil
+	}
+	return strings.Split(field, ";")
+}
+
+// parseInts parses count colon separated integers
+func parseInts(entry string, count int) ([]int, error) {
+	parts := strings.Split(strings.TrimSpace(entry), ":")
+	if len(parts) != count {
+		return nil, fmt.Errorf("%q should hold %d values", entry, count)
+	}
+
+	values := make([]int, count)
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
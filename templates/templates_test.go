@@ -0,0 +1,68 @@
+package templates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBundled(t *testing.T) {
+	set, err := Bundled(DefaultChronicle)
+	if err != nil {
+		t.Fatalf("Bundled() error = %v", err)
+	}
+
+	template, err := set.Find(0, 0)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if template.Name != "Human Fighter" || template.STR != 40 || len(template.Items) == 0 || len(template.Spawns) == 0 {
+		t.Errorf("Find() = %+v", template)
+	}
+
+	if _, err := set.Find(4, 10); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("Find() error = %v, want %v", err, ErrUnknownTemplate)
+	}
+	if _, err := Bundled("c99"); !errors.Is(err, ErrUnknownChronicle) {
+		t.Errorf("Bundled() error = %v, want %v", err, ErrUnknownChronicle)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	const header = "race,class,name,str,dex,con,int,wit,men,items,spawns\n"
+
+	tests := []struct {
+		name    string
+		csv     string
+		want    error
+		wantLen int
+	}{
+		{"valid", header + "0,0,Human Fighter,40,30,43,21,11,25,1146:1;1147:1,-71338:258271:-3104\n3,49,Orc Mystic,27,24,31,31,15,42,,-56733:-113459:-690", nil, 2},
+		{"wrong header", "race,class\n0,0", ErrInvalidTemplate, 0},
+		{"bad stat", header + "0,0,Human Fighter,strong,30,43,21,11,25,,0:0:0", ErrInvalidTemplate, 0},
+		{"bad spawn", header + "0,0,Human Fighter,40,30,43,21,11,25,,0:0", ErrInvalidTemplate, 0},
+		{"no spawn", header + "0,0,Human Fighter,40,30,43,21,11,25,1146:1,", ErrInvalidTemplate, 0},
+		{"no item", header + "0,0,Human Fighter,40,30,43,21,11,25,1146:0,0:0:0", ErrInvalidTemplate, 0},
+		{"duplicate", header + "0,0,A,1,1,1,1,1,1,,0:0:0\n0,0,B,1,1,1,1,1,1,,0:0:0", ErrInvalidTemplate, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := ParseCSV(strings.NewReader(tt.csv))
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Fatalf("ParseCSV() error = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			if len(set.Templates) != tt.wantLen {
+				t.Fatalf("ParseCSV() = %d templates, want %d", len(set.Templates), tt.wantLen)
+			}
+
+			orc, err := set.Find(3, 49)
+			if err != nil || orc.MEN != 42 || orc.Spawns[0].Y != -113459 || len(orc.Items) != 0 {
+				t.Errorf("Find() = %+v, %v", orc, err)
+			}
+		})
+	}
+}
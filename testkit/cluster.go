@@ -0,0 +1,206 @@
+// Package testkit boots a complete login + game server cluster in-process so
+// the client toolkit can be tested end to end without external services.
+package testkit
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/gameserver"
+	"github.com/frostwind/l2go/loginserver"
+	"github.com/frostwind/l2go/seed"
+)
+
+// HeartbeatInterval is how often the game server reports to the login server in
+// a test cluster. It is short so dead links are detected within a test.
+const HeartbeatInterval = 100 * time.Millisecond
+
+// TestDatabaseEnvPrefix starts the environment variables pointing the integration tests at a MySQL
+// database, such as L2GO_TEST_DB_HOST, named as the DB_ variables of the servers
+const TestDatabaseEnvPrefix = config.ENV_PREFIX + "TEST_DB_"
+
+// Cluster is a running login server and game server pair
+type Cluster struct {
+	LoginServer *loginserver.LoginServer
+	GameServer  *gameserver.GameServer
+
+	// ServerConfig is the configuration both servers were started with
+	ServerConfig config.ConfigObject
+
+	// Config points the toolkit at the cluster
+	Config *client.ToolkitConfig
+}
+
+// StartTestCluster boots a login server backed by the in-memory account
+// storage and a game server registered to it. Both are stopped when the test ends.
+// The options can change the configuration before the servers start.
+func StartTestCluster(t testing.TB, options ...func(*config.ConfigObject)) *Cluster {
+	t.Helper()
+
+	gamePort := freePort(t)
+
+	serverConfig := config.ConfigObject{
+		LoginServer: config.LoginServerType{
+			Host:               "127.0.0.1",
+			ListenAddress:      "127.0.0.1:0",
+			GameServersAddress: "127.0.0.1:0",
+			AutoCreate:         true,
+			HeartbeatInterval:  HeartbeatInterval,
+			MissedHeartbeats:   3,
+			AdminAddress:       "127.0.0.1:0",
+			Database:           config.DatabaseType{Driver: config.DATABASE_DRIVER_MEMORY},
+		},
+		GameServers: []config.GameServerType{
+			{
+				Id:         1,
+				Name:       "Bartz",
+				Secret:     "testkit",
+				InternalIP: "127.0.0.1",
+				ExternalIP: "127.0.0.1",
+				Port:       gamePort,
+				Database:   config.DatabaseType{Driver: config.DATABASE_DRIVER_MEMORY},
+				Options:    config.OptionsType{MaxPlayers: 1000},
+			},
+		},
+	}
+
+	for _, option := range options {
+		option(&serverConfig)
+	}
+
+	loginServer := loginserver.New(serverConfig)
+	loginServer.Init()
+	if loginServer.ClientsAddr() == nil || loginServer.GameServersAddr() == nil {
+		t.Fatal("testkit: the login server couldn't listen")
+	}
+
+	loginStopped := make(chan struct{})
+	go func() {
+		defer close(loginStopped)
+		loginServer.Start()
+	}()
+	t.Cleanup(func() {
+		loginServer.Stop()
+		<-loginStopped
+	})
+
+	// The game server reaches the login server on its actual port
+	serverConfig.LoginServer.GameServersAddress = loginServer.GameServersAddr().String()
+
+	gameServer := gameserver.New(config.GameServerConfigObject{
+		LoginServer: serverConfig.LoginServer,
+		GameServer:  serverConfig.GameServers[0],
+	})
+	gameServer.Init()
+	if gameServer.Addr() == nil {
+		t.Fatal("testkit: the game server couldn't listen")
+	}
+
+	gameStopped := make(chan struct{})
+	go func() {
+		defer close(gameStopped)
+		gameServer.Start()
+	}()
+	t.Cleanup(func() {
+		gameServer.Stop()
+		<-gameStopped
+	})
+
+	// Clients are only offered the game server once it registered
+	deadline := time.Now().Add(5 * time.Second)
+	for !loginServer.GameServerUp(1) {
+		if time.Now().After(deadline) {
+			t.Fatal("testkit: the game server didn't register to the login server")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	loginPort := loginServer.ClientsAddr().(*net.TCPAddr).Port
+
+	return &Cluster{
+		LoginServer:  loginServer,
+		GameServer:   gameServer,
+		ServerConfig: serverConfig,
+		Config:       toolkitConfig(loginPort, gamePort),
+	}
+}
+
+// Seed writes a fixture to the storage of the servers, so that its accounts can log in and find their
+// characters, failing the test when it can't
+func (c *Cluster) Seed(t testing.TB, fixture seed.Fixture) seed.Result {
+	t.Helper()
+
+	repositories := seed.Repositories{Accounts: c.LoginServer.Accounts()}
+	repositories.Characters, repositories.Warehouses, repositories.Friends = c.GameServer.Storage()
+	result, err := seed.Seed(fixture, repositories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// MySQLFromEnv returns the MySQL database the L2GO_TEST_DB_ environment variables point at, which must
+// have been initialized with schema.sql. It skips the test when L2GO_TEST_DB_HOST isn't set, so the
+// suites backed by MySQL only run where one was started, such as a CI job with a MySQL service:
+//
+//	docker run -d -p 3306:3306 -e MYSQL_ROOT_PASSWORD=l2go -v $PWD/schema.sql:/docker-entrypoint-initdb.d/schema.sql mysql:8
+//	L2GO_TEST_DB_HOST=127.0.0.1 L2GO_TEST_DB_PASSWORD=l2go go test ./testkit
+func MySQLFromEnv(t testing.TB) config.DatabaseType {
+	t.Helper()
+
+	database := config.DatabaseType{
+		Driver:      config.DATABASE_DRIVER_MYSQL,
+		Name:        "l2go",
+		Port:        3306,
+		User:        "root",
+		WaitTimeout: 30 * time.Second,
+	}
+	if err := database.ApplyEnv(os.LookupEnv, TestDatabaseEnvPrefix); err != nil {
+		t.Fatalf("testkit: %v", err)
+	}
+	if database.Host == "" {
+		t.Skipf("testkit: %sHOST isn't set, no MySQL database to test against", TestDatabaseEnvPrefix)
+	}
+	return database
+}
+
+// WithLoginDatabase backs the login server of a cluster with the given database
+func WithLoginDatabase(database config.DatabaseType) func(*config.ConfigObject) {
+	return func(serverConfig *config.ConfigObject) {
+		serverConfig.LoginServer.Database = database
+	}
+}
+
+// toolkitConfig builds a toolkit configuration whose active profile targets the cluster
+func toolkitConfig(loginPort, gamePort int) *client.ToolkitConfig {
+	cfg := client.DefaultToolkitConfig()
+
+	cfg.Client.LoginServerHost = "127.0.0.1"
+	cfg.Client.LoginServerPort = loginPort
+	cfg.Client.GameServerHost = "127.0.0.1"
+	cfg.Client.GameServerPort = gamePort
+	cfg.Client.Timeout = 5 * time.Second
+
+	cfg.Profiles.Active = "development"
+	cfg.Profiles.Development.LoginServer = client.ServerProfile{Host: "127.0.0.1", Port: loginPort, Timeout: 5 * time.Second}
+	cfg.Profiles.Development.GameServer = client.ServerProfile{Host: "127.0.0.1", Port: gamePort, Timeout: 5 * time.Second}
+
+	return cfg
+}
+
+// freePort asks the kernel for an unused TCP port
+func freePort(t testing.TB) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("testkit: couldn't reserve a port: %v", err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,3010 @@
+package testkit
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/audit"
+	"github.com/frostwind/l2go/client"
+	"github.com/frostwind/l2go/config"
+	"github.com/frostwind/l2go/gameserver"
+	"github.com/frostwind/l2go/gameserver/ai"
+	"github.com/frostwind/l2go/gameserver/behavior"
+	"github.com/frostwind/l2go/gameserver/capacity"
+	"github.com/frostwind/l2go/gameserver/effects"
+	"github.com/frostwind/l2go/gameserver/linkpackets"
+	"github.com/frostwind/l2go/gameserver/models"
+	"github.com/frostwind/l2go/gameserver/movement"
+	"github.com/frostwind/l2go/gameserver/quests"
+	"github.com/frostwind/l2go/gameserver/repository"
+	"github.com/frostwind/l2go/gameserver/sendqueue"
+	"github.com/frostwind/l2go/gameserver/serverpackets"
+	"github.com/frostwind/l2go/gameserver/shop"
+	"github.com/frostwind/l2go/gameserver/warehouse"
+	"github.com/frostwind/l2go/loadtest"
+	"github.com/frostwind/l2go/loginserver"
+	"github.com/frostwind/l2go/names"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+	"github.com/frostwind/l2go/packettiming"
+	"github.com/frostwind/l2go/protocol"
+	"github.com/frostwind/l2go/seed"
+)
+
+func TestStartTestCluster(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	if err := cluster.Config.Validate(); err != nil {
+		t.Fatalf("cluster config is invalid: %v", err)
+	}
+	if err := cluster.Config.ApplyProfile(); err != nil {
+		t.Fatalf("ApplyProfile() error = %v", err)
+	}
+
+	address := net.JoinHostPort(cluster.Config.Client.LoginServerHost, strconv.Itoa(cluster.Config.Client.LoginServerPort))
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("couldn't reach the login server: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// The login server greets every client with the Init packet
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Fatalf("no Init packet received: %v", err)
+	}
+	if header[2] != 0x00 {
+		t.Errorf("expected the Init opcode, got %#x", header[2])
+	}
+
+	address = net.JoinHostPort(cluster.Config.Client.GameServerHost, strconv.Itoa(cluster.Config.Client.GameServerPort))
+	gameConn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("couldn't reach the game server: %v", err)
+	}
+	gameConn.Close()
+}
+
+func TestClusterLogin(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+
+	if err := c.Login(config.Username, config.Password); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	servers := c.Sessions().LoginSession().ServerList
+	if len(servers) != 1 {
+		t.Fatalf("got %d servers, want 1", len(servers))
+	}
+	if err := c.SelectServer(int(servers[0].ID)); err != nil {
+		t.Fatalf("SelectServer() error = %v", err)
+	}
+}
+
+func TestClusterRejectsOversizedPackets(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	tests := []struct {
+		name string
+		port int
+	}{
+		{"login server", cluster.Config.Client.LoginServerPort},
+		{"game server", cluster.Config.Client.GameServerPort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(tt.port)))
+			if err != nil {
+				t.Fatalf("couldn't connect: %v", err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+			// Announce the largest possible packet and never send its body
+			if _, err := conn.Write([]byte{0xff, 0xff}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			// The server must hang up instead of waiting for 64KB
+			if _, err := io.Copy(io.Discard, conn); err != nil {
+				t.Fatalf("expected the server to close the connection, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClusterGameServerHeartbeats(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	// Impersonate the game server: the login server must replace the previous link
+	link, err := net.Dial("tcp", cluster.LoginServer.GameServersAddr().String())
+	if err != nil {
+		t.Fatalf("couldn't reach the login server: %v", err)
+	}
+	defer link.Close()
+
+	send := func(packet []byte) {
+		t.Helper()
+
+		frame := append([]byte{byte(len(packet) + 2), byte((len(packet) + 2) >> 8)}, packet...)
+		if _, err := link.Write(frame); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	waitFor := func(up bool) {
+		t.Helper()
+
+		deadline := time.Now().Add(20 * HeartbeatInterval)
+		for cluster.LoginServer.GameServerUp(1) != up {
+			if time.Now().After(deadline) {
+				t.Fatalf("the game server never went up=%v", up)
+			}
+			time.Sleep(HeartbeatInterval / 10)
+		}
+	}
+
+	send(linkpackets.NewRegisterGameServerPacket("Bartz", cluster.ServerConfig.GameServers[0].Secret, protocol.CompressionOptions{}))
+	send(linkpackets.NewHeartbeatPacket(42))
+
+	// Silence gets the game server demoted after the missed heartbeats
+	time.Sleep(HeartbeatInterval)
+	waitFor(false)
+
+	// A single heartbeat brings it back
+	send(linkpackets.NewHeartbeatPacket(42))
+	waitFor(true)
+
+	// Losing the link demotes it right away
+	link.Close()
+	waitFor(false)
+}
+
+func TestClusterConnect(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+
+	// The game server only lets the client in with the token the login server minted for it
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+}
+
+func TestClusterNullCrypto(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.LoginServer.NullCrypto = true
+		cfg.GameServers[0].Options.NullCrypto = true
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	// A client expecting encrypted packets is told why it can't read the plain ones
+	c := client.NewClient("e2e", config)
+	if err := c.Connect(); !errors.Is(err, client.ErrNullCryptoMismatch) {
+		t.Fatalf("Connect() without null crypto error = %v, want %v", err, client.ErrNullCryptoMismatch)
+	}
+	c.Disconnect()
+
+	config.NullCrypto = true
+	c = client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if stats := c.CryptoStats(); stats.Encryptions != 0 || stats.Decryptions != 0 {
+		t.Errorf("CryptoStats() = %+v, want nothing encrypted", stats)
+	}
+}
+
+func TestClusterCompression(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.LoginServer.Compression = config.CompressionType{Enabled: true, Threshold: 32}
+		cfg.GameServers[0].Options.Compression = config.CompressionType{Enabled: true, Threshold: 32}
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	// A client which doesn't offer compression gets its packets as they are
+	plain := client.NewClient("plain", config)
+	defer plain.Disconnect()
+	if err := plain.Connect(); err != nil {
+		t.Fatalf("Connect() without compression error = %v", err)
+	}
+	if stats := plain.CompressionStats(); stats != (protocol.CompressionStats{}) {
+		t.Errorf("CompressionStats() without compression = %+v, want nothing compressed", stats)
+	}
+	plain.Disconnect()
+
+	config.Compression = protocol.CompressionOptions{Enabled: true, Threshold: 32}
+	c := client.NewClient("compressed", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	// The character list sent back holds a character, large enough to be compressed
+	if err := c.CreateCharacter("Tester", nil); err != nil {
+		t.Fatalf("CreateCharacter() error = %v", err)
+	}
+
+	stats := c.CompressionStats()
+	if stats.PacketsSent == 0 || stats.PacketsDecompressed == 0 {
+		t.Errorf("CompressionStats() = %+v, want packets sent and inflated", stats)
+	}
+
+	// The session of the client went through the link, compressed since the registration
+	if link := cluster.GameServer.LinkCompressionStats(); link.PacketsReceived == 0 || link.PacketsSent == 0 {
+		t.Errorf("LinkCompressionStats() = %+v, want the session and the heartbeats compressed", link)
+	}
+}
+
+func TestClusterExperience(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.DeathPenalty = 10
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+
+	cluster.GameServer.AddExp(player, 400)
+	if player.Level != 3 || player.Exp != 400 {
+		t.Fatalf("level %d with %d exp after AddExp(400)", player.Level, player.Exp)
+	}
+
+	// Dying at level 3 costs a tenth of the 805 exp between levels 3 and 4, enough to lose the level
+	if lost := cluster.GameServer.ApplyDeathPenalty(player); lost != 80 || player.Level != 2 || player.Exp != 320 {
+		t.Fatalf("ApplyDeathPenalty() = %d, level %d with %d exp", lost, player.Level, player.Exp)
+	}
+}
+
+func TestClusterNpcAI(t *testing.T) {
+	dataPath := t.TempDir()
+	spawns := `[{"templateId": 20001, "type": "monster", "name": "Gremlin", "x": 300, "y": 0, "z": 0, "ai": {"aggressive": true, "aggroRange": 500, "speed": 100}}]`
+	if err := os.WriteFile(filepath.Join(dataPath, "spawns.json"), []byte(spawns), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The AI is driven by hand rather than on time
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.DataDirectory = dataPath
+		cfg.GameServers[0].Options.ThinkInterval = -1
+	})
+
+	npcID := uint32(gameserver.FIRST_NPC_OBJECT_ID)
+	if state, ok := cluster.GameServer.AI().State(npcID); !ok || state != ai.IDLE {
+		t.Fatalf("State() = %q, %v once spawned", state, ok)
+	}
+	if stats := cluster.GameServer.AI().Tick(); stats.Paused != 1 {
+		t.Fatalf("Tick() = %+v without players", stats)
+	}
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	cluster.GameServer.AI().Tick()
+	cluster.GameServer.AI().Advance(time.Second)
+	npc, _ := cluster.GameServer.Npc(npcID)
+	if state, _ := cluster.GameServer.AI().State(npcID); state != ai.AGGRO || npc.X != 200 {
+		t.Errorf("state %s at x %d once a player came close", state, npc.X)
+	}
+}
+
+func TestClusterEffectsSurviveRelog(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	might := effects.Effect{SkillID: 1068, Level: 3, Kind: effects.BUFF, StackType: "pAtk", Duration: time.Hour}
+	if err := cluster.GameServer.AddEffect(player, might); err != nil {
+		t.Fatalf("AddEffect() error = %v", err)
+	}
+
+	// The effects are saved before the player leaves the world
+	c.Disconnect()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the player is still in the world")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	c = client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	player, ok = cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID + 1)
+	if !ok {
+		t.Fatal("the player isn't back in the world")
+	}
+	active := player.Effects.Active()
+	if len(active) != 1 || active[0].SkillID != might.SkillID || active[0].Level != might.Level {
+		t.Fatalf("Active() = %+v after the relog, want %+v", active, might)
+	}
+	if remaining := active[0].Remaining(time.Now()); remaining <= 0 || remaining > time.Hour {
+		t.Errorf("Remaining() = %v after the relog", remaining)
+	}
+}
+
+func TestClusterCharacterSurvivesRelog(t *testing.T) {
+	dataPath := t.TempDir()
+	tables := `[{"npcId": 20001, "drops": [{"itemId": 57, "chance": 100, "min": 50, "max": 50}, {"itemId": 1864, "chance": 100, "min": 2, "max": 2}]}]`
+	if err := os.WriteFile(filepath.Join(dataPath, "drops.json"), []byte(tables), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.DataDirectory = dataPath
+		cfg.GameServers[0].Options.AutoLoot = true
+		cfg.GameServers[0].Options.TicksPerSecond = 50
+		cfg.GameServers[0].Options.SaveInterval = 50 * time.Millisecond
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	cluster.GameServer.AddExp(player, 500)
+	cluster.GameServer.DropLoot(&models.Npc{TemplateID: 20001}, player)
+
+	// The game loop saves the changed character once it waited for the interval
+	persistence := cluster.GameServer.Persistence()
+	deadline := time.Now().Add(5 * time.Second)
+	for persistence.Stats().Saved == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("the character was never saved: %+v", persistence.Stats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The last change is saved as the player leaves
+	cluster.GameServer.Teleport(player, -84318, 244579, -3730)
+	c.Disconnect()
+	for {
+		if _, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the player is still in the world")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := persistence.Stats(); stats.Queued != 0 || stats.Saved < 2 {
+		t.Errorf("Stats() = %+v once the player left", stats)
+	}
+
+	c = client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	player, ok = cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID + 1)
+	if !ok {
+		t.Fatal("the player isn't back in the world")
+	}
+	if player.Exp != 500 || player.Adena != gameserver.STARTING_ADENA+50 || player.Items[1864] != 2 {
+		t.Errorf("the player came back with %d exp, %d adena and %v", player.Exp, player.Adena, player.Items)
+	}
+	if player.X != -84318 || player.Y != 244579 || player.Z != -3730 {
+		t.Errorf("the player came back at %d, %d, %d", player.X, player.Y, player.Z)
+	}
+}
+
+func TestClusterMovementValidation(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.MaxMoveSpeed = 100
+		cfg.GameServers[0].Options.MoveTolerance = 50
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	cluster.GameServer.Teleport(player, 1000, 1000, 0)
+
+	// A client claiming to stand elsewhere is put back, the move starting from where the server knows it stands
+	cluster.GameServer.MoveTo(player, movement.Position{X: 9000, Y: 1000}, movement.Position{X: 3000, Y: 1000})
+	if player.X != 1000 || player.Y != 1000 {
+		t.Fatalf("the player stands at %d, %d after a jump", player.X, player.Y)
+	}
+
+	if !cluster.GameServer.ValidatePosition(player, movement.Position{X: 1040, Y: 1000}) {
+		t.Error("ValidatePosition() = false within the tolerance")
+	}
+	if cluster.GameServer.ValidatePosition(player, movement.Position{X: 2500, Y: 1000}) {
+		t.Error("ValidatePosition() = true faster than the speed limit")
+	}
+	if player.X != 1040 {
+		t.Errorf("the player stands at x %d, want 1040", player.X)
+	}
+
+	if violations := player.Movement.Violations(); violations.Jumped != 1 || violations.TooFast != 1 {
+		t.Errorf("Violations() = %+v", violations)
+	}
+	if stats := cluster.GameServer.Stats(); stats.MovementViolations != 2 {
+		t.Errorf("MovementViolations = %d, want 2", stats.MovementViolations)
+	}
+}
+
+func TestClusterRestart(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	cluster.GameServer.AddExp(player, 500)
+
+	// The player leaves the world with its character saved, its connection staying open
+	if err := cluster.GameServer.Restart(player); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	if stats := cluster.GameServer.Persistence().Stats(); stats.Saved != 1 || stats.Queued != 0 {
+		t.Errorf("Stats() = %+v after the restart", stats)
+	}
+	if _, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID); !ok || player.Effects != nil {
+		t.Errorf("the player is still in the world or got disconnected")
+	}
+
+	if err := cluster.GameServer.Restart(player); !errors.Is(err, gameserver.ErrNotInWorld) {
+		t.Errorf("Restart() error = %v at the character selection, want %v", err, gameserver.ErrNotInWorld)
+	}
+}
+
+func TestClusterFriendsAndWhispers(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	var players []*models.Client
+	for i, username := range []string{"alice", "bob"} {
+		config := cluster.Config.Client
+		config.Username = username
+		config.Password = "e2epass"
+
+		c := client.NewClient(username, config)
+		defer c.Disconnect()
+		if err := c.Connect(); err != nil {
+			t.Fatalf("Connect() error = %v", err)
+		}
+
+		player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID + uint32(i))
+		if !ok {
+			t.Fatalf("%s isn't in the world", username)
+		}
+		players = append(players, player)
+	}
+	alice, bob := players[0], players[1]
+
+	if err := cluster.GameServer.Whisper(alice, "nobody", "hello"); !errors.Is(err, gameserver.ErrPlayerOffline) {
+		t.Errorf("Whisper() error = %v to an offline player, want %v", err, gameserver.ErrPlayerOffline)
+	}
+	if err := cluster.GameServer.Whisper(alice, "BOB", "hello"); err != nil {
+		t.Errorf("Whisper() error = %v", err)
+	}
+
+	if err := cluster.GameServer.InviteFriend(alice, "Alice"); !errors.Is(err, gameserver.ErrInviteSelf) {
+		t.Errorf("InviteFriend() error = %v for herself, want %v", err, gameserver.ErrInviteSelf)
+	}
+	if err := cluster.GameServer.InviteFriend(alice, "bob"); err != nil {
+		t.Fatalf("InviteFriend() error = %v", err)
+	}
+	if err := cluster.GameServer.AnswerFriendInvite(bob, true); err != nil {
+		t.Fatalf("AnswerFriendInvite() error = %v", err)
+	}
+	if err := cluster.GameServer.AnswerFriendInvite(bob, true); !errors.Is(err, gameserver.ErrNoFriendInvite) {
+		t.Errorf("AnswerFriendInvite() error = %v answered twice, want %v", err, gameserver.ErrNoFriendInvite)
+	}
+	if err := cluster.GameServer.InviteFriend(alice, "bob"); !errors.Is(err, gameserver.ErrAlreadyFriends) {
+		t.Errorf("InviteFriend() error = %v for a friend, want %v", err, gameserver.ErrAlreadyFriends)
+	}
+
+	friends, err := cluster.GameServer.Friends(alice)
+	if want := []serverpackets.Friend{{ObjectID: bob.ObjectID, Name: "bob", Online: true}}; err != nil || !slices.Equal(friends, want) {
+		t.Errorf("Friends() = %+v, %v, want %+v", friends, err, want)
+	}
+
+	// Once bob leaves the world, alice sees him offline and can't whisper to him
+	if err := cluster.GameServer.Restart(bob); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	friends, err = cluster.GameServer.Friends(alice)
+	if want := []serverpackets.Friend{{Name: "bob"}}; err != nil || !slices.Equal(friends, want) {
+		t.Errorf("Friends() = %+v, %v once bob left, want %+v", friends, err, want)
+	}
+	if err := cluster.GameServer.Whisper(alice, "bob", "hello?"); !errors.Is(err, gameserver.ErrPlayerOffline) {
+		t.Errorf("Whisper() error = %v once bob left, want %v", err, gameserver.ErrPlayerOffline)
+	}
+
+	if err := cluster.GameServer.RemoveFriend(alice, "Bob"); err != nil {
+		t.Fatalf("RemoveFriend() error = %v", err)
+	}
+	for _, player := range players {
+		if friends, err := cluster.GameServer.Friends(player); err != nil || len(friends) != 0 {
+			t.Errorf("Friends() = %+v, %v for %s after RemoveFriend()", friends, err, player.Account)
+		}
+	}
+}
+
+func TestClusterFriendsPreAuth(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.LoginServer.NullCrypto = true
+		cfg.GameServers[0].Options.NullCrypto = true
+	})
+
+	// A connection which never authenticates, and a player it knows the name of
+	_, anonymous := dialPreAuth(t, cluster)
+
+	config := cluster.Config.Client
+	config.Username = "alice"
+	config.Password = "e2epass"
+	config.NullCrypto = true
+
+	c := client.NewClient("alice", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	alice, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID + 1)
+	if !ok {
+		t.Fatal("alice isn't in the world")
+	}
+
+	calls := []struct {
+		name string
+		call func() error
+	}{
+		{"InviteFriend", func() error { return cluster.GameServer.InviteFriend(anonymous, "alice") }},
+		{"AnswerFriendInvite", func() error { return cluster.GameServer.AnswerFriendInvite(anonymous, true) }},
+		{"RemoveFriend", func() error { return cluster.GameServer.RemoveFriend(anonymous, "alice") }},
+		{"Whisper", func() error { return cluster.GameServer.Whisper(anonymous, "alice", "hello") }},
+	}
+	for _, tt := range calls {
+		if err := tt.call(); !errors.Is(err, gameserver.ErrNotInWorld) {
+			t.Errorf("%s() error = %v before authenticating, want %v", tt.name, err, gameserver.ErrNotInWorld)
+		}
+	}
+
+	// Alice wasn't asked anything, so her answer has no invite to accept
+	if err := cluster.GameServer.AnswerFriendInvite(alice, true); !errors.Is(err, gameserver.ErrNoFriendInvite) {
+		t.Errorf("AnswerFriendInvite() error = %v, want %v", err, gameserver.ErrNoFriendInvite)
+	}
+}
+
+func TestClusterGameLoop(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.TicksPerSecond = 50
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	poison := effects.Effect{SkillID: 4035, Level: 1, Kind: effects.DEBUFF, Duration: 100 * time.Millisecond, Tick: 40 * time.Millisecond, TickHP: -10}
+	if err := cluster.GameServer.AddEffect(player, poison); err != nil {
+		t.Fatalf("AddEffect() error = %v", err)
+	}
+
+	// The loop expires the effect on time
+	deadline := time.Now().Add(5 * time.Second)
+	for len(player.Effects.Active()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the effect never expired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	runs := make(map[string]uint64)
+	for _, system := range cluster.GameServer.Loop().Stats().Systems {
+		runs[system.Name] = system.Runs
+	}
+	if runs["effects"] == 0 || runs["regen"] == 0 || runs["ai"] == 0 || runs["movement"] < runs["ai"] {
+		t.Errorf("runs of the systems = %v", runs)
+	}
+}
+
+func TestClusterLoot(t *testing.T) {
+	tests := []struct {
+		name     string
+		autoLoot bool
+	}{
+		{"on the ground", false},
+		{"auto-loot", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataPath := t.TempDir()
+			tables := `[{"npcId": 20001, "drops": [{"itemId": 57, "chance": 100, "min": 50, "max": 50}, {"itemId": 1864, "chance": 100, "min": 2, "max": 2}]}]`
+			if err := os.WriteFile(filepath.Join(dataPath, "drops.json"), []byte(tables), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+				cfg.GameServers[0].Options.DataDirectory = dataPath
+				cfg.GameServers[0].Options.AutoLoot = tt.autoLoot
+				cfg.GameServers[0].Options.LootProtection = time.Hour
+			})
+
+			// Two players, the first one killing the NPC
+			var players []*models.Client
+			for i, username := range []string{"killer", "bystander"} {
+				config := cluster.Config.Client
+				config.Username = username
+				config.Password = "e2epass"
+
+				c := client.NewClient(username, config)
+				defer c.Disconnect()
+				if err := c.Connect(); err != nil {
+					t.Fatalf("Connect() error = %v", err)
+				}
+
+				player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID + uint32(i))
+				if !ok {
+					t.Fatalf("%s isn't in the world", username)
+				}
+				players = append(players, player)
+			}
+			killer, bystander := players[0], players[1]
+			adena := killer.Adena
+
+			npc := &models.Npc{TemplateID: 20001, Type: "monster", Name: "Gremlin"}
+			cluster.GameServer.SpawnNpc(npc)
+			items := cluster.GameServer.DropLoot(npc, killer)
+
+			if tt.autoLoot {
+				if len(items) != 0 || killer.Adena != adena+50 || killer.Items[1864] != 2 {
+					t.Fatalf("DropLoot() = %d items, the killer has %d adena and %v", len(items), killer.Adena, killer.Items)
+				}
+				return
+			}
+
+			if len(items) != 2 {
+				t.Fatalf("DropLoot() = %d items, want 2", len(items))
+			}
+			for _, item := range items {
+				if err := cluster.GameServer.PickUp(bystander, item.ObjectID); !errors.Is(err, gameserver.ErrItemReserved) {
+					t.Fatalf("PickUp() error = %v, want %v", err, gameserver.ErrItemReserved)
+				}
+				if err := cluster.GameServer.PickUp(killer, item.ObjectID); err != nil {
+					t.Fatalf("PickUp() error = %v", err)
+				}
+				if err := cluster.GameServer.PickUp(killer, item.ObjectID); !errors.Is(err, gameserver.ErrUnknownItem) {
+					t.Fatalf("PickUp() error = %v, want %v", err, gameserver.ErrUnknownItem)
+				}
+			}
+			if killer.Adena != adena+50 || killer.Items[1864] != 2 {
+				t.Errorf("the killer has %d adena and %v", killer.Adena, killer.Items)
+			}
+		})
+	}
+}
+
+func TestClusterLootPreAuth(t *testing.T) {
+	dataPath := t.TempDir()
+	tables := `[{"npcId": 20001, "drops": [{"itemId": 57, "chance": 100, "min": 50, "max": 50}]}]`
+	if err := os.WriteFile(filepath.Join(dataPath, "drops.json"), []byte(tables), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.DataDirectory = dataPath
+		cfg.GameServers[0].Options.NullCrypto = true
+	})
+
+	// A connection which never authenticates, the adena on the ground reserved to it
+	_, player := dialPreAuth(t, cluster)
+	items := cluster.GameServer.DropLoot(&models.Npc{TemplateID: 20001}, player)
+	if len(items) != 1 {
+		t.Fatalf("DropLoot() = %d items, want 1", len(items))
+	}
+
+	if err := cluster.GameServer.PickUp(player, items[0].ObjectID); !errors.Is(err, gameserver.ErrNotInWorld) {
+		t.Errorf("PickUp() error = %v before authenticating, want %v", err, gameserver.ErrNotInWorld)
+	}
+	if _, ok := cluster.GameServer.GroundItem(items[0].ObjectID); !ok {
+		t.Error("the item left the ground")
+	}
+	if player.Adena != gameserver.STARTING_ADENA {
+		t.Errorf("the connection has %d adena", player.Adena)
+	}
+}
+
+func TestClusterRejectsWrongPlayKey(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+
+	if err := c.Login(config.Username, config.Password); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if err := c.SelectServer(1); err != nil {
+		t.Fatalf("SelectServer() error = %v", err)
+	}
+
+	c.Sessions().LoginSession().PlayKey[0] ^= 0xff
+
+	if err := c.ConnectToGame(); !errors.Is(err, client.ErrConnectionClosed) {
+		t.Fatalf("ConnectToGame() error = %v, want %v", err, client.ErrConnectionClosed)
+	}
+}
+
+func TestClusterRejectsWrongSecret(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	link, err := net.Dial("tcp", cluster.LoginServer.GameServersAddr().String())
+	if err != nil {
+		t.Fatalf("couldn't reach the login server: %v", err)
+	}
+	defer link.Close()
+	link.SetDeadline(time.Now().Add(5 * time.Second))
+
+	packet := linkpackets.NewRegisterGameServerPacket("Bartz", "wrong", protocol.CompressionOptions{})
+	frame := append([]byte{byte(len(packet) + 2), byte((len(packet) + 2) >> 8)}, packet...)
+	if _, err := link.Write(frame); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	data, err := packets.ReadFrame(link, packets.MaxFrameSize)
+	if err != nil {
+		t.Fatalf("no RegisterResult received: %v", err)
+	}
+	if data[0] != opcodes.LinkLoginServerRegisterResult {
+		t.Fatalf("expected the RegisterResult opcode, got %#x", data[0])
+	}
+
+	result, err := linkpackets.NewRegisterResult(data[1:])
+	if err != nil {
+		t.Fatalf("NewRegisterResult() error = %v", err)
+	}
+	if result.Accepted {
+		t.Fatal("a game server with a wrong secret was registered")
+	}
+
+	// The genuine game server keeps its link
+	if !cluster.LoginServer.GameServerUp(1) {
+		t.Error("the registered game server has been replaced")
+	}
+}
+
+func TestClusterAdminGameServers(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	if cluster.LoginServer.AdminAddr() == nil {
+		t.Fatal("the admin API isn't listening")
+	}
+
+	server := httptest.NewServer(cluster.LoginServer.AdminHandler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/gameservers")
+	if err != nil {
+		t.Fatalf("GET /gameservers error = %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", response.StatusCode, http.StatusOK)
+	}
+
+	var list []loginserver.GameServerInfo
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+		t.Fatalf("couldn't decode the game servers: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("got %d game servers, want 1", len(list))
+	}
+	if list[0].Id != 1 || list[0].Name != "Bartz" || !list[0].Up || list[0].MaxPlayers != 1000 {
+		t.Errorf("unexpected game server %+v", list[0])
+	}
+}
+
+func TestClusterCharacterNames(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	// Skip the toolkit pre-check, so the names reach the game server
+	permissive := names.NewValidator()
+	permissive.MinLength = 1
+	c.SetNameValidator(permissive)
+
+	tests := []struct {
+		name      string
+		character string
+		want      error
+	}{
+		{"created", "Tester", nil},
+		{"taken", "TESTER", client.ErrCharacterNameTaken},
+		{"refused by the game server", "Ab", client.ErrInvalidCharacterName},
+	}
+
+	for _, tt := range tests {
+		if err := c.CreateCharacter(tt.character, nil); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Fatalf("%s: CreateCharacter(%q) error = %v, want %v", tt.name, tt.character, err, tt.want)
+		}
+	}
+
+	// The templates are the ones the game server offers
+	if err := c.CreateCharacter("Mystic", &client.CharacterTemplate{Race: 3, Class: 49}); err != nil {
+		t.Fatalf("CreateCharacter() error = %v", err)
+	}
+	if err := c.CreateCharacter("Nobody", &client.CharacterTemplate{Race: 4, Class: 10}); !errors.Is(err, client.ErrInvalidTemplate) {
+		t.Fatalf("CreateCharacter() error = %v, want %v", err, client.ErrInvalidTemplate)
+	}
+
+	// The toolkit refuses the reserved names before sending them
+	c.SetNameValidator(names.Default())
+	if err := c.CreateCharacter("Admin", nil); !errors.Is(err, client.ErrInvalidCharacterName) {
+		t.Fatalf("CreateCharacter() error = %v, want %v", err, client.ErrInvalidCharacterName)
+	}
+}
+
+func TestClusterCharacterCreatePreAuth(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.LoginServer.NullCrypto = true
+		cfg.GameServers[0].Options.NullCrypto = true
+	})
+
+	// A connection which never authenticates tries to take a name
+	conn, _ := dialPreAuth(t, cluster)
+	writeGameFrame(t, conn, opcodes.GameClientCharacterCreate, packets.NewPacketWriter().S("Tester").B(make([]byte, 48)).Payload())
+
+	for {
+		data, err := packets.ReadFrame(conn, packets.MaxFrameSize)
+		if err != nil {
+			t.Fatalf("no CharCreateFail received: %v", err)
+		}
+		if data[0] == opcodes.GameServerCharCreateFail {
+			if data[1] != serverpackets.REASON_CREATION_FAILED {
+				t.Errorf("CharCreateFail reason = %d, want %d", data[1], serverpackets.REASON_CREATION_FAILED)
+			}
+			break
+		}
+	}
+	if stats := cluster.GameServer.Stats(); stats.HackAttempts != 1 {
+		t.Errorf("HackAttempts = %d, want 1", stats.HackAttempts)
+	}
+
+	// The name is still free for an account
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+	config.NullCrypto = true
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err := c.CreateCharacter("Tester", nil); err != nil {
+		t.Fatalf("CreateCharacter() error = %v", err)
+	}
+}
+
+func TestClusterKeepsLoggedInConnections(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.LoginServer.PreAuthTimeout = 200 * time.Millisecond
+		cfg.LoginServer.IdleTimeout = 5 * time.Second
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	// The first login creates the account, the second one logs in to the existing account
+	for _, name := range []string{"created", "existing"} {
+		c := client.NewClient(name, config)
+		defer c.Disconnect()
+		if err := c.Login(config.Username, config.Password); err != nil {
+			t.Fatalf("%s: Login() error = %v", name, err)
+		}
+
+		// Logged in, the client is past the time it had to log in
+		time.Sleep(400 * time.Millisecond)
+		if err := c.SelectServer(int(cluster.ServerConfig.GameServers[0].Id)); err != nil {
+			t.Fatalf("%s: SelectServer() error = %v after idling", name, err)
+		}
+		c.Disconnect()
+	}
+
+	if stats := cluster.LoginServer.Stats(); stats.ReapedPreAuth != 0 || stats.SuccessfulLogins != 2 {
+		t.Errorf("login server stats = %+v, want 2 logins and no client reaped", stats)
+	}
+}
+
+func TestClusterReapsIdleConnections(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.LoginServer.PreAuthTimeout = 200 * time.Millisecond
+		cfg.LoginServer.IdleTimeout = 300 * time.Millisecond
+		cfg.GameServers[0].Options.PreAuthTimeout = 200 * time.Millisecond
+		cfg.GameServers[0].Options.IdleTimeout = 300 * time.Millisecond
+	})
+
+	// Clients connecting and never logging in
+	for _, port := range []int{cluster.Config.Client.LoginServerPort, cluster.Config.Client.GameServerPort} {
+		conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			t.Fatalf("couldn't connect: %v", err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		if _, err := io.Copy(io.Discard, conn); err != nil {
+			t.Fatalf("expected the server to close the connection, got %v", err)
+		}
+		conn.Close()
+	}
+
+	if stats := cluster.LoginServer.Stats(); stats.ReapedPreAuth != 1 || stats.ReapedPostAuth != 0 {
+		t.Errorf("login server stats = %+v, want a single client reaped before logging in", stats)
+	}
+	if stats := cluster.GameServer.Stats(); stats.ReapedPreAuth != 1 || stats.ReapedPostAuth != 0 {
+		t.Errorf("game server stats = %+v, want a single client reaped before authenticating", stats)
+	}
+
+	// Clients going silent once authenticated
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	loggedIn := client.NewClient("logged-in", config)
+	defer loggedIn.Disconnect()
+	if err := loggedIn.Login(config.Username, config.Password); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	inGame := client.NewClient("in-game", config)
+	defer inGame.Disconnect()
+	if err := inGame.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cluster.LoginServer.Stats().ReapedPostAuth != 1 || cluster.GameServer.Stats().ReapedPostAuth != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("idle clients not reaped: login %+v, game %+v", cluster.LoginServer.Stats(), cluster.GameServer.Stats())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestClusterSlowConsumers(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		dropBroadcasts bool
+		shed           []sendqueue.Priority // Shed in turn as the queue fills up
+		disconnected   bool
+	}{
+		{"watermarks", "", false, []sendqueue.Priority{sendqueue.LOW, sendqueue.NORMAL}, true},
+		{"fifo", "fifo", false, nil, true},
+		{"fifo missing the broadcasts", "fifo", true, []sendqueue.Priority{sendqueue.LOW, sendqueue.NORMAL}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+				cfg.GameServers[0].Options.SendQueueSize = 8
+				cfg.GameServers[0].Options.SendPolicy = tt.policy
+				cfg.GameServers[0].Options.DropBroadcasts = tt.dropBroadcasts
+			})
+
+			// A client connecting and never reading its socket
+			conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(cluster.Config.Client.GameServerPort)))
+			if err != nil {
+				t.Fatalf("couldn't connect: %v", err)
+			}
+			defer conn.Close()
+
+			var player *models.Client
+			deadline := time.Now().Add(5 * time.Second)
+			for player == nil {
+				if time.Now().After(deadline) {
+					t.Fatal("the connection wasn't accepted")
+				}
+				player, _ = cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			// The packets pile up once the socket buffers are full, the sender never waiting for the client
+			packet := make([]byte, 4096)
+			dropped := func(priority sendqueue.Priority) uint64 {
+				if stats := cluster.GameServer.Stats(); priority == sendqueue.LOW {
+					return stats.DroppedLow
+				} else {
+					return stats.DroppedNormal
+				}
+			}
+			for _, priority := range tt.shed {
+				for i := 0; dropped(priority) == 0; i++ {
+					if i == 100000 {
+						t.Fatalf("the %v packets were never shed: %+v", priority, cluster.GameServer.Stats())
+					}
+					if err := player.SendWithPriority(packet, priority); err != nil {
+						t.Fatalf("SendWithPriority(%v) error = %v", priority, err)
+					}
+				}
+			}
+
+			// The critical packets are never shed, the client is disconnected once they fill its queue
+			for i := 0; tt.disconnected; i++ {
+				if i == 100000 {
+					t.Fatalf("the send queue never filled up: %+v", cluster.GameServer.Stats())
+				}
+				if err := player.Send(packet); err != nil {
+					if !errors.Is(err, sendqueue.ErrQueueFull) {
+						t.Fatalf("Send() error = %v, want %v", err, sendqueue.ErrQueueFull)
+					}
+					break
+				}
+			}
+
+			stats := cluster.GameServer.Stats()
+			if !tt.disconnected {
+				if stats.SlowClients != 0 || stats.SendQueueDepth != 8 || stats.DroppedLow == 0 || stats.DroppedNormal == 0 {
+					t.Errorf("stats = %+v, want the client kept with a full queue", stats)
+				}
+				return
+			}
+			if stats.SlowClients != 1 || stats.MaxSendQueueDepth != 8 {
+				t.Errorf("stats = %+v, want a single slow client with a full queue", stats)
+			}
+
+			// The slow client is kicked from the server
+			for {
+				if _, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID); !ok {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("the slow client is still online")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestClusterAdminStats(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Login(config.Username, config.Password); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	response, err := http.Get("http://" + cluster.LoginServer.AdminAddr().String() + "/stats")
+	if err != nil {
+		t.Fatalf("GET /stats error = %v", err)
+	}
+	defer response.Body.Close()
+
+	var stats loginserver.Stats
+	if err := json.NewDecoder(response.Body).Decode(&stats); err != nil {
+		t.Fatalf("couldn't decode the stats: %v", err)
+	}
+	if stats.SuccessfulAccountCreation != 1 {
+		t.Errorf("stats = %+v, want the account created on the fly", stats)
+	}
+}
+
+func TestClusterLoginStorm(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	config.Username = "storm"
+	config.Password = "stormpass"
+
+	storm := &loadtest.LoginStorm{
+		Client:      config,
+		Concurrency: 2,
+		Duration:    300 * time.Millisecond,
+		Accounts:    2,
+		AdminURL:    "http://" + cluster.LoginServer.AdminAddr().String(),
+	}
+	result, err := storm.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Logins == 0 || result.Failures != 0 {
+		t.Fatalf("Logins = %d, Failures = %d, Errors = %v", result.Logins, result.Failures, result.Errors)
+	}
+	if stats := cluster.LoginServer.Stats(); stats.SuccessfulAccountCreation != 2 {
+		t.Errorf("stats = %+v, want the 2 accounts of the storm created", stats)
+	}
+	if result.Server == nil || result.Server.Crypto <= 0 || result.Server.Database <= 0 {
+		t.Errorf("Server = %+v, want the time of the passwords and of the database", result.Server)
+	}
+}
+
+func TestClusterSnapshot(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.AdminAddress = "127.0.0.1:0"
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	loggedIn := client.NewClient("logged-in", config)
+	defer loggedIn.Disconnect()
+	if err := loggedIn.Login(config.Username, config.Password); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	config.Username = "e2eplayer"
+	inGame := client.NewClient("in-game", config)
+	defer inGame.Disconnect()
+	if err := inGame.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	cluster.GameServer.Teleport(player, 1000, 2000, -30)
+
+	get := func(address net.Addr, snapshot any) {
+		t.Helper()
+
+		response, err := http.Get("http://" + address.String() + "/snapshot")
+		if err != nil {
+			t.Fatalf("GET /snapshot error = %v", err)
+		}
+		defer response.Body.Close()
+
+		if err := json.NewDecoder(response.Body).Decode(snapshot); err != nil {
+			t.Fatalf("couldn't decode the snapshot: %v", err)
+		}
+	}
+
+	var login loginserver.Snapshot
+	get(cluster.LoginServer.AdminAddr(), &login)
+	if !slices.ContainsFunc(login.Sessions, func(session loginserver.SessionInfo) bool {
+		return session.Username == "e2euser" && session.LoggedIn
+	}) {
+		t.Errorf("sessions = %+v, want the logged in client", login.Sessions)
+	}
+	if len(login.GameServers) != 1 || !login.GameServers[0].Up {
+		t.Errorf("game servers = %+v, want the registered game server", login.GameServers)
+	}
+
+	var world gameserver.Snapshot
+	get(cluster.GameServer.AdminAddr(), &world)
+	want := gameserver.PlayerSnapshot{ObjectID: gameserver.FIRST_PLAYER_OBJECT_ID, Account: "e2eplayer", InWorld: true, X: 1000, Y: 2000, Z: -30, Level: 1, HP: gameserver.STARTING_HP, MaxHP: gameserver.STARTING_HP, MP: gameserver.STARTING_MP, MaxMP: gameserver.STARTING_MP, CP: gameserver.STARTING_CP, MaxCP: gameserver.STARTING_CP}
+	if len(world.Players) != 1 {
+		t.Fatalf("players = %+v, want the player in the world", world.Players)
+	}
+	got := world.Players[0]
+	got.Address, got.SendQueueDepth = "", 0
+	if got != want {
+		t.Errorf("player = %+v, want %+v", got, want)
+	}
+	if world.Stats.OnlinePlayers != 1 {
+		t.Errorf("stats = %+v", world.Stats)
+	}
+}
+
+func TestClusterDiagnostics(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.LoginServer.Diagnostics = config.DiagnosticsType{Enabled: true, Address: "127.0.0.1:0"}
+		cfg.GameServers[0].Options.Diagnostics = config.DiagnosticsType{Enabled: true, Address: "127.0.0.1:0"}
+	})
+
+	for name, address := range map[string]net.Addr{"login server": cluster.LoginServer.DiagnosticsAddr(), "game server": cluster.GameServer.DiagnosticsAddr()} {
+		if address == nil {
+			t.Fatalf("the %s doesn't serve its diagnostics", name)
+		}
+
+		response, err := http.Get("http://" + address.String() + "/debug/vars")
+		if err != nil {
+			t.Fatalf("GET /debug/vars error = %v", err)
+		}
+		var vars struct {
+			L2go map[string]json.RawMessage `json:"l2go"`
+		}
+		err = json.NewDecoder(response.Body).Decode(&vars)
+		response.Body.Close()
+		if err != nil {
+			t.Fatalf("couldn't decode the variables of the %s: %v", name, err)
+		}
+
+		// The servers share the variables of the process
+		for _, published := range []string{"loginserver", "gameserver", "gameloop"} {
+			if _, ok := vars.L2go[published]; !ok {
+				t.Errorf("the %s doesn't publish %s: %v", name, published, vars.L2go)
+			}
+		}
+	}
+}
+
+func TestClusterLoginServerHooks(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	events := make(chan string, 16)
+	cluster.LoginServer.OnAccountCreated(func(event loginserver.AccountCreated) {
+		events <- "created " + event.Username
+	})
+	cluster.LoginServer.OnLoginSuccess(func(event loginserver.LoginSucceeded) {
+		events <- "success " + event.Username
+	})
+	cluster.LoginServer.OnLoginFailure(func(event loginserver.LoginFailed) {
+		events <- "failure " + event.Username + ": " + event.Reason
+	})
+	cluster.LoginServer.OnHackAttempt(func(event loginserver.HackAttempt) {
+		events <- "hack: " + event.Reason
+	})
+	cluster.LoginServer.OnClientKicked(func(event loginserver.ClientKicked) {
+		events <- "kicked " + event.Username
+	})
+
+	// The handlers run concurrently, so the events are checked as a set
+	expect := func(want ...string) {
+		t.Helper()
+		pending := make(map[string]bool)
+		for _, event := range want {
+			pending[event] = true
+		}
+		for len(pending) > 0 {
+			select {
+			case event := <-events:
+				if !pending[event] {
+					t.Fatalf("unexpected event %q, waiting for %v", event, pending)
+				}
+				delete(pending, event)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("events %v not published", pending)
+			}
+		}
+	}
+
+	config := cluster.Config.Client
+	c := client.NewClient("e2e", config)
+	if err := c.Login("e2euser", "e2epass"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	expect("created e2euser", "success e2euser")
+	c.Disconnect()
+	expect("kicked e2euser")
+
+	c = client.NewClient("e2e", config)
+	if err := c.Login("e2euser", "wrongpass"); err == nil {
+		t.Fatal("Login() succeeded with a wrong password")
+	}
+	c.Disconnect()
+	expect("failure e2euser: wrong password", "kicked e2euser")
+
+	conn, err := net.Dial("tcp", cluster.LoginServer.ClientsAddr().String())
+	if err != nil {
+		t.Fatalf("couldn't connect: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte{0xff, 0xff})
+	expect("hack: oversized packet", "kicked ")
+}
+
+func TestClusterSecurityEvents(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	login := func() *client.Client {
+		t.Helper()
+		c := client.NewClient("e2e", config)
+		t.Cleanup(func() { c.Disconnect() })
+		if err := c.Login("e2euser", "e2epass"); err != nil {
+			t.Fatalf("Login() error = %v", err)
+		}
+		return c
+	}
+
+	if err := login().SelectServer(99); err == nil {
+		t.Error("SelectServer() succeeded with an unknown server")
+	}
+
+	c := login()
+	c.Sessions().LoginSession().SessionID[0] ^= 0xff
+	if err := c.SelectServer(1); err == nil {
+		t.Error("SelectServer() succeeded with a wrong session id")
+	}
+
+	// A frame that doesn't decrypt to a valid checksum
+	conn, err := net.Dial("tcp", cluster.LoginServer.ClientsAddr().String())
+	if err != nil {
+		t.Fatalf("couldn't connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := packets.ReadFrame(conn, 0); err != nil {
+		t.Fatalf("couldn't read the Init packet: %v", err)
+	}
+	conn.Write([]byte{0x0a, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08})
+	if _, err := io.Copy(io.Discard, conn); err != nil {
+		t.Fatalf("expected the server to close the connection, got %v", err)
+	}
+
+	want := map[string]uint32{
+		loginserver.SECURITY_INVALID_SERVER:   1,
+		loginserver.SECURITY_SESSION_MISMATCH: 1,
+		loginserver.SECURITY_CHECKSUM_FAILURE: 1,
+	}
+	if stats := cluster.LoginServer.Stats(); stats.HackAttempts != 3 || !maps.Equal(stats.HackAttemptsByType, want) {
+		t.Errorf("stats = %+v, want %v", stats, want)
+	}
+
+	response, err := http.Get("http://" + cluster.LoginServer.AdminAddr().String() + "/security?type=" + url.QueryEscape(loginserver.SECURITY_SESSION_MISMATCH))
+	if err != nil {
+		t.Fatalf("GET /security error = %v", err)
+	}
+	defer response.Body.Close()
+
+	var report loginserver.SecurityReport
+	if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+		t.Fatalf("couldn't decode the security events: %v", err)
+	}
+	if len(report.Events) != 1 || report.Events[0].Username != "e2euser" || report.Events[0].Address == "" || report.Events[0].Reason != "wrong session id in RequestPlay" {
+		t.Errorf("events = %+v, want the session mismatch of e2euser", report.Events)
+	}
+	if !maps.Equal(report.Counts, want) {
+		t.Errorf("counts = %v, want %v", report.Counts, want)
+	}
+}
+
+func TestClusterThrottlesAccountCreation(t *testing.T) {
+	cluster := StartTestCluster(t, func(serverConfig *config.ConfigObject) {
+		serverConfig.LoginServer.AccountCreation.PerAddress = 1
+	})
+
+	for i, username := range []string{"first", "second"} {
+		c := client.NewClient(username, cluster.Config.Client)
+		err := c.Login(username, "password")
+		c.Disconnect()
+
+		if i == 0 && err != nil {
+			t.Fatalf("Login(%s) error = %v", username, err)
+		}
+		if i == 1 && err == nil {
+			t.Fatalf("Login(%s) created a second account from the same address", username)
+		}
+	}
+
+	if stats := cluster.LoginServer.Stats(); stats.SuccessfulAccountCreation != 1 || stats.RefusedAccountCreation != 1 {
+		t.Errorf("stats = %+v, want one account created and one refused", stats)
+	}
+}
+
+func TestClusterAccountPolicy(t *testing.T) {
+	cluster := StartTestCluster(t, func(serverConfig *config.ConfigObject) {
+		serverConfig.LoginServer.AccountPolicy = config.AccountPolicyType{UsernameCharset: "a-z0-9", MinPasswordLength: 6, PasswordClasses: 2}
+	})
+
+	tests := []struct {
+		username string
+		password string
+		want     error
+	}{
+		{username: "bad_name", password: "secret42", want: client.ErrInvalidCredentials},
+		{username: "goodname", password: "weak", want: client.ErrInvalidCredentials},
+		{username: "goodname", password: "secret42"},
+	}
+
+	for _, tt := range tests {
+		c := client.NewClient("e2e", cluster.Config.Client)
+		err := c.Login(tt.username, tt.password)
+		c.Disconnect()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Login(%q, %q) error = %v, want %v", tt.username, tt.password, err, tt.want)
+		}
+	}
+
+	if stats := cluster.LoginServer.Stats(); stats.RefusedAccountCreation != 2 || stats.SuccessfulAccountCreation != 1 {
+		t.Errorf("stats = %+v, want 2 accounts refused by the policy", stats)
+	}
+}
+
+func TestClusterMaintenanceMode(t *testing.T) {
+	cluster := StartTestCluster(t, func(serverConfig *config.ConfigObject) {
+		serverConfig.LoginServer.Mode = loginserver.MODE_MAINTENANCE
+		serverConfig.LoginServer.AdminAuth = true
+	})
+	cluster.Seed(t, seed.Fixture{Accounts: []seed.Account{{Username: "admin", Password: "secret", AccessLevel: loginserver.ACCESS_LEVEL_ADMIN}}})
+
+	c := client.NewClient("e2e", cluster.Config.Client)
+	err := c.Login("e2euser", "e2epass")
+	c.Disconnect()
+	if !errors.Is(err, client.ErrServerMaintenance) {
+		t.Fatalf("Login() error = %v, want ErrServerMaintenance", err)
+	}
+
+	// Reopen the server through the admin API, which only an admin can write
+	modeURL := "http://" + cluster.LoginServer.AdminAddr().String() + "/mode"
+	setMode := func(mode string, authenticated bool) int {
+		request, _ := http.NewRequest(http.MethodPut, modeURL, strings.NewReader(`{"mode":"`+mode+`"}`))
+		if authenticated {
+			request.SetBasicAuth("admin", "secret")
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("PUT /mode error = %v", err)
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+	if status := setMode(loginserver.MODE_NORMAL, false); status != http.StatusUnauthorized {
+		t.Errorf("PUT /mode answered %d without credentials", status)
+	}
+	if status := setMode("closed", true); status != http.StatusBadRequest {
+		t.Errorf("PUT /mode answered %d to an invalid mode", status)
+	}
+	if status := setMode(loginserver.MODE_NORMAL, true); status != http.StatusOK {
+		t.Fatalf("PUT /mode answered %d", status)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, modeURL, nil)
+	request.SetBasicAuth("admin", "secret")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("GET /mode error = %v", err)
+	}
+	defer response.Body.Close()
+	var mode loginserver.ModeInfo
+	if err := json.NewDecoder(response.Body).Decode(&mode); err != nil || mode.Mode != loginserver.MODE_NORMAL {
+		t.Errorf("GET /mode = %+v, %v", mode, err)
+	}
+
+	c = client.NewClient("e2e", cluster.Config.Client)
+	defer c.Disconnect()
+	if err := c.Login("e2euser", "e2epass"); err != nil {
+		t.Fatalf("Login() error = %v once the server is reopened", err)
+	}
+}
+
+func TestClusterAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	cluster := StartTestCluster(t, func(serverConfig *config.ConfigObject) {
+		serverConfig.LoginServer.Audit.Path = path
+	})
+
+	c := client.NewClient("e2e", cluster.Config.Client)
+	defer c.Disconnect()
+	if err := c.Login("e2euser", "e2epass"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	response, err := http.Get("http://" + cluster.LoginServer.AdminAddr().String() + "/stats")
+	if err != nil {
+		t.Fatalf("GET /stats error = %v", err)
+	}
+	response.Body.Close()
+
+	// The events are recorded asynchronously
+	want := map[string]bool{"account.created e2euser": true, "admin.call /stats": true}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(want) > 0 {
+		data, _ := os.ReadFile(path)
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var entry audit.Entry
+			if json.Unmarshal([]byte(line), &entry) != nil {
+				continue
+			}
+			if entry.Address != "127.0.0.1" {
+				t.Errorf("entry %+v doesn't come from the local address", entry)
+			}
+			delete(want, entry.Action+" "+entry.Target)
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("entries %v not recorded in %s", want, data)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestClusterSessionExpiry(t *testing.T) {
+	cluster := StartTestCluster(t, func(serverConfig *config.ConfigObject) {
+		serverConfig.LoginServer.SessionLifetime = 200 * time.Millisecond
+	})
+
+	c := client.NewClient("e2e", cluster.Config.Client)
+	defer c.Disconnect()
+
+	if err := c.Login("e2euser", "e2epass"); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	servers := c.Sessions().LoginSession().ServerList
+	if len(servers) != 1 {
+		t.Fatalf("got %d servers, want 1", len(servers))
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if err := c.SelectServer(int(servers[0].ID)); !errors.Is(err, client.ErrAccountExpired) {
+		t.Fatalf("SelectServer() error = %v, want ErrAccountExpired", err)
+	}
+	if stats := cluster.LoginServer.Stats(); stats.ExpiredSessions != 1 {
+		t.Errorf("stats = %+v, want 1 expired session", stats)
+	}
+}
+
+func TestClusterGameGuard(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		gameGuard bool // The toolkit authenticates with GameGuard
+		err       error
+	}{
+		{name: "static", mode: loginserver.GAMEGUARD_STATIC, gameGuard: true},
+		{name: "static without GameGuard", mode: loginserver.GAMEGUARD_STATIC},
+		{name: "strict", mode: loginserver.GAMEGUARD_STRICT, gameGuard: true},
+		{name: "strict without GameGuard", mode: loginserver.GAMEGUARD_STRICT, err: client.ErrAccountBanned},
+		{name: "ignored", mode: loginserver.GAMEGUARD_IGNORE, gameGuard: true, err: client.ErrOperationTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := StartTestCluster(t, func(serverConfig *config.ConfigObject) {
+				serverConfig.LoginServer.GameGuard = tt.mode
+			})
+			clientConfig := cluster.Config.Client
+			clientConfig.Handshake = client.HandshakeConfig{GameGuard: tt.gameGuard, GGAuthTimeout: 200 * time.Millisecond}
+
+			c := client.NewClient("e2e", clientConfig)
+			defer c.Disconnect()
+			err := c.Login("e2euser", "e2epass")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestClusterCharacterSlots(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.MaxCharacters = 2
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if slots, err := c.CharacterSlotsLeft(); err != nil || slots != 2 {
+		t.Fatalf("CharacterSlotsLeft() = %d, %v, want 2", slots, err)
+	}
+
+	tests := []struct {
+		character string
+		want      error
+		slotsLeft int
+	}{
+		{character: "Tester", slotsLeft: 1},
+		{character: "Mystic", slotsLeft: 0},
+		{character: "Extra", want: client.ErrMaxCharactersReached, slotsLeft: 0},
+	}
+
+	for _, tt := range tests {
+		if err := c.CreateCharacter(tt.character, nil); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Fatalf("CreateCharacter(%q) error = %v, want %v", tt.character, err, tt.want)
+		}
+		if slots, _ := c.CharacterSlotsLeft(); slots != tt.slotsLeft {
+			t.Errorf("after %q, CharacterSlotsLeft() = %d, want %d", tt.character, slots, tt.slotsLeft)
+		}
+	}
+
+	characters, err := c.GetCharacterList()
+	if err != nil || len(characters) != 2 || characters[0].Name != "Tester" || characters[1].Name != "Mystic" {
+		t.Errorf("GetCharacterList() = %+v, %v, want Tester and Mystic", characters, err)
+	}
+}
+
+func TestClusterQuests(t *testing.T) {
+	dataPath := t.TempDir()
+	definitions := `[{"id": 1, "name": "Wolves", "startNpc": 30001, "steps": [
+		{"text": "Kill 2 wolves", "kill": 20120, "count": 2},
+		{"text": "Report to the guard", "npc": 30002}
+	], "reward": {"exp": 100, "adena": 50, "items": [{"itemId": 1864, "count": 3}]}}]`
+	if err := os.WriteFile(filepath.Join(dataPath, "quests.json"), []byte(definitions), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.DataDirectory = dataPath
+	})
+	elder := &models.Npc{TemplateID: 30001, Type: "folk", Name: "Elder"}
+	guard := &models.Npc{TemplateID: 30002, Type: "guard", Name: "Guard"}
+	cluster.GameServer.SpawnNpc(elder)
+	cluster.GameServer.SpawnNpc(guard)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	adena := player.Adena
+
+	states := func() []quests.State {
+		t.Helper()
+		states, err := cluster.GameServer.QuestStates(player)
+		if err != nil {
+			t.Fatalf("QuestStates() error = %v", err)
+		}
+		return states
+	}
+
+	steps := []struct {
+		name string
+		talk *models.Npc
+		kill *models.Npc
+		err  error
+		want []quests.State
+	}{
+		{name: "asking the guard", talk: guard, err: quests.ErrWrongNpc},
+		{name: "accepting", talk: elder, want: []quests.State{{QuestID: 1, Cond: 1}}},
+		{name: "reporting too early", talk: guard, err: quests.ErrWrongNpc, want: []quests.State{{QuestID: 1, Cond: 1}}},
+		{name: "killing a wolf", kill: &models.Npc{TemplateID: 20120}, want: []quests.State{{QuestID: 1, Cond: 1, Progress: 1}}},
+		{name: "killing a bear", kill: &models.Npc{TemplateID: 20121}, want: []quests.State{{QuestID: 1, Cond: 1, Progress: 1}}},
+		{name: "killing another wolf", kill: &models.Npc{TemplateID: 20120}, want: []quests.State{{QuestID: 1, Cond: 2}}},
+		{name: "reporting", talk: guard, want: []quests.State{{QuestID: 1, Cond: 2, Completed: true}}},
+		{name: "accepting again", talk: elder, err: quests.ErrQuestCompleted, want: []quests.State{{QuestID: 1, Cond: 2, Completed: true}}},
+	}
+
+	for _, step := range steps {
+		if step.talk != nil {
+			if err := cluster.GameServer.TalkQuest(player, step.talk, 1); !errors.Is(err, step.err) {
+				t.Fatalf("%s: TalkQuest() error = %v, want %v", step.name, err, step.err)
+			}
+		} else {
+			cluster.GameServer.DropLoot(step.kill, player)
+		}
+		if got := states(); !slices.Equal(got, step.want) {
+			t.Fatalf("%s: quests = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+
+	if player.Exp != 100 || player.Adena != adena+50 || player.Items[1864] != 3 {
+		t.Errorf("the player has %d exp, %d adena and %v", player.Exp, player.Adena, player.Items)
+	}
+
+	// An abandoned quest can be started again
+	if err := cluster.GameServer.AbortQuest(player, 1); err != nil {
+		t.Fatalf("AbortQuest() error = %v", err)
+	}
+	if err := cluster.GameServer.TalkQuest(player, elder, 1); err != nil {
+		t.Fatalf("TalkQuest() error = %v once abandoned", err)
+	}
+	if err := cluster.GameServer.TalkQuest(player, elder, 2); !errors.Is(err, quests.ErrUnknownQuest) {
+		t.Errorf("TalkQuest() error = %v, want %v", err, quests.ErrUnknownQuest)
+	}
+}
+
+func TestClusterQuestsPreAuth(t *testing.T) {
+	dataPath := t.TempDir()
+	definitions := `[{"id": 1, "name": "Wolves", "startNpc": 30001, "steps": [{"text": "Kill 2 wolves", "kill": 20120, "count": 2}]}]`
+	if err := os.WriteFile(filepath.Join(dataPath, "quests.json"), []byte(definitions), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.DataDirectory = dataPath
+		cfg.GameServers[0].Options.NullCrypto = true
+	})
+	elder := &models.Npc{TemplateID: 30001, Type: "folk", Name: "Elder"}
+	cluster.GameServer.SpawnNpc(elder)
+
+	// A connection which never authenticates asks the elder about its quest
+	_, player := dialPreAuth(t, cluster)
+	player.TargetID = elder.ObjectID
+
+	if err := cluster.GameServer.TalkQuest(player, elder, 1); !errors.Is(err, gameserver.ErrNotInWorld) {
+		t.Errorf("TalkQuest() error = %v before authenticating, want %v", err, gameserver.ErrNotInWorld)
+	}
+	if err := cluster.GameServer.AbortQuest(player, 1); !errors.Is(err, gameserver.ErrNotInWorld) {
+		t.Errorf("AbortQuest() error = %v before authenticating, want %v", err, gameserver.ErrNotInWorld)
+	}
+
+	// Nothing was started for the empty account
+	if states, err := cluster.GameServer.QuestStates(player); err != nil || len(states) != 0 {
+		t.Errorf("QuestStates() = %+v, %v, want none", states, err)
+	}
+}
+
+func TestClusterShop(t *testing.T) {
+	dataPath := t.TempDir()
+	lists := `[{"id": 1, "npcId": 30003, "goods": [
+		{"itemId": 1835, "price": 10, "weight": 5},
+		{"itemId": 1060, "price": 40, "weight": 10},
+		{"itemId": 1339, "price": 10, "weight": 2000},
+		{"itemId": 1864, "price": 200000, "weight": 1}
+	]}]`
+	if err := os.WriteFile(filepath.Join(dataPath, "shops.json"), []byte(lists), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.DataDirectory = dataPath
+		cfg.GameServers[0].Options.InventorySlots = 2
+		cfg.GameServers[0].Options.WeightLimit = 1000
+	})
+	merchant := &models.Npc{TemplateID: 30003, Type: shop.NPC_TYPE, Name: "Lector"}
+	cluster.GameServer.SpawnNpc(merchant)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+
+	if err := cluster.GameServer.BuyItems(player, 1, []shop.Order{{ItemID: 1835, Count: 1}}); !errors.Is(err, gameserver.ErrNotAtMerchant) {
+		t.Fatalf("BuyItems() away from the merchant error = %v, want %v", err, gameserver.ErrNotAtMerchant)
+	}
+	player.TargetID = merchant.ObjectID
+
+	steps := []struct {
+		name      string
+		sell      bool
+		order     shop.Order
+		err       error
+		wantAdena uint64
+		wantCount uint64 // Of the item 1835
+	}{
+		{name: "buying without the adena", order: shop.Order{ItemID: 1864, Count: 1}, err: gameserver.ErrNotEnoughAdena, wantAdena: gameserver.STARTING_ADENA},
+		{name: "buying too heavy", order: shop.Order{ItemID: 1339, Count: 1}, err: gameserver.ErrWeightLimit, wantAdena: gameserver.STARTING_ADENA},
+		{name: "buying goods not sold", order: shop.Order{ItemID: 57, Count: 1}, err: shop.ErrNotSold, wantAdena: gameserver.STARTING_ADENA},
+		{name: "buying", order: shop.Order{ItemID: 1835, Count: 20}, wantAdena: gameserver.STARTING_ADENA - 200, wantCount: 20},
+		{name: "buying without a slot", order: shop.Order{ItemID: 1060, Count: 1}, err: gameserver.ErrInventoryFull, wantAdena: gameserver.STARTING_ADENA - 200, wantCount: 20},
+		{name: "selling too many", sell: true, order: shop.Order{ItemID: 1835, Count: 21}, err: gameserver.ErrNotEnoughItems, wantAdena: gameserver.STARTING_ADENA - 200, wantCount: 20},
+		{name: "selling", sell: true, order: shop.Order{ItemID: 1835, Count: 20}, wantAdena: gameserver.STARTING_ADENA - 100},
+	}
+
+	for _, step := range steps {
+		var err error
+		if step.sell {
+			err = cluster.GameServer.SellItems(player, 1, []shop.Order{step.order})
+		} else {
+			err = cluster.GameServer.BuyItems(player, 1, []shop.Order{step.order})
+		}
+		if !errors.Is(err, step.err) {
+			t.Fatalf("%s: error = %v, want %v", step.name, err, step.err)
+		}
+		if player.Adena != step.wantAdena || player.Items[1835] != step.wantCount {
+			t.Fatalf("%s: the player has %d adena and %v", step.name, player.Adena, player.Items)
+		}
+	}
+
+	if _, ok := player.Items[1835]; ok {
+		t.Errorf("the items sold are still listed: %v", player.Items)
+	}
+}
+
+func TestClusterShopPreAuth(t *testing.T) {
+	dataPath := t.TempDir()
+	lists := `[{"id": 1, "npcId": 30003, "goods": [{"itemId": 1835, "price": 10, "weight": 5}]}]`
+	if err := os.WriteFile(filepath.Join(dataPath, "shops.json"), []byte(lists), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.DataDirectory = dataPath
+		cfg.GameServers[0].Options.NullCrypto = true
+	})
+	merchant := &models.Npc{TemplateID: 30003, Type: shop.NPC_TYPE, Name: "Lector"}
+	cluster.GameServer.SpawnNpc(merchant)
+
+	// A connection which never authenticates, yet has the starting adena and targets the merchant
+	_, player := dialPreAuth(t, cluster)
+	player.TargetID = merchant.ObjectID
+
+	order := []shop.Order{{ItemID: 1835, Count: 20}}
+	if err := cluster.GameServer.BuyItems(player, 1, order); !errors.Is(err, gameserver.ErrNotInWorld) {
+		t.Errorf("BuyItems() error = %v before authenticating, want %v", err, gameserver.ErrNotInWorld)
+	}
+	if err := cluster.GameServer.SellItems(player, 1, order); !errors.Is(err, gameserver.ErrNotInWorld) {
+		t.Errorf("SellItems() error = %v before authenticating, want %v", err, gameserver.ErrNotInWorld)
+	}
+	if player.Adena != gameserver.STARTING_ADENA || len(player.Items) != 0 {
+		t.Errorf("the connection has %d adena and the items %v", player.Adena, player.Items)
+	}
+}
+
+func TestClusterCapacity(t *testing.T) {
+	dataPath := t.TempDir()
+	files := map[string]string{
+		"shops.json":    `[{"id": 1, "npcId": 30003, "goods": [{"itemId": 1060, "price": 40, "weight": 10}, {"itemId": 1339, "price": 10, "weight": 300}]}]`,
+		"capacity.json": `[{"level": 1, "weight": 1000, "slots": 3}, {"level": 2, "weight": 2000, "slots": 4}]`,
+		"drops.json":    `[{"npcId": 20001, "drops": [{"itemId": 1339, "chance": 100, "min": 2, "max": 2}, {"itemId": 1060, "chance": 100, "min": 1, "max": 1}]}]`,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dataPath, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.DataDirectory = dataPath
+		cfg.GameServers[0].Options.AutoLoot = true
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	check := func(step string, load uint64, limit uint64, penalty int) {
+		t.Helper()
+		if got := cluster.GameServer.Load(player); got != load {
+			t.Errorf("%s: Load() = %d, want %d", step, got, load)
+		}
+		if got := cluster.GameServer.Limits(player).Weight; got != limit {
+			t.Errorf("%s: Limits() = %d weight, want %d", step, got, limit)
+		}
+		if player.WeightPenalty != penalty {
+			t.Errorf("%s: WeightPenalty = %d, want %d", step, player.WeightPenalty, penalty)
+		}
+	}
+
+	// The quest rewards are given whatever their weight
+	cluster.GameServer.GiveItem(player, 1339, 2)
+	check("rewarded", 600, 1000, 1)
+
+	// The auto-loot leaves on the ground what the killer can't carry
+	items := cluster.GameServer.DropLoot(&models.Npc{TemplateID: 20001}, player)
+	if len(items) != 1 || items[0].ItemID != 1339 || player.Items[1060] != 1 {
+		t.Fatalf("DropLoot() = %d items, the killer has %v", len(items), player.Items)
+	}
+	check("looted", 610, 1000, 1)
+
+	if err := cluster.GameServer.PickUp(player, items[0].ObjectID); !errors.Is(err, gameserver.ErrWeightLimit) {
+		t.Fatalf("PickUp() error = %v, want %v", err, gameserver.ErrWeightLimit)
+	}
+	if _, ok := cluster.GameServer.GroundItem(items[0].ObjectID); !ok {
+		t.Fatal("the item refused left the ground")
+	}
+
+	// Leveling up raises the limit
+	cluster.GameServer.AddExp(player, 100)
+	check("leveled up", 610, 2000, 0)
+	if err := cluster.GameServer.PickUp(player, items[0].ObjectID); err != nil {
+		t.Fatalf("PickUp() error = %v", err)
+	}
+	check("picked up", 1210, 2000, 1)
+
+	cluster.GameServer.GiveItem(player, 1339, 3)
+	check("overloaded", 2110, 2000, capacity.MAX_PENALTY)
+}
+
+func TestClusterWarehouse(t *testing.T) {
+	cluster := StartTestCluster(t)
+	keeper := &models.Npc{TemplateID: 30005, Type: warehouse.NPC_TYPE, Name: "Hagger"}
+	cluster.GameServer.SpawnNpc(keeper)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+
+	c := client.NewClient("e2e", config)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	cluster.GameServer.GiveItem(player, 1835, 20)
+
+	if err := cluster.GameServer.Deposit(player, false, []warehouse.Item{{ItemID: 1835, Count: 1}}); !errors.Is(err, gameserver.ErrNotAtWarehouse) {
+		t.Fatalf("Deposit() away from the keeper error = %v, want %v", err, gameserver.ErrNotAtWarehouse)
+	}
+	player.TargetID = keeper.ObjectID
+
+	stored := func(clan bool) map[int]uint64 {
+		t.Helper()
+		items, err := cluster.GameServer.Stored(player, clan)
+		if err != nil {
+			t.Fatalf("Stored() error = %v", err)
+		}
+		return items
+	}
+
+	steps := []struct {
+		name       string
+		withdraw   bool
+		items      []warehouse.Item
+		err        error
+		wantAdena  uint64
+		wantCount  uint64 // Of the item 1835 in the inventory
+		wantStored map[int]uint64
+	}{
+		{
+			name:       "depositing",
+			items:      []warehouse.Item{{ItemID: 57, Count: 1000}, {ItemID: 1835, Count: 20}},
+			wantAdena:  gameserver.STARTING_ADENA - 1000,
+			wantStored: map[int]uint64{57: 1000, 1835: 20},
+		},
+		{
+			name:       "depositing items already stored",
+			items:      []warehouse.Item{{ItemID: 1835, Count: 1}},
+			err:        warehouse.ErrNotEnoughItems,
+			wantAdena:  gameserver.STARTING_ADENA - 1000,
+			wantStored: map[int]uint64{57: 1000, 1835: 20},
+		},
+		{
+			name:       "withdrawing the items twice in a request",
+			withdraw:   true,
+			items:      []warehouse.Item{{ItemID: 1835, Count: 15}, {ItemID: 1835, Count: 15}},
+			err:        warehouse.ErrNotEnoughItems,
+			wantAdena:  gameserver.STARTING_ADENA - 1000,
+			wantStored: map[int]uint64{57: 1000, 1835: 20},
+		},
+		{
+			name:       "withdrawing",
+			withdraw:   true,
+			items:      []warehouse.Item{{ItemID: 1835, Count: 15}},
+			wantAdena:  gameserver.STARTING_ADENA - 1000,
+			wantCount:  15,
+			wantStored: map[int]uint64{57: 1000, 1835: 5},
+		},
+	}
+
+	for _, step := range steps {
+		var err error
+		if step.withdraw {
+			err = cluster.GameServer.Withdraw(player, false, step.items)
+		} else {
+			err = cluster.GameServer.Deposit(player, false, step.items)
+		}
+		if !errors.Is(err, step.err) {
+			t.Fatalf("%s: error = %v, want %v", step.name, err, step.err)
+		}
+		if player.Adena != step.wantAdena || player.Items[1835] != step.wantCount {
+			t.Fatalf("%s: the player has %d adena and %v", step.name, player.Adena, player.Items)
+		}
+		if got := stored(false); !maps.Equal(got, step.wantStored) {
+			t.Fatalf("%s: the warehouse keeps %v, want %v", step.name, got, step.wantStored)
+		}
+	}
+
+	// The clan warehouse is only open to the clan members
+	if err := cluster.GameServer.Deposit(player, true, []warehouse.Item{{ItemID: 1835, Count: 5}}); !errors.Is(err, warehouse.ErrNoClan) {
+		t.Fatalf("Deposit() without a clan error = %v, want %v", err, warehouse.ErrNoClan)
+	}
+	player.Clan = "knights"
+	if err := cluster.GameServer.Deposit(player, true, []warehouse.Item{{ItemID: 1835, Count: 5}}); err != nil {
+		t.Fatalf("Deposit() to the clan warehouse error = %v", err)
+	}
+
+	// Withdrawals racing for the same items never take more than stored
+	var wg sync.WaitGroup
+	var taken atomic.Int32
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if cluster.GameServer.Withdraw(player, true, []warehouse.Item{{ItemID: 1835, Count: 1}}) == nil {
+				taken.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if taken.Load() != 5 || player.Items[1835] != 15 || len(stored(true)) != 0 {
+		t.Fatalf("%d withdrawals succeeded, leaving %v and %v in the clan warehouse", taken.Load(), player.Items, stored(true))
+	}
+
+	// The inventory and the warehouse come back as they were left
+	c.Disconnect()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the player is still in the world")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	c = client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	player, ok = cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID + 1)
+	if !ok {
+		t.Fatal("the player isn't back in the world")
+	}
+	if player.Adena != gameserver.STARTING_ADENA-1000 || player.Items[1835] != 15 {
+		t.Errorf("the player came back with %d adena and %v", player.Adena, player.Items)
+	}
+	if got := stored(false); !maps.Equal(got, map[int]uint64{57: 1000, 1835: 5}) {
+		t.Errorf("the warehouse keeps %v once the player came back", got)
+	}
+}
+
+func TestClusterWarehousePreAuth(t *testing.T) {
+	cluster := StartTestCluster(t)
+	keeper := &models.Npc{TemplateID: 30005, Type: warehouse.NPC_TYPE, Name: "Hagger"}
+	cluster.GameServer.SpawnNpc(keeper)
+
+	// A connection which never authenticates, yet has the starting adena and targets the keeper
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(cluster.Config.Client.GameServerPort)))
+	if err != nil {
+		t.Fatalf("couldn't connect: %v", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	for ; !ok; player, ok = cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID) {
+		if time.Now().After(deadline) {
+			t.Fatal("the connection wasn't accepted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	player.TargetID = keeper.ObjectID
+
+	adena := []warehouse.Item{{ItemID: 57, Count: 1000}}
+	if err := cluster.GameServer.Deposit(player, false, adena); !errors.Is(err, gameserver.ErrNotInWorld) {
+		t.Errorf("Deposit() error = %v before authenticating, want %v", err, gameserver.ErrNotInWorld)
+	}
+	if err := cluster.GameServer.Withdraw(player, false, adena); !errors.Is(err, gameserver.ErrNotInWorld) {
+		t.Errorf("Withdraw() error = %v before authenticating, want %v", err, gameserver.ErrNotInWorld)
+	}
+	if _, err := cluster.GameServer.Stored(player, false); !errors.Is(err, gameserver.ErrNotInWorld) {
+		t.Errorf("Stored() error = %v before authenticating, want %v", err, gameserver.ErrNotInWorld)
+	}
+
+	// Neither the warehouse shared by the connections without an account nor a character of theirs was written
+	characters, warehouses, _ := cluster.GameServer.Storage()
+	if items, err := warehouses.Load(repository.WarehouseOwner{}); err != nil || len(items) != 0 {
+		t.Errorf("the warehouse without an owner keeps %v, %v", items, err)
+	}
+	if _, ok, err := characters.Load(""); ok || err != nil {
+		t.Errorf("a character without an account was written, %v", err)
+	}
+	if player.Adena != gameserver.STARTING_ADENA {
+		t.Errorf("the connection has %d adena", player.Adena)
+	}
+}
+
+func TestClusterPreAuthPackets(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.NullCrypto = true
+	})
+	merchant := &models.Npc{TemplateID: 30001, Name: "Lector"}
+	cluster.GameServer.SpawnNpc(merchant)
+
+	conn, player := dialPreAuth(t, cluster)
+
+	// Every packet following AuthLogin in the protocol, the click selecting the merchant included
+	sent := 0
+	for opcode := range opcodes.Names(opcodes.Game, opcodes.ClientToServer) {
+		if opcode == opcodes.GameClientProtocolVersion || opcode == opcodes.GameClientAuthLogin {
+			continue
+		}
+
+		payload := make([]byte, 64)
+		if opcode == opcodes.GameClientAction {
+			payload = append(binary.LittleEndian.AppendUint32(nil, merchant.ObjectID), make([]byte, 13)...)
+		}
+		writeGameFrame(t, conn, opcode, payload)
+		sent++
+	}
+
+	// Each of them is refused before being handled, however well formed it is
+	deadline := time.Now().Add(5 * time.Second)
+	for cluster.GameServer.Stats().HackAttempts < uint32(sent) {
+		if time.Now().After(deadline) {
+			t.Fatalf("HackAttempts = %d, want %d", cluster.GameServer.Stats().HackAttempts, sent)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := cluster.GameServer.Stats(); stats.HackAttempts != uint32(sent) {
+		t.Errorf("HackAttempts = %d, want %d", stats.HackAttempts, sent)
+	}
+
+	if player.TargetID != 0 {
+		t.Errorf("the connection targets %d", player.TargetID)
+	}
+	if player.Adena != gameserver.STARTING_ADENA || len(player.Items) != 0 {
+		t.Errorf("the connection has %d adena and the items %v", player.Adena, player.Items)
+	}
+	characters, _, _ := cluster.GameServer.Storage()
+	if _, ok, err := characters.Load(""); ok || err != nil {
+		t.Errorf("a character without an account was written, %v", err)
+	}
+}
+
+// dialPreAuth connects to the game server of a cluster in the null crypto mode, stopping after CryptInit,
+// and returns the connection along with the player the game server accepted it as
+func dialPreAuth(t *testing.T, cluster *Cluster) (net.Conn, *models.Client) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(cluster.Config.Client.GameServerPort)))
+	if err != nil {
+		t.Fatalf("couldn't connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	writeGameFrame(t, conn, opcodes.GameClientProtocolVersion, binary.LittleEndian.AppendUint32(nil, opcodes.GameProtocolRevision))
+	data, err := packets.ReadFrame(conn, packets.MaxFrameSize)
+	if err != nil {
+		t.Fatalf("no CryptInit received: %v", err)
+	}
+	if data[0] != opcodes.GameServerCryptInit {
+		t.Fatalf("expected the CryptInit opcode, got %#x", data[0])
+	}
+
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the connection wasn't accepted")
+	}
+	return conn, player
+}
+
+// writeGameFrame sends a packet in clear to a game server in the null crypto mode
+func writeGameFrame(t *testing.T, conn net.Conn, opcode byte, payload []byte) {
+	t.Helper()
+
+	packet := append([]byte{opcode}, payload...)
+	frame := append([]byte{byte(len(packet) + 2), byte((len(packet) + 2) >> 8)}, packet...)
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+}
+
+func TestClusterBotDetection(t *testing.T) {
+	patrol := &behavior.Move{DX: 300}
+
+	tests := []struct {
+		name      string
+		action    string
+		window    int
+		moves     int
+		kicked    int // Action after which the client is kicked, 0 for none
+		heuristic string
+		jailed    bool
+	}{
+		{"jailing a player walking a route", behavior.ACTION_JAIL, behavior.MIN_MOVES, behavior.MIN_MOVES, 0, "moves", true},
+		{"kicking a player acting too fast", behavior.ACTION_KICK, 0, 0, 11, "rate", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+				cfg.GameServers[0].Options.BotDetection = config.BotDetectionType{Enabled: true, Action: tt.action, Window: tt.window}
+			})
+			suspected := make(chan gameserver.BotSuspected, 4)
+			cluster.GameServer.OnBotSuspected(func(event gameserver.BotSuspected) {
+				suspected <- event
+			})
+
+			config := cluster.Config.Client
+			config.Username = "e2euser"
+			config.Password = "e2epass"
+
+			c := client.NewClient("e2e", config)
+			defer c.Disconnect()
+			if err := c.Connect(); err != nil {
+				t.Fatalf("Connect() error = %v", err)
+			}
+			player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+			if !ok {
+				t.Fatal("the player isn't in the world")
+			}
+
+			// Moves which all differ look human
+			for i := range behavior.MIN_MOVES {
+				if cluster.GameServer.ObserveAction(player, &behavior.Move{DX: int32(100 + 37*i), DY: int32(-20 * i)}) {
+					t.Fatal("ObserveAction() kicked a player moving around")
+				}
+			}
+			if score := player.Behavior.Verdict().Score; score != 0 {
+				t.Fatalf("a player moving around scored %.2f", score)
+			}
+			time.Sleep(time.Second)
+
+			for i := 1; i <= max(tt.moves, tt.kicked); i++ {
+				var move *behavior.Move
+				if i <= tt.moves {
+					move = patrol
+				}
+				if kicked := cluster.GameServer.ObserveAction(player, move); kicked != (i == tt.kicked) {
+					t.Fatalf("ObserveAction() #%d kicked = %t", i, kicked)
+				}
+			}
+
+			select {
+			case event := <-suspected:
+				if event.ObjectID != player.ObjectID || event.Account != "e2euser" || event.Heuristic != tt.heuristic || event.Action != tt.action || event.Score < 0.9 {
+					t.Errorf("BotSuspected = %+v", event)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the player was never suspected")
+			}
+
+			// A suspected player is only reported once
+			cluster.GameServer.ObserveAction(player, patrol)
+			select {
+			case event := <-suspected:
+				t.Errorf("the player was suspected again: %+v", event)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			if jailed := player.X == gameserver.JAIL_X && player.Y == gameserver.JAIL_Y; jailed != tt.jailed || player.Behavior.Jailed() != tt.jailed {
+				t.Errorf("the player stands at %d, %d, jailed = %t", player.X, player.Y, player.Behavior.Jailed())
+			}
+			if stats := cluster.GameServer.Stats(); stats.SuspectedBots != 1 {
+				t.Errorf("SuspectedBots = %d, want 1", stats.SuspectedBots)
+			}
+			if players := cluster.GameServer.Snapshot().Players; len(players) != 1 || players[0].BotScore < 0.9 {
+				t.Errorf("Snapshot().Players = %+v", players)
+			}
+		})
+	}
+}
+
+func TestClusterLoginServerBackends(t *testing.T) {
+	backends := []struct {
+		name     string
+		database func(t *testing.T) config.DatabaseType
+	}{
+		{name: "memory", database: func(t *testing.T) config.DatabaseType {
+			return config.DatabaseType{Driver: config.DATABASE_DRIVER_MEMORY}
+		}},
+		{name: "mysql", database: func(t *testing.T) config.DatabaseType { return MySQLFromEnv(t) }},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			database := backend.database(t)
+
+			// A MySQL database outlives the run, so every run logs in with accounts of its own
+			suffix := strconv.FormatInt(time.Now().UnixNano()%1e8, 36)
+			login := func(cluster *Cluster, username, password string) (*client.Client, error) {
+				c := client.NewClient("e2e", cluster.Config.Client)
+				t.Cleanup(func() { c.Disconnect() })
+				return c, c.Login(username, password)
+			}
+
+			t.Run("account auto-creation", func(t *testing.T) {
+				cluster := StartTestCluster(t, WithLoginDatabase(database))
+				username := "new" + suffix
+
+				if _, err := login(cluster, username, "secret"); err != nil {
+					t.Fatalf("Login() of a new account error = %v", err)
+				}
+				if _, err := login(cluster, username, "secret"); err != nil {
+					t.Fatalf("Login() of the created account error = %v", err)
+				}
+				if stats := cluster.LoginServer.Stats(); stats.SuccessfulAccountCreation != 1 || stats.SuccessfulLogins != 2 {
+					t.Errorf("stats = %+v, want the account created once and both logins counted", stats)
+				}
+			})
+
+			t.Run("wrong password", func(t *testing.T) {
+				cluster := StartTestCluster(t, WithLoginDatabase(database))
+				username := "pass" + suffix
+
+				if _, err := login(cluster, username, "secret"); err != nil {
+					t.Fatalf("Login() of a new account error = %v", err)
+				}
+				c, err := login(cluster, username, "guessed")
+				if !errors.Is(err, client.ErrInvalidCredentials) {
+					t.Errorf("Login() with the wrong password error = %v, want %v", err, client.ErrInvalidCredentials)
+				}
+				if state := c.GetState(); state != client.StateError {
+					t.Errorf("GetState() = %v, want %v", state, client.StateError)
+				}
+			})
+
+			t.Run("banned account", func(t *testing.T) {
+				// The accounts are created banned, the login creating them being refused as well
+				cluster := StartTestCluster(t, WithLoginDatabase(database), func(serverConfig *config.ConfigObject) {
+					serverConfig.LoginServer.AccountPolicy.AccessLevel = loginserver.ACCESS_LEVEL_BANNED
+				})
+				username := "ban" + suffix
+
+				if _, err := login(cluster, username, "secret"); !errors.Is(err, client.ErrAccountBanned) {
+					t.Fatalf("Login() creating a banned account error = %v, want %v", err, client.ErrAccountBanned)
+				}
+				if _, err := login(cluster, username, "secret"); !errors.Is(err, client.ErrAccountBanned) {
+					t.Errorf("Login() of a banned account error = %v, want %v", err, client.ErrAccountBanned)
+				}
+				if stats := cluster.LoginServer.Stats(); stats.SuccessfulAccountCreation != 1 || stats.SuccessfulLogins != 0 {
+					t.Errorf("stats = %+v, want the account created once and no login let in", stats)
+				}
+			})
+
+			t.Run("server list", func(t *testing.T) {
+				cluster := StartTestCluster(t, WithLoginDatabase(database))
+
+				c, err := login(cluster, "list"+suffix, "secret")
+				if err != nil {
+					t.Fatalf("Login() error = %v", err)
+				}
+				servers := c.Sessions().LoginSession().ServerList
+				if len(servers) != 1 {
+					t.Fatalf("got %d servers, want 1", len(servers))
+				}
+				gameServer := cluster.ServerConfig.GameServers[0]
+				if server := servers[0]; server.ID != int(gameServer.Id) || server.Port != gameServer.Port || server.Status != 1 || server.MaxPlayers != int(gameServer.Options.MaxPlayers) {
+					t.Errorf("ServerList[0] = %+v, want the game server %d up on port %d", server, gameServer.Id, gameServer.Port)
+				}
+
+				if err := c.SelectServer(servers[0].ID); err != nil {
+					t.Fatalf("SelectServer() error = %v", err)
+				}
+				if err := c.ConnectToGame(); err != nil {
+					t.Fatalf("ConnectToGame() error = %v", err)
+				}
+			})
+		})
+	}
+}
+
+func TestClusterSeed(t *testing.T) {
+	cluster := StartTestCluster(t)
+	fixture, err := seed.ReadFixture("../examples/seed-fixture.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := cluster.Seed(t, fixture); result.Accounts != 3 || result.Characters != 2 || result.Clans != 1 {
+		t.Fatalf("Seed() = %+v", result)
+	}
+
+	config := cluster.Config.Client
+	config.Username = "veteran"
+	config.Password = "wrongpass"
+	if err := client.NewClient("veteran", config).Connect(); !errors.Is(err, client.ErrInvalidCredentials) {
+		t.Fatalf("Connect() error = %v with a wrong password, want %v", err, client.ErrInvalidCredentials)
+	}
+
+	config.Password = "veteranpass"
+	c := client.NewClient("veteran", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if stats := cluster.LoginServer.Stats(); stats.SuccessfulAccountCreation != 0 {
+		t.Errorf("Stats() = %d accounts created, want the seeded one used", stats.SuccessfulAccountCreation)
+	}
+
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	if player.Level != 20 || player.Adena != 50000 || player.Items[1864] != 10 || player.HP != player.MaxHP {
+		t.Errorf("the player came in at level %d with %d adena, %v and %d of %d hp", player.Level, player.Adena, player.Items, player.HP, player.MaxHP)
+	}
+	if player.X != -84318 || player.Y != 244579 || player.Z != -3730 {
+		t.Errorf("the player came in at %d, %d, %d", player.X, player.Y, player.Z)
+	}
+	if friends, err := cluster.GameServer.Friends(player); err != nil || len(friends) != 1 || friends[0].Name != "rookie" {
+		t.Errorf("Friends() = %v, %v, want rookie", friends, err)
+	}
+
+	// The character comes in with its clan, opening the warehouse of the clan
+	if player.Clan != "knights" {
+		t.Fatalf("the player came in with clan %q, want knights", player.Clan)
+	}
+	if items, err := cluster.GameServer.Stored(player, true); err != nil || items[57] != 250000 {
+		t.Errorf("Stored() = %v, %v for the clan warehouse", items, err)
+	}
+}
+
+func TestClusterCharacterAppearance(t *testing.T) {
+	cluster := StartTestCluster(t)
+	fixture, err := seed.ReadFixture("../examples/seed-fixture.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster.Seed(t, fixture)
+
+	config := cluster.Config.Client
+	config.Username = "veteran"
+	config.Password = "veteranpass"
+	c := client.NewClient("veteran", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	// The appearance chosen at the creation comes back in the character list
+	template := &client.CharacterTemplate{Race: 0, Class: 0, Gender: 1, HairStyle: 3, HairColor: 2, Face: 1}
+	if err := c.CreateCharacter("Veteran", template); err != nil {
+		t.Fatalf("CreateCharacter() error = %v", err)
+	}
+	characters, err := c.GetCharacterList()
+	if err != nil || len(characters) != 1 {
+		t.Fatalf("GetCharacterList() = %+v, %v, want one character", characters, err)
+	}
+	character := characters[0]
+	if character.Gender != 1 || character.HairStyle != 3 || character.HairColor != 2 || character.Face != 1 {
+		t.Errorf("character = %+v, want the appearance it was created with", character)
+	}
+
+	// The rest comes from the seeded character of the account
+	if character.Level != 20 || character.Karma != 240 || character.LastAccess.IsZero() {
+		t.Errorf("character = %+v, want level 20, 240 karma and a last access", character)
+	}
+	want := make([]int, client.PaperdollSlots)
+	want[client.PaperdollRightHand], want[client.PaperdollChest], want[client.PaperdollLegs] = 2369, 1146, 1147
+	if !slices.Equal(character.Paperdoll, want) {
+		t.Errorf("Paperdoll = %v, want %v", character.Paperdoll, want)
+	}
+}
+
+func TestClusterAdminCommands(t *testing.T) {
+	cluster := StartTestCluster(t)
+	fixture, err := seed.ReadFixture("../examples/seed-fixture.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster.Seed(t, fixture)
+
+	// The access levels of the accounts reach the game server along with their sessions
+	var players []*models.Client
+	for i, account := range []struct{ username, password string }{{"gamemaster", "gamemasterpass"}, {"rookie", "rookiepass"}} {
+		config := cluster.Config.Client
+		config.Username = account.username
+		config.Password = account.password
+		c := client.NewClient(account.username, config)
+		defer c.Disconnect()
+		if err := c.Connect(); err != nil {
+			t.Fatalf("Connect() error = %v for %s", err, account.username)
+		}
+		player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID + uint32(i))
+		if !ok {
+			t.Fatalf("%s isn't in the world", account.username)
+		}
+		players = append(players, player)
+	}
+	gm, rookie := players[0], players[1]
+	if gm.AccessLevel != 2 || rookie.AccessLevel != 0 {
+		t.Fatalf("access levels = %d and %d, want 2 and 0", gm.AccessLevel, rookie.AccessLevel)
+	}
+
+	tests := []struct {
+		name    string
+		player  *models.Client
+		command string
+		want    error
+	}{
+		{name: "give", player: gm, command: "give_item 2369 2"},
+		{name: "give one", player: gm, command: "give_item 2369"},
+		{name: "give adena", player: gm, command: "give_item 57 1000"},
+		{name: "enchant", player: gm, command: "enchant 2369 16"},
+		{name: "enchant not owned", player: gm, command: "enchant 2370 16", want: gameserver.ErrItemNotOwned},
+		{name: "enchant too high", player: gm, command: "enchant 2369 70000", want: gameserver.ErrInvalidAdminCommand},
+		{name: "missing count", player: gm, command: "enchant 2369", want: gameserver.ErrInvalidAdminCommand},
+		{name: "not a number", player: gm, command: "give_item sword", want: gameserver.ErrInvalidAdminCommand},
+		{name: "unknown", player: gm, command: "kill", want: gameserver.ErrUnknownAdminCommand},
+		{name: "player", player: rookie, command: "give_item 2369 1", want: gameserver.ErrAdminCommandDenied},
+	}
+	for _, tt := range tests {
+		if err := cluster.GameServer.AdminCommand(tt.player, tt.command); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("%s: AdminCommand(%q) error = %v, want %v", tt.name, tt.command, err, tt.want)
+		}
+	}
+
+	if gm.Items[2369] != 3 || gm.Adena != gameserver.STARTING_ADENA+1000 || rookie.Items[2369] != 0 {
+		t.Errorf("the GM has %v and %d adena, the player %v", gm.Items, gm.Adena, rookie.Items)
+	}
+	if level := cluster.GameServer.EnchantLevel(gm, 2369); level != 16 {
+		t.Errorf("EnchantLevel() = %d, want 16", level)
+	}
+}
+
+func TestClusterPagedLists(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		// Two servers and three items a page
+		cfg.LoginServer.ListPacketSize = 64
+		cfg.GameServers[0].Options.ListPacketSize = 64
+		for id := uint8(2); id <= 5; id++ {
+			server := cfg.GameServers[0]
+			server.Id, server.Name, server.Secret, server.Port = id, fmt.Sprintf("Server %d", id), fmt.Sprintf("secret-%d", id), 1
+			cfg.GameServers = append(cfg.GameServers, server)
+		}
+	})
+	fixture, err := seed.ReadFixture("../examples/seed-fixture.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster.Seed(t, fixture)
+
+	config := cluster.Config.Client
+	config.Username = "veteran"
+	config.Password = "veteranpass"
+	c := client.NewClient("veteran", config)
+	defer c.Disconnect()
+
+	// The item list sent on entering the world is skipped by the toolkit, but seen raw
+	var flags []byte
+	items := make(map[int]uint64)
+	c.OnRawPacket(func(packet client.RawPacket) {
+		if packet.Protocol != opcodes.Game || packet.Direction != opcodes.ServerToClient || packet.Opcode != opcodes.GameServerItemList {
+			return
+		}
+		reader := packets.NewReader(packet.Payload)
+		flags = append(flags, reader.ReadUInt8())
+		for range reader.ReadUInt16() {
+			itemID := int(reader.ReadUInt32())
+			items[itemID] += reader.ReadUInt64()
+		}
+	})
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+
+	servers := c.Sessions().LoginSession().ServerList
+	if len(servers) != 5 || servers[0].ID != 1 || servers[4].ID != 5 {
+		t.Errorf("ServerList = %+v, want the 5 servers of the 3 pages", servers)
+	}
+
+	want := maps.Clone(player.Items)
+	want[serverpackets.ADENA_ID] = player.Adena
+	if !slices.Equal(flags, []byte{packets.MorePages, packets.LastPage}) || !maps.Equal(items, want) {
+		t.Errorf("ItemList pages flagged %v with %v, want 2 pages with %v", flags, items, want)
+	}
+}
+
+func TestClusterSocialActions(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+
+	tests := []struct {
+		name    string
+		run     func() error
+		sitting bool
+		want    error
+	}{
+		{name: "bow", run: func() error { return cluster.GameServer.SocialAction(player, client.EmoteBow) }},
+		{name: "sit", run: func() error { return cluster.GameServer.UseAction(player, gameserver.ACTION_SIT_STAND) }, sitting: true},
+		{name: "dance sitting", run: func() error { return cluster.GameServer.SocialAction(player, client.EmoteDance) }, sitting: true, want: gameserver.ErrSitting},
+		{name: "stand", run: func() error { return cluster.GameServer.UseAction(player, gameserver.ACTION_SIT_STAND) }},
+		{name: "dance", run: func() error { return cluster.GameServer.SocialAction(player, client.EmoteDance) }},
+		{name: "level up", run: func() error { return cluster.GameServer.SocialAction(player, 15) }, want: gameserver.ErrInvalidSocialAction},
+		{name: "unsupported action", run: func() error { return cluster.GameServer.UseAction(player, 1000) }, want: gameserver.ErrUnsupportedAction},
+	}
+	for _, tt := range tests {
+		if err := tt.run(); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+		if player.Sitting != tt.sitting {
+			t.Errorf("%s: Sitting = %t, want %t", tt.name, player.Sitting, tt.sitting)
+		}
+	}
+
+	// Going back to the character selection stands the player up
+	cluster.GameServer.SitStand(player)
+	if err := cluster.GameServer.Restart(player); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	if player.Sitting {
+		t.Error("the player still sits after a restart")
+	}
+}
+
+func TestClusterSpectators(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	// alice acts in the world, bob and carol only watch it
+	var spectators []*client.Client
+	for _, username := range []string{"alice", "bob", "carol"} {
+		config := cluster.Config.Client
+		config.Username = username
+		config.Password = "e2epass"
+		config.Spectator = username != "alice"
+
+		c := client.NewClient(username, config)
+		defer c.Disconnect()
+		if err := c.Connect(); err != nil {
+			t.Fatalf("Connect() error = %v", err)
+		}
+		if !config.Spectator {
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go c.Spectate(ctx)
+		spectators = append(spectators, c)
+	}
+	alice, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("alice isn't in the world")
+	}
+
+	if err := spectators[0].Emote(client.EmoteBow); !errors.Is(err, client.ErrSpectator) {
+		t.Errorf("Emote() error = %v for a spectator, want %v", err, client.ErrSpectator)
+	}
+
+	if err := cluster.GameServer.SocialAction(alice, client.EmoteBow); err != nil {
+		t.Fatalf("SocialAction() error = %v", err)
+	}
+	cluster.GameServer.SitStand(alice)
+
+	broadcasts := []struct {
+		name  string
+		match func(event client.WorldEvent) bool
+	}{
+		{name: "bow", match: func(event client.WorldEvent) bool {
+			return event.Kind == client.WorldSocial && event.ObjectID == int(alice.ObjectID) && event.Value == client.EmoteBow
+		}},
+		{name: "sit", match: func(event client.WorldEvent) bool {
+			return event.Kind == client.WorldPosture && event.ObjectID == int(alice.ObjectID) && event.State == "sitting"
+		}},
+	}
+	for _, broadcast := range broadcasts {
+		missed := client.Unwitnessed(spectators, broadcast.match)
+		for deadline := time.Now().Add(2 * time.Second); len(missed) > 0 && time.Now().Before(deadline); {
+			time.Sleep(10 * time.Millisecond)
+			missed = client.Unwitnessed(spectators, broadcast.match)
+		}
+		if len(missed) > 0 {
+			t.Errorf("%s: %v didn't witness it", broadcast.name, missed)
+		}
+	}
+}
+
+func TestClusterBroadcastCheck(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	config := cluster.Config.Client
+	config.Username = "broadcast"
+	config.Password = "e2epass"
+
+	// The clients of the game server of L2Go stay at the character selection, the actor acting
+	// through the game server instead. It enters the world after the observers.
+	const observers = 2
+	act := func(actor client.GameClient, action string, round int) (func(client.WorldEvent) bool, error) {
+		player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID + observers)
+		if !ok {
+			return nil, fmt.Errorf("the actor isn't in the world")
+		}
+
+		switch action {
+		case "bow":
+			if err := cluster.GameServer.SocialAction(player, client.EmoteBow); err != nil {
+				return nil, err
+			}
+			return func(event client.WorldEvent) bool {
+				return event.Kind == client.WorldSocial && event.ObjectID == int(player.ObjectID)
+			}, nil
+		case loadtest.BROADCAST_MOVE:
+			origin := movement.Position{X: player.X, Y: player.Y, Z: player.Z}
+			destination := movement.Position{X: player.X + 20, Y: player.Y, Z: player.Z}
+			cluster.GameServer.MoveTo(player, origin, destination)
+			return func(event client.WorldEvent) bool {
+				return event.Kind == client.WorldMove && event.ObjectID == int(player.ObjectID) &&
+					event.Location != nil && event.Location.X == int(destination.X)
+			}, nil
+		}
+		return nil, fmt.Errorf("unknown action: %s", action)
+	}
+
+	check := &loadtest.BroadcastCheck{
+		Client:    config,
+		Observers: observers,
+		Actions:   []string{"bow", loadtest.BROADCAST_MOVE},
+		Rounds:    2,
+		Latency:   2 * time.Second,
+		Act:       act,
+	}
+	result, err := check.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !result.Consistent || result.Deliveries != 8 {
+		t.Errorf("Consistent = %v, Deliveries = %d, Actions = %+v, want 8 deliveries within the bound", result.Consistent, result.Deliveries, result.Actions)
+	}
+}
+
+func TestClusterGameTime(t *testing.T) {
+	// An hour of the world passes every second, the sun rising a few seconds after the start
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.GameTimeSpeed = 3600
+		cfg.GameServers[0].Options.GameTimeEpoch = time.Now().Add(-3 * time.Second).Unix()
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	// The clock of the player is set when it enters the world, at night
+	state := c.Sessions().GameSession().GameState
+	if state.ServerTime < 3*60 || state.ServerTime >= 6*60 || !state.IsNight {
+		t.Fatalf("ServerTime = %d and IsNight = %t, want a time before the sunrise", state.ServerTime, state.IsNight)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for cluster.GameServer.GameTime().IsNight(time.Now()) {
+		if time.Now().After(deadline) {
+			t.Fatal("the sun never rose")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	time.Sleep(gameserver.SUN_CHECK_INTERVAL + 100*time.Millisecond)
+
+	// The client reads the sunrise along with the answer it waits for
+	if err := c.CreateCharacter("Tester", nil); err != nil {
+		t.Fatalf("CreateCharacter() error = %v", err)
+	}
+	if state := c.Sessions().GameSession().GameState; state.ServerTime < 6*60 || state.IsNight {
+		t.Errorf("ServerTime = %d and IsNight = %t after the sunrise", state.ServerTime, state.IsNight)
+	}
+}
+
+func TestClusterRegeneration(t *testing.T) {
+	cluster := StartTestCluster(t, func(cfg *config.ConfigObject) {
+		cfg.GameServers[0].Options.TicksPerSecond = 50
+	})
+
+	config := cluster.Config.Client
+	config.Username = "e2euser"
+	config.Password = "e2epass"
+	c := client.NewClient("e2e", config)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	player, ok := cluster.GameServer.Player(gameserver.FIRST_PLAYER_OBJECT_ID)
+	if !ok {
+		t.Fatal("the player isn't in the world")
+	}
+	hp := func() int {
+		for _, snapshot := range cluster.GameServer.Snapshot().Players {
+			if snapshot.ObjectID == player.ObjectID {
+				return snapshot.HP
+			}
+		}
+		t.Fatal("the player isn't in the snapshot")
+		return 0
+	}
+
+	poison := effects.Effect{SkillID: 4035, Level: 1, Kind: effects.DEBUFF, Duration: 100 * time.Millisecond, Tick: 40 * time.Millisecond, TickHP: -30}
+	if err := cluster.GameServer.AddEffect(player, poison); err != nil {
+		t.Fatalf("AddEffect() error = %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(player.Effects.Active()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the effect never expired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A human fighter regains a HP every second standing, the first one once a whole second
+	// went by since the wound
+	wounded := hp()
+	time.Sleep(3*gameserver.REGEN_INTERVAL + 500*time.Millisecond)
+	if healed := hp(); healed < wounded+2 || healed > gameserver.STARTING_HP {
+		t.Fatalf("HP = %d %v after being wounded to %d", healed, 3*gameserver.REGEN_INTERVAL+500*time.Millisecond, wounded)
+	}
+
+	// Being attacked stops the regeneration
+	cluster.GameServer.EnterCombat(player)
+	fighting := hp()
+	time.Sleep(2 * gameserver.REGEN_INTERVAL)
+	if current := hp(); current != fighting {
+		t.Errorf("HP = %d in combat, went from %d", current, fighting)
+	}
+}
+
+func TestClusterHandlerTimes(t *testing.T) {
+	cluster := StartTestCluster(t)
+
+	c := client.NewClient("e2e", cluster.Config.Client)
+	defer c.Disconnect()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+
+	for _, server := range []struct {
+		name   string
+		stats  func() packettiming.Stats
+		opcode string
+	}{
+		{name: "login server", stats: cluster.LoginServer.HandlerStats, opcode: "RequestAuthLogin"},
+		{name: "game server", stats: cluster.GameServer.HandlerStats, opcode: "AuthLogin"},
+	} {
+		// The client may get the answer to its last packet before the handler is timed
+		find := func() (packettiming.OpcodeStats, bool) {
+			stats := server.stats()
+			i := slices.IndexFunc(stats.Opcodes, func(o packettiming.OpcodeStats) bool { return o.Name == server.opcode })
+			if i < 0 {
+				return packettiming.OpcodeStats{}, false
+			}
+			return stats.Opcodes[i], true
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		opcode, ok := find()
+		for ; !ok && time.Now().Before(deadline); opcode, ok = find() {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if !ok {
+			t.Errorf("the %s didn't time %s", server.name, server.opcode)
+		} else if opcode.Count != 1 || opcode.Total <= 0 || opcode.Max != opcode.Total {
+			t.Errorf("the %s timed %s as %+v", server.name, server.opcode, opcode)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package testserver
+
+import (
+	"net"
+
+	"github.com/frostwind/l2go/packets"
+)
+
+// readFrame reads a single length prefixed packet from the connection
+func readFrame(conn net.Conn) ([]byte, error) {
+	return packets.ReadFrame(conn, packets.MaxFrameSize)
+}
+
+// writeFrame writes data prefixed with the packet length
+func writeFrame(conn net.Conn, data []byte) error {
+	writer := packets.NewPacketWriter()
+	defer writer.Release()
+
+	frame, err := writer.B(data).Finalize()
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Write(frame)
+	return err
+}
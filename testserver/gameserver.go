@@ -0,0 +1,1113 @@
+// Package testserver provides lightweight stand-ins for the login and game
+// servers so the client toolkit can be tested without a database or the
+// full server implementations.
+package testserver
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/crypt/xor"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// DefaultXORKey is the key sent to clients in the CryptInit packet
+var DefaultXORKey = []byte{0x94, 0x35, 0x00, 0x00, 0xa1, 0x6c, 0x54, 0x87}
+
+// Character is a character advertised by the stub game server
+type Character struct {
+	ObjectID uint32
+	Name     string
+	Race     uint32
+	Sex      uint32
+	ClassID  uint32
+	Level    uint32
+	X, Y, Z  int32
+
+	// Shown on the character selection only
+	Karma      uint32
+	HairStyle  uint32
+	HairColor  uint32
+	Face       uint32
+	Paperdoll  [PaperdollSlots]uint32 // Item ids worn, by paperdoll slot
+	LastAccess time.Time              // Sent as 0 when zero
+}
+
+// PaperdollSlots is the count of the paperdoll slots listed by CharList
+const PaperdollSlots = 16
+
+// NPC is a non playable character clients can talk to
+type NPC struct {
+	ObjectID  uint32
+	HTML      string              // Dialog opened when talking to the NPC
+	Bypasses  map[string]string   // Dialogs answered to the bypass commands
+	Teleports map[string]Location // Destinations of the bypass commands teleporting the character
+	Goods     map[uint32]uint64   // Prices of the items sold by a merchant, which buys them back for half
+	Weights   map[uint32]uint32   // Weights of a single item sold by a merchant, nothing when not listed
+	Warehouse bool                // Keeps the items of the private warehouses, the characters having no clan
+}
+
+// Location is a point of the world
+type Location struct {
+	X, Y, Z int32
+}
+
+// Fingerprint is how a client showed itself to the stub game server
+type Fingerprint struct {
+	Revision uint32
+	Language int    // Sent after the keys of AuthLogin, -1 when the client sent none
+	Opcodes  []byte // Packets sent once encrypted, in order
+}
+
+// GameServer is a fake game server implementing the key exchange, the
+// character list, entering the world, broadcasting the chat messages and the
+// moves to every character in the world, the sit/stand
+// and walk/run actions, the shortcut registration, talking to NPCs and
+// teleporting, going back to the character selection and logging out, along
+// with the friend lists and the whispers between the characters in the world,
+// quests moving a step further every time an NPC is asked about them,
+// merchants trading their goods, their object id being the id of their buy list,
+// within the weight limit of the characters, warehouse keepers, and the //give_item and //enchant commands of the GMs
+type GameServer struct {
+	// ProtocolVersion is the minimum protocol revision accepted
+	ProtocolVersion uint32
+
+	// DelayLogout holds the LogoutOk answer back, to simulate a slow logout
+	DelayLogout time.Duration
+
+	// Adena is what the characters enter the world with
+	Adena uint64
+
+	// NullCrypto announces the null crypto debug mode in CryptInit, the packets then going in clear
+	NullCrypto bool
+
+	// GM lets the characters run the admin commands, which are refused otherwise
+	GM bool
+
+	// GameTime is the minutes of the world ClientSetTime sends to the characters entering it,
+	// followed by SunSet before 6 in the morning
+	GameTime uint32
+
+	// WeightLimit is the weight of the goods a character can buy, told along with what it
+	// carries by StatusUpdate, no limit when 0
+	WeightLimit uint64
+
+	// ItemsPerPage splits the item lists and the warehouse lists into pages of that many items, one page when 0
+	ItemsPerPage int
+
+	listener     net.Listener
+	characters   []Character
+	fingerprints []*Fingerprint
+	npcs         map[uint32]NPC
+	online       map[string]presence       // Characters in the world, by lowercased name
+	world        map[*gameSession]struct{} // Sessions in the world, whatever their character
+	friends      map[string][]string       // Friends of the characters, by lowercased name
+	conns        map[net.Conn]struct{}
+	wg           sync.WaitGroup
+	mu           sync.Mutex
+}
+
+// NewGameServer creates a stub game server advertising the given characters
+func NewGameServer(characters ...Character) *GameServer {
+	return &GameServer{
+		ProtocolVersion: opcodes.GameProtocolRevision,
+		characters:      characters,
+		npcs:            make(map[uint32]NPC),
+		online:          make(map[string]presence),
+		world:           make(map[*gameSession]struct{}),
+		friends:         make(map[string][]string),
+		conns:           make(map[net.Conn]struct{}),
+	}
+}
+
+// Fingerprints returns how the clients showed themselves, in the order they connected
+func (s *GameServer) Fingerprints() []Fingerprint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fingerprints := make([]Fingerprint, len(s.fingerprints))
+	for i, fingerprint := range s.fingerprints {
+		fingerprints[i] = *fingerprint
+		fingerprints[i].Opcodes = slices.Clone(fingerprint.Opcodes)
+	}
+	return fingerprints
+}
+
+// AddNPC places an NPC in the world
+func (s *GameServer) AddNPC(npc NPC) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.npcs[npc.ObjectID] = npc
+}
+
+func (s *GameServer) npc(objectID uint32) (NPC, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	npc, ok := s.npcs[objectID]
+	return npc, ok
+}
+
+// Start listens on an ephemeral loopback port and serves clients in the background
+func (s *GameServer) Start() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to start stub game server: %w", err)
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *GameServer) Addr() *net.TCPAddr {
+	return s.listener.Addr().(*net.TCPAddr)
+}
+
+// Close stops the server and drops all connected clients
+func (s *GameServer) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *GameServer) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.drop(conn)
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *GameServer) drop(conn net.Conn) {
+	conn.Close()
+
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+}
+
+// gameSession holds the per connection state of the stub game server
+type gameSession struct {
+	conn        net.Conn
+	inputKey    []byte
+	outputKey   []byte
+	fingerprint *Fingerprint // Guarded by the server
+	selected    *Character
+	sitting     bool
+	walking     bool
+	target      uint32
+	invitedBy   string      // Character asking this one to be its friend, guarded by the server
+	quests      map[int]int // Steps reached by the character in its quests, by quest id
+	adena       uint64
+	items       map[uint32]uint64 // Counts of the items of the character other than the adena, by item id
+	warehouse   map[uint32]uint64 // Counts of the items of its private warehouse, the adena included
+	load        uint64            // Weight of the goods the character bought
+	sendMu      sync.Mutex
+}
+
+// presence is a character in the world, along with the session it plays in
+type presence struct {
+	session   *gameSession
+	character *Character
+}
+
+func (gs *gameSession) receive() (byte, []byte, error) {
+	data, err := readFrame(gs.conn)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if gs.inputKey != nil {
+		xor.Decrypt(data, gs.inputKey)
+	}
+
+	return data[0], data[1:], nil
+}
+
+func (gs *gameSession) send(data []byte) error {
+	// The other sessions send the friend invites and the whispers
+	gs.sendMu.Lock()
+	defer gs.sendMu.Unlock()
+
+	if gs.outputKey != nil {
+		xor.Encrypt(data, gs.outputKey)
+	}
+
+	return writeFrame(gs.conn, data)
+}
+
+func (s *GameServer) handle(conn net.Conn) {
+	session := &gameSession{conn: conn}
+	defer s.leave(session)
+
+	// Protocol version, sent in clear text
+	opcode, data, err := session.receive()
+	if err != nil || opcode != opcodes.GameClientProtocolVersion {
+		return
+	}
+
+	revision := packets.NewReader(data).ReadUInt32()
+	if revision < s.ProtocolVersion {
+		return
+	}
+
+	session.fingerprint = &Fingerprint{Revision: revision, Language: -1}
+	s.mu.Lock()
+	s.fingerprints = append(s.fingerprints, session.fingerprint)
+	s.mu.Unlock()
+
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerCryptInit)
+	buffer.WriteByte(0x01)
+	buffer.Write(DefaultXORKey)
+	if s.NullCrypto {
+		buffer.WriteUInt32(opcodes.NullCryptoMarker)
+	}
+
+	if err := session.send(buffer.Bytes()); err != nil {
+		return
+	}
+
+	if !s.NullCrypto {
+		session.inputKey = append([]byte(nil), DefaultXORKey...)
+		session.outputKey = append([]byte(nil), DefaultXORKey...)
+	}
+
+	for {
+		opcode, data, err := session.receive()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		session.fingerprint.Opcodes = append(session.fingerprint.Opcodes, opcode)
+		s.mu.Unlock()
+
+		var reply []byte
+
+		switch opcode {
+		case opcodes.GameClientAuthLogin:
+			reader := packets.NewReader(data)
+			reader.ReadString()
+			reader.ReadBytes(16)
+			if reader.Len() >= 4 {
+				s.mu.Lock()
+				session.fingerprint.Language = int(reader.ReadUInt32())
+				s.mu.Unlock()
+			}
+			reply = s.charListPacket()
+
+		case opcodes.GameClientCharacterSelected:
+			slot := packets.NewReader(data).ReadUInt32()
+			if int(slot) >= len(s.characters) {
+				return
+			}
+			session.selected = &s.characters[slot]
+			reply = charSelectedPacket(session.selected)
+
+		case opcodes.GameClientEnterWorld:
+			if session.selected == nil {
+				return
+			}
+			s.enter(session)
+			session.adena, session.items, session.warehouse, session.load = s.Adena, make(map[uint32]uint64), make(map[uint32]uint64), 0
+			if err := session.send(clientSetTimePacket(s.GameTime)); err != nil {
+				return
+			}
+			if s.GameTime%(24*60) < 6*60 {
+				if err := session.send([]byte{opcodes.GameServerSunSet}); err != nil {
+					return
+				}
+			}
+			if s.WeightLimit > 0 {
+				if err := session.send(loadPacket(session.selected, 0, s.WeightLimit)); err != nil {
+					return
+				}
+			}
+			reply = userInfoPacket(session.selected)
+
+		case opcodes.GameClientSay2:
+			if session.selected == nil {
+				return
+			}
+			reader := packets.NewReader(data)
+			text := reader.ReadString()
+			chatType := reader.ReadUInt32()
+			if chatType != chatTell {
+				reply = creatureSayPacket(session.selected.ObjectID, chatType, session.selected.Name, text)
+				s.broadcast(session, reply)
+				break
+			}
+
+			name := reader.ReadString()
+			other, ok := s.presence(name)
+			if !ok {
+				reply = systemMessagePacket(systemMessageNotLoggedIn, name)
+				break
+			}
+			other.session.send(creatureSayPacket(session.selected.ObjectID, chatTell, session.selected.Name, text))
+			reply = creatureSayPacket(session.selected.ObjectID, chatTell, "->"+other.character.Name, text)
+
+		case opcodes.GameClientMoveBackwardToLocation:
+			if session.selected == nil {
+				return
+			}
+			reader := packets.NewReader(data)
+			buffer := packets.NewBuffer()
+			buffer.WriteByte(opcodes.GameServerMoveToLocation)
+			buffer.WriteUInt32(session.selected.ObjectID)
+			buffer.Write(reader.ReadBytes(24)) // Destination then origin
+			reply = buffer.Bytes()
+			s.broadcast(session, reply)
+
+		case opcodes.GameClientRequestFriendInvite:
+			if session.selected == nil {
+				return
+			}
+			name := packets.NewReader(data).ReadString()
+			other, ok := s.presence(name)
+			if !ok {
+				reply = systemMessagePacket(systemMessageNotLoggedIn, name)
+				break
+			}
+			s.mu.Lock()
+			other.session.invitedBy = session.selected.Name
+			s.mu.Unlock()
+			other.session.send(askJoinFriendPacket(session.selected.Name))
+			continue
+
+		case opcodes.GameClientRequestAnswerFriend:
+			if session.selected == nil {
+				return
+			}
+			s.mu.Lock()
+			inviter := session.invitedBy
+			session.invitedBy = ""
+			s.mu.Unlock()
+
+			other, ok := s.presence(inviter)
+			if packets.NewReader(data).ReadUInt32() != 1 || !ok {
+				continue
+			}
+			s.befriend(other.character.Name, session.selected.Name)
+			other.session.send(s.friendListPacket(other.character.Name))
+			reply = s.friendListPacket(session.selected.Name)
+
+		case opcodes.GameClientRequestFriendList:
+			if session.selected == nil {
+				return
+			}
+			reply = s.friendListPacket(session.selected.Name)
+
+		case opcodes.GameClientRequestFriendDel:
+			if session.selected == nil {
+				return
+			}
+			s.unfriend(session.selected.Name, packets.NewReader(data).ReadString())
+			reply = s.friendListPacket(session.selected.Name)
+
+		case opcodes.GameClientRequestQuestList:
+			if session.selected == nil {
+				return
+			}
+			reply = questListPacket(session.quests)
+
+		case opcodes.GameClientRequestQuestAbort:
+			if session.selected == nil {
+				return
+			}
+			delete(session.quests, int(packets.NewReader(data).ReadUInt32()))
+			reply = questListPacket(session.quests)
+
+		case opcodes.GameClientRequestBuyItem, opcodes.GameClientRequestSellItem:
+			npc, ok := s.npc(session.target)
+			if session.selected == nil || !ok || npc.Goods == nil {
+				continue
+			}
+			reader := packets.NewReader(data)
+			if reader.ReadUInt32() != npc.ObjectID || reader.ReadUInt32() != 1 {
+				continue
+			}
+			itemID, count := reader.ReadUInt32(), reader.ReadUInt64()
+			price, sold := npc.Goods[itemID]
+			weight := uint64(npc.Weights[itemID]) * count
+			switch {
+			case !sold:
+				continue
+			case opcode == opcodes.GameClientRequestBuyItem && session.adena < price*count:
+				reply = systemMessagePacket(279) // Not enough adena
+			case opcode == opcodes.GameClientRequestBuyItem && s.WeightLimit > 0 && session.load+weight > s.WeightLimit:
+				reply = systemMessagePacket(422) // Weight limit exceeded
+			case opcode == opcodes.GameClientRequestBuyItem:
+				session.adena -= price * count
+				session.items[itemID] += count
+				session.load += weight
+				reply = s.itemListPacket(session)
+			case session.items[itemID] < count:
+				reply = systemMessagePacket(351) // Incorrect item count
+			default:
+				session.items[itemID] -= count
+				session.adena += price / 2 * count
+				session.load -= min(weight, session.load)
+				reply = s.itemListPacket(session)
+			}
+			if s.WeightLimit > 0 && reply[0] == opcodes.GameServerItemList {
+				if err := session.send(loadPacket(session.selected, session.load, s.WeightLimit)); err != nil {
+					return
+				}
+			}
+
+		case opcodes.GameClientSendWarehouseDeposit, opcodes.GameClientSendWarehouseWithdraw:
+			npc, ok := s.npc(session.target)
+			if session.selected == nil || !ok || !npc.Warehouse {
+				continue
+			}
+			reader := packets.NewReader(data)
+			if reader.ReadUInt32() != 1 { // The clan warehouse
+				reply = systemMessagePacket(212) // Not a clan member
+				break
+			}
+			if reader.ReadUInt32() != 1 {
+				continue
+			}
+			itemID, count := reader.ReadUInt32(), reader.ReadUInt64()
+			inventory := maps.Clone(session.items)
+			inventory[57] = session.adena
+			from, to := inventory, session.warehouse
+			if opcode == opcodes.GameClientSendWarehouseWithdraw {
+				from, to = to, from
+			}
+			if count == 0 || from[itemID] < count {
+				reply = systemMessagePacket(351) // Incorrect item count
+				break
+			}
+			from[itemID] -= count
+			to[itemID] += count
+			session.adena = inventory[57]
+			delete(inventory, 57)
+			session.items = inventory
+			reply = s.itemListPacket(session)
+
+		case opcodes.GameClientSendBypassBuildCmd:
+			if session.selected == nil {
+				continue
+			}
+			// Only the forms the toolkit sends are understood: give_item <item id> <count> and enchant <item id> <level>
+			var command string
+			var itemID uint32
+			var value uint64
+			if fields := strings.Fields(packets.NewReader(data).ReadString()); len(fields) == 3 {
+				id, _ := strconv.ParseUint(fields[1], 10, 32)
+				command, itemID = fields[0], uint32(id)
+				value, _ = strconv.ParseUint(fields[2], 10, 64)
+			}
+			switch {
+			case !s.GM:
+				reply = systemMessagePacket(systemMessageText, "admin command denied")
+			case command == "give_item" && itemID == 57:
+				session.adena += value
+				reply = s.itemListPacket(session)
+			case command == "give_item" && itemID != 0 && value > 0:
+				session.items[itemID] += value
+				reply = s.itemListPacket(session)
+			case command == "enchant" && session.items[itemID] > 0:
+				reply = systemMessagePacket(systemMessageEnchanted, strconv.FormatUint(value, 10), strconv.FormatUint(uint64(itemID), 10))
+			default:
+				reply = systemMessagePacket(systemMessageText, "invalid admin command")
+			}
+
+		case opcodes.GameClientRequestActionUse:
+			if session.selected == nil {
+				return
+			}
+			switch packets.NewReader(data).ReadUInt32() {
+			case 0: // Sit / stand
+				session.sitting = !session.sitting
+				reply = changeWaitTypePacket(session.selected, session.sitting)
+			case 1: // Walk / run
+				session.walking = !session.walking
+				reply = changeMoveTypePacket(session.selected, !session.walking)
+			default:
+				continue
+			}
+
+		case opcodes.GameClientRequestSocialAction:
+			actionID := packets.NewReader(data).ReadUInt32()
+			if session.selected == nil || session.sitting || actionID < 2 || actionID > 13 {
+				continue
+			}
+			reply = socialActionPacket(session.selected, actionID)
+
+		case opcodes.GameClientAction:
+			if session.selected == nil {
+				return
+			}
+			objectID := packets.NewReader(data).ReadUInt32()
+			npc, ok := s.npc(objectID)
+			if !ok {
+				continue
+			}
+			if session.target != objectID {
+				session.target = objectID
+				reply = myTargetSelectedPacket(objectID)
+			} else {
+				reply = npcHtmlMessagePacket(objectID, npc.HTML)
+			}
+
+		case opcodes.GameClientRequestBypassToServer:
+			npc, ok := s.npc(session.target)
+			if !ok {
+				continue
+			}
+			command := packets.NewReader(data).ReadString()
+			var questID int
+			if _, err := fmt.Sscanf(command, "npc_%d_Quest %d", new(uint32), &questID); err == nil && session.selected != nil {
+				if session.quests == nil {
+					session.quests = make(map[int]int)
+				}
+				session.quests[questID]++
+				session.send(npcHtmlMessagePacket(npc.ObjectID, fmt.Sprintf("<html><body>Quest %d, step %d</body></html>", questID, session.quests[questID])))
+				reply = questListPacket(session.quests)
+				break
+			}
+			if location, ok := npc.Teleports[command]; ok && session.selected != nil {
+				session.target = 0
+				reply = teleportToLocationPacket(session.selected.ObjectID, location)
+				break
+			}
+			if npc.Goods != nil && command == fmt.Sprintf("npc_%d_Buy", npc.ObjectID) {
+				reply = buyListPacket(npc, session.adena)
+				break
+			}
+			if npc.Goods != nil && command == fmt.Sprintf("npc_%d_Sell", npc.ObjectID) {
+				reply = sellListPacket(npc, session.adena, session.items)
+				break
+			}
+			if npc.Warehouse && strings.HasSuffix(command, "Clan") && strings.HasPrefix(command, fmt.Sprintf("npc_%d_", npc.ObjectID)) {
+				reply = systemMessagePacket(212) // Not a clan member
+				break
+			}
+			if npc.Warehouse && command == fmt.Sprintf("npc_%d_Deposit", npc.ObjectID) {
+				inventory := maps.Clone(session.items)
+				inventory[57] = session.adena
+				reply = s.warehouseListPacket(session, opcodes.GameServerWarehouseDepositList, inventory)
+				break
+			}
+			if npc.Warehouse && command == fmt.Sprintf("npc_%d_Withdraw", npc.ObjectID) {
+				reply = s.warehouseListPacket(session, opcodes.GameServerWarehouseWithdrawList, session.warehouse)
+				break
+			}
+			html, ok := npc.Bypasses[command]
+			if !ok {
+				continue
+			}
+			reply = npcHtmlMessagePacket(npc.ObjectID, html)
+
+		case opcodes.GameClientRequestTargetCanceld:
+			if session.selected == nil {
+				return
+			}
+			session.target = 0
+			reply = targetUnselectedPacket(session.selected)
+
+		case opcodes.GameClientLogout:
+			if s.DelayLogout > 0 {
+				time.Sleep(s.DelayLogout)
+			}
+			s.leave(session)
+			session.send([]byte{opcodes.GameServerLogoutOk})
+			return
+
+		case opcodes.GameClientRequestRestart:
+			if session.selected == nil {
+				reply = restartResponsePacket(false)
+				break
+			}
+			s.leave(session)
+			session.selected, session.target = nil, 0
+			session.sitting, session.walking = false, false
+			if err := session.send(restartResponsePacket(true)); err != nil {
+				return
+			}
+			reply = s.charListPacket()
+
+		case opcodes.GameClientRequestShortCutReg:
+			if session.selected == nil {
+				return
+			}
+			reply = append([]byte{opcodes.GameServerShortCutRegister}, data...)
+
+		default:
+			continue
+		}
+
+		if err := session.send(reply); err != nil {
+			return
+		}
+	}
+}
+
+// enter puts the character of a session in the world, so the others can whisper to it and befriend it
+func (s *GameServer) enter(session *gameSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.online[strings.ToLower(session.selected.Name)] = presence{session: session, character: session.selected}
+	s.world[session] = struct{}{}
+}
+
+// broadcast sends a packet to the sessions in the world other than the one it comes from, each
+// encrypting its own copy
+func (s *GameServer) broadcast(from *gameSession, packet []byte) {
+	s.mu.Lock()
+	sessions := make([]*gameSession, 0, len(s.world))
+	for session := range s.world {
+		if session != from {
+			sessions = append(sessions, session)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, session := range sessions {
+		session.send(append([]byte(nil), packet...))
+	}
+}
+
+// leave takes the character of a session out of the world
+func (s *GameServer) leave(session *gameSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.world, session)
+	if session.selected == nil {
+		return
+	}
+	key := strings.ToLower(session.selected.Name)
+	if s.online[key].session == session {
+		delete(s.online, key)
+	}
+}
+
+func (s *GameServer) presence(name string) (presence, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	online, ok := s.online[strings.ToLower(name)]
+	return online, ok
+}
+
+// befriend puts two characters on the friend list of each other
+func (s *GameServer) befriend(name, friend string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, pair := range [][2]string{{name, friend}, {friend, name}} {
+		key := strings.ToLower(pair[0])
+		if !slices.ContainsFunc(s.friends[key], func(known string) bool { return strings.EqualFold(known, pair[1]) }) {
+			s.friends[key] = append(s.friends[key], pair[1])
+		}
+	}
+}
+
+// unfriend takes two characters off the friend list of each other
+func (s *GameServer) unfriend(name, friend string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, pair := range [][2]string{{name, friend}, {friend, name}} {
+		key := strings.ToLower(pair[0])
+		s.friends[key] = slices.DeleteFunc(s.friends[key], func(known string) bool { return strings.EqualFold(known, pair[1]) })
+	}
+}
+
+func (s *GameServer) friendListPacket(name string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	friends := s.friends[strings.ToLower(name)]
+
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerFriendList)
+	buffer.WriteUInt16(uint16(len(friends)))
+	for _, friend := range friends {
+		online, ok := s.online[strings.ToLower(friend)]
+		if ok {
+			buffer.WriteUInt32(online.character.ObjectID)
+		} else {
+			buffer.WriteUInt32(0)
+		}
+		buffer.WriteString(friend)
+		if ok {
+			buffer.WriteUInt32(1)
+		} else {
+			buffer.WriteUInt32(0)
+		}
+	}
+
+	return buffer.Bytes()
+}
+
+func (s *GameServer) charListPacket() []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerCharList)
+	buffer.WriteUInt32(uint32(len(s.characters)))
+
+	for _, character := range s.characters {
+		buffer.WriteString(character.Name)
+		buffer.WriteUInt32(character.ObjectID)
+		buffer.WriteUInt32(character.Sex)
+		buffer.WriteUInt32(character.Race)
+		buffer.WriteUInt32(character.ClassID)
+		buffer.WriteUInt32(character.Level)
+		buffer.WriteUInt32(character.Karma)
+		buffer.WriteUInt32(character.HairStyle)
+		buffer.WriteUInt32(character.HairColor)
+		buffer.WriteUInt32(character.Face)
+		for _, itemID := range character.Paperdoll {
+			buffer.WriteUInt32(itemID)
+		}
+
+		var lastAccess uint32
+		if !character.LastAccess.IsZero() {
+			lastAccess = uint32(character.LastAccess.Unix())
+		}
+		buffer.WriteUInt32(lastAccess)
+	}
+
+	return buffer.Bytes()
+}
+
+func charSelectedPacket(character *Character) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerCharSelected)
+	buffer.WriteString(character.Name)
+	buffer.WriteUInt32(character.ObjectID)
+	buffer.WriteUInt32(character.ClassID)
+	buffer.WriteUInt32(character.Level)
+
+	return buffer.Bytes()
+}
+
+func userInfoPacket(character *Character) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerUserInfo)
+	buffer.WriteUInt32(uint32(character.X))
+	buffer.WriteUInt32(uint32(character.Y))
+	buffer.WriteUInt32(uint32(character.Z))
+	buffer.WriteUInt32(character.ObjectID)
+	buffer.WriteString(character.Name)
+	buffer.WriteUInt32(character.Race)
+	buffer.WriteUInt32(character.Sex)
+	buffer.WriteUInt32(character.ClassID)
+	buffer.WriteUInt32(character.Level)
+
+	return buffer.Bytes()
+}
+
+// Chat type and system message of the whispers
+const (
+	chatTell                 = 2
+	systemMessageNotLoggedIn = 3
+)
+
+// System messages answering the admin commands
+const (
+	systemMessageEnchanted = 63
+	systemMessageText      = 1983
+)
+
+func creatureSayPacket(objectID, chatType uint32, name, text string) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerCreatureSay)
+	buffer.WriteUInt32(objectID)
+	buffer.WriteUInt32(chatType)
+	buffer.WriteString(name)
+	buffer.WriteString(text)
+
+	return buffer.Bytes()
+}
+
+func systemMessagePacket(messageID uint32, params ...string) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerSystemMessage)
+	buffer.WriteUInt32(messageID)
+	buffer.WriteUInt32(uint32(len(params)))
+	for _, param := range params {
+		buffer.WriteUInt32(0) // Text
+		buffer.WriteString(param)
+	}
+
+	return buffer.Bytes()
+}
+
+func questListPacket(quests map[int]int) []byte {
+	ids := slices.Sorted(maps.Keys(quests))
+
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerQuestList)
+	buffer.WriteUInt16(uint16(len(ids)))
+	for _, id := range ids {
+		buffer.WriteUInt32(uint32(id))
+		buffer.WriteUInt32(uint32(quests[id]))
+	}
+	buffer.WriteUInt16(0)
+
+	return buffer.Bytes()
+}
+
+func buyListPacket(npc NPC, adena uint64) []byte {
+	ids := slices.Sorted(maps.Keys(npc.Goods))
+
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerBuyList)
+	buffer.WriteUInt64(adena)
+	buffer.WriteUInt32(npc.ObjectID)
+	buffer.WriteUInt16(uint16(len(ids)))
+	for _, id := range ids {
+		buffer.WriteUInt32(id)
+		buffer.WriteUInt64(npc.Goods[id])
+		buffer.WriteUInt32(npc.Weights[id])
+	}
+
+	return buffer.Bytes()
+}
+
+func sellListPacket(npc NPC, adena uint64, items map[uint32]uint64) []byte {
+	var ids []uint32
+	for _, id := range slices.Sorted(maps.Keys(items)) {
+		if _, ok := npc.Goods[id]; ok && items[id] > 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerSellList)
+	buffer.WriteUInt64(adena)
+	buffer.WriteUInt32(npc.ObjectID)
+	buffer.WriteUInt16(uint16(len(ids)))
+	for _, id := range ids {
+		buffer.WriteUInt32(id)
+		buffer.WriteUInt64(items[id])
+		buffer.WriteUInt64(npc.Goods[id] / 2)
+	}
+
+	return buffer.Bytes()
+}
+
+// sendPages splits a list into pages of ItemsPerPage entries and sends them but the last one,
+// returned to be the reply
+func sendPages[T any](s *GameServer, session *gameSession, entries []T, packet func(packets.Page[T]) []byte) []byte {
+	perPage := s.ItemsPerPage
+	if perPage <= 0 {
+		perPage = len(entries)
+	}
+
+	pages := packets.Paginate(entries, perPage)
+	for _, page := range pages[:len(pages)-1] {
+		session.send(packet(page))
+	}
+	return packet(pages[len(pages)-1])
+}
+
+// listedItems returns the ids of the items held, sorted
+func listedItems(items map[uint32]uint64) []uint32 {
+	var ids []uint32
+	for _, id := range slices.Sorted(maps.Keys(items)) {
+		if items[id] > 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (s *GameServer) warehouseListPacket(session *gameSession, opcode byte, items map[uint32]uint64) []byte {
+	return sendPages(s, session, listedItems(items), func(page packets.Page[uint32]) []byte {
+		buffer := packets.NewBuffer()
+		buffer.WriteByte(opcode)
+		buffer.WriteByte(page.Flag)
+		buffer.WriteUInt16(1) // Private warehouse
+		buffer.WriteUInt64(session.adena)
+		buffer.WriteUInt16(uint16(len(page.Items)))
+		for _, id := range page.Items {
+			buffer.WriteUInt32(id)
+			buffer.WriteUInt64(items[id])
+		}
+
+		return buffer.Bytes()
+	})
+}
+
+func (s *GameServer) itemListPacket(session *gameSession) []byte {
+	items := maps.Clone(session.items)
+	items[57] = session.adena // Listed even when 0
+	ids := append([]uint32{57}, slices.DeleteFunc(listedItems(items), func(id uint32) bool { return id == 57 })...)
+
+	return sendPages(s, session, ids, func(page packets.Page[uint32]) []byte {
+		buffer := packets.NewBuffer()
+		buffer.WriteByte(opcodes.GameServerItemList)
+		buffer.WriteByte(page.Flag)
+		buffer.WriteUInt16(uint16(len(page.Items)))
+		for _, id := range page.Items {
+			buffer.WriteUInt32(id)
+			buffer.WriteUInt64(items[id])
+		}
+
+		return buffer.Bytes()
+	})
+}
+
+func askJoinFriendPacket(requestor string) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerAskJoinFriend)
+	buffer.WriteString(requestor)
+
+	return buffer.Bytes()
+}
+
+func changeWaitTypePacket(character *Character, sitting bool) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerChangeWaitType)
+	buffer.WriteUInt32(character.ObjectID)
+	if sitting {
+		buffer.WriteUInt32(0)
+	} else {
+		buffer.WriteUInt32(1)
+	}
+	buffer.WriteUInt32(uint32(character.X))
+	buffer.WriteUInt32(uint32(character.Y))
+	buffer.WriteUInt32(uint32(character.Z))
+
+	return buffer.Bytes()
+}
+
+// loadPacket is the StatusUpdate telling a character the weight it carries, out of its limit
+func loadPacket(character *Character, load, limit uint64) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerStatusUpdate)
+	buffer.WriteUInt32(character.ObjectID)
+	buffer.WriteUInt32(2)
+	buffer.WriteUInt32(0x0e) // Current load
+	buffer.WriteUInt32(uint32(load))
+	buffer.WriteUInt32(0x0f) // Maximum load
+	buffer.WriteUInt32(uint32(limit))
+
+	return buffer.Bytes()
+}
+
+func clientSetTimePacket(minutes uint32) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerClientSetTime)
+	buffer.WriteUInt32(minutes)
+	buffer.WriteUInt32(6) // Retail speed
+
+	return buffer.Bytes()
+}
+
+func socialActionPacket(character *Character, actionID uint32) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerSocialAction)
+	buffer.WriteUInt32(character.ObjectID)
+	buffer.WriteUInt32(actionID)
+
+	return buffer.Bytes()
+}
+
+func changeMoveTypePacket(character *Character, running bool) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerChangeMoveType)
+	buffer.WriteUInt32(character.ObjectID)
+	if running {
+		buffer.WriteUInt32(1)
+	} else {
+		buffer.WriteUInt32(0)
+	}
+	buffer.WriteUInt32(0)
+
+	return buffer.Bytes()
+}
+
+func myTargetSelectedPacket(objectID uint32) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerMyTargetSelected)
+	buffer.WriteUInt32(objectID)
+	buffer.WriteUInt16(0) // Level difference color
+
+	return buffer.Bytes()
+}
+
+func npcHtmlMessagePacket(objectID uint32, html string) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerNpcHtmlMessage)
+	buffer.WriteUInt32(objectID)
+	buffer.WriteString(html)
+	buffer.WriteUInt32(0)
+
+	return buffer.Bytes()
+}
+
+func targetUnselectedPacket(character *Character) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerTargetUnselected)
+	buffer.WriteUInt32(character.ObjectID)
+	buffer.WriteUInt32(uint32(character.X))
+	buffer.WriteUInt32(uint32(character.Y))
+	buffer.WriteUInt32(uint32(character.Z))
+
+	return buffer.Bytes()
+}
+
+func teleportToLocationPacket(objectID uint32, location Location) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerTeleportToLocation)
+	buffer.WriteUInt32(objectID)
+	buffer.WriteUInt32(uint32(location.X))
+	buffer.WriteUInt32(uint32(location.Y))
+	buffer.WriteUInt32(uint32(location.Z))
+
+	return buffer.Bytes()
+}
+
+func restartResponsePacket(accepted bool) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.GameServerRestartResponse)
+	if accepted {
+		buffer.WriteUInt32(1)
+	} else {
+		buffer.WriteUInt32(0)
+	}
+
+	return buffer.Bytes()
+}
@@ -0,0 +1,130 @@
+package testserver
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/frostwind/l2go/gameserver/crypt/xor"
+	"github.com/frostwind/l2go/packets"
+)
+
+// testGameClient drives the stub game server at the packet level
+type testGameClient struct {
+	t         *testing.T
+	conn      net.Conn
+	inputKey  []byte
+	outputKey []byte
+}
+
+func (c *testGameClient) send(data []byte) {
+	c.t.Helper()
+	if c.outputKey != nil {
+		xor.Encrypt(data, c.outputKey)
+	}
+	if err := writeFrame(c.conn, data); err != nil {
+		c.t.Fatalf("send failed: %v", err)
+	}
+}
+
+func (c *testGameClient) receive() (byte, *packets.Reader) {
+	c.t.Helper()
+	data, err := readFrame(c.conn)
+	if err != nil {
+		c.t.Fatalf("receive failed: %v", err)
+	}
+	if c.inputKey != nil {
+		xor.Decrypt(data, c.inputKey)
+	}
+	return data[0], packets.NewReader(data[1:])
+}
+
+func TestGameServerSession(t *testing.T) {
+	server := NewGameServer(Character{ObjectID: 0x10000001, Name: "Tester", Level: 1, X: -71338, Y: 258271, Z: -3104})
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	c := &testGameClient{t: t, conn: conn}
+
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(0x00)
+	buffer.WriteUInt32(419)
+	c.send(buffer.Bytes())
+
+	opcode, reader := c.receive()
+	if opcode != 0x00 {
+		t.Fatalf("expected CryptInit, got opcode %#x", opcode)
+	}
+	reader.ReadUInt8()
+	key := reader.ReadBytes(8)
+	if !bytes.Equal(key, DefaultXORKey) {
+		t.Fatalf("unexpected key %X", key)
+	}
+	c.inputKey = append([]byte(nil), key...)
+	c.outputKey = append([]byte(nil), key...)
+
+	buffer = packets.NewBuffer()
+	buffer.WriteByte(0x08)
+	buffer.WriteString("account")
+	c.send(buffer.Bytes())
+
+	opcode, reader = c.receive()
+	if opcode != 0x1f {
+		t.Fatalf("expected CharList, got opcode %#x", opcode)
+	}
+	if count := reader.ReadUInt32(); count != 1 {
+		t.Fatalf("expected 1 character, got %d", count)
+	}
+	if name := reader.ReadString(); name != "Tester" {
+		t.Fatalf("expected character Tester, got %q", name)
+	}
+
+	buffer = packets.NewBuffer()
+	buffer.WriteByte(0x0d)
+	buffer.WriteUInt32(0)
+	c.send(buffer.Bytes())
+
+	if opcode, _ = c.receive(); opcode != 0x15 {
+		t.Fatalf("expected CharSelected, got opcode %#x", opcode)
+	}
+
+	c.send([]byte{0x03})
+
+	// The world is at midnight of its first day, the sun down
+	if opcode, reader = c.receive(); opcode != 0xec || reader.ReadUInt32() != 0 {
+		t.Fatalf("expected ClientSetTime at midnight, got opcode %#x", opcode)
+	}
+	if opcode, _ = c.receive(); opcode != 0x1d {
+		t.Fatalf("expected SunSet, got opcode %#x", opcode)
+	}
+	if opcode, _ = c.receive(); opcode != 0x04 {
+		t.Fatalf("expected UserInfo, got opcode %#x", opcode)
+	}
+
+	buffer = packets.NewBuffer()
+	buffer.WriteByte(0x38)
+	buffer.WriteString("hello world")
+	buffer.WriteUInt32(0)
+	c.send(buffer.Bytes())
+
+	opcode, reader = c.receive()
+	if opcode != 0x4a {
+		t.Fatalf("expected CreatureSay, got opcode %#x", opcode)
+	}
+	reader.ReadUInt32()
+	reader.ReadUInt32()
+	reader.ReadString()
+	if text := reader.ReadString(); text != "hello world" {
+		t.Fatalf("expected echoed text, got %q", text)
+	}
+}
@@ -0,0 +1,399 @@
+package testserver
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/frostwind/l2go/loginserver/crypt"
+	"github.com/frostwind/l2go/opcodes"
+	"github.com/frostwind/l2go/packets"
+)
+
+// Response describes how the stub login server answers a client packet
+type Response struct {
+	// Delay is waited before anything is sent back
+	Delay time.Duration
+	// Drop closes the connection instead of replying
+	Drop bool
+	// Packets are sent in order, each one being a full unencrypted packet starting with its opcode
+	Packets [][]byte
+	// CorruptChecksum sends the packets with a wrong checksum
+	CorruptChecksum bool
+}
+
+// Script computes the response to a client packet (data excludes the opcode)
+type Script func(data []byte) Response
+
+// Reply answers with the given packets
+func Reply(packets ...[]byte) Script {
+	return func([]byte) Response {
+		return Response{Packets: packets}
+	}
+}
+
+// RejectLogin answers with a LoginFail packet holding the given reason
+func RejectLogin(reason uint32) Script {
+	return Reply(reasonPacket(opcodes.LoginServerLoginFail, reason))
+}
+
+// RejectPlay answers with a PlayFail packet holding the given reason
+func RejectPlay(reason uint32) Script {
+	return Reply(reasonPacket(opcodes.LoginServerPlayFail, reason))
+}
+
+// Drop closes the connection without answering
+func Drop() Script {
+	return func([]byte) Response {
+		return Response{Drop: true}
+	}
+}
+
+// Delay postpones the response produced by script
+func Delay(delay time.Duration, script Script) Script {
+	return func(data []byte) Response {
+		response := script(data)
+		response.Delay += delay
+		return response
+	}
+}
+
+// CorruptChecksum sends the response produced by script with wrong checksums
+func CorruptChecksum(script Script) Script {
+	return func(data []byte) Response {
+		response := script(data)
+		response.CorruptChecksum = true
+		return response
+	}
+}
+
+// Silence never answers, leaving the client waiting
+func Silence() Script {
+	return func([]byte) Response {
+		return Response{}
+	}
+}
+
+// ServerEntry is a game server advertised in the stub server list
+type ServerEntry struct {
+	ID         uint8
+	IP         net.IP
+	Port       int
+	Online     uint16
+	MaxPlayers uint16
+	Testing    bool
+}
+
+// LoginServer is a programmable fake login server. Every client opcode
+// can be scripted to accept, reject, delay or drop the connection.
+type LoginServer struct {
+	SessionKey []byte
+	PlayKey    []byte
+	Servers    []ServerEntry
+
+	// ServersPerPage splits the server list into pages of that many servers, one page when 0
+	ServersPerPage int
+
+	// VerifyChecksum drops clients sending packets with a wrong checksum, like the real server
+	VerifyChecksum bool
+
+	// NullCrypto announces the null crypto debug mode in Init, the packets then going in clear
+	NullCrypto bool
+
+	listener net.Listener
+	scripts  map[int]Script
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+}
+
+// NewLoginServer creates a stub login server accepting every login
+func NewLoginServer() *LoginServer {
+	s := &LoginServer{
+		SessionKey: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		PlayKey:    []byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18},
+		scripts:    make(map[int]Script),
+		conns:      make(map[net.Conn]struct{}),
+	}
+
+	s.scripts[OpcodeConnect] = func([]byte) Response { return Response{Packets: [][]byte{s.initPacket()}} }
+	s.scripts[int(opcodes.LoginClientRequestGGAuth)] = func(data []byte) Response { return Response{Packets: [][]byte{ggAuthPacket(data)}} }
+	s.scripts[int(opcodes.LoginClientRequestAuthLogin)] = s.AcceptLogin()
+	s.scripts[int(opcodes.LoginClientRequestServerList)] = func([]byte) Response { return Response{Packets: s.serverListPackets()} }
+	s.scripts[int(opcodes.LoginClientRequestPlay)] = func([]byte) Response { return Response{Packets: [][]byte{s.playOkPacket()}} }
+
+	return s
+}
+
+// OpcodeConnect is a pseudo opcode whose script runs when a client connects
+const OpcodeConnect = -1
+
+// On replaces the script used to answer the given opcode (or OpcodeConnect)
+func (s *LoginServer) On(opcode int, script Script) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[opcode] = script
+}
+
+// AcceptLogin answers with a LoginOk packet holding the session key, as the server does by default
+func (s *LoginServer) AcceptLogin() Script {
+	return func([]byte) Response { return Response{Packets: [][]byte{s.loginOkPacket()}} }
+}
+
+// AddGameServer advertises a game server listening at addr
+func (s *LoginServer) AddGameServer(id uint8, addr *net.TCPAddr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Servers = append(s.Servers, ServerEntry{ID: id, IP: addr.IP, Port: addr.Port, MaxPlayers: 1000})
+}
+
+// Start listens on an ephemeral loopback port and serves clients in the background
+func (s *LoginServer) Start() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to start stub login server: %w", err)
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return nil
+}
+
+// Addr returns the address the server is listening on
+func (s *LoginServer) Addr() *net.TCPAddr {
+	return s.listener.Addr().(*net.TCPAddr)
+}
+
+// Close stops the server and drops all connected clients
+func (s *LoginServer) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *LoginServer) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.drop(conn)
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *LoginServer) drop(conn net.Conn) {
+	conn.Close()
+
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+}
+
+func (s *LoginServer) script(key int) Script {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scripts[key]
+}
+
+func (s *LoginServer) handle(conn net.Conn) {
+	// The Init packet goes out in clear text
+	if !s.respond(conn, s.script(OpcodeConnect), nil, false) {
+		return
+	}
+
+	for {
+		data, err := readFrame(conn)
+		if err != nil {
+			return
+		}
+
+		if !s.NullCrypto {
+			data, err = crypt.BlowfishDecrypt(data, crypt.StaticBlowfishKey)
+			if err != nil {
+				return
+			}
+			if s.VerifyChecksum && (len(data) < 8 || !crypt.Checksum(data)) {
+				return
+			}
+		}
+		if len(data) == 0 {
+			return
+		}
+
+		script := s.script(int(data[0]))
+		if script == nil {
+			continue
+		}
+
+		if !s.respond(conn, script, data[1:], !s.NullCrypto) {
+			return
+		}
+	}
+}
+
+// respond runs a script and reports whether the connection should stay open
+func (s *LoginServer) respond(conn net.Conn, script Script, data []byte, encrypt bool) bool {
+	response := script(data)
+
+	if response.Delay > 0 {
+		time.Sleep(response.Delay)
+	}
+
+	if response.Drop {
+		return false
+	}
+
+	for _, packet := range response.Packets {
+		if encrypt {
+			var err error
+			packet, err = encryptLoginPacket(packet, response.CorruptChecksum)
+			if err != nil {
+				return false
+			}
+		}
+
+		if err := writeFrame(conn, packet); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// encryptLoginPacket appends the checksum, pads and encrypts a packet like the login server does
+func encryptLoginPacket(packet []byte, corrupt bool) ([]byte, error) {
+	data := make([]byte, len(packet), len(packet)+12)
+	copy(data, packet)
+	data = append(data, 0x00, 0x00, 0x00, 0x00)
+
+	for len(data)%8 != 0 {
+		data = append(data, 0x00)
+	}
+
+	crypt.Checksum(data)
+	if corrupt {
+		data[len(data)-8] ^= 0xff
+	}
+
+	return crypt.BlowfishEncrypt(data, crypt.StaticBlowfishKey)
+}
+
+func reasonPacket(opcode byte, reason uint32) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcode)
+	buffer.WriteUInt32(reason)
+
+	return buffer.Bytes()
+}
+
+func (s *LoginServer) initPacket() []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.LoginServerInit)
+	buffer.Write([]byte{0x9c, 0x77, 0xed, 0x03}) // Session id
+	buffer.Write([]byte{0x5a, 0x78, 0x00, 0x00}) // Protocol version : 785a
+	if s.NullCrypto {
+		buffer.WriteUInt32(opcodes.NullCryptoMarker)
+	}
+
+	return buffer.Bytes()
+}
+
+// ggAuthPacket answers RequestGGAuth with the session id it carries
+func ggAuthPacket(request []byte) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.LoginServerGGAuth)
+	buffer.Write(request[:min(len(request), 4)])
+	buffer.Write(make([]byte, 16))
+
+	return buffer.Bytes()
+}
+
+func (s *LoginServer) loginOkPacket() []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.LoginServerLoginOk)
+	buffer.Write(s.SessionKey[:8])
+	buffer.WriteUInt32(0x00)
+	buffer.WriteUInt32(0x00)
+	buffer.WriteUInt32(0x000003ea)
+	buffer.WriteUInt32(0x00)
+	buffer.WriteUInt32(0x00)
+	buffer.WriteUInt32(0x02)
+
+	return buffer.Bytes()
+}
+
+func (s *LoginServer) serverListPackets() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	perPage := s.ServersPerPage
+	if perPage <= 0 {
+		perPage = len(s.Servers)
+	}
+
+	var buffers [][]byte
+	for _, page := range packets.Paginate(s.Servers, perPage) {
+		buffers = append(buffers, serverListPacket(page))
+	}
+	return buffers
+}
+
+func serverListPacket(page packets.Page[ServerEntry]) []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.LoginServerServerList)
+	buffer.WriteUInt8(uint8(len(page.Items)))
+	buffer.WriteByte(page.Flag)
+
+	for _, server := range page.Items {
+		ip := server.IP.To4()
+		if ip == nil {
+			ip = net.IPv4(127, 0, 0, 1).To4()
+		}
+
+		buffer.WriteUInt8(server.ID)
+		buffer.Write(ip)
+		buffer.WriteUInt32(uint32(server.Port))
+		buffer.WriteByte(0x0f)
+		buffer.WriteByte(0x01)
+		buffer.WriteUInt16(server.Online)
+		buffer.WriteUInt16(server.MaxPlayers)
+		if server.Testing {
+			buffer.WriteByte(0x00)
+		} else {
+			buffer.WriteByte(0x01)
+		}
+		buffer.WriteUInt32(0x02)
+	}
+
+	return buffer.Bytes()
+}
+
+func (s *LoginServer) playOkPacket() []byte {
+	buffer := packets.NewBuffer()
+	buffer.WriteByte(opcodes.LoginServerPlayOk)
+	buffer.Write(s.PlayKey[:8])
+
+	return buffer.Bytes()
+}